@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -9,16 +10,25 @@ import (
 	"syscall"
 	"time"
 
+	"banking/internal/auth"
 	"banking/internal/config"
 	"banking/internal/db"
+	"banking/internal/events"
+	"banking/internal/export"
 	"banking/internal/handlers"
+	"banking/internal/middleware"
+	"banking/internal/notifications"
 	"banking/internal/services"
 	"banking/internal/store"
+	"banking/internal/webhooks"
 	"banking/internal/websocket"
 )
 
 func main() {
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
 	database, err := db.Connect(cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("failed to connect database: %v", err)
@@ -33,11 +43,117 @@ func main() {
 	quotes := store.NewExchangeQuoteStore(database)
 	admin := store.NewAdminStore(database)
 	audit := store.NewAuditStore(database)
+	balances := store.NewBalanceStore(database)
+	gl := store.NewGLStore(database)
+	periods := store.NewPeriodStore(database)
+	outbox := store.NewOutboxStore(database)
+	hooks := store.NewWebhookStore(database)
+	streams := store.NewStreamStore(database)
+	tickets := store.NewWSTicketStore(database)
+	notificationStore := store.NewNotificationStore(database)
+	devices := store.NewDeviceStore(database)
+	alertRules := store.NewAlertRuleStore(database)
+	sessions := store.NewSessionStore(database)
+	challenges := store.NewLoginChallengeStore(database)
+	throttles := store.NewLoginThrottleStore(database)
+	userTokens := store.NewUserTokenStore(database)
+	apiKeys := store.NewAPIKeyStore(database)
+	organizations := store.NewOrganizationStore(database)
+	approvals := store.NewApprovalStore(database)
 	txRunner := db.NewTxRunner(database)
 	hub := websocket.NewHub()
-	service := services.NewTransactionService(txRunner, accounts, ledger, transactions, exchange, quotes, audit, hub)
+	service := services.NewTransactionService(txRunner, accounts, ledger, transactions, exchange, quotes, periods, audit, outbox, services.NewAlertService(alertRules, outbox, cfg.AlertCooldown), services.QuoteTTLs{Default: cfg.QuoteTTL, Pairs: cfg.QuoteTTLByPair})
+	periodService := services.NewPeriodService(txRunner, periods, audit, outbox)
+	quoteNotifier := services.NewQuoteNotifier(txRunner, quotes, outbox, cfg.QuoteExpiryWarning)
+	checkpoints := services.NewCheckpointService(txRunner, accounts, balances, cfg.CheckpointLag, cfg.CheckpointMinEntries)
+	sender := webhooks.NewSender(hooks, webhooks.NewClient(cfg.WebhookTimeout))
+	notifier, closeNotifier, err := newNotifier(cfg)
+	if err != nil {
+		log.Fatalf("failed to configure notifications: %v", err)
+	}
+	defer closeNotifier()
+	notificationSender := notifications.NewSender(notificationStore, notifications.Links{BaseURL: cfg.PublicURL, Key: cfg.MailTokenKey}, notifier)
+	exportWriter, err := newExportWriter(cfg)
+	if err != nil {
+		log.Fatalf("failed to configure event export: %v", err)
+	}
+
+	jobs, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	keys := auth.NewKeyRing(cfg.JWTIssuer, cfg.JWTAudience)
+	rotator := auth.NewKeyRotator(store.NewSigningKeyStore(database), keys, cfg.JWTKeyEncryptionKey, cfg.JWTAlgorithm, cfg.JWTKeyLifetime, cfg.JWTKeyPublishLead, cfg.TokenTTL)
+	if err := rotator.RotateOnce(jobs); err != nil {
+		log.Fatalf("failed to load signing keys: %v", err)
+	}
+	go rotator.Run(jobs, cfg.JWTKeyPollInterval)
+
+	revocations := middleware.NewRevocations(sessions, cfg.TokenTTL)
+	if err := revocations.Reload(jobs); err != nil {
+		log.Fatalf("failed to load session revocations: %v", err)
+	}
+	go revocations.Run(jobs, cfg.RevocationPollInterval)
+
+	var rateLimitStore middleware.RateLimitStore = middleware.NewMemoryRateLimitStore()
+	if cfg.RateLimitBackend == "postgres" {
+		rateLimitStore = store.NewRateLimitStore(database)
+	}
+	rateLimits := middleware.NewRateLimiter(rateLimitStore, rateLimitPolicies(cfg.RateLimits))
+	go rateLimits.Run(jobs, cfg.RateLimitPruneInterval)
+
+	var broadcaster websocket.Broadcaster = websocket.NewMemoryBroadcaster(hub)
+	if cfg.BalanceBroadcaster == "postgres" {
+		pg := websocket.NewPGBroadcaster(database, hub)
+		go pg.Listen(jobs, cfg.DatabaseURL)
+		broadcaster = pg
+	}
+	dispatcher := events.NewDispatcher(outbox, events.NewHubSink(streams, broadcaster), webhooks.NewSink(hooks), notifications.NewSink(notificationStore, notifier.Channel()))
+	go checkpoints.Run(jobs, cfg.CheckpointInterval)
+	go quoteNotifier.Run(jobs, cfg.QuotePollInterval)
+	go dispatcher.Run(jobs, cfg.OutboxPollInterval)
+	go sender.Run(jobs, cfg.WebhookPollInterval)
+	go notificationSender.Run(jobs, cfg.NotificationPollInterval)
+	if exportWriter != nil {
+		exporter := export.NewExporter("cloudevents", cfg.EventExportSource, outbox, store.NewExportCheckpointStore(database), exportWriter)
+		go exporter.Run(jobs, cfg.EventExportPollInterval)
+	}
 
-	handler := handlers.New(database, txRunner, cfg, users, accounts, ledger, transactions, exchange, admin, audit, service, hub)
+	handler := handlers.New(handlers.Deps{
+		ReconcileDB:   database,
+		TxRunner:      txRunner,
+		Config:        cfg,
+		Users:         users,
+		Accounts:      accounts,
+		Ledger:        ledger,
+		Balances:      balances,
+		GL:            gl,
+		Transactions:  transactions,
+		Exchange:      exchange,
+		Admin:         admin,
+		Audit:         audit,
+		Outbox:        outbox,
+		Webhooks:      hooks,
+		Streams:       streams,
+		Quotes:        quotes,
+		Tickets:       tickets,
+		Notifications: notificationStore,
+		Devices:       devices,
+		Alerts:        alertRules,
+		Sessions:      sessions,
+		Keys:          keys,
+		Revocations:   revocations,
+		RateLimits:    rateLimits,
+		TwoFactor:     users,
+		Challenges:    challenges,
+		Throttles:     throttles,
+		Tokens:        userTokens,
+		APIKeys:       apiKeys,
+		Organizations: organizations,
+		Approvals:     approvals,
+		Service:       service,
+		Periods:       periodService,
+		Hub:           hub,
+	})
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
 		Handler:      handler.Routes(),
@@ -56,6 +172,7 @@ func main() {
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 	<-shutdown
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -63,3 +180,47 @@ func main() {
 		log.Fatalf("shutdown error: %v", err)
 	}
 }
+
+func newNotifier(cfg config.Config) (notifications.Notifier, func(), error) {
+	switch cfg.NotificationBackend {
+	case "smtp":
+		return notifications.NewSMTPNotifier(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom), func() {}, nil
+	case "log":
+		if cfg.NotificationLogPath == "" {
+			return notifications.NewLogNotifier(os.Stdout), func() {}, nil
+		}
+		file, err := os.OpenFile(cfg.NotificationLogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, nil, err
+		}
+		return notifications.NewLogNotifier(file), func() { _ = file.Close() }, nil
+	}
+	return nil, nil, fmt.Errorf("unknown notification backend %q", cfg.NotificationBackend)
+}
+
+func newExportWriter(cfg config.Config) (export.Writer, error) {
+	switch cfg.EventExportBackend {
+	case "":
+		return nil, nil
+	case "file":
+		writer, err := export.NewFileWriter(cfg.EventExportDir, cfg.EventExportMaxFileBytes, cfg.EventExportRotate)
+		if err != nil {
+			return nil, err
+		}
+		return writer, nil
+	case "http":
+		if cfg.EventExportURL == "" {
+			return nil, fmt.Errorf("EVENT_EXPORT_URL is required for the http backend")
+		}
+		return export.NewHTTPWriter(cfg.EventExportURL, &http.Client{Timeout: cfg.EventExportTimeout}), nil
+	}
+	return nil, fmt.Errorf("unknown event export backend %q", cfg.EventExportBackend)
+}
+
+func rateLimitPolicies(limits map[string]config.RateLimit) map[string]middleware.RateLimitPolicy {
+	policies := make(map[string]middleware.RateLimitPolicy, len(limits))
+	for name, limit := range limits {
+		policies[name] = middleware.RateLimitPolicy{Limit: limit.Limit, Window: limit.Window}
+	}
+	return policies
+}
@@ -0,0 +1,54 @@
+package auth
+
+import "strings"
+
+// Checked in order: Edge and Opera also claim to be Chrome, and Chrome claims
+// to be Safari.
+var browsers = []struct{ token, name string }{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+}
+
+// Checked in order: iOS user agents say "like Mac OS X" and Android ones say
+// Linux.
+var platforms = []struct{ token, name string }{
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+func DeviceLabel(userAgent string) string {
+	var browser, platform string
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, p := range platforms {
+		if strings.Contains(userAgent, p.token) {
+			platform = p.name
+			break
+		}
+	}
+	if browser == "" && !strings.HasPrefix(userAgent, "Mozilla/") {
+		product, _, _ := strings.Cut(strings.TrimSpace(userAgent), " ")
+		browser, _, _ = strings.Cut(product, "/")
+	}
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+	return "Unknown device"
+}
@@ -1,31 +1,128 @@
 package auth
 
 import (
+	"errors"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+var (
+	ErrNoSigningKey  = errors.New("no signing key available")
+	ErrUnknownKey    = errors.New("unknown signing key")
+	ErrKeyAlgorithm  = errors.New("token algorithm does not match its key")
+	ErrMissingClaims = errors.New("token is missing required claims")
+)
+
 type Claims struct {
 	UserID string `json:"user_id"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(secret string, userID string, ttl time.Duration) (string, error) {
+type KeyRing struct {
+	issuer   string
+	audience string
+	mu       sync.RWMutex
+	keys     []SigningKey
+}
+
+func NewKeyRing(issuer, audience string) *KeyRing {
+	return &KeyRing{issuer: issuer, audience: audience}
+}
+
+func (k *KeyRing) SetKeys(keys []SigningKey) {
+	sorted := append([]SigningKey(nil), keys...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ActivatesAt.Before(sorted[j].ActivatesAt) })
+	k.mu.Lock()
+	k.keys = sorted
+	k.mu.Unlock()
+}
+
+// signingKey is the most recently activated key. A retired key keeps signing
+// if no newer one has activated, so a stalled rotation does not stop logins.
+func (k *KeyRing) signingKey(now time.Time) (SigningKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if !k.keys[i].ActivatesAt.After(now) {
+			return k.keys[i], true
+		}
+	}
+	return SigningKey{}, false
+}
+
+func (k *KeyRing) key(id string) (SigningKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return SigningKey{}, false
+}
+
+func (k *KeyRing) JWKS() []JWK {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	jwks := make([]JWK, 0, len(k.keys))
+	for _, key := range k.keys {
+		jwks = append(jwks, key.JWK())
+	}
+	return jwks
+}
+
+func GenerateToken(keys *KeyRing, userID string, ttl time.Duration) (string, error) {
+	return GenerateSessionToken(keys, userID, "", ttl)
+}
+
+func GenerateSessionToken(keys *KeyRing, userID, sessionID string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	key, ok := keys.signingKey(now)
+	if !ok {
+		return "", ErrNoSigningKey
+	}
+	method := jwt.GetSigningMethod(key.Algorithm)
+	if method == nil {
+		return "", ErrUnsupportedAlgorithm
+	}
 	claims := Claims{
 		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        sessionID,
+			Subject:   userID,
+			Issuer:    keys.issuer,
+			Audience:  jwt.ClaimStrings{keys.audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
 }
 
-func ParseToken(secret, tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (any, error) {
-		return []byte(secret), nil
+func ParseToken(keys *KeyRing, tokenString string) (*Claims, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA}),
+		jwt.WithIssuer(keys.issuer),
+		jwt.WithAudience(keys.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	token, err := parser.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (any, error) {
+		id, _ := token.Header["kid"].(string)
+		key, ok := keys.key(id)
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, ErrKeyAlgorithm
+		}
+		return key.private.Public(), nil
 	})
 	if err != nil {
 		return nil, err
@@ -34,5 +131,8 @@ func ParseToken(secret, tokenString string) (*Claims, error) {
 	if !ok || !token.Valid {
 		return nil, jwt.ErrTokenInvalidClaims
 	}
+	if claims.NotBefore == nil || claims.IssuedAt == nil {
+		return nil, ErrMissingClaims
+	}
 	return claims, nil
 }
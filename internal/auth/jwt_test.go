@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestRing(t *testing.T, algorithm string) (*KeyRing, SigningKey) {
+	t.Helper()
+	key, err := NewSigningKey(algorithm, time.Now().Add(-time.Minute), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	ring := NewKeyRing("banking", "banking-api")
+	ring.SetKeys([]SigningKey{key})
+	return ring, key
+}
+
+func TestTokenRoundTrip(t *testing.T) {
+	for _, algorithm := range []string{AlgEdDSA, AlgRS256} {
+		ring, key := newTestRing(t, algorithm)
+		token, err := GenerateSessionToken(ring, "user-1", "session-1", time.Minute)
+		if err != nil {
+			t.Fatalf("%s: failed to sign: %v", algorithm, err)
+		}
+		header, _ := decodeSegment(t, token, 0)
+		if header["alg"] != algorithm || header["kid"] != key.ID {
+			t.Fatalf("%s: unexpected header %#v", algorithm, header)
+		}
+		claims, err := ParseToken(ring, token)
+		if err != nil {
+			t.Fatalf("%s: failed to parse: %v", algorithm, err)
+		}
+		if claims.UserID != "user-1" || claims.Subject != "user-1" || claims.ID != "session-1" || claims.Issuer != "banking" {
+			t.Fatalf("%s: unexpected claims %#v", algorithm, claims)
+		}
+	}
+}
+
+func TestParseTokenRejectsOtherIssuerAudienceAndKeys(t *testing.T) {
+	ring, key := newTestRing(t, AlgEdDSA)
+	token, err := GenerateToken(ring, "user-1", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	for name, other := range map[string]*KeyRing{
+		"issuer":   NewKeyRing("elsewhere", "banking-api"),
+		"audience": NewKeyRing("banking", "other-api"),
+	} {
+		other.SetKeys([]SigningKey{key})
+		if _, err := ParseToken(other, token); err == nil {
+			t.Fatalf("expected a token for another %s to be rejected", name)
+		}
+	}
+	stranger, _ := newTestRing(t, AlgEdDSA)
+	if _, err := ParseToken(stranger, token); err == nil {
+		t.Fatalf("expected a token with an unknown kid to be rejected")
+	}
+}
+
+func TestParseTokenRejectsForgedAlgorithms(t *testing.T) {
+	ring, key := newTestRing(t, AlgRS256)
+	claims := Claims{UserID: "user-1", RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    "banking",
+		Audience:  jwt.ClaimStrings{"banking-api"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		NotBefore: jwt.NewNumericDate(time.Now()),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}}
+
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+	none.Header["kid"] = key.ID
+	unsigned, err := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("failed to build token: %v", err)
+	}
+	if _, err := ParseToken(ring, unsigned); err == nil {
+		t.Fatalf("expected alg none to be rejected")
+	}
+
+	// HS256 signed with the published public key is the classic confusion
+	// attack against verifiers that trust the token's alg.
+	jwk, _ := json.Marshal(key.JWK())
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	hs.Header["kid"] = key.ID
+	forged, err := hs.SignedString(jwk)
+	if err != nil {
+		t.Fatalf("failed to build token: %v", err)
+	}
+	if _, err := ParseToken(ring, forged); err == nil {
+		t.Fatalf("expected HS256 to be rejected")
+	}
+
+	eddsa, _ := newTestRing(t, AlgEdDSA)
+	edToken, _ := GenerateToken(eddsa, "user-1", time.Minute)
+	parts := strings.Split(edToken, ".")
+	header, _ := json.Marshal(map[string]string{"alg": AlgEdDSA, "kid": key.ID, "typ": "JWT"})
+	parts[0] = base64.RawURLEncoding.EncodeToString(header)
+	if _, err := ParseToken(ring, strings.Join(parts, ".")); err == nil {
+		t.Fatalf("expected an algorithm that does not match the key to be rejected")
+	}
+}
+
+func TestParseTokenRequiresNotBefore(t *testing.T) {
+	ring, key := newTestRing(t, AlgEdDSA)
+	claims := Claims{UserID: "user-1", RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    "banking",
+		Audience:  jwt.ClaimStrings{"banking-api"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.private)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if _, err := ParseToken(ring, signed); err != ErrMissingClaims {
+		t.Fatalf("expected ErrMissingClaims, got %v", err)
+	}
+
+	claims.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	token = jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = key.ID
+	signed, _ = token.SignedString(key.private)
+	if _, err := ParseToken(ring, signed); err == nil {
+		t.Fatalf("expected a token that is not valid yet to be rejected")
+	}
+}
+
+func TestKeyRingSignsWithNewestActiveKey(t *testing.T) {
+	now := time.Now()
+	old, _ := NewSigningKey(AlgEdDSA, now.Add(-2*time.Hour), time.Hour)
+	current, _ := NewSigningKey(AlgEdDSA, now.Add(-time.Hour), 2*time.Hour)
+	next, _ := NewSigningKey(AlgEdDSA, now.Add(time.Hour), time.Hour)
+	ring := NewKeyRing("banking", "banking-api")
+	ring.SetKeys([]SigningKey{next, old, current})
+	token, err := GenerateToken(ring, "user-1", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if header, _ := decodeSegment(t, token, 0); header["kid"] != current.ID {
+		t.Fatalf("expected the current key to sign, got %v", header["kid"])
+	}
+	if jwks := ring.JWKS(); len(jwks) != 3 || jwks[2].KeyID != next.ID || jwks[2].Curve != "Ed25519" {
+		t.Fatalf("expected every key published, got %#v", jwks)
+	}
+
+	empty := NewKeyRing("banking", "banking-api")
+	empty.SetKeys([]SigningKey{next})
+	if _, err := GenerateToken(empty, "user-1", time.Minute); err != ErrNoSigningKey {
+		t.Fatalf("expected ErrNoSigningKey, got %v", err)
+	}
+}
+
+func TestSigningKeyMarshalRoundTrip(t *testing.T) {
+	for _, algorithm := range []string{AlgEdDSA, AlgRS256} {
+		key, err := NewSigningKey(algorithm, time.Now(), time.Hour)
+		if err != nil {
+			t.Fatalf("failed to create key: %v", err)
+		}
+		private, err := key.MarshalPrivateKey()
+		if err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+		parsed, err := ParseSigningKey(key.ID, algorithm, private, key.ActivatesAt, key.RetiresAt)
+		if err != nil || parsed.JWK() != key.JWK() {
+			t.Fatalf("%s: key did not round-trip: %v", algorithm, err)
+		}
+		other := AlgRS256
+		if algorithm == AlgRS256 {
+			other = AlgEdDSA
+		}
+		if _, err := ParseSigningKey(key.ID, other, private, key.ActivatesAt, key.RetiresAt); err != ErrUnsupportedAlgorithm {
+			t.Fatalf("%s: expected a mismatched algorithm to be rejected, got %v", algorithm, err)
+		}
+	}
+}
+
+func decodeSegment(t *testing.T, token string, index int) (map[string]any, error) {
+	t.Helper()
+	raw, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[index])
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	err = json.Unmarshal(raw, &out)
+	return out, err
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"banking/internal/store"
+)
+
+type KeyStore interface {
+	Latest(ctx context.Context) (store.SigningKey, error)
+	Create(ctx context.Context, key store.SigningKey) (bool, error)
+	ListUsable(ctx context.Context, retiredWithin time.Duration) ([]store.SigningKey, error)
+}
+
+// KeyRotator creates each key's successor lead before it signs, so it is in
+// every ring and cached JWKS first, and keeps retired keys for grace so the
+// tokens they signed verify until they expire.
+type KeyRotator struct {
+	store      KeyStore
+	ring       *KeyRing
+	passphrase string
+	algorithm  string
+	lifetime   time.Duration
+	lead       time.Duration
+	grace      time.Duration
+}
+
+func NewKeyRotator(store KeyStore, ring *KeyRing, passphrase, algorithm string, lifetime, lead, grace time.Duration) *KeyRotator {
+	return &KeyRotator{
+		store:      store,
+		ring:       ring,
+		passphrase: passphrase,
+		algorithm:  algorithm,
+		lifetime:   lifetime,
+		lead:       lead,
+		grace:      grace,
+	}
+}
+
+func (r *KeyRotator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := r.RotateOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("signing key rotation failed: %v", err)
+		}
+	}
+}
+
+func (r *KeyRotator) RotateOnce(ctx context.Context) error {
+	now := time.Now()
+	latest, err := r.store.Latest(ctx)
+	switch {
+	case err == sql.ErrNoRows:
+		if err := r.create(ctx, 1, now); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case latest.RetiresAt.Sub(now) <= r.lead:
+		activatesAt := latest.RetiresAt
+		if activatesAt.Before(now) {
+			activatesAt = now
+		}
+		if err := r.create(ctx, latest.Generation+1, activatesAt); err != nil {
+			return err
+		}
+	}
+	return r.Reload(ctx)
+}
+
+func (r *KeyRotator) create(ctx context.Context, generation int, activatesAt time.Time) error {
+	key, err := NewSigningKey(r.algorithm, activatesAt, r.lifetime)
+	if err != nil {
+		return err
+	}
+	private, err := key.MarshalPrivateKey()
+	if err != nil {
+		return err
+	}
+	sealed, err := SealSecret(r.passphrase, private)
+	if err != nil {
+		return err
+	}
+	_, err = r.store.Create(ctx, store.SigningKey{
+		ID:          key.ID,
+		Generation:  generation,
+		Algorithm:   key.Algorithm,
+		PrivateKey:  sealed,
+		ActivatesAt: key.ActivatesAt,
+		RetiresAt:   key.RetiresAt,
+	})
+	return err
+}
+
+func (r *KeyRotator) Reload(ctx context.Context) error {
+	stored, err := r.store.ListUsable(ctx, r.grace)
+	if err != nil {
+		return err
+	}
+	keys := make([]SigningKey, 0, len(stored))
+	for _, row := range stored {
+		private, err := OpenSecret(r.passphrase, row.PrivateKey)
+		if err != nil {
+			return err
+		}
+		key, err := ParseSigningKey(row.ID, row.Algorithm, private, row.ActivatesAt, row.RetiresAt)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	r.ring.SetKeys(keys)
+	return nil
+}
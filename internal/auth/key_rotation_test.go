@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"banking/internal/store"
+)
+
+type memoryKeyStore struct {
+	keys []store.SigningKey
+}
+
+func (s *memoryKeyStore) Latest(context.Context) (store.SigningKey, error) {
+	if len(s.keys) == 0 {
+		return store.SigningKey{}, sql.ErrNoRows
+	}
+	return s.keys[len(s.keys)-1], nil
+}
+
+func (s *memoryKeyStore) Create(_ context.Context, key store.SigningKey) (bool, error) {
+	for _, existing := range s.keys {
+		if existing.Generation == key.Generation {
+			return false, nil
+		}
+	}
+	s.keys = append(s.keys, key)
+	return true, nil
+}
+
+func (s *memoryKeyStore) ListUsable(_ context.Context, retiredWithin time.Duration) ([]store.SigningKey, error) {
+	var usable []store.SigningKey
+	for _, key := range s.keys {
+		if key.RetiresAt.After(time.Now().Add(-retiredWithin)) {
+			usable = append(usable, key)
+		}
+	}
+	return usable, nil
+}
+
+func TestKeyRotatorCreatesFirstKey(t *testing.T) {
+	keys := &memoryKeyStore{}
+	ring := NewKeyRing("banking", "banking-api")
+	rotator := NewKeyRotator(keys, ring, "passphrase", AlgEdDSA, 30*24*time.Hour, 24*time.Hour, 15*time.Minute)
+	if err := rotator.RotateOnce(context.Background()); err != nil {
+		t.Fatalf("rotation failed: %v", err)
+	}
+	if err := rotator.RotateOnce(context.Background()); err != nil {
+		t.Fatalf("rotation failed: %v", err)
+	}
+	if len(keys.keys) != 1 || keys.keys[0].Generation != 1 {
+		t.Fatalf("expected one key, got %#v", keys.keys)
+	}
+	if _, err := OpenSecret("passphrase", keys.keys[0].PrivateKey); err != nil {
+		t.Fatalf("expected the private key to be sealed: %v", err)
+	}
+	token, err := GenerateToken(ring, "user-1", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if _, err := ParseToken(ring, token); err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+}
+
+func TestKeyRotatorPublishesSuccessorAheadOfRetirement(t *testing.T) {
+	keys := &memoryKeyStore{}
+	ring := NewKeyRing("banking", "banking-api")
+	rotator := NewKeyRotator(keys, ring, "passphrase", AlgEdDSA, time.Hour, 24*time.Hour, 15*time.Minute)
+	if err := rotator.RotateOnce(context.Background()); err != nil {
+		t.Fatalf("rotation failed: %v", err)
+	}
+	signed, err := GenerateToken(ring, "user-1", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if err := rotator.RotateOnce(context.Background()); err != nil {
+		t.Fatalf("rotation failed: %v", err)
+	}
+	if len(keys.keys) != 2 || !keys.keys[1].ActivatesAt.Equal(keys.keys[0].RetiresAt) {
+		t.Fatalf("expected a successor activating when the first key retires, got %#v", keys.keys)
+	}
+	if len(ring.JWKS()) != 2 {
+		t.Fatalf("expected both keys published")
+	}
+	if _, err := ParseToken(ring, signed); err != nil {
+		t.Fatalf("expected tokens from the current key to keep verifying: %v", err)
+	}
+	again, _ := GenerateToken(ring, "user-1", time.Minute)
+	if header, _ := decodeSegment(t, again, 0); header["kid"] != keys.keys[0].ID {
+		t.Fatalf("expected the current key to keep signing until its successor activates")
+	}
+}
@@ -0,0 +1,26 @@
+package auth
+
+const (
+	PermissionViewUsers        = "CanViewUsers"
+	PermissionViewTransactions = "CanViewTransactions"
+	PermissionViewReports      = "CanViewReports"
+	PermissionClosePeriods     = "CanClosePeriods"
+	PermissionPostAdjustments  = "CanPostAdjustments"
+	PermissionManageRates      = "CanManageRates"
+	PermissionFreezeAccounts   = "CanFreezeAccounts"
+	PermissionViewAdmins       = "CanViewAdmins"
+
+	PermissionApproveAdminChanges = "CanApproveAdminChanges"
+	PermissionApproveTransfers    = "CanApproveTransfers"
+)
+
+var Permissions = []string{PermissionViewUsers, PermissionViewTransactions, PermissionViewReports, PermissionClosePeriods, PermissionPostAdjustments, PermissionManageRates, PermissionFreezeAccounts, PermissionViewAdmins, PermissionApproveAdminChanges, PermissionApproveTransfers}
+
+func IsPermission(value string) bool {
+	for _, permission := range Permissions {
+		if permission == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package auth
+
+// APIKeyPrefix tells a bearer API key apart from a JWT without parsing it.
+const APIKeyPrefix = "bk_"
+
+const (
+	ScopeReadAccounts     = "read:accounts"
+	ScopeReadTransactions = "read:transactions"
+	ScopeReadUsers        = "read:users"
+	ScopeWriteTransfers   = "write:transfers"
+	ScopeWriteExchanges   = "write:exchanges"
+	ScopeReadWebhooks     = "read:webhooks"
+	ScopeWriteWebhooks    = "write:webhooks"
+	ScopeReadEvents       = "read:events"
+)
+
+var Scopes = []string{ScopeReadAccounts, ScopeReadTransactions, ScopeReadUsers, ScopeWriteTransfers, ScopeWriteExchanges, ScopeReadWebhooks, ScopeWriteWebhooks, ScopeReadEvents}
+
+func IsScope(value string) bool {
+	for _, scope := range Scopes {
+		if scope == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+func NewOpaqueToken(prefix string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(raw), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// DeriveToken rebuilds the same token from ref for as long as key is
+// unchanged, so only ref has to be stored until the token is sent.
+func DeriveToken(key, prefix, ref string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(ref))
+	return prefix + hex.EncodeToString(mac.Sum(nil))
+}
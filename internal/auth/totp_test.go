@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key from the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeMatchesRFCVectors(t *testing.T) {
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range cases {
+		got, err := TOTPCode(rfcSecret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want {
+			t.Fatalf("at %d expected %s, got %s", unix, want, got)
+		}
+	}
+}
+
+func TestValidateTOTPAllowsOnePeriodOfDrift(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	previous, _ := TOTPCode(rfcSecret, now.Add(-30*time.Second))
+	counter, ok := ValidateTOTP(rfcSecret, previous, now)
+	if !ok || counter != now.Unix()/30-1 {
+		t.Fatalf("expected the previous code to validate, got %d %v", counter, ok)
+	}
+	stale, _ := TOTPCode(rfcSecret, now.Add(-90*time.Second))
+	if _, ok := ValidateTOTP(rfcSecret, stale, now); ok {
+		t.Fatalf("expected a code three periods old to fail")
+	}
+	if _, ok := ValidateTOTP(rfcSecret, "12345", now); ok {
+		t.Fatalf("expected a short code to fail")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Banking", "alice@example.com", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/Banking:alice@example.com?") || !strings.Contains(uri, "secret=ABC") || !strings.Contains(uri, "issuer=Banking") {
+		t.Fatalf("unexpected uri: %s", uri)
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import "testing"
+
+func TestValidateRejectsDevelopmentKeysOutsideDevelopment(t *testing.T) {
+	cfg := Config{
+		AppEnv:              "development",
+		JWTKeyEncryptionKey: defaultJWTKeyEncryptionKey,
+		TOTPEncryptionKey:   defaultTOTPEncryptionKey,
+		MailTokenKey:        defaultMailTokenKey,
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("development keys should be allowed in development: %v", err)
+	}
+	cfg.AppEnv = "production"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected an error for the default keys")
+	}
+	cfg.JWTKeyEncryptionKey = "jwt-secret"
+	cfg.TOTPEncryptionKey = "totp-secret"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected an error for the default mail token key")
+	}
+	cfg.MailTokenKey = ""
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected an error for an empty mail token key")
+	}
+	cfg.MailTokenKey = "mail-secret"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"banking/internal/store"
+)
+
+type Store interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]store.DomainEvent, error)
+	MarkDelivered(ctx context.Context, eventID string, sinks []string) error
+	MarkFailed(ctx context.Context, eventID string, sinks []string, message string, retryAt time.Time) error
+}
+
+type Sink interface {
+	Name() string
+	Deliver(ctx context.Context, event store.DomainEvent) error
+}
+
+// A retried event only goes to the sinks that have not accepted it yet.
+type Dispatcher struct {
+	store     Store
+	sinks     []Sink
+	batchSize int
+	lease     time.Duration
+	maxDelay  time.Duration
+}
+
+func NewDispatcher(store Store, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		store:     store,
+		sinks:     sinks,
+		batchSize: 100,
+		lease:     30 * time.Second,
+		maxDelay:  10 * time.Minute,
+	}
+}
+
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DispatchOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("event dispatch failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	delivered := 0
+	for {
+		batch, err := d.store.Claim(ctx, d.batchSize, d.lease)
+		if err != nil {
+			return delivered, err
+		}
+		for _, event := range batch {
+			ok, err := d.deliver(ctx, event)
+			if err != nil {
+				return delivered, err
+			}
+			if ok {
+				delivered++
+			}
+		}
+		if len(batch) < d.batchSize {
+			return delivered, nil
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, event store.DomainEvent) (bool, error) {
+	done := make(map[string]bool, len(event.DeliveredSinks))
+	for _, name := range event.DeliveredSinks {
+		done[name] = true
+	}
+	sinks := append([]string(nil), event.DeliveredSinks...)
+	var failure error
+	for _, sink := range d.sinks {
+		if done[sink.Name()] {
+			continue
+		}
+		if err := sink.Deliver(ctx, event); err != nil {
+			if failure == nil {
+				failure = err
+			}
+			continue
+		}
+		sinks = append(sinks, sink.Name())
+	}
+	if failure != nil {
+		retryAt := time.Now().Add(d.retryDelay(event.Attempts))
+		return false, d.store.MarkFailed(ctx, event.ID, sinks, failure.Error(), retryAt)
+	}
+	return true, d.store.MarkDelivered(ctx, event.ID, sinks)
+}
+
+func (d *Dispatcher) retryDelay(attempts int) time.Duration {
+	delay := time.Second
+	for i := 0; i < attempts && delay < d.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.maxDelay {
+		return d.maxDelay
+	}
+	return delay
+}
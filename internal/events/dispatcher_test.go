@@ -0,0 +1,132 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"banking/internal/store"
+)
+
+type stubStore struct {
+	claims    [][]store.DomainEvent
+	delivered map[string][]string
+	failed    map[string][]string
+	messages  map[string]string
+	retryAt   map[string]time.Time
+}
+
+func newStubStore(claims ...[]store.DomainEvent) *stubStore {
+	return &stubStore{
+		claims:    claims,
+		delivered: map[string][]string{},
+		failed:    map[string][]string{},
+		messages:  map[string]string{},
+		retryAt:   map[string]time.Time{},
+	}
+}
+
+func (s *stubStore) Claim(context.Context, int, time.Duration) ([]store.DomainEvent, error) {
+	if len(s.claims) == 0 {
+		return nil, nil
+	}
+	batch := s.claims[0]
+	s.claims = s.claims[1:]
+	return batch, nil
+}
+
+func (s *stubStore) MarkDelivered(_ context.Context, eventID string, sinks []string) error {
+	s.delivered[eventID] = sinks
+	return nil
+}
+
+func (s *stubStore) MarkFailed(_ context.Context, eventID string, sinks []string, message string, retryAt time.Time) error {
+	s.failed[eventID] = sinks
+	s.messages[eventID] = message
+	s.retryAt[eventID] = retryAt
+	return nil
+}
+
+type stubSink struct {
+	name  string
+	err   error
+	calls []string
+}
+
+func (s *stubSink) Name() string {
+	return s.name
+}
+
+func (s *stubSink) Deliver(_ context.Context, event store.DomainEvent) error {
+	s.calls = append(s.calls, event.ID)
+	return s.err
+}
+
+func TestDispatchOnceDeliversToAllSinks(t *testing.T) {
+	events := newStubStore([]store.DomainEvent{{ID: "e1", Seq: 1}, {ID: "e2", Seq: 2}})
+	first := &stubSink{name: "first"}
+	second := &stubSink{name: "second"}
+	dispatcher := NewDispatcher(events, first, second)
+
+	delivered, err := dispatcher.DispatchOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if delivered != 2 {
+		t.Fatalf("expected 2 delivered, got %d", delivered)
+	}
+	if len(first.calls) != 2 || first.calls[0] != "e1" || len(second.calls) != 2 {
+		t.Fatalf("unexpected sink calls: %v %v", first.calls, second.calls)
+	}
+	if got := events.delivered["e1"]; len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Fatalf("unexpected delivered sinks: %v", got)
+	}
+}
+
+func TestDispatchOnceRetriesOnlyFailedSinks(t *testing.T) {
+	events := newStubStore([]store.DomainEvent{{ID: "e1", Attempts: 2}})
+	ok := &stubSink{name: "ok"}
+	broken := &stubSink{name: "broken", err: errors.New("unavailable")}
+	dispatcher := NewDispatcher(events, ok, broken)
+
+	before := time.Now()
+	delivered, err := dispatcher.DispatchOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if delivered != 0 {
+		t.Fatalf("expected no delivered events, got %d", delivered)
+	}
+	if got := events.failed["e1"]; len(got) != 1 || got[0] != "ok" {
+		t.Fatalf("unexpected partial sinks: %v", got)
+	}
+	if events.messages["e1"] != "unavailable" {
+		t.Fatalf("unexpected message: %q", events.messages["e1"])
+	}
+	if delay := events.retryAt["e1"].Sub(before); delay < 4*time.Second || delay > 5*time.Second {
+		t.Fatalf("unexpected retry delay: %v", delay)
+	}
+
+	events.claims = [][]store.DomainEvent{{{ID: "e1", Attempts: 3, DeliveredSinks: []string{"ok"}}}}
+	broken.err = nil
+	if _, err := dispatcher.DispatchOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ok.calls) != 1 {
+		t.Fatalf("expected delivered sink to be skipped, got %v", ok.calls)
+	}
+	if got := events.delivered["e1"]; len(got) != 2 {
+		t.Fatalf("unexpected delivered sinks: %v", got)
+	}
+}
+
+func TestRetryDelayIsCapped(t *testing.T) {
+	dispatcher := NewDispatcher(newStubStore())
+	if got := dispatcher.retryDelay(0); got != time.Second {
+		t.Fatalf("unexpected first delay: %v", got)
+	}
+	if got := dispatcher.retryDelay(50); got != 10*time.Minute {
+		t.Fatalf("unexpected capped delay: %v", got)
+	}
+}
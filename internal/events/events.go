@@ -0,0 +1,76 @@
+package events
+
+import (
+	"encoding/json"
+
+	"banking/internal/store"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TransferCompleted          = "transfer.completed"
+	ExchangeCompleted          = "exchange.completed"
+	AdjustmentPosted           = "adjustment.posted"
+	ReversalPosted             = "reversal.posted"
+	RatePublished              = "exchange.rate_published"
+	QuoteExpiring              = "exchange.quote_expiring"
+	QuoteExpired               = "exchange.quote_expired"
+	AlertTriggered             = "alert.triggered"
+	AccountFrozen              = "account.frozen"
+	AccountUnfrozen            = "account.unfrozen"
+	UserRegistered             = "user.registered"
+	SessionRevoked             = "user.session_revoked"
+	NewDeviceLogin             = "user.new_device_login"
+	PasswordChanged            = "user.password_changed"
+	EmailVerificationRequested = "user.email_verification_requested"
+	PasswordResetRequested     = "user.password_reset_requested"
+	AdminPromoted              = "admin.promoted"
+	AdminDemoted               = "admin.demoted"
+	AdminRoleGranted           = "admin.role_granted"
+	AdminRoleRevoked           = "admin.role_revoked"
+	AdminRoleCreated           = "admin.role_created"
+	GLAccountMapped            = "admin.gl_account_mapped"
+	PeriodClosed               = "admin.period_closed"
+	PeriodReopened             = "admin.period_reopened"
+)
+
+const (
+	AggregateTransaction = "transaction"
+	AggregateUser        = "user"
+	AggregateAccount     = "account"
+	AggregatePeriod      = "accounting_period"
+	AggregateRate        = "exchange_rate"
+	AggregateQuote       = "exchange_quote"
+	AggregateAlertRule   = "alert_rule"
+	AggregateRole        = "admin_role"
+)
+
+type BalanceChange struct {
+	UserID    string `json:"user_id"`
+	AccountID string `json:"account_id"`
+	Currency  string `json:"currency"`
+	Balance   string `json:"balance"`
+}
+
+func New(eventType, aggregateType, aggregateID string, userID *string, payload any) store.DomainEventInput {
+	data, _ := json.Marshal(payload)
+	return store.DomainEventInput{
+		ID:            uuid.NewString(),
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		UserID:        userID,
+		Payload:       string(data),
+	}
+}
+
+func BalanceChanges(event store.DomainEvent) ([]BalanceChange, error) {
+	var payload struct {
+		Balances []BalanceChange `json:"balances"`
+	}
+	if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+		return nil, err
+	}
+	return payload.Balances, nil
+}
@@ -0,0 +1,220 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"banking/internal/store"
+	"banking/internal/websocket"
+
+	"github.com/shopspring/decimal"
+)
+
+type StreamStore interface {
+	Append(ctx context.Context, input store.StreamEventInput) (int64, error)
+}
+
+type HubSink struct {
+	streams     StreamStore
+	broadcaster websocket.Broadcaster
+}
+
+func NewHubSink(streams StreamStore, broadcaster websocket.Broadcaster) *HubSink {
+	return &HubSink{streams: streams, broadcaster: broadcaster}
+}
+
+func (s *HubSink) Name() string {
+	return "websocket"
+}
+
+func (s *HubSink) Deliver(ctx context.Context, event store.DomainEvent) error {
+	switch event.Type {
+	case SessionRevoked:
+		var payload struct {
+			SessionID string `json:"session_id"`
+		}
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			return err
+		}
+		return s.broadcaster.Publish(ctx, websocket.SessionRevokedMessage(event.AggregateID, payload.SessionID))
+	case RatePublished:
+		return s.publishRate(ctx, event)
+	case QuoteExpiring, QuoteExpired:
+		return s.publishQuote(ctx, event)
+	case AlertTriggered:
+		if event.UserID == nil {
+			return nil
+		}
+		return s.broadcaster.Publish(ctx, websocket.Message{
+			UserID:  *event.UserID,
+			Channel: websocket.ChannelAlerts,
+			Event:   websocket.EventAlertTriggered,
+			Data:    json.RawMessage(event.Payload),
+		})
+	}
+	changes, err := BalanceChanges(event)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if change.UserID == "" {
+			continue
+		}
+		update := websocket.BalanceUpdate{
+			AccountID: change.AccountID,
+			Balance:   change.Balance,
+			Currency:  change.Currency,
+		}
+		payload, err := json.Marshal(update)
+		if err != nil {
+			return err
+		}
+		update.Seq, err = s.streams.Append(ctx, store.StreamEventInput{
+			UserID:    change.UserID,
+			EventID:   event.ID,
+			AccountID: change.AccountID,
+			Payload:   string(payload),
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.broadcaster.Publish(ctx, websocket.BalanceMessage(change.UserID, update)); err != nil {
+			return err
+		}
+	}
+	if !transactionEvents[event.Type] {
+		return nil
+	}
+	data, err := transactionData(event.Payload)
+	if err != nil {
+		return err
+	}
+	published := make(map[string]bool)
+	for _, change := range changes {
+		if change.UserID == "" || published[change.UserID] {
+			continue
+		}
+		published[change.UserID] = true
+		err := s.broadcaster.Publish(ctx, websocket.Message{
+			UserID:  change.UserID,
+			Channel: websocket.ChannelTransactions,
+			Event:   event.Type,
+			Data:    data,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if event.Type == ExchangeCompleted {
+		return s.publishQuoteConsumed(ctx, event)
+	}
+	return nil
+}
+
+type rateTick struct {
+	BaseCurrency  string    `json:"base_currency"`
+	QuoteCurrency string    `json:"quote_currency"`
+	Rate          string    `json:"rate"`
+	PublishedAt   time.Time `json:"published_at"`
+}
+
+func (s *HubSink) publishRate(ctx context.Context, event store.DomainEvent) error {
+	var payload struct {
+		BaseCurrency  string `json:"base_currency"`
+		QuoteCurrency string `json:"quote_currency"`
+		Rate          string `json:"rate"`
+	}
+	if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+		return err
+	}
+	rate, err := decimal.NewFromString(payload.Rate)
+	if err != nil {
+		return err
+	}
+	ticks := []rateTick{
+		{BaseCurrency: payload.BaseCurrency, QuoteCurrency: payload.QuoteCurrency, Rate: rate.StringFixedBank(6), PublishedAt: event.CreatedAt},
+		{BaseCurrency: payload.QuoteCurrency, QuoteCurrency: payload.BaseCurrency, Rate: decimal.NewFromInt(1).Div(rate).StringFixedBank(6), PublishedAt: event.CreatedAt},
+	}
+	for _, tick := range ticks {
+		data, err := json.Marshal(tick)
+		if err != nil {
+			return err
+		}
+		err = s.broadcaster.Publish(ctx, websocket.Message{
+			Channel: websocket.ChannelRatesPrefix + tick.BaseCurrency + "-" + tick.QuoteCurrency,
+			Event:   websocket.EventRateUpdated,
+			Data:    data,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var quoteEvents = map[string]string{
+	QuoteExpiring: websocket.EventQuoteExpiring,
+	QuoteExpired:  websocket.EventQuoteExpired,
+}
+
+func (s *HubSink) publishQuote(ctx context.Context, event store.DomainEvent) error {
+	var payload struct {
+		QuoteID string `json:"quote_id"`
+	}
+	if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+		return err
+	}
+	if event.UserID == nil || payload.QuoteID == "" {
+		return nil
+	}
+	return s.broadcaster.Publish(ctx, websocket.Message{
+		UserID:  *event.UserID,
+		Channel: websocket.ChannelQuotesPrefix + payload.QuoteID,
+		Event:   quoteEvents[event.Type],
+		Data:    json.RawMessage(event.Payload),
+	})
+}
+
+func (s *HubSink) publishQuoteConsumed(ctx context.Context, event store.DomainEvent) error {
+	var payload struct {
+		QuoteID       string `json:"quote_id"`
+		TransactionID string `json:"transaction_id"`
+	}
+	if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+		return err
+	}
+	if event.UserID == nil || payload.QuoteID == "" {
+		return nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return s.broadcaster.Publish(ctx, websocket.Message{
+		UserID:  *event.UserID,
+		Channel: websocket.ChannelQuotesPrefix + payload.QuoteID,
+		Event:   websocket.EventQuoteConsumed,
+		Data:    data,
+	})
+}
+
+var transactionEvents = map[string]bool{
+	TransferCompleted: true,
+	ExchangeCompleted: true,
+	AdjustmentPosted:  true,
+	ReversalPosted:    true,
+}
+
+// transactionData is the event payload without the fields one party should
+// not see about the other: their balances, user id and the acting admin.
+func transactionData(payload string) (json.RawMessage, error) {
+	var data map[string]any
+	if err := json.Unmarshal([]byte(payload), &data); err != nil {
+		return nil, err
+	}
+	delete(data, "balances")
+	delete(data, "to_user_id")
+	delete(data, "actor_id")
+	return json.Marshal(data)
+}
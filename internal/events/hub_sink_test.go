@@ -0,0 +1,217 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"banking/internal/store"
+	"banking/internal/websocket"
+)
+
+type recordingHub struct {
+	users    []string
+	updates  []websocket.BalanceUpdate
+	messages []websocket.Message
+	err      error
+}
+
+func (h *recordingHub) Publish(_ context.Context, message websocket.Message) error {
+	if h.err != nil {
+		return h.err
+	}
+	if message.Channel != websocket.ChannelBalances {
+		h.messages = append(h.messages, message)
+		return nil
+	}
+	var update websocket.BalanceUpdate
+	if err := json.Unmarshal(message.Data, &update); err != nil {
+		return err
+	}
+	update.Seq = message.Seq
+	h.users = append(h.users, message.UserID)
+	h.updates = append(h.updates, update)
+	return nil
+}
+
+type recordingStreams struct {
+	inputs []store.StreamEventInput
+	err    error
+}
+
+func (s *recordingStreams) Append(_ context.Context, input store.StreamEventInput) (int64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	s.inputs = append(s.inputs, input)
+	return int64(len(s.inputs)) + 10, nil
+}
+
+func TestHubSinkBroadcastsBalances(t *testing.T) {
+	hub := &recordingHub{}
+	streams := &recordingStreams{}
+	event := New(TransferCompleted, AggregateTransaction, "tx-1", nil, map[string]any{
+		"balances": []BalanceChange{
+			{UserID: "user-1", AccountID: "acc-1", Currency: "USD", Balance: "90.00"},
+			{AccountID: "system", Currency: "USD", Balance: "10.00"},
+			{UserID: "user-2", AccountID: "acc-2", Currency: "USD", Balance: "110.00"},
+		},
+	})
+	err := NewHubSink(streams, hub).Deliver(context.Background(), store.DomainEvent{ID: event.ID, Payload: event.Payload})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hub.users) != 2 || hub.users[0] != "user-1" || hub.users[1] != "user-2" {
+		t.Fatalf("unexpected broadcasts: %v", hub.users)
+	}
+	if hub.updates[1].Balance != "110.00" || hub.updates[1].AccountID != "acc-2" || hub.updates[1].Seq != 12 {
+		t.Fatalf("unexpected update: %#v", hub.updates[1])
+	}
+	if len(streams.inputs) != 2 || streams.inputs[0].EventID != event.ID || streams.inputs[0].Payload != `{"account_id":"acc-1","balance":"90.00","currency":"USD"}` {
+		t.Fatalf("unexpected stream entries: %#v", streams.inputs)
+	}
+}
+
+func TestHubSinkIgnoresEventsWithoutBalances(t *testing.T) {
+	hub := &recordingHub{}
+	event := New(UserRegistered, AggregateUser, "user-1", nil, map[string]string{"username": "alice"})
+	if err := NewHubSink(&recordingStreams{}, hub).Deliver(context.Background(), store.DomainEvent{Payload: event.Payload}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hub.users) != 0 {
+		t.Fatalf("expected no broadcasts, got %v", hub.users)
+	}
+}
+
+func TestHubSinkDisconnectsRevokedSessions(t *testing.T) {
+	hub := &recordingHub{}
+	event := New(SessionRevoked, AggregateUser, "user-1", nil, map[string]string{"session_id": "session-1"})
+	err := NewHubSink(&recordingStreams{}, hub).Deliver(context.Background(), store.DomainEvent{Type: event.Type, AggregateID: event.AggregateID, Payload: event.Payload})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hub.messages) != 1 {
+		t.Fatalf("expected one message, got %d", len(hub.messages))
+	}
+	message := hub.messages[0]
+	if message.UserID != "user-1" || message.Channel != websocket.ChannelControl || message.Event != websocket.EventSessionRevoked || string(message.Data) != `{"session_id":"session-1"}` {
+		t.Fatalf("unexpected message: %#v %s", message, message.Data)
+	}
+}
+
+func TestHubSinkReturnsErrors(t *testing.T) {
+	event := New(TransferCompleted, AggregateTransaction, "tx-1", nil, map[string]any{
+		"balances": []BalanceChange{{UserID: "user-1", AccountID: "acc-1", Currency: "USD", Balance: "90.00"}},
+	})
+	hub := &recordingHub{}
+	if err := NewHubSink(&recordingStreams{err: errors.New("db down")}, hub).Deliver(context.Background(), store.DomainEvent{Payload: event.Payload}); err == nil {
+		t.Fatalf("expected stream error")
+	}
+	if len(hub.users) != 0 {
+		t.Fatalf("expected nothing published without a seq, got %v", hub.users)
+	}
+	if err := NewHubSink(&recordingStreams{}, &recordingHub{err: errors.New("notify failed")}).Deliver(context.Background(), store.DomainEvent{Payload: event.Payload}); err == nil {
+		t.Fatalf("expected publish error")
+	}
+}
+
+func TestHubSinkPublishesTransactions(t *testing.T) {
+	hub := &recordingHub{}
+	event := New(TransferCompleted, AggregateTransaction, "tx-1", nil, map[string]any{
+		"transaction_id": "tx-1",
+		"to_user_id":     "user-2",
+		"amount":         "10.00",
+		"balances": []BalanceChange{
+			{UserID: "user-1", AccountID: "acc-1", Currency: "USD", Balance: "90.00"},
+			{UserID: "user-2", AccountID: "acc-2", Currency: "USD", Balance: "110.00"},
+		},
+	})
+	err := NewHubSink(&recordingStreams{}, hub).Deliver(context.Background(), store.DomainEvent{ID: event.ID, Type: TransferCompleted, Payload: event.Payload})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hub.messages) != 2 || hub.messages[0].UserID != "user-1" || hub.messages[1].UserID != "user-2" {
+		t.Fatalf("unexpected messages: %#v", hub.messages)
+	}
+	message := hub.messages[1]
+	if message.Channel != websocket.ChannelTransactions || message.Event != TransferCompleted || message.Seq != 0 {
+		t.Fatalf("unexpected message: %#v", message)
+	}
+	if string(message.Data) != `{"amount":"10.00","transaction_id":"tx-1"}` {
+		t.Fatalf("unexpected data: %s", message.Data)
+	}
+}
+
+func TestHubSinkPublishesRateTicks(t *testing.T) {
+	hub := &recordingHub{}
+	event := New(RatePublished, AggregateRate, "rate-1", nil, map[string]string{
+		"rate_id":        "rate-1",
+		"base_currency":  "USD",
+		"quote_currency": "EUR",
+		"rate":           "0.8",
+	})
+	publishedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	err := NewHubSink(&recordingStreams{}, hub).Deliver(context.Background(), store.DomainEvent{Type: event.Type, Payload: event.Payload, CreatedAt: publishedAt})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hub.messages) != 2 {
+		t.Fatalf("expected 2 ticks, got %#v", hub.messages)
+	}
+	direct, inverse := hub.messages[0], hub.messages[1]
+	if direct.UserID != "" || direct.Channel != "rates:USD-EUR" || direct.Event != websocket.EventRateUpdated {
+		t.Fatalf("unexpected tick: %#v", direct)
+	}
+	if string(direct.Data) != `{"base_currency":"USD","quote_currency":"EUR","rate":"0.800000","published_at":"2024-05-01T12:00:00Z"}` {
+		t.Fatalf("unexpected data: %s", direct.Data)
+	}
+	if inverse.Channel != "rates:EUR-USD" || string(inverse.Data) != `{"base_currency":"EUR","quote_currency":"USD","rate":"1.250000","published_at":"2024-05-01T12:00:00Z"}` {
+		t.Fatalf("unexpected inverse tick: %#v %s", inverse, inverse.Data)
+	}
+}
+
+func TestHubSinkPublishesQuoteLifecycle(t *testing.T) {
+	hub := &recordingHub{}
+	sink := NewHubSink(&recordingStreams{}, hub)
+	userID := "user-1"
+	expiring := New(QuoteExpiring, AggregateQuote, "quote-1", &userID, map[string]string{"quote_id": "quote-1"})
+	if err := sink.Deliver(context.Background(), store.DomainEvent{Type: expiring.Type, UserID: &userID, Payload: expiring.Payload}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exchanged := New(ExchangeCompleted, AggregateTransaction, "tx-1", &userID, map[string]any{
+		"transaction_id": "tx-1",
+		"quote_id":       "quote-1",
+		"balances":       []BalanceChange{{UserID: "user-1", AccountID: "acc-1", Currency: "USD", Balance: "90.00"}},
+	})
+	if err := sink.Deliver(context.Background(), store.DomainEvent{ID: exchanged.ID, Type: exchanged.Type, UserID: &userID, Payload: exchanged.Payload}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hub.messages) != 3 {
+		t.Fatalf("unexpected messages: %#v", hub.messages)
+	}
+	if message := hub.messages[0]; message.UserID != "user-1" || message.Channel != "quotes:quote-1" || message.Event != websocket.EventQuoteExpiring {
+		t.Fatalf("unexpected expiring message: %#v", message)
+	}
+	consumed := hub.messages[2]
+	if consumed.UserID != "user-1" || consumed.Channel != "quotes:quote-1" || consumed.Event != websocket.EventQuoteConsumed || string(consumed.Data) != `{"quote_id":"quote-1","transaction_id":"tx-1"}` {
+		t.Fatalf("unexpected consumed message: %#v %s", consumed, consumed.Data)
+	}
+}
+
+func TestHubSinkPublishesAlerts(t *testing.T) {
+	hub := &recordingHub{}
+	streams := &recordingStreams{}
+	userID := "user-1"
+	alert := New(AlertTriggered, AggregateAlertRule, "rule-1", &userID, map[string]string{"rule_id": "rule-1", "rule_kind": "low_balance"})
+	if err := NewHubSink(streams, hub).Deliver(context.Background(), store.DomainEvent{Type: alert.Type, UserID: &userID, Payload: alert.Payload}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(streams.inputs) != 0 || len(hub.messages) != 1 {
+		t.Fatalf("unexpected publishes: %#v %#v", streams.inputs, hub.messages)
+	}
+	if message := hub.messages[0]; message.UserID != "user-1" || message.Channel != websocket.ChannelAlerts || message.Event != websocket.EventAlertTriggered || string(message.Data) != alert.Payload {
+		t.Fatalf("unexpected alert message: %#v", message)
+	}
+}
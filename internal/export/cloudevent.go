@@ -0,0 +1,68 @@
+package export
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"banking/internal/events"
+	"banking/internal/store"
+)
+
+const (
+	SpecVersion = "1.0"
+	TypePrefix  = "banking."
+)
+
+var Types = []string{
+	events.TransferCompleted,
+	events.ExchangeCompleted,
+	events.AdjustmentPosted,
+	events.ReversalPosted,
+	events.UserRegistered,
+	events.AccountFrozen,
+	events.AccountUnfrozen,
+	events.AdminPromoted,
+	events.AdminDemoted,
+	events.AdminRoleGranted,
+	events.AdminRoleRevoked,
+	events.AdminRoleCreated,
+	events.GLAccountMapped,
+	events.PeriodClosed,
+	events.PeriodReopened,
+}
+
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Sequence        string          `json:"sequence"`
+	Data            json.RawMessage `json:"data"`
+}
+
+func Exported(eventType string) bool {
+	for _, candidate := range Types {
+		if candidate == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func FromDomainEvent(event store.DomainEvent, source string) CloudEvent {
+	return CloudEvent{
+		SpecVersion:     SpecVersion,
+		ID:              event.ID,
+		Source:          source,
+		Type:            TypePrefix + event.Type,
+		Subject:         event.AggregateType + "/" + event.AggregateID,
+		Time:            event.CreatedAt.UTC(),
+		DataContentType: "application/json",
+		Sequence:        strconv.FormatInt(event.Seq, 10),
+		Data:            json.RawMessage(event.Payload),
+	}
+}
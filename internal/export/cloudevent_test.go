@@ -0,0 +1,63 @@
+package export
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"banking/internal/events"
+	"banking/internal/store"
+)
+
+func TestFromDomainEvent(t *testing.T) {
+	createdAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	event := FromDomainEvent(store.DomainEvent{
+		ID:            "event-1",
+		Seq:           42,
+		Type:          events.TransferCompleted,
+		AggregateType: events.AggregateTransaction,
+		AggregateID:   "tx-1",
+		Payload:       `{"amount":"10.00"}`,
+		CreatedAt:     createdAt,
+	}, "/banking")
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]any{
+		"specversion":     "1.0",
+		"id":              "event-1",
+		"source":          "/banking",
+		"type":            "banking.transfer.completed",
+		"subject":         "transaction/tx-1",
+		"time":            "2025-03-01T11:00:00Z",
+		"datacontenttype": "application/json",
+		"sequence":        "42",
+	}
+	for key, value := range want {
+		if decoded[key] != value {
+			t.Fatalf("expected %s=%v, got %v", key, value, decoded[key])
+		}
+	}
+	payload, ok := decoded["data"].(map[string]any)
+	if !ok || payload["amount"] != "10.00" {
+		t.Fatalf("expected payload as data, got %#v", decoded["data"])
+	}
+}
+
+func TestExported(t *testing.T) {
+	for _, eventType := range []string{events.TransferCompleted, events.ExchangeCompleted, events.AdjustmentPosted, events.UserRegistered, events.PeriodClosed} {
+		if !Exported(eventType) {
+			t.Fatalf("expected %s to be exported", eventType)
+		}
+	}
+	for _, eventType := range []string{events.RatePublished, events.QuoteExpiring, events.AlertTriggered, events.PasswordChanged} {
+		if Exported(eventType) {
+			t.Fatalf("expected %s not to be exported", eventType)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package export
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"banking/internal/store"
+)
+
+type EventStore interface {
+	Since(ctx context.Context, after store.ExportPosition, limit int) ([]store.DomainEvent, error)
+}
+
+type CheckpointStore interface {
+	Claim(ctx context.Context, name string, lease time.Duration) (store.ExportPosition, bool, error)
+	Advance(ctx context.Context, name string, position store.ExportPosition, lease time.Duration) error
+	Release(ctx context.Context, name string) error
+}
+
+type Writer interface {
+	Write(ctx context.Context, batch []CloudEvent) error
+	Close() error
+}
+
+// The checkpoint only moves after a batch is written, so delivery is at least
+// once: a crash between the two exports the batch again.
+type Exporter struct {
+	name        string
+	source      string
+	events      EventStore
+	checkpoints CheckpointStore
+	writer      Writer
+	batchSize   int
+	lease       time.Duration
+}
+
+func NewExporter(name, source string, events EventStore, checkpoints CheckpointStore, writer Writer) *Exporter {
+	return &Exporter{
+		name:        name,
+		source:      source,
+		events:      events,
+		checkpoints: checkpoints,
+		writer:      writer,
+		batchSize:   500,
+		lease:       time.Minute,
+	}
+}
+
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer func() {
+		if err := e.writer.Close(); err != nil {
+			log.Printf("event export close failed: %v", err)
+		}
+	}()
+	for {
+		if _, err := e.ExportOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("event export failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Exporter) ExportOnce(ctx context.Context) (int, error) {
+	after, ok, err := e.checkpoints.Claim(ctx, e.name, e.lease)
+	if err != nil || !ok {
+		return 0, err
+	}
+	defer func() {
+		if err := e.checkpoints.Release(context.Background(), e.name); err != nil {
+			log.Printf("event export release failed: %v", err)
+		}
+	}()
+	exported := 0
+	for {
+		batch, err := e.events.Since(ctx, after, e.batchSize)
+		if err != nil {
+			return exported, err
+		}
+		cloudEvents := make([]CloudEvent, 0, len(batch))
+		for _, event := range batch {
+			if Exported(event.Type) {
+				cloudEvents = append(cloudEvents, FromDomainEvent(event, e.source))
+			}
+		}
+		if err := e.writer.Write(ctx, cloudEvents); err != nil {
+			return exported, err
+		}
+		exported += len(cloudEvents)
+		if len(batch) > 0 {
+			last := batch[len(batch)-1]
+			after = store.ExportPosition{TxID: last.TxID, Seq: last.Seq}
+			if err := e.checkpoints.Advance(ctx, e.name, after, e.lease); err != nil {
+				return exported, err
+			}
+		}
+		if len(batch) < e.batchSize {
+			return exported, nil
+		}
+	}
+}
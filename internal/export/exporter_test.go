@@ -0,0 +1,119 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"banking/internal/events"
+	"banking/internal/store"
+)
+
+type stubEventStore struct {
+	events []store.DomainEvent
+}
+
+func (s stubEventStore) Since(_ context.Context, after store.ExportPosition, limit int) ([]store.DomainEvent, error) {
+	var rows []store.DomainEvent
+	for _, event := range s.events {
+		later := event.TxID > after.TxID || (event.TxID == after.TxID && event.Seq > after.Seq)
+		if later && len(rows) < limit {
+			rows = append(rows, event)
+		}
+	}
+	return rows, nil
+}
+
+type stubCheckpointStore struct {
+	last     store.ExportPosition
+	leased   bool
+	released bool
+}
+
+func (s *stubCheckpointStore) Claim(context.Context, string, time.Duration) (store.ExportPosition, bool, error) {
+	return s.last, !s.leased, nil
+}
+
+func (s *stubCheckpointStore) Advance(_ context.Context, _ string, position store.ExportPosition, _ time.Duration) error {
+	s.last = position
+	return nil
+}
+
+func (s *stubCheckpointStore) Release(context.Context, string) error {
+	s.released = true
+	return nil
+}
+
+type stubWriter struct {
+	written []CloudEvent
+	calls   int
+	err     error
+}
+
+func (w *stubWriter) Write(_ context.Context, batch []CloudEvent) error {
+	w.calls++
+	if w.err != nil {
+		return w.err
+	}
+	w.written = append(w.written, batch...)
+	return nil
+}
+
+func (w *stubWriter) Close() error {
+	return nil
+}
+
+func testEvents() []store.DomainEvent {
+	return []store.DomainEvent{
+		{ID: "e1", TxID: 10, Seq: 1, Type: events.UserRegistered, Payload: `{}`},
+		{ID: "e2", TxID: 10, Seq: 2, Type: events.RatePublished, Payload: `{}`},
+		{ID: "e3", TxID: 11, Seq: 5, Type: events.TransferCompleted, Payload: `{}`},
+		{ID: "e4", TxID: 12, Seq: 3, Type: events.AlertTriggered, Payload: `{}`},
+	}
+}
+
+func TestExporterResumesFromCheckpoint(t *testing.T) {
+	checkpoints := &stubCheckpointStore{last: store.ExportPosition{TxID: 10, Seq: 1}}
+	writer := &stubWriter{}
+	exporter := NewExporter("cloudevents", "/banking", stubEventStore{events: testEvents()}, checkpoints, writer)
+	exporter.batchSize = 2
+
+	exported, err := exporter.ExportOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exported != 1 || len(writer.written) != 1 || writer.written[0].ID != "e3" {
+		t.Fatalf("expected only the transfer after the checkpoint, got %d %#v", exported, writer.written)
+	}
+	if checkpoints.last != (store.ExportPosition{TxID: 12, Seq: 3}) {
+		t.Fatalf("expected checkpoint past skipped types, got %#v", checkpoints.last)
+	}
+	if !checkpoints.released {
+		t.Fatalf("expected the lease to be released")
+	}
+}
+
+func TestExporterKeepsCheckpointOnWriteFailure(t *testing.T) {
+	checkpoints := &stubCheckpointStore{}
+	writer := &stubWriter{err: errors.New("collector down")}
+	exporter := NewExporter("cloudevents", "/banking", stubEventStore{events: testEvents()}, checkpoints, writer)
+
+	if _, err := exporter.ExportOnce(context.Background()); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if checkpoints.last != (store.ExportPosition{}) {
+		t.Fatalf("expected checkpoint to stay, got %#v", checkpoints.last)
+	}
+}
+
+func TestExporterSkipsWhileLeased(t *testing.T) {
+	checkpoints := &stubCheckpointStore{leased: true}
+	writer := &stubWriter{}
+	exporter := NewExporter("cloudevents", "/banking", stubEventStore{events: testEvents()}, checkpoints, writer)
+
+	exported, err := exporter.ExportOnce(context.Background())
+	if err != nil || exported != 0 || writer.calls != 0 || checkpoints.released {
+		t.Fatalf("expected nothing to happen, got %d %v %d", exported, err, writer.calls)
+	}
+}
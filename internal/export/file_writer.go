@@ -0,0 +1,124 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const openSuffix = ".open"
+
+// The file being written ends in ".open" and is renamed to ".ndjson" when
+// rotated, so readers only pick up finished files.
+type FileWriter struct {
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+	now      func() time.Time
+
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// A partly written last line left by a previous run is dropped; those events
+// are after the checkpoint and are exported again.
+func NewFileWriter(dir string, maxBytes int64, maxAge time.Duration) (*FileWriter, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	leftovers, err := filepath.Glob(filepath.Join(dir, "*"+openSuffix))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range leftovers {
+		if err := finishFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return &FileWriter{dir: dir, maxBytes: maxBytes, maxAge: maxAge, now: time.Now}, nil
+}
+
+func (w *FileWriter) Write(_ context.Context, batch []CloudEvent) error {
+	if w.file != nil && w.now().Sub(w.openedAt) >= w.maxAge {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	for _, event := range batch {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+		if w.file == nil {
+			if err := w.open(); err != nil {
+				return err
+			}
+		}
+		n, err := w.file.Write(line)
+		w.size += int64(n)
+		if err != nil {
+			return err
+		}
+		if w.size >= w.maxBytes {
+			if err := w.rotate(); err != nil {
+				return err
+			}
+		}
+	}
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+func (w *FileWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.rotate()
+}
+
+func (w *FileWriter) open() error {
+	now := w.now().UTC()
+	name := fmt.Sprintf("events-%s.ndjson%s", now.Format("20060102T150405.000000000Z"), openSuffix)
+	file, err := os.OpenFile(filepath.Join(w.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.size = 0
+	w.openedAt = now
+	return nil
+}
+
+func (w *FileWriter) rotate() error {
+	path := w.file.Name()
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	return os.Rename(path, strings.TrimSuffix(path, openSuffix))
+}
+
+func finishFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if end := bytes.LastIndexByte(data, '\n') + 1; end < len(data) {
+		if err := os.Truncate(path, int64(end)); err != nil {
+			return err
+		}
+	}
+	return os.Rename(path, strings.TrimSuffix(path, openSuffix))
+}
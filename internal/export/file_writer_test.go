@@ -0,0 +1,102 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileWriterRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := NewFileWriter(dir, 1, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	writer.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	if err := writer.Write(context.Background(), []CloudEvent{{ID: "a"}, {ID: "b"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	finished := readFinished(t, dir)
+	if len(finished) != 2 || finished[0][0] != "a" || finished[1][0] != "b" {
+		t.Fatalf("expected one event per file, got %v", finished)
+	}
+}
+
+func TestFileWriterRotatesByAge(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := NewFileWriter(dir, 1<<20, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	writer.now = func() time.Time { return now }
+	if err := writer.Write(context.Background(), []CloudEvent{{ID: "a"}, {ID: "b"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if finished := readFinished(t, dir); len(finished) != 0 {
+		t.Fatalf("expected the file to stay open, got %v", finished)
+	}
+	now = now.Add(time.Minute)
+	if err := writer.Write(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	finished := readFinished(t, dir)
+	if len(finished) != 1 || strings.Join(finished[0], ",") != "a,b" {
+		t.Fatalf("expected the idle file to rotate, got %v", finished)
+	}
+	if open, _ := filepath.Glob(filepath.Join(dir, "*"+openSuffix)); len(open) != 0 {
+		t.Fatalf("expected no open file, got %v", open)
+	}
+}
+
+func TestNewFileWriterFinishesLeftovers(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events-old.ndjson"+openSuffix)
+	if err := os.WriteFile(path, []byte(`{"id":"a"}`+"\n"+`{"id":"b`), 0o640); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := NewFileWriter(dir, 1<<20, time.Hour); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	finished := readFinished(t, dir)
+	if len(finished) != 1 || strings.Join(finished[0], ",") != "a" {
+		t.Fatalf("expected the partial line to be dropped, got %v", finished)
+	}
+}
+
+// readFinished returns the event ids in each finished file, oldest first.
+func readFinished(t *testing.T, dir string) [][]string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.ndjson"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var files [][]string
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var ids []string
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var event CloudEvent
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+			}
+			ids = append(ids, event.ID)
+		}
+		file.Close()
+		files = append(files, ids)
+	}
+	return files
+}
@@ -0,0 +1,50 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const batchContentType = "application/cloudevents-batch+json"
+
+type HTTPWriter struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPWriter(url string, client *http.Client) *HTTPWriter {
+	return &HTTPWriter{url: url, client: client}
+}
+
+func (w *HTTPWriter) Write(ctx context.Context, batch []CloudEvent) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", batchContentType)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector responded %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (w *HTTPWriter) Close() error {
+	return nil
+}
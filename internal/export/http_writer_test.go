@@ -0,0 +1,50 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPWriterPostsBatch(t *testing.T) {
+	var received []CloudEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != batchContentType {
+			t.Fatalf("unexpected content type: %s", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("invalid body: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	writer := NewHTTPWriter(server.URL, server.Client())
+	if err := writer.Write(context.Background(), []CloudEvent{{ID: "a"}, {ID: "b"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(received) != 2 || received[0].ID != "a" || received[1].ID != "b" {
+		t.Fatalf("unexpected batch: %#v", received)
+	}
+}
+
+func TestHTTPWriterFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	writer := NewHTTPWriter(server.URL, server.Client())
+	if err := writer.Write(context.Background(), []CloudEvent{{ID: "a"}}); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+func TestHTTPWriterSkipsEmptyBatch(t *testing.T) {
+	writer := NewHTTPWriter("http://127.0.0.1:0", http.DefaultClient)
+	if err := writer.Write(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
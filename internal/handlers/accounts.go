@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
+	"time"
 
+	"banking/internal/auth"
 	"banking/internal/middleware"
+	"banking/internal/store"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -51,15 +55,69 @@ func (h *Handler) GetBalance(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusNotFound, "account not found")
 		return
 	}
+	asOfRaw := r.URL.Query().Get("as_of")
 	if account.UserID == nil || *account.UserID != userID {
-		respondError(w, http.StatusForbidden, "access denied")
+		allowed := false
+		if asOfRaw != "" {
+			allowed, err = h.canViewAllAccounts(r.Context(), userID)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "unable to verify admin")
+				return
+			}
+		}
+		if !allowed {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+	}
+	if asOfRaw == "" {
+		respondJSON(w, http.StatusOK, map[string]any{
+			"account_id": accountID,
+			"balance":    valueToMoney(account.Balance),
+			"currency":   account.Currency,
+		})
+		return
+	}
+	asOf, err := parseAsOf(asOfRaw)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid as_of")
+		return
+	}
+	balance, err := h.balances.BalanceAsOf(r.Context(), accountID, asOf)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load balance")
 		return
 	}
-	respondJSON(w, http.StatusOK, map[string]any{
-		"account_id": accountID,
-		"balance":    valueToMoney(account.Balance),
-		"currency":   account.Currency,
-	})
+	respondJSON(w, http.StatusOK, balanceAsOfResponse(balance, asOf))
+}
+
+func (h *Handler) canViewAllAccounts(ctx context.Context, userID string) (bool, error) {
+	access, err := h.adminAccess.Access(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return access.Can(auth.PermissionViewTransactions), nil
+}
+
+func balanceAsOfResponse(balance store.BalanceAsOf, asOf time.Time) map[string]any {
+	accountUserID := ""
+	if balance.UserID != nil {
+		accountUserID = *balance.UserID
+	}
+	lastEntryID := ""
+	if balance.LastEntryID != nil {
+		lastEntryID = *balance.LastEntryID
+	}
+	return map[string]any{
+		"account_id":     balance.AccountID,
+		"user_id":        accountUserID,
+		"currency":       balance.Currency,
+		"is_system":      balance.IsSystem,
+		"as_of":          asOf,
+		"booked_balance": valueToMoney(balance.Balance),
+		"last_entry_id":  lastEntryID,
+		"last_entry_seq": balance.LastEntrySeq,
+	}
 }
 
 func (h *Handler) SelfCheck(w http.ResponseWriter, r *http.Request) {
@@ -79,12 +137,22 @@ func (h *Handler) SelfCheck(w http.ResponseWriter, r *http.Request) {
 		SELECT a.id AS account_id,
 		       a.currency,
 		       a.balance AS account_balance,
-		       COALESCE(SUM(l.amount), 0) AS ledger_sum,
-		       (a.balance - COALESCE(SUM(l.amount), 0)) AS difference
+		       COALESCE(c.balance, 0) + d.amount AS ledger_sum,
+		       (a.balance - COALESCE(c.balance, 0) - d.amount) AS difference
 		FROM accounts a
-		LEFT JOIN ledger_entries l ON l.account_id = a.id
+		LEFT JOIN LATERAL (
+			SELECT entry_seq, balance
+			FROM balance_checkpoints
+			WHERE account_id = a.id
+			ORDER BY entry_seq DESC
+			LIMIT 1
+		) c ON TRUE
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(SUM(l.amount), 0) AS amount
+			FROM ledger_entries l
+			WHERE l.account_id = a.id AND l.seq > COALESCE(c.entry_seq, 0)
+		) d
 		WHERE a.user_id = $1
-		GROUP BY a.id, a.currency, a.balance
 		ORDER BY a.currency
 	`
 	var rows []row
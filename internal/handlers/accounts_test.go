@@ -38,20 +38,20 @@ func TestListAccounts(t *testing.T) {
 	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return false, false, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
 		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
 	}, stubService{})
 
-	token, err := auth.GenerateToken("secret", "user-1", time.Minute)
+	token, err := auth.GenerateToken(testKeys, "user-1", time.Minute)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
 	req := httptest.NewRequest(http.MethodGet, "/accounts", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
-	middleware.Auth("secret")(http.HandlerFunc(handler.ListAccounts)).ServeHTTP(rr, req)
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.ListAccounts)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", rr.Code)
 	}
@@ -77,13 +77,13 @@ func TestGetBalanceForbidden(t *testing.T) {
 	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return false, false, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
 		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
 	}, stubService{})
 
-	token, err := auth.GenerateToken("secret", "user-1", time.Minute)
+	token, err := auth.GenerateToken(testKeys, "user-1", time.Minute)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
@@ -93,7 +93,7 @@ func TestGetBalanceForbidden(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
-	middleware.Auth("secret")(http.HandlerFunc(handler.GetBalance)).ServeHTTP(rr, req)
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.GetBalance)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusForbidden {
 		t.Fatalf("expected 403, got %d", rr.Code)
 	}
@@ -124,21 +124,112 @@ func TestSelfCheck(t *testing.T) {
 	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return false, false, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
 		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
 	}, stubService{})
 
-	token, err := auth.GenerateToken("secret", "user-1", time.Minute)
+	token, err := auth.GenerateToken(testKeys, "user-1", time.Minute)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
 	req := httptest.NewRequest(http.MethodGet, "/accounts/self-check", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
-	middleware.Auth("secret")(http.HandlerFunc(handler.SelfCheck)).ServeHTTP(rr, req)
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.SelfCheck)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", rr.Code)
 	}
 }
+
+func serveBalanceAsOf(t *testing.T, handler *Handler, userID, accountID, asOf string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := auth.GenerateToken(testKeys, userID, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/accounts/"+accountID+"/balance?as_of="+asOf, nil)
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("id", accountID)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.GetBalance)).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestGetBalanceAsOfOwner(t *testing.T) {
+	var requestedAsOf time.Time
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{
+		getByIDFn: func(context.Context, string) (store.Account, error) {
+			return store.Account{ID: "acc-1", UserID: stringPtr("user-1"), Currency: "USD", Balance: 9000}, nil
+		},
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.balances = stubBalanceStore{
+		balanceAsOfFn: func(_ context.Context, accountID string, asOf time.Time) (store.BalanceAsOf, error) {
+			requestedAsOf = asOf
+			return store.BalanceAsOf{AccountID: accountID, Currency: "USD", Balance: 12345, LastEntryID: stringPtr("entry-7"), LastEntrySeq: 7}, nil
+		},
+	}
+
+	rr := serveBalanceAsOf(t, handler, "user-1", "acc-1", "2025-03-31")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	expected := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC).Add(-time.Microsecond)
+	if !requestedAsOf.Equal(expected) {
+		t.Fatalf("expected end of day cut-off, got %v", requestedAsOf)
+	}
+	var payload map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if payload["booked_balance"] != "123.45" || payload["last_entry_id"] != "entry-7" {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+}
+
+func TestGetBalanceAsOfAdmin(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{
+		getByIDFn: func(context.Context, string) (store.Account, error) {
+			return store.Account{ID: "acc-1", UserID: stringPtr("other"), Currency: "USD"}, nil
+		},
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
+		isAdminFn: func(context.Context, string) (bool, bool, error) { return true, false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) {
+			return []string{auth.PermissionViewTransactions}, nil
+		},
+	}, stubAuditStore{}, stubService{})
+
+	rr := serveBalanceAsOf(t, handler, "admin-1", "acc-1", "2025-03-31T12:00:00Z")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+}
+
+func TestGetBalanceAsOfForbiddenForOtherUsers(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{
+		getByIDFn: func(context.Context, string) (store.Account, error) {
+			return store.Account{ID: "acc-1", UserID: stringPtr("other"), Currency: "USD"}, nil
+		},
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+
+	rr := serveBalanceAsOf(t, handler, "user-1", "acc-1", "2025-03-31")
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+}
+
+func TestGetBalanceAsOfInvalid(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{
+		getByIDFn: func(context.Context, string) (store.Account, error) {
+			return store.Account{ID: "acc-1", UserID: stringPtr("user-1"), Currency: "USD"}, nil
+		},
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+
+	rr := serveBalanceAsOf(t, handler, "user-1", "acc-1", "yesterday")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
@@ -1,16 +1,22 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
-	"banking/internal/auth"
+	"banking/internal/events"
 	"banking/internal/middleware"
-	"banking/internal/websocket"
+	"banking/internal/money"
+	"banking/internal/services"
+	"banking/internal/store"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type promoteRequest struct {
@@ -23,15 +29,6 @@ func (h *Handler) PromoteAdmin(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
-	_, isSuper, err := h.admin.IsAdmin(r.Context(), userID)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to verify admin")
-		return
-	}
-	if !isSuper {
-		respondError(w, http.StatusForbidden, "super_admin_required")
-		return
-	}
 	var req promoteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Identifier == "" {
 		respondError(w, http.StatusBadRequest, "invalid payload")
@@ -53,22 +50,39 @@ func (h *Handler) PromoteAdmin(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "unable to resolve user")
 		return
 	}
-	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
-		if err := h.admin.CreateAdmin(r.Context(), tx, targetUserID, false, &userID); err != nil {
-			return err
-		}
-		data, _ := json.Marshal(map[string]string{
-			"target_user_id": targetUserID,
-		})
-		return h.audit.Log(r.Context(), tx, userID, "promote_admin", "admin", targetUserID, string(data))
-	})
+	needsApproval, err := h.needsAdminApproval(r.Context(), userID, approvalPromoteAdmin)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "unable to promote admin")
 		return
 	}
+	if needsApproval {
+		h.requestApproval(w, r, userID, approvalPromoteAdmin, approvalPayload{TargetUserID: targetUserID})
+		return
+	}
+	if err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		return h.promoteAdmin(r.Context(), tx, userID, targetUserID)
+	}); err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to promote admin")
+		return
+	}
+	h.adminAccess.Invalidate(targetUserID)
 	respondJSON(w, http.StatusCreated, map[string]string{"status": "promoted"})
 }
 
+func (h *Handler) promoteAdmin(ctx context.Context, tx *sqlx.Tx, actorID, targetUserID string) error {
+	if err := h.admin.CreateAdmin(ctx, tx, targetUserID, false, &actorID); err != nil {
+		return err
+	}
+	payload := map[string]string{
+		"target_user_id": targetUserID,
+	}
+	data, _ := json.Marshal(payload)
+	if err := h.audit.Log(ctx, tx, actorID, "promote_admin", "admin", targetUserID, string(data)); err != nil {
+		return err
+	}
+	return h.outbox.Append(ctx, tx, events.New(events.AdminPromoted, events.AggregateUser, targetUserID, &actorID, payload))
+}
+
 type grantRoleRequest struct {
 	AdminUserID string `json:"admin_user_id"`
 	Role        string `json:"role"`
@@ -80,20 +94,19 @@ func (h *Handler) GrantRole(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
-	_, isSuper, err := h.admin.IsAdmin(r.Context(), userID)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "unable to verify admin")
-		return
-	}
-	if !isSuper {
-		respondError(w, http.StatusForbidden, "super_admin_required")
-		return
-	}
 	var req grantRoleRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AdminUserID == "" || req.Role == "" {
 		respondError(w, http.StatusBadRequest, "invalid payload")
 		return
 	}
+	if _, err := h.admin.GetRole(r.Context(), req.Role); err != nil {
+		if err == sql.ErrNoRows {
+			respondError(w, http.StatusNotFound, "role not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "unable to load role")
+		return
+	}
 	isAdmin, isSuper, err := h.admin.IsAdmin(r.Context(), req.AdminUserID)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "unable to verify target admin")
@@ -107,30 +120,94 @@ func (h *Handler) GrantRole(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "cannot assign roles to super admin")
 		return
 	}
-	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
-		if err := h.admin.GrantRole(r.Context(), tx, req.AdminUserID, req.Role); err != nil {
-			return err
-		}
-		data, _ := json.Marshal(map[string]string{
-			"admin_user_id": req.AdminUserID,
-			"role":          req.Role,
-		})
-		return h.audit.Log(r.Context(), tx, userID, "grant_role", "admin_role", req.AdminUserID, string(data))
-	})
+	needsApproval, err := h.needsAdminApproval(r.Context(), userID, approvalGrantRole)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "unable to grant role")
 		return
 	}
+	if needsApproval {
+		h.requestApproval(w, r, userID, approvalGrantRole, approvalPayload{AdminUserID: req.AdminUserID, Role: req.Role})
+		return
+	}
+	if err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		return h.grantRole(r.Context(), tx, userID, req.AdminUserID, req.Role)
+	}); err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to grant role")
+		return
+	}
+	h.adminAccess.Invalidate(req.AdminUserID)
 	respondJSON(w, http.StatusCreated, map[string]string{"status": "role_granted"})
 }
 
+func (h *Handler) grantRole(ctx context.Context, tx *sqlx.Tx, actorID, adminUserID, role string) error {
+	if err := h.admin.GrantRole(ctx, tx, adminUserID, role, actorID); err != nil {
+		return err
+	}
+	payload := map[string]string{
+		"admin_user_id": adminUserID,
+		"role":          role,
+	}
+	data, _ := json.Marshal(payload)
+	if err := h.audit.Log(ctx, tx, actorID, "grant_role", "admin_role", adminUserID, string(data)); err != nil {
+		return err
+	}
+	return h.outbox.Append(ctx, tx, events.New(events.AdminRoleGranted, events.AggregateUser, adminUserID, &actorID, payload))
+}
+
 type exchangeRateRequest struct {
 	QuoteCurrency string `json:"quote_currency"`
 	Rate          string `json:"rate"`
 }
 
 func (h *Handler) SetExchangeRate(w http.ResponseWriter, r *http.Request) {
-	respondError(w, http.StatusForbidden, "rate_fixed")
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req exchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if req.QuoteCurrency != "EUR" {
+		respondError(w, http.StatusBadRequest, "unsupported_currency_pair")
+		return
+	}
+	rate, err := parseRate(req.Rate)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_rate")
+		return
+	}
+	normalized := rate.StringFixedBank(6)
+	var rateID string
+	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		rateID, err = h.exchange.SetRate(r.Context(), tx, "USD", req.QuoteCurrency, normalized, userID)
+		if err != nil {
+			return err
+		}
+		payload := map[string]string{
+			"rate_id":        rateID,
+			"base_currency":  "USD",
+			"quote_currency": req.QuoteCurrency,
+			"rate":           normalized,
+		}
+		data, _ := json.Marshal(payload)
+		if err := h.audit.Log(r.Context(), tx, userID, "set_exchange_rate", "exchange_rate", rateID, string(data)); err != nil {
+			return err
+		}
+		return h.outbox.Append(r.Context(), tx, events.New(events.RatePublished, events.AggregateRate, rateID, &userID, payload))
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to set rate")
+		return
+	}
+	respondJSON(w, http.StatusCreated, map[string]string{
+		"id":             rateID,
+		"base_currency":  "USD",
+		"quote_currency": req.QuoteCurrency,
+		"rate":           normalized,
+	})
 }
 
 func (h *Handler) AdminListUsers(w http.ResponseWriter, r *http.Request) {
@@ -162,6 +239,226 @@ func (h *Handler) AdminListUsers(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, normalized)
 }
 
+type adjustmentRequest struct {
+	AccountID       string  `json:"account_id"`
+	Amount          string  `json:"amount"`
+	Reason          string  `json:"reason"`
+	EffectiveAt     string  `json:"effective_at"`
+	ClientRequestID *string `json:"client_request_id"`
+}
+
+func (h *Handler) PostAdjustment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req adjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AccountID == "" || strings.TrimSpace(req.Reason) == "" {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	amountMinor, err := money.ParseMinor(req.Amount)
+	if err != nil || amountMinor == 0 {
+		respondError(w, http.StatusBadRequest, "invalid_amount")
+		return
+	}
+	var effectiveAt *time.Time
+	if req.EffectiveAt != "" {
+		parsed, err := parseAsOf(req.EffectiveAt)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid effective_at")
+			return
+		}
+		effectiveAt = &parsed
+	}
+	if h.needsApproval(approvalAdjustment) {
+		if effectiveAt != nil && effectiveAt.After(time.Now()) {
+			respondError(w, http.StatusBadRequest, "invalid effective_at")
+			return
+		}
+		h.requestApproval(w, r, userID, approvalAdjustment, approvalPayload{
+			AccountID:       req.AccountID,
+			AmountMinor:     amountMinor,
+			Reason:          strings.TrimSpace(req.Reason),
+			EffectiveAt:     effectiveAt,
+			ClientRequestID: req.ClientRequestID,
+		})
+		return
+	}
+	transactionID, err := h.service.Adjust(r.Context(), services.AdjustmentRequest{
+		ActorID:         userID,
+		AccountID:       req.AccountID,
+		AmountMinor:     amountMinor,
+		Reason:          strings.TrimSpace(req.Reason),
+		EffectiveAt:     effectiveAt,
+		ClientRequestID: req.ClientRequestID,
+	})
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			respondError(w, http.StatusNotFound, "account not found")
+		case services.ErrPeriodClosed:
+			respondError(w, http.StatusConflict, "period_closed")
+		case services.ErrInsufficientFunds:
+			respondError(w, http.StatusBadRequest, "insufficient_funds")
+		case services.ErrInvalidAmount:
+			respondError(w, http.StatusBadRequest, "invalid_amount")
+		case services.ErrInvalidAdjustment:
+			respondError(w, http.StatusBadRequest, "invalid_adjustment")
+		case services.ErrInvalidEffectiveDate:
+			respondError(w, http.StatusBadRequest, "invalid effective_at")
+		default:
+			if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+				respondError(w, http.StatusConflict, "duplicate_request")
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "adjustment_failed")
+		}
+		return
+	}
+	respondJSON(w, http.StatusCreated, map[string]string{"transaction_id": transactionID})
+}
+
+type reversalRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (h *Handler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req reversalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		respondError(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+	transactionID := chi.URLParam(r, "id")
+	if h.needsApproval(approvalReversal) {
+		h.requestApproval(w, r, userID, approvalReversal, approvalPayload{
+			TransactionID: transactionID,
+			Reason:        strings.TrimSpace(req.Reason),
+		})
+		return
+	}
+	reversalID, err := h.service.Reverse(r.Context(), services.ReversalRequest{
+		ActorID:       userID,
+		TransactionID: transactionID,
+		Reason:        strings.TrimSpace(req.Reason),
+	})
+	if err != nil {
+		switch err {
+		case services.ErrTransactionNotFound:
+			respondError(w, http.StatusNotFound, "transaction not found")
+		case services.ErrAlreadyReversed:
+			respondError(w, http.StatusConflict, "already_reversed")
+		case services.ErrNotReversible:
+			respondError(w, http.StatusBadRequest, "not_reversible")
+		case services.ErrPeriodClosed:
+			respondError(w, http.StatusConflict, "period_closed")
+		case services.ErrInsufficientFunds:
+			respondError(w, http.StatusBadRequest, "insufficient_funds")
+		default:
+			if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+				respondError(w, http.StatusConflict, "already_reversed")
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "reversal_failed")
+		}
+		return
+	}
+	respondJSON(w, http.StatusCreated, map[string]string{"transaction_id": reversalID, "reversal_of": transactionID})
+}
+
+type freezeRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (h *Handler) FreezeAccount(w http.ResponseWriter, r *http.Request) {
+	var req freezeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		respondError(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+	h.setAccountFrozen(w, r, true, strings.TrimSpace(req.Reason))
+}
+
+func (h *Handler) UnfreezeAccount(w http.ResponseWriter, r *http.Request) {
+	h.setAccountFrozen(w, r, false, "")
+}
+
+func (h *Handler) setAccountFrozen(w http.ResponseWriter, r *http.Request, frozen bool, reason string) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	accountID := chi.URLParam(r, "id")
+	account, err := h.accounts.GetByID(r.Context(), accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(w, http.StatusNotFound, "account not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "unable to load account")
+		return
+	}
+	if account.IsSystem || account.UserID == nil {
+		respondError(w, http.StatusBadRequest, "system accounts cannot be frozen")
+		return
+	}
+	conflict := "account_already_frozen"
+	if !frozen {
+		conflict = "account_not_frozen"
+	}
+	if frozen && h.needsApproval(approvalFreezeAccount) {
+		if account.FrozenAt != nil {
+			respondError(w, http.StatusConflict, conflict)
+			return
+		}
+		h.requestApproval(w, r, userID, approvalFreezeAccount, approvalPayload{AccountID: accountID, Reason: reason})
+		return
+	}
+	var updated int64
+	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		updated, err = h.updateAccountFrozen(r.Context(), tx, userID, account, frozen, reason)
+		return err
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to update account")
+		return
+	}
+	if updated == 0 {
+		respondError(w, http.StatusConflict, conflict)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"account_id": accountID, "frozen": frozen})
+}
+
+func (h *Handler) updateAccountFrozen(ctx context.Context, tx *sqlx.Tx, actorID string, account store.Account, frozen bool, reason string) (int64, error) {
+	action, eventType := "freeze_account", events.AccountFrozen
+	if !frozen {
+		action, eventType = "unfreeze_account", events.AccountUnfrozen
+	}
+	updated, err := h.accounts.SetFrozen(ctx, tx, account.ID, frozen, reason)
+	if err != nil || updated == 0 {
+		return updated, err
+	}
+	payload := map[string]string{
+		"account_id": account.ID,
+		"currency":   account.Currency,
+		"reason":     reason,
+		"actor_id":   actorID,
+	}
+	data, _ := json.Marshal(payload)
+	if err := h.audit.Log(ctx, tx, actorID, action, "account", account.ID, string(data)); err != nil {
+		return 0, err
+	}
+	return updated, h.outbox.Append(ctx, tx, events.New(eventType, events.AggregateAccount, account.ID, account.UserID, payload))
+}
+
 func (h *Handler) AdminListTransactions(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	limit := parseInt(query.Get("limit"), 50)
@@ -241,22 +538,25 @@ func (h *Handler) Reconcile(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, normalized)
 }
 
-func (h *Handler) WSBalances(w http.ResponseWriter, r *http.Request) {
-	token := r.URL.Query().Get("token")
-	if token == "" {
-		authHeader := r.Header.Get("Authorization")
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			token = strings.TrimPrefix(authHeader, "Bearer ")
-		}
+func (h *Handler) AdminBalancesAsOf(w http.ResponseWriter, r *http.Request) {
+	asOfRaw := r.URL.Query().Get("as_of")
+	if asOfRaw == "" {
+		respondError(w, http.StatusBadRequest, "as_of is required")
+		return
 	}
-	if token == "" {
-		respondError(w, http.StatusUnauthorized, "missing token")
+	asOf, err := parseAsOf(asOfRaw)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid as_of")
 		return
 	}
-	claims, err := auth.ParseToken(h.cfg.JWTSecret, token)
+	rows, err := h.balances.BalancesAsOf(r.Context(), asOf)
 	if err != nil {
-		respondError(w, http.StatusUnauthorized, "invalid token")
+		respondError(w, http.StatusInternalServerError, "unable to load balances")
 		return
 	}
-	websocket.ServeWS(w, r, h.hub, claims.UserID)
+	normalized := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		normalized = append(normalized, balanceAsOfResponse(row, asOf))
+	}
+	respondJSON(w, http.StatusOK, normalized)
 }
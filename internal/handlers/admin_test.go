@@ -3,6 +3,8 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"database/sql"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -10,36 +12,12 @@ import (
 	"time"
 
 	"banking/internal/auth"
+	"banking/internal/events"
 	"banking/internal/middleware"
+	"banking/internal/services"
 	"banking/internal/store"
 )
 
-func TestPromoteAdminForbidden(t *testing.T) {
-	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
-		getByUsernameFn: func(context.Context, string) (map[string]any, error) { return nil, nil },
-		getByEmailFn:    func(context.Context, string) (map[string]any, error) { return nil, nil },
-		getByIDFn:       func(context.Context, string) (map[string]any, error) { return nil, nil },
-		createFn:        func(context.Context, store.Execer, string, string, string, string) error { return nil },
-	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
-		isAdminFn:     func(context.Context, string) (bool, bool, error) { return true, false, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
-		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
-	}, stubAuditStore{
-		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
-		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
-	}, stubService{})
-
-	body := []byte(`{"identifier":"bob"}`)
-	req := httptest.NewRequest(http.MethodPost, "/admin/promote", bytes.NewReader(body))
-	token, _ := auth.GenerateToken("secret", "user-1", time.Minute)
-	req.Header.Set("Authorization", "Bearer "+token)
-	rr := httptest.NewRecorder()
-	middleware.Auth("secret")(http.HandlerFunc(handler.PromoteAdmin)).ServeHTTP(rr, req)
-	if rr.Code != http.StatusForbidden {
-		t.Fatalf("expected 403, got %d", rr.Code)
-	}
-}
-
 func TestPromoteAdminSuccess(t *testing.T) {
 	created := 0
 	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
@@ -55,25 +33,35 @@ func TestPromoteAdminSuccess(t *testing.T) {
 			created++
 			return nil
 		},
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
 		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
 	}, stubService{})
+	var appended []store.DomainEventInput
+	handler.outbox = stubOutboxStore{
+		appendFn: func(_ context.Context, _ store.Execer, input store.DomainEventInput) error {
+			appended = append(appended, input)
+			return nil
+		},
+	}
 
 	body := []byte(`{"identifier":"bob"}`)
 	req := httptest.NewRequest(http.MethodPost, "/admin/promote", bytes.NewReader(body))
-	token, _ := auth.GenerateToken("secret", "admin-1", time.Minute)
+	token, _ := auth.GenerateToken(testKeys, "admin-1", time.Minute)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
-	middleware.Auth("secret")(http.HandlerFunc(handler.PromoteAdmin)).ServeHTTP(rr, req)
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.PromoteAdmin)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusCreated {
 		t.Fatalf("expected 201, got %d", rr.Code)
 	}
 	if created != 1 {
 		t.Fatalf("expected admin creation")
 	}
+	if len(appended) != 1 || appended[0].Type != events.AdminPromoted || appended[0].AggregateID != "user-2" {
+		t.Fatalf("unexpected events: %#v", appended)
+	}
 }
 
 func TestGrantRoleSuccess(t *testing.T) {
@@ -89,8 +77,8 @@ func TestGrantRoleSuccess(t *testing.T) {
 			}
 			return true, false, nil
 		},
-		grantRoleFn:   func(context.Context, store.Execer, string, string) error { return nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		grantRoleFn:   func(context.Context, store.Execer, string, string, string) error { return nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
@@ -99,38 +87,57 @@ func TestGrantRoleSuccess(t *testing.T) {
 
 	body := []byte(`{"admin_user_id":"target","role":"CanViewUsers"}`)
 	req := httptest.NewRequest(http.MethodPost, "/admin/roles/grant", bytes.NewReader(body))
-	token, _ := auth.GenerateToken("secret", "admin-1", time.Minute)
+	token, _ := auth.GenerateToken(testKeys, "admin-1", time.Minute)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
-	middleware.Auth("secret")(http.HandlerFunc(handler.GrantRole)).ServeHTTP(rr, req)
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.GrantRole)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusCreated {
 		t.Fatalf("expected 201, got %d", rr.Code)
 	}
 }
 
 func TestSetExchangeRate(t *testing.T) {
-	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
-		getByUsernameFn: func(context.Context, string) (map[string]any, error) { return nil, nil },
-		getByEmailFn:    func(context.Context, string) (map[string]any, error) { return nil, nil },
-		getByIDFn:       func(context.Context, string) (map[string]any, error) { return nil, nil },
-		createFn:        func(context.Context, store.Execer, string, string, string, string) error { return nil },
-	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
-		isAdminFn:     func(context.Context, string) (bool, bool, error) { return true, true, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
-		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
-	}, stubAuditStore{
-		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
-		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{
+		setRateFn: func(_ context.Context, _ store.Tx, base, quote, rate, actorID string) (string, error) {
+			if base != "USD" || quote != "EUR" || rate != "1.100000" || actorID != "admin-1" {
+				t.Fatalf("unexpected rate: %s %s %s %s", base, quote, rate, actorID)
+			}
+			return "rate-1", nil
+		},
+	}, stubAdminStore{}, stubAuditStore{
+		logFn: func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
 	}, stubService{})
-
-	body := []byte(`{"quote_currency":"EUR","rate":"1.1"}`)
-	req := httptest.NewRequest(http.MethodPost, "/admin/exchange-rate", bytes.NewReader(body))
-	token, _ := auth.GenerateToken("secret", "admin-1", time.Minute)
-	req.Header.Set("Authorization", "Bearer "+token)
-	rr := httptest.NewRecorder()
-	middleware.Auth("secret")(http.HandlerFunc(handler.SetExchangeRate)).ServeHTTP(rr, req)
-	if rr.Code != http.StatusForbidden {
-		t.Fatalf("expected 403, got %d", rr.Code)
+	var appended []store.DomainEventInput
+	handler.outbox = stubOutboxStore{
+		appendFn: func(_ context.Context, _ store.Execer, input store.DomainEventInput) error {
+			appended = append(appended, input)
+			return nil
+		},
+	}
+	token, _ := auth.GenerateToken(testKeys, "admin-1", time.Minute)
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{body: `{"quote_currency":"GBP","rate":"1.1"}`, status: http.StatusBadRequest},
+		{body: `{"quote_currency":"EUR","rate":"-1"}`, status: http.StatusBadRequest},
+		{body: `{"quote_currency":"EUR","rate":"1.1234567"}`, status: http.StatusBadRequest},
+		{body: `{"quote_currency":"EUR","rate":"1.1"}`, status: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/admin/exchange-rate", bytes.NewReader([]byte(tt.body)))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.SetExchangeRate)).ServeHTTP(rr, req)
+		if rr.Code != tt.status {
+			t.Fatalf("%s: expected %d, got %d", tt.body, tt.status, rr.Code)
+		}
+	}
+	if len(appended) != 1 || appended[0].Type != events.RatePublished || appended[0].AggregateID != "rate-1" {
+		t.Fatalf("unexpected events: %#v", appended)
+	}
+	if appended[0].Payload != `{"base_currency":"USD","quote_currency":"EUR","rate":"1.100000","rate_id":"rate-1"}` {
+		t.Fatalf("unexpected payload: %s", appended[0].Payload)
 	}
 }
 
@@ -146,7 +153,7 @@ func TestAdminListUsers(t *testing.T) {
 		},
 	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return true, true, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
@@ -174,7 +181,7 @@ func TestAdminListTransactions(t *testing.T) {
 		listByUserFn: func(context.Context, string, string, int, int) ([]map[string]any, error) { return nil, nil },
 	}, stubExchangeStore{}, stubAdminStore{
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return true, true, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
@@ -197,7 +204,7 @@ func TestListAuditLogs(t *testing.T) {
 		createFn:        func(context.Context, store.Execer, string, string, string, string) error { return nil },
 	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return true, true, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 	}, stubAuditStore{
 		listFn: func(context.Context, int, int) ([]map[string]any, error) {
@@ -237,7 +244,7 @@ func TestReconcile(t *testing.T) {
 		createFn:        func(context.Context, store.Execer, string, string, string, string) error { return nil },
 	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return true, true, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 	}, stubAuditStore{
 		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
@@ -252,48 +259,165 @@ func TestReconcile(t *testing.T) {
 	}
 }
 
-func TestWSBalancesMissingToken(t *testing.T) {
-	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
-		getByUsernameFn: func(context.Context, string) (map[string]any, error) { return nil, nil },
-		getByEmailFn:    func(context.Context, string) (map[string]any, error) { return nil, nil },
-		getByIDFn:       func(context.Context, string) (map[string]any, error) { return nil, nil },
-		createFn:        func(context.Context, store.Execer, string, string, string, string) error { return nil },
-	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
-		isAdminFn:     func(context.Context, string) (bool, bool, error) { return true, true, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
-		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
-	}, stubAuditStore{
-		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
-		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
-	}, stubService{})
+func TestAdminBalancesAsOf(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.balances = stubBalanceStore{
+		balancesAsOfFn: func(context.Context, time.Time) ([]store.BalanceAsOf, error) {
+			return []store.BalanceAsOf{
+				{AccountID: "acc-1", UserID: stringPtr("user-1"), Currency: "USD", Balance: 100},
+				{AccountID: "sys-usd", Currency: "USD", IsSystem: true, Balance: -100},
+			}, nil
+		},
+	}
 
-	req := httptest.NewRequest(http.MethodGet, "/ws/balances", nil)
 	rr := httptest.NewRecorder()
-	handler.WSBalances(rr, req)
-	if rr.Code != http.StatusUnauthorized {
-		t.Fatalf("expected 401, got %d", rr.Code)
+	req := httptest.NewRequest(http.MethodGet, "/admin/balances?as_of=2025-03-31", nil)
+	handler.AdminBalancesAsOf(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var payload []map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload) != 2 || payload[1]["booked_balance"] != "-1.00" {
+		t.Fatalf("unexpected payload: %#v", payload)
 	}
-}
 
-func TestWSBalancesInvalidToken(t *testing.T) {
-	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
-		getByUsernameFn: func(context.Context, string) (map[string]any, error) { return nil, nil },
-		getByEmailFn:    func(context.Context, string) (map[string]any, error) { return nil, nil },
-		getByIDFn:       func(context.Context, string) (map[string]any, error) { return nil, nil },
-		createFn:        func(context.Context, store.Execer, string, string, string, string) error { return nil },
-	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
-		isAdminFn:     func(context.Context, string) (bool, bool, error) { return true, true, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
-		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
-	}, stubAuditStore{
-		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
-		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
-	}, stubService{})
+	rr = httptest.NewRecorder()
+	handler.AdminBalancesAsOf(rr, httptest.NewRequest(http.MethodGet, "/admin/balances", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without as_of, got %d", rr.Code)
+	}
+}
 
-	req := httptest.NewRequest(http.MethodGet, "/ws/balances?token=bad", nil)
+func serveAdjustment(t *testing.T, handler *Handler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/admin/adjustments", bytes.NewReader([]byte(body)))
+	token, _ := auth.GenerateToken(testKeys, "admin-1", time.Minute)
+	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
-	handler.WSBalances(rr, req)
-	if rr.Code != http.StatusUnauthorized {
-		t.Fatalf("expected 401, got %d", rr.Code)
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.PostAdjustment)).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestPostAdjustment(t *testing.T) {
+	var received services.AdjustmentRequest
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{
+		adjustFn: func(_ context.Context, req services.AdjustmentRequest) (string, error) {
+			received = req
+			return "tx-1", nil
+		},
+	})
+	rr := serveAdjustment(t, handler, `{"account_id":"acc-1","amount":"-2.50","reason":"fee reversal","effective_at":"2025-03-31"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rr.Code)
+	}
+	if received.ActorID != "admin-1" || received.AmountMinor != -250 || received.EffectiveAt == nil || received.EffectiveAt.Day() != 31 {
+		t.Fatalf("unexpected request: %#v", received)
+	}
+}
+
+func TestPostAdjustmentErrors(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{
+		adjustFn: func(context.Context, services.AdjustmentRequest) (string, error) {
+			return "", services.ErrPeriodClosed
+		},
+	})
+	if rr := serveAdjustment(t, handler, `{"account_id":"acc-1","amount":"1.00","reason":"late fee","effective_at":"2025-01-15"}`); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for closed period, got %d", rr.Code)
+	}
+	if rr := serveAdjustment(t, handler, `{"account_id":"acc-1","amount":"0","reason":"late fee"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for zero amount, got %d", rr.Code)
+	}
+	if rr := serveAdjustment(t, handler, `{"account_id":"acc-1","amount":"1.00"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without reason, got %d", rr.Code)
+	}
+}
+
+func TestReverseTransaction(t *testing.T) {
+	var received services.ReversalRequest
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{
+		reverseFn: func(_ context.Context, req services.ReversalRequest) (string, error) {
+			received = req
+			if req.TransactionID == "tx-2" {
+				return "", services.ErrAlreadyReversed
+			}
+			return "tx-r", nil
+		},
+	})
+	rr := servePeriodRequest(t, handler.ReverseTransaction, http.MethodPost, "/admin/transactions/{id}/reverse", "/admin/transactions/tx-1/reverse", `{"reason":"sent in error"}`, "admin-1")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if received.ActorID != "admin-1" || received.TransactionID != "tx-1" || received.Reason != "sent in error" {
+		t.Fatalf("unexpected request: %#v", received)
+	}
+	if rr := servePeriodRequest(t, handler.ReverseTransaction, http.MethodPost, "/admin/transactions/{id}/reverse", "/admin/transactions/tx-2/reverse", `{"reason":"again"}`, "admin-1"); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a reversed transaction, got %d", rr.Code)
+	}
+	if rr := servePeriodRequest(t, handler.ReverseTransaction, http.MethodPost, "/admin/transactions/{id}/reverse", "/admin/transactions/tx-1/reverse", `{}`, "admin-1"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without reason, got %d", rr.Code)
+	}
+}
+
+func TestFreezeAccount(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{
+		getByIDFn: func(_ context.Context, accountID string) (store.Account, error) {
+			return store.Account{ID: accountID, UserID: stringPtr("user-1"), Currency: "USD"}, nil
+		},
+		setFrozenFn: func(_ context.Context, _ store.Execer, accountID string, frozen bool, reason string) (int64, error) {
+			if accountID != "acc-1" || !frozen || reason != "chargeback" {
+				t.Fatalf("unexpected freeze: %s %v %q", accountID, frozen, reason)
+			}
+			return 1, nil
+		},
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	var appended []store.DomainEventInput
+	handler.outbox = stubOutboxStore{
+		appendFn: func(_ context.Context, _ store.Execer, input store.DomainEventInput) error {
+			appended = append(appended, input)
+			return nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.FreezeAccount, http.MethodPost, "/admin/accounts/{id}/freeze", "/admin/accounts/acc-1/freeze", `{"reason":"chargeback"}`, "admin-1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if len(appended) != 1 || appended[0].Type != events.AccountFrozen || *appended[0].UserID != "user-1" {
+		t.Fatalf("unexpected events: %#v", appended)
+	}
+}
+
+func TestFreezeAccountErrors(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{
+		getByIDFn: func(_ context.Context, accountID string) (store.Account, error) {
+			switch accountID {
+			case "missing":
+				return store.Account{}, sql.ErrNoRows
+			case "system":
+				return store.Account{ID: accountID, IsSystem: true}, nil
+			}
+			return store.Account{ID: accountID, UserID: stringPtr("user-1")}, nil
+		},
+		setFrozenFn: func(context.Context, store.Execer, string, bool, string) (int64, error) {
+			return 0, nil
+		},
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	cases := []struct {
+		target string
+		body   string
+		status int
+	}{
+		{"/admin/accounts/acc-1/freeze", `{}`, http.StatusBadRequest},
+		{"/admin/accounts/missing/freeze", `{"reason":"x"}`, http.StatusNotFound},
+		{"/admin/accounts/system/freeze", `{"reason":"x"}`, http.StatusBadRequest},
+		{"/admin/accounts/acc-1/freeze", `{"reason":"x"}`, http.StatusConflict},
+	}
+	for _, tc := range cases {
+		rr := servePeriodRequest(t, handler.FreezeAccount, http.MethodPost, "/admin/accounts/{id}/freeze", tc.target, tc.body, "admin-1")
+		if rr.Code != tc.status {
+			t.Fatalf("expected %d for %s %s, got %d", tc.status, tc.target, tc.body, rr.Code)
+		}
 	}
 }
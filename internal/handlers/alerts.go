@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"banking/internal/middleware"
+	"banking/internal/money"
+	"banking/internal/services"
+	"banking/internal/store"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const maxAlertRules = 20
+
+type alertRuleRequest struct {
+	Kind      string `json:"kind"`
+	Currency  string `json:"currency"`
+	Threshold string `json:"threshold"`
+}
+
+func (h *Handler) ListAlertRules(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	rules, err := h.alerts.ListByUser(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load alert rules")
+		return
+	}
+	normalized := make([]map[string]any, 0, len(rules))
+	for _, rule := range rules {
+		normalized = append(normalized, alertRuleResponse(rule))
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
+
+func (h *Handler) CreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req alertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if !services.IsAlertKind(req.Kind) {
+		respondError(w, http.StatusBadRequest, "unknown kind: "+req.Kind)
+		return
+	}
+	currency := strings.ToUpper(req.Currency)
+	if otherCurrency(currency) == "" {
+		respondError(w, http.StatusBadRequest, "unsupported currency")
+		return
+	}
+	threshold, err := money.ParseMinor(req.Threshold)
+	if err != nil || threshold < 0 {
+		respondError(w, http.StatusBadRequest, "invalid threshold")
+		return
+	}
+	existing, err := h.alerts.ListByUser(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to create alert rule")
+		return
+	}
+	if len(existing) >= maxAlertRules {
+		respondError(w, http.StatusConflict, "alert rule limit reached")
+		return
+	}
+	rule, err := h.alerts.Create(r.Context(), store.AlertRuleInput{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Kind:      req.Kind,
+		Currency:  currency,
+		Threshold: threshold,
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to create alert rule")
+		return
+	}
+	respondJSON(w, http.StatusCreated, alertRuleResponse(rule))
+}
+
+func (h *Handler) DeleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	deleted, err := h.alerts.Delete(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to delete alert rule")
+		return
+	}
+	if deleted == 0 {
+		respondError(w, http.StatusNotFound, "alert rule not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func alertRuleResponse(rule store.AlertRule) map[string]any {
+	return map[string]any{
+		"id":                rule.ID,
+		"kind":              rule.Kind,
+		"currency":          rule.Currency,
+		"threshold":         money.FormatMinor(rule.Threshold),
+		"active":            rule.Active,
+		"last_triggered_at": rule.LastTriggeredAt,
+		"created_at":        rule.CreatedAt,
+	}
+}
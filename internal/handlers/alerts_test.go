@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"banking/internal/services"
+	"banking/internal/store"
+)
+
+func TestCreateAlertRule(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	var created store.AlertRuleInput
+	handler.alerts = stubAlertRuleStore{
+		createFn: func(_ context.Context, input store.AlertRuleInput) (store.AlertRule, error) {
+			created = input
+			return store.AlertRule{ID: input.ID, UserID: input.UserID, Kind: input.Kind, Currency: input.Currency, Threshold: input.Threshold, Active: true}, nil
+		},
+	}
+	body := `{"kind":"low_balance","currency":"usd","threshold":"100.50"}`
+	rr := servePeriodRequest(t, handler.CreateAlertRule, http.MethodPost, "/alerts/rules", "/alerts/rules", body, "user-1")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if created.UserID != "user-1" || created.Kind != services.AlertLowBalance || created.Currency != "USD" || created.Threshold != 10050 || created.ID == "" {
+		t.Fatalf("unexpected rule input: %#v", created)
+	}
+	var resp map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp["threshold"] != "100.50" || resp["active"] != true {
+		t.Fatalf("unexpected response: %#v", resp)
+	}
+}
+
+func TestCreateAlertRuleValidation(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.alerts = stubAlertRuleStore{
+		createFn: func(context.Context, store.AlertRuleInput) (store.AlertRule, error) {
+			t.Fatalf("expected no rule to be created")
+			return store.AlertRule{}, nil
+		},
+	}
+	cases := []string{
+		`{"kind":"overdraft","currency":"USD","threshold":"1.00"}`,
+		`{"kind":"low_balance","currency":"GBP","threshold":"1.00"}`,
+		`{"kind":"low_balance","currency":"USD","threshold":"-1.00"}`,
+		`{"kind":"low_balance","currency":"USD","threshold":"abc"}`,
+		`not json`,
+	}
+	for _, body := range cases {
+		rr := servePeriodRequest(t, handler.CreateAlertRule, http.MethodPost, "/alerts/rules", "/alerts/rules", body, "user-1")
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, rr.Code)
+		}
+	}
+}
+
+func TestCreateAlertRuleLimit(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.alerts = stubAlertRuleStore{
+		listByUserFn: func(context.Context, string) ([]store.AlertRule, error) {
+			return make([]store.AlertRule, maxAlertRules), nil
+		},
+		createFn: func(context.Context, store.AlertRuleInput) (store.AlertRule, error) {
+			t.Fatalf("expected no rule to be created")
+			return store.AlertRule{}, nil
+		},
+	}
+	body := `{"kind":"incoming_funds","currency":"EUR","threshold":"0"}`
+	rr := servePeriodRequest(t, handler.CreateAlertRule, http.MethodPost, "/alerts/rules", "/alerts/rules", body, "user-1")
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestDeleteAlertRule(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.alerts = stubAlertRuleStore{
+		deleteFn: func(_ context.Context, userID, ruleID string) (int64, error) {
+			if userID != "user-1" {
+				t.Fatalf("unexpected user: %s", userID)
+			}
+			if ruleID == "rule-1" {
+				return 1, nil
+			}
+			return 0, nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.DeleteAlertRule, http.MethodDelete, "/alerts/rules/{id}", "/alerts/rules/rule-1", "", "user-1")
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = servePeriodRequest(t, handler.DeleteAlertRule, http.MethodDelete, "/alerts/rules/{id}", "/alerts/rules/other", "", "user-1")
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/middleware"
+	"banking/internal/store"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type apiKeyRequest struct {
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	h.createAPIKey(w, r, userID, nil)
+}
+
+func (h *Handler) createAPIKey(w http.ResponseWriter, r *http.Request, userID string, organizationID *string) {
+	var req apiKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		respondError(w, http.StatusBadRequest, "name is required and at most 100 characters")
+		return
+	}
+	if len(req.Scopes) == 0 {
+		respondError(w, http.StatusBadRequest, "scopes is required")
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !auth.IsScope(scope) {
+			respondError(w, http.StatusBadRequest, "unknown scope: "+scope)
+			return
+		}
+	}
+	allowedIPs := make([]string, 0, len(req.AllowedIPs))
+	for _, entry := range req.AllowedIPs {
+		network, ok := parseAllowedIP(entry)
+		if !ok {
+			respondError(w, http.StatusBadRequest, "invalid allowed_ips entry: "+entry)
+			return
+		}
+		allowedIPs = append(allowedIPs, network)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		respondError(w, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+	key, err := auth.NewOpaqueToken(auth.APIKeyPrefix)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to create api key")
+		return
+	}
+	input := store.APIKeyInput{
+		ID:             uuid.NewString(),
+		UserID:         userID,
+		OrganizationID: organizationID,
+		Name:           name,
+		Prefix:         key[:len(auth.APIKeyPrefix)+8],
+		KeyHash:        auth.HashToken(key),
+		Scopes:         req.Scopes,
+		AllowedIPs:     allowedIPs,
+		ExpiresAt:      req.ExpiresAt,
+	}
+	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		if err := h.apiKeys.Create(r.Context(), tx, input); err != nil {
+			return err
+		}
+		data, _ := json.Marshal(map[string]any{
+			"name":            input.Name,
+			"prefix":          input.Prefix,
+			"scopes":          input.Scopes,
+			"allowed_ips":     input.AllowedIPs,
+			"expires_at":      input.ExpiresAt,
+			"organization_id": input.OrganizationID,
+			"ip":              middleware.RemoteIP(r),
+		})
+		return h.audit.Log(r.Context(), tx, userID, "create_api_key", "api_key", input.ID, string(data))
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to create api key")
+		return
+	}
+	response := apiKeyResponse(store.APIKey{
+		ID:             input.ID,
+		UserID:         userID,
+		OrganizationID: organizationID,
+		Name:           input.Name,
+		Prefix:         input.Prefix,
+		Scopes:         input.Scopes,
+		AllowedIPs:     input.AllowedIPs,
+		ExpiresAt:      input.ExpiresAt,
+		CreatedAt:      time.Now().UTC(),
+	})
+	response["key"] = key
+	respondJSON(w, http.StatusCreated, response)
+}
+
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	keys, err := h.apiKeys.ListByUser(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load api keys")
+		return
+	}
+	respondJSON(w, http.StatusOK, apiKeysResponse(keys))
+}
+
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	keyID := chi.URLParam(r, "id")
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		rows, err := h.apiKeys.Revoke(r.Context(), tx, userID, keyID)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return sql.ErrNoRows
+		}
+		data, _ := json.Marshal(map[string]string{
+			"ip":         middleware.RemoteIP(r),
+			"user_agent": r.UserAgent(),
+		})
+		return h.audit.Log(r.Context(), tx, userID, "revoke_api_key", "api_key", keyID, string(data))
+	})
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "api key not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to revoke api key")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiKeyResponse(key store.APIKey) map[string]any {
+	scopes, allowedIPs := []string(key.Scopes), []string(key.AllowedIPs)
+	if allowedIPs == nil {
+		allowedIPs = []string{}
+	}
+	response := map[string]any{
+		"id":           key.ID,
+		"name":         key.Name,
+		"prefix":       key.Prefix,
+		"scopes":       scopes,
+		"allowed_ips":  allowedIPs,
+		"expires_at":   key.ExpiresAt,
+		"last_used_at": key.LastUsedAt,
+		"last_used_ip": key.LastUsedIP,
+		"created_at":   key.CreatedAt,
+	}
+	if key.OrganizationID != nil {
+		response["organization_id"] = *key.OrganizationID
+		response["created_by"] = key.UserID
+	}
+	return response
+}
+
+func apiKeysResponse(keys []store.APIKey) []map[string]any {
+	normalized := make([]map[string]any, 0, len(keys))
+	for _, key := range keys {
+		normalized = append(normalized, apiKeyResponse(key))
+	}
+	return normalized
+}
+
+func parseAllowedIP(entry string) (string, bool) {
+	entry = strings.TrimSpace(entry)
+	if _, network, err := net.ParseCIDR(entry); err == nil {
+		return network.String(), true
+	}
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return "", false
+	}
+	if ip.To4() != nil {
+		return ip.String() + "/32", true
+	}
+	return ip.String() + "/128", true
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"banking/internal/auth"
+	"banking/internal/store"
+)
+
+func TestCreateAPIKeyReturnsKeyOnce(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	var input store.APIKeyInput
+	handler.apiKeys = stubAPIKeyStore{
+		createFn: func(_ context.Context, _ store.Execer, keyInput store.APIKeyInput) error {
+			input = keyInput
+			return nil
+		},
+	}
+	var action, entityID string
+	handler.audit = stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, _, auditAction, _, auditEntityID, _ string) error {
+			action, entityID = auditAction, auditEntityID
+			return nil
+		},
+	}
+	body := `{"name":"payroll","scopes":["read:accounts","write:transfers"],"allowed_ips":["10.0.0.7","192.0.2.0/24"]}`
+	rr := servePeriodRequest(t, handler.CreateAPIKey, http.MethodPost, "/api-keys", "/api-keys", body, "user-1")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var response struct {
+		ID         string   `json:"id"`
+		Key        string   `json:"key"`
+		Prefix     string   `json:"prefix"`
+		AllowedIPs []string `json:"allowed_ips"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if !strings.HasPrefix(response.Key, auth.APIKeyPrefix) || auth.HashToken(response.Key) != input.KeyHash || !strings.HasPrefix(response.Key, response.Prefix) {
+		t.Fatalf("returned key does not match what was stored: %#v %#v", response, input)
+	}
+	if input.UserID != "user-1" || strings.Join(input.AllowedIPs, ",") != "10.0.0.7/32,192.0.2.0/24" {
+		t.Fatalf("unexpected input: %#v", input)
+	}
+	if action != "create_api_key" || entityID != response.ID {
+		t.Fatalf("unexpected audit: %s %s", action, entityID)
+	}
+}
+
+func TestCreateAPIKeyValidates(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	for _, body := range []string{
+		`{"name":"","scopes":["read:accounts"]}`,
+		`{"name":"job","scopes":[]}`,
+		`{"name":"job","scopes":["admin:everything"]}`,
+		`{"name":"job","scopes":["read:accounts"],"allowed_ips":["not-an-ip"]}`,
+		`{"name":"job","scopes":["read:accounts"],"expires_at":"2001-01-01T00:00:00Z"}`,
+	} {
+		rr := servePeriodRequest(t, handler.CreateAPIKey, http.MethodPost, "/api-keys", "/api-keys", body, "user-1")
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, rr.Code)
+		}
+	}
+}
+
+func TestRevokeAPIKeyNotFound(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.apiKeys = stubAPIKeyStore{
+		revokeFn: func(context.Context, store.Execer, string, string) (int64, error) { return 0, nil },
+	}
+	rr := servePeriodRequest(t, handler.RevokeAPIKey, http.MethodDelete, "/api-keys/{id}", "/api-keys/key-9", "", "user-1")
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestAPIKeysOnlyReachScopedRoutes(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.apiKeys = stubAPIKeyStore{
+		getFn: func(_ context.Context, keyHash string) (store.APIKey, error) {
+			if keyHash != auth.HashToken("bk_reader") {
+				t.Fatalf("unexpected key hash: %s", keyHash)
+			}
+			return store.APIKey{ID: "key-1", UserID: "user-1", Scopes: []string{auth.ScopeReadAccounts}}, nil
+		},
+	}
+	routes := handler.Routes()
+	cases := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/accounts", http.StatusOK},
+		{http.MethodGet, "/transactions", http.StatusForbidden},
+		{http.MethodGet, "/api-keys", http.StatusUnauthorized},
+		{http.MethodGet, "/organizations", http.StatusUnauthorized},
+		{http.MethodGet, "/auth/me", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("Authorization", "Bearer bk_reader")
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Fatalf("expected %d for %s %s, got %d: %s", tc.want, tc.method, tc.path, rr.Code, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,496 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/middleware"
+	"banking/internal/services"
+	"banking/internal/store"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	approvalPromoteAdmin  = "promote_admin"
+	approvalGrantRole     = "grant_role"
+	approvalAdjustment    = "adjustment"
+	approvalReversal      = "reversal"
+	approvalFreezeAccount = "freeze_account"
+	approvalLargeTransfer = "large_transfer"
+)
+
+var approvalPermissions = map[string]string{
+	approvalPromoteAdmin:  auth.PermissionApproveAdminChanges,
+	approvalGrantRole:     auth.PermissionApproveAdminChanges,
+	approvalAdjustment:    auth.PermissionPostAdjustments,
+	approvalReversal:      auth.PermissionPostAdjustments,
+	approvalFreezeAccount: auth.PermissionFreezeAccounts,
+	approvalLargeTransfer: auth.PermissionApproveTransfers,
+}
+
+var requesterPermissions = map[string]string{
+	approvalPromoteAdmin:  "",
+	approvalGrantRole:     "",
+	approvalAdjustment:    auth.PermissionPostAdjustments,
+	approvalReversal:      auth.PermissionPostAdjustments,
+	approvalFreezeAccount: auth.PermissionFreezeAccounts,
+}
+
+var (
+	errAccountAlreadyFrozen   = errors.New("account already frozen")
+	errApprovalNotPending     = errors.New("approval not pending")
+	errRequesterNotAuthorized = errors.New("requester not authorized")
+)
+
+// approvalExecError unwraps so WithTx still retries serialization failures.
+type approvalExecError struct {
+	err error
+}
+
+func (e approvalExecError) Error() string { return e.err.Error() }
+
+func (e approvalExecError) Unwrap() error { return e.err }
+
+type approvalPayload struct {
+	TargetUserID    string     `json:"target_user_id,omitempty"`
+	AdminUserID     string     `json:"admin_user_id,omitempty"`
+	Role            string     `json:"role,omitempty"`
+	AccountID       string     `json:"account_id,omitempty"`
+	TransactionID   string     `json:"transaction_id,omitempty"`
+	FromAccountID   string     `json:"from_account_id,omitempty"`
+	ToAccountID     string     `json:"to_account_id,omitempty"`
+	AmountMinor     int64      `json:"amount_minor,omitempty"`
+	Reason          string     `json:"reason,omitempty"`
+	EffectiveAt     *time.Time `json:"effective_at,omitempty"`
+	ClientRequestID *string    `json:"client_request_id,omitempty"`
+}
+
+func (h *Handler) needsApproval(action string) bool {
+	for _, configured := range h.cfg.ApprovalActions {
+		if configured == action {
+			return true
+		}
+	}
+	return false
+}
+
+// needsAdminApproval lets admin changes through while no other admin could
+// approve them, so a lone super admin can still bring in the second one.
+func (h *Handler) needsAdminApproval(ctx context.Context, userID, action string) (bool, error) {
+	if !h.needsApproval(action) {
+		return false, nil
+	}
+	return h.admin.HasOtherApprover(ctx, userID, approvalPermissions[action])
+}
+
+func (h *Handler) requestApproval(w http.ResponseWriter, r *http.Request, userID, action string, payload approvalPayload) {
+	data, _ := json.Marshal(payload)
+	input := store.ApprovalInput{
+		ID:          uuid.NewString(),
+		Action:      action,
+		Payload:     string(data),
+		RequestedBy: userID,
+		ExpiresAt:   time.Now().Add(h.cfg.ApprovalTTL),
+	}
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		if err := h.approvals.Create(r.Context(), tx, input); err != nil {
+			return err
+		}
+		return h.audit.Log(r.Context(), tx, userID, "request_approval", "approval_request", input.ID, string(data))
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to request approval")
+		return
+	}
+	respondJSON(w, http.StatusAccepted, map[string]any{
+		"approval_id": input.ID,
+		"action":      action,
+		"status":      store.ApprovalPending,
+		"expires_at":  input.ExpiresAt,
+	})
+}
+
+func (h *Handler) expireApprovals(ctx context.Context) error {
+	return h.txRunner.WithTx(ctx, func(tx *sqlx.Tx) error {
+		ids, err := h.approvals.ExpirePending(ctx, tx)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := h.audit.Log(ctx, tx, "", "approval_expired", "approval_request", id, "{}"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (h *Handler) ListApprovals(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	status := query.Get("status")
+	switch status {
+	case "", store.ApprovalPending, store.ApprovalApproved, store.ApprovalRejected, store.ApprovalExpired, store.ApprovalExecuted, store.ApprovalFailed:
+	default:
+		respondError(w, http.StatusBadRequest, "invalid status")
+		return
+	}
+	if err := h.expireApprovals(r.Context()); err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load approvals")
+		return
+	}
+	limit := parseInt(query.Get("limit"), 50)
+	page := parseInt(query.Get("page"), 1)
+	offset := (page - 1) * limit
+	approvals, err := h.approvals.List(r.Context(), status, limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load approvals")
+		return
+	}
+	respondJSON(w, http.StatusOK, approvalsResponse(approvals))
+}
+
+func (h *Handler) ListMyApprovals(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if err := h.expireApprovals(r.Context()); err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load approvals")
+		return
+	}
+	query := r.URL.Query()
+	limit := parseInt(query.Get("limit"), 50)
+	page := parseInt(query.Get("page"), 1)
+	offset := (page - 1) * limit
+	approvals, err := h.approvals.ListByRequester(r.Context(), userID, limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load approvals")
+		return
+	}
+	respondJSON(w, http.StatusOK, approvalsResponse(approvals))
+}
+
+func (h *Handler) GetApproval(w http.ResponseWriter, r *http.Request) {
+	if err := h.expireApprovals(r.Context()); err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load approval")
+		return
+	}
+	approval, err := h.approvals.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(w, http.StatusNotFound, "approval not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "unable to load approval")
+		return
+	}
+	respondJSON(w, http.StatusOK, approvalResponse(approval))
+}
+
+func (h *Handler) ApproveRequest(w http.ResponseWriter, r *http.Request) {
+	approval, payload, userID, ok := h.loadDecidableApproval(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	err := h.txRunner.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := h.decideApproval(ctx, tx, approval.ID, userID, store.ApprovalApproved, "", "approve_request"); err != nil {
+			return err
+		}
+		result, err := h.executeApproval(ctx, tx, approval, payload)
+		if err != nil {
+			return approvalExecError{err: err}
+		}
+		data, _ := json.Marshal(result)
+		return h.completeApproval(ctx, tx, approval, userID, store.ApprovalExecuted, string(data), "")
+	})
+	var execErr approvalExecError
+	if errors.As(err, &execErr) {
+		err = h.txRunner.WithTx(ctx, func(tx *sqlx.Tx) error {
+			if err := h.decideApproval(ctx, tx, approval.ID, userID, store.ApprovalApproved, "", "approve_request"); err != nil {
+				return err
+			}
+			return h.completeApproval(ctx, tx, approval, userID, store.ApprovalFailed, "", approvalFailure(execErr.err))
+		})
+	}
+	if errors.Is(err, errApprovalNotPending) {
+		respondError(w, http.StatusConflict, "approval_not_pending")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to approve request")
+		return
+	}
+	if execErr.err == nil {
+		switch approval.Action {
+		case approvalPromoteAdmin:
+			h.adminAccess.Invalidate(payload.TargetUserID)
+		case approvalGrantRole:
+			h.adminAccess.Invalidate(payload.AdminUserID)
+		}
+	}
+	h.respondApproval(w, r, approval.ID)
+}
+
+func (h *Handler) completeApproval(ctx context.Context, tx *sqlx.Tx, approval store.Approval, deciderID, status, result, message string) error {
+	if err := h.approvals.Complete(ctx, tx, approval.ID, status, result, message); err != nil {
+		return err
+	}
+	action := "approval_executed"
+	if status == store.ApprovalFailed {
+		action = "approval_failed"
+	}
+	data, _ := json.Marshal(map[string]string{"action": approval.Action, "error": message})
+	return h.audit.Log(ctx, tx, deciderID, action, "approval_request", approval.ID, string(data))
+}
+
+type rejectApprovalRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (h *Handler) RejectRequest(w http.ResponseWriter, r *http.Request) {
+	var req rejectApprovalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		respondError(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+	approval, _, userID, ok := h.loadDecidableApproval(w, r)
+	if !ok {
+		return
+	}
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		return h.decideApproval(r.Context(), tx, approval.ID, userID, store.ApprovalRejected, strings.TrimSpace(req.Reason), "reject_request")
+	})
+	if errors.Is(err, errApprovalNotPending) {
+		respondError(w, http.StatusConflict, "approval_not_pending")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to reject request")
+		return
+	}
+	h.respondApproval(w, r, approval.ID)
+}
+
+func (h *Handler) loadDecidableApproval(w http.ResponseWriter, r *http.Request) (store.Approval, approvalPayload, string, bool) {
+	var payload approvalPayload
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return store.Approval{}, payload, "", false
+	}
+	if err := h.expireApprovals(r.Context()); err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load approval")
+		return store.Approval{}, payload, "", false
+	}
+	approval, err := h.approvals.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(w, http.StatusNotFound, "approval not found")
+			return approval, payload, "", false
+		}
+		respondError(w, http.StatusInternalServerError, "unable to load approval")
+		return approval, payload, "", false
+	}
+	if approval.Status == store.ApprovalExpired {
+		respondError(w, http.StatusConflict, "approval_expired")
+		return approval, payload, "", false
+	}
+	if approval.Status != store.ApprovalPending {
+		respondError(w, http.StatusConflict, "approval_not_pending")
+		return approval, payload, "", false
+	}
+	if err := json.Unmarshal([]byte(approval.Payload), &payload); err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load approval")
+		return approval, payload, "", false
+	}
+	if approval.RequestedBy == userID {
+		respondError(w, http.StatusForbidden, "cannot_approve_own_request")
+		return approval, payload, "", false
+	}
+	if payload.TargetUserID == userID || payload.AdminUserID == userID {
+		respondError(w, http.StatusForbidden, "cannot_approve_own_elevation")
+		return approval, payload, "", false
+	}
+	access, err := h.adminAccess.Access(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to verify admin")
+		return approval, payload, "", false
+	}
+	permission, known := approvalPermissions[approval.Action]
+	if !known || !access.Can(permission) {
+		respondError(w, http.StatusForbidden, "missing required permission")
+		return approval, payload, "", false
+	}
+	return approval, payload, userID, true
+}
+
+func (h *Handler) decideApproval(ctx context.Context, tx *sqlx.Tx, id, deciderID, status, reason, auditAction string) error {
+	updated, err := h.approvals.Decide(ctx, tx, id, deciderID, status, reason)
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return errApprovalNotPending
+	}
+	data, _ := json.Marshal(map[string]string{"reason": reason})
+	return h.audit.Log(ctx, tx, deciderID, auditAction, "approval_request", id, string(data))
+}
+
+// executeApproval runs the action as its requester, who must still hold the
+// permission its endpoint needs.
+func (h *Handler) executeApproval(ctx context.Context, tx *sqlx.Tx, approval store.Approval, payload approvalPayload) (map[string]any, error) {
+	actorID := approval.RequestedBy
+	if permission, ok := requesterPermissions[approval.Action]; ok {
+		access, err := h.adminAccess.Access(ctx, actorID)
+		if err != nil {
+			return nil, err
+		}
+		if !access.Can(permission) {
+			return nil, errRequesterNotAuthorized
+		}
+	}
+	switch approval.Action {
+	case approvalPromoteAdmin:
+		if err := h.promoteAdmin(ctx, tx, actorID, payload.TargetUserID); err != nil {
+			return nil, err
+		}
+		return map[string]any{"target_user_id": payload.TargetUserID}, nil
+	case approvalGrantRole:
+		if err := h.grantRole(ctx, tx, actorID, payload.AdminUserID, payload.Role); err != nil {
+			return nil, err
+		}
+		return map[string]any{"admin_user_id": payload.AdminUserID, "role": payload.Role}, nil
+	case approvalAdjustment:
+		transactionID, err := h.service.AdjustTx(ctx, tx, services.AdjustmentRequest{
+			ActorID:         actorID,
+			AccountID:       payload.AccountID,
+			AmountMinor:     payload.AmountMinor,
+			Reason:          payload.Reason,
+			EffectiveAt:     payload.EffectiveAt,
+			ClientRequestID: payload.ClientRequestID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"transaction_id": transactionID}, nil
+	case approvalReversal:
+		transactionID, err := h.service.ReverseTx(ctx, tx, services.ReversalRequest{
+			ActorID:       actorID,
+			TransactionID: payload.TransactionID,
+			Reason:        payload.Reason,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"transaction_id": transactionID, "reversal_of": payload.TransactionID}, nil
+	case approvalFreezeAccount:
+		account, err := h.accounts.GetByID(ctx, payload.AccountID)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := h.updateAccountFrozen(ctx, tx, actorID, account, true, payload.Reason)
+		if err != nil {
+			return nil, err
+		}
+		if updated == 0 {
+			return nil, errAccountAlreadyFrozen
+		}
+		return map[string]any{"account_id": payload.AccountID, "frozen": true}, nil
+	case approvalLargeTransfer:
+		transactionID, err := h.service.TransferTx(ctx, tx, services.TransferRequest{
+			UserID:          actorID,
+			FromAccountID:   payload.FromAccountID,
+			ToAccountID:     payload.ToAccountID,
+			AmountMinor:     payload.AmountMinor,
+			ClientRequestID: payload.ClientRequestID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"transaction_id": transactionID}, nil
+	}
+	return nil, errors.New("unknown approval action")
+}
+
+func approvalFailure(err error) string {
+	switch err {
+	case sql.ErrNoRows:
+		return "not_found"
+	case errAccountAlreadyFrozen:
+		return "account_already_frozen"
+	case errRequesterNotAuthorized:
+		return "requester_not_authorized"
+	case services.ErrInsufficientFunds:
+		return "insufficient_funds"
+	case services.ErrCurrencyMismatch:
+		return "currency_mismatch"
+	case services.ErrUnauthorizedAccount:
+		return "account_access_denied"
+	case services.ErrInvalidAmount:
+		return "invalid_amount"
+	case services.ErrPeriodClosed:
+		return "period_closed"
+	case services.ErrAccountFrozen:
+		return "account_frozen"
+	case services.ErrInvalidAdjustment:
+		return "invalid_adjustment"
+	case services.ErrInvalidEffectiveDate:
+		return "invalid_effective_at"
+	case services.ErrTransactionNotFound:
+		return "not_found"
+	case services.ErrAlreadyReversed:
+		return "already_reversed"
+	case services.ErrNotReversible:
+		return "not_reversible"
+	}
+	return "execution_failed"
+}
+
+func (h *Handler) respondApproval(w http.ResponseWriter, r *http.Request, id string) {
+	approval, err := h.approvals.Get(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load approval")
+		return
+	}
+	respondJSON(w, http.StatusOK, approvalResponse(approval))
+}
+
+func approvalsResponse(approvals []store.Approval) []map[string]any {
+	normalized := make([]map[string]any, 0, len(approvals))
+	for _, approval := range approvals {
+		normalized = append(normalized, approvalResponse(approval))
+	}
+	return normalized
+}
+
+func approvalResponse(approval store.Approval) map[string]any {
+	var result json.RawMessage
+	if approval.Result != nil {
+		result = json.RawMessage(*approval.Result)
+	}
+	return map[string]any{
+		"id":              approval.ID,
+		"action":          approval.Action,
+		"payload":         json.RawMessage(approval.Payload),
+		"requested_by":    approval.RequestedBy,
+		"status":          approval.Status,
+		"decided_by":      approval.DecidedBy,
+		"decided_at":      approval.DecidedAt,
+		"decision_reason": approval.DecisionReason,
+		"result":          result,
+		"error":           approval.Error,
+		"expires_at":      approval.ExpiresAt,
+		"created_at":      approval.CreatedAt,
+	}
+}
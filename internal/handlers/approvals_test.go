@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/services"
+	"banking/internal/store"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// approvalAdmins makes admin-1 the super admin, admin-2 an approver of admin
+// changes and transfers who may post adjustments, and admin-3 an admin
+// without permissions.
+func approvalAdmins() stubAdminStore {
+	return stubAdminStore{
+		isAdminFn: func(_ context.Context, userID string) (bool, bool, error) {
+			switch userID {
+			case "admin-1":
+				return true, true, nil
+			case "admin-2", "admin-3":
+				return true, false, nil
+			}
+			return false, false, nil
+		},
+		permissionsFn: func(_ context.Context, userID string) ([]string, error) {
+			if userID == "admin-2" {
+				return []string{auth.PermissionApproveAdminChanges, auth.PermissionApproveTransfers, auth.PermissionPostAdjustments}, nil
+			}
+			return nil, nil
+		},
+		approverFn: func(context.Context, string, string) (bool, error) {
+			return true, nil
+		},
+	}
+}
+
+// rollbackApprovals undoes approval changes made by a transaction that fails,
+// as the database would.
+func rollbackApprovals(approvals *stubApprovalStore) fakeTxRunner {
+	return fakeTxRunner{withTxFn: func(_ context.Context, fn func(*sqlx.Tx) error) error {
+		saved := make(map[string]store.Approval, len(approvals.approvals))
+		for id, approval := range approvals.approvals {
+			saved[id] = approval
+		}
+		err := fn(nil)
+		if err != nil {
+			approvals.approvals = saved
+		}
+		return err
+	}}
+}
+
+func decodeApproval(t *testing.T, body []byte) map[string]any {
+	t.Helper()
+	var approval map[string]any
+	if err := json.Unmarshal(body, &approval); err != nil {
+		t.Fatalf("unexpected response: %s", body)
+	}
+	return approval
+}
+
+func TestPromoteAdminWaitsForApproval(t *testing.T) {
+	admin := approvalAdmins()
+	var promotedBy *string
+	admin.createAdminFn = func(_ context.Context, _ store.Execer, userID string, _ bool, createdBy *string) error {
+		if userID != "user-2" {
+			t.Fatalf("unexpected promotion: %s", userID)
+		}
+		promotedBy = createdBy
+		return nil
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByUsernameFn: func(context.Context, string) (map[string]any, error) {
+			return map[string]any{"id": "user-2"}, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, admin, stubAuditStore{}, stubService{})
+	handler.cfg.ApprovalActions = []string{approvalPromoteAdmin}
+	handler.cfg.ApprovalTTL = time.Hour
+	var actions []string
+	handler.audit = stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, actorID, action, _, _, _ string) error {
+			actions = append(actions, actorID+":"+action)
+			return nil
+		},
+	}
+
+	rr := servePeriodRequest(t, handler.PromoteAdmin, http.MethodPost, "/admin/promote", "/admin/promote", `{"identifier":"bob"}`, "admin-1")
+	if rr.Code != http.StatusAccepted || promotedBy != nil {
+		t.Fatalf("expected a pending request, got %d: %s", rr.Code, rr.Body.String())
+	}
+	id, _ := decodeApproval(t, rr.Body.Bytes())["approval_id"].(string)
+
+	cases := []struct {
+		userID string
+		want   int
+	}{
+		{"admin-1", http.StatusForbidden},
+		{"admin-3", http.StatusForbidden},
+		{"admin-2", http.StatusOK},
+		{"admin-1", http.StatusConflict},
+	}
+	for _, tc := range cases {
+		rr = servePeriodRequest(t, handler.ApproveRequest, http.MethodPost, "/admin/approvals/{id}/approve", "/admin/approvals/"+id+"/approve", "", tc.userID)
+		if rr.Code != tc.want {
+			t.Fatalf("expected %d approving as %s, got %d: %s", tc.want, tc.userID, rr.Code, rr.Body.String())
+		}
+	}
+	if promotedBy == nil || *promotedBy != "admin-1" {
+		t.Fatalf("expected the promotion to run as the requester, got %v", promotedBy)
+	}
+	want := []string{"admin-1:request_approval", "admin-2:approve_request", "admin-1:promote_admin", "admin-2:approval_executed"}
+	if len(actions) != len(want) {
+		t.Fatalf("unexpected audit trail: %v", actions)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Fatalf("unexpected audit trail: %v", actions)
+		}
+	}
+}
+
+func TestLoneSuperAdminPromotesWithoutApproval(t *testing.T) {
+	admin := approvalAdmins()
+	var promoted string
+	admin.createAdminFn = func(_ context.Context, _ store.Execer, userID string, _ bool, _ *string) error {
+		promoted = userID
+		return nil
+	}
+	admin.approverFn = func(_ context.Context, userID, permission string) (bool, error) {
+		if userID != "admin-1" || permission != auth.PermissionApproveAdminChanges {
+			t.Fatalf("unexpected approver lookup: %s %s", userID, permission)
+		}
+		return false, nil
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByUsernameFn: func(context.Context, string) (map[string]any, error) {
+			return map[string]any{"id": "user-2"}, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, admin, stubAuditStore{}, stubService{})
+	handler.cfg.ApprovalActions = []string{approvalPromoteAdmin}
+	rr := servePeriodRequest(t, handler.PromoteAdmin, http.MethodPost, "/admin/promote", "/admin/promote", `{"identifier":"bob"}`, "admin-1")
+	if rr.Code != http.StatusCreated || promoted != "user-2" {
+		t.Fatalf("expected the promotion to run right away, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestApproveOwnElevation(t *testing.T) {
+	approvals := &stubApprovalStore{}
+	approvals.Create(context.Background(), nil, store.ApprovalInput{
+		ID: "apr-1", Action: approvalGrantRole, Payload: `{"admin_user_id":"admin-2","role":"treasury"}`, RequestedBy: "admin-1", ExpiresAt: time.Now().Add(time.Hour),
+	})
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, approvalAdmins(), stubAuditStore{}, stubService{})
+	handler.approvals = approvals
+	rr := servePeriodRequest(t, handler.ApproveRequest, http.MethodPost, "/admin/approvals/{id}/approve", "/admin/approvals/apr-1/approve", "", "admin-2")
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+	if approvals.approvals["apr-1"].Status != store.ApprovalPending {
+		t.Fatalf("request should stay pending: %#v", approvals.approvals["apr-1"])
+	}
+}
+
+func TestRejectApprovalRequiresReason(t *testing.T) {
+	approvals := &stubApprovalStore{}
+	approvals.Create(context.Background(), nil, store.ApprovalInput{
+		ID: "apr-1", Action: approvalGrantRole, Payload: `{"admin_user_id":"admin-3","role":"treasury"}`, RequestedBy: "admin-1", ExpiresAt: time.Now().Add(time.Hour),
+	})
+	admin := approvalAdmins()
+	admin.grantRoleFn = func(context.Context, store.Execer, string, string, string) error {
+		t.Fatalf("rejected grants must not run")
+		return nil
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, admin, stubAuditStore{}, stubService{})
+	handler.approvals = approvals
+	rr := servePeriodRequest(t, handler.RejectRequest, http.MethodPost, "/admin/approvals/{id}/reject", "/admin/approvals/apr-1/reject", `{"reason":" "}`, "admin-2")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	rr = servePeriodRequest(t, handler.RejectRequest, http.MethodPost, "/admin/approvals/{id}/reject", "/admin/approvals/apr-1/reject", `{"reason":"not on the rota"}`, "admin-2")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	approval := decodeApproval(t, rr.Body.Bytes())
+	if approval["status"] != store.ApprovalRejected || approval["decision_reason"] != "not on the rota" || approval["decided_by"] != "admin-2" {
+		t.Fatalf("unexpected approval: %#v", approval)
+	}
+}
+
+func TestApproveExpiredRequest(t *testing.T) {
+	approvals := &stubApprovalStore{}
+	approvals.Create(context.Background(), nil, store.ApprovalInput{
+		ID: "apr-1", Action: approvalPromoteAdmin, Payload: `{"target_user_id":"user-2"}`, RequestedBy: "admin-1", ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, approvalAdmins(), stubAuditStore{}, stubService{})
+	handler.approvals = approvals
+	var expired []string
+	handler.audit = stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, _, action, _, entityID, _ string) error {
+			if action == "approval_expired" {
+				expired = append(expired, entityID)
+			}
+			return nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.ApproveRequest, http.MethodPost, "/admin/approvals/{id}/approve", "/admin/approvals/apr-1/approve", "", "admin-2")
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+	if len(expired) != 1 || expired[0] != "apr-1" || approvals.approvals["apr-1"].Status != store.ApprovalExpired {
+		t.Fatalf("expected the request to be expired and audited: %v %#v", expired, approvals.approvals["apr-1"])
+	}
+}
+
+func TestReversalWaitsForApproval(t *testing.T) {
+	var reversedBy string
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, approvalAdmins(), stubAuditStore{}, stubService{
+		reverseFn: func(_ context.Context, req services.ReversalRequest) (string, error) {
+			if req.TransactionID != "tx-1" || req.Reason != "sent in error" {
+				t.Fatalf("unexpected reversal: %#v", req)
+			}
+			reversedBy = req.ActorID
+			return "tx-r", nil
+		},
+	})
+	handler.cfg.ApprovalActions = []string{approvalReversal}
+	handler.cfg.ApprovalTTL = time.Hour
+
+	rr := servePeriodRequest(t, handler.ReverseTransaction, http.MethodPost, "/admin/transactions/{id}/reverse", "/admin/transactions/tx-1/reverse", `{"reason":"sent in error"}`, "admin-1")
+	if rr.Code != http.StatusAccepted || reversedBy != "" {
+		t.Fatalf("expected a pending request, got %d: %s", rr.Code, rr.Body.String())
+	}
+	id, _ := decodeApproval(t, rr.Body.Bytes())["approval_id"].(string)
+
+	rr = servePeriodRequest(t, handler.ApproveRequest, http.MethodPost, "/admin/approvals/{id}/approve", "/admin/approvals/"+id+"/approve", "", "admin-2")
+	if rr.Code != http.StatusOK || reversedBy != "admin-1" {
+		t.Fatalf("expected the reversal to run as the requester, got %d: %s", rr.Code, rr.Body.String())
+	}
+	approval := decodeApproval(t, rr.Body.Bytes())
+	result, _ := approval["result"].(map[string]any)
+	if approval["status"] != store.ApprovalExecuted || result["transaction_id"] != "tx-r" || result["reversal_of"] != "tx-1" {
+		t.Fatalf("unexpected approval: %#v", approval)
+	}
+}
+
+func TestApprovedAdjustmentRecordsFailure(t *testing.T) {
+	approvals := &stubApprovalStore{}
+	approvals.Create(context.Background(), nil, store.ApprovalInput{
+		ID: "apr-1", Action: approvalAdjustment, Payload: `{"account_id":"acc-1","amount_minor":-250,"reason":"fee reversal"}`, RequestedBy: "admin-2", ExpiresAt: time.Now().Add(time.Hour),
+	})
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, approvalAdmins(), stubAuditStore{}, stubService{
+		adjustFn: func(_ context.Context, req services.AdjustmentRequest) (string, error) {
+			if req.ActorID != "admin-2" || req.AccountID != "acc-1" || req.AmountMinor != -250 {
+				t.Fatalf("unexpected adjustment: %#v", req)
+			}
+			return "", services.ErrPeriodClosed
+		},
+	})
+	handler.approvals = approvals
+	handler.txRunner = rollbackApprovals(approvals)
+	var actions []string
+	handler.audit = stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, actorID, action, _, _, _ string) error {
+			actions = append(actions, actorID+":"+action)
+			return nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.ApproveRequest, http.MethodPost, "/admin/approvals/{id}/approve", "/admin/approvals/apr-1/approve", "", "admin-1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	approval := decodeApproval(t, rr.Body.Bytes())
+	if approval["status"] != store.ApprovalFailed || approval["error"] != "period_closed" {
+		t.Fatalf("unexpected approval: %#v", approval)
+	}
+	want := []string{"admin-1:approve_request", "admin-1:approve_request", "admin-1:approval_failed"}
+	if len(actions) != len(want) || actions[1] != want[1] || actions[2] != want[2] {
+		t.Fatalf("unexpected audit trail: %v", actions)
+	}
+}
+
+func TestApprovalFailsWhenRequesterLostPermission(t *testing.T) {
+	approvals := &stubApprovalStore{}
+	approvals.Create(context.Background(), nil, store.ApprovalInput{
+		ID: "apr-1", Action: approvalAdjustment, Payload: `{"account_id":"acc-1","amount_minor":-250,"reason":"fee reversal"}`, RequestedBy: "admin-3", ExpiresAt: time.Now().Add(time.Hour),
+	})
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, approvalAdmins(), stubAuditStore{}, stubService{
+		adjustFn: func(context.Context, services.AdjustmentRequest) (string, error) {
+			t.Fatalf("the adjustment must not run")
+			return "", nil
+		},
+	})
+	handler.approvals = approvals
+	handler.txRunner = rollbackApprovals(approvals)
+	rr := servePeriodRequest(t, handler.ApproveRequest, http.MethodPost, "/admin/approvals/{id}/approve", "/admin/approvals/apr-1/approve", "", "admin-2")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	approval := decodeApproval(t, rr.Body.Bytes())
+	if approval["status"] != store.ApprovalFailed || approval["error"] != "requester_not_authorized" {
+		t.Fatalf("unexpected approval: %#v", approval)
+	}
+}
+
+func TestLargeTransferWaitsForApproval(t *testing.T) {
+	owner := "user-1"
+	transfers := 0
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{
+		getByIDFn: func(context.Context, string) (store.Account, error) {
+			return store.Account{ID: "a1", UserID: &owner, Currency: "USD"}, nil
+		},
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, approvalAdmins(), stubAuditStore{}, stubService{
+		transferFn: func(_ context.Context, req services.TransferRequest) (string, error) {
+			if req.UserID != "user-1" || req.ToAccountID != "a2" {
+				t.Fatalf("unexpected transfer: %#v", req)
+			}
+			transfers++
+			return "tx-1", nil
+		},
+	})
+	handler.cfg.ApprovalActions = []string{approvalLargeTransfer}
+	handler.cfg.ApprovalTTL = time.Hour
+	handler.cfg.ApprovalTransferThresholdMinor = 100000
+	handler.cfg.StepUpThresholdMinor = 1000000
+
+	rr := servePeriodRequest(t, handler.Transfer, http.MethodPost, "/transactions/transfer", "/transactions/transfer", `{"from_account_id":"a1","to_account_id":"a2","amount":"10.00","confirm":true}`, "user-1")
+	if rr.Code != http.StatusCreated || transfers != 1 {
+		t.Fatalf("small transfers should run, got %d", rr.Code)
+	}
+	rr = servePeriodRequest(t, handler.Transfer, http.MethodPost, "/transactions/transfer", "/transactions/transfer", `{"from_account_id":"a1","to_account_id":"a2","amount":"5000.00","confirm":true}`, "user-1")
+	if rr.Code != http.StatusAccepted || transfers != 1 {
+		t.Fatalf("expected a pending request, got %d: %s", rr.Code, rr.Body.String())
+	}
+	id, _ := decodeApproval(t, rr.Body.Bytes())["approval_id"].(string)
+
+	rr = servePeriodRequest(t, handler.ListMyApprovals, http.MethodGet, "/transactions/approvals", "/transactions/approvals", "", "user-1")
+	var mine []map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &mine); err != nil || len(mine) != 1 || mine[0]["status"] != store.ApprovalPending {
+		t.Fatalf("unexpected approvals: %s", rr.Body.String())
+	}
+
+	rr = servePeriodRequest(t, handler.ApproveRequest, http.MethodPost, "/admin/approvals/{id}/approve", "/admin/approvals/"+id+"/approve", "", "admin-2")
+	if rr.Code != http.StatusOK || transfers != 2 {
+		t.Fatalf("expected the transfer to run, got %d: %s", rr.Code, rr.Body.String())
+	}
+	approval := decodeApproval(t, rr.Body.Bytes())
+	result, _ := approval["result"].(map[string]any)
+	if approval["status"] != store.ApprovalExecuted || result["transaction_id"] != "tx-1" {
+		t.Fatalf("unexpected approval: %#v", approval)
+	}
+}
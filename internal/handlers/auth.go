@@ -4,9 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"banking/internal/auth"
+	"banking/internal/events"
 	"banking/internal/middleware"
 	"banking/internal/store"
 	"banking/internal/validator"
@@ -46,6 +49,7 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	userID := uuid.NewString()
+	var sessionID, refreshToken string
 	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
 		if err := h.users.Create(r.Context(), tx, userID, req.Username, req.Email, passwordHash); err != nil {
 			return err
@@ -75,10 +79,31 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		}
 		data, _ := json.Marshal(map[string]string{
 			"user_id":    userID,
-			"ip":         r.RemoteAddr,
+			"ip":         middleware.RemoteIP(r),
 			"user_agent": r.UserAgent(),
 		})
-		return h.audit.Log(r.Context(), tx, userID, "register", "user", userID, string(data))
+		if err := h.audit.Log(r.Context(), tx, userID, "register", "user", userID, string(data)); err != nil {
+			return err
+		}
+		if sessionID, refreshToken, err = h.startSession(r.Context(), tx, r, userID); err != nil {
+			return err
+		}
+		if err := h.outbox.Append(r.Context(), tx, events.New(events.UserRegistered, events.AggregateUser, userID, &userID, map[string]any{
+			"user_id":  userID,
+			"username": req.Username,
+			"email":    req.Email,
+			"accounts": []map[string]string{
+				{"account_id": usdAccountID, "currency": "USD"},
+				{"account_id": eurAccountID, "currency": "EUR"},
+			},
+		})); err != nil {
+			return err
+		}
+		verifyRef, err := h.issueUserToken(r.Context(), tx, userID, req.Email, store.TokenVerifyEmail, h.cfg.EmailVerificationTTL)
+		if err != nil || verifyRef == "" {
+			return err
+		}
+		return h.queueTokenMail(r.Context(), tx, events.EmailVerificationRequested, userID, verifyRef, h.cfg.EmailVerificationTTL)
 	})
 	if err != nil {
 		if pgErr, ok := err.(*pq.Error); ok {
@@ -90,14 +115,7 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "registration failed")
 		return
 	}
-	token, err := auth.GenerateToken(h.cfg.JWTSecret, userID, h.cfg.TokenTTL)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to generate token")
-		return
-	}
-	respondJSON(w, http.StatusCreated, map[string]string{
-		"token": token,
-	})
+	h.respondSession(w, http.StatusCreated, userID, sessionID, refreshToken)
 }
 
 func (h *Handler) createOpeningBalance(ctx context.Context, tx *sqlx.Tx, userID, accountID, currency string, amount int64) error {
@@ -136,13 +154,10 @@ func (h *Handler) createOpeningBalance(ctx context.Context, tx *sqlx.Tx, userID,
 			Description:   "Opening balance credit",
 		},
 	}
-	if err := h.ledger.InsertEntries(ctx, tx, entries); err != nil {
-		return err
-	}
 	if _, err := h.accounts.AdjustBalance(ctx, tx, systemAccountID, -amount); err != nil {
 		return err
 	}
-	return nil
+	return h.ledger.InsertEntries(ctx, tx, entries)
 }
 
 type loginRequest struct {
@@ -156,37 +171,303 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "invalid payload")
 		return
 	}
+	email := loginThrottleKey(req.Email)
+	attempt, ok := h.reserveLogin(w, r, "", email)
+	if !ok {
+		return
+	}
 	user, err := h.users.GetByEmail(r.Context(), req.Email)
+	if err == sql.ErrNoRows {
+		// Unknown emails cost the same bcrypt check and are throttled and
+		// audited like known ones, so the response gives nothing away.
+		auth.CheckDummyPassword(req.Password)
+		h.loginFailed(w, r, attempt, "", "unknown_email")
+		return
+	}
 	if err != nil {
-		if err == sql.ErrNoRows {
-			respondError(w, http.StatusUnauthorized, "invalid credentials")
-			return
-		}
 		respondError(w, http.StatusInternalServerError, "login failed")
 		return
 	}
+	userID := valueToString(user["id"])
 	if !auth.CheckPassword(valueToString(user["password_hash"]), req.Password) {
-		respondError(w, http.StatusUnauthorized, "invalid credentials")
+		h.loginFailed(w, r, attempt, userID, "invalid_password")
+		return
+	}
+	totp, err := h.twoFactor.GetTOTP(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "login failed")
+		return
+	}
+	if totp.Enabled {
+		if err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+			return h.releaseLogin(r.Context(), tx, attempt)
+		}); err != nil {
+			respondError(w, http.StatusInternalServerError, "login failed")
+			return
+		}
+		h.startLoginChallenge(w, r, userID)
 		return
 	}
+	h.completeLogin(w, r, attempt, userID, "password")
+}
+
+func (h *Handler) completeLogin(w http.ResponseWriter, r *http.Request, attempt loginAttempt, userID, method string) {
+	var sessionID, refreshToken string
 	if err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		if _, err := h.throttles.Reset(r.Context(), tx, store.LoginThrottleEmail, attempt.email); err != nil {
+			return err
+		}
+		if err := h.throttles.Release(r.Context(), tx, store.LoginThrottleIP, attempt.ip, attempt.address, attempt.addrPrev); err != nil {
+			return err
+		}
 		data, _ := json.Marshal(map[string]string{
-			"user_id":    valueToString(user["id"]),
-			"ip":         r.RemoteAddr,
+			"user_id":    userID,
+			"method":     method,
+			"ip":         middleware.RemoteIP(r),
 			"user_agent": r.UserAgent(),
 		})
-		return h.audit.Log(r.Context(), tx, valueToString(user["id"]), "login", "user", valueToString(user["id"]), string(data))
+		if err := h.audit.Log(r.Context(), tx, userID, "login", "user", userID, string(data)); err != nil {
+			return err
+		}
+		session, token, err := h.startSession(r.Context(), tx, r, userID)
+		if err != nil {
+			return err
+		}
+		sessionID, refreshToken = session, token
+		isNew, err := h.devices.Touch(r.Context(), tx, userID, auth.HashToken(r.UserAgent()), r.UserAgent())
+		if err != nil || !isNew {
+			return err
+		}
+		return h.outbox.Append(r.Context(), tx, events.New(events.NewDeviceLogin, events.AggregateUser, userID, &userID, map[string]string{
+			"user_id":    userID,
+			"session_id": sessionID,
+			"device":     auth.DeviceLabel(r.UserAgent()),
+			"ip":         middleware.RemoteIP(r),
+			"user_agent": r.UserAgent(),
+		}))
 	}); err != nil {
 		respondError(w, http.StatusInternalServerError, "login failed")
 		return
 	}
-	token, err := auth.GenerateToken(h.cfg.JWTSecret, valueToString(user["id"]), h.cfg.TokenTTL)
+	h.respondSession(w, http.StatusOK, userID, sessionID, refreshToken)
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req changePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if err := validator.ValidatePassword(req.NewPassword); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	user, err := h.users.GetByID(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load user")
+		return
+	}
+	if !auth.CheckPassword(valueToString(user["password_hash"]), req.CurrentPassword) {
+		respondError(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+	passwordHash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to secure password")
+		return
+	}
+	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		if _, err := h.users.UpdatePassword(r.Context(), tx, userID, passwordHash); err != nil {
+			return err
+		}
+		data, _ := json.Marshal(map[string]string{
+			"user_id":    userID,
+			"ip":         middleware.RemoteIP(r),
+			"user_agent": r.UserAgent(),
+		})
+		if err := h.audit.Log(r.Context(), tx, userID, "change_password", "user", userID, string(data)); err != nil {
+			return err
+		}
+		return h.outbox.Append(r.Context(), tx, events.New(events.PasswordChanged, events.AggregateUser, userID, &userID, map[string]string{
+			"user_id":    userID,
+			"ip":         middleware.RemoteIP(r),
+			"user_agent": r.UserAgent(),
+		}))
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to change password")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Presenting a refresh token that was already swapped means it leaked, so the
+// whole session is revoked.
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		respondError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+	var userID, sessionID, refreshToken string
+	var reused bool
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		reused = false
+		token, err := h.sessions.GetRefreshToken(r.Context(), tx, auth.HashToken(req.RefreshToken))
+		if err != nil {
+			return err
+		}
+		userID, sessionID = token.UserID, token.SessionID
+		switch {
+		case token.SessionRevokedAt != nil, !token.ExpiresAt.After(time.Now()):
+			return errInvalidRefreshToken
+		case token.UsedAt != nil:
+			reused = true
+			_, err := h.revokeSession(r.Context(), tx, r, userID, sessionID, "refresh_token_reuse")
+			return err
+		}
+		if err := h.sessions.MarkRefreshTokenUsed(r.Context(), tx, token.TokenHash); err != nil {
+			return err
+		}
+		refreshToken, err = h.addRefreshToken(r.Context(), tx, userID, sessionID)
+		return err
+	})
+	if err == sql.ErrNoRows || errors.Is(err, errInvalidRefreshToken) {
+		respondError(w, http.StatusUnauthorized, "invalid refresh token")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to refresh session")
+		return
+	}
+	if reused {
+		h.endSessions(userID, sessionID)
+		respondError(w, http.StatusUnauthorized, "refresh token reused; session revoked")
+		return
+	}
+	h.respondSession(w, http.StatusOK, userID, sessionID, refreshToken)
+}
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	sessionID := middleware.SessionIDFromContext(r.Context())
+	if sessionID == "" {
+		respondError(w, http.StatusBadRequest, "token has no session")
+		return
+	}
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		_, err := h.revokeSession(r.Context(), tx, r, userID, sessionID, "logout")
+		return err
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to log out")
+		return
+	}
+	h.endSessions(userID, sessionID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) startSession(ctx context.Context, tx *sqlx.Tx, r *http.Request, userID string) (string, string, error) {
+	sessionID := uuid.NewString()
+	if err := h.sessions.CreateSession(ctx, tx, store.SessionInput{
+		ID:          sessionID,
+		UserID:      userID,
+		DeviceLabel: auth.DeviceLabel(r.UserAgent()),
+		IP:          middleware.RemoteIP(r),
+		UserAgent:   r.UserAgent(),
+	}); err != nil {
+		return "", "", err
+	}
+	refreshToken, err := h.addRefreshToken(ctx, tx, userID, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+	return sessionID, refreshToken, nil
+}
+
+func (h *Handler) addRefreshToken(ctx context.Context, tx *sqlx.Tx, userID, sessionID string) (string, error) {
+	refreshToken, err := auth.NewOpaqueToken("rt_")
+	if err != nil {
+		return "", err
+	}
+	err = h.sessions.AddRefreshToken(ctx, tx, store.RefreshTokenInput{
+		TokenHash: auth.HashToken(refreshToken),
+		SessionID: sessionID,
+		UserID:    userID,
+		TTL:       h.cfg.RefreshTokenTTL,
+	})
+	if err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+func (h *Handler) revokeSession(ctx context.Context, tx *sqlx.Tx, r *http.Request, userID, sessionID, reason string) (bool, error) {
+	revoked, err := h.sessions.Revoke(ctx, tx, userID, sessionID, reason)
+	if err != nil || !revoked {
+		return false, err
+	}
+	data, _ := json.Marshal(map[string]string{
+		"session_id": sessionID,
+		"reason":     reason,
+		"ip":         middleware.RemoteIP(r),
+		"user_agent": r.UserAgent(),
+	})
+	if err := h.audit.Log(ctx, tx, userID, "revoke_session", "session", sessionID, string(data)); err != nil {
+		return false, err
+	}
+	return true, h.appendSessionsRevoked(ctx, tx, userID, []string{sessionID}, reason)
+}
+
+func (h *Handler) appendSessionsRevoked(ctx context.Context, tx *sqlx.Tx, userID string, sessionIDs []string, reason string) error {
+	for _, sessionID := range sessionIDs {
+		if err := h.outbox.Append(ctx, tx, events.New(events.SessionRevoked, events.AggregateUser, userID, &userID, map[string]string{
+			"session_id": sessionID,
+			"reason":     reason,
+		})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// endSessions applies committed revocations on this instance at once; other
+// instances follow on their next reload and when SessionRevoked is dispatched.
+func (h *Handler) endSessions(userID string, sessionIDs ...string) {
+	for _, sessionID := range sessionIDs {
+		h.revocations.Revoke(sessionID)
+		h.hub.Disconnect(userID, sessionID)
+	}
+}
+
+func (h *Handler) respondSession(w http.ResponseWriter, status int, userID, sessionID, refreshToken string) {
+	token, err := auth.GenerateSessionToken(h.keys, userID, sessionID, h.cfg.TokenTTL)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to generate token")
 		return
 	}
-	respondJSON(w, http.StatusOK, map[string]string{
-		"token": token,
+	respondJSON(w, status, map[string]any{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(h.cfg.TokenTTL.Seconds()),
 	})
 }
 
@@ -201,10 +482,18 @@ func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "unable to load user")
 		return
 	}
+	verifiedAt := emailVerifiedAt(user)
 	respondJSON(w, http.StatusOK, map[string]any{
-		"id":         valueToString(user["id"]),
-		"username":   valueToString(user["username"]),
-		"email":      valueToString(user["email"]),
-		"created_at": user["created_at"],
+		"id":                valueToString(user["id"]),
+		"username":          valueToString(user["username"]),
+		"email":             valueToString(user["email"]),
+		"email_verified":    verifiedAt != nil,
+		"email_verified_at": verifiedAt,
+		"created_at":        user["created_at"],
 	})
 }
+
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondJSON(w, http.StatusOK, map[string]any{"keys": h.keys.JWKS()})
+}
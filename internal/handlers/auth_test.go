@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"banking/internal/auth"
+	"banking/internal/events"
 	"banking/internal/middleware"
 	"banking/internal/store"
 
@@ -74,8 +75,8 @@ func TestRegisterSuccess(t *testing.T) {
 		isAdminFn: func(context.Context, string) (bool, bool, error) {
 			return false, false, nil
 		},
-		hasRoleFn: func(context.Context, string, string) (bool, error) {
-			return false, nil
+		permissionsFn: func(context.Context, string) ([]string, error) {
+			return nil, nil
 		},
 	}, stubAuditStore{
 		logFn: func(context.Context, store.Execer, string, string, string, string, string) error {
@@ -85,6 +86,13 @@ func TestRegisterSuccess(t *testing.T) {
 			return nil, nil
 		},
 	}, stubService{})
+	var appended []store.DomainEventInput
+	handler.outbox = stubOutboxStore{
+		appendFn: func(_ context.Context, _ store.Execer, input store.DomainEventInput) error {
+			appended = append(appended, input)
+			return nil
+		},
+	}
 
 	body := []byte(`{"username":"alice","email":"alice@example.com","password":"pass1234"}`)
 	req := httptest.NewRequest(http.MethodPost, "/auth/register", bytes.NewReader(body))
@@ -93,16 +101,22 @@ func TestRegisterSuccess(t *testing.T) {
 	if rr.Code != http.StatusCreated {
 		t.Fatalf("expected 201, got %d", rr.Code)
 	}
-	var payload map[string]string
+	var payload map[string]any
 	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
 		t.Fatalf("failed to decode response: %v", err)
 	}
-	if payload["token"] == "" {
+	if payload["token"] == "" || payload["token"] == nil {
 		t.Fatalf("expected token")
 	}
+	if refreshToken, _ := payload["refresh_token"].(string); !strings.HasPrefix(refreshToken, "rt_") {
+		t.Fatalf("expected refresh token, got %#v", payload["refresh_token"])
+	}
 	if createdUsers != 1 || createdAccounts != 2 || createdAdmins != 1 {
 		t.Fatalf("unexpected create counts: users=%d accounts=%d admins=%d", createdUsers, createdAccounts, createdAdmins)
 	}
+	if len(appended) != 2 || appended[0].Type != events.UserRegistered || appended[0].UserID == nil || *appended[0].UserID != appended[0].AggregateID || appended[1].Type != events.EmailVerificationRequested {
+		t.Fatalf("unexpected events: %#v", appended)
+	}
 	if len(ledgerEntries) != 4 {
 		t.Fatalf("expected 4 ledger entries, got %d", len(ledgerEntries))
 	}
@@ -182,8 +196,8 @@ func TestRegisterDuplicateUser(t *testing.T) {
 		isAdminFn: func(context.Context, string) (bool, bool, error) {
 			return false, false, nil
 		},
-		hasRoleFn: func(context.Context, string, string) (bool, error) {
-			return false, nil
+		permissionsFn: func(context.Context, string) ([]string, error) {
+			return nil, nil
 		},
 	}, stubAuditStore{
 		logFn: func(context.Context, store.Execer, string, string, string, string, string) error {
@@ -228,8 +242,8 @@ func TestLoginSuccess(t *testing.T) {
 		isAdminFn: func(context.Context, string) (bool, bool, error) {
 			return false, false, nil
 		},
-		hasRoleFn: func(context.Context, string, string) (bool, error) {
-			return false, nil
+		permissionsFn: func(context.Context, string) ([]string, error) {
+			return nil, nil
 		},
 	}, stubAuditStore{
 		logFn: func(context.Context, store.Execer, string, string, string, string, string) error {
@@ -270,8 +284,8 @@ func TestLoginInvalidCredentials(t *testing.T) {
 		isAdminFn: func(context.Context, string) (bool, bool, error) {
 			return false, false, nil
 		},
-		hasRoleFn: func(context.Context, string, string) (bool, error) {
-			return false, nil
+		permissionsFn: func(context.Context, string) ([]string, error) {
+			return nil, nil
 		},
 	}, stubAuditStore{
 		logFn: func(context.Context, store.Execer, string, string, string, string, string) error {
@@ -312,8 +326,8 @@ func TestMe(t *testing.T) {
 		isAdminFn: func(context.Context, string) (bool, bool, error) {
 			return false, false, nil
 		},
-		hasRoleFn: func(context.Context, string, string) (bool, error) {
-			return false, nil
+		permissionsFn: func(context.Context, string) ([]string, error) {
+			return nil, nil
 		},
 	}, stubAuditStore{
 		logFn: func(context.Context, store.Execer, string, string, string, string, string) error {
@@ -324,14 +338,14 @@ func TestMe(t *testing.T) {
 		},
 	}, stubService{})
 
-	token, err := auth.GenerateToken("secret", "user-1", time.Minute)
+	token, err := auth.GenerateToken(testKeys, "user-1", time.Minute)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
 	req := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
-	middleware.Auth("secret")(http.HandlerFunc(handler.Me)).ServeHTTP(rr, req)
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.Me)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", rr.Code)
 	}
@@ -445,3 +459,330 @@ func newTestTxRunner(t *testing.T) (fakeTxRunner, *[]txExecRecord) {
 		},
 	}, &txExecRecords
 }
+
+func TestLoginFromNewDeviceEmitsEvent(t *testing.T) {
+	passwordHash, err := auth.HashPassword("pass1234")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByEmailFn: func(context.Context, string) (map[string]any, error) {
+			return map[string]any{"id": "user-1", "password_hash": passwordHash}, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	var deviceHash string
+	handler.devices = stubDeviceStore{
+		touchFn: func(_ context.Context, _ store.Getter, userID, hash, userAgent string) (bool, error) {
+			if userID != "user-1" || userAgent != "curl/8.0" {
+				t.Fatalf("unexpected device: %s %s", userID, userAgent)
+			}
+			deviceHash = hash
+			return true, nil
+		},
+	}
+	var emitted []store.DomainEventInput
+	handler.outbox = stubOutboxStore{
+		appendFn: func(_ context.Context, _ store.Execer, input store.DomainEventInput) error {
+			emitted = append(emitted, input)
+			return nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"alice@example.com","password":"pass1234"}`))
+	req.Header.Set("User-Agent", "curl/8.0")
+	rr := httptest.NewRecorder()
+	handler.Login(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if deviceHash != auth.HashToken("curl/8.0") {
+		t.Fatalf("unexpected device hash: %s", deviceHash)
+	}
+	if len(emitted) != 1 || emitted[0].Type != events.NewDeviceLogin || *emitted[0].UserID != "user-1" {
+		t.Fatalf("unexpected events: %#v", emitted)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	passwordHash, err := auth.HashPassword("pass1234")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	var newHash, audited string
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByIDFn: func(context.Context, string) (map[string]any, error) {
+			return map[string]any{"id": "user-1", "password_hash": passwordHash}, nil
+		},
+		updatePasswordFn: func(_ context.Context, _ store.Execer, userID, hash string) (int64, error) {
+			if userID != "user-1" {
+				t.Fatalf("unexpected user: %s", userID)
+			}
+			newHash = hash
+			return 1, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, _, action, _, _, _ string) error {
+			audited = action
+			return nil
+		},
+	}, stubService{})
+	var emitted []store.DomainEventInput
+	handler.outbox = stubOutboxStore{
+		appendFn: func(_ context.Context, _ store.Execer, input store.DomainEventInput) error {
+			emitted = append(emitted, input)
+			return nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.ChangePassword, http.MethodPost, "/auth/password", "/auth/password", `{"current_password":"pass1234","new_password":"newpass5678"}`, "user-1")
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !auth.CheckPassword(newHash, "newpass5678") {
+		t.Fatalf("expected new password to be stored")
+	}
+	if audited != "change_password" {
+		t.Fatalf("unexpected audit action: %s", audited)
+	}
+	if len(emitted) != 1 || emitted[0].Type != events.PasswordChanged {
+		t.Fatalf("unexpected events: %#v", emitted)
+	}
+}
+
+func TestChangePasswordRejectsWrongCurrentPassword(t *testing.T) {
+	passwordHash, err := auth.HashPassword("pass1234")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByIDFn: func(context.Context, string) (map[string]any, error) {
+			return map[string]any{"id": "user-1", "password_hash": passwordHash}, nil
+		},
+		updatePasswordFn: func(context.Context, store.Execer, string, string) (int64, error) {
+			t.Fatalf("password must not change")
+			return 0, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	rr := servePeriodRequest(t, handler.ChangePassword, http.MethodPost, "/auth/password", "/auth/password", `{"current_password":"wrong","new_password":"newpass5678"}`, "user-1")
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rr.Code)
+	}
+	rr = servePeriodRequest(t, handler.ChangePassword, http.MethodPost, "/auth/password", "/auth/password", `{"current_password":"pass1234","new_password":"short"}`, "user-1")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestLoginIssuesSessionTokens(t *testing.T) {
+	passwordHash, err := auth.HashPassword("pass1234")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByEmailFn: func(context.Context, string) (map[string]any, error) {
+			return map[string]any{"id": "user-1", "password_hash": passwordHash}, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	var stored store.RefreshTokenInput
+	handler.sessions = stubSessionStore{
+		addRefreshFn: func(_ context.Context, _ store.Execer, input store.RefreshTokenInput) error {
+			stored = input
+			return nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"alice@example.com","password":"pass1234"}`))
+	rr := httptest.NewRecorder()
+	handler.Login(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var payload struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	claims, err := auth.ParseToken(testKeys, payload.Token)
+	if err != nil {
+		t.Fatalf("invalid access token: %v", err)
+	}
+	if claims.ID == "" || claims.ID != stored.SessionID || stored.UserID != "user-1" {
+		t.Fatalf("expected jti to be the session, got %q and %#v", claims.ID, stored)
+	}
+	if stored.TokenHash != auth.HashToken(payload.RefreshToken) {
+		t.Fatalf("expected only the refresh token hash to be stored")
+	}
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	var used string
+	var added store.RefreshTokenInput
+	handler.sessions = stubSessionStore{
+		getRefreshTokenFn: func(_ context.Context, _ store.Getter, tokenHash string) (store.RefreshToken, error) {
+			if tokenHash != auth.HashToken("rt_old") {
+				t.Fatalf("unexpected hash: %s", tokenHash)
+			}
+			return store.RefreshToken{TokenHash: tokenHash, SessionID: "session-1", UserID: "user-1", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		},
+		markUsedFn: func(_ context.Context, _ store.Execer, tokenHash string) error {
+			used = tokenHash
+			return nil
+		},
+		addRefreshFn: func(_ context.Context, _ store.Execer, input store.RefreshTokenInput) error {
+			added = input
+			return nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(`{"refresh_token":"rt_old"}`))
+	rr := httptest.NewRecorder()
+	handler.Refresh(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var payload struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if used != auth.HashToken("rt_old") || added.SessionID != "session-1" || added.TokenHash != auth.HashToken(payload.RefreshToken) {
+		t.Fatalf("expected rotation within the session, got used=%s added=%#v", used, added)
+	}
+	claims, err := auth.ParseToken(testKeys, payload.Token)
+	if err != nil || claims.ID != "session-1" || claims.UserID != "user-1" {
+		t.Fatalf("unexpected access token: %#v %v", claims, err)
+	}
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.revocations = middleware.NewRevocations(nil, time.Minute)
+	usedAt := time.Now().Add(-time.Minute)
+	var revokedReason string
+	handler.sessions = stubSessionStore{
+		getRefreshTokenFn: func(context.Context, store.Getter, string) (store.RefreshToken, error) {
+			return store.RefreshToken{SessionID: "session-1", UserID: "user-1", ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt}, nil
+		},
+		addRefreshFn: func(context.Context, store.Execer, store.RefreshTokenInput) error {
+			t.Fatalf("expected no new refresh token")
+			return nil
+		},
+		revokeFn: func(_ context.Context, _ store.Getter, userID, sessionID, reason string) (bool, error) {
+			if userID != "user-1" || sessionID != "session-1" {
+				t.Fatalf("unexpected session: %s %s", userID, sessionID)
+			}
+			revokedReason = reason
+			return true, nil
+		},
+	}
+	var emitted []store.DomainEventInput
+	handler.outbox = stubOutboxStore{
+		appendFn: func(_ context.Context, _ store.Execer, input store.DomainEventInput) error {
+			emitted = append(emitted, input)
+			return nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(`{"refresh_token":"rt_stolen"}`))
+	rr := httptest.NewRecorder()
+	handler.Refresh(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rr.Code)
+	}
+	if revokedReason != "refresh_token_reuse" {
+		t.Fatalf("expected the session to be revoked, got %q", revokedReason)
+	}
+	if len(emitted) != 1 || emitted[0].Type != events.SessionRevoked || emitted[0].AggregateID != "user-1" || !strings.Contains(emitted[0].Payload, `"session_id":"session-1"`) {
+		t.Fatalf("unexpected events: %#v", emitted)
+	}
+	if !handler.revocations.Revoked("session-1") {
+		t.Fatalf("expected the session to be revoked locally")
+	}
+}
+
+func TestRefreshRejectsInvalidTokens(t *testing.T) {
+	revokedAt := time.Now()
+	cases := map[string]func(context.Context, store.Getter, string) (store.RefreshToken, error){
+		"unknown": func(context.Context, store.Getter, string) (store.RefreshToken, error) {
+			return store.RefreshToken{}, sql.ErrNoRows
+		},
+		"expired": func(context.Context, store.Getter, string) (store.RefreshToken, error) {
+			return store.RefreshToken{SessionID: "session-1", UserID: "user-1", ExpiresAt: time.Now().Add(-time.Second)}, nil
+		},
+		"revoked": func(context.Context, store.Getter, string) (store.RefreshToken, error) {
+			return store.RefreshToken{SessionID: "session-1", UserID: "user-1", ExpiresAt: time.Now().Add(time.Hour), SessionRevokedAt: &revokedAt}, nil
+		},
+	}
+	for name, getFn := range cases {
+		handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+		handler.sessions = stubSessionStore{
+			getRefreshTokenFn: getFn,
+			addRefreshFn: func(context.Context, store.Execer, store.RefreshTokenInput) error {
+				t.Fatalf("%s: expected no new refresh token", name)
+				return nil
+			},
+		}
+		req := httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(`{"refresh_token":"rt_x"}`))
+		rr := httptest.NewRecorder()
+		handler.Refresh(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: expected 401, got %d", name, rr.Code)
+		}
+	}
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.revocations = middleware.NewRevocations(nil, time.Minute)
+	var revoked string
+	handler.sessions = stubSessionStore{
+		revokeFn: func(_ context.Context, _ store.Getter, userID, sessionID, reason string) (bool, error) {
+			if userID != "user-1" || reason != "logout" {
+				t.Fatalf("unexpected revocation: %s %s", userID, reason)
+			}
+			revoked = sessionID
+			return true, nil
+		},
+	}
+	token, err := auth.GenerateSessionToken(testKeys, "user-1", "session-1", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	logout := middleware.Auth(testKeys, handler.revocations)(http.HandlerFunc(handler.Logout))
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	logout.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if revoked != "session-1" {
+		t.Fatalf("unexpected session revoked: %s", revoked)
+	}
+
+	rr = httptest.NewRecorder()
+	logout.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the revoked token to be rejected, got %d", rr.Code)
+	}
+}
+
+func TestRegisterIsRateLimitedPerIP(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.rateLimits = middleware.NewRateLimiter(middleware.NewMemoryRateLimitStore(), map[string]middleware.RateLimitPolicy{
+		"register": {Limit: 1, Window: time.Hour},
+	})
+	routes := handler.Routes()
+	for _, want := range []int{http.StatusBadRequest, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(`{}`))
+		req.RemoteAddr = "203.0.113.7:5000"
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Fatalf("expected %d, got %d: %s", want, rr.Code, rr.Body.String())
+		}
+		if rr.Header().Get("RateLimit-Limit") != "1" {
+			t.Fatalf("expected rate limit headers, got %v", rr.Header())
+		}
+	}
+}
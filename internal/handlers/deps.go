@@ -2,16 +2,64 @@ package handlers
 
 import (
 	"context"
+	"time"
 
+	"banking/internal/auth"
+	"banking/internal/config"
+	"banking/internal/db"
+	"banking/internal/middleware"
 	"banking/internal/services"
 	"banking/internal/store"
+	"banking/internal/websocket"
+
+	"github.com/jmoiron/sqlx"
 )
 
+type Deps struct {
+	ReconcileDB   store.Selecter
+	TxRunner      db.TxRunner
+	Config        config.Config
+	Users         UserStore
+	Accounts      AccountStore
+	Ledger        LedgerStore
+	Balances      BalanceStore
+	GL            GLStore
+	Transactions  TransactionStore
+	Exchange      ExchangeStore
+	Admin         AdminStore
+	Audit         AuditStore
+	Outbox        OutboxStore
+	Webhooks      WebhookStore
+	Streams       StreamStore
+	Quotes        QuoteStore
+	Tickets       WSTicketStore
+	Notifications NotificationStore
+	Devices       DeviceStore
+	Alerts        AlertRuleStore
+	Sessions      SessionStore
+	Keys          *auth.KeyRing
+	Revocations   *middleware.Revocations
+	RateLimits    *middleware.RateLimiter
+	TwoFactor     TwoFactorStore
+	Challenges    LoginChallengeStore
+	Throttles     LoginThrottleStore
+	Tokens        UserTokenStore
+	APIKeys       APIKeyStore
+	Organizations OrganizationStore
+	Approvals     ApprovalStore
+	Service       TransactionService
+	Periods       PeriodService
+	Hub           *websocket.Hub
+}
+
 type UserStore interface {
 	Create(ctx context.Context, tx store.Execer, id, username, email, passwordHash string) error
 	GetByEmail(ctx context.Context, email string) (map[string]any, error)
 	GetByUsername(ctx context.Context, username string) (map[string]any, error)
 	GetByID(ctx context.Context, userID string) (map[string]any, error)
+	UpdatePassword(ctx context.Context, tx store.Execer, userID, passwordHash string) (int64, error)
+	EmailVerified(ctx context.Context, userID string) (bool, error)
+	MarkEmailVerified(ctx context.Context, tx store.Execer, userID, email string) (int64, error)
 }
 
 type AccountStore interface {
@@ -22,12 +70,24 @@ type AccountStore interface {
 	ListAllWithUsers(ctx context.Context) ([]store.AccountWithUser, error)
 	GetSystemAccount(ctx context.Context, currency string) (string, error)
 	AdjustBalance(ctx context.Context, tx store.Execer, accountID string, delta int64) (int64, error)
+	SetFrozen(ctx context.Context, tx store.Execer, accountID string, frozen bool, reason string) (int64, error)
 }
 
 type LedgerStore interface {
 	InsertEntries(ctx context.Context, tx store.Execer, entries []store.LedgerEntryInput) error
 }
 
+type BalanceStore interface {
+	BalanceAsOf(ctx context.Context, accountID string, asOf time.Time) (store.BalanceAsOf, error)
+	BalancesAsOf(ctx context.Context, asOf time.Time) ([]store.BalanceAsOf, error)
+}
+
+type GLStore interface {
+	ListAccounts(ctx context.Context) ([]store.GLAccount, error)
+	MapSystemAccount(ctx context.Context, tx store.Execer, accountID, glCode string) (int64, error)
+	Balances(ctx context.Context, from, to time.Time, currency string) ([]store.GLBalance, error)
+}
+
 type TransactionStore interface {
 	ListByUser(ctx context.Context, userID, txType string, limit, offset int) ([]map[string]any, error)
 	ListAll(ctx context.Context, limit, offset int) ([]map[string]any, error)
@@ -39,10 +99,17 @@ type ExchangeStore interface {
 
 type AdminStore interface {
 	IsAdmin(ctx context.Context, userID string) (bool, bool, error)
-	HasRole(ctx context.Context, userID, role string) (bool, error)
+	Permissions(ctx context.Context, userID string) ([]string, error)
 	CreateAdmin(ctx context.Context, tx store.Execer, userID string, isSuper bool, createdBy *string) error
-	GrantRole(ctx context.Context, tx store.Execer, adminUserID, role string) error
+	RemoveAdmin(ctx context.Context, tx store.Execer, userID string) (int64, error)
+	GrantRole(ctx context.Context, tx store.Execer, adminUserID, role, grantedBy string) error
+	RevokeRole(ctx context.Context, tx store.Execer, adminUserID, role string) (int64, error)
+	CreateRole(ctx context.Context, tx store.Execer, role store.Role) (int64, error)
+	GetRole(ctx context.Context, name string) (store.Role, error)
+	ListRoles(ctx context.Context) ([]store.Role, error)
+	ListAdmins(ctx context.Context) ([]store.AdminSummary, error)
 	HasAnyAdmin(ctx context.Context) (bool, error)
+	HasOtherApprover(ctx context.Context, userID, permission string) (bool, error)
 }
 
 type AuditStore interface {
@@ -50,8 +117,134 @@ type AuditStore interface {
 	List(ctx context.Context, limit, offset int) ([]map[string]any, error)
 }
 
+type OutboxStore interface {
+	Append(ctx context.Context, tx store.Execer, input store.DomainEventInput) error
+	ListUndelivered(ctx context.Context, eventType string, limit, offset int) ([]store.DomainEvent, error)
+}
+
+type StreamStore interface {
+	Head(ctx context.Context, userID string) (int64, error)
+	Since(ctx context.Context, userID string, afterSeq int64, limit int) ([]store.StreamEvent, error)
+}
+
+type QuoteStore interface {
+	GetByID(ctx context.Context, quoteID string) (store.ExchangeQuote, error)
+}
+
+type WSTicketStore interface {
+	Create(ctx context.Context, input store.WSTicketInput) (time.Time, error)
+	Consume(ctx context.Context, ticketHash string) (store.WSTicket, error)
+}
+
+type NotificationStore interface {
+	ListPreferences(ctx context.Context, userID string) ([]store.NotificationPreference, error)
+	SetPreference(ctx context.Context, tx store.Execer, userID, channel, kind string, enabled bool) error
+	ListDeliveries(ctx context.Context, userID string, limit, offset int) ([]store.NotificationDelivery, error)
+}
+
+type SessionStore interface {
+	CreateSession(ctx context.Context, tx store.Execer, input store.SessionInput) error
+	ListActive(ctx context.Context, userID string) ([]store.Session, error)
+	AddRefreshToken(ctx context.Context, tx store.Execer, input store.RefreshTokenInput) error
+	GetRefreshToken(ctx context.Context, tx store.Getter, tokenHash string) (store.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, tx store.Execer, tokenHash string) error
+	Revoke(ctx context.Context, tx store.Getter, userID, sessionID, reason string) (bool, error)
+	RevokeAll(ctx context.Context, tx store.Selecter, userID, exceptSessionID, reason string) ([]string, error)
+}
+
+type TwoFactorStore interface {
+	GetTOTP(ctx context.Context, userID string) (store.TOTPState, error)
+	SetPendingTOTP(ctx context.Context, tx store.Execer, userID, sealedSecret string) (int64, error)
+	EnableTOTP(ctx context.Context, tx store.Execer, userID string, counter int64) (int64, error)
+	DisableTOTP(ctx context.Context, tx store.Execer, userID string) (int64, error)
+	UseTOTPCounter(ctx context.Context, userID string, counter int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, tx store.Execer, userID string, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+}
+
+type LoginChallengeStore interface {
+	Create(ctx context.Context, challengeHash, userID string, ttl time.Duration) (time.Time, error)
+	Attempt(ctx context.Context, challengeHash string, maxAttempts int) (store.LoginChallenge, error)
+	Delete(ctx context.Context, challengeHash string) error
+}
+
+type LoginThrottleStore interface {
+	Lock(ctx context.Context, tx store.Tx, scope, key string) (store.LoginThrottle, error)
+	RecordFailure(ctx context.Context, tx store.Getter, scope, key string, window time.Duration, maxFailures int, lockout time.Duration) (store.LoginThrottle, error)
+	Release(ctx context.Context, tx store.Execer, scope, key string, recorded, previous store.LoginThrottle) error
+	Reset(ctx context.Context, tx store.Execer, scope, key string) (int64, error)
+}
+
+type UserTokenStore interface {
+	Create(ctx context.Context, tx store.Execer, input store.UserTokenInput) (bool, error)
+	Consume(ctx context.Context, tx store.Getter, tokenHash, purpose string) (store.UserToken, error)
+}
+
+type APIKeyStore interface {
+	Create(ctx context.Context, tx store.Execer, input store.APIKeyInput) error
+	ListByUser(ctx context.Context, userID string) ([]store.APIKey, error)
+	ListByOrganization(ctx context.Context, organizationID string) ([]store.APIKey, error)
+	GetActiveByHash(ctx context.Context, keyHash string) (store.APIKey, error)
+	Revoke(ctx context.Context, tx store.Execer, userID, keyID string) (int64, error)
+	RevokeForOrganization(ctx context.Context, tx store.Execer, organizationID, keyID, createdBy string) (int64, error)
+	RevokeMemberKeys(ctx context.Context, tx store.Execer, organizationID, userID string) (int64, error)
+	Touch(ctx context.Context, keyID, ip string) error
+}
+
+type OrganizationStore interface {
+	Create(ctx context.Context, tx store.Execer, id, name, ownerID string) error
+	ListByUser(ctx context.Context, userID string) ([]store.Organization, error)
+	Role(ctx context.Context, organizationID, userID string) (string, error)
+	ListMembers(ctx context.Context, organizationID string) ([]store.OrganizationMemberRecord, error)
+	AddMember(ctx context.Context, tx store.Execer, organizationID, userID, role, addedBy string) (int64, error)
+	RemoveMember(ctx context.Context, tx store.Execer, organizationID, userID string) (int64, error)
+}
+
+type ApprovalStore interface {
+	Create(ctx context.Context, tx store.Execer, input store.ApprovalInput) error
+	Get(ctx context.Context, id string) (store.Approval, error)
+	List(ctx context.Context, status string, limit, offset int) ([]store.Approval, error)
+	ListByRequester(ctx context.Context, userID string, limit, offset int) ([]store.Approval, error)
+	Decide(ctx context.Context, tx store.Execer, id, deciderID, status, reason string) (int64, error)
+	Complete(ctx context.Context, tx store.Execer, id, status, result, errMessage string) error
+	ExpirePending(ctx context.Context, tx store.Selecter) ([]string, error)
+}
+
+type AlertRuleStore interface {
+	Create(ctx context.Context, input store.AlertRuleInput) (store.AlertRule, error)
+	ListByUser(ctx context.Context, userID string) ([]store.AlertRule, error)
+	Delete(ctx context.Context, userID, ruleID string) (int64, error)
+}
+
+type DeviceStore interface {
+	Touch(ctx context.Context, tx store.Getter, userID, deviceHash, userAgent string) (bool, error)
+}
+
+type WebhookStore interface {
+	CreateEndpoint(ctx context.Context, input store.WebhookEndpointInput) error
+	ListEndpoints(ctx context.Context, ownerUserID string) ([]store.WebhookEndpoint, error)
+	GetEndpoint(ctx context.Context, endpointID string) (store.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, endpointID string) (int64, error)
+	EnableEndpoint(ctx context.Context, endpointID string) (int64, error)
+	ListDeliveries(ctx context.Context, endpointID string, limit, offset int) ([]store.WebhookDelivery, error)
+	ListAttempts(ctx context.Context, endpointID, deliveryID string) ([]store.WebhookAttempt, error)
+	Redeliver(ctx context.Context, endpointID, deliveryID string) (int64, error)
+}
+
 type TransactionService interface {
 	Transfer(ctx context.Context, req services.TransferRequest) (string, error)
+	TransferTx(ctx context.Context, tx *sqlx.Tx, req services.TransferRequest) (string, error)
 	Exchange(ctx context.Context, req services.ExchangeRequest) (string, error)
 	QuoteExchange(ctx context.Context, req services.ExchangeQuoteRequest) (services.ExchangeQuote, error)
+	Adjust(ctx context.Context, req services.AdjustmentRequest) (string, error)
+	AdjustTx(ctx context.Context, tx *sqlx.Tx, req services.AdjustmentRequest) (string, error)
+	Reverse(ctx context.Context, req services.ReversalRequest) (string, error)
+	ReverseTx(ctx context.Context, tx *sqlx.Tx, req services.ReversalRequest) (string, error)
+}
+
+type PeriodService interface {
+	List(ctx context.Context) ([]store.AccountingPeriod, error)
+	Balances(ctx context.Context, periodID string) ([]store.PeriodBalance, error)
+	Close(ctx context.Context, actorID string, periodEnd time.Time) (store.AccountingPeriod, error)
+	Reopen(ctx context.Context, actorID, periodID, reason string) (store.AccountingPeriod, error)
 }
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/events"
+	"banking/internal/middleware"
+	"banking/internal/notifications"
+	"banking/internal/store"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+func (h *Handler) RequestEmailVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	user, err := h.users.GetByID(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load user")
+		return
+	}
+	if emailVerifiedAt(user) != nil {
+		respondError(w, http.StatusConflict, "email already verified")
+		return
+	}
+	email := valueToString(user["email"])
+	var ref string
+	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		ref, err = h.issueUserToken(r.Context(), tx, userID, email, store.TokenVerifyEmail, h.cfg.EmailVerificationTTL)
+		if err != nil || ref == "" {
+			return err
+		}
+		return h.queueTokenMail(r.Context(), tx, events.EmailVerificationRequested, userID, ref, h.cfg.EmailVerificationTTL)
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to send verification email")
+		return
+	}
+	if ref == "" {
+		w.Header().Set("Retry-After", strconv.Itoa(int(h.cfg.EmailTokenCooldown.Seconds())))
+		respondError(w, http.StatusTooManyRequests, "verification email recently sent")
+		return
+	}
+	respondJSON(w, http.StatusAccepted, map[string]string{"status": "sent"})
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// A token only verifies the address it was mailed to.
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req verifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		respondError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		token, err := h.tokens.Consume(r.Context(), tx, auth.HashToken(req.Token), store.TokenVerifyEmail)
+		if err != nil {
+			return err
+		}
+		rows, err := h.users.MarkEmailVerified(r.Context(), tx, token.UserID, token.Email)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return errInvalidEmailToken
+		}
+		data, _ := json.Marshal(map[string]string{
+			"email":      token.Email,
+			"ip":         middleware.RemoteIP(r),
+			"user_agent": r.UserAgent(),
+		})
+		return h.audit.Log(r.Context(), tx, token.UserID, "verify_email", "user", token.UserID, string(data))
+	})
+	if err == sql.ErrNoRows || errors.Is(err, errInvalidEmailToken) {
+		respondError(w, http.StatusBadRequest, "invalid or expired token")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to verify email")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]bool{"email_verified": true})
+}
+
+func (h *Handler) requireVerifiedEmail(w http.ResponseWriter, r *http.Request, userID string) bool {
+	verified, err := h.users.EmailVerified(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load user")
+		return false
+	}
+	if !verified {
+		respondError(w, http.StatusForbidden, "email_not_verified")
+		return false
+	}
+	return true
+}
+
+// issueUserToken returns the reference the mailed token is derived from,
+// or "" while the previous token is in its cooldown.
+func (h *Handler) issueUserToken(ctx context.Context, tx *sqlx.Tx, userID, email, purpose string, ttl time.Duration) (string, error) {
+	kind := notifications.KindEmailVerification
+	if purpose == store.TokenResetPassword {
+		kind = notifications.KindPasswordReset
+	}
+	ref := uuid.NewString()
+	created, err := h.tokens.Create(ctx, tx, store.UserTokenInput{
+		TokenHash: auth.HashToken(h.mailLinks().Token(kind, ref)),
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		TTL:       ttl,
+		Cooldown:  h.cfg.EmailTokenCooldown,
+	})
+	if err != nil || !created {
+		return "", err
+	}
+	return ref, nil
+}
+
+// queueTokenMail goes through the outbox so the mail is only sent if tx
+// commits. The event holds only the token reference; the notification
+// sender derives the link when it sends.
+func (h *Handler) queueTokenMail(ctx context.Context, tx *sqlx.Tx, eventType, userID, ref string, ttl time.Duration) error {
+	return h.outbox.Append(ctx, tx, events.New(eventType, events.AggregateUser, userID, &userID, map[string]string{
+		"token_ref":  ref,
+		"expires_in": formatTTL(ttl),
+	}))
+}
+
+func (h *Handler) mailLinks() notifications.Links {
+	return notifications.Links{BaseURL: h.cfg.PublicURL, Key: h.cfg.MailTokenKey}
+}
+
+func formatTTL(ttl time.Duration) string {
+	unit, count := "minute", int(ttl/time.Minute)
+	if ttl >= time.Hour && ttl%time.Hour == 0 {
+		unit, count = "hour", int(ttl/time.Hour)
+	}
+	if count != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", count, unit)
+}
+
+func emailVerifiedAt(user map[string]any) *time.Time {
+	verifiedAt, _ := user["email_verified_at"].(*time.Time)
+	return verifiedAt
+}
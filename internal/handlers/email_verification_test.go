@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/events"
+	"banking/internal/notifications"
+	"banking/internal/services"
+	"banking/internal/store"
+)
+
+func queuedMail(t *testing.T, appended []store.DomainEventInput, eventType string) (string, string, string) {
+	t.Helper()
+	if len(appended) != 1 || appended[0].Type != eventType || appended[0].UserID == nil {
+		t.Fatalf("expected one %s event, got %#v", eventType, appended)
+	}
+	var payload map[string]string
+	if err := json.Unmarshal([]byte(appended[0].Payload), &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if _, ok := payload["link"]; ok || payload["token_ref"] == "" {
+		t.Fatalf("expected only a token reference in the outbox: %#v", payload)
+	}
+	return *appended[0].UserID, payload["token_ref"], payload["expires_in"]
+}
+
+func captureOutbox(handler *Handler) *[]store.DomainEventInput {
+	var appended []store.DomainEventInput
+	handler.outbox = stubOutboxStore{
+		appendFn: func(_ context.Context, _ store.Execer, input store.DomainEventInput) error {
+			appended = append(appended, input)
+			return nil
+		},
+	}
+	return &appended
+}
+
+func TestRequestEmailVerificationMailsLink(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByIDFn: func(context.Context, string) (map[string]any, error) {
+			return map[string]any{"id": "user-1", "username": "alice", "email": "alice@example.com", "email_verified_at": (*time.Time)(nil)}, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.cfg.PublicURL = "https://bank.example"
+	handler.cfg.MailTokenKey = "key"
+	handler.cfg.EmailVerificationTTL = 24 * time.Hour
+	handler.cfg.EmailTokenCooldown = time.Minute
+	appended := captureOutbox(handler)
+	var input store.UserTokenInput
+	handler.tokens = stubUserTokenStore{
+		createFn: func(_ context.Context, _ store.Execer, tokenInput store.UserTokenInput) (bool, error) {
+			input = tokenInput
+			return true, nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.RequestEmailVerification, http.MethodPost, "/auth/email/verify/request", "/auth/email/verify/request", "", "user-1")
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if input.UserID != "user-1" || input.Purpose != store.TokenVerifyEmail || input.Email != "alice@example.com" || input.TTL != 24*time.Hour || input.Cooldown != time.Minute {
+		t.Fatalf("unexpected token input: %#v", input)
+	}
+	userID, ref, expiresIn := queuedMail(t, *appended, events.EmailVerificationRequested)
+	link := handler.mailLinks().Link(notifications.KindEmailVerification, ref)
+	token, found := strings.CutPrefix(link, "https://bank.example/verify-email?token=")
+	if userID != "user-1" || !found || expiresIn != "24 hours" {
+		t.Fatalf("unexpected mail for %s: %s %s", userID, link, expiresIn)
+	}
+	if !strings.HasPrefix(token, "ev_") || auth.HashToken(token) != input.TokenHash {
+		t.Fatalf("mailed token does not match the stored hash: %s", token)
+	}
+}
+
+func TestRequestEmailVerificationCooldown(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByIDFn: func(context.Context, string) (map[string]any, error) {
+			return map[string]any{"id": "user-1", "email": "alice@example.com"}, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.cfg.EmailTokenCooldown = time.Minute
+	appended := captureOutbox(handler)
+	handler.tokens = stubUserTokenStore{
+		createFn: func(context.Context, store.Execer, store.UserTokenInput) (bool, error) { return false, nil },
+	}
+	rr := servePeriodRequest(t, handler.RequestEmailVerification, http.MethodPost, "/auth/email/verify/request", "/auth/email/verify/request", "", "user-1")
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected 429 with Retry-After 60, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	if len(*appended) != 0 {
+		t.Fatal("expected no email during the cooldown")
+	}
+}
+
+func TestRequestEmailVerificationAlreadyVerified(t *testing.T) {
+	verifiedAt := time.Now()
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByIDFn: func(context.Context, string) (map[string]any, error) {
+			return map[string]any{"id": "user-1", "email": "alice@example.com", "email_verified_at": &verifiedAt}, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.tokens = stubUserTokenStore{
+		createFn: func(context.Context, store.Execer, store.UserTokenInput) (bool, error) {
+			t.Fatal("no token should be issued")
+			return false, nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.RequestEmailVerification, http.MethodPost, "/auth/email/verify/request", "/auth/email/verify/request", "", "user-1")
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	var verifiedUser, verifiedEmail string
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		markVerifiedFn: func(_ context.Context, _ store.Execer, userID, email string) (int64, error) {
+			verifiedUser, verifiedEmail = userID, email
+			return 1, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.tokens = stubUserTokenStore{
+		consumeFn: func(_ context.Context, _ store.Getter, tokenHash, purpose string) (store.UserToken, error) {
+			if tokenHash != auth.HashToken("ev_abc") || purpose != store.TokenVerifyEmail {
+				t.Fatalf("unexpected consume: %s %s", tokenHash, purpose)
+			}
+			return store.UserToken{UserID: "user-1", Email: "alice@example.com"}, nil
+		},
+	}
+	var action string
+	handler.audit = stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, _, auditAction, _, _, _ string) error {
+			action = auditAction
+			return nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/email/verify", strings.NewReader(`{"token":"ev_abc"}`))
+	rr := httptest.NewRecorder()
+	handler.VerifyEmail(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if verifiedUser != "user-1" || verifiedEmail != "alice@example.com" || action != "verify_email" {
+		t.Fatalf("unexpected verification: %s %s %s", verifiedUser, verifiedEmail, action)
+	}
+}
+
+func TestVerifyEmailRejectsStaleToken(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		// The user changed their email after the link was sent.
+		markVerifiedFn: func(context.Context, store.Execer, string, string) (int64, error) { return 0, nil },
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.tokens = stubUserTokenStore{
+		consumeFn: func(context.Context, store.Getter, string, string) (store.UserToken, error) {
+			return store.UserToken{UserID: "user-1", Email: "old@example.com"}, nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/email/verify", strings.NewReader(`{"token":"ev_abc"}`))
+	rr := httptest.NewRecorder()
+	handler.VerifyEmail(rr, req)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "invalid or expired token") {
+		t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestVerifyEmailRejectsUnknownToken(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	req := httptest.NewRequest(http.MethodPost, "/auth/email/verify", strings.NewReader(`{"token":"ev_unknown"}`))
+	rr := httptest.NewRecorder()
+	handler.VerifyEmail(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestTransferRequiresVerifiedEmail(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		emailVerifiedFn: func(context.Context, string) (bool, error) { return false, nil },
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{
+		transferFn: func(context.Context, services.TransferRequest) (string, error) {
+			t.Fatal("unverified users must not transfer")
+			return "", nil
+		},
+	})
+	rr := servePeriodRequest(t, handler.Transfer, http.MethodPost, "/transactions/transfer", "/transactions/transfer", `{"from_account_id":"a1","to_account_id":"a2","amount":"10.00","confirm":true}`, "user-1")
+	if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "email_not_verified") {
+		t.Fatalf("expected 403 email_not_verified, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestExchangeRequiresVerifiedEmail(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		emailVerifiedFn: func(context.Context, string) (bool, error) { return false, nil },
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{
+		exchangeFn: func(context.Context, services.ExchangeRequest) (string, error) {
+			t.Fatal("unverified users must not exchange")
+			return "", nil
+		},
+	})
+	rr := servePeriodRequest(t, handler.Exchange, http.MethodPost, "/transactions/exchange", "/transactions/exchange", `{"from_account_id":"a1","to_account_id":"a2","amount":"10.00","quoted_rate":"0.9","confirm":true}`, "user-1")
+	if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "email_not_verified") {
+		t.Fatalf("expected 403 email_not_verified, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"banking/internal/events"
+)
+
+func (h *Handler) ListUndeliveredEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := parseInt(query.Get("limit"), 50)
+	page := parseInt(query.Get("page"), 1)
+	offset := (page - 1) * limit
+	rows, err := h.outbox.ListUndelivered(r.Context(), query.Get("type"), limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load events")
+		return
+	}
+	normalized := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		payload := json.RawMessage(row.Payload)
+		if row.Type == events.EmailVerificationRequested || row.Type == events.PasswordResetRequested {
+			payload = json.RawMessage(`{}`)
+		}
+		normalized = append(normalized, map[string]any{
+			"id":              row.ID,
+			"seq":             row.Seq,
+			"type":            row.Type,
+			"aggregate_type":  row.AggregateType,
+			"aggregate_id":    row.AggregateID,
+			"user_id":         row.UserID,
+			"payload":         payload,
+			"created_at":      row.CreatedAt,
+			"delivered_sinks": []string(row.DeliveredSinks),
+			"attempts":        row.Attempts,
+			"last_error":      row.LastError,
+			"next_attempt_at": row.NextAttemptAt,
+		})
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"banking/internal/store"
+)
+
+func TestListUndeliveredEvents(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.outbox = stubOutboxStore{
+		listUndeliveredFn: func(_ context.Context, eventType string, limit, offset int) ([]store.DomainEvent, error) {
+			if eventType != "transfer.completed" || limit != 10 || offset != 10 {
+				t.Fatalf("unexpected filters: %q %d %d", eventType, limit, offset)
+			}
+			return []store.DomainEvent{{
+				ID:             "event-1",
+				Seq:            7,
+				Type:           "transfer.completed",
+				Payload:        `{"transaction_id":"tx-1"}`,
+				DeliveredSinks: []string{"websocket"},
+				Attempts:       3,
+			}}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/events/undelivered?type=transfer.completed&limit=10&page=2", nil)
+	rr := httptest.NewRecorder()
+	handler.ListUndeliveredEvents(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var payload []struct {
+		ID       string            `json:"id"`
+		Seq      int64             `json:"seq"`
+		Payload  map[string]string `json:"payload"`
+		Attempts int               `json:"attempts"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload) != 1 || payload[0].Seq != 7 || payload[0].Payload["transaction_id"] != "tx-1" || payload[0].Attempts != 3 {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+}
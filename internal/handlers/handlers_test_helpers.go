@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -41,10 +42,13 @@ func (s stubReconcileDB) SelectContext(ctx context.Context, dest any, query stri
 }
 
 type stubUserStore struct {
-	createFn        func(ctx context.Context, tx store.Execer, id, username, email, passwordHash string) error
-	getByEmailFn    func(ctx context.Context, email string) (map[string]any, error)
-	getByUsernameFn func(ctx context.Context, username string) (map[string]any, error)
-	getByIDFn       func(ctx context.Context, userID string) (map[string]any, error)
+	createFn         func(ctx context.Context, tx store.Execer, id, username, email, passwordHash string) error
+	getByEmailFn     func(ctx context.Context, email string) (map[string]any, error)
+	getByUsernameFn  func(ctx context.Context, username string) (map[string]any, error)
+	getByIDFn        func(ctx context.Context, userID string) (map[string]any, error)
+	updatePasswordFn func(ctx context.Context, tx store.Execer, userID, passwordHash string) (int64, error)
+	emailVerifiedFn  func(ctx context.Context, userID string) (bool, error)
+	markVerifiedFn   func(ctx context.Context, tx store.Execer, userID, email string) (int64, error)
 }
 
 func (s stubUserStore) Create(ctx context.Context, tx store.Execer, id, username, email, passwordHash string) error {
@@ -75,6 +79,27 @@ func (s stubUserStore) GetByID(ctx context.Context, userID string) (map[string]a
 	return s.getByIDFn(ctx, userID)
 }
 
+func (s stubUserStore) UpdatePassword(ctx context.Context, tx store.Execer, userID, passwordHash string) (int64, error) {
+	if s.updatePasswordFn == nil {
+		return 1, nil
+	}
+	return s.updatePasswordFn(ctx, tx, userID, passwordHash)
+}
+
+func (s stubUserStore) EmailVerified(ctx context.Context, userID string) (bool, error) {
+	if s.emailVerifiedFn == nil {
+		return true, nil
+	}
+	return s.emailVerifiedFn(ctx, userID)
+}
+
+func (s stubUserStore) MarkEmailVerified(ctx context.Context, tx store.Execer, userID, email string) (int64, error) {
+	if s.markVerifiedFn == nil {
+		return 1, nil
+	}
+	return s.markVerifiedFn(ctx, tx, userID, email)
+}
+
 type stubAccountStore struct {
 	createFn            func(ctx context.Context, tx store.Execer, id string, userID *string, currency string, balance int64, isSystem bool) error
 	getByUserFn         func(ctx context.Context, userID string) ([]store.AccountBalanceSummary, error)
@@ -83,6 +108,7 @@ type stubAccountStore struct {
 	listAllWithUsersFn  func(ctx context.Context) ([]store.AccountWithUser, error)
 	getSystemAccountFn  func(ctx context.Context, currency string) (string, error)
 	adjustBalanceFn     func(ctx context.Context, tx store.Execer, accountID string, delta int64) (int64, error)
+	setFrozenFn         func(ctx context.Context, tx store.Execer, accountID string, frozen bool, reason string) (int64, error)
 }
 
 func (s stubAccountStore) Create(ctx context.Context, tx store.Execer, id string, userID *string, currency string, balance int64, isSystem bool) error {
@@ -134,6 +160,13 @@ func (s stubAccountStore) AdjustBalance(ctx context.Context, tx store.Execer, ac
 	return s.adjustBalanceFn(ctx, tx, accountID, delta)
 }
 
+func (s stubAccountStore) SetFrozen(ctx context.Context, tx store.Execer, accountID string, frozen bool, reason string) (int64, error) {
+	if s.setFrozenFn == nil {
+		return 1, nil
+	}
+	return s.setFrozenFn(ctx, tx, accountID, frozen, reason)
+}
+
 type stubLedgerStore struct {
 	insertFn func(ctx context.Context, tx store.Execer, entries []store.LedgerEntryInput) error
 }
@@ -145,6 +178,52 @@ func (s stubLedgerStore) InsertEntries(ctx context.Context, tx store.Execer, ent
 	return s.insertFn(ctx, tx, entries)
 }
 
+type stubBalanceStore struct {
+	balanceAsOfFn  func(ctx context.Context, accountID string, asOf time.Time) (store.BalanceAsOf, error)
+	balancesAsOfFn func(ctx context.Context, asOf time.Time) ([]store.BalanceAsOf, error)
+}
+
+func (s stubBalanceStore) BalanceAsOf(ctx context.Context, accountID string, asOf time.Time) (store.BalanceAsOf, error) {
+	if s.balanceAsOfFn == nil {
+		return store.BalanceAsOf{AccountID: accountID}, nil
+	}
+	return s.balanceAsOfFn(ctx, accountID, asOf)
+}
+
+func (s stubBalanceStore) BalancesAsOf(ctx context.Context, asOf time.Time) ([]store.BalanceAsOf, error) {
+	if s.balancesAsOfFn == nil {
+		return nil, nil
+	}
+	return s.balancesAsOfFn(ctx, asOf)
+}
+
+type stubGLStore struct {
+	listAccountsFn func(ctx context.Context) ([]store.GLAccount, error)
+	mapFn          func(ctx context.Context, tx store.Execer, accountID, glCode string) (int64, error)
+	balancesFn     func(ctx context.Context, from, to time.Time, currency string) ([]store.GLBalance, error)
+}
+
+func (s stubGLStore) ListAccounts(ctx context.Context) ([]store.GLAccount, error) {
+	if s.listAccountsFn == nil {
+		return nil, nil
+	}
+	return s.listAccountsFn(ctx)
+}
+
+func (s stubGLStore) MapSystemAccount(ctx context.Context, tx store.Execer, accountID, glCode string) (int64, error) {
+	if s.mapFn == nil {
+		return 1, nil
+	}
+	return s.mapFn(ctx, tx, accountID, glCode)
+}
+
+func (s stubGLStore) Balances(ctx context.Context, from, to time.Time, currency string) ([]store.GLBalance, error) {
+	if s.balancesFn == nil {
+		return nil, nil
+	}
+	return s.balancesFn(ctx, from, to, currency)
+}
+
 type stubTransactionStore struct {
 	listByUserFn func(ctx context.Context, userID, txType string, limit, offset int) ([]map[string]any, error)
 	listAllFn    func(ctx context.Context, limit, offset int) ([]map[string]any, error)
@@ -177,10 +256,17 @@ func (s stubExchangeStore) SetRate(ctx context.Context, tx store.Tx, baseCurrenc
 
 type stubAdminStore struct {
 	isAdminFn     func(ctx context.Context, userID string) (bool, bool, error)
-	hasRoleFn     func(ctx context.Context, userID, role string) (bool, error)
+	permissionsFn func(ctx context.Context, userID string) ([]string, error)
 	createAdminFn func(ctx context.Context, tx store.Execer, userID string, isSuper bool, createdBy *string) error
-	grantRoleFn   func(ctx context.Context, tx store.Execer, adminUserID, role string) error
+	removeAdminFn func(ctx context.Context, tx store.Execer, userID string) (int64, error)
+	grantRoleFn   func(ctx context.Context, tx store.Execer, adminUserID, role, grantedBy string) error
+	revokeRoleFn  func(ctx context.Context, tx store.Execer, adminUserID, role string) (int64, error)
+	createRoleFn  func(ctx context.Context, tx store.Execer, role store.Role) (int64, error)
+	getRoleFn     func(ctx context.Context, name string) (store.Role, error)
+	listRolesFn   func(ctx context.Context) ([]store.Role, error)
+	listAdminsFn  func(ctx context.Context) ([]store.AdminSummary, error)
 	hasAnyAdminFn func(ctx context.Context) (bool, error)
+	approverFn    func(ctx context.Context, userID, permission string) (bool, error)
 }
 
 func (s stubAdminStore) IsAdmin(ctx context.Context, userID string) (bool, bool, error) {
@@ -190,11 +276,11 @@ func (s stubAdminStore) IsAdmin(ctx context.Context, userID string) (bool, bool,
 	return s.isAdminFn(ctx, userID)
 }
 
-func (s stubAdminStore) HasRole(ctx context.Context, userID, role string) (bool, error) {
-	if s.hasRoleFn == nil {
-		return false, nil
+func (s stubAdminStore) Permissions(ctx context.Context, userID string) ([]string, error) {
+	if s.permissionsFn == nil {
+		return nil, nil
 	}
-	return s.hasRoleFn(ctx, userID, role)
+	return s.permissionsFn(ctx, userID)
 }
 
 func (s stubAdminStore) CreateAdmin(ctx context.Context, tx store.Execer, userID string, isSuper bool, createdBy *string) error {
@@ -204,11 +290,53 @@ func (s stubAdminStore) CreateAdmin(ctx context.Context, tx store.Execer, userID
 	return s.createAdminFn(ctx, tx, userID, isSuper, createdBy)
 }
 
-func (s stubAdminStore) GrantRole(ctx context.Context, tx store.Execer, adminUserID, role string) error {
+func (s stubAdminStore) RemoveAdmin(ctx context.Context, tx store.Execer, userID string) (int64, error) {
+	if s.removeAdminFn == nil {
+		return 1, nil
+	}
+	return s.removeAdminFn(ctx, tx, userID)
+}
+
+func (s stubAdminStore) GrantRole(ctx context.Context, tx store.Execer, adminUserID, role, grantedBy string) error {
 	if s.grantRoleFn == nil {
 		return nil
 	}
-	return s.grantRoleFn(ctx, tx, adminUserID, role)
+	return s.grantRoleFn(ctx, tx, adminUserID, role, grantedBy)
+}
+
+func (s stubAdminStore) RevokeRole(ctx context.Context, tx store.Execer, adminUserID, role string) (int64, error) {
+	if s.revokeRoleFn == nil {
+		return 1, nil
+	}
+	return s.revokeRoleFn(ctx, tx, adminUserID, role)
+}
+
+func (s stubAdminStore) CreateRole(ctx context.Context, tx store.Execer, role store.Role) (int64, error) {
+	if s.createRoleFn == nil {
+		return 1, nil
+	}
+	return s.createRoleFn(ctx, tx, role)
+}
+
+func (s stubAdminStore) GetRole(ctx context.Context, name string) (store.Role, error) {
+	if s.getRoleFn == nil {
+		return store.Role{Name: name}, nil
+	}
+	return s.getRoleFn(ctx, name)
+}
+
+func (s stubAdminStore) ListRoles(ctx context.Context) ([]store.Role, error) {
+	if s.listRolesFn == nil {
+		return nil, nil
+	}
+	return s.listRolesFn(ctx)
+}
+
+func (s stubAdminStore) ListAdmins(ctx context.Context) ([]store.AdminSummary, error) {
+	if s.listAdminsFn == nil {
+		return nil, nil
+	}
+	return s.listAdminsFn(ctx)
 }
 
 func (s stubAdminStore) HasAnyAdmin(ctx context.Context) (bool, error) {
@@ -218,6 +346,13 @@ func (s stubAdminStore) HasAnyAdmin(ctx context.Context) (bool, error) {
 	return s.hasAnyAdminFn(ctx)
 }
 
+func (s stubAdminStore) HasOtherApprover(ctx context.Context, userID, permission string) (bool, error) {
+	if s.approverFn == nil {
+		return false, nil
+	}
+	return s.approverFn(ctx, userID, permission)
+}
+
 type stubAuditStore struct {
 	logFn  func(ctx context.Context, tx store.Execer, actorID, action, entityType, entityID, data string) error
 	listFn func(ctx context.Context, limit, offset int) ([]map[string]any, error)
@@ -237,10 +372,98 @@ func (s stubAuditStore) List(ctx context.Context, limit, offset int) ([]map[stri
 	return s.listFn(ctx, limit, offset)
 }
 
+type stubOutboxStore struct {
+	appendFn          func(ctx context.Context, tx store.Execer, input store.DomainEventInput) error
+	listUndeliveredFn func(ctx context.Context, eventType string, limit, offset int) ([]store.DomainEvent, error)
+}
+
+func (s stubOutboxStore) Append(ctx context.Context, tx store.Execer, input store.DomainEventInput) error {
+	if s.appendFn == nil {
+		return nil
+	}
+	return s.appendFn(ctx, tx, input)
+}
+
+func (s stubOutboxStore) ListUndelivered(ctx context.Context, eventType string, limit, offset int) ([]store.DomainEvent, error) {
+	if s.listUndeliveredFn == nil {
+		return nil, nil
+	}
+	return s.listUndeliveredFn(ctx, eventType, limit, offset)
+}
+
+type stubWebhookStore struct {
+	createEndpointFn func(ctx context.Context, input store.WebhookEndpointInput) error
+	listEndpointsFn  func(ctx context.Context, ownerUserID string) ([]store.WebhookEndpoint, error)
+	getEndpointFn    func(ctx context.Context, endpointID string) (store.WebhookEndpoint, error)
+	deleteEndpointFn func(ctx context.Context, endpointID string) (int64, error)
+	enableEndpointFn func(ctx context.Context, endpointID string) (int64, error)
+	listDeliveriesFn func(ctx context.Context, endpointID string, limit, offset int) ([]store.WebhookDelivery, error)
+	listAttemptsFn   func(ctx context.Context, endpointID, deliveryID string) ([]store.WebhookAttempt, error)
+	redeliverFn      func(ctx context.Context, endpointID, deliveryID string) (int64, error)
+}
+
+func (s stubWebhookStore) CreateEndpoint(ctx context.Context, input store.WebhookEndpointInput) error {
+	if s.createEndpointFn == nil {
+		return nil
+	}
+	return s.createEndpointFn(ctx, input)
+}
+
+func (s stubWebhookStore) ListEndpoints(ctx context.Context, ownerUserID string) ([]store.WebhookEndpoint, error) {
+	if s.listEndpointsFn == nil {
+		return nil, nil
+	}
+	return s.listEndpointsFn(ctx, ownerUserID)
+}
+
+func (s stubWebhookStore) GetEndpoint(ctx context.Context, endpointID string) (store.WebhookEndpoint, error) {
+	if s.getEndpointFn == nil {
+		return store.WebhookEndpoint{}, sql.ErrNoRows
+	}
+	return s.getEndpointFn(ctx, endpointID)
+}
+
+func (s stubWebhookStore) DeleteEndpoint(ctx context.Context, endpointID string) (int64, error) {
+	if s.deleteEndpointFn == nil {
+		return 1, nil
+	}
+	return s.deleteEndpointFn(ctx, endpointID)
+}
+
+func (s stubWebhookStore) EnableEndpoint(ctx context.Context, endpointID string) (int64, error) {
+	if s.enableEndpointFn == nil {
+		return 1, nil
+	}
+	return s.enableEndpointFn(ctx, endpointID)
+}
+
+func (s stubWebhookStore) ListDeliveries(ctx context.Context, endpointID string, limit, offset int) ([]store.WebhookDelivery, error) {
+	if s.listDeliveriesFn == nil {
+		return nil, nil
+	}
+	return s.listDeliveriesFn(ctx, endpointID, limit, offset)
+}
+
+func (s stubWebhookStore) ListAttempts(ctx context.Context, endpointID, deliveryID string) ([]store.WebhookAttempt, error) {
+	if s.listAttemptsFn == nil {
+		return nil, nil
+	}
+	return s.listAttemptsFn(ctx, endpointID, deliveryID)
+}
+
+func (s stubWebhookStore) Redeliver(ctx context.Context, endpointID, deliveryID string) (int64, error) {
+	if s.redeliverFn == nil {
+		return 1, nil
+	}
+	return s.redeliverFn(ctx, endpointID, deliveryID)
+}
+
 type stubService struct {
 	transferFn func(ctx context.Context, req services.TransferRequest) (string, error)
 	exchangeFn func(ctx context.Context, req services.ExchangeRequest) (string, error)
 	quoteFn    func(ctx context.Context, req services.ExchangeQuoteRequest) (services.ExchangeQuote, error)
+	adjustFn   func(ctx context.Context, req services.AdjustmentRequest) (string, error)
+	reverseFn  func(ctx context.Context, req services.ReversalRequest) (string, error)
 }
 
 func (s stubService) Transfer(ctx context.Context, req services.TransferRequest) (string, error) {
@@ -250,6 +473,10 @@ func (s stubService) Transfer(ctx context.Context, req services.TransferRequest)
 	return s.transferFn(ctx, req)
 }
 
+func (s stubService) TransferTx(ctx context.Context, _ *sqlx.Tx, req services.TransferRequest) (string, error) {
+	return s.Transfer(ctx, req)
+}
+
 func (s stubService) Exchange(ctx context.Context, req services.ExchangeRequest) (string, error) {
 	if s.exchangeFn == nil {
 		return "", nil
@@ -264,28 +491,646 @@ func (s stubService) QuoteExchange(ctx context.Context, req services.ExchangeQuo
 	return s.quoteFn(ctx, req)
 }
 
+func (s stubService) Adjust(ctx context.Context, req services.AdjustmentRequest) (string, error) {
+	if s.adjustFn == nil {
+		return "", nil
+	}
+	return s.adjustFn(ctx, req)
+}
+
+func (s stubService) AdjustTx(ctx context.Context, _ *sqlx.Tx, req services.AdjustmentRequest) (string, error) {
+	return s.Adjust(ctx, req)
+}
+
+func (s stubService) Reverse(ctx context.Context, req services.ReversalRequest) (string, error) {
+	if s.reverseFn == nil {
+		return "", nil
+	}
+	return s.reverseFn(ctx, req)
+}
+
+func (s stubService) ReverseTx(ctx context.Context, _ *sqlx.Tx, req services.ReversalRequest) (string, error) {
+	return s.Reverse(ctx, req)
+}
+
+type stubPeriodService struct {
+	listFn     func(ctx context.Context) ([]store.AccountingPeriod, error)
+	balancesFn func(ctx context.Context, periodID string) ([]store.PeriodBalance, error)
+	closeFn    func(ctx context.Context, actorID string, periodEnd time.Time) (store.AccountingPeriod, error)
+	reopenFn   func(ctx context.Context, actorID, periodID, reason string) (store.AccountingPeriod, error)
+}
+
+func (s stubPeriodService) List(ctx context.Context) ([]store.AccountingPeriod, error) {
+	if s.listFn == nil {
+		return nil, nil
+	}
+	return s.listFn(ctx)
+}
+
+func (s stubPeriodService) Balances(ctx context.Context, periodID string) ([]store.PeriodBalance, error) {
+	if s.balancesFn == nil {
+		return nil, nil
+	}
+	return s.balancesFn(ctx, periodID)
+}
+
+func (s stubPeriodService) Close(ctx context.Context, actorID string, periodEnd time.Time) (store.AccountingPeriod, error) {
+	if s.closeFn == nil {
+		return store.AccountingPeriod{PeriodEnd: periodEnd, Status: services.PeriodClosed}, nil
+	}
+	return s.closeFn(ctx, actorID, periodEnd)
+}
+
+func (s stubPeriodService) Reopen(ctx context.Context, actorID, periodID, reason string) (store.AccountingPeriod, error) {
+	if s.reopenFn == nil {
+		return store.AccountingPeriod{ID: periodID, Status: services.PeriodReopened}, nil
+	}
+	return s.reopenFn(ctx, actorID, periodID, reason)
+}
+
+type stubStreamStore struct {
+	headFn  func(ctx context.Context, userID string) (int64, error)
+	sinceFn func(ctx context.Context, userID string, afterSeq int64, limit int) ([]store.StreamEvent, error)
+}
+
+func (s stubStreamStore) Head(ctx context.Context, userID string) (int64, error) {
+	if s.headFn == nil {
+		return 0, nil
+	}
+	return s.headFn(ctx, userID)
+}
+
+func (s stubStreamStore) Since(ctx context.Context, userID string, afterSeq int64, limit int) ([]store.StreamEvent, error) {
+	if s.sinceFn == nil {
+		return nil, nil
+	}
+	return s.sinceFn(ctx, userID, afterSeq, limit)
+}
+
+type stubQuoteStore struct {
+	getByIDFn func(ctx context.Context, quoteID string) (store.ExchangeQuote, error)
+}
+
+func (s stubQuoteStore) GetByID(ctx context.Context, quoteID string) (store.ExchangeQuote, error) {
+	if s.getByIDFn == nil {
+		return store.ExchangeQuote{}, sql.ErrNoRows
+	}
+	return s.getByIDFn(ctx, quoteID)
+}
+
+type stubWSTicketStore struct {
+	createFn  func(ctx context.Context, input store.WSTicketInput) (time.Time, error)
+	consumeFn func(ctx context.Context, ticketHash string) (store.WSTicket, error)
+}
+
+func (s stubWSTicketStore) Create(ctx context.Context, input store.WSTicketInput) (time.Time, error) {
+	if s.createFn == nil {
+		return time.Now().Add(input.TTL), nil
+	}
+	return s.createFn(ctx, input)
+}
+
+func (s stubWSTicketStore) Consume(ctx context.Context, ticketHash string) (store.WSTicket, error) {
+	if s.consumeFn == nil {
+		return store.WSTicket{}, sql.ErrNoRows
+	}
+	return s.consumeFn(ctx, ticketHash)
+}
+
+type stubNotificationStore struct {
+	listPreferencesFn func(ctx context.Context, userID string) ([]store.NotificationPreference, error)
+	setPreferenceFn   func(ctx context.Context, tx store.Execer, userID, channel, kind string, enabled bool) error
+	listDeliveriesFn  func(ctx context.Context, userID string, limit, offset int) ([]store.NotificationDelivery, error)
+}
+
+func (s stubNotificationStore) ListPreferences(ctx context.Context, userID string) ([]store.NotificationPreference, error) {
+	if s.listPreferencesFn == nil {
+		return nil, nil
+	}
+	return s.listPreferencesFn(ctx, userID)
+}
+
+func (s stubNotificationStore) SetPreference(ctx context.Context, tx store.Execer, userID, channel, kind string, enabled bool) error {
+	if s.setPreferenceFn == nil {
+		return nil
+	}
+	return s.setPreferenceFn(ctx, tx, userID, channel, kind, enabled)
+}
+
+func (s stubNotificationStore) ListDeliveries(ctx context.Context, userID string, limit, offset int) ([]store.NotificationDelivery, error) {
+	if s.listDeliveriesFn == nil {
+		return nil, nil
+	}
+	return s.listDeliveriesFn(ctx, userID, limit, offset)
+}
+
+type stubDeviceStore struct {
+	touchFn func(ctx context.Context, tx store.Getter, userID, deviceHash, userAgent string) (bool, error)
+}
+
+func (s stubDeviceStore) Touch(ctx context.Context, tx store.Getter, userID, deviceHash, userAgent string) (bool, error) {
+	if s.touchFn == nil {
+		return false, nil
+	}
+	return s.touchFn(ctx, tx, userID, deviceHash, userAgent)
+}
+
+type stubSessionStore struct {
+	getRefreshTokenFn func(ctx context.Context, tx store.Getter, tokenHash string) (store.RefreshToken, error)
+	addRefreshFn      func(ctx context.Context, tx store.Execer, input store.RefreshTokenInput) error
+	markUsedFn        func(ctx context.Context, tx store.Execer, tokenHash string) error
+	revokeFn          func(ctx context.Context, tx store.Getter, userID, sessionID, reason string) (bool, error)
+	revokeAllFn       func(ctx context.Context, tx store.Selecter, userID, exceptSessionID, reason string) ([]string, error)
+	createSessionFn   func(ctx context.Context, tx store.Execer, input store.SessionInput) error
+	listActiveFn      func(ctx context.Context, userID string) ([]store.Session, error)
+}
+
+func (s stubSessionStore) CreateSession(ctx context.Context, tx store.Execer, input store.SessionInput) error {
+	if s.createSessionFn == nil {
+		return nil
+	}
+	return s.createSessionFn(ctx, tx, input)
+}
+
+func (s stubSessionStore) ListActive(ctx context.Context, userID string) ([]store.Session, error) {
+	if s.listActiveFn == nil {
+		return nil, nil
+	}
+	return s.listActiveFn(ctx, userID)
+}
+
+func (s stubSessionStore) AddRefreshToken(ctx context.Context, tx store.Execer, input store.RefreshTokenInput) error {
+	if s.addRefreshFn == nil {
+		return nil
+	}
+	return s.addRefreshFn(ctx, tx, input)
+}
+
+func (s stubSessionStore) GetRefreshToken(ctx context.Context, tx store.Getter, tokenHash string) (store.RefreshToken, error) {
+	if s.getRefreshTokenFn == nil {
+		return store.RefreshToken{}, sql.ErrNoRows
+	}
+	return s.getRefreshTokenFn(ctx, tx, tokenHash)
+}
+
+func (s stubSessionStore) MarkRefreshTokenUsed(ctx context.Context, tx store.Execer, tokenHash string) error {
+	if s.markUsedFn == nil {
+		return nil
+	}
+	return s.markUsedFn(ctx, tx, tokenHash)
+}
+
+func (s stubSessionStore) Revoke(ctx context.Context, tx store.Getter, userID, sessionID, reason string) (bool, error) {
+	if s.revokeFn == nil {
+		return true, nil
+	}
+	return s.revokeFn(ctx, tx, userID, sessionID, reason)
+}
+
+func (s stubSessionStore) RevokeAll(ctx context.Context, tx store.Selecter, userID, exceptSessionID, reason string) ([]string, error) {
+	if s.revokeAllFn == nil {
+		return nil, nil
+	}
+	return s.revokeAllFn(ctx, tx, userID, exceptSessionID, reason)
+}
+
+type stubTwoFactorStore struct {
+	getTOTPFn         func(ctx context.Context, userID string) (store.TOTPState, error)
+	setPendingFn      func(ctx context.Context, tx store.Execer, userID, sealedSecret string) (int64, error)
+	enableFn          func(ctx context.Context, tx store.Execer, userID string, counter int64) (int64, error)
+	disableFn         func(ctx context.Context, tx store.Execer, userID string) (int64, error)
+	useCounterFn      func(ctx context.Context, userID string, counter int64) (bool, error)
+	replaceRecoveryFn func(ctx context.Context, tx store.Execer, userID string, codeHashes []string) error
+	useRecoveryCodeFn func(ctx context.Context, userID, codeHash string) (bool, error)
+}
+
+func (s stubTwoFactorStore) GetTOTP(ctx context.Context, userID string) (store.TOTPState, error) {
+	if s.getTOTPFn == nil {
+		return store.TOTPState{}, nil
+	}
+	return s.getTOTPFn(ctx, userID)
+}
+
+func (s stubTwoFactorStore) SetPendingTOTP(ctx context.Context, tx store.Execer, userID, sealedSecret string) (int64, error) {
+	if s.setPendingFn == nil {
+		return 1, nil
+	}
+	return s.setPendingFn(ctx, tx, userID, sealedSecret)
+}
+
+func (s stubTwoFactorStore) EnableTOTP(ctx context.Context, tx store.Execer, userID string, counter int64) (int64, error) {
+	if s.enableFn == nil {
+		return 1, nil
+	}
+	return s.enableFn(ctx, tx, userID, counter)
+}
+
+func (s stubTwoFactorStore) DisableTOTP(ctx context.Context, tx store.Execer, userID string) (int64, error) {
+	if s.disableFn == nil {
+		return 1, nil
+	}
+	return s.disableFn(ctx, tx, userID)
+}
+
+func (s stubTwoFactorStore) UseTOTPCounter(ctx context.Context, userID string, counter int64) (bool, error) {
+	if s.useCounterFn == nil {
+		return true, nil
+	}
+	return s.useCounterFn(ctx, userID, counter)
+}
+
+func (s stubTwoFactorStore) ReplaceRecoveryCodes(ctx context.Context, tx store.Execer, userID string, codeHashes []string) error {
+	if s.replaceRecoveryFn == nil {
+		return nil
+	}
+	return s.replaceRecoveryFn(ctx, tx, userID, codeHashes)
+}
+
+func (s stubTwoFactorStore) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	if s.useRecoveryCodeFn == nil {
+		return false, nil
+	}
+	return s.useRecoveryCodeFn(ctx, userID, codeHash)
+}
+
+type stubLoginChallengeStore struct {
+	createFn  func(ctx context.Context, challengeHash, userID string, ttl time.Duration) (time.Time, error)
+	attemptFn func(ctx context.Context, challengeHash string, maxAttempts int) (store.LoginChallenge, error)
+	deleteFn  func(ctx context.Context, challengeHash string) error
+}
+
+func (s stubLoginChallengeStore) Create(ctx context.Context, challengeHash, userID string, ttl time.Duration) (time.Time, error) {
+	if s.createFn == nil {
+		return time.Now().Add(ttl), nil
+	}
+	return s.createFn(ctx, challengeHash, userID, ttl)
+}
+
+func (s stubLoginChallengeStore) Attempt(ctx context.Context, challengeHash string, maxAttempts int) (store.LoginChallenge, error) {
+	if s.attemptFn == nil {
+		return store.LoginChallenge{}, sql.ErrNoRows
+	}
+	return s.attemptFn(ctx, challengeHash, maxAttempts)
+}
+
+func (s stubLoginChallengeStore) Delete(ctx context.Context, challengeHash string) error {
+	if s.deleteFn == nil {
+		return nil
+	}
+	return s.deleteFn(ctx, challengeHash)
+}
+
+type stubLoginThrottleStore struct {
+	lockFn          func(ctx context.Context, tx store.Tx, scope, key string) (store.LoginThrottle, error)
+	recordFailureFn func(ctx context.Context, tx store.Getter, scope, key string, window time.Duration, maxFailures int, lockout time.Duration) (store.LoginThrottle, error)
+	releaseFn       func(ctx context.Context, tx store.Execer, scope, key string, recorded, previous store.LoginThrottle) error
+	resetFn         func(ctx context.Context, tx store.Execer, scope, key string) (int64, error)
+}
+
+func (s stubLoginThrottleStore) Lock(ctx context.Context, tx store.Tx, scope, key string) (store.LoginThrottle, error) {
+	if s.lockFn == nil {
+		return store.LoginThrottle{}, nil
+	}
+	return s.lockFn(ctx, tx, scope, key)
+}
+
+func (s stubLoginThrottleStore) RecordFailure(ctx context.Context, tx store.Getter, scope, key string, window time.Duration, maxFailures int, lockout time.Duration) (store.LoginThrottle, error) {
+	if s.recordFailureFn == nil {
+		return store.LoginThrottle{Failures: 1}, nil
+	}
+	return s.recordFailureFn(ctx, tx, scope, key, window, maxFailures, lockout)
+}
+
+func (s stubLoginThrottleStore) Release(ctx context.Context, tx store.Execer, scope, key string, recorded, previous store.LoginThrottle) error {
+	if s.releaseFn == nil {
+		return nil
+	}
+	return s.releaseFn(ctx, tx, scope, key, recorded, previous)
+}
+
+func (s stubLoginThrottleStore) Reset(ctx context.Context, tx store.Execer, scope, key string) (int64, error) {
+	if s.resetFn == nil {
+		return 0, nil
+	}
+	return s.resetFn(ctx, tx, scope, key)
+}
+
+type stubUserTokenStore struct {
+	createFn  func(ctx context.Context, tx store.Execer, input store.UserTokenInput) (bool, error)
+	consumeFn func(ctx context.Context, tx store.Getter, tokenHash, purpose string) (store.UserToken, error)
+}
+
+func (s stubUserTokenStore) Create(ctx context.Context, tx store.Execer, input store.UserTokenInput) (bool, error) {
+	if s.createFn == nil {
+		return true, nil
+	}
+	return s.createFn(ctx, tx, input)
+}
+
+func (s stubUserTokenStore) Consume(ctx context.Context, tx store.Getter, tokenHash, purpose string) (store.UserToken, error) {
+	if s.consumeFn == nil {
+		return store.UserToken{}, sql.ErrNoRows
+	}
+	return s.consumeFn(ctx, tx, tokenHash, purpose)
+}
+
+type stubAPIKeyStore struct {
+	createFn         func(ctx context.Context, tx store.Execer, input store.APIKeyInput) error
+	listByUserFn     func(ctx context.Context, userID string) ([]store.APIKey, error)
+	listByOrgFn      func(ctx context.Context, organizationID string) ([]store.APIKey, error)
+	getFn            func(ctx context.Context, keyHash string) (store.APIKey, error)
+	revokeFn         func(ctx context.Context, tx store.Execer, userID, keyID string) (int64, error)
+	revokeForOrgFn   func(ctx context.Context, tx store.Execer, organizationID, keyID, createdBy string) (int64, error)
+	revokeMemberKeys func(ctx context.Context, tx store.Execer, organizationID, userID string) (int64, error)
+}
+
+func (s stubAPIKeyStore) Create(ctx context.Context, tx store.Execer, input store.APIKeyInput) error {
+	if s.createFn == nil {
+		return nil
+	}
+	return s.createFn(ctx, tx, input)
+}
+
+func (s stubAPIKeyStore) ListByUser(ctx context.Context, userID string) ([]store.APIKey, error) {
+	if s.listByUserFn == nil {
+		return nil, nil
+	}
+	return s.listByUserFn(ctx, userID)
+}
+
+func (s stubAPIKeyStore) ListByOrganization(ctx context.Context, organizationID string) ([]store.APIKey, error) {
+	if s.listByOrgFn == nil {
+		return nil, nil
+	}
+	return s.listByOrgFn(ctx, organizationID)
+}
+
+func (s stubAPIKeyStore) GetActiveByHash(ctx context.Context, keyHash string) (store.APIKey, error) {
+	if s.getFn == nil {
+		return store.APIKey{}, sql.ErrNoRows
+	}
+	return s.getFn(ctx, keyHash)
+}
+
+func (s stubAPIKeyStore) Revoke(ctx context.Context, tx store.Execer, userID, keyID string) (int64, error) {
+	if s.revokeFn == nil {
+		return 1, nil
+	}
+	return s.revokeFn(ctx, tx, userID, keyID)
+}
+
+func (s stubAPIKeyStore) RevokeForOrganization(ctx context.Context, tx store.Execer, organizationID, keyID, createdBy string) (int64, error) {
+	if s.revokeForOrgFn == nil {
+		return 1, nil
+	}
+	return s.revokeForOrgFn(ctx, tx, organizationID, keyID, createdBy)
+}
+
+func (s stubAPIKeyStore) RevokeMemberKeys(ctx context.Context, tx store.Execer, organizationID, userID string) (int64, error) {
+	if s.revokeMemberKeys == nil {
+		return 0, nil
+	}
+	return s.revokeMemberKeys(ctx, tx, organizationID, userID)
+}
+
+func (s stubAPIKeyStore) Touch(context.Context, string, string) error {
+	return nil
+}
+
+// stubOrganizationStore keeps roles per organization and user in memory.
+type stubOrganizationStore struct {
+	roles map[string]map[string]string
+}
+
+func (s *stubOrganizationStore) Create(_ context.Context, _ store.Execer, id, _, ownerID string) error {
+	_, err := s.AddMember(context.Background(), nil, id, ownerID, store.OrganizationOwner, "")
+	return err
+}
+
+func (s *stubOrganizationStore) ListByUser(_ context.Context, userID string) ([]store.Organization, error) {
+	var organizations []store.Organization
+	for id, members := range s.roles {
+		if role, ok := members[userID]; ok {
+			organizations = append(organizations, store.Organization{ID: id, Role: role})
+		}
+	}
+	return organizations, nil
+}
+
+func (s *stubOrganizationStore) Role(_ context.Context, organizationID, userID string) (string, error) {
+	role, ok := s.roles[organizationID][userID]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return role, nil
+}
+
+func (s *stubOrganizationStore) ListMembers(_ context.Context, organizationID string) ([]store.OrganizationMemberRecord, error) {
+	var members []store.OrganizationMemberRecord
+	for userID, role := range s.roles[organizationID] {
+		members = append(members, store.OrganizationMemberRecord{UserID: userID, Role: role})
+	}
+	return members, nil
+}
+
+func (s *stubOrganizationStore) AddMember(_ context.Context, _ store.Execer, organizationID, userID, role, _ string) (int64, error) {
+	if s.roles == nil {
+		s.roles = make(map[string]map[string]string)
+	}
+	if s.roles[organizationID] == nil {
+		s.roles[organizationID] = make(map[string]string)
+	}
+	if _, ok := s.roles[organizationID][userID]; ok {
+		return 0, nil
+	}
+	s.roles[organizationID][userID] = role
+	return 1, nil
+}
+
+func (s *stubOrganizationStore) RemoveMember(_ context.Context, _ store.Execer, organizationID, userID string) (int64, error) {
+	if s.roles[organizationID][userID] != store.OrganizationMember {
+		return 0, nil
+	}
+	delete(s.roles[organizationID], userID)
+	return 1, nil
+}
+
+// stubApprovalStore keeps requests in memory so tests can follow one from
+// creation to its decision.
+type stubApprovalStore struct {
+	approvals map[string]store.Approval
+}
+
+func (s *stubApprovalStore) Create(_ context.Context, _ store.Execer, input store.ApprovalInput) error {
+	if s.approvals == nil {
+		s.approvals = make(map[string]store.Approval)
+	}
+	s.approvals[input.ID] = store.Approval{
+		ID:          input.ID,
+		Action:      input.Action,
+		Payload:     input.Payload,
+		RequestedBy: input.RequestedBy,
+		Status:      store.ApprovalPending,
+		ExpiresAt:   input.ExpiresAt,
+		CreatedAt:   time.Now(),
+	}
+	return nil
+}
+
+func (s *stubApprovalStore) Get(_ context.Context, id string) (store.Approval, error) {
+	approval, ok := s.approvals[id]
+	if !ok {
+		return store.Approval{}, sql.ErrNoRows
+	}
+	return approval, nil
+}
+
+func (s *stubApprovalStore) List(_ context.Context, status string, _, _ int) ([]store.Approval, error) {
+	var approvals []store.Approval
+	for _, approval := range s.approvals {
+		if status == "" || approval.Status == status {
+			approvals = append(approvals, approval)
+		}
+	}
+	return approvals, nil
+}
+
+func (s *stubApprovalStore) ListByRequester(_ context.Context, userID string, _, _ int) ([]store.Approval, error) {
+	var approvals []store.Approval
+	for _, approval := range s.approvals {
+		if approval.RequestedBy == userID {
+			approvals = append(approvals, approval)
+		}
+	}
+	return approvals, nil
+}
+
+func (s *stubApprovalStore) Decide(_ context.Context, _ store.Execer, id, deciderID, status, reason string) (int64, error) {
+	approval, ok := s.approvals[id]
+	if !ok || approval.Status != store.ApprovalPending || !time.Now().Before(approval.ExpiresAt) || approval.RequestedBy == deciderID {
+		return 0, nil
+	}
+	now := time.Now()
+	approval.Status, approval.DecidedBy, approval.DecidedAt, approval.DecisionReason = status, &deciderID, &now, reason
+	s.approvals[id] = approval
+	return 1, nil
+}
+
+func (s *stubApprovalStore) Complete(_ context.Context, _ store.Execer, id, status, result, errMessage string) error {
+	approval := s.approvals[id]
+	approval.Status, approval.Error = status, errMessage
+	if result != "" {
+		approval.Result = &result
+	}
+	s.approvals[id] = approval
+	return nil
+}
+
+func (s *stubApprovalStore) ExpirePending(context.Context, store.Selecter) ([]string, error) {
+	var ids []string
+	for id, approval := range s.approvals {
+		if approval.Status == store.ApprovalPending && !time.Now().Before(approval.ExpiresAt) {
+			approval.Status = store.ApprovalExpired
+			s.approvals[id] = approval
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+type stubAlertRuleStore struct {
+	createFn     func(ctx context.Context, input store.AlertRuleInput) (store.AlertRule, error)
+	listByUserFn func(ctx context.Context, userID string) ([]store.AlertRule, error)
+	deleteFn     func(ctx context.Context, userID, ruleID string) (int64, error)
+}
+
+func (s stubAlertRuleStore) Create(ctx context.Context, input store.AlertRuleInput) (store.AlertRule, error) {
+	if s.createFn == nil {
+		return store.AlertRule{ID: input.ID, UserID: input.UserID, Kind: input.Kind, Currency: input.Currency, Threshold: input.Threshold, Active: true}, nil
+	}
+	return s.createFn(ctx, input)
+}
+
+func (s stubAlertRuleStore) ListByUser(ctx context.Context, userID string) ([]store.AlertRule, error) {
+	if s.listByUserFn == nil {
+		return nil, nil
+	}
+	return s.listByUserFn(ctx, userID)
+}
+
+func (s stubAlertRuleStore) Delete(ctx context.Context, userID, ruleID string) (int64, error) {
+	if s.deleteFn == nil {
+		return 1, nil
+	}
+	return s.deleteFn(ctx, userID, ruleID)
+}
+
+var testKeys = newTestKeyRing()
+
+func newTestKeyRing() *auth.KeyRing {
+	key, err := auth.NewSigningKey(auth.AlgEdDSA, time.Now().Add(-time.Minute), time.Hour)
+	if err != nil {
+		panic(err)
+	}
+	keys := auth.NewKeyRing("banking", "banking-api")
+	keys.SetKeys([]auth.SigningKey{key})
+	return keys
+}
+
 func newTestHandler(reconcileDB store.Selecter, txRunner db.TxRunner, users UserStore, accounts AccountStore, ledger LedgerStore, transactions TransactionStore, exchange ExchangeStore, admin AdminStore, audit AuditStore, service TransactionService) *Handler {
 	cfg := config.Config{
 		AppEnv:         "test",
 		Port:           "0",
 		DatabaseURL:    "",
-		JWTSecret:      "secret",
 		TokenTTL:       time.Minute,
 		AllowedOrigins: "*",
 	}
-	return New(reconcileDB, txRunner, cfg, users, accounts, ledger, transactions, exchange, admin, audit, service, websocket.NewHub())
+	return New(Deps{
+		ReconcileDB:   reconcileDB,
+		TxRunner:      txRunner,
+		Config:        cfg,
+		Users:         users,
+		Accounts:      accounts,
+		Ledger:        ledger,
+		Balances:      stubBalanceStore{},
+		GL:            stubGLStore{},
+		Transactions:  transactions,
+		Exchange:      exchange,
+		Admin:         admin,
+		Audit:         audit,
+		Outbox:        stubOutboxStore{},
+		Webhooks:      stubWebhookStore{},
+		Streams:       stubStreamStore{},
+		Quotes:        stubQuoteStore{},
+		Tickets:       stubWSTicketStore{},
+		Notifications: stubNotificationStore{},
+		Devices:       stubDeviceStore{},
+		Alerts:        stubAlertRuleStore{},
+		Sessions:      stubSessionStore{},
+		Keys:          testKeys,
+		TwoFactor:     stubTwoFactorStore{},
+		Challenges:    stubLoginChallengeStore{},
+		Throttles:     stubLoginThrottleStore{},
+		Tokens:        stubUserTokenStore{},
+		APIKeys:       stubAPIKeyStore{},
+		Organizations: &stubOrganizationStore{},
+		Approvals:     &stubApprovalStore{},
+		Service:       service,
+		Periods:       stubPeriodService{},
+		Hub:           websocket.NewHub(),
+	})
 }
 
 func serveWithAuth(t *testing.T, handler http.HandlerFunc, userID string) *httptest.ResponseRecorder {
 	t.Helper()
-	token, err := auth.GenerateToken("secret", userID, time.Minute)
+	token, err := auth.GenerateToken(testKeys, userID, time.Minute)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
 	rr := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
-	middleware.Auth("secret")(handler).ServeHTTP(rr, req)
+	middleware.Auth(testKeys, nil)(handler).ServeHTTP(rr, req)
 	return rr
 }
 
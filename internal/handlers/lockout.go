@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"banking/internal/middleware"
+	"banking/internal/store"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+)
+
+var errLoginRefused = errors.New("login refused")
+
+type loginAttempt struct {
+	email, ip             string
+	account, address      store.LoginThrottle
+	accountPrev, addrPrev store.LoginThrottle
+}
+
+// reserveLogin counts an attempt as failed before its credentials are
+// checked, so concurrent guesses cannot all pass the same check; the attempt
+// is released once it succeeds. Refused attempts are audited but not counted.
+func (h *Handler) reserveLogin(w http.ResponseWriter, r *http.Request, userID, email string) (loginAttempt, bool) {
+	now := time.Now()
+	attempt := loginAttempt{email: email, ip: middleware.RemoteIP(r)}
+	var retryAt time.Time
+	reason, message := "", ""
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		ip, err := h.throttles.Lock(r.Context(), tx, store.LoginThrottleIP, attempt.ip)
+		if err != nil {
+			return err
+		}
+		account, err := h.throttles.Lock(r.Context(), tx, store.LoginThrottleEmail, email)
+		if err != nil {
+			return err
+		}
+		switch {
+		case ip.LockedUntil != nil && ip.LockedUntil.After(now):
+			retryAt, reason, message = *ip.LockedUntil, "ip_locked", "too many failed login attempts"
+		case account.LockedUntil != nil && account.LockedUntil.After(now):
+			retryAt, reason, message = *account.LockedUntil, "locked", "account temporarily locked"
+		case account.LastFailedAt != nil:
+			retryAt = account.LastFailedAt.Add(loginDelay(account.Failures, h.cfg.LoginDelayBase, h.cfg.LoginLockout))
+			reason, message = "throttled", "too many failed login attempts"
+		}
+		if retryAt.After(now) {
+			return errLoginRefused
+		}
+		attempt.accountPrev, attempt.addrPrev = account, ip
+		if attempt.account, err = h.throttles.RecordFailure(r.Context(), tx, store.LoginThrottleEmail, email, h.cfg.LoginFailureWindow, h.cfg.LoginMaxFailures, h.cfg.LoginLockout); err != nil {
+			return err
+		}
+		attempt.address, err = h.throttles.RecordFailure(r.Context(), tx, store.LoginThrottleIP, attempt.ip, h.cfg.LoginFailureWindow, h.cfg.LoginIPMaxFailures, h.cfg.LoginLockout)
+		return err
+	})
+	if err == nil {
+		return attempt, true
+	}
+	if !errors.Is(err, errLoginRefused) {
+		respondError(w, http.StatusInternalServerError, "login failed")
+		return attempt, false
+	}
+	if err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		return h.auditLoginFailure(r.Context(), tx, r, userID, email, reason, 0)
+	}); err != nil {
+		respondError(w, http.StatusInternalServerError, "login failed")
+		return attempt, false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAt.Sub(now).Seconds()))))
+	respondError(w, http.StatusTooManyRequests, message)
+	return attempt, false
+}
+
+func (h *Handler) releaseLogin(ctx context.Context, tx store.Execer, attempt loginAttempt) error {
+	if err := h.throttles.Release(ctx, tx, store.LoginThrottleEmail, attempt.email, attempt.account, attempt.accountPrev); err != nil {
+		return err
+	}
+	return h.throttles.Release(ctx, tx, store.LoginThrottleIP, attempt.ip, attempt.address, attempt.addrPrev)
+}
+
+// reserveStepUp counts a step-up code as failed before it is checked, like
+// reserveLogin, so LOGIN_MAX_FAILURES wrong codes lock step-up for the user.
+func (h *Handler) reserveStepUp(w http.ResponseWriter, r *http.Request, userID string) (store.LoginThrottle, store.LoginThrottle, bool) {
+	now := time.Now()
+	var recorded, previous store.LoginThrottle
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		var err error
+		if previous, err = h.throttles.Lock(r.Context(), tx, store.LoginThrottleStepUp, userID); err != nil {
+			return err
+		}
+		if previous.LockedUntil != nil && previous.LockedUntil.After(now) {
+			return errLoginRefused
+		}
+		recorded, err = h.throttles.RecordFailure(r.Context(), tx, store.LoginThrottleStepUp, userID, h.cfg.LoginFailureWindow, h.cfg.LoginMaxFailures, h.cfg.LoginLockout)
+		return err
+	})
+	if errors.Is(err, errLoginRefused) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(previous.LockedUntil.Sub(now).Seconds()))))
+		respondError(w, http.StatusTooManyRequests, "step_up_locked")
+		return recorded, previous, false
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "step_up_failed")
+		return recorded, previous, false
+	}
+	return recorded, previous, true
+}
+
+func (h *Handler) loginFailed(w http.ResponseWriter, r *http.Request, attempt loginAttempt, userID, reason string) {
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		return h.auditLoginFailure(r.Context(), tx, r, userID, attempt.email, reason, attempt.account.Failures)
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "login failed")
+		return
+	}
+	if reason == "invalid_code" {
+		respondError(w, http.StatusUnauthorized, "invalid code")
+		return
+	}
+	respondError(w, http.StatusUnauthorized, "invalid credentials")
+}
+
+func (h *Handler) auditLoginFailure(ctx context.Context, tx *sqlx.Tx, r *http.Request, userID, email, reason string, failures int) error {
+	data, _ := json.Marshal(map[string]any{
+		"email":      email,
+		"reason":     reason,
+		"failures":   failures,
+		"ip":         middleware.RemoteIP(r),
+		"user_agent": r.UserAgent(),
+	})
+	return h.audit.Log(ctx, tx, userID, "login_failed", "login", email, string(data))
+}
+
+func (h *Handler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	userID := chi.URLParam(r, "id")
+	user, err := h.users.GetByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "unable to load user")
+		return
+	}
+	email := loginThrottleKey(valueToString(user["email"]))
+	var removed int64
+	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		removed, err = h.throttles.Reset(r.Context(), tx, store.LoginThrottleEmail, email)
+		if err != nil {
+			return err
+		}
+		stepUp, err := h.throttles.Reset(r.Context(), tx, store.LoginThrottleStepUp, userID)
+		if removed += stepUp; err != nil || removed == 0 {
+			return err
+		}
+		data, _ := json.Marshal(map[string]string{
+			"user_id":  userID,
+			"actor_id": actorID,
+		})
+		return h.audit.Log(r.Context(), tx, actorID, "unlock_user", "user", userID, string(data))
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to unlock user")
+		return
+	}
+	if removed == 0 {
+		respondError(w, http.StatusConflict, "user_not_locked")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"user_id": userID, "unlocked": true})
+}
+
+func loginDelay(failures int, base, max time.Duration) time.Duration {
+	if failures <= 0 || base <= 0 {
+		return 0
+	}
+	delay := base
+	for i := 1; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+func loginThrottleKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
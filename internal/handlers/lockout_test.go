@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/store"
+)
+
+type recordedFailure struct {
+	scope, key  string
+	maxFailures int
+}
+
+func TestLoginUnknownEmailIsCountedAndAudited(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByEmailFn: func(context.Context, string) (map[string]any, error) { return nil, sql.ErrNoRows },
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.cfg.LoginMaxFailures = 5
+	handler.cfg.LoginIPMaxFailures = 50
+	var failures []recordedFailure
+	handler.throttles = stubLoginThrottleStore{
+		recordFailureFn: func(_ context.Context, _ store.Getter, scope, key string, _ time.Duration, maxFailures int, _ time.Duration) (store.LoginThrottle, error) {
+			failures = append(failures, recordedFailure{scope, key, maxFailures})
+			return store.LoginThrottle{Failures: 2}, nil
+		},
+	}
+	var actor, action, data string
+	handler.audit = stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, actorID, auditAction, _, _, auditData string) error {
+			actor, action, data = actorID, auditAction, auditData
+			return nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":" Nobody@Example.com","password":"guess"}`))
+	req.RemoteAddr = "10.0.0.1:5555"
+	rr := httptest.NewRecorder()
+	handler.Login(rr, req)
+	if rr.Code != http.StatusUnauthorized || !strings.Contains(rr.Body.String(), "invalid credentials") {
+		t.Fatalf("expected 401 invalid credentials, got %d: %s", rr.Code, rr.Body.String())
+	}
+	want := []recordedFailure{{store.LoginThrottleEmail, "nobody@example.com", 5}, {store.LoginThrottleIP, "10.0.0.1", 50}}
+	if len(failures) != 2 || failures[0] != want[0] || failures[1] != want[1] {
+		t.Fatalf("unexpected failures: %#v", failures)
+	}
+	if actor != "" || action != "login_failed" || !strings.Contains(data, `"reason":"unknown_email"`) {
+		t.Fatalf("unexpected audit: %q %q %s", actor, action, data)
+	}
+}
+
+func TestLoginWrongPasswordIsAudited(t *testing.T) {
+	passwordHash, err := auth.HashPassword("pass1234")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByEmailFn: func(context.Context, string) (map[string]any, error) {
+			return map[string]any{"id": "user-1", "password_hash": passwordHash}, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	var actor, data string
+	handler.audit = stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, actorID, _, _, _, auditData string) error {
+			actor, data = actorID, auditData
+			return nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"alice@example.com","password":"wrong"}`))
+	rr := httptest.NewRecorder()
+	handler.Login(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rr.Code)
+	}
+	if actor != "user-1" || !strings.Contains(data, `"reason":"invalid_password"`) {
+		t.Fatalf("unexpected audit: %q %s", actor, data)
+	}
+}
+
+func TestLoginRefusedWhileLockedOrThrottled(t *testing.T) {
+	lockedUntil := time.Now().Add(10 * time.Minute)
+	lastFailed := time.Now()
+	cases := []struct {
+		name       string
+		throttles  map[string]store.LoginThrottle
+		message    string
+		retryAfter string
+	}{
+		{"account locked", map[string]store.LoginThrottle{store.LoginThrottleEmail: {Failures: 5, LockedUntil: &lockedUntil}}, "account temporarily locked", "600"},
+		{"ip locked", map[string]store.LoginThrottle{store.LoginThrottleIP: {Failures: 50, LockedUntil: &lockedUntil}}, "too many failed login attempts", "600"},
+		{"delayed", map[string]store.LoginThrottle{store.LoginThrottleEmail: {Failures: 3, LastFailedAt: &lastFailed}}, "too many failed login attempts", "4"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+				getByEmailFn: func(context.Context, string) (map[string]any, error) {
+					t.Fatal("refused attempts must not reach the password check")
+					return nil, nil
+				},
+			}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+			handler.cfg.LoginDelayBase = time.Second
+			handler.cfg.LoginLockout = 15 * time.Minute
+			handler.throttles = stubLoginThrottleStore{
+				lockFn: func(_ context.Context, _ store.Tx, scope, _ string) (store.LoginThrottle, error) {
+					return tc.throttles[scope], nil
+				},
+				recordFailureFn: func(context.Context, store.Getter, string, string, time.Duration, int, time.Duration) (store.LoginThrottle, error) {
+					t.Fatal("refused attempts must not be counted")
+					return store.LoginThrottle{}, nil
+				},
+			}
+			audited := false
+			handler.audit = stubAuditStore{
+				logFn: func(_ context.Context, _ store.Execer, _, action, _, _, _ string) error {
+					audited = action == "login_failed"
+					return nil
+				},
+			}
+			req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"alice@example.com","password":"pass1234"}`))
+			rr := httptest.NewRecorder()
+			handler.Login(rr, req)
+			if rr.Code != http.StatusTooManyRequests || !strings.Contains(rr.Body.String(), tc.message) {
+				t.Fatalf("expected 429 %q, got %d: %s", tc.message, rr.Code, rr.Body.String())
+			}
+			if rr.Header().Get("Retry-After") != tc.retryAfter || !audited {
+				t.Fatalf("expected Retry-After %s and an audit entry, got %q %v", tc.retryAfter, rr.Header().Get("Retry-After"), audited)
+			}
+		})
+	}
+}
+
+func TestLoginSuccessResetsThrottle(t *testing.T) {
+	passwordHash, err := auth.HashPassword("pass1234")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByEmailFn: func(context.Context, string) (map[string]any, error) {
+			return map[string]any{"id": "user-1", "password_hash": passwordHash}, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	lastFailed := time.Now().Add(-time.Hour)
+	var reset, released string
+	handler.throttles = stubLoginThrottleStore{
+		lockFn: func(_ context.Context, _ store.Tx, scope, _ string) (store.LoginThrottle, error) {
+			if scope == store.LoginThrottleIP {
+				return store.LoginThrottle{Failures: 3, LastFailedAt: &lastFailed}, nil
+			}
+			return store.LoginThrottle{}, nil
+		},
+		recordFailureFn: func(_ context.Context, _ store.Getter, _, _ string, _ time.Duration, _ int, _ time.Duration) (store.LoginThrottle, error) {
+			return store.LoginThrottle{Failures: 4}, nil
+		},
+		releaseFn: func(_ context.Context, _ store.Execer, scope, key string, recorded, previous store.LoginThrottle) error {
+			if recorded.Failures != 4 || previous.Failures != 3 || previous.LastFailedAt != &lastFailed {
+				t.Fatalf("unexpected release: %#v %#v", recorded, previous)
+			}
+			released += scope + ":" + key + " "
+			return nil
+		},
+		resetFn: func(_ context.Context, _ store.Execer, scope, key string) (int64, error) {
+			reset = scope + ":" + key
+			return 1, nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"Alice@example.com","password":"pass1234"}`))
+	req.RemoteAddr = "10.0.0.1:5555"
+	rr := httptest.NewRecorder()
+	handler.Login(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if reset != "email:alice@example.com" || released != "ip:10.0.0.1 " {
+		t.Fatalf("expected the email throttle reset and the IP attempt released, got %q %q", reset, released)
+	}
+}
+
+func TestUnlockUser(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByIDFn: func(_ context.Context, userID string) (map[string]any, error) {
+			if userID == "missing" {
+				return nil, sql.ErrNoRows
+			}
+			return map[string]any{"id": userID, "email": "Alice@example.com"}, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	removed := int64(1)
+	handler.throttles = stubLoginThrottleStore{
+		resetFn: func(_ context.Context, _ store.Execer, scope, key string) (int64, error) {
+			switch {
+			case scope == store.LoginThrottleEmail && key == "alice@example.com":
+				return removed, nil
+			case scope == store.LoginThrottleStepUp && key == "user-1":
+				return 0, nil
+			}
+			t.Fatalf("unexpected reset %s %s", scope, key)
+			return 0, nil
+		},
+	}
+	var action string
+	handler.audit = stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, actorID, auditAction, _, entityID, _ string) error {
+			if actorID != "admin-1" || entityID != "user-1" {
+				t.Fatalf("unexpected audit %s %s", actorID, entityID)
+			}
+			action = auditAction
+			return nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.UnlockUser, http.MethodPost, "/admin/users/{id}/unlock", "/admin/users/user-1/unlock", "", "admin-1")
+	if rr.Code != http.StatusOK || action != "unlock_user" {
+		t.Fatalf("expected 200 with an audit entry, got %d %q", rr.Code, action)
+	}
+	var payload map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil || payload["unlocked"] != true {
+		t.Fatalf("unexpected response: %#v %v", payload, err)
+	}
+
+	removed = 0
+	rr = servePeriodRequest(t, handler.UnlockUser, http.MethodPost, "/admin/users/{id}/unlock", "/admin/users/user-1/unlock", "", "admin-1")
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a user that is not locked, got %d", rr.Code)
+	}
+	rr = servePeriodRequest(t, handler.UnlockUser, http.MethodPost, "/admin/users/{id}/unlock", "/admin/users/missing/unlock", "", "admin-1")
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestLoginDelay(t *testing.T) {
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{10, 30 * time.Second},
+	}
+	for _, tc := range cases {
+		if got := loginDelay(tc.failures, time.Second, 30*time.Second); got != tc.want {
+			t.Fatalf("loginDelay(%d) = %s, want %s", tc.failures, got, tc.want)
+		}
+	}
+}
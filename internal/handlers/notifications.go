@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"banking/internal/middleware"
+	"banking/internal/notifications"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type notificationPreferenceRequest struct {
+	Channel string `json:"channel"`
+	Kind    string `json:"kind"`
+	Enabled bool   `json:"enabled"`
+}
+
+type notificationPreferencesRequest struct {
+	Preferences []notificationPreferenceRequest `json:"preferences"`
+}
+
+func (h *Handler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	h.respondNotificationPreferences(w, r, userID)
+}
+
+func (h *Handler) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req notificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if len(req.Preferences) == 0 {
+		respondError(w, http.StatusBadRequest, "preferences is required")
+		return
+	}
+	for _, pref := range req.Preferences {
+		if !notifications.IsChannel(pref.Channel) {
+			respondError(w, http.StatusBadRequest, "unknown channel: "+pref.Channel)
+			return
+		}
+		if !notifications.IsKind(pref.Kind) {
+			respondError(w, http.StatusBadRequest, "unknown kind: "+pref.Kind)
+			return
+		}
+		if !pref.Enabled && notifications.Mandatory(pref.Kind) {
+			respondError(w, http.StatusBadRequest, "security notifications cannot be disabled")
+			return
+		}
+	}
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		for _, pref := range req.Preferences {
+			if err := h.notifications.SetPreference(r.Context(), tx, userID, pref.Channel, pref.Kind, pref.Enabled); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to save preferences")
+		return
+	}
+	h.respondNotificationPreferences(w, r, userID)
+}
+
+func (h *Handler) respondNotificationPreferences(w http.ResponseWriter, r *http.Request, userID string) {
+	stored, err := h.notifications.ListPreferences(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load preferences")
+		return
+	}
+	enabled := make(map[string]bool, len(stored))
+	for _, pref := range stored {
+		enabled[pref.Channel+"/"+pref.Kind] = pref.Enabled
+	}
+	prefs := make([]map[string]any, 0, len(notifications.Channels)*len(notifications.Kinds))
+	for _, channel := range notifications.Channels {
+		for _, kind := range notifications.Kinds {
+			on, ok := enabled[channel+"/"+kind]
+			prefs = append(prefs, map[string]any{
+				"channel":   channel,
+				"kind":      kind,
+				"enabled":   !ok || on || notifications.Mandatory(kind),
+				"mandatory": notifications.Mandatory(kind),
+			})
+		}
+	}
+	respondJSON(w, http.StatusOK, prefs)
+}
+
+func (h *Handler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	query := r.URL.Query()
+	limit := parseInt(query.Get("limit"), 50)
+	page := parseInt(query.Get("page"), 1)
+	offset := (page - 1) * limit
+	rows, err := h.notifications.ListDeliveries(r.Context(), userID, limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load notifications")
+		return
+	}
+	normalized := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		body := row.Body
+		if notifications.CarriesToken(row.Kind) {
+			body = ""
+		}
+		normalized = append(normalized, map[string]any{
+			"id":              row.ID,
+			"event_id":        row.EventID,
+			"channel":         row.Channel,
+			"kind":            row.Kind,
+			"destination":     row.Destination,
+			"subject":         row.Subject,
+			"body":            body,
+			"status":          row.Status,
+			"attempts":        row.Attempts,
+			"last_error":      row.LastError,
+			"next_attempt_at": row.NextAttemptAt,
+			"sent_at":         row.SentAt,
+			"created_at":      row.CreatedAt,
+		})
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"banking/internal/notifications"
+	"banking/internal/store"
+)
+
+func TestGetNotificationPreferencesFillsDefaults(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.notifications = stubNotificationStore{
+		listPreferencesFn: func(_ context.Context, userID string) ([]store.NotificationPreference, error) {
+			if userID != "user-1" {
+				t.Fatalf("unexpected user: %s", userID)
+			}
+			return []store.NotificationPreference{{Channel: notifications.ChannelEmail, Kind: notifications.KindTransferSent, Enabled: false}}, nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.GetNotificationPreferences, http.MethodGet, "/notifications/preferences", "/notifications/preferences", "", "user-1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var prefs []struct {
+		Channel   string `json:"channel"`
+		Kind      string `json:"kind"`
+		Enabled   bool   `json:"enabled"`
+		Mandatory bool   `json:"mandatory"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&prefs); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(prefs) != len(notifications.Kinds) {
+		t.Fatalf("expected every kind, got %#v", prefs)
+	}
+	for _, pref := range prefs {
+		want := pref.Kind != notifications.KindTransferSent
+		if pref.Enabled != want {
+			t.Fatalf("unexpected preference: %#v", pref)
+		}
+		if pref.Mandatory != notifications.Mandatory(pref.Kind) {
+			t.Fatalf("unexpected mandatory flag: %#v", pref)
+		}
+	}
+}
+
+func TestUpdateNotificationPreferences(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	var saved []string
+	handler.notifications = stubNotificationStore{
+		setPreferenceFn: func(_ context.Context, _ store.Execer, userID, channel, kind string, enabled bool) error {
+			if userID != "user-1" || channel != notifications.ChannelEmail || enabled {
+				t.Fatalf("unexpected preference: %s %s %s %v", userID, channel, kind, enabled)
+			}
+			saved = append(saved, kind)
+			return nil
+		},
+	}
+	body := `{"preferences":[{"channel":"email","kind":"transfer.sent","enabled":false},{"channel":"email","kind":"exchange.completed","enabled":false}]}`
+	rr := servePeriodRequest(t, handler.UpdateNotificationPreferences, http.MethodPut, "/notifications/preferences", "/notifications/preferences", body, "user-1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(saved) != 2 || saved[0] != notifications.KindTransferSent || saved[1] != notifications.KindExchangeCompleted {
+		t.Fatalf("unexpected saved preferences: %v", saved)
+	}
+}
+
+func TestUpdateNotificationPreferencesValidation(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.notifications = stubNotificationStore{
+		setPreferenceFn: func(context.Context, store.Execer, string, string, string, bool) error {
+			t.Fatalf("expected nothing to be saved")
+			return nil
+		},
+	}
+	cases := []string{
+		`{"preferences":[]}`,
+		`{"preferences":[{"channel":"pigeon","kind":"transfer.sent","enabled":false}]}`,
+		`{"preferences":[{"channel":"email","kind":"transfer.lost","enabled":false}]}`,
+		`{"preferences":[{"channel":"email","kind":"security.password_changed","enabled":false}]}`,
+	}
+	for _, body := range cases {
+		rr := servePeriodRequest(t, handler.UpdateNotificationPreferences, http.MethodPut, "/notifications/preferences", "/notifications/preferences", body, "user-1")
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, rr.Code)
+		}
+	}
+}
+
+func TestListNotifications(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.notifications = stubNotificationStore{
+		listDeliveriesFn: func(_ context.Context, userID string, limit, offset int) ([]store.NotificationDelivery, error) {
+			if userID != "user-1" || limit != 10 || offset != 10 {
+				t.Fatalf("unexpected query: %s %d %d", userID, limit, offset)
+			}
+			return []store.NotificationDelivery{{ID: "n-1", Kind: notifications.KindTransferReceived, Status: "sent"}}, nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.ListNotifications, http.MethodGet, "/notifications", "/notifications?limit=10&page=2", "", "user-1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var rows []map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&rows); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["id"] != "n-1" || rows[0]["status"] != "sent" {
+		t.Fatalf("unexpected rows: %#v", rows)
+	}
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"banking/internal/middleware"
+	"banking/internal/store"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var errAlreadyMember = errors.New("already a member")
+
+type organizationRequest struct {
+	Name string `json:"name"`
+}
+
+type organizationMemberRequest struct {
+	Identifier string `json:"identifier"`
+	Role       string `json:"role"`
+}
+
+func (h *Handler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req organizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		respondError(w, http.StatusBadRequest, "name is required and at most 100 characters")
+		return
+	}
+	organization := store.Organization{
+		ID:        uuid.NewString(),
+		Name:      name,
+		CreatedBy: userID,
+		CreatedAt: time.Now().UTC(),
+		Role:      store.OrganizationOwner,
+	}
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		if err := h.organizations.Create(r.Context(), tx, organization.ID, name, userID); err != nil {
+			return err
+		}
+		data, _ := json.Marshal(map[string]string{"name": name})
+		return h.audit.Log(r.Context(), tx, userID, "create_organization", "organization", organization.ID, string(data))
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to create organization")
+		return
+	}
+	respondJSON(w, http.StatusCreated, organizationResponse(organization))
+}
+
+func (h *Handler) ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	organizations, err := h.organizations.ListByUser(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load organizations")
+		return
+	}
+	normalized := make([]map[string]any, 0, len(organizations))
+	for _, organization := range organizations {
+		normalized = append(normalized, organizationResponse(organization))
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
+
+func (h *Handler) ListOrganizationMembers(w http.ResponseWriter, r *http.Request) {
+	_, organizationID, _, ok := h.organizationMember(w, r)
+	if !ok {
+		return
+	}
+	members, err := h.organizations.ListMembers(r.Context(), organizationID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load members")
+		return
+	}
+	normalized := make([]map[string]any, 0, len(members))
+	for _, member := range members {
+		normalized = append(normalized, map[string]any{
+			"user_id":    member.UserID,
+			"role":       member.Role,
+			"added_by":   member.AddedBy,
+			"created_at": member.CreatedAt,
+		})
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
+
+func (h *Handler) AddOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	userID, organizationID, ok := h.organizationOwner(w, r)
+	if !ok {
+		return
+	}
+	var req organizationMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Identifier == "" {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	role := req.Role
+	if role == "" {
+		role = store.OrganizationMember
+	}
+	if role != store.OrganizationOwner && role != store.OrganizationMember {
+		respondError(w, http.StatusBadRequest, "role must be owner or member")
+		return
+	}
+	var username, email string
+	if strings.Contains(req.Identifier, "@") {
+		email = req.Identifier
+	} else {
+		username = req.Identifier
+	}
+	memberID, err := h.resolveUserID(r.Context(), username, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "unable to resolve user")
+		return
+	}
+	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		added, err := h.organizations.AddMember(r.Context(), tx, organizationID, memberID, role, userID)
+		if err != nil {
+			return err
+		}
+		if added == 0 {
+			return errAlreadyMember
+		}
+		data, _ := json.Marshal(map[string]string{"organization_id": organizationID, "user_id": memberID, "role": role})
+		return h.audit.Log(r.Context(), tx, userID, "add_organization_member", "organization", organizationID, string(data))
+	})
+	if err == errAlreadyMember {
+		respondError(w, http.StatusConflict, "already a member")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to add member")
+		return
+	}
+	respondJSON(w, http.StatusCreated, map[string]any{"user_id": memberID, "role": role, "added_by": userID})
+}
+
+func (h *Handler) RemoveOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	userID, organizationID, ok := h.organizationOwner(w, r)
+	if !ok {
+		return
+	}
+	memberID := chi.URLParam(r, "userID")
+	role, err := h.organizations.Role(r.Context(), organizationID, memberID)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "member not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to remove member")
+		return
+	}
+	if role == store.OrganizationOwner {
+		respondError(w, http.StatusConflict, "owners cannot be removed")
+		return
+	}
+	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		removed, err := h.organizations.RemoveMember(r.Context(), tx, organizationID, memberID)
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			return sql.ErrNoRows
+		}
+		revoked, err := h.apiKeys.RevokeMemberKeys(r.Context(), tx, organizationID, memberID)
+		if err != nil {
+			return err
+		}
+		data, _ := json.Marshal(map[string]any{"organization_id": organizationID, "user_id": memberID, "revoked_api_keys": revoked})
+		return h.audit.Log(r.Context(), tx, userID, "remove_organization_member", "organization", organizationID, string(data))
+	})
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "member not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to remove member")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ListOrganizationAPIKeys(w http.ResponseWriter, r *http.Request) {
+	_, organizationID, _, ok := h.organizationMember(w, r)
+	if !ok {
+		return
+	}
+	keys, err := h.apiKeys.ListByOrganization(r.Context(), organizationID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load api keys")
+		return
+	}
+	respondJSON(w, http.StatusOK, apiKeysResponse(keys))
+}
+
+// Organization keys act as the member creating them; organizations own no
+// accounts, so nothing is authorized against the organization itself.
+func (h *Handler) CreateOrganizationAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, organizationID, _, ok := h.organizationMember(w, r)
+	if !ok {
+		return
+	}
+	h.createAPIKey(w, r, userID, &organizationID)
+}
+
+func (h *Handler) RevokeOrganizationAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, organizationID, role, ok := h.organizationMember(w, r)
+	if !ok {
+		return
+	}
+	createdBy := userID
+	if role == store.OrganizationOwner {
+		createdBy = ""
+	}
+	keyID := chi.URLParam(r, "keyID")
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		rows, err := h.apiKeys.RevokeForOrganization(r.Context(), tx, organizationID, keyID, createdBy)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return sql.ErrNoRows
+		}
+		data, _ := json.Marshal(map[string]string{
+			"organization_id": organizationID,
+			"ip":              middleware.RemoteIP(r),
+			"user_agent":      r.UserAgent(),
+		})
+		return h.audit.Log(r.Context(), tx, userID, "revoke_api_key", "api_key", keyID, string(data))
+	})
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "api key not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to revoke api key")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// organizationMember answers 404 to non-members so they can't tell the
+// organization exists.
+func (h *Handler) organizationMember(w http.ResponseWriter, r *http.Request) (string, string, string, bool) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return "", "", "", false
+	}
+	organizationID := chi.URLParam(r, "id")
+	role, err := h.organizations.Role(r.Context(), organizationID, userID)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "organization not found")
+		return "", "", "", false
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load organization")
+		return "", "", "", false
+	}
+	return userID, organizationID, role, true
+}
+
+func (h *Handler) organizationOwner(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	userID, organizationID, role, ok := h.organizationMember(w, r)
+	if !ok {
+		return "", "", false
+	}
+	if role != store.OrganizationOwner {
+		respondError(w, http.StatusForbidden, "only owners manage members")
+		return "", "", false
+	}
+	return userID, organizationID, true
+}
+
+func organizationResponse(organization store.Organization) map[string]any {
+	return map[string]any{
+		"id":         organization.ID,
+		"name":       organization.Name,
+		"created_by": organization.CreatedBy,
+		"created_at": organization.CreatedAt,
+		"role":       organization.Role,
+	}
+}
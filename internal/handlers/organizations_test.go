@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"banking/internal/store"
+)
+
+func TestOrganizationMembership(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByUsernameFn: func(_ context.Context, username string) (map[string]any, error) {
+			return map[string]any{"id": "user-" + username}, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	var revokedFor string
+	handler.apiKeys = stubAPIKeyStore{
+		revokeMemberKeys: func(_ context.Context, _ store.Execer, _, userID string) (int64, error) {
+			revokedFor = userID
+			return 1, nil
+		},
+	}
+	var actions []string
+	handler.audit = stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, actorID, action, _, _, _ string) error {
+			actions = append(actions, actorID+":"+action)
+			return nil
+		},
+	}
+
+	rr := servePeriodRequest(t, handler.CreateOrganization, http.MethodPost, "/organizations", "/organizations", `{"name":"Acme"}`, "user-1")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var organization map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &organization); err != nil || organization["role"] != store.OrganizationOwner {
+		t.Fatalf("unexpected organization: %s", rr.Body.String())
+	}
+	id, _ := organization["id"].(string)
+
+	cases := []struct {
+		handler      http.HandlerFunc
+		method, path string
+		pattern      string
+		body, userID string
+		want         int
+	}{
+		{handler.AddOrganizationMember, http.MethodPost, "/organizations/" + id + "/members", "/organizations/{id}/members", `{"identifier":"2"}`, "user-1", http.StatusCreated},
+		{handler.AddOrganizationMember, http.MethodPost, "/organizations/" + id + "/members", "/organizations/{id}/members", `{"identifier":"2"}`, "user-1", http.StatusConflict},
+		{handler.AddOrganizationMember, http.MethodPost, "/organizations/" + id + "/members", "/organizations/{id}/members", `{"identifier":"3","role":"admin"}`, "user-1", http.StatusBadRequest},
+		{handler.AddOrganizationMember, http.MethodPost, "/organizations/" + id + "/members", "/organizations/{id}/members", `{"identifier":"3"}`, "user-2", http.StatusForbidden},
+		{handler.ListOrganizationMembers, http.MethodGet, "/organizations/" + id + "/members", "/organizations/{id}/members", "", "user-3", http.StatusNotFound},
+		{handler.RemoveOrganizationMember, http.MethodDelete, "/organizations/" + id + "/members/user-1", "/organizations/{id}/members/{userID}", "", "user-1", http.StatusConflict},
+		{handler.RemoveOrganizationMember, http.MethodDelete, "/organizations/" + id + "/members/user-2", "/organizations/{id}/members/{userID}", "", "user-1", http.StatusNoContent},
+		{handler.ListOrganizationAPIKeys, http.MethodGet, "/organizations/" + id + "/api-keys", "/organizations/{id}/api-keys", "", "user-2", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		rr = servePeriodRequest(t, tc.handler, tc.method, tc.pattern, tc.path, tc.body, tc.userID)
+		if rr.Code != tc.want {
+			t.Fatalf("expected %d for %s %s as %s, got %d: %s", tc.want, tc.method, tc.path, tc.userID, rr.Code, rr.Body.String())
+		}
+	}
+	if revokedFor != "user-2" {
+		t.Fatalf("expected the removed member's keys to be revoked, got %q", revokedFor)
+	}
+	want := []string{"user-1:create_organization", "user-1:add_organization_member", "user-1:remove_organization_member"}
+	if len(actions) != len(want) || actions[0] != want[0] || actions[1] != want[1] || actions[2] != want[2] {
+		t.Fatalf("unexpected audit trail: %v", actions)
+	}
+}
+
+func TestOrganizationAPIKeysActAsTheirCreator(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	organizations := &stubOrganizationStore{}
+	organizations.Create(context.Background(), nil, "org-1", "Acme", "user-1")
+	organizations.AddMember(context.Background(), nil, "org-1", "user-2", store.OrganizationMember, "user-1")
+	handler.organizations = organizations
+	var input store.APIKeyInput
+	var revokedBy []string
+	handler.apiKeys = stubAPIKeyStore{
+		createFn: func(_ context.Context, _ store.Execer, keyInput store.APIKeyInput) error {
+			input = keyInput
+			return nil
+		},
+		revokeForOrgFn: func(_ context.Context, _ store.Execer, organizationID, _, createdBy string) (int64, error) {
+			if organizationID != "org-1" {
+				t.Fatalf("unexpected organization: %s", organizationID)
+			}
+			revokedBy = append(revokedBy, createdBy)
+			return 1, nil
+		},
+	}
+
+	rr := servePeriodRequest(t, handler.CreateOrganizationAPIKey, http.MethodPost, "/organizations/{id}/api-keys", "/organizations/org-1/api-keys", `{"name":"payroll","scopes":["read:accounts"]}`, "user-2")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if input.UserID != "user-2" || input.OrganizationID == nil || *input.OrganizationID != "org-1" {
+		t.Fatalf("unexpected input: %#v", input)
+	}
+	var response map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || response["organization_id"] != "org-1" || response["created_by"] != "user-2" {
+		t.Fatalf("unexpected response: %s", rr.Body.String())
+	}
+
+	for _, userID := range []string{"user-2", "user-1"} {
+		rr = servePeriodRequest(t, handler.RevokeOrganizationAPIKey, http.MethodDelete, "/organizations/{id}/api-keys/{keyID}", "/organizations/org-1/api-keys/key-1", "", userID)
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("expected 204 as %s, got %d", userID, rr.Code)
+		}
+	}
+	if len(revokedBy) != 2 || revokedBy[0] != "user-2" || revokedBy[1] != "" {
+		t.Fatalf("members should only revoke their own keys and owners any: %v", revokedBy)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"banking/internal/auth"
+	"banking/internal/events"
+	"banking/internal/middleware"
+	"banking/internal/store"
+	"banking/internal/validator"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type passwordResetRequest struct {
+	Email string `json:"email"`
+}
+
+// The response is the same whether or not the email belongs to a user.
+func (h *Handler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req passwordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if err := validator.ValidateEmail(req.Email); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	user, err := h.users.GetByEmail(r.Context(), req.Email)
+	if err == sql.ErrNoRows {
+		respondJSON(w, http.StatusAccepted, map[string]string{"status": "accepted"})
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to request password reset")
+		return
+	}
+	userID, email := valueToString(user["id"]), valueToString(user["email"])
+	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		ref, err := h.issueUserToken(r.Context(), tx, userID, email, store.TokenResetPassword, h.cfg.PasswordResetTTL)
+		if err != nil || ref == "" {
+			return err
+		}
+		data, _ := json.Marshal(map[string]string{
+			"ip":         middleware.RemoteIP(r),
+			"user_agent": r.UserAgent(),
+		})
+		if err := h.audit.Log(r.Context(), tx, userID, "request_password_reset", "user", userID, string(data)); err != nil {
+			return err
+		}
+		return h.queueTokenMail(r.Context(), tx, events.PasswordResetRequested, userID, ref, h.cfg.PasswordResetTTL)
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to request password reset")
+		return
+	}
+	respondJSON(w, http.StatusAccepted, map[string]string{"status": "accepted"})
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// Redeeming a reset link proves the user reads the mailbox, so it also
+// verifies the email and clears failed logins against it.
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if req.Token == "" {
+		respondError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+	if err := validator.ValidatePassword(req.NewPassword); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	passwordHash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to secure password")
+		return
+	}
+	var userID string
+	var sessionIDs []string
+	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		token, err := h.tokens.Consume(r.Context(), tx, auth.HashToken(req.Token), store.TokenResetPassword)
+		if err != nil {
+			return err
+		}
+		userID = token.UserID
+		rows, err := h.users.UpdatePassword(r.Context(), tx, userID, passwordHash)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return errInvalidEmailToken
+		}
+		if _, err := h.users.MarkEmailVerified(r.Context(), tx, userID, token.Email); err != nil {
+			return err
+		}
+		if _, err := h.throttles.Reset(r.Context(), tx, store.LoginThrottleEmail, loginThrottleKey(token.Email)); err != nil {
+			return err
+		}
+		sessionIDs, err = h.sessions.RevokeAll(r.Context(), tx, userID, "", "password_reset")
+		if err != nil {
+			return err
+		}
+		if err := h.appendSessionsRevoked(r.Context(), tx, userID, sessionIDs, "password_reset"); err != nil {
+			return err
+		}
+		data, _ := json.Marshal(map[string]any{
+			"user_id":          userID,
+			"sessions_revoked": len(sessionIDs),
+			"ip":               middleware.RemoteIP(r),
+			"user_agent":       r.UserAgent(),
+		})
+		if err := h.audit.Log(r.Context(), tx, userID, "reset_password", "user", userID, string(data)); err != nil {
+			return err
+		}
+		return h.outbox.Append(r.Context(), tx, events.New(events.PasswordChanged, events.AggregateUser, userID, &userID, map[string]string{
+			"user_id":    userID,
+			"ip":         middleware.RemoteIP(r),
+			"user_agent": r.UserAgent(),
+		}))
+	})
+	if err == sql.ErrNoRows || errors.Is(err, errInvalidEmailToken) {
+		respondError(w, http.StatusBadRequest, "invalid or expired token")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to reset password")
+		return
+	}
+	h.endSessions(userID, sessionIDs...)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/events"
+	"banking/internal/middleware"
+	"banking/internal/notifications"
+	"banking/internal/store"
+)
+
+func TestRequestPasswordResetUnknownEmail(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByEmailFn: func(context.Context, string) (map[string]any, error) { return nil, sql.ErrNoRows },
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.tokens = stubUserTokenStore{
+		createFn: func(context.Context, store.Execer, store.UserTokenInput) (bool, error) {
+			t.Fatal("no token should be issued for an unknown email")
+			return false, nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/reset/request", strings.NewReader(`{"email":"nobody@example.com"}`))
+	rr := httptest.NewRecorder()
+	handler.RequestPasswordReset(rr, req)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestRequestPasswordResetMailsLink(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByEmailFn: func(context.Context, string) (map[string]any, error) {
+			return map[string]any{"id": "user-1", "username": "alice", "email": "alice@example.com"}, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.cfg.PublicURL = "https://bank.example"
+	handler.cfg.MailTokenKey = "key"
+	handler.cfg.PasswordResetTTL = time.Hour
+	appended := captureOutbox(handler)
+	var input store.UserTokenInput
+	handler.tokens = stubUserTokenStore{
+		createFn: func(_ context.Context, _ store.Execer, tokenInput store.UserTokenInput) (bool, error) {
+			input = tokenInput
+			return true, nil
+		},
+	}
+	var action string
+	handler.audit = stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, _, auditAction, _, _, _ string) error {
+			action = auditAction
+			return nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/reset/request", strings.NewReader(`{"email":"alice@example.com"}`))
+	rr := httptest.NewRecorder()
+	handler.RequestPasswordReset(rr, req)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if input.Purpose != store.TokenResetPassword || input.UserID != "user-1" || input.TTL != time.Hour || action != "request_password_reset" {
+		t.Fatalf("unexpected token input %#v or audit %q", input, action)
+	}
+	userID, ref, expiresIn := queuedMail(t, *appended, events.PasswordResetRequested)
+	link := handler.mailLinks().Link(notifications.KindPasswordReset, ref)
+	token, found := strings.CutPrefix(link, "https://bank.example/reset-password?token=")
+	if userID != "user-1" || !found || expiresIn != "1 hour" {
+		t.Fatalf("unexpected mail for %s: %s %s", userID, link, expiresIn)
+	}
+	if !strings.HasPrefix(token, "pr_") || auth.HashToken(token) != input.TokenHash {
+		t.Fatalf("mailed token does not match the stored hash: %s", token)
+	}
+}
+
+func TestResetPasswordRevokesSessions(t *testing.T) {
+	var passwordHash, verifiedEmail string
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		updatePasswordFn: func(_ context.Context, _ store.Execer, userID, hash string) (int64, error) {
+			passwordHash = hash
+			return 1, nil
+		},
+		markVerifiedFn: func(_ context.Context, _ store.Execer, _, email string) (int64, error) {
+			verifiedEmail = email
+			return 1, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.tokens = stubUserTokenStore{
+		consumeFn: func(_ context.Context, _ store.Getter, tokenHash, purpose string) (store.UserToken, error) {
+			if tokenHash != auth.HashToken("pr_abc") || purpose != store.TokenResetPassword {
+				t.Fatalf("unexpected consume: %s %s", tokenHash, purpose)
+			}
+			return store.UserToken{UserID: "user-1", Email: "Alice@Example.com"}, nil
+		},
+	}
+	handler.sessions = stubSessionStore{
+		revokeAllFn: func(_ context.Context, _ store.Selecter, userID, exceptSessionID, reason string) ([]string, error) {
+			if userID != "user-1" || exceptSessionID != "" || reason != "password_reset" {
+				t.Fatalf("unexpected revoke: %s %q %s", userID, exceptSessionID, reason)
+			}
+			return []string{"session-1", "session-2"}, nil
+		},
+	}
+	var resetKey string
+	handler.throttles = stubLoginThrottleStore{
+		resetFn: func(_ context.Context, _ store.Execer, scope, key string) (int64, error) {
+			resetKey = scope + ":" + key
+			return 1, nil
+		},
+	}
+	var eventTypes []string
+	handler.outbox = stubOutboxStore{
+		appendFn: func(_ context.Context, _ store.Execer, input store.DomainEventInput) error {
+			eventTypes = append(eventTypes, input.Type)
+			return nil
+		},
+	}
+	handler.revocations = middleware.NewRevocations(nil, time.Minute)
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/reset", strings.NewReader(`{"token":"pr_abc","new_password":"newpass123"}`))
+	rr := httptest.NewRecorder()
+	handler.ResetPassword(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !auth.CheckPassword(passwordHash, "newpass123") {
+		t.Fatal("expected the new password to be stored")
+	}
+	if verifiedEmail != "Alice@Example.com" || resetKey != store.LoginThrottleEmail+":alice@example.com" {
+		t.Fatalf("unexpected verification %q or throttle reset %q", verifiedEmail, resetKey)
+	}
+	want := []string{events.SessionRevoked, events.SessionRevoked, events.PasswordChanged}
+	if strings.Join(eventTypes, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected events: %v", eventTypes)
+	}
+	if !handler.revocations.Revoked("session-1") || !handler.revocations.Revoked("session-2") {
+		t.Fatal("expected both sessions to be revoked in the cache")
+	}
+}
+
+func TestResetPasswordInvalidToken(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		updatePasswordFn: func(context.Context, store.Execer, string, string) (int64, error) {
+			t.Fatal("password must not change")
+			return 0, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/reset", strings.NewReader(`{"token":"pr_used","new_password":"newpass123"}`))
+	rr := httptest.NewRecorder()
+	handler.ResetPassword(rr, req)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "invalid or expired token") {
+		t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
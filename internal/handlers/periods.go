@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"banking/internal/middleware"
+	"banking/internal/money"
+	"banking/internal/services"
+	"banking/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type closePeriodRequest struct {
+	PeriodEnd string `json:"period_end"`
+}
+
+type reopenPeriodRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (h *Handler) ListPeriods(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.periods.List(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load periods")
+		return
+	}
+	normalized := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		normalized = append(normalized, periodResponse(row))
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
+
+func (h *Handler) PeriodBalances(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.periods.Balances(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load period balances")
+		return
+	}
+	normalized := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		normalized = append(normalized, map[string]any{
+			"period_id":      row.PeriodID,
+			"account_id":     row.AccountID,
+			"user_id":        row.UserID,
+			"currency":       row.Currency,
+			"is_system":      row.IsSystem,
+			"balance":        money.FormatMinor(row.Balance),
+			"last_entry_id":  row.LastEntryID,
+			"last_entry_seq": row.LastEntrySeq,
+		})
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
+
+func (h *Handler) ClosePeriod(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req closePeriodRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PeriodEnd == "" {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	periodEnd, err := parseAsOf(req.PeriodEnd)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid period_end")
+		return
+	}
+	period, err := h.periods.Close(r.Context(), userID, periodEnd)
+	if err != nil {
+		switch err {
+		case services.ErrPeriodNotEnded:
+			respondError(w, http.StatusBadRequest, "period_not_ended")
+		case services.ErrPeriodOutOfOrder:
+			respondError(w, http.StatusConflict, "period_out_of_order")
+		case services.ErrPeriodAlreadyClosed:
+			respondError(w, http.StatusConflict, "period_already_closed")
+		default:
+			respondError(w, http.StatusInternalServerError, "unable to close period")
+		}
+		return
+	}
+	respondJSON(w, http.StatusCreated, periodResponse(period))
+}
+
+func (h *Handler) ReopenPeriod(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req reopenPeriodRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		respondError(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+	period, err := h.periods.Reopen(r.Context(), userID, chi.URLParam(r, "id"), strings.TrimSpace(req.Reason))
+	if err != nil {
+		switch err {
+		case services.ErrPeriodNotFound:
+			respondError(w, http.StatusNotFound, "period not found")
+		case services.ErrPeriodNotClosed:
+			respondError(w, http.StatusConflict, "period_not_closed")
+		case services.ErrPeriodOutOfOrder:
+			respondError(w, http.StatusConflict, "period_out_of_order")
+		default:
+			respondError(w, http.StatusInternalServerError, "unable to reopen period")
+		}
+		return
+	}
+	respondJSON(w, http.StatusOK, periodResponse(period))
+}
+
+func periodResponse(period store.AccountingPeriod) map[string]any {
+	return map[string]any{
+		"id":            period.ID,
+		"period_start":  period.PeriodStart,
+		"period_end":    period.PeriodEnd,
+		"status":        period.Status,
+		"closed_by":     period.ClosedBy,
+		"closed_at":     period.ClosedAt,
+		"reopened_by":   period.ReopenedBy,
+		"reopened_at":   period.ReopenedAt,
+		"reopen_reason": period.ReopenReason,
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/middleware"
+	"banking/internal/services"
+	"banking/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func servePeriodRequest(t *testing.T, handler http.HandlerFunc, method, pattern, target, body, userID string) *httptest.ResponseRecorder {
+	t.Helper()
+	router := chi.NewRouter()
+	router.Method(method, pattern, middleware.Auth(testKeys, nil)(handler))
+	req := httptest.NewRequest(method, target, bytes.NewReader([]byte(body)))
+	token, _ := auth.GenerateToken(testKeys, userID, time.Minute)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestClosePeriod(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	var closedAt time.Time
+	handler.periods = stubPeriodService{
+		closeFn: func(_ context.Context, actorID string, periodEnd time.Time) (store.AccountingPeriod, error) {
+			if actorID != "admin-1" {
+				t.Fatalf("unexpected actor: %s", actorID)
+			}
+			closedAt = periodEnd
+			return store.AccountingPeriod{ID: "mar", PeriodEnd: periodEnd, Status: services.PeriodClosed}, nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.ClosePeriod, http.MethodPost, "/admin/periods/close", "/admin/periods/close", `{"period_end":"2025-03-31"}`, "admin-1")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rr.Code)
+	}
+	if !closedAt.Equal(time.Date(2025, 3, 31, 23, 59, 59, 999999000, time.UTC)) {
+		t.Fatalf("expected end of day, got %v", closedAt)
+	}
+	var payload map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if payload["id"] != "mar" || payload["status"] != "closed" {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+}
+
+func TestClosePeriodErrors(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	if rr := servePeriodRequest(t, handler.ClosePeriod, http.MethodPost, "/admin/periods/close", "/admin/periods/close", `{"period_end":"March"}`, "admin-1"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid date, got %d", rr.Code)
+	}
+	handler.periods = stubPeriodService{
+		closeFn: func(context.Context, string, time.Time) (store.AccountingPeriod, error) {
+			return store.AccountingPeriod{}, services.ErrPeriodOutOfOrder
+		},
+	}
+	if rr := servePeriodRequest(t, handler.ClosePeriod, http.MethodPost, "/admin/periods/close", "/admin/periods/close", `{"period_end":"2025-01-31"}`, "admin-1"); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for out of order close, got %d", rr.Code)
+	}
+}
+
+func TestReopenPeriod(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
+		isAdminFn: func(context.Context, string) (bool, bool, error) { return true, true, nil },
+	}, stubAuditStore{}, stubService{})
+	handler.periods = stubPeriodService{
+		reopenFn: func(_ context.Context, actorID, periodID, reason string) (store.AccountingPeriod, error) {
+			if actorID != "super-1" || periodID != "mar" || reason != "late invoice" {
+				t.Fatalf("unexpected reopen: %s %s %s", actorID, periodID, reason)
+			}
+			return store.AccountingPeriod{ID: periodID, Status: services.PeriodReopened, ReopenReason: &reason}, nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.ReopenPeriod, http.MethodPost, "/admin/periods/{id}/reopen", "/admin/periods/mar/reopen", `{"reason":" late invoice "}`, "super-1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	rr = servePeriodRequest(t, handler.ReopenPeriod, http.MethodPost, "/admin/periods/{id}/reopen", "/admin/periods/mar/reopen", `{}`, "super-1")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without reason, got %d", rr.Code)
+	}
+}
+
+func TestPeriodBalances(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.periods = stubPeriodService{
+		balancesFn: func(_ context.Context, periodID string) ([]store.PeriodBalance, error) {
+			return []store.PeriodBalance{{PeriodID: periodID, AccountID: "acc-1", Currency: "USD", Balance: 1250}}, nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.PeriodBalances, http.MethodGet, "/admin/periods/{id}/balances", "/admin/periods/mar/balances", "", "admin-1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var payload []map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload) != 1 || payload[0]["period_id"] != "mar" || payload[0]["balance"] != "12.50" {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"banking/internal/events"
+	"banking/internal/middleware"
+	"banking/internal/money"
+	"banking/internal/services"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type glMappingRequest struct {
+	AccountID string `json:"account_id"`
+	GLCode    string `json:"gl_code"`
+}
+
+func (h *Handler) ListGLAccounts(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.gl.ListAccounts(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load chart of accounts")
+		return
+	}
+	normalized := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		normalized = append(normalized, map[string]any{
+			"code":       row.Code,
+			"name":       row.Name,
+			"type":       row.Type,
+			"accounts":   row.Accounts,
+			"created_at": row.CreatedAt,
+		})
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
+
+func (h *Handler) MapGLAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req glMappingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AccountID == "" || req.GLCode == "" {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	var updated int64
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		var err error
+		updated, err = h.gl.MapSystemAccount(r.Context(), tx, req.AccountID, req.GLCode)
+		if err != nil || updated == 0 {
+			return err
+		}
+		payload := map[string]string{
+			"account_id": req.AccountID,
+			"gl_code":    req.GLCode,
+		}
+		data, _ := json.Marshal(payload)
+		if err := h.audit.Log(r.Context(), tx, userID, "map_gl_account", "account", req.AccountID, string(data)); err != nil {
+			return err
+		}
+		return h.outbox.Append(r.Context(), tx, events.New(events.GLAccountMapped, events.AggregateAccount, req.AccountID, &userID, payload))
+	})
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23503" {
+			respondError(w, http.StatusBadRequest, "unknown gl_code")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "unable to map account")
+		return
+	}
+	if updated == 0 {
+		respondError(w, http.StatusNotFound, "system account not found")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"status": "mapped"})
+}
+
+func (h *Handler) TrialBalance(w http.ResponseWriter, r *http.Request) {
+	from, to, currency, ok := parseReportRange(w, r)
+	if !ok {
+		return
+	}
+	rows, err := h.gl.Balances(r.Context(), from, to, currency)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load trial balance")
+		return
+	}
+	reports := services.BuildTrialBalances(rows)
+	normalized := make([]map[string]any, 0, len(reports))
+	for _, report := range reports {
+		lines := make([]map[string]any, 0, len(report.Lines))
+		for _, line := range report.Lines {
+			lines = append(lines, map[string]any{
+				"gl_code":        line.Code,
+				"name":           line.Name,
+				"type":           line.Type,
+				"opening_debit":  money.FormatMinor(line.OpeningDebit),
+				"opening_credit": money.FormatMinor(line.OpeningCredit),
+				"debits":         money.FormatMinor(line.Debits),
+				"credits":        money.FormatMinor(line.Credits),
+				"closing_debit":  money.FormatMinor(line.ClosingDebit),
+				"closing_credit": money.FormatMinor(line.ClosingCredit),
+			})
+		}
+		normalized = append(normalized, map[string]any{
+			"currency":      report.Currency,
+			"from":          from,
+			"to":            to,
+			"lines":         lines,
+			"total_debits":  money.FormatMinor(report.TotalDebits),
+			"total_credits": money.FormatMinor(report.TotalCredits),
+			"balanced":      report.Balanced,
+		})
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
+
+func (h *Handler) BalanceSheet(w http.ResponseWriter, r *http.Request) {
+	asOf := time.Now().UTC()
+	if raw := r.URL.Query().Get("as_of"); raw != "" {
+		parsed, err := parseAsOf(raw)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid as_of")
+			return
+		}
+		asOf = parsed
+	}
+	currency, ok := parseReportCurrency(w, r)
+	if !ok {
+		return
+	}
+	rows, err := h.gl.Balances(r.Context(), asOf, asOf, currency)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load balance sheet")
+		return
+	}
+	sheets := services.BuildBalanceSheets(rows)
+	normalized := make([]map[string]any, 0, len(sheets))
+	for _, sheet := range sheets {
+		normalized = append(normalized, map[string]any{
+			"currency":          sheet.Currency,
+			"as_of":             asOf,
+			"assets":            statementLinesResponse(sheet.Assets),
+			"liabilities":       statementLinesResponse(sheet.Liabilities),
+			"equity":            statementLinesResponse(sheet.Equity),
+			"retained_earnings": money.FormatMinor(sheet.RetainedEarnings),
+			"total_assets":      money.FormatMinor(sheet.TotalAssets),
+			"total_liabilities": money.FormatMinor(sheet.TotalLiabilities),
+			"total_equity":      money.FormatMinor(sheet.TotalEquity),
+			"balanced":          sheet.Balanced,
+		})
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
+
+func (h *Handler) IncomeStatement(w http.ResponseWriter, r *http.Request) {
+	from, to, currency, ok := parseReportRange(w, r)
+	if !ok {
+		return
+	}
+	rows, err := h.gl.Balances(r.Context(), from, to, currency)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load income statement")
+		return
+	}
+	statements := services.BuildIncomeStatements(rows)
+	normalized := make([]map[string]any, 0, len(statements))
+	for _, statement := range statements {
+		normalized = append(normalized, map[string]any{
+			"currency":      statement.Currency,
+			"from":          from,
+			"to":            to,
+			"income":        statementLinesResponse(statement.Income),
+			"expenses":      statementLinesResponse(statement.Expenses),
+			"total_income":  money.FormatMinor(statement.TotalIncome),
+			"total_expense": money.FormatMinor(statement.TotalExpense),
+			"net_income":    money.FormatMinor(statement.NetIncome),
+		})
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
+
+func parseReportRange(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, string, bool) {
+	fromRaw := r.URL.Query().Get("from")
+	toRaw := r.URL.Query().Get("to")
+	if fromRaw == "" || toRaw == "" {
+		respondError(w, http.StatusBadRequest, "from and to are required")
+		return time.Time{}, time.Time{}, "", false
+	}
+	from, err := parseFrom(fromRaw)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid from")
+		return time.Time{}, time.Time{}, "", false
+	}
+	to, err := parseAsOf(toRaw)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid to")
+		return time.Time{}, time.Time{}, "", false
+	}
+	if to.Before(from) {
+		respondError(w, http.StatusBadRequest, "invalid range")
+		return time.Time{}, time.Time{}, "", false
+	}
+	currency, ok := parseReportCurrency(w, r)
+	if !ok {
+		return time.Time{}, time.Time{}, "", false
+	}
+	return from, to, currency, true
+}
+
+func parseReportCurrency(w http.ResponseWriter, r *http.Request) (string, bool) {
+	currency := strings.ToUpper(r.URL.Query().Get("currency"))
+	if currency != "" && otherCurrency(currency) == "" {
+		respondError(w, http.StatusBadRequest, "invalid currency")
+		return "", false
+	}
+	return currency, true
+}
+
+func statementLinesResponse(lines []services.StatementLine) []map[string]any {
+	normalized := make([]map[string]any, 0, len(lines))
+	for _, line := range lines {
+		normalized = append(normalized, map[string]any{
+			"gl_code": line.Code,
+			"name":    line.Name,
+			"amount":  money.FormatMinor(line.Amount),
+		})
+	}
+	return normalized
+}
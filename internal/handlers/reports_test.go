@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/middleware"
+	"banking/internal/store"
+
+	"github.com/lib/pq"
+)
+
+func glBalancesFixture() []store.GLBalance {
+	return []store.GLBalance{
+		{Code: "1000", Name: "Settlement and treasury", Type: "asset", Currency: "USD", Opening: -1000, Debits: 150, Credits: 50, Closing: -1100},
+		{Code: "2000", Name: "Customer deposits", Type: "liability", Currency: "USD", Opening: 1000, Debits: 40, Credits: 100, Closing: 1060},
+		{Code: "4000", Name: "Foreign exchange income", Type: "income", Currency: "USD", Credits: 60, Closing: 60},
+		{Code: "5000", Name: "Customer incentives", Type: "expense", Currency: "USD", Debits: 20, Closing: -20},
+	}
+}
+
+func TestListGLAccounts(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.gl = stubGLStore{
+		listAccountsFn: func(context.Context) ([]store.GLAccount, error) {
+			return []store.GLAccount{{Code: "1000", Name: "Settlement and treasury", Type: "asset", Accounts: 2}}, nil
+		},
+	}
+	rr := httptest.NewRecorder()
+	handler.ListGLAccounts(rr, httptest.NewRequest(http.MethodGet, "/admin/gl/accounts", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var payload []map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload) != 1 || payload[0]["code"] != "1000" || payload[0]["type"] != "asset" {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+}
+
+func serveMapGLAccount(t *testing.T, handler *Handler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/admin/gl/mappings", bytes.NewReader([]byte(body)))
+	token, _ := auth.GenerateToken(testKeys, "admin-1", time.Minute)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.MapGLAccount)).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestMapGLAccount(t *testing.T) {
+	audited := ""
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
+		isAdminFn: func(context.Context, string) (bool, bool, error) { return true, true, nil },
+	}, stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, _, action, _, _, _ string) error {
+			audited = action
+			return nil
+		},
+	}, stubService{})
+	handler.gl = stubGLStore{
+		mapFn: func(_ context.Context, _ store.Execer, accountID, glCode string) (int64, error) {
+			if accountID != "sys-usd" || glCode != "4000" {
+				t.Fatalf("unexpected mapping: %s %s", accountID, glCode)
+			}
+			return 1, nil
+		},
+	}
+	rr := serveMapGLAccount(t, handler, `{"account_id":"sys-usd","gl_code":"4000"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if audited != "map_gl_account" {
+		t.Fatalf("expected audit log, got %q", audited)
+	}
+}
+
+func TestMapGLAccountErrors(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.gl = stubGLStore{
+		mapFn: func(context.Context, store.Execer, string, string) (int64, error) { return 0, nil },
+	}
+	if rr := serveMapGLAccount(t, handler, `{"account_id":"acc-1","gl_code":"4000"}`); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for customer account, got %d", rr.Code)
+	}
+
+	handler.gl = stubGLStore{
+		mapFn: func(context.Context, store.Execer, string, string) (int64, error) {
+			return 0, &pq.Error{Code: "23503"}
+		},
+	}
+	if rr := serveMapGLAccount(t, handler, `{"account_id":"sys-usd","gl_code":"9999"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown code, got %d", rr.Code)
+	}
+}
+
+func TestTrialBalance(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.gl = stubGLStore{
+		balancesFn: func(_ context.Context, from, to time.Time, currency string) ([]store.GLBalance, error) {
+			if !from.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) || to.Day() != 31 || currency != "USD" {
+				t.Fatalf("unexpected range: %v %v %s", from, to, currency)
+			}
+			return glBalancesFixture(), nil
+		},
+	}
+	rr := httptest.NewRecorder()
+	handler.TrialBalance(rr, httptest.NewRequest(http.MethodGet, "/admin/reports/trial-balance?from=2025-01-01&to=2025-03-31&currency=usd", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var payload []map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload) != 1 || payload[0]["total_debits"] != "11.20" || payload[0]["total_credits"] != "11.20" || payload[0]["balanced"] != true {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+}
+
+func TestTrialBalanceInvalidRange(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	for _, target := range []string{
+		"/admin/reports/trial-balance?to=2025-03-31",
+		"/admin/reports/trial-balance?from=2025-04-01&to=2025-03-31",
+		"/admin/reports/trial-balance?from=yesterday&to=2025-03-31",
+		"/admin/reports/trial-balance?from=2025-01-01&to=2025-03-31&currency=GBP",
+	} {
+		rr := httptest.NewRecorder()
+		handler.TrialBalance(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", target, rr.Code)
+		}
+	}
+}
+
+func TestBalanceSheet(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.gl = stubGLStore{
+		balancesFn: func(context.Context, time.Time, time.Time, string) ([]store.GLBalance, error) {
+			return glBalancesFixture(), nil
+		},
+	}
+	rr := httptest.NewRecorder()
+	handler.BalanceSheet(rr, httptest.NewRequest(http.MethodGet, "/admin/reports/balance-sheet?as_of=2025-03-31", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var payload []map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload) != 1 || payload[0]["total_assets"] != "11.00" || payload[0]["retained_earnings"] != "0.40" || payload[0]["balanced"] != true {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+}
+
+func TestIncomeStatement(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.gl = stubGLStore{
+		balancesFn: func(context.Context, time.Time, time.Time, string) ([]store.GLBalance, error) {
+			return glBalancesFixture(), nil
+		},
+	}
+	rr := httptest.NewRecorder()
+	handler.IncomeStatement(rr, httptest.NewRequest(http.MethodGet, "/admin/reports/income-statement?from=2025-01-01&to=2025-03-31", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var payload []map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload) != 1 || payload[0]["net_income"] != "0.40" || payload[0]["total_expense"] != "0.20" {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+}
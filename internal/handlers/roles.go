@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/events"
+	"banking/internal/middleware"
+	"banking/internal/store"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]{0,49}$`)
+
+func (h *Handler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, auth.Permissions)
+}
+
+func (h *Handler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.admin.ListRoles(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load roles")
+		return
+	}
+	normalized := make([]map[string]any, 0, len(roles))
+	for _, role := range roles {
+		normalized = append(normalized, roleResponse(role))
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
+
+type createRoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+func (h *Handler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req createRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if !roleNamePattern.MatchString(req.Name) {
+		respondError(w, http.StatusBadRequest, "name must be 1-50 letters, digits, '_', '.' or '-', starting with a letter")
+		return
+	}
+	if len(req.Permissions) == 0 {
+		respondError(w, http.StatusBadRequest, "permissions is required")
+		return
+	}
+	permissions := make([]string, 0, len(req.Permissions))
+	seen := make(map[string]bool, len(req.Permissions))
+	for _, permission := range req.Permissions {
+		if !auth.IsPermission(permission) {
+			respondError(w, http.StatusBadRequest, "unknown permission: "+permission)
+			return
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			permissions = append(permissions, permission)
+		}
+	}
+	role := store.Role{
+		Name:        req.Name,
+		Description: strings.TrimSpace(req.Description),
+		Permissions: permissions,
+		CreatedBy:   &userID,
+		CreatedAt:   time.Now().UTC(),
+	}
+	var created int64
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		var err error
+		created, err = h.admin.CreateRole(r.Context(), tx, role)
+		if err != nil || created == 0 {
+			return err
+		}
+		payload := map[string]any{
+			"role":        role.Name,
+			"description": role.Description,
+			"permissions": permissions,
+		}
+		data, _ := json.Marshal(payload)
+		if err := h.audit.Log(r.Context(), tx, userID, "create_role", "admin_role", role.Name, string(data)); err != nil {
+			return err
+		}
+		return h.outbox.Append(r.Context(), tx, events.New(events.AdminRoleCreated, events.AggregateRole, role.Name, &userID, payload))
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to create role")
+		return
+	}
+	if created == 0 {
+		respondError(w, http.StatusConflict, "role already exists")
+		return
+	}
+	respondJSON(w, http.StatusCreated, roleResponse(role))
+}
+
+func (h *Handler) RevokeRole(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req grantRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AdminUserID == "" || req.Role == "" {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	var revoked int64
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		var err error
+		revoked, err = h.admin.RevokeRole(r.Context(), tx, req.AdminUserID, req.Role)
+		if err != nil || revoked == 0 {
+			return err
+		}
+		payload := map[string]string{
+			"admin_user_id": req.AdminUserID,
+			"role":          req.Role,
+		}
+		data, _ := json.Marshal(payload)
+		if err := h.audit.Log(r.Context(), tx, userID, "revoke_role", "admin_role", req.AdminUserID, string(data)); err != nil {
+			return err
+		}
+		return h.outbox.Append(r.Context(), tx, events.New(events.AdminRoleRevoked, events.AggregateUser, req.AdminUserID, &userID, payload))
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to revoke role")
+		return
+	}
+	if revoked == 0 {
+		respondError(w, http.StatusNotFound, "role not granted")
+		return
+	}
+	h.adminAccess.Invalidate(req.AdminUserID)
+	respondJSON(w, http.StatusOK, map[string]string{"status": "role_revoked"})
+}
+
+type demoteRequest struct {
+	AdminUserID string `json:"admin_user_id"`
+}
+
+func (h *Handler) DemoteAdmin(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req demoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AdminUserID == "" {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if req.AdminUserID == userID {
+		respondError(w, http.StatusBadRequest, "cannot demote yourself")
+		return
+	}
+	isAdmin, isSuper, err := h.admin.IsAdmin(r.Context(), req.AdminUserID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to verify target admin")
+		return
+	}
+	if !isAdmin {
+		respondError(w, http.StatusNotFound, "admin not found")
+		return
+	}
+	if isSuper {
+		respondError(w, http.StatusBadRequest, "cannot demote super admin")
+		return
+	}
+	var removed int64
+	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		removed, err = h.admin.RemoveAdmin(r.Context(), tx, req.AdminUserID)
+		if err != nil || removed == 0 {
+			return err
+		}
+		payload := map[string]string{
+			"target_user_id": req.AdminUserID,
+		}
+		data, _ := json.Marshal(payload)
+		if err := h.audit.Log(r.Context(), tx, userID, "demote_admin", "admin", req.AdminUserID, string(data)); err != nil {
+			return err
+		}
+		return h.outbox.Append(r.Context(), tx, events.New(events.AdminDemoted, events.AggregateUser, req.AdminUserID, &userID, payload))
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to demote admin")
+		return
+	}
+	if removed == 0 {
+		respondError(w, http.StatusNotFound, "admin not found")
+		return
+	}
+	h.adminAccess.Invalidate(req.AdminUserID)
+	respondJSON(w, http.StatusOK, map[string]string{"status": "demoted"})
+}
+
+func (h *Handler) ListAdmins(w http.ResponseWriter, r *http.Request) {
+	admins, err := h.admin.ListAdmins(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load admins")
+		return
+	}
+	normalized := make([]map[string]any, 0, len(admins))
+	for _, admin := range admins {
+		permissions := []string(admin.Permissions)
+		if admin.IsSuper {
+			permissions = auth.Permissions
+		}
+		if permissions == nil {
+			permissions = []string{}
+		}
+		roles := []string(admin.Roles)
+		if roles == nil {
+			roles = []string{}
+		}
+		normalized = append(normalized, map[string]any{
+			"user_id":     admin.UserID,
+			"username":    admin.Username,
+			"email":       admin.Email,
+			"is_super":    admin.IsSuper,
+			"roles":       roles,
+			"permissions": permissions,
+			"created_at":  admin.CreatedAt,
+		})
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
+
+func roleResponse(role store.Role) map[string]any {
+	permissions := []string(role.Permissions)
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return map[string]any{
+		"name":        role.Name,
+		"description": role.Description,
+		"permissions": permissions,
+		"created_at":  role.CreatedAt,
+	}
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/events"
+	"banking/internal/store"
+)
+
+func superAdminStore() stubAdminStore {
+	return stubAdminStore{
+		isAdminFn: func(_ context.Context, userID string) (bool, bool, error) {
+			return true, userID == "admin-1", nil
+		},
+	}
+}
+
+func TestCreateRole(t *testing.T) {
+	admin := superAdminStore()
+	var created store.Role
+	admin.createRoleFn = func(_ context.Context, _ store.Execer, role store.Role) (int64, error) {
+		created = role
+		return 1, nil
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, admin, stubAuditStore{}, stubService{})
+	var appended []store.DomainEventInput
+	handler.outbox = stubOutboxStore{
+		appendFn: func(_ context.Context, _ store.Execer, input store.DomainEventInput) error {
+			appended = append(appended, input)
+			return nil
+		},
+	}
+	body := `{"name":"auditor","description":"Reads reports","permissions":["CanViewReports","CanViewReports","CanViewAdmins"]}`
+	rr := servePeriodRequest(t, handler.CreateRole, http.MethodPost, "/admin/roles", "/admin/roles", body, "admin-1")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if created.Name != "auditor" || len(created.Permissions) != 2 || *created.CreatedBy != "admin-1" {
+		t.Fatalf("unexpected role: %#v", created)
+	}
+	if len(appended) != 1 || appended[0].Type != events.AdminRoleCreated || appended[0].AggregateID != "auditor" {
+		t.Fatalf("unexpected events: %#v", appended)
+	}
+}
+
+func TestCreateRoleRejects(t *testing.T) {
+	admin := superAdminStore()
+	admin.createRoleFn = func(context.Context, store.Execer, store.Role) (int64, error) { return 0, nil }
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, admin, stubAuditStore{}, stubService{})
+	cases := []struct {
+		userID, body string
+		want         int
+	}{
+		{"admin-1", `{"name":"auditor","permissions":["CanDoAnything"]}`, http.StatusBadRequest},
+		{"admin-1", `{"name":"auditor","permissions":[]}`, http.StatusBadRequest},
+		{"admin-1", `{"name":"no spaces","permissions":["CanViewReports"]}`, http.StatusBadRequest},
+		{"admin-1", `{"name":"viewer","permissions":["CanViewReports"]}`, http.StatusConflict},
+	}
+	for _, tc := range cases {
+		rr := servePeriodRequest(t, handler.CreateRole, http.MethodPost, "/admin/roles", "/admin/roles", tc.body, tc.userID)
+		if rr.Code != tc.want {
+			t.Fatalf("expected %d for %s as %s, got %d", tc.want, tc.body, tc.userID, rr.Code)
+		}
+	}
+}
+
+func TestGrantRoleUnknownRole(t *testing.T) {
+	admin := superAdminStore()
+	admin.getRoleFn = func(context.Context, string) (store.Role, error) { return store.Role{}, sql.ErrNoRows }
+	admin.grantRoleFn = func(context.Context, store.Execer, string, string, string) error {
+		t.Fatalf("unknown roles must not be granted")
+		return nil
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, admin, stubAuditStore{}, stubService{})
+	rr := servePeriodRequest(t, handler.GrantRole, http.MethodPost, "/admin/roles/grant", "/admin/roles/grant", `{"admin_user_id":"admin-2","role":"ghost"}`, "admin-1")
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestRevokeRole(t *testing.T) {
+	admin := superAdminStore()
+	admin.revokeRoleFn = func(_ context.Context, _ store.Execer, adminUserID, role string) (int64, error) {
+		if adminUserID != "admin-2" || role != "viewer" {
+			t.Fatalf("unexpected revoke: %s %s", adminUserID, role)
+		}
+		return 1, nil
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, admin, stubAuditStore{}, stubService{})
+	var action string
+	handler.audit = stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, _, auditAction, _, _, _ string) error {
+			action = auditAction
+			return nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.RevokeRole, http.MethodPost, "/admin/roles/revoke", "/admin/roles/revoke", `{"admin_user_id":"admin-2","role":"viewer"}`, "admin-1")
+	if rr.Code != http.StatusOK || action != "revoke_role" {
+		t.Fatalf("expected revocation, got %d %q", rr.Code, action)
+	}
+
+	admin.revokeRoleFn = func(context.Context, store.Execer, string, string) (int64, error) { return 0, nil }
+	handler.admin = admin
+	rr = servePeriodRequest(t, handler.RevokeRole, http.MethodPost, "/admin/roles/revoke", "/admin/roles/revoke", `{"admin_user_id":"admin-2","role":"viewer"}`, "admin-1")
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a role not granted, got %d", rr.Code)
+	}
+}
+
+func TestDemoteAdmin(t *testing.T) {
+	admin := superAdminStore()
+	admin.isAdminFn = func(_ context.Context, userID string) (bool, bool, error) {
+		switch userID {
+		case "admin-1", "admin-3":
+			return true, true, nil
+		case "admin-2":
+			return true, false, nil
+		}
+		return false, false, nil
+	}
+	removed := 0
+	admin.removeAdminFn = func(_ context.Context, _ store.Execer, userID string) (int64, error) {
+		if userID != "admin-2" {
+			t.Fatalf("unexpected demotion: %s", userID)
+		}
+		removed++
+		return 1, nil
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, admin, stubAuditStore{}, stubService{})
+	var appended []store.DomainEventInput
+	handler.outbox = stubOutboxStore{
+		appendFn: func(_ context.Context, _ store.Execer, input store.DomainEventInput) error {
+			appended = append(appended, input)
+			return nil
+		},
+	}
+	cases := []struct {
+		target string
+		want   int
+	}{
+		{"admin-1", http.StatusBadRequest},
+		{"admin-3", http.StatusBadRequest},
+		{"user-9", http.StatusNotFound},
+		{"admin-2", http.StatusOK},
+	}
+	for _, tc := range cases {
+		rr := servePeriodRequest(t, handler.DemoteAdmin, http.MethodPost, "/admin/demote", "/admin/demote", `{"admin_user_id":"`+tc.target+`"}`, "admin-1")
+		if rr.Code != tc.want {
+			t.Fatalf("expected %d demoting %s, got %d", tc.want, tc.target, rr.Code)
+		}
+	}
+	if removed != 1 || len(appended) != 1 || appended[0].Type != events.AdminDemoted {
+		t.Fatalf("unexpected demotion: %d %#v", removed, appended)
+	}
+}
+
+func TestListAdminsShowsEffectivePermissions(t *testing.T) {
+	admin := superAdminStore()
+	admin.listAdminsFn = func(context.Context) ([]store.AdminSummary, error) {
+		return []store.AdminSummary{
+			{UserID: "admin-1", IsSuper: true},
+			{UserID: "admin-2", Roles: []string{"viewer"}, Permissions: []string{auth.PermissionViewReports}},
+		}, nil
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, admin, stubAuditStore{}, stubService{})
+	rr := servePeriodRequest(t, handler.ListAdmins, http.MethodGet, "/admin/admins", "/admin/admins", "", "admin-1")
+	var admins []struct {
+		UserID      string   `json:"user_id"`
+		Roles       []string `json:"roles"`
+		Permissions []string `json:"permissions"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &admins); err != nil || len(admins) != 2 {
+		t.Fatalf("unexpected response: %s", rr.Body.String())
+	}
+	if len(admins[0].Permissions) != len(auth.Permissions) || len(admins[0].Roles) != 0 {
+		t.Fatalf("super admins hold every permission: %#v", admins[0])
+	}
+	if len(admins[1].Permissions) != 1 || admins[1].Permissions[0] != auth.PermissionViewReports {
+		t.Fatalf("unexpected permissions: %#v", admins[1])
+	}
+}
+
+func TestAdminRoutesCheckPermissions(t *testing.T) {
+	admin := stubAdminStore{
+		isAdminFn: func(context.Context, string) (bool, bool, error) { return true, false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) {
+			return []string{auth.PermissionViewReports}, nil
+		},
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, admin, stubAuditStore{}, stubService{})
+	routes := handler.Routes()
+	token, err := auth.GenerateToken(testKeys, "admin-2", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	cases := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/admin/periods", http.StatusOK},
+		{http.MethodGet, "/admin/users", http.StatusForbidden},
+		{http.MethodGet, "/admin/admins", http.StatusForbidden},
+		{http.MethodPost, "/admin/roles", http.StatusForbidden},
+		{http.MethodPost, "/admin/roles/grant", http.StatusForbidden},
+		{http.MethodPost, "/admin/roles/revoke", http.StatusForbidden},
+		{http.MethodPost, "/admin/promote", http.StatusForbidden},
+		{http.MethodPost, "/admin/demote", http.StatusForbidden},
+		{http.MethodPost, "/admin/gl/mappings", http.StatusForbidden},
+		{http.MethodPost, "/admin/periods/mar/reopen", http.StatusForbidden},
+		{http.MethodPost, "/admin/webhooks", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Fatalf("expected %d for %s, got %d: %s", tc.want, tc.path, rr.Code, rr.Body.String())
+		}
+	}
+}
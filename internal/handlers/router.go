@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"banking/internal/auth"
 	"banking/internal/config"
 	"banking/internal/db"
 	"banking/internal/middleware"
@@ -15,75 +16,212 @@ import (
 )
 
 type Handler struct {
-	reconcileDB  store.Selecter
-	txRunner     db.TxRunner
-	cfg          config.Config
-	users        UserStore
-	accounts     AccountStore
-	ledger       LedgerStore
-	transactions TransactionStore
-	exchange     ExchangeStore
-	admin        AdminStore
-	audit        AuditStore
-	service      TransactionService
-	hub          *websocket.Hub
+	reconcileDB   store.Selecter
+	txRunner      db.TxRunner
+	cfg           config.Config
+	users         UserStore
+	accounts      AccountStore
+	ledger        LedgerStore
+	balances      BalanceStore
+	gl            GLStore
+	transactions  TransactionStore
+	exchange      ExchangeStore
+	admin         AdminStore
+	adminAccess   *middleware.AdminCache
+	audit         AuditStore
+	outbox        OutboxStore
+	webhooks      WebhookStore
+	streams       StreamStore
+	quotes        QuoteStore
+	tickets       WSTicketStore
+	notifications NotificationStore
+	devices       DeviceStore
+	alerts        AlertRuleStore
+	sessions      SessionStore
+	keys          *auth.KeyRing
+	revocations   *middleware.Revocations
+	rateLimits    *middleware.RateLimiter
+	twoFactor     TwoFactorStore
+	challenges    LoginChallengeStore
+	throttles     LoginThrottleStore
+	tokens        UserTokenStore
+	apiKeys       APIKeyStore
+	organizations OrganizationStore
+	approvals     ApprovalStore
+	service       TransactionService
+	periods       PeriodService
+	hub           *websocket.Hub
 }
 
-func New(reconcileDB store.Selecter, txRunner db.TxRunner, cfg config.Config, users UserStore, accounts AccountStore, ledger LedgerStore, transactions TransactionStore, exchange ExchangeStore, admin AdminStore, audit AuditStore, service TransactionService, hub *websocket.Hub) *Handler {
+func New(deps Deps) *Handler {
 	return &Handler{
-		reconcileDB:  reconcileDB,
-		txRunner:     txRunner,
-		cfg:          cfg,
-		users:        users,
-		accounts:     accounts,
-		ledger:       ledger,
-		transactions: transactions,
-		exchange:     exchange,
-		admin:        admin,
-		audit:        audit,
-		service:      service,
-		hub:          hub,
+		reconcileDB:   deps.ReconcileDB,
+		txRunner:      deps.TxRunner,
+		cfg:           deps.Config,
+		users:         deps.Users,
+		accounts:      deps.Accounts,
+		ledger:        deps.Ledger,
+		balances:      deps.Balances,
+		gl:            deps.GL,
+		transactions:  deps.Transactions,
+		exchange:      deps.Exchange,
+		admin:         deps.Admin,
+		adminAccess:   middleware.NewAdminCache(deps.Admin, deps.Config.AdminCacheTTL),
+		audit:         deps.Audit,
+		outbox:        deps.Outbox,
+		webhooks:      deps.Webhooks,
+		streams:       deps.Streams,
+		quotes:        deps.Quotes,
+		tickets:       deps.Tickets,
+		notifications: deps.Notifications,
+		devices:       deps.Devices,
+		alerts:        deps.Alerts,
+		sessions:      deps.Sessions,
+		keys:          deps.Keys,
+		revocations:   deps.Revocations,
+		rateLimits:    deps.RateLimits,
+		twoFactor:     deps.TwoFactor,
+		challenges:    deps.Challenges,
+		throttles:     deps.Throttles,
+		tokens:        deps.Tokens,
+		apiKeys:       deps.APIKeys,
+		organizations: deps.Organizations,
+		approvals:     deps.Approvals,
+		service:       deps.Service,
+		periods:       deps.Periods,
+		hub:           deps.Hub,
 	}
 }
 
 func (h *Handler) Routes() http.Handler {
 	router := chi.NewRouter()
+	router.Use(middleware.ClientIP(h.cfg.TrustedProxies))
 	router.Use(chimiddleware.Logger)
 	router.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{h.cfg.AllowedOrigins},
+		AllowedOrigins:   allowedOrigins(h.cfg.AllowedOrigins),
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Last-Event-ID", "X-Request-ID"},
+		ExposedHeaders:   []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 	router.Route("/auth", func(r chi.Router) {
-		r.Post("/register", h.Register)
-		r.Post("/login", h.Login)
-		r.With(middleware.Auth(h.cfg.JWTSecret)).Get("/me", h.Me)
+		r.With(h.rateLimits.Limit("register")).Post("/register", h.Register)
+		r.With(h.rateLimits.Limit("login")).Post("/login", h.Login)
+		r.With(h.rateLimits.Limit("verify")).Post("/login/verify", h.VerifyLogin)
+		r.With(h.rateLimits.Limit("refresh")).Post("/refresh", h.Refresh)
+		r.Post("/email/verify", h.VerifyEmail)
+		r.With(h.rateLimits.Limit("password_reset")).Post("/password/reset/request", h.RequestPasswordReset)
+		r.With(h.rateLimits.Limit("verify")).Post("/password/reset", h.ResetPassword)
+		r.With(middleware.Auth(h.keys, h.revocations)).Post("/logout", h.Logout)
+		r.With(middleware.Auth(h.keys, h.revocations)).Get("/sessions", h.ListSessions)
+		r.With(middleware.Auth(h.keys, h.revocations)).Delete("/sessions", h.RevokeOtherSessions)
+		r.With(middleware.Auth(h.keys, h.revocations)).Delete("/sessions/{id}", h.RevokeSession)
+		r.With(middleware.Auth(h.keys, h.revocations)).Get("/me", h.Me)
+		r.With(middleware.Auth(h.keys, h.revocations)).Post("/password", h.ChangePassword)
+		r.With(middleware.Auth(h.keys, h.revocations)).Post("/email/verify/request", h.RequestEmailVerification)
+		r.With(middleware.Auth(h.keys, h.revocations)).Post("/totp/enroll", h.EnrollTOTP)
+		r.With(middleware.Auth(h.keys, h.revocations)).Post("/totp/confirm", h.ConfirmTOTP)
+		r.With(middleware.Auth(h.keys, h.revocations)).Post("/totp/disable", h.DisableTOTP)
 	})
-	router.With(middleware.Auth(h.cfg.JWTSecret)).Get("/accounts", h.ListAccounts)
-	router.With(middleware.Auth(h.cfg.JWTSecret)).Get("/accounts/{id}/balance", h.GetBalance)
-	router.With(middleware.Auth(h.cfg.JWTSecret)).Get("/accounts/self-check", h.SelfCheck)
-	router.With(middleware.Auth(h.cfg.JWTSecret)).Post("/transactions/transfer", h.Transfer)
-	router.With(middleware.Auth(h.cfg.JWTSecret)).Post("/transactions/exchange/quote", h.ExchangeQuote)
-	router.With(middleware.Auth(h.cfg.JWTSecret)).Post("/transactions/exchange", h.Exchange)
-	router.With(middleware.Auth(h.cfg.JWTSecret)).Get("/transactions", h.ListTransactions)
-	router.With(middleware.Auth(h.cfg.JWTSecret)).Get("/users/username/{username}", h.GetUserByUsername)
-	router.With(middleware.Auth(h.cfg.JWTSecret)).Get("/users/email/{email}", h.GetUserByEmail)
+	router.With(h.authScope(auth.ScopeReadAccounts)).Get("/accounts", h.ListAccounts)
+	router.With(h.authScope(auth.ScopeReadAccounts)).Get("/accounts/{id}/balance", h.GetBalance)
+	router.With(h.authScope(auth.ScopeReadAccounts)).Get("/accounts/self-check", h.SelfCheck)
+	router.With(h.authScope(auth.ScopeWriteTransfers), h.rateLimits.Limit("transfer")).Post("/transactions/transfer", h.Transfer)
+	router.With(h.authScope(auth.ScopeWriteExchanges), h.rateLimits.Limit("quote")).Post("/transactions/exchange/quote", h.ExchangeQuote)
+	router.With(h.authScope(auth.ScopeWriteExchanges), h.rateLimits.Limit("exchange")).Post("/transactions/exchange", h.Exchange)
+	router.With(h.authScope(auth.ScopeReadTransactions)).Get("/transactions", h.ListTransactions)
+	router.With(h.authScope(auth.ScopeReadTransactions)).Get("/transactions/approvals", h.ListMyApprovals)
+	router.With(h.authScope(auth.ScopeReadUsers)).Get("/users/username/{username}", h.GetUserByUsername)
+	router.With(h.authScope(auth.ScopeReadUsers)).Get("/users/email/{email}", h.GetUserByEmail)
+	router.Route("/webhooks", func(r chi.Router) {
+		r.With(h.authScope(auth.ScopeReadWebhooks)).Get("/", h.ListWebhooks)
+		r.With(h.authScope(auth.ScopeWriteWebhooks)).Post("/", h.CreateWebhook)
+		r.With(h.authScope(auth.ScopeWriteWebhooks)).Delete("/{id}", h.DeleteWebhook)
+		r.With(h.authScope(auth.ScopeWriteWebhooks)).Post("/{id}/enable", h.EnableWebhook)
+		r.With(h.authScope(auth.ScopeReadWebhooks)).Get("/{id}/deliveries", h.ListWebhookDeliveries)
+		r.With(h.authScope(auth.ScopeReadWebhooks)).Get("/{id}/deliveries/{deliveryID}/attempts", h.ListWebhookAttempts)
+		r.With(h.authScope(auth.ScopeWriteWebhooks)).Post("/{id}/deliveries/{deliveryID}/redeliver", h.RedeliverWebhook)
+	})
+	router.Route("/api-keys", func(r chi.Router) {
+		r.Use(middleware.Auth(h.keys, h.revocations))
+		r.Get("/", h.ListAPIKeys)
+		r.Post("/", h.CreateAPIKey)
+		r.Delete("/{id}", h.RevokeAPIKey)
+	})
+	router.Route("/organizations", func(r chi.Router) {
+		r.Use(middleware.Auth(h.keys, h.revocations))
+		r.Get("/", h.ListOrganizations)
+		r.Post("/", h.CreateOrganization)
+		r.Get("/{id}/members", h.ListOrganizationMembers)
+		r.Post("/{id}/members", h.AddOrganizationMember)
+		r.Delete("/{id}/members/{userID}", h.RemoveOrganizationMember)
+		r.Get("/{id}/api-keys", h.ListOrganizationAPIKeys)
+		r.Post("/{id}/api-keys", h.CreateOrganizationAPIKey)
+		r.Delete("/{id}/api-keys/{keyID}", h.RevokeOrganizationAPIKey)
+	})
+	router.Route("/alerts/rules", func(r chi.Router) {
+		r.Use(middleware.Auth(h.keys, h.revocations))
+		r.Get("/", h.ListAlertRules)
+		r.Post("/", h.CreateAlertRule)
+		r.Delete("/{id}", h.DeleteAlertRule)
+	})
+	router.Route("/notifications", func(r chi.Router) {
+		r.Use(middleware.Auth(h.keys, h.revocations))
+		r.Get("/", h.ListNotifications)
+		r.Get("/preferences", h.GetNotificationPreferences)
+		r.Put("/preferences", h.UpdateNotificationPreferences)
+	})
+	router.With(middleware.Auth(h.keys, h.revocations)).Post("/ws/ticket", h.CreateWSTicket)
 	router.Get("/ws/balances", h.WSBalances)
+	router.Get("/ws", h.WS)
+	router.With(h.authScope(auth.ScopeReadEvents)).Get("/events", h.Events)
 
 	router.Route("/admin", func(r chi.Router) {
-		r.Use(middleware.Auth(h.cfg.JWTSecret))
-		r.With(middleware.RequireAdmin(h.admin, "CanViewUsers")).Get("/users", h.AdminListUsers)
-		r.With(middleware.RequireAdmin(h.admin, "CanViewTransactions")).Get("/transactions", h.AdminListTransactions)
-		r.With(middleware.RequireAdmin(h.admin, "")).Post("/roles/grant", h.GrantRole)
-		r.With(middleware.RequireAdmin(h.admin, "")).Post("/promote", h.PromoteAdmin)
-		r.With(middleware.RequireAdmin(h.admin, "CanViewTransactions")).Get("/audit", h.ListAuditLogs)
-		r.With(middleware.RequireAdmin(h.admin, "CanViewTransactions")).Get("/reconcile", h.Reconcile)
+		r.Use(middleware.Auth(h.keys, h.revocations))
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionViewUsers)).Get("/users", h.AdminListUsers)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionViewTransactions)).Get("/transactions", h.AdminListTransactions)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionViewAdmins)).Get("/admins", h.ListAdmins)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionViewAdmins)).Get("/permissions", h.ListPermissions)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionViewAdmins)).Get("/roles", h.ListRoles)
+		r.With(middleware.RequireSuperAdmin(h.adminAccess)).Post("/roles", h.CreateRole)
+		r.With(middleware.RequireSuperAdmin(h.adminAccess)).Post("/roles/grant", h.GrantRole)
+		r.With(middleware.RequireSuperAdmin(h.adminAccess)).Post("/roles/revoke", h.RevokeRole)
+		r.With(middleware.RequireSuperAdmin(h.adminAccess)).Post("/promote", h.PromoteAdmin)
+		r.With(middleware.RequireSuperAdmin(h.adminAccess)).Post("/demote", h.DemoteAdmin)
+		r.With(middleware.RequireAdmin(h.adminAccess, "")).Get("/approvals", h.ListApprovals)
+		r.With(middleware.RequireAdmin(h.adminAccess, "")).Get("/approvals/{id}", h.GetApproval)
+		r.With(middleware.RequireAdmin(h.adminAccess, "")).Post("/approvals/{id}/approve", h.ApproveRequest)
+		r.With(middleware.RequireAdmin(h.adminAccess, "")).Post("/approvals/{id}/reject", h.RejectRequest)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionViewTransactions)).Get("/audit", h.ListAuditLogs)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionViewTransactions)).Get("/events/undelivered", h.ListUndeliveredEvents)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionViewTransactions)).Get("/reconcile", h.Reconcile)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionViewTransactions)).Get("/balances", h.AdminBalancesAsOf)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionViewReports)).Get("/gl/accounts", h.ListGLAccounts)
+		r.With(middleware.RequireSuperAdmin(h.adminAccess)).Post("/gl/mappings", h.MapGLAccount)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionViewReports)).Get("/reports/trial-balance", h.TrialBalance)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionViewReports)).Get("/reports/balance-sheet", h.BalanceSheet)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionViewReports)).Get("/reports/income-statement", h.IncomeStatement)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionViewReports)).Get("/periods", h.ListPeriods)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionViewReports)).Get("/periods/{id}/balances", h.PeriodBalances)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionClosePeriods)).Post("/periods/close", h.ClosePeriod)
+		r.With(middleware.RequireSuperAdmin(h.adminAccess)).Post("/periods/{id}/reopen", h.ReopenPeriod)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionPostAdjustments)).Post("/adjustments", h.PostAdjustment)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionPostAdjustments)).Post("/transactions/{id}/reverse", h.ReverseTransaction)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionManageRates)).Post("/exchange-rate", h.SetExchangeRate)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionFreezeAccounts)).Post("/accounts/{id}/freeze", h.FreezeAccount)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionFreezeAccounts)).Post("/accounts/{id}/unfreeze", h.UnfreezeAccount)
+		r.With(middleware.RequireAdmin(h.adminAccess, auth.PermissionFreezeAccounts)).Post("/users/{id}/unlock", h.UnlockUser)
+		r.With(middleware.RequireSuperAdmin(h.adminAccess)).Post("/webhooks", h.CreateAdminWebhook)
 	})
 
+	router.Get("/.well-known/jwks.json", h.JWKS)
 	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 	})
 	return router
 }
+
+func (h *Handler) authScope(scope string) func(http.Handler) http.Handler {
+	return middleware.AuthOrAPIKey(h.keys, h.revocations, h.apiKeys, scope)
+}
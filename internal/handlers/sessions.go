@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"banking/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+)
+
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	sessions, err := h.sessions.ListActive(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load sessions")
+		return
+	}
+	currentID := middleware.SessionIDFromContext(r.Context())
+	items := make([]map[string]any, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, map[string]any{
+			"id":           session.ID,
+			"device":       session.DeviceLabel,
+			"ip":           session.IP,
+			"user_agent":   session.UserAgent,
+			"created_at":   session.CreatedAt,
+			"last_seen_at": session.LastSeenAt,
+			"current":      session.ID == currentID,
+		})
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"sessions": items})
+}
+
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	sessionID := chi.URLParam(r, "id")
+	var revoked bool
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		var err error
+		revoked, err = h.revokeSession(r.Context(), tx, r, userID, sessionID, "user_revoked")
+		return err
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to revoke session")
+		return
+	}
+	if !revoked {
+		respondError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	h.endSessions(userID, sessionID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	currentID := middleware.SessionIDFromContext(r.Context())
+	if currentID == "" {
+		respondError(w, http.StatusBadRequest, "token has no session")
+		return
+	}
+	var sessionIDs []string
+	err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		var err error
+		sessionIDs, err = h.sessions.RevokeAll(r.Context(), tx, userID, currentID, "user_revoked_others")
+		if err != nil || len(sessionIDs) == 0 {
+			return err
+		}
+		data, _ := json.Marshal(map[string]any{
+			"session_ids": sessionIDs,
+			"kept":        currentID,
+			"ip":          middleware.RemoteIP(r),
+			"user_agent":  r.UserAgent(),
+		})
+		if err := h.audit.Log(r.Context(), tx, userID, "revoke_other_sessions", "user", userID, string(data)); err != nil {
+			return err
+		}
+		return h.appendSessionsRevoked(r.Context(), tx, userID, sessionIDs, "user_revoked_others")
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to revoke sessions")
+		return
+	}
+	h.endSessions(userID, sessionIDs...)
+	respondJSON(w, http.StatusOK, map[string]int{"revoked": len(sessionIDs)})
+}
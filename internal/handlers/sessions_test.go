@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/events"
+	"banking/internal/middleware"
+	"banking/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func serveSessionRequest(t *testing.T, handler *Handler, fn http.HandlerFunc, method, pattern, target, userID, sessionID string) *httptest.ResponseRecorder {
+	t.Helper()
+	router := chi.NewRouter()
+	router.Method(method, pattern, middleware.Auth(testKeys, handler.revocations)(fn))
+	token, err := auth.GenerateSessionToken(testKeys, userID, sessionID, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestListSessionsMarksCurrent(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.sessions = stubSessionStore{
+		listActiveFn: func(_ context.Context, userID string) ([]store.Session, error) {
+			if userID != "user-1" {
+				t.Fatalf("unexpected user: %s", userID)
+			}
+			return []store.Session{
+				{ID: "session-1", DeviceLabel: "Firefox on Linux", IP: "10.0.0.1"},
+				{ID: "session-2", DeviceLabel: "Safari on iOS", IP: "10.0.0.2"},
+			}, nil
+		},
+	}
+	rr := serveSessionRequest(t, handler, handler.ListSessions, http.MethodGet, "/auth/sessions", "/auth/sessions", "user-1", "session-2")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var body struct {
+		Sessions []struct {
+			ID      string `json:"id"`
+			Device  string `json:"device"`
+			Current bool   `json:"current"`
+		} `json:"sessions"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(body.Sessions) != 2 || body.Sessions[0].Current || !body.Sessions[1].Current || body.Sessions[1].Device != "Safari on iOS" {
+		t.Fatalf("unexpected sessions: %#v", body.Sessions)
+	}
+}
+
+func TestRevokeSessionTakesEffectAtOnce(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.revocations = middleware.NewRevocations(nil, time.Minute)
+	var revokedID, reason string
+	handler.sessions = stubSessionStore{
+		revokeFn: func(_ context.Context, _ store.Getter, userID, sessionID, revokeReason string) (bool, error) {
+			if userID != "user-1" {
+				t.Fatalf("unexpected user: %s", userID)
+			}
+			revokedID, reason = sessionID, revokeReason
+			return true, nil
+		},
+	}
+	rr := serveSessionRequest(t, handler, handler.RevokeSession, http.MethodDelete, "/auth/sessions/{id}", "/auth/sessions/session-2", "user-1", "session-1")
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if revokedID != "session-2" || reason != "user_revoked" || !handler.revocations.Revoked("session-2") {
+		t.Fatalf("unexpected revocation: %s %s", revokedID, reason)
+	}
+	rr = serveSessionRequest(t, handler, handler.ListSessions, http.MethodGet, "/auth/sessions", "/auth/sessions", "user-1", "session-2")
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the revoked session's token to be refused, got %d", rr.Code)
+	}
+}
+
+func TestRevokeSessionNotFound(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.sessions = stubSessionStore{
+		// Another user's session, or one already revoked.
+		revokeFn: func(context.Context, store.Getter, string, string, string) (bool, error) { return false, nil },
+	}
+	rr := serveSessionRequest(t, handler, handler.RevokeSession, http.MethodDelete, "/auth/sessions/{id}", "/auth/sessions/session-9", "user-1", "session-1")
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestRevokeOtherSessionsKeepsCurrent(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.revocations = middleware.NewRevocations(nil, time.Minute)
+	handler.sessions = stubSessionStore{
+		revokeAllFn: func(_ context.Context, _ store.Selecter, userID, exceptSessionID, reason string) ([]string, error) {
+			if userID != "user-1" || exceptSessionID != "session-1" || reason != "user_revoked_others" {
+				t.Fatalf("unexpected revoke: %s %s %s", userID, exceptSessionID, reason)
+			}
+			return []string{"session-2", "session-3"}, nil
+		},
+	}
+	var action string
+	handler.audit = stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, _, auditAction, _, _, _ string) error {
+			action = auditAction
+			return nil
+		},
+	}
+	var revokedEvents int
+	handler.outbox = stubOutboxStore{
+		appendFn: func(_ context.Context, _ store.Execer, input store.DomainEventInput) error {
+			if input.Type == events.SessionRevoked {
+				revokedEvents++
+			}
+			return nil
+		},
+	}
+	rr := serveSessionRequest(t, handler, handler.RevokeOtherSessions, http.MethodDelete, "/auth/sessions", "/auth/sessions", "user-1", "session-1")
+	if rr.Code != http.StatusOK || rr.Body.String() != "{\"revoked\":2}\n" {
+		t.Fatalf("expected 200 with 2 revoked, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if action != "revoke_other_sessions" || revokedEvents != 2 {
+		t.Fatalf("unexpected audit %q or %d events", action, revokedEvents)
+	}
+	if handler.revocations.Revoked("session-1") || !handler.revocations.Revoked("session-2") || !handler.revocations.Revoked("session-3") {
+		t.Fatal("expected only the other sessions to be revoked")
+	}
+}
@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/middleware"
+	"banking/internal/store"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	loginChallengeTTL         = 5 * time.Minute
+	loginChallengeMaxAttempts = 5
+	recoveryCodeCount         = 10
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type totpCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type disableTOTPRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type verifyLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+func (h *Handler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	state, err := h.twoFactor.GetTOTP(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to start enrollment")
+		return
+	}
+	if state.Enabled {
+		respondError(w, http.StatusConflict, "two-factor authentication already enabled")
+		return
+	}
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to start enrollment")
+		return
+	}
+	sealed, err := auth.SealSecret(h.cfg.TOTPEncryptionKey, secret)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to start enrollment")
+		return
+	}
+	var updated int64
+	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		var err error
+		if updated, err = h.twoFactor.SetPendingTOTP(r.Context(), tx, userID, sealed); err != nil || updated == 0 {
+			return err
+		}
+		return h.auditTOTP(r.Context(), tx, r, userID, "totp_enroll")
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to start enrollment")
+		return
+	}
+	if updated == 0 {
+		respondError(w, http.StatusConflict, "two-factor authentication already enabled")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{
+		"secret":           secret,
+		"provisioning_uri": auth.TOTPProvisioningURI(h.cfg.TOTPIssuer, state.Email, secret),
+	})
+}
+
+func (h *Handler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req totpCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	state, err := h.twoFactor.GetTOTP(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to enable two-factor authentication")
+		return
+	}
+	if state.Enabled {
+		respondError(w, http.StatusConflict, "two-factor authentication already enabled")
+		return
+	}
+	if state.Secret == nil {
+		respondError(w, http.StatusBadRequest, "no enrollment in progress")
+		return
+	}
+	secret, err := auth.OpenSecret(h.cfg.TOTPEncryptionKey, *state.Secret)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to enable two-factor authentication")
+		return
+	}
+	counter, ok := auth.ValidateTOTP(secret, req.Code, time.Now())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "invalid code")
+		return
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to enable two-factor authentication")
+		return
+	}
+	var updated int64
+	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		var err error
+		if updated, err = h.twoFactor.EnableTOTP(r.Context(), tx, userID, counter); err != nil || updated == 0 {
+			return err
+		}
+		if err := h.twoFactor.ReplaceRecoveryCodes(r.Context(), tx, userID, hashes); err != nil {
+			return err
+		}
+		return h.auditTOTP(r.Context(), tx, r, userID, "totp_enable")
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to enable two-factor authentication")
+		return
+	}
+	if updated == 0 {
+		respondError(w, http.StatusConflict, "two-factor authentication already enabled")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTOTP needs the password and a second factor, so a stolen session
+// alone cannot turn two-factor off.
+func (h *Handler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req disableTOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	user, err := h.users.GetByID(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load user")
+		return
+	}
+	if !auth.CheckPassword(valueToString(user["password_hash"]), req.Password) {
+		respondError(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+	state, err := h.twoFactor.GetTOTP(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to disable two-factor authentication")
+		return
+	}
+	if !state.Enabled {
+		respondError(w, http.StatusBadRequest, "two-factor authentication is not enabled")
+		return
+	}
+	if _, ok, err := h.checkSecondFactor(r.Context(), userID, state, req.Code, req.RecoveryCode); err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to disable two-factor authentication")
+		return
+	} else if !ok {
+		respondError(w, http.StatusUnauthorized, "invalid code")
+		return
+	}
+	err = h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		if _, err := h.twoFactor.DisableTOTP(r.Context(), tx, userID); err != nil {
+			return err
+		}
+		return h.auditTOTP(r.Context(), tx, r, userID, "totp_disable")
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to disable two-factor authentication")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) startLoginChallenge(w http.ResponseWriter, r *http.Request, userID string) {
+	challenge, err := auth.NewOpaqueToken("lc_")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "login failed")
+		return
+	}
+	expiresAt, err := h.challenges.Create(r.Context(), auth.HashToken(challenge), userID, loginChallengeTTL)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "login failed")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"mfa_required":    true,
+		"challenge_token": challenge,
+		"expires_at":      expiresAt,
+	})
+}
+
+func (h *Handler) VerifyLogin(w http.ResponseWriter, r *http.Request) {
+	var req verifyLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeToken == "" {
+		respondError(w, http.StatusBadRequest, "challenge_token is required")
+		return
+	}
+	challengeHash := auth.HashToken(req.ChallengeToken)
+	challenge, err := h.challenges.Attempt(r.Context(), challengeHash, loginChallengeMaxAttempts)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusUnauthorized, "invalid or expired challenge")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "login failed")
+		return
+	}
+	state, err := h.twoFactor.GetTOTP(r.Context(), challenge.UserID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "login failed")
+		return
+	}
+	if !state.Enabled {
+		respondError(w, http.StatusUnauthorized, "invalid or expired challenge")
+		return
+	}
+	email := loginThrottleKey(state.Email)
+	attempt, ok := h.reserveLogin(w, r, challenge.UserID, email)
+	if !ok {
+		return
+	}
+	method, ok, err := h.checkSecondFactor(r.Context(), challenge.UserID, state, req.Code, req.RecoveryCode)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "login failed")
+		return
+	}
+	if !ok {
+		h.loginFailed(w, r, attempt, challenge.UserID, "invalid_code")
+		return
+	}
+	if err := h.challenges.Delete(r.Context(), challengeHash); err != nil {
+		respondError(w, http.StatusInternalServerError, "login failed")
+		return
+	}
+	h.completeLogin(w, r, attempt, challenge.UserID, method)
+}
+
+func (h *Handler) stepUp(w http.ResponseWriter, r *http.Request, userID string, amountMinor int64, code string) bool {
+	if amountMinor <= h.cfg.StepUpThresholdMinor {
+		return true
+	}
+	state, err := h.twoFactor.GetTOTP(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "step_up_failed")
+		return false
+	}
+	if !state.Enabled {
+		return true
+	}
+	if strings.TrimSpace(code) == "" {
+		respondError(w, http.StatusForbidden, "step_up_required")
+		return false
+	}
+	recorded, previous, ok := h.reserveStepUp(w, r, userID)
+	if !ok {
+		return false
+	}
+	ok, err = h.checkTOTP(r.Context(), userID, state, code)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "step_up_failed")
+		return false
+	}
+	if !ok {
+		respondError(w, http.StatusForbidden, "invalid_step_up_code")
+		return false
+	}
+	if err := h.txRunner.WithTx(r.Context(), func(tx *sqlx.Tx) error {
+		return h.throttles.Release(r.Context(), tx, store.LoginThrottleStepUp, userID, recorded, previous)
+	}); err != nil {
+		respondError(w, http.StatusInternalServerError, "step_up_failed")
+		return false
+	}
+	return true
+}
+
+func (h *Handler) checkSecondFactor(ctx context.Context, userID string, state store.TOTPState, code, recoveryCode string) (string, bool, error) {
+	if recoveryCode != "" {
+		ok, err := h.twoFactor.UseRecoveryCode(ctx, userID, hashRecoveryCode(recoveryCode))
+		return "recovery_code", ok, err
+	}
+	ok, err := h.checkTOTP(ctx, userID, state, code)
+	return "totp", ok, err
+}
+
+func (h *Handler) checkTOTP(ctx context.Context, userID string, state store.TOTPState, code string) (bool, error) {
+	if state.Secret == nil {
+		return false, nil
+	}
+	secret, err := auth.OpenSecret(h.cfg.TOTPEncryptionKey, *state.Secret)
+	if err != nil {
+		return false, err
+	}
+	counter, ok := auth.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return h.twoFactor.UseTOTPCounter(ctx, userID, counter)
+}
+
+func (h *Handler) auditTOTP(ctx context.Context, tx *sqlx.Tx, r *http.Request, userID, action string) error {
+	data, _ := json.Marshal(map[string]string{
+		"user_id":    userID,
+		"ip":         middleware.RemoteIP(r),
+		"user_agent": r.UserAgent(),
+	})
+	return h.audit.Log(ctx, tx, userID, action, "user", userID, string(data))
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return auth.HashToken(normalized)
+}
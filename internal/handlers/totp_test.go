@@ -0,0 +1,392 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/services"
+	"banking/internal/store"
+)
+
+func enabledTOTPState(t *testing.T, key string) (store.TOTPState, string) {
+	t.Helper()
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+	sealed, err := auth.SealSecret(key, secret)
+	if err != nil {
+		t.Fatalf("failed to seal secret: %v", err)
+	}
+	return store.TOTPState{Email: "alice@example.com", Secret: &sealed, Enabled: true}, secret
+}
+
+func currentTOTPCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := auth.TOTPCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	return code
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.cfg.TOTPIssuer = "Banking"
+	handler.cfg.TOTPEncryptionKey = "key"
+	var sealed string
+	handler.twoFactor = stubTwoFactorStore{
+		getTOTPFn: func(context.Context, string) (store.TOTPState, error) {
+			return store.TOTPState{Email: "alice@example.com"}, nil
+		},
+		setPendingFn: func(_ context.Context, _ store.Execer, _ string, sealedSecret string) (int64, error) {
+			sealed = sealedSecret
+			return 1, nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.EnrollTOTP, http.MethodPost, "/auth/totp/enroll", "/auth/totp/enroll", "", "user-1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var payload map[string]string
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	opened, err := auth.OpenSecret("key", sealed)
+	if err != nil || opened != payload["secret"] {
+		t.Fatalf("expected the stored secret to be sealed, got %q (%v)", opened, err)
+	}
+	if !strings.HasPrefix(payload["provisioning_uri"], "otpauth://totp/Banking:") {
+		t.Fatalf("unexpected provisioning uri %q", payload["provisioning_uri"])
+	}
+}
+
+func TestEnrollTOTPRejectsEnabledUser(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	state, _ := enabledTOTPState(t, handler.cfg.TOTPEncryptionKey)
+	handler.twoFactor = stubTwoFactorStore{
+		getTOTPFn: func(context.Context, string) (store.TOTPState, error) { return state, nil },
+	}
+	rr := servePeriodRequest(t, handler.EnrollTOTP, http.MethodPost, "/auth/totp/enroll", "/auth/totp/enroll", "", "user-1")
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+}
+
+func TestConfirmTOTPReturnsRecoveryCodes(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	state, secret := enabledTOTPState(t, handler.cfg.TOTPEncryptionKey)
+	state.Enabled = false
+	var stored []string
+	enabled := false
+	handler.twoFactor = stubTwoFactorStore{
+		getTOTPFn: func(context.Context, string) (store.TOTPState, error) { return state, nil },
+		enableFn: func(context.Context, store.Execer, string, int64) (int64, error) {
+			enabled = true
+			return 1, nil
+		},
+		replaceRecoveryFn: func(_ context.Context, _ store.Execer, _ string, hashes []string) error {
+			stored = hashes
+			return nil
+		},
+	}
+	body := `{"code":"` + currentTOTPCode(t, secret) + `"}`
+	rr := servePeriodRequest(t, handler.ConfirmTOTP, http.MethodPost, "/auth/totp/confirm", "/auth/totp/confirm", body, "user-1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var payload struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !enabled || len(payload.RecoveryCodes) != recoveryCodeCount || len(stored) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d (stored %d)", recoveryCodeCount, len(payload.RecoveryCodes), len(stored))
+	}
+	if stored[0] != hashRecoveryCode(strings.ToUpper(payload.RecoveryCodes[0])) {
+		t.Fatalf("expected recovery codes to be stored hashed and normalized")
+	}
+}
+
+func TestConfirmTOTPRejectsInvalidCode(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	state, _ := enabledTOTPState(t, handler.cfg.TOTPEncryptionKey)
+	state.Enabled = false
+	handler.twoFactor = stubTwoFactorStore{
+		getTOTPFn: func(context.Context, string) (store.TOTPState, error) { return state, nil },
+		enableFn: func(context.Context, store.Execer, string, int64) (int64, error) {
+			t.Fatal("totp must not be enabled with a wrong code")
+			return 0, nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.ConfirmTOTP, http.MethodPost, "/auth/totp/confirm", "/auth/totp/confirm", `{"code":"abcdef"}`, "user-1")
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rr.Code)
+	}
+}
+
+func TestLoginWithTOTPReturnsChallenge(t *testing.T) {
+	passwordHash, err := auth.HashPassword("pass1234")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByEmailFn: func(context.Context, string) (map[string]any, error) {
+			return map[string]any{"id": "user-1", "password_hash": passwordHash}, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	state, _ := enabledTOTPState(t, handler.cfg.TOTPEncryptionKey)
+	handler.twoFactor = stubTwoFactorStore{
+		getTOTPFn: func(context.Context, string) (store.TOTPState, error) { return state, nil },
+	}
+	var storedHash string
+	handler.challenges = stubLoginChallengeStore{
+		createFn: func(_ context.Context, challengeHash, userID string, ttl time.Duration) (time.Time, error) {
+			storedHash = challengeHash
+			return time.Now().Add(ttl), nil
+		},
+	}
+	handler.sessions = stubSessionStore{
+		addRefreshFn: func(context.Context, store.Execer, store.RefreshTokenInput) error {
+			t.Fatal("no session may start before the second factor")
+			return nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"alice@example.com","password":"pass1234"}`))
+	rr := httptest.NewRecorder()
+	handler.Login(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var payload map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	challenge, _ := payload["challenge_token"].(string)
+	if payload["mfa_required"] != true || payload["token"] != nil || storedHash != auth.HashToken(challenge) {
+		t.Fatalf("expected a login challenge, got %#v", payload)
+	}
+}
+
+func TestVerifyLoginIssuesSession(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	state, secret := enabledTOTPState(t, handler.cfg.TOTPEncryptionKey)
+	handler.twoFactor = stubTwoFactorStore{
+		getTOTPFn: func(context.Context, string) (store.TOTPState, error) { return state, nil },
+	}
+	deleted := false
+	handler.challenges = stubLoginChallengeStore{
+		attemptFn: func(_ context.Context, challengeHash string, _ int) (store.LoginChallenge, error) {
+			if challengeHash != auth.HashToken("lc_challenge") {
+				t.Fatalf("unexpected challenge hash %q", challengeHash)
+			}
+			return store.LoginChallenge{UserID: "user-1", Attempts: 1}, nil
+		},
+		deleteFn: func(context.Context, string) error {
+			deleted = true
+			return nil
+		},
+	}
+	body := `{"challenge_token":"lc_challenge","code":"` + currentTOTPCode(t, secret) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/auth/login/verify", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.VerifyLogin(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var payload map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if payload["token"] == nil || !deleted {
+		t.Fatalf("expected a session and a consumed challenge, got %#v", payload)
+	}
+}
+
+func TestVerifyLoginAcceptsRecoveryCode(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	state, _ := enabledTOTPState(t, handler.cfg.TOTPEncryptionKey)
+	handler.twoFactor = stubTwoFactorStore{
+		getTOTPFn: func(context.Context, string) (store.TOTPState, error) { return state, nil },
+		useRecoveryCodeFn: func(_ context.Context, _ string, codeHash string) (bool, error) {
+			return codeHash == hashRecoveryCode("abcdefgh"), nil
+		},
+	}
+	handler.challenges = stubLoginChallengeStore{
+		attemptFn: func(context.Context, string, int) (store.LoginChallenge, error) {
+			return store.LoginChallenge{UserID: "user-1", Attempts: 1}, nil
+		},
+	}
+	var method string
+	handler.audit = stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, _, action, _, _, data string) error {
+			if action == "login" {
+				var fields map[string]string
+				_ = json.Unmarshal([]byte(data), &fields)
+				method = fields["method"]
+			}
+			return nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/login/verify", strings.NewReader(`{"challenge_token":"lc_challenge","recovery_code":"ABCD-EFGH"}`))
+	rr := httptest.NewRecorder()
+	handler.VerifyLogin(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if method != "recovery_code" {
+		t.Fatalf("expected login audited with recovery_code, got %q", method)
+	}
+}
+
+func TestVerifyLoginRejectsInvalidChallengeAndCode(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	state, _ := enabledTOTPState(t, handler.cfg.TOTPEncryptionKey)
+	handler.twoFactor = stubTwoFactorStore{
+		getTOTPFn: func(context.Context, string) (store.TOTPState, error) { return state, nil },
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/login/verify", strings.NewReader(`{"challenge_token":"lc_expired","code":"123456"}`))
+	rr := httptest.NewRecorder()
+	handler.VerifyLogin(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an unknown challenge, got %d", rr.Code)
+	}
+
+	handler.challenges = stubLoginChallengeStore{
+		attemptFn: func(context.Context, string, int) (store.LoginChallenge, error) {
+			return store.LoginChallenge{UserID: "user-1", Attempts: 1}, nil
+		},
+		deleteFn: func(context.Context, string) error {
+			t.Fatal("challenge must survive a wrong code")
+			return nil
+		},
+	}
+	req = httptest.NewRequest(http.MethodPost, "/auth/login/verify", strings.NewReader(`{"challenge_token":"lc_challenge","code":"abcdef"}`))
+	rr = httptest.NewRecorder()
+	handler.VerifyLogin(rr, req)
+	if rr.Code != http.StatusUnauthorized || !strings.Contains(rr.Body.String(), "invalid code") {
+		t.Fatalf("expected 401 invalid code, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestDisableTOTPRequiresPassword(t *testing.T) {
+	passwordHash, err := auth.HashPassword("pass1234")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
+		getByIDFn: func(context.Context, string) (map[string]any, error) {
+			return map[string]any{"id": "user-1", "password_hash": passwordHash}, nil
+		},
+	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	state, secret := enabledTOTPState(t, handler.cfg.TOTPEncryptionKey)
+	disabled := false
+	handler.twoFactor = stubTwoFactorStore{
+		getTOTPFn: func(context.Context, string) (store.TOTPState, error) { return state, nil },
+		disableFn: func(context.Context, store.Execer, string) (int64, error) {
+			disabled = true
+			return 1, nil
+		},
+	}
+	code := currentTOTPCode(t, secret)
+	rr := servePeriodRequest(t, handler.DisableTOTP, http.MethodPost, "/auth/totp/disable", "/auth/totp/disable", `{"password":"wrong","code":"`+code+`"}`, "user-1")
+	if rr.Code != http.StatusUnauthorized || disabled {
+		t.Fatalf("expected 401 without disabling, got %d", rr.Code)
+	}
+	rr = servePeriodRequest(t, handler.DisableTOTP, http.MethodPost, "/auth/totp/disable", "/auth/totp/disable", `{"password":"pass1234","code":"`+code+`"}`, "user-1")
+	if rr.Code != http.StatusNoContent || !disabled {
+		t.Fatalf("expected 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestTransferStepUp(t *testing.T) {
+	transfers := 0
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{
+		transferFn: func(context.Context, services.TransferRequest) (string, error) {
+			transfers++
+			return "tx-1", nil
+		},
+	})
+	handler.cfg.StepUpThresholdMinor = 100000
+	state, secret := enabledTOTPState(t, handler.cfg.TOTPEncryptionKey)
+	handler.twoFactor = stubTwoFactorStore{
+		getTOTPFn: func(context.Context, string) (store.TOTPState, error) { return state, nil },
+	}
+
+	small := `{"from_account_id":"a1","to_account_id":"a2","amount":"10.00","confirm":true}`
+	rr := servePeriodRequest(t, handler.Transfer, http.MethodPost, "/transactions/transfer", "/transactions/transfer", small, "user-1")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201 below the threshold, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	large := `{"from_account_id":"a1","to_account_id":"a2","amount":"5000.00","confirm":true`
+	rr = servePeriodRequest(t, handler.Transfer, http.MethodPost, "/transactions/transfer", "/transactions/transfer", large+`}`, "user-1")
+	if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "step_up_required") {
+		t.Fatalf("expected 403 step_up_required, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = servePeriodRequest(t, handler.Transfer, http.MethodPost, "/transactions/transfer", "/transactions/transfer", large+`,"totp_code":"abcdef"}`, "user-1")
+	if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "invalid_step_up_code") {
+		t.Fatalf("expected 403 invalid_step_up_code, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = servePeriodRequest(t, handler.Transfer, http.MethodPost, "/transactions/transfer", "/transactions/transfer", large+`,"totp_code":"`+currentTOTPCode(t, secret)+`"}`, "user-1")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201 with a valid code, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if transfers != 2 {
+		t.Fatalf("expected 2 transfers, got %d", transfers)
+	}
+}
+
+func TestStepUpLocksAfterFailedCodes(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{
+		transferFn: func(context.Context, services.TransferRequest) (string, error) {
+			t.Fatalf("locked step-up must not transfer")
+			return "", nil
+		},
+	})
+	handler.cfg.StepUpThresholdMinor = 100000
+	handler.cfg.LoginMaxFailures = 2
+	handler.cfg.LoginLockout = time.Minute
+	state, secret := enabledTOTPState(t, handler.cfg.TOTPEncryptionKey)
+	handler.twoFactor = stubTwoFactorStore{
+		getTOTPFn: func(context.Context, string) (store.TOTPState, error) { return state, nil },
+	}
+	var throttle store.LoginThrottle
+	handler.throttles = stubLoginThrottleStore{
+		lockFn: func(_ context.Context, _ store.Tx, scope, key string) (store.LoginThrottle, error) {
+			if scope != store.LoginThrottleStepUp || key != "user-1" {
+				t.Fatalf("unexpected throttle %s %s", scope, key)
+			}
+			return throttle, nil
+		},
+		recordFailureFn: func(_ context.Context, _ store.Getter, _, _ string, _ time.Duration, maxFailures int, lockout time.Duration) (store.LoginThrottle, error) {
+			now := time.Now()
+			throttle.Failures++
+			throttle.LastFailedAt = &now
+			if throttle.Failures >= maxFailures {
+				lockedUntil := now.Add(lockout)
+				throttle.LockedUntil = &lockedUntil
+			}
+			return throttle, nil
+		},
+	}
+
+	large := `{"from_account_id":"a1","to_account_id":"a2","amount":"5000.00","confirm":true,"totp_code":"`
+	for i := 0; i < 2; i++ {
+		rr := servePeriodRequest(t, handler.Transfer, http.MethodPost, "/transactions/transfer", "/transactions/transfer", large+`abcdef"}`, "user-1")
+		if rr.Code != http.StatusForbidden {
+			t.Fatalf("expected 403 for a wrong code, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+	rr := servePeriodRequest(t, handler.Transfer, http.MethodPost, "/transactions/transfer", "/transactions/transfer", large+currentTOTPCode(t, secret)+`"}`, "user-1")
+	if rr.Code != http.StatusTooManyRequests || !strings.Contains(rr.Body.String(), "step_up_locked") || rr.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 step_up_locked, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
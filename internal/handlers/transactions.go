@@ -25,6 +25,7 @@ type transferRequest struct {
 	Amount          string  `json:"amount"`
 	Confirm         bool    `json:"confirm"`
 	ClientRequestID *string `json:"client_request_id"`
+	TOTPCode        string  `json:"totp_code"`
 }
 
 func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
@@ -76,6 +77,30 @@ func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
 		}
 		toAccountID = targetAccount.ID
 	}
+	if !h.requireVerifiedEmail(w, r, userID) {
+		return
+	}
+	if !h.stepUp(w, r, userID, amountMinor, req.TOTPCode) {
+		return
+	}
+	if amountMinor > h.cfg.ApprovalTransferThresholdMinor && h.needsApproval(approvalLargeTransfer) {
+		fromAccount, err := h.accounts.GetByID(r.Context(), req.FromAccountID)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid from account")
+			return
+		}
+		if fromAccount.UserID == nil || *fromAccount.UserID != userID {
+			respondError(w, http.StatusForbidden, "account_access_denied")
+			return
+		}
+		h.requestApproval(w, r, userID, approvalLargeTransfer, approvalPayload{
+			FromAccountID:   req.FromAccountID,
+			ToAccountID:     toAccountID,
+			AmountMinor:     amountMinor,
+			ClientRequestID: req.ClientRequestID,
+		})
+		return
+	}
 	transactionID, err := h.service.Transfer(r.Context(), services.TransferRequest{
 		UserID:          userID,
 		FromAccountID:   req.FromAccountID,
@@ -101,6 +126,14 @@ func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
 			respondError(w, http.StatusBadRequest, "invalid_amount")
 			return
 		}
+		if err == services.ErrPeriodClosed {
+			respondError(w, http.StatusConflict, "period_closed")
+			return
+		}
+		if err == services.ErrAccountFrozen {
+			respondError(w, http.StatusConflict, "account_frozen")
+			return
+		}
 		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
 			respondError(w, http.StatusConflict, "duplicate_request")
 			return
@@ -119,6 +152,7 @@ type exchangeRequest struct {
 	ClientRequestID *string `json:"client_request_id"`
 	QuoteID         *string `json:"quote_id"`
 	QuotedRate      *string `json:"quoted_rate"`
+	TOTPCode        string  `json:"totp_code"`
 }
 
 type exchangeQuoteRequest struct {
@@ -204,6 +238,12 @@ func (h *Handler) Exchange(w http.ResponseWriter, r *http.Request) {
 		normalized := rate.StringFixedBank(6)
 		quotedRate = &normalized
 	}
+	if !h.requireVerifiedEmail(w, r, userID) {
+		return
+	}
+	if !h.stepUp(w, r, userID, amountMinor, req.TOTPCode) {
+		return
+	}
 	transactionID, err := h.service.Exchange(r.Context(), services.ExchangeRequest{
 		UserID:          userID,
 		FromAccountID:   req.FromAccountID,
@@ -233,6 +273,10 @@ func (h *Handler) Exchange(w http.ResponseWriter, r *http.Request) {
 			respondError(w, http.StatusBadRequest, "quote_consumed")
 		case services.ErrRateMismatch:
 			respondError(w, http.StatusBadRequest, "quoted_rate_mismatch")
+		case services.ErrPeriodClosed:
+			respondError(w, http.StatusConflict, "period_closed")
+		case services.ErrAccountFrozen:
+			respondError(w, http.StatusConflict, "account_frozen")
 		default:
 			if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
 				respondError(w, http.StatusConflict, "duplicate_request")
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -26,7 +27,7 @@ func TestTransferSuccess(t *testing.T) {
 	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return false, false, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
 		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
@@ -39,13 +40,13 @@ func TestTransferSuccess(t *testing.T) {
 
 	body := []byte(`{"from_account_id":"a1","to_account_id":"a2","amount":"10.00","confirm":true}`)
 	req := httptest.NewRequest(http.MethodPost, "/transactions/transfer", bytes.NewReader(body))
-	token, err := auth.GenerateToken("secret", "user-1", time.Minute)
+	token, err := auth.GenerateToken(testKeys, "user-1", time.Minute)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
-	middleware.Auth("secret")(http.HandlerFunc(handler.Transfer)).ServeHTTP(rr, req)
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.Transfer)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusCreated {
 		t.Fatalf("expected 201, got %d", rr.Code)
 	}
@@ -60,7 +61,7 @@ func TestTransferInsufficientFunds(t *testing.T) {
 	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return false, false, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
 		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
@@ -73,13 +74,13 @@ func TestTransferInsufficientFunds(t *testing.T) {
 
 	body := []byte(`{"from_account_id":"a1","to_account_id":"a2","amount":"10.00","confirm":true}`)
 	req := httptest.NewRequest(http.MethodPost, "/transactions/transfer", bytes.NewReader(body))
-	token, err := auth.GenerateToken("secret", "user-1", time.Minute)
+	token, err := auth.GenerateToken(testKeys, "user-1", time.Minute)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
-	middleware.Auth("secret")(http.HandlerFunc(handler.Transfer)).ServeHTTP(rr, req)
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.Transfer)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusBadRequest {
 		t.Fatalf("expected 400, got %d", rr.Code)
 	}
@@ -94,7 +95,7 @@ func TestTransferDuplicateRequest(t *testing.T) {
 	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return false, false, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
 		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
@@ -106,18 +107,54 @@ func TestTransferDuplicateRequest(t *testing.T) {
 
 	body := []byte(`{"from_account_id":"a1","to_account_id":"a2","amount":"10.00","confirm":true}`)
 	req := httptest.NewRequest(http.MethodPost, "/transactions/transfer", bytes.NewReader(body))
-	token, err := auth.GenerateToken("secret", "user-1", time.Minute)
+	token, err := auth.GenerateToken(testKeys, "user-1", time.Minute)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
-	middleware.Auth("secret")(http.HandlerFunc(handler.Transfer)).ServeHTTP(rr, req)
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.Transfer)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusConflict {
 		t.Fatalf("expected 409, got %d", rr.Code)
 	}
 }
 
+func TestTransferPeriodClosed(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{
+		transferFn: func(context.Context, services.TransferRequest) (string, error) {
+			return "", services.ErrPeriodClosed
+		},
+	})
+
+	body := []byte(`{"from_account_id":"a1","to_account_id":"a2","amount":"10.00","confirm":true}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions/transfer", bytes.NewReader(body))
+	token, _ := auth.GenerateToken(testKeys, "user-1", time.Minute)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.Transfer)).ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+}
+
+func TestTransferAccountFrozen(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{
+		transferFn: func(context.Context, services.TransferRequest) (string, error) {
+			return "", services.ErrAccountFrozen
+		},
+	})
+
+	body := []byte(`{"from_account_id":"a1","to_account_id":"a2","amount":"10.00","confirm":true}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions/transfer", bytes.NewReader(body))
+	token, _ := auth.GenerateToken(testKeys, "user-1", time.Minute)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.Transfer)).ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "account_frozen") {
+		t.Fatalf("expected 409 account_frozen, got %d %s", rr.Code, rr.Body.String())
+	}
+}
+
 func TestExchangeSuccess(t *testing.T) {
 	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{
 		getByEmailFn:    func(context.Context, string) (map[string]any, error) { return nil, nil },
@@ -127,7 +164,7 @@ func TestExchangeSuccess(t *testing.T) {
 	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return false, false, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
 		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
@@ -138,13 +175,13 @@ func TestExchangeSuccess(t *testing.T) {
 
 	body := []byte(`{"from_account_id":"a1","to_account_id":"a2","amount":"10.00","confirm":true,"quoted_rate":"0.920000"}`)
 	req := httptest.NewRequest(http.MethodPost, "/transactions/exchange", bytes.NewReader(body))
-	token, err := auth.GenerateToken("secret", "user-1", time.Minute)
+	token, err := auth.GenerateToken(testKeys, "user-1", time.Minute)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
-	middleware.Auth("secret")(http.HandlerFunc(handler.Exchange)).ServeHTTP(rr, req)
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.Exchange)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusCreated {
 		t.Fatalf("expected 201, got %d", rr.Code)
 	}
@@ -159,7 +196,7 @@ func TestExchangeQuote(t *testing.T) {
 	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return false, false, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
 		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
@@ -176,13 +213,13 @@ func TestExchangeQuote(t *testing.T) {
 
 	body := []byte(`{"from_account_id":"a1","to_account_id":"a2","amount":"10.00"}`)
 	req := httptest.NewRequest(http.MethodPost, "/transactions/exchange/quote", bytes.NewReader(body))
-	token, err := auth.GenerateToken("secret", "user-1", time.Minute)
+	token, err := auth.GenerateToken(testKeys, "user-1", time.Minute)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
-	middleware.Auth("secret")(http.HandlerFunc(handler.ExchangeQuote)).ServeHTTP(rr, req)
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.ExchangeQuote)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", rr.Code)
 	}
@@ -202,20 +239,20 @@ func TestListTransactions(t *testing.T) {
 	}, stubExchangeStore{}, stubAdminStore{
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return false, false, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
 		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
 	}, stubService{})
 
-	token, err := auth.GenerateToken("secret", "user-1", time.Minute)
+	token, err := auth.GenerateToken(testKeys, "user-1", time.Minute)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
 	req := httptest.NewRequest(http.MethodGet, "/transactions", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
-	middleware.Auth("secret")(http.HandlerFunc(handler.ListTransactions)).ServeHTTP(rr, req)
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.ListTransactions)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", rr.Code)
 	}
@@ -237,7 +274,7 @@ func TestExchangeDuplicateRequest(t *testing.T) {
 	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return false, false, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
 		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
@@ -249,13 +286,13 @@ func TestExchangeDuplicateRequest(t *testing.T) {
 
 	body := []byte(`{"from_account_id":"a1","to_account_id":"a2","amount":"10.00","confirm":true,"quoted_rate":"0.920000"}`)
 	req := httptest.NewRequest(http.MethodPost, "/transactions/exchange", bytes.NewReader(body))
-	token, err := auth.GenerateToken("secret", "user-1", time.Minute)
+	token, err := auth.GenerateToken(testKeys, "user-1", time.Minute)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr := httptest.NewRecorder()
-	middleware.Auth("secret")(http.HandlerFunc(handler.Exchange)).ServeHTTP(rr, req)
+	middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.Exchange)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusConflict {
 		t.Fatalf("expected 409, got %d", rr.Code)
 	}
@@ -22,7 +22,7 @@ func TestGetUserByUsername(t *testing.T) {
 	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return false, false, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
 		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
@@ -50,7 +50,7 @@ func TestGetUserByEmail(t *testing.T) {
 	}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{
 		hasAnyAdminFn: func(context.Context) (bool, error) { return true, nil },
 		isAdminFn:     func(context.Context, string) (bool, bool, error) { return false, false, nil },
-		hasRoleFn:     func(context.Context, string, string) (bool, error) { return false, nil },
+		permissionsFn: func(context.Context, string) ([]string, error) { return nil, nil },
 	}, stubAuditStore{
 		logFn:  func(context.Context, store.Execer, string, string, string, string, string) error { return nil },
 		listFn: func(context.Context, int, int) ([]map[string]any, error) { return nil, nil },
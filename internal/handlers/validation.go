@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"errors"
+	"time"
 
 	"banking/internal/money"
 
@@ -10,6 +11,9 @@ import (
 
 var errInvalidAmount = errors.New("invalid amount")
 var errInvalidRate = errors.New("invalid rate")
+var errInvalidTimestamp = errors.New("invalid timestamp")
+var errInvalidRefreshToken = errors.New("invalid refresh token")
+var errInvalidEmailToken = errors.New("invalid email token")
 
 func parseAmountMinor(raw string) (int64, error) {
 	amount, err := money.ParseMinor(raw)
@@ -29,3 +33,25 @@ func parseRate(raw string) (decimal.Decimal, error) {
 	}
 	return rate, nil
 }
+
+func parseAsOf(raw string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+		return parsed.UTC(), nil
+	}
+	day, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return time.Time{}, errInvalidTimestamp
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Microsecond).UTC(), nil
+}
+
+func parseFrom(raw string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+		return parsed.UTC(), nil
+	}
+	day, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return time.Time{}, errInvalidTimestamp
+	}
+	return day.UTC(), nil
+}
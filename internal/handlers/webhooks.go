@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+
+	"banking/internal/middleware"
+	"banking/internal/store"
+	"banking/internal/webhooks"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type webhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	h.createWebhook(w, r, webhooks.ScopeUser)
+}
+
+func (h *Handler) CreateAdminWebhook(w http.ResponseWriter, r *http.Request) {
+	h.createWebhook(w, r, webhooks.ScopeAdmin)
+}
+
+func (h *Handler) createWebhook(w http.ResponseWriter, r *http.Request, scope string) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" {
+		respondError(w, http.StatusBadRequest, "invalid url")
+		return
+	}
+	if ip := net.ParseIP(target.Hostname()); ip != nil && !webhooks.AllowedAddress(ip) {
+		respondError(w, http.StatusBadRequest, "url must not point at a private address")
+		return
+	}
+	if len(req.EventTypes) == 0 {
+		respondError(w, http.StatusBadRequest, "event_types is required")
+		return
+	}
+	for _, eventType := range req.EventTypes {
+		if !webhooks.IsEventType(eventType) {
+			respondError(w, http.StatusBadRequest, "unknown event type: "+eventType)
+			return
+		}
+	}
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to create webhook")
+		return
+	}
+	input := store.WebhookEndpointInput{
+		ID:          uuid.NewString(),
+		OwnerUserID: userID,
+		Scope:       scope,
+		URL:         target.String(),
+		Secret:      secret,
+		EventTypes:  req.EventTypes,
+	}
+	if err := h.webhooks.CreateEndpoint(r.Context(), input); err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to create webhook")
+		return
+	}
+	response := webhookResponse(store.WebhookEndpoint{
+		ID:          input.ID,
+		OwnerUserID: input.OwnerUserID,
+		Scope:       input.Scope,
+		URL:         input.URL,
+		EventTypes:  input.EventTypes,
+		Active:      true,
+	})
+	response["secret"] = secret
+	respondJSON(w, http.StatusCreated, response)
+}
+
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	rows, err := h.webhooks.ListEndpoints(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load webhooks")
+		return
+	}
+	normalized := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		normalized = append(normalized, webhookResponse(row))
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
+
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := h.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+	if _, err := h.webhooks.DeleteEndpoint(r.Context(), endpoint.ID); err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to delete webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) EnableWebhook(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := h.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+	if _, err := h.webhooks.EnableEndpoint(r.Context(), endpoint.ID); err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to enable webhook")
+		return
+	}
+	endpoint.Active = true
+	endpoint.ConsecutiveFailures = 0
+	endpoint.DisabledAt = nil
+	respondJSON(w, http.StatusOK, webhookResponse(endpoint))
+}
+
+func (h *Handler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := h.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	limit := parseInt(query.Get("limit"), 50)
+	page := parseInt(query.Get("page"), 1)
+	offset := (page - 1) * limit
+	rows, err := h.webhooks.ListDeliveries(r.Context(), endpoint.ID, limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load deliveries")
+		return
+	}
+	normalized := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		normalized = append(normalized, map[string]any{
+			"id":               row.ID,
+			"event_id":         row.EventID,
+			"event_type":       row.EventType,
+			"payload":          json.RawMessage(row.Payload),
+			"status":           row.Status,
+			"attempts":         row.Attempts,
+			"last_status_code": row.LastStatusCode,
+			"last_error":       row.LastError,
+			"next_attempt_at":  row.NextAttemptAt,
+			"delivered_at":     row.DeliveredAt,
+			"created_at":       row.CreatedAt,
+		})
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
+
+func (h *Handler) ListWebhookAttempts(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := h.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+	rows, err := h.webhooks.ListAttempts(r.Context(), endpoint.ID, chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to load attempts")
+		return
+	}
+	normalized := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		normalized = append(normalized, map[string]any{
+			"status_code":  row.StatusCode,
+			"error":        row.Error,
+			"attempted_at": row.AttemptedAt,
+		})
+	}
+	respondJSON(w, http.StatusOK, normalized)
+}
+
+func (h *Handler) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := h.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+	updated, err := h.webhooks.Redeliver(r.Context(), endpoint.ID, chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to redeliver")
+		return
+	}
+	if updated == 0 {
+		respondError(w, http.StatusNotFound, "delivery not found")
+		return
+	}
+	respondJSON(w, http.StatusAccepted, map[string]string{"status": "queued"})
+}
+
+// ownedWebhook answers 404 for other users' endpoints so ids can't be probed.
+func (h *Handler) ownedWebhook(w http.ResponseWriter, r *http.Request) (store.WebhookEndpoint, bool) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return store.WebhookEndpoint{}, false
+	}
+	endpoint, err := h.webhooks.GetEndpoint(r.Context(), chi.URLParam(r, "id"))
+	if err != nil && err != sql.ErrNoRows {
+		respondError(w, http.StatusInternalServerError, "unable to load webhook")
+		return store.WebhookEndpoint{}, false
+	}
+	if err == sql.ErrNoRows || endpoint.OwnerUserID != userID {
+		respondError(w, http.StatusNotFound, "webhook not found")
+		return store.WebhookEndpoint{}, false
+	}
+	return endpoint, true
+}
+
+func webhookResponse(endpoint store.WebhookEndpoint) map[string]any {
+	eventTypes := []string(endpoint.EventTypes)
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	return map[string]any{
+		"id":                   endpoint.ID,
+		"scope":                endpoint.Scope,
+		"url":                  endpoint.URL,
+		"event_types":          eventTypes,
+		"active":               endpoint.Active,
+		"consecutive_failures": endpoint.ConsecutiveFailures,
+		"disabled_at":          endpoint.DisabledAt,
+		"created_at":           endpoint.CreatedAt,
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"banking/internal/store"
+)
+
+func TestCreateWebhook(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	var created store.WebhookEndpointInput
+	handler.webhooks = stubWebhookStore{
+		createEndpointFn: func(_ context.Context, input store.WebhookEndpointInput) error {
+			created = input
+			return nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.CreateWebhook, http.MethodPost, "/webhooks", "/webhooks", `{"url":"https://example.com/hooks","event_types":["transfer.received","account.frozen"]}`, "user-1")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var payload map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if payload["secret"] != created.Secret || created.Secret == "" {
+		t.Fatalf("expected secret to be returned once, got %#v", payload)
+	}
+	if created.OwnerUserID != "user-1" || created.Scope != "user" || len(created.EventTypes) != 2 {
+		t.Fatalf("unexpected endpoint: %#v", created)
+	}
+}
+
+func TestCreateWebhookValidation(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	cases := []string{
+		`{"url":"ftp://example.com","event_types":["transfer.sent"]}`,
+		`{"url":"https://example.com","event_types":[]}`,
+		`{"url":"https://example.com","event_types":["admin.promoted"]}`,
+		`{"url":"http://127.0.0.1:8080/hook","event_types":["transfer.sent"]}`,
+		`{"url":"http://169.254.169.254/latest","event_types":["transfer.sent"]}`,
+		`{"url":"https://[::1]/hook","event_types":["transfer.sent"]}`,
+		`{"url":"https://10.0.0.5/hook","event_types":["transfer.sent"]}`,
+	}
+	for _, body := range cases {
+		rr := servePeriodRequest(t, handler.CreateWebhook, http.MethodPost, "/webhooks", "/webhooks", body, "user-1")
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, rr.Code)
+		}
+	}
+}
+
+func TestWebhookOwnership(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.webhooks = stubWebhookStore{
+		getEndpointFn: func(_ context.Context, endpointID string) (store.WebhookEndpoint, error) {
+			return store.WebhookEndpoint{ID: endpointID, OwnerUserID: "user-1"}, nil
+		},
+		deleteEndpointFn: func(context.Context, string) (int64, error) {
+			t.Fatalf("unexpected delete")
+			return 0, nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.DeleteWebhook, http.MethodDelete, "/webhooks/{id}", "/webhooks/hook-1", "", "user-2")
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestRedeliverWebhook(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	var redelivered string
+	handler.webhooks = stubWebhookStore{
+		getEndpointFn: func(_ context.Context, endpointID string) (store.WebhookEndpoint, error) {
+			return store.WebhookEndpoint{ID: endpointID, OwnerUserID: "user-1"}, nil
+		},
+		redeliverFn: func(_ context.Context, endpointID, deliveryID string) (int64, error) {
+			redelivered = endpointID + "/" + deliveryID
+			if deliveryID == "missing" {
+				return 0, nil
+			}
+			return 1, nil
+		},
+	}
+	pattern := "/webhooks/{id}/deliveries/{deliveryID}/redeliver"
+	rr := servePeriodRequest(t, handler.RedeliverWebhook, http.MethodPost, pattern, "/webhooks/hook-1/deliveries/d-1/redeliver", "", "user-1")
+	if rr.Code != http.StatusAccepted || redelivered != "hook-1/d-1" {
+		t.Fatalf("expected 202 for hook-1/d-1, got %d for %s", rr.Code, redelivered)
+	}
+	rr = servePeriodRequest(t, handler.RedeliverWebhook, http.MethodPost, pattern, "/webhooks/hook-1/deliveries/missing/redeliver", "", "user-1")
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	code := 500
+	handler.webhooks = stubWebhookStore{
+		getEndpointFn: func(_ context.Context, endpointID string) (store.WebhookEndpoint, error) {
+			return store.WebhookEndpoint{ID: endpointID, OwnerUserID: "user-1"}, nil
+		},
+		listDeliveriesFn: func(_ context.Context, endpointID string, limit, offset int) ([]store.WebhookDelivery, error) {
+			return []store.WebhookDelivery{{ID: "d-1", Status: "pending", Attempts: 2, LastStatusCode: &code, Payload: `{"type":"transfer.sent"}`}}, nil
+		},
+	}
+	rr := servePeriodRequest(t, handler.ListWebhookDeliveries, http.MethodGet, "/webhooks/{id}/deliveries", "/webhooks/hook-1/deliveries", "", "user-1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var payload []map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload) != 1 || payload[0]["last_status_code"] != float64(500) || payload[0]["payload"].(map[string]any)["type"] != "transfer.sent" {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+}
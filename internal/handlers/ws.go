@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/middleware"
+	"banking/internal/money"
+	"banking/internal/store"
+	"banking/internal/websocket"
+)
+
+const wsReplayLimit = 500
+
+const wsTicketTTL = 30 * time.Second
+
+func (h *Handler) CreateWSTicket(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	origin := r.Header.Get("Origin")
+	if !h.checkOrigin(r) {
+		respondError(w, http.StatusForbidden, "origin not allowed")
+		return
+	}
+	ticket, err := auth.NewOpaqueToken("wst_")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to issue ticket")
+		return
+	}
+	expiresAt, err := h.tickets.Create(r.Context(), store.WSTicketInput{
+		TicketHash: auth.HashToken(ticket),
+		UserID:     userID,
+		SessionID:  middleware.SessionIDFromContext(r.Context()),
+		Origin:     origin,
+		TTL:        wsTicketTTL,
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to issue ticket")
+		return
+	}
+	respondJSON(w, http.StatusCreated, map[string]any{
+		"ticket":     ticket,
+		"expires_at": expiresAt,
+	})
+}
+
+func (h *Handler) WSBalances(w http.ResponseWriter, r *http.Request) {
+	var since *int64
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 0 {
+			respondError(w, http.StatusBadRequest, "invalid since")
+			return
+		}
+		since = &parsed
+	}
+	ticket, ok := h.consumeWSTicket(w, r)
+	if !ok {
+		return
+	}
+	websocket.ServeWS(w, r, h.hub, ticket.UserID, websocket.Options{
+		Legacy:      true,
+		Since:       since,
+		SessionID:   ticket.SessionID,
+		CheckOrigin: h.checkOrigin,
+		Backlog: func(since *int64) (websocket.Backlog, error) {
+			return h.balanceBacklog(r.Context(), ticket.UserID, since)
+		},
+	})
+}
+
+func (h *Handler) WS(w http.ResponseWriter, r *http.Request) {
+	ticket, ok := h.consumeWSTicket(w, r)
+	if !ok {
+		return
+	}
+	websocket.ServeWS(w, r, h.hub, ticket.UserID, websocket.Options{
+		SessionID:   ticket.SessionID,
+		CheckOrigin: h.checkOrigin,
+		Backlog: func(since *int64) (websocket.Backlog, error) {
+			return h.balanceBacklog(r.Context(), ticket.UserID, since)
+		},
+		Authorize: func(channel string) (bool, error) {
+			return h.authorizeChannel(r.Context(), ticket.UserID, channel)
+		},
+	})
+}
+
+// consumeWSTicket redeems the ?ticket= parameter. A ticket is spent even if
+// its session was revoked since it was issued or the origin check fails.
+func (h *Handler) consumeWSTicket(w http.ResponseWriter, r *http.Request) (store.WSTicket, bool) {
+	raw := r.URL.Query().Get("ticket")
+	if raw == "" {
+		respondError(w, http.StatusUnauthorized, "missing ticket")
+		return store.WSTicket{}, false
+	}
+	ticket, err := h.tickets.Consume(r.Context(), auth.HashToken(raw))
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusUnauthorized, "invalid ticket")
+		return store.WSTicket{}, false
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "unable to verify ticket")
+		return store.WSTicket{}, false
+	}
+	if h.revocations.Revoked(ticket.SessionID) {
+		respondError(w, http.StatusUnauthorized, "session revoked")
+		return store.WSTicket{}, false
+	}
+	if ticket.Origin != r.Header.Get("Origin") {
+		respondError(w, http.StatusForbidden, "origin mismatch")
+		return store.WSTicket{}, false
+	}
+	return ticket, true
+}
+
+// checkOrigin accepts requests without an Origin, which only non-browser
+// clients send, and otherwise the origins in Config.AllowedOrigins.
+func (h *Handler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range allowedOrigins(h.cfg.AllowedOrigins) {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func allowedOrigins(raw string) []string {
+	var origins []string
+	for _, origin := range strings.Split(raw, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+func (h *Handler) authorizeChannel(ctx context.Context, userID, channel string) (bool, error) {
+	quoteID, ok := strings.CutPrefix(channel, websocket.ChannelQuotesPrefix)
+	if !ok {
+		return true, nil
+	}
+	quote, err := h.quotes.GetByID(ctx, quoteID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return quote.UserID == userID, nil
+}
+
+// balanceBacklog replays the updates after since when they are all still
+// available, and otherwise sends a snapshot. The stream head is read before
+// the balances, so every update after the snapshot's seq is newer than it.
+func (h *Handler) balanceBacklog(ctx context.Context, userID string, since *int64) (websocket.Backlog, error) {
+	head, err := h.streams.Head(ctx, userID)
+	if err != nil {
+		return websocket.Backlog{}, err
+	}
+	if since != nil && *since <= head {
+		rows, err := h.streams.Since(ctx, userID, *since, wsReplayLimit+1)
+		if err != nil {
+			return websocket.Backlog{}, err
+		}
+		if len(rows) <= wsReplayLimit {
+			updates := make([]websocket.BalanceUpdate, 0, len(rows))
+			for _, row := range rows {
+				var update websocket.BalanceUpdate
+				if err := json.Unmarshal([]byte(row.Payload), &update); err != nil {
+					return websocket.Backlog{}, err
+				}
+				update.Seq = row.Seq
+				updates = append(updates, update)
+			}
+			return websocket.Backlog{Updates: updates}, nil
+		}
+	}
+	accounts, err := h.accounts.GetByUser(ctx, userID)
+	if err != nil {
+		return websocket.Backlog{}, err
+	}
+	balances := make([]websocket.BalanceUpdate, 0, len(accounts))
+	for _, account := range accounts {
+		if account.IsSystem {
+			continue
+		}
+		balances = append(balances, websocket.BalanceUpdate{
+			AccountID: account.ID,
+			Balance:   money.FormatMinor(account.StoredBalance),
+			Currency:  account.Currency,
+		})
+	}
+	return websocket.Backlog{Snapshot: &websocket.Snapshot{Seq: head, Balances: balances}}, nil
+}
+
+func (h *Handler) Events(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	channels := []string{websocket.ChannelBalances, websocket.ChannelTransactions}
+	if raw := r.URL.Query().Get("channels"); raw != "" {
+		channels = strings.Split(raw, ",")
+	}
+	for _, channel := range channels {
+		if !websocket.ValidChannel(channel) {
+			respondError(w, http.StatusBadRequest, "unknown channel: "+channel)
+			return
+		}
+		allowed, err := h.authorizeChannel(r.Context(), userID, channel)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "unable to subscribe")
+			return
+		}
+		if !allowed {
+			respondError(w, http.StatusForbidden, "not allowed to subscribe to "+channel)
+			return
+		}
+	}
+	var since *int64
+	if raw := r.Header.Get("Last-Event-ID"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 0 {
+			respondError(w, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+		since = &parsed
+	}
+	websocket.ServeSSE(w, r, h.hub, userID, websocket.SSEOptions{
+		Channels:  channels,
+		SessionID: middleware.SessionIDFromContext(r.Context()),
+		Since:     since,
+		Backlog: func(since *int64) (websocket.Backlog, error) {
+			return h.balanceBacklog(r.Context(), userID, since)
+		},
+	})
+}
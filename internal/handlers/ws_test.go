@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/middleware"
+	"banking/internal/store"
+)
+
+func TestCreateWSTicket(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.cfg.AllowedOrigins = "https://app.example.com, https://admin.example.com"
+	var stored store.WSTicketInput
+	handler.tickets = stubWSTicketStore{
+		createFn: func(_ context.Context, input store.WSTicketInput) (time.Time, error) {
+			stored = input
+			return time.Now().Add(input.TTL), nil
+		},
+	}
+	token, err := auth.GenerateToken(testKeys, "user-1", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	serve := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/ws/ticket", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Origin", origin)
+		rr := httptest.NewRecorder()
+		middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.CreateWSTicket)).ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := serve("https://evil.example.com"); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+	rr := serve("https://admin.example.com")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var body struct {
+		Ticket string `json:"ticket"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || body.Ticket == "" {
+		t.Fatalf("unexpected body: %s", rr.Body.String())
+	}
+	if stored.TicketHash != auth.HashToken(body.Ticket) || stored.UserID != "user-1" || stored.Origin != "https://admin.example.com" || stored.TTL != wsTicketTTL {
+		t.Fatalf("unexpected stored ticket: %#v", stored)
+	}
+}
+
+func TestWSRejectsBadTickets(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.tickets = stubWSTicketStore{
+		consumeFn: func(_ context.Context, ticketHash string) (store.WSTicket, error) {
+			switch ticketHash {
+			case auth.HashToken("wst_valid"):
+				return store.WSTicket{UserID: "user-1", SessionID: "session-1", Origin: "https://app.example.com"}, nil
+			case auth.HashToken("wst_revoked"):
+				return store.WSTicket{UserID: "user-1", SessionID: "session-2", Origin: "https://app.example.com"}, nil
+			}
+			return store.WSTicket{}, sql.ErrNoRows
+		},
+	}
+	handler.revocations = middleware.NewRevocations(nil, time.Hour)
+	handler.revocations.Revoke("session-2")
+	tests := []struct {
+		name    string
+		target  string
+		origin  string
+		status  int
+		handler http.HandlerFunc
+	}{
+		{name: "missing", target: "/ws/balances", status: http.StatusUnauthorized, handler: handler.WSBalances},
+		{name: "token is not a ticket", target: "/ws/balances?token=abc", status: http.StatusUnauthorized, handler: handler.WSBalances},
+		{name: "unknown", target: "/ws?ticket=wst_other", status: http.StatusUnauthorized, handler: handler.WS},
+		{name: "revoked session", target: "/ws?ticket=wst_revoked", origin: "https://app.example.com", status: http.StatusUnauthorized, handler: handler.WS},
+		{name: "origin mismatch", target: "/ws?ticket=wst_valid", origin: "https://evil.example.com", status: http.StatusForbidden, handler: handler.WS},
+		{name: "invalid since", target: "/ws/balances?ticket=wst_valid&since=-1", status: http.StatusBadRequest, handler: handler.WSBalances},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		rr := httptest.NewRecorder()
+		tt.handler(rr, req)
+		if rr.Code != tt.status {
+			t.Fatalf("%s: expected %d, got %d", tt.name, tt.status, rr.Code)
+		}
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	tests := []struct {
+		allowed string
+		origin  string
+		ok      bool
+	}{
+		{allowed: "*", origin: "https://anything.example.com", ok: true},
+		{allowed: "https://app.example.com", origin: "", ok: true},
+		{allowed: "https://app.example.com,https://admin.example.com", origin: "https://admin.example.com", ok: true},
+		{allowed: "https://app.example.com", origin: "https://evil.example.com", ok: false},
+	}
+	for _, tt := range tests {
+		handler.cfg.AllowedOrigins = tt.allowed
+		req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if got := handler.checkOrigin(req); got != tt.ok {
+			t.Fatalf("%q with %q: got %v", tt.origin, tt.allowed, got)
+		}
+	}
+}
+
+func TestBalanceBacklogReplaysMissedUpdates(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{
+		getByUserFn: func(context.Context, string) ([]store.AccountBalanceSummary, error) {
+			t.Fatalf("snapshot should not be loaded")
+			return nil, nil
+		},
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.streams = stubStreamStore{
+		headFn: func(context.Context, string) (int64, error) { return 6, nil },
+		sinceFn: func(_ context.Context, userID string, afterSeq int64, limit int) ([]store.StreamEvent, error) {
+			if userID != "user-1" || afterSeq != 4 || limit != wsReplayLimit+1 {
+				t.Fatalf("unexpected args: %s %d %d", userID, afterSeq, limit)
+			}
+			return []store.StreamEvent{
+				{Seq: 5, Payload: `{"account_id":"acc-1","balance":"1.00","currency":"USD"}`},
+				{Seq: 6, Payload: `{"account_id":"acc-2","balance":"2.00","currency":"EUR"}`},
+			}, nil
+		},
+	}
+
+	since := int64(4)
+	backlog, err := handler.balanceBacklog(context.Background(), "user-1", &since)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if backlog.Snapshot != nil || len(backlog.Updates) != 2 || backlog.Updates[1].Seq != 6 || backlog.Updates[1].AccountID != "acc-2" {
+		t.Fatalf("unexpected backlog: %#v", backlog)
+	}
+}
+
+func TestBalanceBacklogSendsSnapshot(t *testing.T) {
+	tests := []struct {
+		name   string
+		since  *int64
+		replay int
+	}{
+		{name: "fresh connection"},
+		{name: "ahead of stream", since: func() *int64 { v := int64(50); return &v }()},
+		{name: "too far behind", since: func() *int64 { v := int64(0); return &v }(), replay: wsReplayLimit + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{
+				getByUserFn: func(context.Context, string) ([]store.AccountBalanceSummary, error) {
+					return []store.AccountBalanceSummary{
+						{ID: "acc-1", Currency: "USD", StoredBalance: 12345},
+						{ID: "sys-usd", Currency: "USD", IsSystem: true},
+					}, nil
+				},
+			}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+			handler.streams = stubStreamStore{
+				headFn: func(context.Context, string) (int64, error) { return 9, nil },
+				sinceFn: func(context.Context, string, int64, int) ([]store.StreamEvent, error) {
+					return make([]store.StreamEvent, tt.replay), nil
+				},
+			}
+
+			backlog, err := handler.balanceBacklog(context.Background(), "user-1", tt.since)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if backlog.Snapshot == nil || backlog.Snapshot.Seq != 9 || len(backlog.Updates) != 0 {
+				t.Fatalf("unexpected backlog: %#v", backlog)
+			}
+			if len(backlog.Snapshot.Balances) != 1 || backlog.Snapshot.Balances[0].Balance != "123.45" {
+				t.Fatalf("unexpected balances: %#v", backlog.Snapshot.Balances)
+			}
+		})
+	}
+}
+
+func TestAuthorizeChannel(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.quotes = stubQuoteStore{
+		getByIDFn: func(_ context.Context, quoteID string) (store.ExchangeQuote, error) {
+			if quoteID == "missing" {
+				return store.ExchangeQuote{}, sql.ErrNoRows
+			}
+			return store.ExchangeQuote{ID: quoteID, UserID: "user-2"}, nil
+		},
+	}
+	tests := []struct {
+		userID  string
+		channel string
+		allowed bool
+	}{
+		{userID: "user-1", channel: "balances", allowed: true},
+		{userID: "user-1", channel: "rates:USD-EUR", allowed: true},
+		{userID: "user-2", channel: "quotes:q-1", allowed: true},
+		{userID: "user-1", channel: "quotes:q-1", allowed: false},
+		{userID: "user-1", channel: "quotes:missing", allowed: false},
+	}
+	for _, tt := range tests {
+		allowed, err := handler.authorizeChannel(context.Background(), tt.userID, tt.channel)
+		if err != nil || allowed != tt.allowed {
+			t.Fatalf("%s %s: got %v %v", tt.userID, tt.channel, allowed, err)
+		}
+	}
+}
+
+func TestEventsRejectsBadRequests(t *testing.T) {
+	handler := newTestHandler(stubReconcileDB{}, fakeTxRunner{}, stubUserStore{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubAdminStore{}, stubAuditStore{}, stubService{})
+	handler.quotes = stubQuoteStore{
+		getByIDFn: func(context.Context, string) (store.ExchangeQuote, error) {
+			return store.ExchangeQuote{UserID: "user-2"}, nil
+		},
+	}
+	token, err := auth.GenerateToken(testKeys, "user-1", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	tests := []struct {
+		target      string
+		lastEventID string
+		status      int
+	}{
+		{target: "/events?channels=balances,ledger", status: http.StatusBadRequest},
+		{target: "/events?channels=quotes:q-1", status: http.StatusForbidden},
+		{target: "/events", lastEventID: "abc", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if tt.lastEventID != "" {
+			req.Header.Set("Last-Event-ID", tt.lastEventID)
+		}
+		rr := httptest.NewRecorder()
+		middleware.Auth(testKeys, nil)(http.HandlerFunc(handler.Events)).ServeHTTP(rr, req)
+		if rr.Code != tt.status {
+			t.Fatalf("%s: expected %d, got %d", tt.target, tt.status, rr.Code)
+		}
+	}
+}
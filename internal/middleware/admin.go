@@ -3,14 +3,95 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"sync"
+	"time"
 )
 
 type AdminStore interface {
 	IsAdmin(ctx context.Context, userID string) (bool, bool, error)
-	HasRole(ctx context.Context, userID, role string) (bool, error)
+	Permissions(ctx context.Context, userID string) ([]string, error)
 }
 
-func RequireAdmin(adminStore AdminStore, role string) func(http.Handler) http.Handler {
+type AdminAccess struct {
+	IsAdmin     bool
+	IsSuper     bool
+	Permissions []string
+}
+
+// An empty permission only needs the user to be an admin.
+func (a AdminAccess) Can(permission string) bool {
+	if !a.IsAdmin {
+		return false
+	}
+	if a.IsSuper || permission == "" {
+		return true
+	}
+	for _, granted := range a.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Other instances pick up admin changes within ttl. A zero ttl disables the
+// cache.
+type AdminCache struct {
+	store   AdminStore
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]adminCacheEntry
+}
+
+type adminCacheEntry struct {
+	access    AdminAccess
+	expiresAt time.Time
+}
+
+func NewAdminCache(store AdminStore, ttl time.Duration) *AdminCache {
+	return &AdminCache{store: store, ttl: ttl, entries: make(map[string]adminCacheEntry)}
+}
+
+func (c *AdminCache) Access(ctx context.Context, userID string) (AdminAccess, error) {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[userID]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.access, nil
+	}
+	var access AdminAccess
+	var err error
+	access.IsAdmin, access.IsSuper, err = c.store.IsAdmin(ctx, userID)
+	if err != nil {
+		return AdminAccess{}, err
+	}
+	if access.IsAdmin && !access.IsSuper {
+		if access.Permissions, err = c.store.Permissions(ctx, userID); err != nil {
+			return AdminAccess{}, err
+		}
+	}
+	if c.ttl > 0 {
+		c.mu.Lock()
+		c.entries[userID] = adminCacheEntry{access: access, expiresAt: now.Add(c.ttl)}
+		c.mu.Unlock()
+	}
+	return access, nil
+}
+
+func (c *AdminCache) Invalidate(userIDs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(userIDs) == 0 {
+		c.entries = make(map[string]adminCacheEntry)
+		return
+	}
+	for _, userID := range userIDs {
+		delete(c.entries, userID)
+	}
+}
+
+func RequireAdmin(admins *AdminCache, permission string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			userID, ok := UserIDFromContext(r.Context())
@@ -18,30 +99,41 @@ func RequireAdmin(adminStore AdminStore, role string) func(http.Handler) http.Ha
 				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
-			isAdmin, isSuper, err := adminStore.IsAdmin(r.Context(), userID)
+			access, err := admins.Access(r.Context(), userID)
 			if err != nil {
 				http.Error(w, "unable to verify admin", http.StatusInternalServerError)
 				return
 			}
-			if !isAdmin {
+			if !access.IsAdmin {
 				http.Error(w, "admin privileges required", http.StatusForbidden)
 				return
 			}
-			if isSuper {
-				next.ServeHTTP(w, r)
+			if !access.Can(permission) {
+				http.Error(w, "missing required permission", http.StatusForbidden)
 				return
 			}
-			if role == "" {
-				next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Managing admins, roles, GL mappings, admin webhooks and reopening periods is
+// reserved to super admins, so no role can grant itself more.
+func RequireSuperAdmin(admins *AdminCache) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := UserIDFromContext(r.Context())
+			if !ok {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
-			hasRole, err := adminStore.HasRole(r.Context(), userID, role)
+			access, err := admins.Access(r.Context(), userID)
 			if err != nil {
-				http.Error(w, "unable to verify role", http.StatusInternalServerError)
+				http.Error(w, "unable to verify admin", http.StatusInternalServerError)
 				return
 			}
-			if !hasRole {
-				http.Error(w, "missing required role", http.StatusForbidden)
+			if !access.IsSuper {
+				http.Error(w, "super admin privileges required", http.StatusForbidden)
 				return
 			}
 			next.ServeHTTP(w, r)
@@ -5,32 +5,33 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 type stubAdminStore struct {
-	isAdminFn func(ctx context.Context, userID string) (bool, bool, error)
-	hasRoleFn func(ctx context.Context, userID, role string) (bool, error)
+	isAdminFn     func(ctx context.Context, userID string) (bool, bool, error)
+	permissionsFn func(ctx context.Context, userID string) ([]string, error)
 }
 
 func (s stubAdminStore) IsAdmin(ctx context.Context, userID string) (bool, bool, error) {
 	return s.isAdminFn(ctx, userID)
 }
 
-func (s stubAdminStore) HasRole(ctx context.Context, userID, role string) (bool, error) {
-	return s.hasRoleFn(ctx, userID, role)
+func (s stubAdminStore) Permissions(ctx context.Context, userID string) ([]string, error) {
+	return s.permissionsFn(ctx, userID)
 }
 
 func TestRequireAdminMissingUser(t *testing.T) {
-	handler := RequireAdmin(stubAdminStore{
+	handler := RequireAdmin(NewAdminCache(stubAdminStore{
 		isAdminFn: func(context.Context, string) (bool, bool, error) {
 			t.Fatalf("unexpected call")
 			return false, false, nil
 		},
-		hasRoleFn: func(context.Context, string, string) (bool, error) {
+		permissionsFn: func(context.Context, string) ([]string, error) {
 			t.Fatalf("unexpected call")
-			return false, nil
+			return nil, nil
 		},
-	}, "role")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	}, 0), "role")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		t.Fatalf("handler should not be called")
 	}))
 	rr := httptest.NewRecorder()
@@ -42,14 +43,14 @@ func TestRequireAdminMissingUser(t *testing.T) {
 }
 
 func TestRequireAdminNotAdmin(t *testing.T) {
-	handler := RequireAdmin(stubAdminStore{
+	handler := RequireAdmin(NewAdminCache(stubAdminStore{
 		isAdminFn: func(context.Context, string) (bool, bool, error) {
 			return false, false, nil
 		},
-		hasRoleFn: func(context.Context, string, string) (bool, error) {
-			return false, nil
+		permissionsFn: func(context.Context, string) ([]string, error) {
+			return nil, nil
 		},
-	}, "role")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	}, 0), "role")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		t.Fatalf("handler should not be called")
 	}))
 	rr := httptest.NewRecorder()
@@ -62,14 +63,14 @@ func TestRequireAdminNotAdmin(t *testing.T) {
 }
 
 func TestRequireAdminSuperUser(t *testing.T) {
-	handler := RequireAdmin(stubAdminStore{
+	handler := RequireAdmin(NewAdminCache(stubAdminStore{
 		isAdminFn: func(context.Context, string) (bool, bool, error) {
 			return true, true, nil
 		},
-		hasRoleFn: func(context.Context, string, string) (bool, error) {
-			return false, nil
+		permissionsFn: func(context.Context, string) ([]string, error) {
+			return nil, nil
 		},
-	}, "role")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	}, 0), "role")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 	rr := httptest.NewRecorder()
@@ -82,14 +83,14 @@ func TestRequireAdminSuperUser(t *testing.T) {
 }
 
 func TestRequireAdminMissingRole(t *testing.T) {
-	handler := RequireAdmin(stubAdminStore{
+	handler := RequireAdmin(NewAdminCache(stubAdminStore{
 		isAdminFn: func(context.Context, string) (bool, bool, error) {
 			return true, false, nil
 		},
-		hasRoleFn: func(context.Context, string, string) (bool, error) {
-			return false, nil
+		permissionsFn: func(context.Context, string) ([]string, error) {
+			return nil, nil
 		},
-	}, "role")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	}, 0), "role")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		t.Fatalf("handler should not be called")
 	}))
 	rr := httptest.NewRecorder()
@@ -102,14 +103,14 @@ func TestRequireAdminMissingRole(t *testing.T) {
 }
 
 func TestRequireAdminWithRole(t *testing.T) {
-	handler := RequireAdmin(stubAdminStore{
+	handler := RequireAdmin(NewAdminCache(stubAdminStore{
 		isAdminFn: func(context.Context, string) (bool, bool, error) {
 			return true, false, nil
 		},
-		hasRoleFn: func(context.Context, string, string) (bool, error) {
-			return true, nil
+		permissionsFn: func(context.Context, string) ([]string, error) {
+			return []string{"role"}, nil
 		},
-	}, "role")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	}, 0), "role")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 	rr := httptest.NewRecorder()
@@ -121,6 +122,55 @@ func TestRequireAdminWithRole(t *testing.T) {
 	}
 }
 
+func TestAdminCacheKeepsAccessUntilInvalidated(t *testing.T) {
+	loads := 0
+	cache := NewAdminCache(stubAdminStore{
+		isAdminFn: func(context.Context, string) (bool, bool, error) {
+			loads++
+			return true, false, nil
+		},
+		permissionsFn: func(context.Context, string) ([]string, error) {
+			return []string{"CanViewReports"}, nil
+		},
+	}, time.Minute)
+	for i := 0; i < 2; i++ {
+		access, err := cache.Access(context.Background(), "user-1")
+		if err != nil || !access.Can("CanViewReports") || access.Can("CanManageRates") {
+			t.Fatalf("unexpected access: %#v %v", access, err)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("expected one load, got %d", loads)
+	}
+	cache.Invalidate("user-1")
+	if _, err := cache.Access(context.Background(), "user-1"); err != nil || loads != 2 {
+		t.Fatalf("expected a reload after invalidation, got %d loads: %v", loads, err)
+	}
+}
+
 func contextWithUser(ctx context.Context, userID string) context.Context {
 	return context.WithValue(ctx, userIDKey, userID)
 }
+
+func TestRequireSuperAdmin(t *testing.T) {
+	cache := NewAdminCache(stubAdminStore{
+		isAdminFn: func(_ context.Context, userID string) (bool, bool, error) {
+			return true, userID == "super", nil
+		},
+		permissionsFn: func(context.Context, string) ([]string, error) {
+			return []string{"CanViewReports"}, nil
+		},
+	}, 0)
+	handler := RequireSuperAdmin(cache)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for userID, want := range map[string]int{"super": http.StatusOK, "admin": http.StatusForbidden} {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req = req.WithContext(contextWithUser(req.Context(), userID))
+		handler.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Fatalf("expected %d for %s, got %d", want, userID, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"banking/internal/auth"
+	"banking/internal/store"
+)
+
+const apiKeyIDKey contextKey = "api_key_id"
+
+type APIKeyStore interface {
+	GetActiveByHash(ctx context.Context, keyHash string) (store.APIKey, error)
+	Touch(ctx context.Context, keyID, ip string) error
+}
+
+func APIKeyIDFromContext(ctx context.Context) string {
+	keyID, _ := ctx.Value(apiKeyIDKey).(string)
+	return keyID
+}
+
+// Access tokens carry every scope.
+func AuthOrAPIKey(keys *auth.KeyRing, revocations *Revocations, apiKeys APIKeyStore, scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		tokenAuth := Auth(keys, revocations)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw := r.Header.Get("X-API-Key")
+			if raw == "" {
+				parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+				if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") && strings.HasPrefix(parts[1], auth.APIKeyPrefix) {
+					raw = parts[1]
+				}
+			}
+			if raw == "" {
+				tokenAuth.ServeHTTP(w, r)
+				return
+			}
+			key, err := apiKeys.GetActiveByHash(r.Context(), auth.HashToken(raw))
+			if err == sql.ErrNoRows {
+				http.Error(w, "invalid api key", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				http.Error(w, "unable to verify api key", http.StatusInternalServerError)
+				return
+			}
+			ip := RemoteIP(r)
+			if !ipAllowed(key.AllowedIPs, ip) {
+				http.Error(w, "api key not allowed from this address", http.StatusForbidden)
+				return
+			}
+			if !hasScope(key.Scopes, scope) {
+				http.Error(w, "api key lacks scope "+scope, http.StatusForbidden)
+				return
+			}
+			if err := apiKeys.Touch(r.Context(), key.ID, ip); err != nil {
+				log.Printf("touch api key %s: %v", key.ID, err)
+			}
+			ctx := context.WithValue(r.Context(), userIDKey, key.UserID)
+			ctx = context.WithValue(ctx, apiKeyIDKey, key.ID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, granted := range scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func ipAllowed(allowlist []string, ip string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, entry := range allowlist {
+		if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
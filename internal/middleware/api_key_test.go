@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"banking/internal/auth"
+	"banking/internal/store"
+)
+
+type stubAPIKeyStore struct {
+	keys    map[string]store.APIKey
+	touched []string
+}
+
+func (s *stubAPIKeyStore) GetActiveByHash(_ context.Context, keyHash string) (store.APIKey, error) {
+	key, ok := s.keys[keyHash]
+	if !ok {
+		return store.APIKey{}, sql.ErrNoRows
+	}
+	return key, nil
+}
+
+func (s *stubAPIKeyStore) Touch(_ context.Context, keyID, ip string) error {
+	s.touched = append(s.touched, keyID+"@"+ip)
+	return nil
+}
+
+func serveAPIKey(t *testing.T, apiKeys *stubAPIKeyStore, scope string, setup func(*http.Request)) (*httptest.ResponseRecorder, string, string) {
+	t.Helper()
+	var userID, keyID string
+	handler := AuthOrAPIKey(testKeys, nil, apiKeys, scope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ = UserIDFromContext(r.Context())
+		keyID = APIKeyIDFromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.1.2.3:4567"
+	setup(req)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr, userID, keyID
+}
+
+func TestAuthOrAPIKeyAcceptsScopedKey(t *testing.T) {
+	apiKeys := &stubAPIKeyStore{keys: map[string]store.APIKey{
+		auth.HashToken("bk_good"): {ID: "key-1", UserID: "user-1", Scopes: []string{auth.ScopeReadAccounts}, AllowedIPs: []string{"10.1.0.0/16"}},
+	}}
+	for _, setup := range []func(*http.Request){
+		func(r *http.Request) { r.Header.Set("Authorization", "Bearer bk_good") },
+		func(r *http.Request) { r.Header.Set("X-API-Key", "bk_good") },
+	} {
+		rr, userID, keyID := serveAPIKey(t, apiKeys, auth.ScopeReadAccounts, setup)
+		if rr.Code != http.StatusOK || userID != "user-1" || keyID != "key-1" {
+			t.Fatalf("expected key to be accepted, got %d %q %q", rr.Code, userID, keyID)
+		}
+	}
+	if len(apiKeys.touched) != 2 || apiKeys.touched[0] != "key-1@10.1.2.3" {
+		t.Fatalf("expected usage to be recorded, got %v", apiKeys.touched)
+	}
+}
+
+func TestAuthOrAPIKeyRejects(t *testing.T) {
+	apiKeys := &stubAPIKeyStore{keys: map[string]store.APIKey{
+		auth.HashToken("bk_reader"): {ID: "key-1", UserID: "user-1", Scopes: []string{auth.ScopeReadAccounts}},
+		auth.HashToken("bk_office"): {ID: "key-2", UserID: "user-1", Scopes: []string{auth.ScopeWriteTransfers}, AllowedIPs: []string{"192.0.2.0/24"}},
+	}}
+	cases := []struct {
+		key  string
+		want int
+	}{
+		{"bk_unknown", http.StatusUnauthorized},
+		{"bk_reader", http.StatusForbidden},
+		{"bk_office", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		rr, _, _ := serveAPIKey(t, apiKeys, auth.ScopeWriteTransfers, func(r *http.Request) { r.Header.Set("X-API-Key", tc.key) })
+		if rr.Code != tc.want {
+			t.Fatalf("expected %d for %s, got %d", tc.want, tc.key, rr.Code)
+		}
+	}
+	if len(apiKeys.touched) != 0 {
+		t.Fatalf("rejected keys must not be marked used: %v", apiKeys.touched)
+	}
+}
+
+func TestAuthOrAPIKeyAcceptsAccessTokens(t *testing.T) {
+	token, err := auth.GenerateToken(testKeys, "user-1", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	rr, userID, keyID := serveAPIKey(t, &stubAPIKeyStore{}, auth.ScopeWriteTransfers, func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) })
+	if rr.Code != http.StatusOK || userID != "user-1" || keyID != "" {
+		t.Fatalf("expected access token to be accepted, got %d %q %q", rr.Code, userID, keyID)
+	}
+}
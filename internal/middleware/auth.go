@@ -10,14 +10,23 @@ import (
 
 type contextKey string
 
-const userIDKey contextKey = "user_id"
+const (
+	userIDKey    contextKey = "user_id"
+	sessionIDKey contextKey = "session_id"
+)
 
 func UserIDFromContext(ctx context.Context) (string, bool) {
 	userID, ok := ctx.Value(userIDKey).(string)
 	return userID, ok
 }
 
-func Auth(secret string) func(http.Handler) http.Handler {
+func SessionIDFromContext(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionIDKey).(string)
+	return sessionID
+}
+
+// Tokens issued without a jti cannot be revoked and live until they expire.
+func Auth(keys *auth.KeyRing, revocations *Revocations) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			header := r.Header.Get("Authorization")
@@ -30,12 +39,17 @@ func Auth(secret string) func(http.Handler) http.Handler {
 				http.Error(w, "invalid authorization header", http.StatusUnauthorized)
 				return
 			}
-			claims, err := auth.ParseToken(secret, parts[1])
+			claims, err := auth.ParseToken(keys, parts[1])
 			if err != nil {
 				http.Error(w, "invalid token", http.StatusUnauthorized)
 				return
 			}
+			if revocations.Revoked(claims.ID) {
+				http.Error(w, "session revoked", http.StatusUnauthorized)
+				return
+			}
 			ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, sessionIDKey, claims.ID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
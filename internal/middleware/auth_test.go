@@ -9,8 +9,20 @@ import (
 	"banking/internal/auth"
 )
 
+var testKeys = newTestKeyRing()
+
+func newTestKeyRing() *auth.KeyRing {
+	key, err := auth.NewSigningKey(auth.AlgEdDSA, time.Now().Add(-time.Minute), time.Hour)
+	if err != nil {
+		panic(err)
+	}
+	keys := auth.NewKeyRing("banking", "banking-api")
+	keys.SetKeys([]auth.SigningKey{key})
+	return keys
+}
+
 func TestAuthMissingHeader(t *testing.T) {
-	handler := Auth("secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := Auth(testKeys, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		t.Fatalf("handler should not be called")
 	}))
 	rr := httptest.NewRecorder()
@@ -22,7 +34,7 @@ func TestAuthMissingHeader(t *testing.T) {
 }
 
 func TestAuthInvalidHeader(t *testing.T) {
-	handler := Auth("secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := Auth(testKeys, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		t.Fatalf("handler should not be called")
 	}))
 	rr := httptest.NewRecorder()
@@ -35,7 +47,7 @@ func TestAuthInvalidHeader(t *testing.T) {
 }
 
 func TestAuthInvalidToken(t *testing.T) {
-	handler := Auth("secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := Auth(testKeys, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		t.Fatalf("handler should not be called")
 	}))
 	rr := httptest.NewRecorder()
@@ -48,11 +60,11 @@ func TestAuthInvalidToken(t *testing.T) {
 }
 
 func TestAuthValidToken(t *testing.T) {
-	token, err := auth.GenerateToken("secret", "user-1", time.Minute)
+	token, err := auth.GenerateToken(testKeys, "user-1", time.Minute)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
-	handler := Auth("secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := Auth(testKeys, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := UserIDFromContext(r.Context())
 		if !ok || userID != "user-1" {
 			t.Fatalf("expected user-1 in context")
@@ -67,3 +79,27 @@ func TestAuthValidToken(t *testing.T) {
 		t.Fatalf("expected 200, got %d", rr.Code)
 	}
 }
+
+func TestAuthRevokedSession(t *testing.T) {
+	revocations := NewRevocations(nil, time.Minute)
+	revocations.Revoke("session-1")
+	handler := Auth(testKeys, revocations)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if SessionIDFromContext(r.Context()) != "session-2" {
+			t.Fatalf("handler should only see session-2")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	for sessionID, want := range map[string]int{"session-1": http.StatusUnauthorized, "session-2": http.StatusOK} {
+		token, err := auth.GenerateSessionToken(testKeys, "user-1", sessionID, time.Minute)
+		if err != nil {
+			t.Fatalf("failed to generate token: %v", err)
+		}
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		handler.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Fatalf("expected %d for %s, got %d", want, sessionID, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	var got string
+	handler := ClientIP([]string{"10.0.0.0/8", "192.0.2.1"})(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = RemoteIP(r)
+	}))
+	cases := []struct {
+		remoteAddr, forwarded, want string
+	}{
+		{"10.1.1.1:5000", "203.0.113.9", "203.0.113.9"},
+		{"10.1.1.1:5000", "198.51.100.1, 203.0.113.9, 10.2.2.2", "203.0.113.9"},
+		{"192.0.2.1:5000", "203.0.113.9", "203.0.113.9"},
+		{"203.0.113.50:5000", "198.51.100.1", "203.0.113.50"},
+		{"10.1.1.1:5000", "", "10.1.1.1"},
+		{"10.1.1.1:5000", "garbage, 10.2.2.2", "10.2.2.2"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.remoteAddr
+		if tc.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tc.forwarded)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if got != tc.want {
+			t.Fatalf("%s via %q: expected %s, got %s", tc.remoteAddr, tc.forwarded, tc.want, got)
+		}
+	}
+}
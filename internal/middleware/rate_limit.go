@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit int, window time.Duration) (bool, float64, error)
+	Prune(ctx context.Context, idle time.Duration) error
+}
+
+type RateLimitPolicy struct {
+	Limit  int
+	Window time.Duration
+}
+
+// If the store fails the request goes through.
+type RateLimiter struct {
+	store    RateLimitStore
+	policies map[string]RateLimitPolicy
+}
+
+func NewRateLimiter(store RateLimitStore, policies map[string]RateLimitPolicy) *RateLimiter {
+	return &RateLimiter{store: store, policies: policies}
+}
+
+// Limit has to run after authentication for requests to be counted per user
+// or API key.
+func (l *RateLimiter) Limit(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+		policy, ok := l.policies[name]
+		if !ok || policy.Limit <= 0 || policy.Window <= 0 {
+			return next
+		}
+		rate := float64(policy.Limit) / policy.Window.Seconds()
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, tokens, err := l.store.Take(r.Context(), name+":"+rateLimitSubject(r), policy.Limit, policy.Window)
+			if err != nil {
+				log.Printf("rate limit %s: %v", name, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(int(math.Max(0, math.Floor(tokens)))))
+			header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds((float64(policy.Limit)-tokens)/rate)))
+			header.Set("RateLimit-Policy", strconv.Itoa(policy.Limit)+";w="+strconv.Itoa(int(policy.Window.Seconds())))
+			if !allowed {
+				header.Set("Retry-After", strconv.Itoa(ceilSeconds((1-tokens)/rate)))
+				header.Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "rate_limit_exceeded"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (l *RateLimiter) Run(ctx context.Context, interval time.Duration) {
+	var idle time.Duration
+	for _, policy := range l.policies {
+		if policy.Window > idle {
+			idle = policy.Window
+		}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := l.store.Prune(ctx, idle); err != nil && ctx.Err() == nil {
+			log.Printf("rate limit prune failed: %v", err)
+		}
+	}
+}
+
+func rateLimitSubject(r *http.Request) string {
+	if keyID := APIKeyIDFromContext(r.Context()); keyID != "" {
+		return "key:" + keyID
+	}
+	if userID, ok := UserIDFromContext(r.Context()); ok && userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + RemoteIP(r)
+}
+
+func ceilSeconds(seconds float64) int {
+	if seconds <= 0 {
+		return 0
+	}
+	return int(math.Ceil(seconds))
+}
+
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]rateLimitBucket
+	now     func() time.Time
+}
+
+type rateLimitBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]rateLimitBucket), now: time.Now}
+}
+
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, limit int, window time.Duration) (bool, float64, error) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens := float64(limit)
+	if bucket, ok := s.buckets[key]; ok {
+		rate := float64(limit) / window.Seconds()
+		tokens = math.Min(tokens, bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*rate)
+	}
+	if tokens < 1 {
+		return false, tokens, nil
+	}
+	s.buckets[key] = rateLimitBucket{tokens: tokens - 1, updatedAt: now}
+	return true, tokens - 1, nil
+}
+
+func (s *MemoryRateLimitStore) Prune(_ context.Context, idle time.Duration) error {
+	cutoff := s.now().Add(-idle)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, bucket := range s.buckets {
+		if bucket.updatedAt.Before(cutoff) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
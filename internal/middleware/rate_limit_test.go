@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(context.Context, string, int, time.Duration) (bool, float64, error) {
+	return false, 0, errors.New("database down")
+}
+
+func (failingRateLimitStore) Prune(context.Context, time.Duration) error { return nil }
+
+func serveRateLimited(ctx context.Context, handler http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx)
+	req.RemoteAddr = remoteAddr
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRateLimiterLimitsPerClient(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	limiter := NewRateLimiter(store, map[string]RateLimitPolicy{"register": {Limit: 2, Window: time.Minute}})
+	handler := limiter.Limit("register")(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	for i, want := range []string{"1", "0"} {
+		rr := serveRateLimited(context.Background(), handler, "10.0.0.1:1000")
+		if rr.Code != http.StatusOK || rr.Header().Get("RateLimit-Remaining") != want {
+			t.Fatalf("request %d: expected 200 with %s remaining, got %d %q", i, want, rr.Code, rr.Header().Get("RateLimit-Remaining"))
+		}
+	}
+	rr := serveRateLimited(context.Background(), handler, "10.0.0.1:2000")
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Content-Type") != "application/json" || !strings.Contains(rr.Body.String(), `"error":"rate_limit_exceeded"`) {
+		t.Fatalf("expected a JSON 429, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("Retry-After") != "30" || rr.Header().Get("RateLimit-Limit") != "2" || rr.Header().Get("RateLimit-Reset") != "60" || rr.Header().Get("RateLimit-Policy") != "2;w=60" {
+		t.Fatalf("unexpected headers: %v", rr.Header())
+	}
+	if rr = serveRateLimited(context.Background(), handler, "10.0.0.2:1000"); rr.Code != http.StatusOK {
+		t.Fatalf("other clients have their own bucket, got %d", rr.Code)
+	}
+
+	now = now.Add(30 * time.Second)
+	if rr = serveRateLimited(context.Background(), handler, "10.0.0.1:1000"); rr.Code != http.StatusOK {
+		t.Fatalf("expected a refilled token, got %d", rr.Code)
+	}
+}
+
+func TestRateLimiterKeysByUserAndAPIKey(t *testing.T) {
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(), map[string]RateLimitPolicy{"quote": {Limit: 1, Window: time.Hour}})
+	handler := limiter.Limit("quote")(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	user := context.WithValue(context.Background(), userIDKey, "user-1")
+	key := context.WithValue(user, apiKeyIDKey, "key-1")
+
+	if rr := serveRateLimited(user, handler, "10.0.0.1:1000"); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if rr := serveRateLimited(user, handler, "10.0.0.9:1000"); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("a user's requests share a bucket across addresses, got %d", rr.Code)
+	}
+	if rr := serveRateLimited(key, handler, "10.0.0.1:1000"); rr.Code != http.StatusOK {
+		t.Fatalf("API keys have their own bucket, got %d", rr.Code)
+	}
+}
+
+func TestRateLimiterPassesThrough(t *testing.T) {
+	served := 0
+	next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) { served++ })
+	var unset *RateLimiter
+	failing := NewRateLimiter(failingRateLimitStore{}, map[string]RateLimitPolicy{"quote": {Limit: 1, Window: time.Minute}})
+	handlers := []http.Handler{
+		unset.Limit("quote")(next),
+		failing.Limit("register")(next),
+		failing.Limit("quote")(next),
+	}
+	for _, handler := range handlers {
+		if rr := serveRateLimited(context.Background(), handler, "10.0.0.1:1000"); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rr.Code)
+		}
+	}
+	if served != len(handlers) {
+		t.Fatalf("expected every request served, got %d", served)
+	}
+}
+
+func TestMemoryRateLimitStorePrune(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	store.Take(context.Background(), "a", 5, time.Minute)
+	now = now.Add(2 * time.Minute)
+	store.Take(context.Background(), "b", 5, time.Minute)
+	if err := store.Prune(context.Background(), time.Minute); err != nil || len(store.buckets) != 1 {
+		t.Fatalf("expected only the idle bucket pruned, got %d %v", len(store.buckets), err)
+	}
+}
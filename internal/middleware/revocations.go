@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+type RevocationStore interface {
+	ListRevoked(ctx context.Context, window time.Duration) ([]string, error)
+}
+
+// Revocations only reloads sessions revoked within window, the access token
+// TTL: tokens of sessions revoked earlier have expired anyway. A nil
+// *Revocations revokes nothing.
+type Revocations struct {
+	store   RevocationStore
+	window  time.Duration
+	mu      sync.RWMutex
+	revoked map[string]struct{}
+}
+
+func NewRevocations(store RevocationStore, window time.Duration) *Revocations {
+	return &Revocations{store: store, window: window, revoked: make(map[string]struct{})}
+}
+
+func (r *Revocations) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := r.Reload(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("session revocation reload failed: %v", err)
+		}
+	}
+}
+
+func (r *Revocations) Reload(ctx context.Context) error {
+	ids, err := r.store.ListRevoked(ctx, r.window)
+	if err != nil {
+		return err
+	}
+	revoked := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		revoked[id] = struct{}{}
+	}
+	r.mu.Lock()
+	r.revoked = revoked
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *Revocations) Revoke(sessionID string) {
+	if r == nil || sessionID == "" {
+		return
+	}
+	r.mu.Lock()
+	r.revoked[sessionID] = struct{}{}
+	r.mu.Unlock()
+}
+
+func (r *Revocations) Revoked(sessionID string) bool {
+	if r == nil || sessionID == "" {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.revoked[sessionID]
+	return ok
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type stubRevocationStore struct {
+	ids    []string
+	window time.Duration
+}
+
+func (s *stubRevocationStore) ListRevoked(_ context.Context, window time.Duration) ([]string, error) {
+	s.window = window
+	return s.ids, nil
+}
+
+func TestRevocationsReloadReplacesSet(t *testing.T) {
+	store := &stubRevocationStore{ids: []string{"session-1"}}
+	revocations := NewRevocations(store, 15*time.Minute)
+	revocations.Revoke("local")
+	if err := revocations.Reload(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.window != 15*time.Minute {
+		t.Fatalf("unexpected window: %v", store.window)
+	}
+	if !revocations.Revoked("session-1") {
+		t.Fatalf("expected session-1 to be revoked")
+	}
+	if revocations.Revoked("local") {
+		t.Fatalf("expected entries outside the window to be dropped")
+	}
+}
+
+func TestNilRevocationsRevokesNothing(t *testing.T) {
+	var revocations *Revocations
+	revocations.Revoke("session-1")
+	if revocations.Revoked("session-1") {
+		t.Fatalf("expected nil revocations to revoke nothing")
+	}
+}
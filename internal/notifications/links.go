@@ -0,0 +1,34 @@
+package notifications
+
+import (
+	"net/url"
+
+	"banking/internal/auth"
+)
+
+// LinkPlaceholder stands in for the single-use link in queued bodies; the
+// sender puts the link in when it sends.
+const LinkPlaceholder = "{{token_link}}"
+
+type tokenLink struct {
+	path   string
+	prefix string
+}
+
+var tokenLinks = map[string]tokenLink{
+	KindEmailVerification: {path: "/verify-email", prefix: "ev_"},
+	KindPasswordReset:     {path: "/reset-password", prefix: "pr_"},
+}
+
+type Links struct {
+	BaseURL string
+	Key     string
+}
+
+func (l Links) Token(kind, ref string) string {
+	return auth.DeriveToken(l.Key, tokenLinks[kind].prefix, ref)
+}
+
+func (l Links) Link(kind, ref string) string {
+	return l.BaseURL + tokenLinks[kind].path + "?token=" + url.QueryEscape(l.Token(kind, ref))
+}
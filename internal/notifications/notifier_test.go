@@ -0,0 +1,44 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogNotifierWritesMessage(t *testing.T) {
+	var out bytes.Buffer
+	notifier := NewLogNotifier(&out)
+	if notifier.Channel() != ChannelEmail {
+		t.Fatalf("unexpected channel: %s", notifier.Channel())
+	}
+	if err := notifier.Send(context.Background(), Message{To: "a@example.com", Subject: "Hello", Body: "Body"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "To: a@example.com\nSubject: Hello\n\nBody") {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+}
+
+func TestFormatEmailStripsHeaderInjection(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	raw := string(formatEmail("bank@example.com", Message{
+		To:      "a@example.com",
+		Subject: "Hi\r\nBcc: evil@example.com",
+		Body:    "line one\nline two",
+	}, now))
+	if strings.Contains(raw, "\r\nBcc:") {
+		t.Fatalf("header injection not stripped: %q", raw)
+	}
+	if !strings.Contains(raw, "Subject: Hi  Bcc: evil@example.com\r\n") {
+		t.Fatalf("unexpected subject: %q", raw)
+	}
+	if !strings.HasSuffix(raw, "\r\n\r\nline one\r\nline two") {
+		t.Fatalf("unexpected body: %q", raw)
+	}
+	if !strings.Contains(raw, "Date: Thu, 02 Jan 2025 03:04:05 +0000\r\n") {
+		t.Fatalf("unexpected date: %q", raw)
+	}
+}
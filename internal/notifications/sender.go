@@ -0,0 +1,116 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"banking/internal/store"
+)
+
+var ErrNoNotifier = errors.New("no notifier for channel")
+
+type DeliveryStore interface {
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]store.NotificationDelivery, error)
+	RecordSuccess(ctx context.Context, deliveryID string) error
+	RecordFailure(ctx context.Context, deliveryID, message string, retryAt *time.Time) error
+}
+
+type Sender struct {
+	store       DeliveryStore
+	links       Links
+	notifiers   map[string]Notifier
+	batchSize   int
+	lease       time.Duration
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+func NewSender(store DeliveryStore, links Links, notifiers ...Notifier) *Sender {
+	byChannel := make(map[string]Notifier, len(notifiers))
+	for _, notifier := range notifiers {
+		byChannel[notifier.Channel()] = notifier
+	}
+	return &Sender{
+		store:       store,
+		links:       links,
+		notifiers:   byChannel,
+		batchSize:   50,
+		lease:       time.Minute,
+		maxAttempts: 6,
+		baseDelay:   30 * time.Second,
+		maxDelay:    time.Hour,
+	}
+}
+
+func (s *Sender) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.SendOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("notification delivery failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sender) SendOnce(ctx context.Context) (int, error) {
+	sent := 0
+	for {
+		batch, err := s.store.ClaimDeliveries(ctx, s.batchSize, s.lease)
+		if err != nil {
+			return sent, err
+		}
+		for _, delivery := range batch {
+			ok, err := s.send(ctx, delivery)
+			if err != nil {
+				return sent, err
+			}
+			if ok {
+				sent++
+			}
+		}
+		if len(batch) < s.batchSize {
+			return sent, nil
+		}
+	}
+}
+
+func (s *Sender) send(ctx context.Context, delivery store.NotificationDelivery) (bool, error) {
+	notifier, ok := s.notifiers[delivery.Channel]
+	if !ok {
+		return false, s.store.RecordFailure(ctx, delivery.ID, ErrNoNotifier.Error(), nil)
+	}
+	body := delivery.Body
+	if delivery.TokenRef != nil {
+		body = strings.ReplaceAll(body, LinkPlaceholder, s.links.Link(delivery.Kind, *delivery.TokenRef))
+	}
+	err := notifier.Send(ctx, Message{To: delivery.Destination, Subject: delivery.Subject, Body: body})
+	if err == nil {
+		return true, s.store.RecordSuccess(ctx, delivery.ID)
+	}
+	var retryAt *time.Time
+	if attempts := delivery.Attempts + 1; attempts < s.maxAttempts {
+		next := time.Now().Add(s.retryDelay(attempts))
+		retryAt = &next
+	}
+	return false, s.store.RecordFailure(ctx, delivery.ID, err.Error(), retryAt)
+}
+
+func (s *Sender) retryDelay(attempts int) time.Duration {
+	delay := s.baseDelay
+	for i := 1; i < attempts && delay < s.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.maxDelay {
+		return s.maxDelay
+	}
+	return delay
+}
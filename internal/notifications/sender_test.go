@@ -0,0 +1,135 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"banking/internal/store"
+)
+
+type failure struct {
+	message string
+	retryAt *time.Time
+}
+
+type stubDeliveryStore struct {
+	pending []store.NotificationDelivery
+	sent    []string
+	failed  map[string]failure
+}
+
+func (s *stubDeliveryStore) ClaimDeliveries(context.Context, int, time.Duration) ([]store.NotificationDelivery, error) {
+	batch := s.pending
+	s.pending = nil
+	return batch, nil
+}
+
+func (s *stubDeliveryStore) RecordSuccess(_ context.Context, deliveryID string) error {
+	s.sent = append(s.sent, deliveryID)
+	return nil
+}
+
+func (s *stubDeliveryStore) RecordFailure(_ context.Context, deliveryID, message string, retryAt *time.Time) error {
+	s.failed[deliveryID] = failure{message: message, retryAt: retryAt}
+	return nil
+}
+
+type stubNotifier struct {
+	channel string
+	err     error
+	sent    []Message
+}
+
+func (n *stubNotifier) Channel() string {
+	return n.channel
+}
+
+func (n *stubNotifier) Send(_ context.Context, message Message) error {
+	n.sent = append(n.sent, message)
+	return n.err
+}
+
+func TestSenderDeliversThroughChannelNotifier(t *testing.T) {
+	deliveries := &stubDeliveryStore{
+		pending: []store.NotificationDelivery{{ID: "n-1", Channel: ChannelEmail, Destination: "a@example.com", Subject: "s", Body: "b"}},
+		failed:  map[string]failure{},
+	}
+	notifier := &stubNotifier{channel: ChannelEmail}
+	sent, err := NewSender(deliveries, Links{}, notifier).SendOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent != 1 || len(deliveries.sent) != 1 || deliveries.sent[0] != "n-1" {
+		t.Fatalf("expected delivery to succeed, got %d %v", sent, deliveries.sent)
+	}
+	if len(notifier.sent) != 1 || notifier.sent[0].To != "a@example.com" {
+		t.Fatalf("unexpected messages: %#v", notifier.sent)
+	}
+}
+
+func TestSenderSchedulesRetryWithBackoff(t *testing.T) {
+	deliveries := &stubDeliveryStore{
+		pending: []store.NotificationDelivery{{ID: "n-1", Channel: ChannelEmail, Attempts: 2}},
+		failed:  map[string]failure{},
+	}
+	before := time.Now()
+	if _, err := NewSender(deliveries, Links{}, &stubNotifier{channel: ChannelEmail, err: errors.New("smtp down")}).SendOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	failed := deliveries.failed["n-1"]
+	if failed.message != "smtp down" || failed.retryAt == nil {
+		t.Fatalf("expected retry, got %#v", failed)
+	}
+	if delay := failed.retryAt.Sub(before); delay < 2*time.Minute || delay > 2*time.Minute+time.Second {
+		t.Fatalf("expected 2m backoff, got %s", delay)
+	}
+}
+
+func TestSenderGivesUpAfterMaxAttempts(t *testing.T) {
+	deliveries := &stubDeliveryStore{
+		pending: []store.NotificationDelivery{{ID: "n-1", Channel: ChannelEmail, Attempts: 5}},
+		failed:  map[string]failure{},
+	}
+	if _, err := NewSender(deliveries, Links{}, &stubNotifier{channel: ChannelEmail, err: errors.New("smtp down")}).SendOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if failed, ok := deliveries.failed["n-1"]; !ok || failed.retryAt != nil {
+		t.Fatalf("expected permanent failure, got %#v", failed)
+	}
+}
+
+func TestSenderFailsChannelsWithoutNotifier(t *testing.T) {
+	deliveries := &stubDeliveryStore{
+		pending: []store.NotificationDelivery{{ID: "n-1", Channel: "sms"}},
+		failed:  map[string]failure{},
+	}
+	if _, err := NewSender(deliveries, Links{}, &stubNotifier{channel: ChannelEmail}).SendOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if failed := deliveries.failed["n-1"]; failed.message != ErrNoNotifier.Error() || failed.retryAt != nil {
+		t.Fatalf("expected permanent failure, got %#v", failed)
+	}
+}
+
+func TestSenderPutsTokenLinkInAtSendTime(t *testing.T) {
+	ref := "ref-1"
+	deliveries := &stubDeliveryStore{
+		pending: []store.NotificationDelivery{{ID: "n-1", Channel: ChannelEmail, Kind: KindPasswordReset, Destination: "a@example.com", Subject: "s", Body: "open " + LinkPlaceholder, TokenRef: &ref}},
+		failed:  map[string]failure{},
+	}
+	notifier := &stubNotifier{channel: ChannelEmail}
+	links := Links{BaseURL: "https://bank.example", Key: "key"}
+	if _, err := NewSender(deliveries, links, notifier).SendOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "open https://bank.example/reset-password?token=" + links.Token(KindPasswordReset, ref)
+	if len(notifier.sent) != 1 || notifier.sent[0].Body != want {
+		t.Fatalf("unexpected mail: %#v", notifier.sent)
+	}
+	if !strings.HasPrefix(links.Token(KindPasswordReset, ref), "pr_") || links.Token(KindPasswordReset, ref) == (Links{Key: "other"}).Token(KindPasswordReset, ref) {
+		t.Fatalf("expected a prefixed token keyed by the mail key")
+	}
+}
@@ -0,0 +1,158 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"banking/internal/events"
+	"banking/internal/store"
+
+	"github.com/google/uuid"
+)
+
+type RecipientStore interface {
+	Recipient(ctx context.Context, userID, kind string) (store.NotificationRecipient, error)
+	EnqueueDelivery(ctx context.Context, input store.NotificationDeliveryInput) error
+}
+
+// Enqueueing is idempotent per event, so dispatcher retries never notify
+// twice.
+type Sink struct {
+	store    RecipientStore
+	channels []string
+}
+
+type target struct {
+	kind           string
+	userID         string
+	accountID      string
+	counterpartyID string
+}
+
+func NewSink(store RecipientStore, channels ...string) *Sink {
+	return &Sink{store: store, channels: channels}
+}
+
+func (s *Sink) Name() string {
+	return "notifications"
+}
+
+func (s *Sink) Deliver(ctx context.Context, event store.DomainEvent) error {
+	targets := targetsFor(event)
+	if len(targets) == 0 {
+		return nil
+	}
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+		return err
+	}
+	changes, err := events.BalanceChanges(event)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		recipient, err := s.store.Recipient(ctx, target.userID, target.kind)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		data := make(map[string]any, len(payload)+4)
+		for key, value := range payload {
+			data[key] = value
+		}
+		data["username"] = recipient.Username
+		data["occurred_at"] = event.CreatedAt.UTC().Format(time.RFC1123)
+		for _, change := range changes {
+			if change.UserID == target.userID && change.AccountID == target.accountID {
+				data["balance"] = change.Balance
+			}
+		}
+		if target.counterpartyID != "" {
+			counterparty, err := s.store.Recipient(ctx, target.counterpartyID, target.kind)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			data["counterparty"] = counterparty.Username
+		}
+		tokenRef := ""
+		if CarriesToken(target.kind) {
+			tokenRef, _ = payload["token_ref"].(string)
+			data["link"] = LinkPlaceholder
+		}
+		subject, body, err := Render(target.kind, data)
+		if err != nil {
+			return err
+		}
+		for _, channel := range s.channels {
+			if recipient.Disabled(channel) && !Mandatory(target.kind) {
+				continue
+			}
+			destination := destinationFor(channel, recipient)
+			if destination == "" {
+				continue
+			}
+			if err := s.store.EnqueueDelivery(ctx, store.NotificationDeliveryInput{
+				ID:          uuid.NewString(),
+				EventID:     event.ID,
+				UserID:      target.userID,
+				Channel:     channel,
+				Kind:        target.kind,
+				Destination: destination,
+				Subject:     subject,
+				Body:        body,
+				TokenRef:    tokenRef,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func targetsFor(event store.DomainEvent) []target {
+	if event.UserID == nil {
+		return nil
+	}
+	userID := *event.UserID
+	switch event.Type {
+	case events.TransferCompleted:
+		var payload struct {
+			FromAccountID string `json:"from_account_id"`
+			ToAccountID   string `json:"to_account_id"`
+			ToUserID      string `json:"to_user_id"`
+		}
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			return nil
+		}
+		targets := []target{{kind: KindTransferSent, userID: userID, accountID: payload.FromAccountID, counterpartyID: payload.ToUserID}}
+		if payload.ToUserID != "" && payload.ToUserID != userID {
+			targets = append(targets, target{kind: KindTransferReceived, userID: payload.ToUserID, accountID: payload.ToAccountID, counterpartyID: userID})
+		}
+		return targets
+	case events.ExchangeCompleted:
+		return []target{{kind: KindExchangeCompleted, userID: userID}}
+	case events.NewDeviceLogin:
+		return []target{{kind: KindNewDeviceLogin, userID: userID}}
+	case events.PasswordChanged:
+		return []target{{kind: KindPasswordChanged, userID: userID}}
+	case events.EmailVerificationRequested:
+		return []target{{kind: KindEmailVerification, userID: userID}}
+	case events.PasswordResetRequested:
+		return []target{{kind: KindPasswordReset, userID: userID}}
+	case events.AlertTriggered:
+		return []target{{kind: KindAlertTriggered, userID: userID}}
+	}
+	return nil
+}
+
+func destinationFor(channel string, recipient store.NotificationRecipient) string {
+	switch channel {
+	case ChannelEmail:
+		return recipient.Email
+	}
+	return ""
+}
@@ -0,0 +1,178 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"banking/internal/events"
+	"banking/internal/store"
+)
+
+type stubRecipientStore struct {
+	recipients map[string]store.NotificationRecipient
+	queued     []store.NotificationDeliveryInput
+}
+
+func (s *stubRecipientStore) Recipient(_ context.Context, userID, _ string) (store.NotificationRecipient, error) {
+	recipient, ok := s.recipients[userID]
+	if !ok {
+		return store.NotificationRecipient{}, sql.ErrNoRows
+	}
+	return recipient, nil
+}
+
+func (s *stubRecipientStore) EnqueueDelivery(_ context.Context, input store.NotificationDeliveryInput) error {
+	s.queued = append(s.queued, input)
+	return nil
+}
+
+func domainEvent(input store.DomainEventInput) store.DomainEvent {
+	return store.DomainEvent{
+		ID:        input.ID,
+		Type:      input.Type,
+		UserID:    input.UserID,
+		Payload:   input.Payload,
+		CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestSinkNotifiesBothSidesOfTransfer(t *testing.T) {
+	sender := "user-1"
+	event := domainEvent(events.New(events.TransferCompleted, events.AggregateTransaction, "tx-1", &sender, map[string]any{
+		"transaction_id":  "tx-1",
+		"from_account_id": "acc-1",
+		"to_account_id":   "acc-2",
+		"to_user_id":      "user-2",
+		"amount":          "10.00",
+		"currency":        "USD",
+		"balances": []events.BalanceChange{
+			{UserID: "user-1", AccountID: "acc-1", Currency: "USD", Balance: "90.00"},
+			{UserID: "user-2", AccountID: "acc-2", Currency: "USD", Balance: "110.00"},
+		},
+	}))
+	recipients := &stubRecipientStore{recipients: map[string]store.NotificationRecipient{
+		"user-1": {UserID: "user-1", Username: "alice", Email: "alice@example.com"},
+		"user-2": {UserID: "user-2", Username: "bob", Email: "bob@example.com"},
+	}}
+	if err := NewSink(recipients, ChannelEmail).Deliver(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recipients.queued) != 2 {
+		t.Fatalf("expected 2 notifications, got %#v", recipients.queued)
+	}
+	sent, received := recipients.queued[0], recipients.queued[1]
+	if sent.Kind != KindTransferSent || sent.Destination != "alice@example.com" || !strings.Contains(sent.Body, "to bob") || !strings.Contains(sent.Body, "now 90.00") {
+		t.Fatalf("unexpected sent notice: %#v", sent)
+	}
+	if received.Kind != KindTransferReceived || received.Destination != "bob@example.com" || !strings.Contains(received.Body, "alice sent you 10.00 USD") || !strings.Contains(received.Body, "now 110.00") {
+		t.Fatalf("unexpected received notice: %#v", received)
+	}
+	if received.EventID != event.ID || received.Channel != ChannelEmail {
+		t.Fatalf("unexpected delivery: %#v", received)
+	}
+}
+
+func TestSinkRespectsOptOut(t *testing.T) {
+	userID := "user-1"
+	event := domainEvent(events.New(events.ExchangeCompleted, events.AggregateTransaction, "tx-1", &userID, map[string]any{
+		"transaction_id":   "tx-1",
+		"from_currency":    "USD",
+		"to_currency":      "EUR",
+		"amount":           "10.00",
+		"converted_amount": "9.20",
+		"rate":             "0.920000",
+	}))
+	recipients := &stubRecipientStore{recipients: map[string]store.NotificationRecipient{
+		"user-1": {UserID: "user-1", Username: "alice", Email: "alice@example.com", DisabledChannels: []string{ChannelEmail}},
+	}}
+	if err := NewSink(recipients, ChannelEmail).Deliver(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recipients.queued) != 0 {
+		t.Fatalf("expected no notifications, got %#v", recipients.queued)
+	}
+}
+
+func TestSinkAlwaysSendsSecurityNotices(t *testing.T) {
+	userID := "user-1"
+	event := domainEvent(events.New(events.NewDeviceLogin, events.AggregateUser, userID, &userID, map[string]any{
+		"user_id":    userID,
+		"ip":         "203.0.113.7",
+		"user_agent": "curl/8.0",
+	}))
+	recipients := &stubRecipientStore{recipients: map[string]store.NotificationRecipient{
+		"user-1": {UserID: "user-1", Username: "alice", Email: "alice@example.com", DisabledChannels: []string{ChannelEmail}},
+	}}
+	if err := NewSink(recipients, ChannelEmail).Deliver(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recipients.queued) != 1 || recipients.queued[0].Kind != KindNewDeviceLogin {
+		t.Fatalf("expected security notice, got %#v", recipients.queued)
+	}
+	if !strings.Contains(recipients.queued[0].Body, "curl/8.0") || !strings.Contains(recipients.queued[0].Body, "Thu, 02 Jan 2025 03:04:05 UTC") {
+		t.Fatalf("unexpected body: %q", recipients.queued[0].Body)
+	}
+}
+
+func TestSinkIgnoresOtherEvents(t *testing.T) {
+	userID := "user-1"
+	event := domainEvent(events.New(events.AccountFrozen, events.AggregateAccount, "acc-1", &userID, map[string]any{}))
+	recipients := &stubRecipientStore{}
+	if err := NewSink(recipients, ChannelEmail).Deliver(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recipients.queued) != 0 {
+		t.Fatalf("expected no notifications, got %#v", recipients.queued)
+	}
+}
+
+func TestSinkNotifiesTriggeredAlerts(t *testing.T) {
+	userID := "user-1"
+	event := domainEvent(events.New(events.AlertTriggered, events.AggregateAlertRule, "rule-1", &userID, map[string]any{
+		"rule_id":        "rule-1",
+		"rule_kind":      "large_transaction",
+		"account_id":     "acc-1",
+		"currency":       "USD",
+		"threshold":      "1000.00",
+		"amount":         "1500.00",
+		"balance":        "200.00",
+		"transaction_id": "tx-1",
+		"suppressed":     0,
+	}))
+	recipients := &stubRecipientStore{recipients: map[string]store.NotificationRecipient{
+		"user-1": {UserID: "user-1", Username: "alice", Email: "alice@example.com"},
+	}}
+	if err := NewSink(recipients, ChannelEmail).Deliver(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recipients.queued) != 1 || recipients.queued[0].Kind != KindAlertTriggered || recipients.queued[0].Subject != "Debit of 1500.00 USD" {
+		t.Fatalf("unexpected notifications: %#v", recipients.queued)
+	}
+	if strings.Contains(recipients.queued[0].Body, "held back") {
+		t.Fatalf("unexpected body: %q", recipients.queued[0].Body)
+	}
+}
+
+func TestSinkMailsTokenLinks(t *testing.T) {
+	userID := "user-1"
+	event := domainEvent(events.New(events.PasswordResetRequested, events.AggregateUser, userID, &userID, map[string]string{
+		"token_ref":  "ref-1",
+		"expires_in": "1 hour",
+	}))
+	recipients := &stubRecipientStore{recipients: map[string]store.NotificationRecipient{
+		"user-1": {UserID: "user-1", Username: "alice", Email: "alice@example.com", DisabledChannels: []string{ChannelEmail}},
+	}}
+	if err := NewSink(recipients, ChannelEmail).Deliver(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recipients.queued) != 1 {
+		t.Fatalf("expected one mail, got %#v", recipients.queued)
+	}
+	mail := recipients.queued[0]
+	if mail.Kind != KindPasswordReset || mail.Destination != "alice@example.com" || !strings.Contains(mail.Body, LinkPlaceholder) || mail.TokenRef != "ref-1" {
+		t.Fatalf("unexpected mail: %#v", mail)
+	}
+}
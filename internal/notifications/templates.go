@@ -0,0 +1,152 @@
+package notifications
+
+import (
+	"errors"
+	"strings"
+	"text/template"
+)
+
+const (
+	KindTransferReceived  = "transfer.received"
+	KindTransferSent      = "transfer.sent"
+	KindExchangeCompleted = "exchange.completed"
+	KindNewDeviceLogin    = "security.new_device_login"
+	KindPasswordChanged   = "security.password_changed"
+	KindAlertTriggered    = "alert.triggered"
+)
+
+var Kinds = []string{KindTransferReceived, KindTransferSent, KindExchangeCompleted, KindNewDeviceLogin, KindPasswordChanged, KindAlertTriggered}
+
+const (
+	KindEmailVerification = "security.email_verification"
+	KindPasswordReset     = "security.password_reset"
+)
+
+var ErrUnknownKind = errors.New("unknown notification kind")
+
+// CarriesToken reports whether a kind mails a single-use link, which is
+// only put into the body when it is sent.
+func CarriesToken(kind string) bool {
+	_, ok := tokenLinks[kind]
+	return ok
+}
+
+func IsKind(value string) bool {
+	for _, kind := range Kinds {
+		if kind == value {
+			return true
+		}
+	}
+	return false
+}
+
+func Mandatory(kind string) bool {
+	return strings.HasPrefix(kind, "security.")
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+var templates = map[string]messageTemplate{
+	KindTransferReceived: parseTemplate(KindTransferReceived,
+		`You received {{.amount}} {{.currency}}`,
+		`Hi {{.username}},
+
+{{.counterparty}} sent you {{.amount}} {{.currency}}.
+Your {{.currency}} balance is now {{.balance}}.
+
+Transaction: {{.transaction_id}}
+`),
+	KindTransferSent: parseTemplate(KindTransferSent,
+		`You sent {{.amount}} {{.currency}}`,
+		`Hi {{.username}},
+
+You sent {{.amount}} {{.currency}} to {{.counterparty}}.
+Your {{.currency}} balance is now {{.balance}}.
+
+Transaction: {{.transaction_id}}
+`),
+	KindExchangeCompleted: parseTemplate(KindExchangeCompleted,
+		`Exchange of {{.amount}} {{.from_currency}} completed`,
+		`Hi {{.username}},
+
+You exchanged {{.amount}} {{.from_currency}} for {{.converted_amount}} {{.to_currency}} at a rate of {{.rate}}.
+
+Transaction: {{.transaction_id}}
+`),
+	KindNewDeviceLogin: parseTemplate(KindNewDeviceLogin,
+		`New sign-in to your account`,
+		`Hi {{.username}},
+
+Your account was signed in to from a device we haven't seen before.
+
+Device: {{with index . "device"}}{{.}} ({{end}}{{.user_agent}}{{with index . "device"}}){{end}}
+Address: {{.ip}}
+Time: {{.occurred_at}}
+
+If this wasn't you, change your password now.
+`),
+	KindPasswordChanged: parseTemplate(KindPasswordChanged,
+		`Your password was changed`,
+		`Hi {{.username}},
+
+The password for your account was changed at {{.occurred_at}}.
+
+If you didn't do this, contact support immediately.
+`),
+	KindAlertTriggered: parseTemplate(KindAlertTriggered,
+		`{{if eq .rule_kind "low_balance"}}Your {{.currency}} balance is below {{.threshold}}{{else if eq .rule_kind "large_transaction"}}Debit of {{.amount}} {{.currency}}{{else}}{{.amount}} {{.currency}} arrived{{end}}`,
+		`Hi {{.username}},
+
+{{if eq .rule_kind "low_balance"}}Your {{.currency}} balance dropped to {{.balance}}, below your alert at {{.threshold}}.
+{{else if eq .rule_kind "large_transaction"}}{{.amount}} {{.currency}} left your account, matching your alert for debits of {{.threshold}} or more. Your balance is now {{.balance}}.
+{{else}}{{.amount}} {{.currency}} arrived in your account. Your balance is now {{.balance}}.
+{{end}}{{if .suppressed}}{{.suppressed}} more alerts for this rule were held back since the last one.
+{{end}}
+Transaction: {{.transaction_id}}
+`),
+	KindEmailVerification: parseTemplate(KindEmailVerification,
+		`Confirm your email address`,
+		`Hi {{.username}},
+
+Confirm that this is your email address by opening the link below:
+
+{{.link}}
+
+The link works once and expires in {{.expires_in}}. If you didn't create an account, you can ignore this email.
+`),
+	KindPasswordReset: parseTemplate(KindPasswordReset,
+		`Reset your password`,
+		`Hi {{.username}},
+
+Someone asked to reset the password for your account. To choose a new one, open the link below:
+
+{{.link}}
+
+The link works once and expires in {{.expires_in}}. Resetting signs you out everywhere. If you didn't ask for this, you can ignore this email; your password stays the same.
+`),
+}
+
+func parseTemplate(kind, subject, body string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New(kind + ".subject").Option("missingkey=error").Parse(subject)),
+		body:    template.Must(template.New(kind + ".body").Option("missingkey=error").Parse(body)),
+	}
+}
+
+func Render(kind string, data map[string]any) (string, string, error) {
+	tmpl, ok := templates[kind]
+	if !ok {
+		return "", "", ErrUnknownKind
+	}
+	var subject, body strings.Builder
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+	return subject.String(), body.String(), nil
+}
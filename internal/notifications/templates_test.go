@@ -0,0 +1,90 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderTransferReceived(t *testing.T) {
+	subject, body, err := Render(KindTransferReceived, map[string]any{
+		"username":       "bob",
+		"counterparty":   "alice",
+		"amount":         "12.50",
+		"currency":       "USD",
+		"balance":        "112.50",
+		"transaction_id": "tx-1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if subject != "You received 12.50 USD" {
+		t.Fatalf("unexpected subject: %q", subject)
+	}
+	if !strings.Contains(body, "alice sent you 12.50 USD.") || !strings.Contains(body, "balance is now 112.50") {
+		t.Fatalf("unexpected body: %q", body)
+	}
+}
+
+func TestRenderNewDeviceLoginNamesDevice(t *testing.T) {
+	data := map[string]any{"username": "bob", "user_agent": "curl/8.0", "ip": "203.0.113.7", "occurred_at": "now"}
+	_, body, err := Render(KindNewDeviceLogin, data)
+	if err != nil || !strings.Contains(body, "Device: curl/8.0\n") {
+		t.Fatalf("unexpected body without a device label: %q %v", body, err)
+	}
+	data["device"] = "curl"
+	_, body, err = Render(KindNewDeviceLogin, data)
+	if err != nil || !strings.Contains(body, "Device: curl (curl/8.0)\n") {
+		t.Fatalf("unexpected body: %q %v", body, err)
+	}
+}
+
+func TestRenderRequiresEveryField(t *testing.T) {
+	if _, _, err := Render(KindPasswordChanged, map[string]any{"username": "bob"}); err == nil {
+		t.Fatalf("expected missing field error")
+	}
+}
+
+func TestRenderUnknownKind(t *testing.T) {
+	if _, _, err := Render("nope", nil); err != ErrUnknownKind {
+		t.Fatalf("expected ErrUnknownKind, got %v", err)
+	}
+}
+
+func TestEveryKindHasTemplate(t *testing.T) {
+	for _, kind := range Kinds {
+		if _, ok := templates[kind]; !ok {
+			t.Fatalf("missing template for %s", kind)
+		}
+	}
+}
+
+func TestMandatoryKinds(t *testing.T) {
+	if !Mandatory(KindNewDeviceLogin) || !Mandatory(KindPasswordChanged) {
+		t.Fatalf("expected security notices to be mandatory")
+	}
+	if Mandatory(KindTransferReceived) {
+		t.Fatalf("expected transfer notices to be optional")
+	}
+}
+
+func TestRenderAlertTriggered(t *testing.T) {
+	subject, body, err := Render(KindAlertTriggered, map[string]any{
+		"username":       "bob",
+		"rule_kind":      "low_balance",
+		"currency":       "USD",
+		"threshold":      "100.00",
+		"amount":         "20.00",
+		"balance":        "95.00",
+		"suppressed":     float64(2),
+		"transaction_id": "tx-1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if subject != "Your USD balance is below 100.00" {
+		t.Fatalf("unexpected subject: %q", subject)
+	}
+	if !strings.Contains(body, "dropped to 95.00") || !strings.Contains(body, "2 more alerts") {
+		t.Fatalf("unexpected body: %q", body)
+	}
+}
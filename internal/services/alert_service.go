@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"banking/internal/events"
+	"banking/internal/money"
+	"banking/internal/store"
+)
+
+const (
+	AlertLowBalance       = "low_balance"
+	AlertLargeTransaction = "large_transaction"
+	AlertIncomingFunds    = "incoming_funds"
+)
+
+var AlertKinds = []string{AlertLowBalance, AlertLargeTransaction, AlertIncomingFunds}
+
+func IsAlertKind(value string) bool {
+	for _, kind := range AlertKinds {
+		if kind == value {
+			return true
+		}
+	}
+	return false
+}
+
+type BalanceMovement struct {
+	UserID    string
+	AccountID string
+	Currency  string
+	Before    int64
+	After     int64
+	Internal  bool
+}
+
+type AlertEvaluator interface {
+	Evaluate(ctx context.Context, tx store.DB, transactionID string, movements []BalanceMovement) error
+}
+
+type AlertRuleStore interface {
+	ActiveForUsers(ctx context.Context, tx store.Selecter, userIDs []string) ([]store.AlertRule, error)
+	Claim(ctx context.Context, tx store.Getter, ruleID string, cooldown time.Duration) (store.AlertClaim, error)
+}
+
+// A rule fires at most once per transaction and once per cooldown; triggers
+// inside the cooldown are only counted.
+type AlertService struct {
+	ruleStore   AlertRuleStore
+	outboxStore OutboxStore
+	cooldown    time.Duration
+}
+
+func NewAlertService(ruleStore AlertRuleStore, outboxStore OutboxStore, cooldown time.Duration) *AlertService {
+	return &AlertService{ruleStore: ruleStore, outboxStore: outboxStore, cooldown: cooldown}
+}
+
+func (s *AlertService) Evaluate(ctx context.Context, tx store.DB, transactionID string, movements []BalanceMovement) error {
+	var userIDs []string
+	seen := make(map[string]bool)
+	for _, movement := range movements {
+		if movement.UserID != "" && !seen[movement.UserID] {
+			seen[movement.UserID] = true
+			userIDs = append(userIDs, movement.UserID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+	rules, err := s.ruleStore.ActiveForUsers(ctx, tx, userIDs)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		for _, movement := range movements {
+			if movement.UserID != rule.UserID || movement.Currency != rule.Currency || !alertMatches(rule, movement) {
+				continue
+			}
+			claim, err := s.ruleStore.Claim(ctx, tx, rule.ID, s.cooldown)
+			if err != nil {
+				return err
+			}
+			if claim.Ready {
+				if err := s.outboxStore.Append(ctx, tx, alertEvent(rule, movement, transactionID, claim.Suppressed)); err != nil {
+					return err
+				}
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// Low balance fires when the balance crosses below the threshold, not on
+// every debit below it.
+func alertMatches(rule store.AlertRule, movement BalanceMovement) bool {
+	delta := movement.After - movement.Before
+	switch rule.Kind {
+	case AlertLowBalance:
+		return movement.Before >= rule.Threshold && movement.After < rule.Threshold
+	case AlertLargeTransaction:
+		return delta < 0 && -delta >= rule.Threshold
+	case AlertIncomingFunds:
+		return !movement.Internal && delta > 0 && delta >= rule.Threshold
+	}
+	return false
+}
+
+func alertEvent(rule store.AlertRule, movement BalanceMovement, transactionID string, suppressed int) store.DomainEventInput {
+	amount := movement.After - movement.Before
+	if amount < 0 {
+		amount = -amount
+	}
+	return events.New(events.AlertTriggered, events.AggregateAlertRule, rule.ID, &rule.UserID, map[string]any{
+		"rule_id":        rule.ID,
+		"rule_kind":      rule.Kind,
+		"account_id":     movement.AccountID,
+		"currency":       rule.Currency,
+		"threshold":      money.FormatMinor(rule.Threshold),
+		"amount":         money.FormatMinor(amount),
+		"balance":        money.FormatMinor(movement.After),
+		"transaction_id": transactionID,
+		"suppressed":     suppressed,
+	})
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"banking/internal/events"
+	"banking/internal/store"
+)
+
+type stubAlertRuleStore struct {
+	rules   []store.AlertRule
+	claims  map[string]store.AlertClaim
+	claimed []string
+}
+
+func (s *stubAlertRuleStore) ActiveForUsers(_ context.Context, _ store.Selecter, userIDs []string) ([]store.AlertRule, error) {
+	var rules []store.AlertRule
+	for _, rule := range s.rules {
+		for _, userID := range userIDs {
+			if rule.UserID == userID {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules, nil
+}
+
+func (s *stubAlertRuleStore) Claim(_ context.Context, _ store.Getter, ruleID string, _ time.Duration) (store.AlertClaim, error) {
+	s.claimed = append(s.claimed, ruleID)
+	if claim, ok := s.claims[ruleID]; ok {
+		return claim, nil
+	}
+	return store.AlertClaim{Ready: true}, nil
+}
+
+func TestAlertServiceFiresMatchingRules(t *testing.T) {
+	rules := &stubAlertRuleStore{rules: []store.AlertRule{
+		{ID: "low", UserID: "user-1", Kind: AlertLowBalance, Currency: "USD", Threshold: 10000},
+		{ID: "large", UserID: "user-1", Kind: AlertLargeTransaction, Currency: "USD", Threshold: 100000},
+		{ID: "eur", UserID: "user-1", Kind: AlertLowBalance, Currency: "EUR", Threshold: 10000},
+		{ID: "incoming", UserID: "user-2", Kind: AlertIncomingFunds, Currency: "USD", Threshold: 0},
+	}}
+	outbox := &stubOutboxStore{}
+	err := NewAlertService(rules, outbox, time.Minute).Evaluate(context.Background(), nil, "tx-1", []BalanceMovement{
+		{UserID: "user-1", AccountID: "acc-1", Currency: "USD", Before: 15000, After: 5000},
+		{UserID: "user-2", AccountID: "acc-2", Currency: "USD", Before: 0, After: 10000},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules.claimed) != 2 || rules.claimed[0] != "low" || rules.claimed[1] != "incoming" {
+		t.Fatalf("unexpected claims: %v", rules.claimed)
+	}
+	if len(outbox.events) != 2 || outbox.events[0].Type != events.AlertTriggered || *outbox.events[0].UserID != "user-1" {
+		t.Fatalf("unexpected events: %#v", outbox.events)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(outbox.events[0].Payload), &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if payload["rule_kind"] != AlertLowBalance || payload["balance"] != "50.00" || payload["amount"] != "100.00" || payload["transaction_id"] != "tx-1" {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+}
+
+func TestAlertServiceHoldsBackDuringCooldown(t *testing.T) {
+	rules := &stubAlertRuleStore{
+		rules:  []store.AlertRule{{ID: "large", UserID: "user-1", Kind: AlertLargeTransaction, Currency: "USD", Threshold: 100}},
+		claims: map[string]store.AlertClaim{"large": {Ready: false}},
+	}
+	outbox := &stubOutboxStore{}
+	err := NewAlertService(rules, outbox, time.Minute).Evaluate(context.Background(), nil, "tx-1", []BalanceMovement{
+		{UserID: "user-1", AccountID: "acc-1", Currency: "USD", Before: 1000, After: 500},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules.claimed) != 1 || len(outbox.events) != 0 {
+		t.Fatalf("expected a suppressed trigger, got %v %#v", rules.claimed, outbox.events)
+	}
+}
+
+func TestAlertMatches(t *testing.T) {
+	cases := []struct {
+		name     string
+		rule     store.AlertRule
+		movement BalanceMovement
+		want     bool
+	}{
+		{"low balance crossing", store.AlertRule{Kind: AlertLowBalance, Threshold: 100}, BalanceMovement{Before: 100, After: 99}, true},
+		{"low balance already below", store.AlertRule{Kind: AlertLowBalance, Threshold: 100}, BalanceMovement{Before: 90, After: 80}, false},
+		{"large debit", store.AlertRule{Kind: AlertLargeTransaction, Threshold: 100}, BalanceMovement{Before: 500, After: 400}, true},
+		{"small debit", store.AlertRule{Kind: AlertLargeTransaction, Threshold: 100}, BalanceMovement{Before: 500, After: 450}, false},
+		{"large credit is not a debit", store.AlertRule{Kind: AlertLargeTransaction, Threshold: 100}, BalanceMovement{Before: 0, After: 500}, false},
+		{"incoming funds", store.AlertRule{Kind: AlertIncomingFunds, Threshold: 0}, BalanceMovement{Before: 0, After: 1}, true},
+		{"exchange is not incoming", store.AlertRule{Kind: AlertIncomingFunds, Threshold: 0}, BalanceMovement{Before: 0, After: 1, Internal: true}, false},
+	}
+	for _, tc := range cases {
+		if got := alertMatches(tc.rule, tc.movement); got != tc.want {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
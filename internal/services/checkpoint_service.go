@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"banking/internal/db"
+	"banking/internal/store"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type BalanceStore interface {
+	ListCheckpointCandidates(ctx context.Context, cutoff time.Time, minEntries int64, limit int) ([]string, error)
+	LatestCheckpoint(ctx context.Context, tx store.Getter, accountID string) (store.BalanceCheckpoint, error)
+	LedgerDeltaSince(ctx context.Context, tx store.Getter, accountID string, afterSeq int64, cutoff time.Time) (store.LedgerDelta, error)
+	CreateCheckpoint(ctx context.Context, tx store.Execer, input store.BalanceCheckpointInput) error
+}
+
+type CheckpointService struct {
+	txRunner     db.TxRunner
+	accountStore AccountStore
+	balanceStore BalanceStore
+	lag          time.Duration
+	minEntries   int64
+	batchSize    int
+}
+
+func NewCheckpointService(txRunner db.TxRunner, accountStore AccountStore, balanceStore BalanceStore, lag time.Duration, minEntries int64) *CheckpointService {
+	if minEntries <= 0 {
+		minEntries = 1
+	}
+	return &CheckpointService{
+		txRunner:     txRunner,
+		accountStore: accountStore,
+		balanceStore: balanceStore,
+		lag:          lag,
+		minEntries:   minEntries,
+		batchSize:    100,
+	}
+}
+
+func (s *CheckpointService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		created, err := s.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("balance checkpoint run failed: %v", err)
+		} else if created > 0 {
+			log.Printf("created %d balance checkpoints", created)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *CheckpointService) RunOnce(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-s.lag)
+	total := 0
+	for {
+		accountIDs, err := s.balanceStore.ListCheckpointCandidates(ctx, cutoff, s.minEntries, s.batchSize)
+		if err != nil {
+			return total, err
+		}
+		created := 0
+		for _, accountID := range accountIDs {
+			ok, err := s.CheckpointAccount(ctx, accountID, cutoff)
+			if err != nil {
+				return total, err
+			}
+			if ok {
+				created++
+			}
+		}
+		total += created
+		if len(accountIDs) < s.batchSize || created == 0 {
+			return total, nil
+		}
+	}
+}
+
+func (s *CheckpointService) CheckpointAccount(ctx context.Context, accountID string, cutoff time.Time) (bool, error) {
+	created := false
+	err := s.txRunner.WithTx(ctx, func(tx *sqlx.Tx) error {
+		created = false
+		if _, err := s.accountStore.GetForUpdate(ctx, tx, accountID); err != nil {
+			return err
+		}
+		latest, err := s.balanceStore.LatestCheckpoint(ctx, tx, accountID)
+		if err != nil {
+			return err
+		}
+		delta, err := s.balanceStore.LedgerDeltaSince(ctx, tx, accountID, latest.EntrySeq, cutoff)
+		if err != nil {
+			return err
+		}
+		if delta.EntryCount == 0 {
+			return nil
+		}
+		lastEntryAt := latest.LastEntryAt
+		if delta.LastEntryAt != nil && (lastEntryAt == nil || delta.LastEntryAt.After(*lastEntryAt)) {
+			lastEntryAt = delta.LastEntryAt
+		}
+		if err := s.balanceStore.CreateCheckpoint(ctx, tx, store.BalanceCheckpointInput{
+			ID:          uuid.NewString(),
+			AccountID:   accountID,
+			EntrySeq:    delta.LastSeq,
+			LastEntryID: delta.LastEntryID,
+			LastEntryAt: lastEntryAt,
+			Balance:     latest.Balance + delta.Amount,
+			EntryCount:  latest.EntryCount + delta.EntryCount,
+		}); err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return created, nil
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"banking/internal/store"
+)
+
+type stubBalanceStore struct {
+	candidatesFn func(ctx context.Context, cutoff time.Time, minEntries int64, limit int) ([]string, error)
+	latestFn     func(ctx context.Context, tx store.Getter, accountID string) (store.BalanceCheckpoint, error)
+	deltaFn      func(ctx context.Context, tx store.Getter, accountID string, afterSeq int64, cutoff time.Time) (store.LedgerDelta, error)
+	createFn     func(ctx context.Context, tx store.Execer, input store.BalanceCheckpointInput) error
+}
+
+func (s stubBalanceStore) ListCheckpointCandidates(ctx context.Context, cutoff time.Time, minEntries int64, limit int) ([]string, error) {
+	if s.candidatesFn == nil {
+		return nil, nil
+	}
+	return s.candidatesFn(ctx, cutoff, minEntries, limit)
+}
+
+func (s stubBalanceStore) LatestCheckpoint(ctx context.Context, tx store.Getter, accountID string) (store.BalanceCheckpoint, error) {
+	if s.latestFn == nil {
+		return store.BalanceCheckpoint{AccountID: accountID}, nil
+	}
+	return s.latestFn(ctx, tx, accountID)
+}
+
+func (s stubBalanceStore) LedgerDeltaSince(ctx context.Context, tx store.Getter, accountID string, afterSeq int64, cutoff time.Time) (store.LedgerDelta, error) {
+	if s.deltaFn == nil {
+		return store.LedgerDelta{}, nil
+	}
+	return s.deltaFn(ctx, tx, accountID, afterSeq, cutoff)
+}
+
+func (s stubBalanceStore) CreateCheckpoint(ctx context.Context, tx store.Execer, input store.BalanceCheckpointInput) error {
+	if s.createFn == nil {
+		return nil
+	}
+	return s.createFn(ctx, tx, input)
+}
+
+func TestCheckpointAccountExtendsLatestCheckpoint(t *testing.T) {
+	previousAt := time.Now().Add(-time.Hour)
+	deltaAt := time.Now().Add(-5 * time.Minute)
+	entryID := "entry-90"
+	locked := false
+	var created store.BalanceCheckpointInput
+	service := NewCheckpointService(fakeTxRunner{}, stubAccountStore{
+		getForUpdateFn: func(_ context.Context, _ store.Getter, accountID string) (store.Account, error) {
+			locked = accountID == "acc-1"
+			return store.Account{ID: accountID}, nil
+		},
+	}, stubBalanceStore{
+		latestFn: func(_ context.Context, _ store.Getter, accountID string) (store.BalanceCheckpoint, error) {
+			if !locked {
+				t.Fatalf("expected account to be locked before reading checkpoint")
+			}
+			return store.BalanceCheckpoint{AccountID: accountID, EntrySeq: 40, Balance: 10000, EntryCount: 12, LastEntryAt: &previousAt}, nil
+		},
+		deltaFn: func(_ context.Context, _ store.Getter, _ string, afterSeq int64, _ time.Time) (store.LedgerDelta, error) {
+			if afterSeq != 40 {
+				t.Fatalf("expected delta after seq 40, got %d", afterSeq)
+			}
+			return store.LedgerDelta{Amount: -2500, EntryCount: 3, LastSeq: 90, LastEntryID: &entryID, LastEntryAt: &deltaAt}, nil
+		},
+		createFn: func(_ context.Context, _ store.Execer, input store.BalanceCheckpointInput) error {
+			created = input
+			return nil
+		},
+	}, time.Minute, 1)
+
+	ok, err := service.CheckpointAccount(context.Background(), "acc-1", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Fatalf("expected checkpoint to be created")
+	}
+	if created.Balance != 7500 || created.EntryCount != 15 || created.EntrySeq != 90 {
+		t.Fatalf("unexpected checkpoint: %#v", created)
+	}
+	if created.LastEntryID == nil || *created.LastEntryID != "entry-90" || created.LastEntryAt == nil || !created.LastEntryAt.Equal(deltaAt) {
+		t.Fatalf("unexpected last entry: %#v", created)
+	}
+}
+
+func TestCheckpointAccountSkipsWithoutNewEntries(t *testing.T) {
+	service := NewCheckpointService(fakeTxRunner{}, stubAccountStore{
+		getForUpdateFn: func(context.Context, store.Getter, string) (store.Account, error) {
+			return store.Account{}, nil
+		},
+	}, stubBalanceStore{
+		createFn: func(context.Context, store.Execer, store.BalanceCheckpointInput) error {
+			t.Fatalf("unexpected checkpoint")
+			return nil
+		},
+	}, time.Minute, 1)
+
+	ok, err := service.CheckpointAccount(context.Background(), "acc-1", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Fatalf("expected no checkpoint")
+	}
+}
+
+func TestCheckpointRunOnceAppliesLag(t *testing.T) {
+	created := 0
+	service := NewCheckpointService(fakeTxRunner{}, stubAccountStore{
+		getForUpdateFn: func(context.Context, store.Getter, string) (store.Account, error) {
+			return store.Account{}, nil
+		},
+	}, stubBalanceStore{
+		candidatesFn: func(_ context.Context, cutoff time.Time, minEntries int64, _ int) ([]string, error) {
+			if time.Since(cutoff) < 10*time.Minute {
+				t.Fatalf("expected cutoff to honour lag, got %v", cutoff)
+			}
+			if minEntries != 250 {
+				t.Fatalf("unexpected min entries: %d", minEntries)
+			}
+			return []string{"acc-1", "acc-2"}, nil
+		},
+		deltaFn: func(context.Context, store.Getter, string, int64, time.Time) (store.LedgerDelta, error) {
+			return store.LedgerDelta{Amount: 100, EntryCount: 1, LastSeq: 5}, nil
+		},
+		createFn: func(context.Context, store.Execer, store.BalanceCheckpointInput) error {
+			created++
+			return nil
+		},
+	}, 10*time.Minute, 250)
+
+	total, err := service.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total != 2 || created != 2 {
+		t.Fatalf("expected 2 checkpoints, got total=%d created=%d", total, created)
+	}
+}
+
+func TestCheckpointRunOnceStopsOnError(t *testing.T) {
+	service := NewCheckpointService(fakeTxRunner{}, stubAccountStore{
+		getForUpdateFn: func(context.Context, store.Getter, string) (store.Account, error) {
+			return store.Account{}, errors.New("locked")
+		},
+	}, stubBalanceStore{
+		candidatesFn: func(context.Context, time.Time, int64, int) ([]string, error) {
+			return []string{"acc-1"}, nil
+		},
+	}, time.Minute, 1)
+
+	if _, err := service.RunOnce(context.Background()); err == nil {
+		t.Fatalf("expected error")
+	}
+}
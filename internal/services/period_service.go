@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"banking/internal/db"
+	"banking/internal/events"
+	"banking/internal/store"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrPeriodClosed        = errors.New("posting date falls in a closed period")
+	ErrPeriodNotEnded      = errors.New("period has not ended yet")
+	ErrPeriodOutOfOrder    = errors.New("periods must be closed and reopened in order")
+	ErrPeriodAlreadyClosed = errors.New("period already closed")
+	ErrPeriodNotFound      = errors.New("period not found")
+	ErrPeriodNotClosed     = errors.New("period is not closed")
+)
+
+const (
+	PeriodClosed   = "closed"
+	PeriodReopened = "reopened"
+)
+
+type AccountingPeriodStore interface {
+	List(ctx context.Context) ([]store.AccountingPeriod, error)
+	GetForUpdate(ctx context.Context, tx store.Getter, periodID string) (store.AccountingPeriod, error)
+	GetByEndForUpdate(ctx context.Context, tx store.Getter, periodEnd time.Time) (store.AccountingPeriod, error)
+	Latest(ctx context.Context, tx store.Getter) (store.AccountingPeriod, error)
+	LockForClose(ctx context.Context, tx store.Execer) error
+	ReopenedBefore(ctx context.Context, tx store.Getter, periodEnd time.Time) (bool, error)
+	ClosedAfter(ctx context.Context, tx store.Getter, periodEnd time.Time) (bool, error)
+	Create(ctx context.Context, tx store.Execer, input store.AccountingPeriodInput) error
+	MarkClosed(ctx context.Context, tx store.Execer, periodID, actorID string) error
+	MarkReopened(ctx context.Context, tx store.Execer, periodID, actorID, reason string) (int64, error)
+	SnapshotBalances(ctx context.Context, tx store.Execer, periodID string, asOf time.Time) (int64, error)
+	Balances(ctx context.Context, periodID string) ([]store.PeriodBalance, error)
+}
+
+type PeriodService struct {
+	txRunner db.TxRunner
+	periods  AccountingPeriodStore
+	audit    AuditStore
+	outbox   OutboxStore
+}
+
+func NewPeriodService(txRunner db.TxRunner, periods AccountingPeriodStore, audit AuditStore, outbox OutboxStore) *PeriodService {
+	return &PeriodService{
+		txRunner: txRunner,
+		periods:  periods,
+		audit:    audit,
+		outbox:   outbox,
+	}
+}
+
+func (s *PeriodService) List(ctx context.Context) ([]store.AccountingPeriod, error) {
+	return s.periods.List(ctx)
+}
+
+func (s *PeriodService) Balances(ctx context.Context, periodID string) ([]store.PeriodBalance, error) {
+	return s.periods.Balances(ctx, periodID)
+}
+
+func (s *PeriodService) Close(ctx context.Context, actorID string, periodEnd time.Time) (store.AccountingPeriod, error) {
+	periodEnd = periodEnd.UTC()
+	if !periodEnd.Before(time.Now()) {
+		return store.AccountingPeriod{}, ErrPeriodNotEnded
+	}
+	var period store.AccountingPeriod
+	err := s.txRunner.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := s.periods.LockForClose(ctx, tx); err != nil {
+			return err
+		}
+		reopened, err := s.periods.ReopenedBefore(ctx, tx, periodEnd)
+		if err != nil {
+			return err
+		}
+		if reopened {
+			return ErrPeriodOutOfOrder
+		}
+		existing, err := s.periods.GetByEndForUpdate(ctx, tx, periodEnd)
+		switch {
+		case err == nil:
+			if existing.Status == PeriodClosed {
+				return ErrPeriodAlreadyClosed
+			}
+			if err := s.periods.MarkClosed(ctx, tx, existing.ID, actorID); err != nil {
+				return err
+			}
+			period = existing
+		case err == sql.ErrNoRows:
+			latest, err := s.periods.Latest(ctx, tx)
+			if err != nil {
+				return err
+			}
+			period = store.AccountingPeriod{ID: uuid.NewString(), PeriodEnd: periodEnd}
+			if latest.ID != "" {
+				if !periodEnd.After(latest.PeriodEnd) {
+					return ErrPeriodOutOfOrder
+				}
+				start := latest.PeriodEnd.Add(time.Microsecond)
+				period.PeriodStart = &start
+			}
+			if err := s.periods.Create(ctx, tx, store.AccountingPeriodInput{
+				ID:          period.ID,
+				PeriodStart: period.PeriodStart,
+				PeriodEnd:   period.PeriodEnd,
+				ClosedBy:    actorID,
+			}); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+		accounts, err := s.periods.SnapshotBalances(ctx, tx, period.ID, periodEnd)
+		if err != nil {
+			return err
+		}
+		period.Status = PeriodClosed
+		period.ClosedBy = actorID
+		period.ClosedAt = time.Now().UTC()
+		payload := map[string]any{
+			"period_start": period.PeriodStart,
+			"period_end":   period.PeriodEnd,
+			"accounts":     accounts,
+		}
+		data, _ := json.Marshal(payload)
+		if err := s.audit.Log(ctx, tx, actorID, "close_period", "accounting_period", period.ID, string(data)); err != nil {
+			return err
+		}
+		return s.outbox.Append(ctx, tx, events.New(events.PeriodClosed, events.AggregatePeriod, period.ID, &actorID, payload))
+	})
+	if err != nil {
+		return store.AccountingPeriod{}, err
+	}
+	return period, nil
+}
+
+func (s *PeriodService) Reopen(ctx context.Context, actorID, periodID, reason string) (store.AccountingPeriod, error) {
+	var period store.AccountingPeriod
+	err := s.txRunner.WithTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		period, err = s.periods.GetForUpdate(ctx, tx, periodID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrPeriodNotFound
+			}
+			return err
+		}
+		if period.Status != PeriodClosed {
+			return ErrPeriodNotClosed
+		}
+		later, err := s.periods.ClosedAfter(ctx, tx, period.PeriodEnd)
+		if err != nil {
+			return err
+		}
+		if later {
+			return ErrPeriodOutOfOrder
+		}
+		updated, err := s.periods.MarkReopened(ctx, tx, periodID, actorID, reason)
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return ErrPeriodNotClosed
+		}
+		now := time.Now().UTC()
+		period.Status = PeriodReopened
+		period.ReopenedBy = &actorID
+		period.ReopenedAt = &now
+		period.ReopenReason = &reason
+		payload := map[string]any{
+			"period_start": period.PeriodStart,
+			"period_end":   period.PeriodEnd,
+			"reason":       reason,
+		}
+		data, _ := json.Marshal(payload)
+		if err := s.audit.Log(ctx, tx, actorID, "reopen_period", "accounting_period", periodID, string(data)); err != nil {
+			return err
+		}
+		return s.outbox.Append(ctx, tx, events.New(events.PeriodReopened, events.AggregatePeriod, periodID, &actorID, payload))
+	})
+	if err != nil {
+		return store.AccountingPeriod{}, err
+	}
+	return period, nil
+}
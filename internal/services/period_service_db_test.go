@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"banking/internal/db"
+	"banking/internal/store"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+func TestClosePeriodWithConcurrentPostings(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	database, err := sqlx.Connect("postgres", databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	userID := uuid.NewString()
+	fromID := uuid.NewString()
+	toID := uuid.NewString()
+	seed(t, database, `INSERT INTO users (id, username, email, password_hash) VALUES ($1, $2, $3, 'x')`,
+		userID, "period_"+userID[:8], userID[:8]+"@period.local")
+	seed(t, database, `INSERT INTO accounts (id, user_id, currency, balance) VALUES ($1, $2, 'USD', 1000000), ($3, $2, 'USD', 0)`,
+		fromID, userID, toID)
+
+	txRunner := db.NewTxRunner(database)
+	periods := store.NewPeriodStore(database)
+	audit := store.NewAuditStore(database)
+	outbox := store.NewOutboxStore(database)
+	transactions := NewTransactionService(txRunner, store.NewAccountStore(database), store.NewLedgerStore(database),
+		store.NewTransactionStore(database), store.NewExchangeStore(database), store.NewExchangeQuoteStore(database),
+		periods, audit, outbox, NewAlertService(store.NewAlertRuleStore(database), outbox, time.Minute), QuoteTTLs{Default: time.Minute})
+	service := NewPeriodService(txRunner, periods, audit, outbox)
+
+	stop := make(chan struct{})
+	var posted atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, err := transactions.Transfer(ctx, TransferRequest{UserID: userID, FromAccountID: fromID, ToAccountID: toID, AmountMinor: 1}); err == nil {
+					posted.Add(1)
+				}
+			}
+		}()
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	periodEnd := time.Now().UTC().Truncate(time.Microsecond)
+	time.Sleep(time.Millisecond)
+	period, err := service.Close(ctx, userID, periodEnd)
+	time.Sleep(200 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("failed to close period: %v", err)
+	}
+	if posted.Load() == 0 {
+		t.Fatal("expected postings while the period closed")
+	}
+
+	for _, accountID := range []string{fromID, toID} {
+		var ledger, snapshot int64
+		if err := database.GetContext(ctx, &ledger, `
+			SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE account_id = $1 AND created_at <= $2
+		`, accountID, periodEnd); err != nil {
+			t.Fatalf("failed to sum ledger: %v", err)
+		}
+		if err := database.GetContext(ctx, &snapshot, `
+			SELECT COALESCE(SUM(balance), 0) FROM period_balances WHERE period_id = $1 AND account_id = $2
+		`, period.ID, accountID); err != nil {
+			t.Fatalf("failed to load snapshot: %v", err)
+		}
+		if ledger != snapshot {
+			t.Fatalf("account %s: closed snapshot %d, ledger up to period end %d", accountID, snapshot, ledger)
+		}
+	}
+}
+
+func seed(t *testing.T, database *sqlx.DB, query string, args ...any) {
+	t.Helper()
+	if _, err := database.ExecContext(context.Background(), query, args...); err != nil {
+		t.Fatalf("seed failed: %v", err)
+	}
+}
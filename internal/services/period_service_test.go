@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"banking/internal/events"
+	"banking/internal/store"
+)
+
+type stubAccountingPeriodStore struct {
+	listFn           func(ctx context.Context) ([]store.AccountingPeriod, error)
+	getForUpdateFn   func(ctx context.Context, tx store.Getter, periodID string) (store.AccountingPeriod, error)
+	getByEndFn       func(ctx context.Context, tx store.Getter, periodEnd time.Time) (store.AccountingPeriod, error)
+	latestFn         func(ctx context.Context, tx store.Getter) (store.AccountingPeriod, error)
+	lockForCloseFn   func(ctx context.Context, tx store.Execer) error
+	reopenedBeforeFn func(ctx context.Context, tx store.Getter, periodEnd time.Time) (bool, error)
+	closedAfterFn    func(ctx context.Context, tx store.Getter, periodEnd time.Time) (bool, error)
+	createFn         func(ctx context.Context, tx store.Execer, input store.AccountingPeriodInput) error
+	markClosedFn     func(ctx context.Context, tx store.Execer, periodID, actorID string) error
+	markReopenedFn   func(ctx context.Context, tx store.Execer, periodID, actorID, reason string) (int64, error)
+	snapshotFn       func(ctx context.Context, tx store.Execer, periodID string, asOf time.Time) (int64, error)
+	balancesFn       func(ctx context.Context, periodID string) ([]store.PeriodBalance, error)
+}
+
+func (s stubAccountingPeriodStore) List(ctx context.Context) ([]store.AccountingPeriod, error) {
+	if s.listFn == nil {
+		return nil, nil
+	}
+	return s.listFn(ctx)
+}
+
+func (s stubAccountingPeriodStore) GetForUpdate(ctx context.Context, tx store.Getter, periodID string) (store.AccountingPeriod, error) {
+	if s.getForUpdateFn == nil {
+		return store.AccountingPeriod{}, sql.ErrNoRows
+	}
+	return s.getForUpdateFn(ctx, tx, periodID)
+}
+
+func (s stubAccountingPeriodStore) GetByEndForUpdate(ctx context.Context, tx store.Getter, periodEnd time.Time) (store.AccountingPeriod, error) {
+	if s.getByEndFn == nil {
+		return store.AccountingPeriod{}, sql.ErrNoRows
+	}
+	return s.getByEndFn(ctx, tx, periodEnd)
+}
+
+func (s stubAccountingPeriodStore) Latest(ctx context.Context, tx store.Getter) (store.AccountingPeriod, error) {
+	if s.latestFn == nil {
+		return store.AccountingPeriod{}, nil
+	}
+	return s.latestFn(ctx, tx)
+}
+
+func (s stubAccountingPeriodStore) LockForClose(ctx context.Context, tx store.Execer) error {
+	if s.lockForCloseFn == nil {
+		return nil
+	}
+	return s.lockForCloseFn(ctx, tx)
+}
+
+func (s stubAccountingPeriodStore) ReopenedBefore(ctx context.Context, tx store.Getter, periodEnd time.Time) (bool, error) {
+	if s.reopenedBeforeFn == nil {
+		return false, nil
+	}
+	return s.reopenedBeforeFn(ctx, tx, periodEnd)
+}
+
+func (s stubAccountingPeriodStore) ClosedAfter(ctx context.Context, tx store.Getter, periodEnd time.Time) (bool, error) {
+	if s.closedAfterFn == nil {
+		return false, nil
+	}
+	return s.closedAfterFn(ctx, tx, periodEnd)
+}
+
+func (s stubAccountingPeriodStore) Create(ctx context.Context, tx store.Execer, input store.AccountingPeriodInput) error {
+	if s.createFn == nil {
+		return nil
+	}
+	return s.createFn(ctx, tx, input)
+}
+
+func (s stubAccountingPeriodStore) MarkClosed(ctx context.Context, tx store.Execer, periodID, actorID string) error {
+	if s.markClosedFn == nil {
+		return nil
+	}
+	return s.markClosedFn(ctx, tx, periodID, actorID)
+}
+
+func (s stubAccountingPeriodStore) MarkReopened(ctx context.Context, tx store.Execer, periodID, actorID, reason string) (int64, error) {
+	if s.markReopenedFn == nil {
+		return 1, nil
+	}
+	return s.markReopenedFn(ctx, tx, periodID, actorID, reason)
+}
+
+func (s stubAccountingPeriodStore) SnapshotBalances(ctx context.Context, tx store.Execer, periodID string, asOf time.Time) (int64, error) {
+	if s.snapshotFn == nil {
+		return 0, nil
+	}
+	return s.snapshotFn(ctx, tx, periodID, asOf)
+}
+
+func (s stubAccountingPeriodStore) Balances(ctx context.Context, periodID string) ([]store.PeriodBalance, error) {
+	if s.balancesFn == nil {
+		return nil, nil
+	}
+	return s.balancesFn(ctx, periodID)
+}
+
+func TestClosePeriodFollowsLatest(t *testing.T) {
+	previousEnd := time.Date(2025, 2, 28, 23, 59, 59, 999999000, time.UTC)
+	periodEnd := time.Date(2025, 3, 31, 23, 59, 59, 999999000, time.UTC)
+	var created store.AccountingPeriodInput
+	snapshotAt := time.Time{}
+	audited := ""
+	outbox := &stubOutboxStore{}
+	locked := false
+	service := NewPeriodService(fakeTxRunner{}, stubAccountingPeriodStore{
+		lockForCloseFn: func(context.Context, store.Execer) error {
+			locked = true
+			return nil
+		},
+		latestFn: func(context.Context, store.Getter) (store.AccountingPeriod, error) {
+			if !locked {
+				t.Fatalf("expected the period lock before reading periods")
+			}
+			return store.AccountingPeriod{ID: "feb", PeriodEnd: previousEnd, Status: PeriodClosed}, nil
+		},
+		createFn: func(_ context.Context, _ store.Execer, input store.AccountingPeriodInput) error {
+			created = input
+			return nil
+		},
+		snapshotFn: func(_ context.Context, _ store.Execer, periodID string, asOf time.Time) (int64, error) {
+			if periodID != created.ID {
+				t.Fatalf("expected snapshot for new period")
+			}
+			snapshotAt = asOf
+			return 12, nil
+		},
+	}, stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, _, action, _, _, _ string) error {
+			audited = action
+			return nil
+		},
+	}, outbox)
+
+	period, err := service.Close(context.Background(), "admin-1", periodEnd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.PeriodStart == nil || !created.PeriodStart.Equal(previousEnd.Add(time.Microsecond)) || !created.PeriodEnd.Equal(periodEnd) {
+		t.Fatalf("unexpected period: %#v", created)
+	}
+	if created.ClosedBy != "admin-1" || period.Status != PeriodClosed {
+		t.Fatalf("unexpected period: %#v", period)
+	}
+	if !snapshotAt.Equal(periodEnd) {
+		t.Fatalf("expected snapshot at period end, got %v", snapshotAt)
+	}
+	if audited != "close_period" {
+		t.Fatalf("expected close to be audited, got %q", audited)
+	}
+	if len(outbox.events) != 1 || outbox.events[0].Type != events.PeriodClosed || outbox.events[0].AggregateID != created.ID {
+		t.Fatalf("unexpected events: %#v", outbox.events)
+	}
+}
+
+func TestClosePeriodRejectsInvalidRequests(t *testing.T) {
+	periodEnd := time.Date(2025, 3, 31, 23, 59, 59, 999999000, time.UTC)
+	service := NewPeriodService(fakeTxRunner{}, stubAccountingPeriodStore{}, stubAuditStore{}, &stubOutboxStore{})
+	if _, err := service.Close(context.Background(), "admin-1", time.Now().Add(time.Hour)); err != ErrPeriodNotEnded {
+		t.Fatalf("expected ErrPeriodNotEnded, got %v", err)
+	}
+
+	service = NewPeriodService(fakeTxRunner{}, stubAccountingPeriodStore{
+		latestFn: func(context.Context, store.Getter) (store.AccountingPeriod, error) {
+			return store.AccountingPeriod{ID: "apr", PeriodEnd: periodEnd.AddDate(0, 1, 0), Status: PeriodClosed}, nil
+		},
+	}, stubAuditStore{}, &stubOutboxStore{})
+	if _, err := service.Close(context.Background(), "admin-1", periodEnd); err != ErrPeriodOutOfOrder {
+		t.Fatalf("expected ErrPeriodOutOfOrder, got %v", err)
+	}
+
+	service = NewPeriodService(fakeTxRunner{}, stubAccountingPeriodStore{
+		reopenedBeforeFn: func(context.Context, store.Getter, time.Time) (bool, error) { return true, nil },
+	}, stubAuditStore{}, &stubOutboxStore{})
+	if _, err := service.Close(context.Background(), "admin-1", periodEnd); err != ErrPeriodOutOfOrder {
+		t.Fatalf("expected ErrPeriodOutOfOrder with an earlier reopened period, got %v", err)
+	}
+
+	service = NewPeriodService(fakeTxRunner{}, stubAccountingPeriodStore{
+		getByEndFn: func(context.Context, store.Getter, time.Time) (store.AccountingPeriod, error) {
+			return store.AccountingPeriod{ID: "mar", PeriodEnd: periodEnd, Status: PeriodClosed}, nil
+		},
+	}, stubAuditStore{}, &stubOutboxStore{})
+	if _, err := service.Close(context.Background(), "admin-1", periodEnd); err != ErrPeriodAlreadyClosed {
+		t.Fatalf("expected ErrPeriodAlreadyClosed, got %v", err)
+	}
+}
+
+func TestClosePeriodRecloseReopened(t *testing.T) {
+	periodEnd := time.Date(2025, 3, 31, 23, 59, 59, 999999000, time.UTC)
+	closed := ""
+	snapshots := 0
+	service := NewPeriodService(fakeTxRunner{}, stubAccountingPeriodStore{
+		getByEndFn: func(context.Context, store.Getter, time.Time) (store.AccountingPeriod, error) {
+			return store.AccountingPeriod{ID: "mar", PeriodEnd: periodEnd, Status: PeriodReopened}, nil
+		},
+		createFn: func(context.Context, store.Execer, store.AccountingPeriodInput) error {
+			t.Fatalf("unexpected new period")
+			return nil
+		},
+		markClosedFn: func(_ context.Context, _ store.Execer, periodID, _ string) error {
+			closed = periodID
+			return nil
+		},
+		snapshotFn: func(context.Context, store.Execer, string, time.Time) (int64, error) {
+			snapshots++
+			return 3, nil
+		},
+	}, stubAuditStore{}, &stubOutboxStore{})
+	period, err := service.Close(context.Background(), "admin-1", periodEnd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if closed != "mar" || snapshots != 1 || period.Status != PeriodClosed {
+		t.Fatalf("expected reopened period to be closed and re-snapshotted: %#v", period)
+	}
+}
+
+func TestReopenPeriod(t *testing.T) {
+	var reason string
+	audited := ""
+	outbox := &stubOutboxStore{}
+	service := NewPeriodService(fakeTxRunner{}, stubAccountingPeriodStore{
+		getForUpdateFn: func(_ context.Context, _ store.Getter, periodID string) (store.AccountingPeriod, error) {
+			return store.AccountingPeriod{ID: periodID, Status: PeriodClosed}, nil
+		},
+		markReopenedFn: func(_ context.Context, _ store.Execer, _, _, value string) (int64, error) {
+			reason = value
+			return 1, nil
+		},
+	}, stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, _, action, _, _, _ string) error {
+			audited = action
+			return nil
+		},
+	}, outbox)
+	period, err := service.Reopen(context.Background(), "super-1", "mar", "late invoice")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if period.Status != PeriodReopened || reason != "late invoice" || audited != "reopen_period" {
+		t.Fatalf("unexpected reopen: %#v", period)
+	}
+	if len(outbox.events) != 1 || outbox.events[0].Type != events.PeriodReopened {
+		t.Fatalf("unexpected events: %#v", outbox.events)
+	}
+}
+
+func TestReopenPeriodRejectsInvalidRequests(t *testing.T) {
+	service := NewPeriodService(fakeTxRunner{}, stubAccountingPeriodStore{}, stubAuditStore{}, &stubOutboxStore{})
+	if _, err := service.Reopen(context.Background(), "super-1", "missing", "x"); err != ErrPeriodNotFound {
+		t.Fatalf("expected ErrPeriodNotFound, got %v", err)
+	}
+
+	service = NewPeriodService(fakeTxRunner{}, stubAccountingPeriodStore{
+		getForUpdateFn: func(_ context.Context, _ store.Getter, periodID string) (store.AccountingPeriod, error) {
+			return store.AccountingPeriod{ID: periodID, Status: PeriodReopened}, nil
+		},
+	}, stubAuditStore{}, &stubOutboxStore{})
+	if _, err := service.Reopen(context.Background(), "super-1", "mar", "x"); err != ErrPeriodNotClosed {
+		t.Fatalf("expected ErrPeriodNotClosed, got %v", err)
+	}
+
+	service = NewPeriodService(fakeTxRunner{}, stubAccountingPeriodStore{
+		getForUpdateFn: func(_ context.Context, _ store.Getter, periodID string) (store.AccountingPeriod, error) {
+			return store.AccountingPeriod{ID: periodID, Status: PeriodClosed}, nil
+		},
+		closedAfterFn: func(context.Context, store.Getter, time.Time) (bool, error) { return true, nil },
+	}, stubAuditStore{}, &stubOutboxStore{})
+	if _, err := service.Reopen(context.Background(), "super-1", "feb", "x"); err != ErrPeriodOutOfOrder {
+		t.Fatalf("expected ErrPeriodOutOfOrder, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"banking/internal/db"
+	"banking/internal/events"
+	"banking/internal/store"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type QuoteLifecycleStore interface {
+	ClaimExpiring(ctx context.Context, tx store.Selecter, warning time.Duration, limit int) ([]store.ExchangeQuote, error)
+	ClaimExpired(ctx context.Context, tx store.Selecter, limit int) ([]store.ExchangeQuote, error)
+}
+
+// QuoteNotifier commits each claim with its event, so every event is emitted
+// once whichever instance runs it.
+type QuoteNotifier struct {
+	txRunner    db.TxRunner
+	quoteStore  QuoteLifecycleStore
+	outboxStore OutboxStore
+	warning     time.Duration
+	batchSize   int
+}
+
+func NewQuoteNotifier(txRunner db.TxRunner, quoteStore QuoteLifecycleStore, outboxStore OutboxStore, warning time.Duration) *QuoteNotifier {
+	return &QuoteNotifier{
+		txRunner:    txRunner,
+		quoteStore:  quoteStore,
+		outboxStore: outboxStore,
+		warning:     warning,
+		batchSize:   100,
+	}
+}
+
+func (n *QuoteNotifier) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := n.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("quote notifier run failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (n *QuoteNotifier) RunOnce(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		claimed := 0
+		err := n.txRunner.WithTx(ctx, func(tx *sqlx.Tx) error {
+			expiring, err := n.quoteStore.ClaimExpiring(ctx, tx, n.warning, n.batchSize)
+			if err != nil {
+				return err
+			}
+			expired, err := n.quoteStore.ClaimExpired(ctx, tx, n.batchSize)
+			if err != nil {
+				return err
+			}
+			for _, quote := range expiring {
+				if err := n.outboxStore.Append(ctx, tx, quoteEvent(events.QuoteExpiring, quote)); err != nil {
+					return err
+				}
+			}
+			for _, quote := range expired {
+				if err := n.outboxStore.Append(ctx, tx, quoteEvent(events.QuoteExpired, quote)); err != nil {
+					return err
+				}
+			}
+			claimed = len(expiring) + len(expired)
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+		total += claimed
+		if claimed < n.batchSize {
+			return total, nil
+		}
+	}
+}
+
+func quoteEvent(eventType string, quote store.ExchangeQuote) store.DomainEventInput {
+	return events.New(eventType, events.AggregateQuote, quote.ID, &quote.UserID, map[string]any{
+		"quote_id":        quote.ID,
+		"from_account_id": quote.FromAccountID,
+		"to_account_id":   quote.ToAccountID,
+		"base_currency":   quote.BaseCurrency,
+		"quote_currency":  quote.QuoteCurrency,
+		"rate":            quote.Rate,
+		"expires_at":      quote.ExpiresAt.UTC(),
+	})
+}
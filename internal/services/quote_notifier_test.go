@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"banking/internal/events"
+	"banking/internal/store"
+)
+
+type stubQuoteLifecycleStore struct {
+	expiringFn func(ctx context.Context, tx store.Selecter, warning time.Duration, limit int) ([]store.ExchangeQuote, error)
+	expiredFn  func(ctx context.Context, tx store.Selecter, limit int) ([]store.ExchangeQuote, error)
+}
+
+func (s stubQuoteLifecycleStore) ClaimExpiring(ctx context.Context, tx store.Selecter, warning time.Duration, limit int) ([]store.ExchangeQuote, error) {
+	if s.expiringFn == nil {
+		return nil, nil
+	}
+	return s.expiringFn(ctx, tx, warning, limit)
+}
+
+func (s stubQuoteLifecycleStore) ClaimExpired(ctx context.Context, tx store.Selecter, limit int) ([]store.ExchangeQuote, error) {
+	if s.expiredFn == nil {
+		return nil, nil
+	}
+	return s.expiredFn(ctx, tx, limit)
+}
+
+func TestQuoteNotifierEmitsLifecycleEvents(t *testing.T) {
+	outbox := &stubOutboxStore{}
+	notifier := NewQuoteNotifier(fakeTxRunner{}, stubQuoteLifecycleStore{
+		expiringFn: func(_ context.Context, _ store.Selecter, warning time.Duration, limit int) ([]store.ExchangeQuote, error) {
+			if warning != 15*time.Second || limit != 100 {
+				t.Fatalf("unexpected args: %s %d", warning, limit)
+			}
+			return []store.ExchangeQuote{{ID: "quote-1", UserID: "user-1", Rate: "0.920000"}}, nil
+		},
+		expiredFn: func(context.Context, store.Selecter, int) ([]store.ExchangeQuote, error) {
+			return []store.ExchangeQuote{{ID: "quote-2", UserID: "user-2"}}, nil
+		},
+	}, outbox, 15*time.Second)
+
+	count, err := notifier.RunOnce(context.Background())
+	if err != nil || count != 2 {
+		t.Fatalf("unexpected result: %d %v", count, err)
+	}
+	if len(outbox.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(outbox.events))
+	}
+	expiring, expired := outbox.events[0], outbox.events[1]
+	if expiring.Type != events.QuoteExpiring || expiring.AggregateID != "quote-1" || *expiring.UserID != "user-1" {
+		t.Fatalf("unexpected expiring event: %#v", expiring)
+	}
+	if expired.Type != events.QuoteExpired || expired.AggregateID != "quote-2" || *expired.UserID != "user-2" {
+		t.Fatalf("unexpected expired event: %#v", expired)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(expiring.Payload), &payload); err != nil || payload["quote_id"] != "quote-1" || payload["rate"] != "0.920000" {
+		t.Fatalf("unexpected payload: %s", expiring.Payload)
+	}
+}
+
+func TestQuoteNotifierReturnsErrors(t *testing.T) {
+	notifier := NewQuoteNotifier(fakeTxRunner{}, stubQuoteLifecycleStore{
+		expiredFn: func(context.Context, store.Selecter, int) ([]store.ExchangeQuote, error) {
+			return nil, errors.New("boom")
+		},
+	}, &stubOutboxStore{}, time.Second)
+	if _, err := notifier.RunOnce(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"sort"
+
+	"banking/internal/store"
+)
+
+const (
+	GLAsset     = "asset"
+	GLLiability = "liability"
+	GLEquity    = "equity"
+	GLIncome    = "income"
+	GLExpense   = "expense"
+)
+
+type TrialBalanceLine struct {
+	Code          string
+	Name          string
+	Type          string
+	OpeningDebit  int64
+	OpeningCredit int64
+	Debits        int64
+	Credits       int64
+	ClosingDebit  int64
+	ClosingCredit int64
+}
+
+type TrialBalance struct {
+	Currency     string
+	Lines        []TrialBalanceLine
+	TotalDebits  int64
+	TotalCredits int64
+	Balanced     bool
+}
+
+type StatementLine struct {
+	Code   string
+	Name   string
+	Type   string
+	Amount int64
+}
+
+type BalanceSheet struct {
+	Currency         string
+	Assets           []StatementLine
+	Liabilities      []StatementLine
+	Equity           []StatementLine
+	RetainedEarnings int64
+	TotalAssets      int64
+	TotalLiabilities int64
+	TotalEquity      int64
+	Balanced         bool
+}
+
+type IncomeStatement struct {
+	Currency     string
+	Income       []StatementLine
+	Expenses     []StatementLine
+	TotalIncome  int64
+	TotalExpense int64
+	NetIncome    int64
+}
+
+func BuildTrialBalances(rows []store.GLBalance) []TrialBalance {
+	byCurrency := map[string]*TrialBalance{}
+	var currencies []string
+	for _, row := range rows {
+		report, ok := byCurrency[row.Currency]
+		if !ok {
+			report = &TrialBalance{Currency: row.Currency}
+			byCurrency[row.Currency] = report
+			currencies = append(currencies, row.Currency)
+		}
+		line := TrialBalanceLine{
+			Code:    row.Code,
+			Name:    row.Name,
+			Type:    row.Type,
+			Debits:  row.Debits,
+			Credits: row.Credits,
+		}
+		line.OpeningDebit, line.OpeningCredit = splitSigned(row.Opening)
+		line.ClosingDebit, line.ClosingCredit = splitSigned(row.Closing)
+		report.Lines = append(report.Lines, line)
+		report.TotalDebits += line.ClosingDebit
+		report.TotalCredits += line.ClosingCredit
+	}
+	sort.Strings(currencies)
+	reports := make([]TrialBalance, 0, len(currencies))
+	for _, currency := range currencies {
+		report := byCurrency[currency]
+		report.Balanced = report.TotalDebits == report.TotalCredits
+		reports = append(reports, *report)
+	}
+	return reports
+}
+
+func BuildBalanceSheets(rows []store.GLBalance) []BalanceSheet {
+	byCurrency := map[string]*BalanceSheet{}
+	var currencies []string
+	for _, row := range rows {
+		sheet, ok := byCurrency[row.Currency]
+		if !ok {
+			sheet = &BalanceSheet{Currency: row.Currency}
+			byCurrency[row.Currency] = sheet
+			currencies = append(currencies, row.Currency)
+		}
+		switch row.Type {
+		case GLAsset:
+			line := StatementLine{Code: row.Code, Name: row.Name, Type: row.Type, Amount: -row.Closing}
+			sheet.Assets = append(sheet.Assets, line)
+			sheet.TotalAssets += line.Amount
+		case GLLiability:
+			line := StatementLine{Code: row.Code, Name: row.Name, Type: row.Type, Amount: row.Closing}
+			sheet.Liabilities = append(sheet.Liabilities, line)
+			sheet.TotalLiabilities += line.Amount
+		case GLEquity:
+			line := StatementLine{Code: row.Code, Name: row.Name, Type: row.Type, Amount: row.Closing}
+			sheet.Equity = append(sheet.Equity, line)
+			sheet.TotalEquity += line.Amount
+		case GLIncome, GLExpense:
+			sheet.RetainedEarnings += row.Closing
+		}
+	}
+	sort.Strings(currencies)
+	sheets := make([]BalanceSheet, 0, len(currencies))
+	for _, currency := range currencies {
+		sheet := byCurrency[currency]
+		sheet.TotalEquity += sheet.RetainedEarnings
+		sheet.Balanced = sheet.TotalAssets == sheet.TotalLiabilities+sheet.TotalEquity
+		sheets = append(sheets, *sheet)
+	}
+	return sheets
+}
+
+func BuildIncomeStatements(rows []store.GLBalance) []IncomeStatement {
+	byCurrency := map[string]*IncomeStatement{}
+	var currencies []string
+	for _, row := range rows {
+		if row.Type != GLIncome && row.Type != GLExpense {
+			continue
+		}
+		statement, ok := byCurrency[row.Currency]
+		if !ok {
+			statement = &IncomeStatement{Currency: row.Currency}
+			byCurrency[row.Currency] = statement
+			currencies = append(currencies, row.Currency)
+		}
+		movement := row.Credits - row.Debits
+		if row.Type == GLIncome {
+			statement.Income = append(statement.Income, StatementLine{Code: row.Code, Name: row.Name, Type: row.Type, Amount: movement})
+			statement.TotalIncome += movement
+		} else {
+			statement.Expenses = append(statement.Expenses, StatementLine{Code: row.Code, Name: row.Name, Type: row.Type, Amount: -movement})
+			statement.TotalExpense -= movement
+		}
+	}
+	sort.Strings(currencies)
+	statements := make([]IncomeStatement, 0, len(currencies))
+	for _, currency := range currencies {
+		statement := byCurrency[currency]
+		statement.NetIncome = statement.TotalIncome - statement.TotalExpense
+		statements = append(statements, *statement)
+	}
+	return statements
+}
+
+func splitSigned(amount int64) (int64, int64) {
+	if amount < 0 {
+		return -amount, 0
+	}
+	return 0, amount
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+
+	"banking/internal/store"
+)
+
+func glFixture() []store.GLBalance {
+	return []store.GLBalance{
+		{Code: "1000", Name: "Settlement and treasury", Type: GLAsset, Currency: "EUR", Opening: -300, Debits: 200, Credits: 0, Closing: -500},
+		{Code: "2000", Name: "Customer deposits", Type: GLLiability, Currency: "EUR", Opening: 300, Debits: 0, Credits: 200, Closing: 500},
+		{Code: "1000", Name: "Settlement and treasury", Type: GLAsset, Currency: "USD", Opening: -1000, Debits: 150, Credits: 50, Closing: -1100},
+		{Code: "2000", Name: "Customer deposits", Type: GLLiability, Currency: "USD", Opening: 1000, Debits: 40, Credits: 100, Closing: 1060},
+		{Code: "4000", Name: "Foreign exchange income", Type: GLIncome, Currency: "USD", Opening: 0, Debits: 0, Credits: 60, Closing: 60},
+		{Code: "5000", Name: "Customer incentives", Type: GLExpense, Currency: "USD", Opening: 0, Debits: 20, Credits: 0, Closing: -20},
+	}
+}
+
+func TestBuildTrialBalances(t *testing.T) {
+	reports := BuildTrialBalances(glFixture())
+	if len(reports) != 2 || reports[0].Currency != "EUR" || reports[1].Currency != "USD" {
+		t.Fatalf("unexpected reports: %#v", reports)
+	}
+	usd := reports[1]
+	if len(usd.Lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d", len(usd.Lines))
+	}
+	if usd.Lines[0].OpeningDebit != 1000 || usd.Lines[0].ClosingDebit != 1100 || usd.Lines[0].ClosingCredit != 0 {
+		t.Fatalf("unexpected asset line: %#v", usd.Lines[0])
+	}
+	if usd.TotalDebits != 1120 || usd.TotalCredits != 1120 || !usd.Balanced {
+		t.Fatalf("unexpected totals: %#v", usd)
+	}
+}
+
+func TestBuildTrialBalancesDetectsImbalance(t *testing.T) {
+	reports := BuildTrialBalances([]store.GLBalance{{Code: "2000", Type: GLLiability, Currency: "USD", Closing: 10}})
+	if len(reports) != 1 || reports[0].Balanced {
+		t.Fatalf("expected unbalanced report: %#v", reports)
+	}
+}
+
+func TestBuildBalanceSheets(t *testing.T) {
+	sheets := BuildBalanceSheets(glFixture())
+	if len(sheets) != 2 {
+		t.Fatalf("unexpected sheets: %#v", sheets)
+	}
+	usd := sheets[1]
+	if usd.TotalAssets != 1100 || usd.TotalLiabilities != 1060 || usd.RetainedEarnings != 40 || usd.TotalEquity != 40 {
+		t.Fatalf("unexpected balance sheet: %#v", usd)
+	}
+	if !usd.Balanced {
+		t.Fatalf("expected balance sheet to balance: %#v", usd)
+	}
+}
+
+func TestBuildIncomeStatements(t *testing.T) {
+	statements := BuildIncomeStatements(glFixture())
+	if len(statements) != 1 || statements[0].Currency != "USD" {
+		t.Fatalf("unexpected statements: %#v", statements)
+	}
+	usd := statements[0]
+	if usd.TotalIncome != 60 || usd.TotalExpense != 20 || usd.NetIncome != 40 {
+		t.Fatalf("unexpected statement: %#v", usd)
+	}
+	if len(usd.Expenses) != 1 || usd.Expenses[0].Amount != 20 {
+		t.Fatalf("unexpected expenses: %#v", usd.Expenses)
+	}
+}
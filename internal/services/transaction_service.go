@@ -2,16 +2,18 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"banking/internal/db"
+	"banking/internal/events"
 	"banking/internal/money"
 	"banking/internal/store"
-	"banking/internal/websocket"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -30,6 +32,12 @@ var (
 	ErrQuoteExpired           = errors.New("quote expired")
 	ErrQuoteConsumed          = errors.New("quote already used")
 	ErrRateMismatch           = errors.New("quoted rate mismatch")
+	ErrInvalidAdjustment      = errors.New("adjustments apply to customer accounts")
+	ErrInvalidEffectiveDate   = errors.New("effective date is in the future")
+	ErrAccountFrozen          = errors.New("account is frozen")
+	ErrTransactionNotFound    = errors.New("transaction not found")
+	ErrNotReversible          = errors.New("only completed transfers, exchanges and adjustments can be reversed")
+	ErrAlreadyReversed        = errors.New("transaction already reversed")
 )
 
 type TransactionService struct {
@@ -39,8 +47,28 @@ type TransactionService struct {
 	txStore       TransactionStore
 	exchangeStore ExchangeStore
 	quoteStore    ExchangeQuoteStore
+	periodStore   PeriodStore
 	auditStore    AuditStore
-	hub           BalanceHub
+	outboxStore   OutboxStore
+	alerts        AlertEvaluator
+	quoteTTLs     QuoteTTLs
+}
+
+type QuoteTTLs struct {
+	Default time.Duration
+	Pairs   map[string]time.Duration
+}
+
+const defaultQuoteTTL = 2 * time.Minute
+
+func (t QuoteTTLs) For(baseCurrency, quoteCurrency string) time.Duration {
+	if ttl := t.Pairs[baseCurrency+"-"+quoteCurrency]; ttl > 0 {
+		return ttl
+	}
+	if t.Default > 0 {
+		return t.Default
+	}
+	return defaultQuoteTTL
 }
 
 type AccountStore interface {
@@ -52,10 +80,12 @@ type AccountStore interface {
 
 type LedgerStore interface {
 	InsertEntries(ctx context.Context, tx store.Execer, entries []store.LedgerEntryInput) error
+	EntriesByTransaction(ctx context.Context, tx store.Selecter, transactionID string) ([]store.LedgerEntry, error)
 }
 
 type TransactionStore interface {
 	Create(ctx context.Context, tx store.Execer, input store.TransactionInput) error
+	GetForReversal(ctx context.Context, tx store.Getter, transactionID string) (store.TransactionRecord, error)
 }
 
 type ExchangeStore interface {
@@ -68,15 +98,19 @@ type ExchangeQuoteStore interface {
 	Consume(ctx context.Context, tx store.Execer, quoteID string) (int64, error)
 }
 
+type PeriodStore interface {
+	ClosedPeriodAt(ctx context.Context, tx store.Tx, at *time.Time) (string, error)
+}
+
 type AuditStore interface {
 	Log(ctx context.Context, tx store.Execer, actorID, action, entityType, entityID, data string) error
 }
 
-type BalanceHub interface {
-	BroadcastBalance(userID string, update websocket.BalanceUpdate)
+type OutboxStore interface {
+	Append(ctx context.Context, tx store.Execer, input store.DomainEventInput) error
 }
 
-func NewTransactionService(txRunner db.TxRunner, accountStore AccountStore, ledgerStore LedgerStore, txStore TransactionStore, exchangeStore ExchangeStore, quoteStore ExchangeQuoteStore, auditStore AuditStore, hub BalanceHub) *TransactionService {
+func NewTransactionService(txRunner db.TxRunner, accountStore AccountStore, ledgerStore LedgerStore, txStore TransactionStore, exchangeStore ExchangeStore, quoteStore ExchangeQuoteStore, periodStore PeriodStore, auditStore AuditStore, outboxStore OutboxStore, alerts AlertEvaluator, quoteTTLs QuoteTTLs) *TransactionService {
 	return &TransactionService{
 		txRunner:      txRunner,
 		accountStore:  accountStore,
@@ -84,8 +118,11 @@ func NewTransactionService(txRunner db.TxRunner, accountStore AccountStore, ledg
 		txStore:       txStore,
 		exchangeStore: exchangeStore,
 		quoteStore:    quoteStore,
+		periodStore:   periodStore,
 		auditStore:    auditStore,
-		hub:           hub,
+		outboxStore:   outboxStore,
+		alerts:        alerts,
+		quoteTTLs:     quoteTTLs,
 	}
 }
 
@@ -98,109 +135,128 @@ type TransferRequest struct {
 }
 
 func (s *TransactionService) Transfer(ctx context.Context, req TransferRequest) (string, error) {
+	var transactionID string
+	err := s.txRunner.WithTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		transactionID, err = s.TransferTx(ctx, tx, req)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return transactionID, nil
+}
+
+func (s *TransactionService) TransferTx(ctx context.Context, tx *sqlx.Tx, req TransferRequest) (string, error) {
 	if req.AmountMinor <= 0 {
 		return "", ErrInvalidAmount
 	}
 	if req.FromAccountID == req.ToAccountID {
 		return "", ErrSameAccountTransfer
 	}
-	var transactionID string
-	var toUserID string
-	var fromBalanceAfter int64
-	var toBalanceAfter int64
-	var currency string
-	err := s.txRunner.WithTx(ctx, func(tx *sqlx.Tx) error {
-		fromAccount, toAccount, err := lockTwoAccounts(ctx, tx, s.accountStore, req.FromAccountID, req.ToAccountID)
-		if err != nil {
-			return err
-		}
-		log.Println("fromAccount", fromAccount)
-		log.Println("fromAccount user_id", fromAccount.UserID)
-		log.Println("req.UserID", req.UserID)
-		if fromAccount.UserID == nil || *fromAccount.UserID != req.UserID {
-			return ErrUnauthorizedAccount
-		}
-		if fromAccount.Currency != toAccount.Currency {
-			return ErrCurrencyMismatch
-		}
-		currency = fromAccount.Currency
-		if toAccount.UserID != nil {
-			toUserID = *toAccount.UserID
-		}
-		fromBalance := fromAccount.Balance
-		if fromBalance < req.AmountMinor {
-			return ErrInsufficientFunds
-		}
-		newFrom := fromBalance - req.AmountMinor
-		toBalance := toAccount.Balance
-		newTo := toBalance + req.AmountMinor
-		fromBalanceAfter = newFrom
-		toBalanceAfter = newTo
-		if err := s.accountStore.UpdateBalance(ctx, tx, req.FromAccountID, newFrom); err != nil {
-			return err
-		}
-		if err := s.accountStore.UpdateBalance(ctx, tx, req.ToAccountID, newTo); err != nil {
-			return err
-		}
+	if err := s.ensurePeriodOpen(ctx, tx, nil); err != nil {
+		return "", err
+	}
+	fromAccount, toAccount, err := lockTwoAccounts(ctx, tx, s.accountStore, req.FromAccountID, req.ToAccountID)
+	if err != nil {
+		return "", err
+	}
+	log.Println("fromAccount", fromAccount)
+	log.Println("fromAccount user_id", fromAccount.UserID)
+	log.Println("req.UserID", req.UserID)
+	if fromAccount.UserID == nil || *fromAccount.UserID != req.UserID {
+		return "", ErrUnauthorizedAccount
+	}
+	if fromAccount.FrozenAt != nil || toAccount.FrozenAt != nil {
+		return "", ErrAccountFrozen
+	}
+	if fromAccount.Currency != toAccount.Currency {
+		return "", ErrCurrencyMismatch
+	}
+	currency := fromAccount.Currency
+	toUserID := ""
+	if toAccount.UserID != nil {
+		toUserID = *toAccount.UserID
+	}
+	fromBalance := fromAccount.Balance
+	if fromBalance < req.AmountMinor {
+		return "", ErrInsufficientFunds
+	}
+	newFrom := fromBalance - req.AmountMinor
+	toBalance := toAccount.Balance
+	newTo := toBalance + req.AmountMinor
+	if err := s.accountStore.UpdateBalance(ctx, tx, req.FromAccountID, newFrom); err != nil {
+		return "", err
+	}
+	if err := s.accountStore.UpdateBalance(ctx, tx, req.ToAccountID, newTo); err != nil {
+		return "", err
+	}
 
-		transactionID = uuid.NewString()
-		if err := s.txStore.Create(ctx, tx, store.TransactionInput{
-			ID:              transactionID,
-			UserID:          req.UserID,
-			Type:            "transfer",
-			Status:          "completed",
-			Amount:          req.AmountMinor,
-			Currency:        currency,
-			FromAccountID:   &req.FromAccountID,
-			ToAccountID:     &req.ToAccountID,
-			Metadata:        "{}",
-			ClientRequestID: req.ClientRequestID,
-		}); err != nil {
-			return err
-		}
-		entries := []store.LedgerEntryInput{
-			{
-				ID:            uuid.NewString(),
-				TransactionID: transactionID,
-				AccountID:     req.FromAccountID,
-				Amount:        -req.AmountMinor,
-				Currency:      currency,
-				Description:   "Transfer debit",
-			},
-			{
-				ID:            uuid.NewString(),
-				TransactionID: transactionID,
-				AccountID:     req.ToAccountID,
-				Amount:        req.AmountMinor,
-				Currency:      currency,
-				Description:   "Transfer credit",
-			},
-		}
-		if err := ensureBalanced(entries); err != nil {
-			return err
-		}
-		if err := s.ledgerStore.InsertEntries(ctx, tx, entries); err != nil {
-			return err
-		}
-		data, _ := json.Marshal(map[string]string{
-			"transaction_id": transactionID,
-		})
-		return s.auditStore.Log(ctx, tx, req.UserID, "transfer", "transaction", transactionID, string(data))
+	transactionID := uuid.NewString()
+	if err := s.txStore.Create(ctx, tx, store.TransactionInput{
+		ID:              transactionID,
+		UserID:          req.UserID,
+		Type:            "transfer",
+		Status:          "completed",
+		Amount:          req.AmountMinor,
+		Currency:        currency,
+		FromAccountID:   &req.FromAccountID,
+		ToAccountID:     &req.ToAccountID,
+		Metadata:        "{}",
+		ClientRequestID: req.ClientRequestID,
+	}); err != nil {
+		return "", err
+	}
+	entries := []store.LedgerEntryInput{
+		{
+			ID:            uuid.NewString(),
+			TransactionID: transactionID,
+			AccountID:     req.FromAccountID,
+			Amount:        -req.AmountMinor,
+			Currency:      currency,
+			Description:   "Transfer debit",
+		},
+		{
+			ID:            uuid.NewString(),
+			TransactionID: transactionID,
+			AccountID:     req.ToAccountID,
+			Amount:        req.AmountMinor,
+			Currency:      currency,
+			Description:   "Transfer credit",
+		},
+	}
+	if err := ensureBalanced(entries); err != nil {
+		return "", err
+	}
+	if err := s.ledgerStore.InsertEntries(ctx, tx, entries); err != nil {
+		return "", err
+	}
+	data, _ := json.Marshal(map[string]string{
+		"transaction_id": transactionID,
 	})
+	if err := s.auditStore.Log(ctx, tx, req.UserID, "transfer", "transaction", transactionID, string(data)); err != nil {
+		return "", err
+	}
+	err = s.outboxStore.Append(ctx, tx, events.New(events.TransferCompleted, events.AggregateTransaction, transactionID, &req.UserID, map[string]any{
+		"transaction_id":  transactionID,
+		"from_account_id": req.FromAccountID,
+		"to_account_id":   req.ToAccountID,
+		"to_user_id":      toUserID,
+		"amount":          money.FormatMinor(req.AmountMinor),
+		"currency":        currency,
+		"balances": []events.BalanceChange{
+			{UserID: req.UserID, AccountID: req.FromAccountID, Currency: currency, Balance: money.FormatMinor(newFrom)},
+			{UserID: toUserID, AccountID: req.ToAccountID, Currency: currency, Balance: money.FormatMinor(newTo)},
+		},
+	}))
 	if err != nil {
 		return "", err
 	}
-	s.hub.BroadcastBalance(req.UserID, websocket.BalanceUpdate{
-		AccountID: req.FromAccountID,
-		Balance:   money.FormatMinor(fromBalanceAfter),
-		Currency:  currency,
-	})
-	if toUserID != "" {
-		s.hub.BroadcastBalance(toUserID, websocket.BalanceUpdate{
-			AccountID: req.ToAccountID,
-			Balance:   money.FormatMinor(toBalanceAfter),
-			Currency:  currency,
-		})
+	if err := s.alerts.Evaluate(ctx, tx, transactionID, []BalanceMovement{
+		{UserID: req.UserID, AccountID: req.FromAccountID, Currency: currency, Before: fromBalance, After: newFrom},
+		{UserID: toUserID, AccountID: req.ToAccountID, Currency: currency, Before: toBalance, After: newTo},
+	}); err != nil {
+		return "", err
 	}
 	return transactionID, nil
 }
@@ -242,14 +298,13 @@ func (s *TransactionService) QuoteExchange(ctx context.Context, req ExchangeQuot
 	if fromCurrency == toCurrency || !isExchangePairAllowed(fromCurrency, toCurrency) {
 		return ExchangeQuote{}, ErrInvalidExchangeRequest
 	}
-	baseRate := fixedUSDEURRate()
-	rate := baseRate
-	if fromCurrency == "EUR" && toCurrency == "USD" {
-		rate = decimal.NewFromInt(1).Div(baseRate).RoundBank(6)
+	rate, err := s.currentRate(ctx, fromCurrency, toCurrency)
+	if err != nil {
+		return ExchangeQuote{}, err
 	}
 	convertedMinor := convertMinor(req.AmountMinor, rate)
 	quoteID := uuid.NewString()
-	expiresAt := time.Now().Add(2 * time.Minute)
+	expiresAt := time.Now().Add(s.quoteTTLs.For(fromCurrency, toCurrency))
 	if err := s.quoteStore.Create(ctx, store.ExchangeQuoteInput{
 		ID:             quoteID,
 		UserID:         req.UserID,
@@ -287,8 +342,6 @@ func (s *TransactionService) Exchange(ctx context.Context, req ExchangeRequest)
 		return "", ErrInvalidAmount
 	}
 	var transactionID string
-	var fromBalanceAfter int64
-	var toBalanceAfter int64
 	var toCurrency string
 	var fromCurrency string
 	var rate decimal.Decimal
@@ -331,6 +384,9 @@ func (s *TransactionService) Exchange(ctx context.Context, req ExchangeRequest)
 	}
 
 	err := s.txRunner.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := s.ensurePeriodOpen(ctx, tx, nil); err != nil {
+			return err
+		}
 		fromAccount, toAccount, err := lockTwoAccounts(ctx, tx, s.accountStore, req.FromAccountID, req.ToAccountID)
 		if err != nil {
 			return err
@@ -338,18 +394,26 @@ func (s *TransactionService) Exchange(ctx context.Context, req ExchangeRequest)
 		if fromAccount.UserID == nil || *fromAccount.UserID != req.UserID {
 			return ErrUnauthorizedAccount
 		}
+		if fromAccount.FrozenAt != nil || toAccount.FrozenAt != nil {
+			return ErrAccountFrozen
+		}
 		fromCurrency = fromAccount.Currency
 		toCurrency = toAccount.Currency
 		if fromCurrency == toCurrency || !isExchangePairAllowed(fromCurrency, toCurrency) {
 			return ErrInvalidExchangeRequest
 		}
-		baseRate := fixedUSDEURRate()
-		directionalRate := baseRate
-		if fromCurrency == "EUR" && toCurrency == "USD" {
-			directionalRate = decimal.NewFromInt(1).Div(baseRate).RoundBank(6)
-		}
-		if !rate.Equal(directionalRate) {
-			return ErrRateMismatch
+		// A quote locks its rate until it expires; otherwise the client's
+		// rate must still be the active one.
+		directionalRate := rate
+		if quoteID == "" {
+			current, err := s.currentRate(ctx, fromCurrency, toCurrency)
+			if err != nil {
+				return err
+			}
+			if !rate.Equal(current) {
+				return ErrRateMismatch
+			}
+			directionalRate = current
 		}
 		convertedMinor := convertMinor(req.AmountMinor, directionalRate)
 		if expectedConverted != 0 && expectedConverted != convertedMinor {
@@ -363,8 +427,6 @@ func (s *TransactionService) Exchange(ctx context.Context, req ExchangeRequest)
 		toBalance := toAccount.Balance
 		newFrom := fromBalance - req.AmountMinor
 		newTo := toBalance + convertedMinor
-		fromBalanceAfter = newFrom
-		toBalanceAfter = newTo
 
 		systemFromID, err := s.accountStore.GetSystemAccount(ctx, fromCurrency)
 		if err != nil {
@@ -471,24 +533,321 @@ func (s *TransactionService) Exchange(ctx context.Context, req ExchangeRequest)
 				return ErrQuoteConsumed
 			}
 		}
-		return nil
+		err = s.outboxStore.Append(ctx, tx, events.New(events.ExchangeCompleted, events.AggregateTransaction, transactionID, &req.UserID, map[string]any{
+			"transaction_id":   transactionID,
+			"from_account_id":  req.FromAccountID,
+			"to_account_id":    req.ToAccountID,
+			"from_currency":    fromCurrency,
+			"to_currency":      toCurrency,
+			"amount":           money.FormatMinor(req.AmountMinor),
+			"converted_amount": money.FormatMinor(convertedMinor),
+			"rate":             directionalRate.StringFixedBank(6),
+			"quote_id":         quoteID,
+			"balances": []events.BalanceChange{
+				{UserID: req.UserID, AccountID: req.FromAccountID, Currency: fromCurrency, Balance: money.FormatMinor(newFrom)},
+				{UserID: req.UserID, AccountID: req.ToAccountID, Currency: toCurrency, Balance: money.FormatMinor(newTo)},
+			},
+		}))
+		if err != nil {
+			return err
+		}
+		return s.alerts.Evaluate(ctx, tx, transactionID, []BalanceMovement{
+			{UserID: req.UserID, AccountID: req.FromAccountID, Currency: fromCurrency, Before: fromBalance, After: newFrom, Internal: true},
+			{UserID: req.UserID, AccountID: req.ToAccountID, Currency: toCurrency, Before: toBalance, After: newTo, Internal: true},
+		})
 	})
 	if err != nil {
 		return "", err
 	}
-	s.hub.BroadcastBalance(req.UserID, websocket.BalanceUpdate{
-		AccountID: req.FromAccountID,
-		Balance:   money.FormatMinor(fromBalanceAfter),
-		Currency:  fromCurrency,
+	return transactionID, nil
+}
+
+type AdjustmentRequest struct {
+	ActorID         string
+	AccountID       string
+	AmountMinor     int64
+	Reason          string
+	EffectiveAt     *time.Time
+	ClientRequestID *string
+}
+
+func (s *TransactionService) Adjust(ctx context.Context, req AdjustmentRequest) (string, error) {
+	var transactionID string
+	err := s.txRunner.WithTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		transactionID, err = s.AdjustTx(ctx, tx, req)
+		return err
 	})
-	s.hub.BroadcastBalance(req.UserID, websocket.BalanceUpdate{
-		AccountID: req.ToAccountID,
-		Balance:   money.FormatMinor(toBalanceAfter),
-		Currency:  toCurrency,
+	if err != nil {
+		return "", err
+	}
+	return transactionID, nil
+}
+
+func (s *TransactionService) AdjustTx(ctx context.Context, tx *sqlx.Tx, req AdjustmentRequest) (string, error) {
+	if req.AmountMinor == 0 {
+		return "", ErrInvalidAmount
+	}
+	if req.EffectiveAt != nil && req.EffectiveAt.After(time.Now()) {
+		return "", ErrInvalidEffectiveDate
+	}
+	account, err := s.accountStore.GetByID(ctx, req.AccountID)
+	if err != nil {
+		return "", err
+	}
+	if account.UserID == nil || account.IsSystem {
+		return "", ErrInvalidAdjustment
+	}
+	userID := *account.UserID
+	currency := account.Currency
+	systemID, err := s.accountStore.GetSystemAccount(ctx, currency)
+	if err != nil {
+		return "", err
+	}
+	if err := s.ensurePeriodOpen(ctx, tx, req.EffectiveAt); err != nil {
+		return "", err
+	}
+	locked, system, err := lockTwoAccounts(ctx, tx, s.accountStore, req.AccountID, systemID)
+	if err != nil {
+		return "", err
+	}
+	newBalance := locked.Balance + req.AmountMinor
+	newSystem := system.Balance - req.AmountMinor
+	if newBalance < 0 || newSystem < 0 {
+		return "", ErrInsufficientFunds
+	}
+	if err := s.accountStore.UpdateBalance(ctx, tx, req.AccountID, newBalance); err != nil {
+		return "", err
+	}
+	if err := s.accountStore.UpdateBalance(ctx, tx, systemID, newSystem); err != nil {
+		return "", err
+	}
+
+	transactionID := uuid.NewString()
+	fromAccountID, toAccountID := systemID, req.AccountID
+	amount := req.AmountMinor
+	if amount < 0 {
+		fromAccountID, toAccountID = req.AccountID, systemID
+		amount = -amount
+	}
+	effectiveAt := ""
+	if req.EffectiveAt != nil {
+		effectiveAt = req.EffectiveAt.UTC().Format(time.RFC3339Nano)
+	}
+	metadata, _ := json.Marshal(map[string]string{
+		"reason":       req.Reason,
+		"actor_id":     req.ActorID,
+		"effective_at": effectiveAt,
 	})
+	if err := s.txStore.Create(ctx, tx, store.TransactionInput{
+		ID:              transactionID,
+		UserID:          userID,
+		Type:            "adjustment",
+		Status:          "completed",
+		Amount:          amount,
+		Currency:        currency,
+		FromAccountID:   &fromAccountID,
+		ToAccountID:     &toAccountID,
+		Metadata:        string(metadata),
+		ClientRequestID: req.ClientRequestID,
+	}); err != nil {
+		return "", err
+	}
+	entries := []store.LedgerEntryInput{
+		{
+			ID:            uuid.NewString(),
+			TransactionID: transactionID,
+			AccountID:     req.AccountID,
+			Amount:        req.AmountMinor,
+			Currency:      currency,
+			Description:   "Adjustment",
+			CreatedAt:     req.EffectiveAt,
+		},
+		{
+			ID:            uuid.NewString(),
+			TransactionID: transactionID,
+			AccountID:     systemID,
+			Amount:        -req.AmountMinor,
+			Currency:      currency,
+			Description:   "Adjustment offset",
+			CreatedAt:     req.EffectiveAt,
+		},
+	}
+	if err := ensureBalanced(entries); err != nil {
+		return "", err
+	}
+	if err := s.ledgerStore.InsertEntries(ctx, tx, entries); err != nil {
+		return "", err
+	}
+	if err := s.auditStore.Log(ctx, tx, req.ActorID, "adjustment", "transaction", transactionID, string(metadata)); err != nil {
+		return "", err
+	}
+	err = s.outboxStore.Append(ctx, tx, events.New(events.AdjustmentPosted, events.AggregateTransaction, transactionID, &userID, map[string]any{
+		"transaction_id": transactionID,
+		"account_id":     req.AccountID,
+		"amount":         money.FormatMinor(req.AmountMinor),
+		"currency":       currency,
+		"reason":         req.Reason,
+		"actor_id":       req.ActorID,
+		"effective_at":   effectiveAt,
+		"balances": []events.BalanceChange{
+			{UserID: userID, AccountID: req.AccountID, Currency: currency, Balance: money.FormatMinor(newBalance)},
+		},
+	}))
+	if err != nil {
+		return "", err
+	}
+	if err := s.alerts.Evaluate(ctx, tx, transactionID, []BalanceMovement{
+		{UserID: userID, AccountID: req.AccountID, Currency: currency, Before: locked.Balance, After: newBalance},
+	}); err != nil {
+		return "", err
+	}
 	return transactionID, nil
 }
 
+type ReversalRequest struct {
+	ActorID       string
+	TransactionID string
+	Reason        string
+}
+
+func (s *TransactionService) Reverse(ctx context.Context, req ReversalRequest) (string, error) {
+	var transactionID string
+	err := s.txRunner.WithTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		transactionID, err = s.ReverseTx(ctx, tx, req)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return transactionID, nil
+}
+
+// ReverseTx posts the opposite of every ledger entry of the original
+// transaction, dated now. The unique index on reversal_of keeps a transaction
+// from being reversed twice.
+func (s *TransactionService) ReverseTx(ctx context.Context, tx *sqlx.Tx, req ReversalRequest) (string, error) {
+	original, err := s.txStore.GetForReversal(ctx, tx, req.TransactionID)
+	if err == sql.ErrNoRows {
+		return "", ErrTransactionNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if original.Type == "reversal" || original.Status != "completed" {
+		return "", ErrNotReversible
+	}
+	if original.ReversedBy != nil {
+		return "", ErrAlreadyReversed
+	}
+	if err := s.ensurePeriodOpen(ctx, tx, nil); err != nil {
+		return "", err
+	}
+	originalEntries, err := s.ledgerStore.EntriesByTransaction(ctx, tx, original.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(originalEntries) == 0 {
+		return "", ErrNotReversible
+	}
+
+	transactionID := uuid.NewString()
+	deltas := map[string]int64{}
+	entries := make([]store.LedgerEntryInput, 0, len(originalEntries))
+	for _, entry := range originalEntries {
+		deltas[entry.AccountID] -= entry.Amount
+		entries = append(entries, store.LedgerEntryInput{
+			ID:            uuid.NewString(),
+			TransactionID: transactionID,
+			AccountID:     entry.AccountID,
+			Amount:        -entry.Amount,
+			Currency:      entry.Currency,
+			Description:   "Reversal: " + entry.Description,
+		})
+	}
+	if err := ensureBalancedByCurrency(entries); err != nil {
+		return "", err
+	}
+	accountIDs := make([]string, 0, len(deltas))
+	for accountID := range deltas {
+		accountIDs = append(accountIDs, accountID)
+	}
+	sort.Strings(accountIDs)
+	var movements []BalanceMovement
+	var balances []events.BalanceChange
+	for _, accountID := range accountIDs {
+		account, err := s.accountStore.GetForUpdate(ctx, tx, accountID)
+		if err != nil {
+			return "", err
+		}
+		newBalance := account.Balance + deltas[accountID]
+		if newBalance < 0 {
+			return "", ErrInsufficientFunds
+		}
+		if err := s.accountStore.UpdateBalance(ctx, tx, accountID, newBalance); err != nil {
+			return "", err
+		}
+		if account.UserID != nil && !account.IsSystem {
+			movements = append(movements, BalanceMovement{UserID: *account.UserID, AccountID: accountID, Currency: account.Currency, Before: account.Balance, After: newBalance})
+			balances = append(balances, events.BalanceChange{UserID: *account.UserID, AccountID: accountID, Currency: account.Currency, Balance: money.FormatMinor(newBalance)})
+		}
+	}
+
+	metadata, _ := json.Marshal(map[string]string{
+		"reason":      req.Reason,
+		"actor_id":    req.ActorID,
+		"reversal_of": original.ID,
+	})
+	if err := s.txStore.Create(ctx, tx, store.TransactionInput{
+		ID:            transactionID,
+		UserID:        original.UserID,
+		Type:          "reversal",
+		Status:        "completed",
+		Amount:        original.Amount,
+		Currency:      original.Currency,
+		FromAccountID: original.ToAccountID,
+		ToAccountID:   original.FromAccountID,
+		Metadata:      string(metadata),
+		ReversalOf:    &original.ID,
+	}); err != nil {
+		return "", err
+	}
+	if err := s.ledgerStore.InsertEntries(ctx, tx, entries); err != nil {
+		return "", err
+	}
+	if err := s.auditStore.Log(ctx, tx, req.ActorID, "reversal", "transaction", transactionID, string(metadata)); err != nil {
+		return "", err
+	}
+	err = s.outboxStore.Append(ctx, tx, events.New(events.ReversalPosted, events.AggregateTransaction, transactionID, &original.UserID, map[string]any{
+		"transaction_id": transactionID,
+		"reversal_of":    original.ID,
+		"amount":         money.FormatMinor(original.Amount),
+		"currency":       original.Currency,
+		"reason":         req.Reason,
+		"actor_id":       req.ActorID,
+		"balances":       balances,
+	}))
+	if err != nil {
+		return "", err
+	}
+	if err := s.alerts.Evaluate(ctx, tx, transactionID, movements); err != nil {
+		return "", err
+	}
+	return transactionID, nil
+}
+
+func (s *TransactionService) ensurePeriodOpen(ctx context.Context, tx store.Tx, at *time.Time) error {
+	periodID, err := s.periodStore.ClosedPeriodAt(ctx, tx, at)
+	if err != nil {
+		return err
+	}
+	if periodID != "" {
+		return ErrPeriodClosed
+	}
+	return nil
+}
+
 func ensureBalanced(entries []store.LedgerEntryInput) error {
 	var sum int64
 	for _, entry := range entries {
@@ -517,12 +876,22 @@ func convertMinor(amountMinor int64, rate decimal.Decimal) int64 {
 	return decimal.NewFromInt(amountMinor).Mul(rate).RoundBank(0).IntPart()
 }
 
-func fixedUSDEURRate() decimal.Decimal {
-	rate, err := decimal.NewFromString("0.92")
+func (s *TransactionService) currentRate(ctx context.Context, fromCurrency, toCurrency string) (decimal.Decimal, error) {
+	active, err := s.exchangeStore.GetActive(ctx, "USD", "EUR")
+	if err == sql.ErrNoRows {
+		return decimal.Decimal{}, ErrExchangeRateNotSet
+	}
 	if err != nil {
-		return decimal.NewFromFloat(0.92)
+		return decimal.Decimal{}, err
+	}
+	rate, err := decimal.NewFromString(fmt.Sprint(active["rate"]))
+	if err != nil || !rate.IsPositive() {
+		return decimal.Decimal{}, ErrExchangeRateNotSet
+	}
+	if fromCurrency == "EUR" && toCurrency == "USD" {
+		return decimal.NewFromInt(1).Div(rate).RoundBank(6), nil
 	}
-	return rate
+	return rate, nil
 }
 
 func isExchangePairAllowed(fromCurrency, toCurrency string) bool {
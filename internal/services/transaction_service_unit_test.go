@@ -2,13 +2,14 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"sync"
 	"testing"
 	"time"
 
+	"banking/internal/events"
 	"banking/internal/store"
-	"banking/internal/websocket"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -57,7 +58,8 @@ func (s stubAccountStore) GetSystemAccount(ctx context.Context, currency string)
 }
 
 type stubLedgerStore struct {
-	insertFn func(ctx context.Context, tx store.Execer, entries []store.LedgerEntryInput) error
+	insertFn  func(ctx context.Context, tx store.Execer, entries []store.LedgerEntryInput) error
+	entriesFn func(ctx context.Context, tx store.Selecter, transactionID string) ([]store.LedgerEntry, error)
 }
 
 func (s stubLedgerStore) InsertEntries(ctx context.Context, tx store.Execer, entries []store.LedgerEntryInput) error {
@@ -67,8 +69,16 @@ func (s stubLedgerStore) InsertEntries(ctx context.Context, tx store.Execer, ent
 	return s.insertFn(ctx, tx, entries)
 }
 
+func (s stubLedgerStore) EntriesByTransaction(ctx context.Context, tx store.Selecter, transactionID string) ([]store.LedgerEntry, error) {
+	if s.entriesFn == nil {
+		return nil, nil
+	}
+	return s.entriesFn(ctx, tx, transactionID)
+}
+
 type stubTransactionStore struct {
-	createFn func(ctx context.Context, tx store.Execer, input store.TransactionInput) error
+	createFn         func(ctx context.Context, tx store.Execer, input store.TransactionInput) error
+	getForReversalFn func(ctx context.Context, tx store.Getter, transactionID string) (store.TransactionRecord, error)
 }
 
 func (s stubTransactionStore) Create(ctx context.Context, tx store.Execer, input store.TransactionInput) error {
@@ -78,6 +88,13 @@ func (s stubTransactionStore) Create(ctx context.Context, tx store.Execer, input
 	return s.createFn(ctx, tx, input)
 }
 
+func (s stubTransactionStore) GetForReversal(ctx context.Context, tx store.Getter, transactionID string) (store.TransactionRecord, error) {
+	if s.getForReversalFn == nil {
+		return store.TransactionRecord{}, sql.ErrNoRows
+	}
+	return s.getForReversalFn(ctx, tx, transactionID)
+}
+
 type stubExchangeStore struct {
 	getActiveFn func(ctx context.Context, baseCurrency, quoteCurrency string) (map[string]any, error)
 }
@@ -110,6 +127,17 @@ func (s stubQuoteStore) Consume(ctx context.Context, tx store.Execer, quoteID st
 	return s.consumeFn(ctx, tx, quoteID)
 }
 
+type stubPeriodStore struct {
+	closedPeriodAtFn func(ctx context.Context, tx store.Tx, at *time.Time) (string, error)
+}
+
+func (s stubPeriodStore) ClosedPeriodAt(ctx context.Context, tx store.Tx, at *time.Time) (string, error) {
+	if s.closedPeriodAtFn == nil {
+		return "", nil
+	}
+	return s.closedPeriodAtFn(ctx, tx, at)
+}
+
 type stubAuditStore struct {
 	logFn func(ctx context.Context, tx store.Execer, actorID, action, entityType, entityID, data string) error
 }
@@ -121,12 +149,36 @@ func (s stubAuditStore) Log(ctx context.Context, tx store.Execer, actorID, actio
 	return s.logFn(ctx, tx, actorID, action, entityType, entityID, data)
 }
 
-type stubHub struct {
-	calls []websocket.BalanceUpdate
+type stubOutboxStore struct {
+	events []store.DomainEventInput
 }
 
-func (s *stubHub) BroadcastBalance(_ string, update websocket.BalanceUpdate) {
-	s.calls = append(s.calls, update)
+func (s *stubOutboxStore) Append(_ context.Context, _ store.Execer, input store.DomainEventInput) error {
+	s.events = append(s.events, input)
+	return nil
+}
+
+type stubAlertEvaluator struct {
+	evaluateFn func(ctx context.Context, tx store.DB, transactionID string, movements []BalanceMovement) error
+}
+
+func (s stubAlertEvaluator) Evaluate(ctx context.Context, tx store.DB, transactionID string, movements []BalanceMovement) error {
+	if s.evaluateFn == nil {
+		return nil
+	}
+	return s.evaluateFn(ctx, tx, transactionID, movements)
+}
+
+func (s *stubOutboxStore) balanceChanges(t *testing.T, eventType string) []events.BalanceChange {
+	t.Helper()
+	if len(s.events) != 1 || s.events[0].Type != eventType {
+		t.Fatalf("expected one %s event, got %#v", eventType, s.events)
+	}
+	changes, err := events.BalanceChanges(store.DomainEvent{Payload: s.events[0].Payload})
+	if err != nil {
+		t.Fatalf("invalid event payload: %v", err)
+	}
+	return changes
 }
 
 func TestTransferInvalidAmount(t *testing.T) {
@@ -135,7 +187,7 @@ func TestTransferInvalidAmount(t *testing.T) {
 			t.Fatalf("unexpected store call")
 			return store.Account{}, nil
 		},
-	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubQuoteStore{}, stubAuditStore{}, &stubHub{})
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubQuoteStore{}, stubPeriodStore{}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
 	_, err := service.Transfer(context.Background(), TransferRequest{
 		UserID: "user-1", FromAccountID: "a1", ToAccountID: "a2", AmountMinor: 0,
 	})
@@ -152,7 +204,7 @@ func TestTransferUnauthorized(t *testing.T) {
 			}
 			return store.Account{UserID: stringPtr("user-2"), Currency: "USD", Balance: int64(5000)}, nil
 		},
-	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubQuoteStore{}, stubAuditStore{}, &stubHub{})
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubQuoteStore{}, stubPeriodStore{}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
 	_, err := service.Transfer(context.Background(), TransferRequest{
 		UserID: "user-1", FromAccountID: "from", ToAccountID: "to", AmountMinor: 1000,
 	})
@@ -169,7 +221,7 @@ func TestTransferCurrencyMismatch(t *testing.T) {
 			}
 			return store.Account{UserID: stringPtr("user-2"), Currency: "EUR", Balance: int64(5000)}, nil
 		},
-	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubQuoteStore{}, stubAuditStore{}, &stubHub{})
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubQuoteStore{}, stubPeriodStore{}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
 	_, err := service.Transfer(context.Background(), TransferRequest{
 		UserID: "user-1", FromAccountID: "from", ToAccountID: "to", AmountMinor: 1000,
 	})
@@ -186,7 +238,7 @@ func TestTransferInsufficientFunds(t *testing.T) {
 			}
 			return store.Account{UserID: stringPtr("user-2"), Currency: "USD", Balance: int64(5000)}, nil
 		},
-	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubQuoteStore{}, stubAuditStore{}, &stubHub{})
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubQuoteStore{}, stubPeriodStore{}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
 	_, err := service.Transfer(context.Background(), TransferRequest{
 		UserID: "user-1", FromAccountID: "from", ToAccountID: "to", AmountMinor: 1000,
 	})
@@ -199,7 +251,8 @@ func TestTransferSuccess(t *testing.T) {
 	var balances []int64
 	var ledgerEntries []store.LedgerEntryInput
 	var createdTx store.TransactionInput
-	hub := &stubHub{}
+	var movements []BalanceMovement
+	outbox := &stubOutboxStore{}
 	service := NewTransactionService(fakeTxRunner{}, stubAccountStore{
 		getForUpdateFn: func(_ context.Context, _ store.Getter, accountID string) (store.Account, error) {
 			if accountID == "from" {
@@ -221,7 +274,12 @@ func TestTransferSuccess(t *testing.T) {
 			createdTx = input
 			return nil
 		},
-	}, stubExchangeStore{}, stubQuoteStore{}, stubAuditStore{}, hub)
+	}, stubExchangeStore{}, stubQuoteStore{}, stubPeriodStore{}, stubAuditStore{}, outbox, stubAlertEvaluator{
+		evaluateFn: func(_ context.Context, _ store.DB, _ string, given []BalanceMovement) error {
+			movements = given
+			return nil
+		},
+	}, QuoteTTLs{})
 
 	id, err := service.Transfer(context.Background(), TransferRequest{
 		UserID: "user-1", FromAccountID: "from", ToAccountID: "to", AmountMinor: 1000,
@@ -238,8 +296,12 @@ func TestTransferSuccess(t *testing.T) {
 	if len(ledgerEntries) != 2 {
 		t.Fatalf("unexpected ledger entries: %#v", ledgerEntries)
 	}
-	if len(hub.calls) != 2 {
-		t.Fatalf("expected 2 balance broadcasts, got %d", len(hub.calls))
+	changes := outbox.balanceChanges(t, events.TransferCompleted)
+	if len(changes) != 2 || changes[0].Balance != "90.00" || changes[1].Balance != "60.00" {
+		t.Fatalf("unexpected balance changes: %#v", changes)
+	}
+	if len(movements) != 2 || movements[0].Before != 10000 || movements[0].After != 9000 || movements[1].UserID != "user-2" || movements[1].After != 6000 {
+		t.Fatalf("unexpected movements: %#v", movements)
 	}
 }
 
@@ -267,7 +329,7 @@ func TestExchangeQuoteSuccess(t *testing.T) {
 			}
 			return nil
 		},
-	}, stubAuditStore{}, &stubHub{})
+	}, stubPeriodStore{}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
 
 	quote, err := service.QuoteExchange(context.Background(), ExchangeQuoteRequest{
 		UserID: "user-1", FromAccountID: "from", ToAccountID: "to", AmountMinor: 1000,
@@ -301,7 +363,7 @@ func TestExchangeRateMismatch(t *testing.T) {
 		getActiveFn: func(context.Context, string, string) (map[string]any, error) {
 			return map[string]any{"id": "rate-1", "rate": "0.92"}, nil
 		},
-	}, stubQuoteStore{}, stubAuditStore{}, &stubHub{})
+	}, stubQuoteStore{}, stubPeriodStore{}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
 
 	badRate := "0.910000"
 	_, err := service.Exchange(context.Background(), ExchangeRequest{
@@ -313,7 +375,7 @@ func TestExchangeRateMismatch(t *testing.T) {
 }
 
 func TestExchangeSuccessWithQuote(t *testing.T) {
-	hub := &stubHub{}
+	outbox := &stubOutboxStore{}
 	consumed := false
 	service := NewTransactionService(fakeTxRunner{}, stubAccountStore{
 		getForUpdateFn: func(_ context.Context, _ store.Getter, accountID string) (store.Account, error) {
@@ -338,7 +400,8 @@ func TestExchangeSuccessWithQuote(t *testing.T) {
 		},
 	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{
 		getActiveFn: func(context.Context, string, string) (map[string]any, error) {
-			return map[string]any{"id": "rate-1", "rate": "0.92"}, nil
+			// The rate moved after the quote; the quote keeps its own.
+			return map[string]any{"id": "rate-2", "rate": "0.95"}, nil
 		},
 	}, stubQuoteStore{
 		getByIDFn: func(context.Context, string) (store.ExchangeQuote, error) {
@@ -359,7 +422,7 @@ func TestExchangeSuccessWithQuote(t *testing.T) {
 			consumed = true
 			return 1, nil
 		},
-	}, stubAuditStore{}, hub)
+	}, stubPeriodStore{}, stubAuditStore{}, outbox, stubAlertEvaluator{}, QuoteTTLs{})
 
 	quoteID := "quote-1"
 	id, err := service.Exchange(context.Background(), ExchangeRequest{
@@ -371,8 +434,8 @@ func TestExchangeSuccessWithQuote(t *testing.T) {
 	if id == "" || !consumed {
 		t.Fatalf("expected exchange with consumed quote")
 	}
-	if len(hub.calls) != 2 {
-		t.Fatalf("expected 2 hub broadcasts, got %d", len(hub.calls))
+	if changes := outbox.balanceChanges(t, events.ExchangeCompleted); len(changes) != 2 {
+		t.Fatalf("unexpected balance changes: %#v", changes)
 	}
 }
 
@@ -399,8 +462,10 @@ func TestExchangeRoundingEdge(t *testing.T) {
 			return "sys-eur", nil
 		},
 	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{
-		getActiveFn: func(context.Context, string, string) (map[string]any, error) { return nil, nil },
-	}, stubQuoteStore{}, stubAuditStore{}, &stubHub{})
+		getActiveFn: func(context.Context, string, string) (map[string]any, error) {
+			return map[string]any{"rate": "0.92"}, nil
+		},
+	}, stubQuoteStore{}, stubPeriodStore{}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
 
 	rate := "0.920000"
 	_, err := service.Exchange(context.Background(), ExchangeRequest{
@@ -419,7 +484,7 @@ func TestTransferConcurrent(t *testing.T) {
 		updateBalanceFn: func(context.Context, store.Execer, string, int64) error {
 			return nil
 		},
-	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubQuoteStore{}, stubAuditStore{}, &stubHub{})
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubQuoteStore{}, stubPeriodStore{}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
 
 	var wg sync.WaitGroup
 	errs := make(chan error, 5)
@@ -451,7 +516,7 @@ func TestExchangeQuoteNotFound(t *testing.T) {
 		getByIDFn: func(context.Context, string) (store.ExchangeQuote, error) {
 			return store.ExchangeQuote{}, errors.New("missing")
 		},
-	}, stubAuditStore{}, &stubHub{})
+	}, stubPeriodStore{}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
 
 	quoteID := "missing"
 	_, err := service.Exchange(context.Background(), ExchangeRequest{
@@ -461,3 +526,331 @@ func TestExchangeQuoteNotFound(t *testing.T) {
 		t.Fatalf("expected ErrQuoteNotFound, got %v", err)
 	}
 }
+
+func TestTransferRejectedInClosedPeriod(t *testing.T) {
+	service := NewTransactionService(fakeTxRunner{}, stubAccountStore{
+		getForUpdateFn: func(context.Context, store.Getter, string) (store.Account, error) {
+			t.Fatalf("unexpected account lock")
+			return store.Account{}, nil
+		},
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubQuoteStore{}, stubPeriodStore{
+		closedPeriodAtFn: func(_ context.Context, _ store.Tx, at *time.Time) (string, error) {
+			if at != nil {
+				t.Fatalf("expected posting to be checked at the current time")
+			}
+			return "period-1", nil
+		},
+	}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
+	_, err := service.Transfer(context.Background(), TransferRequest{
+		UserID: "user-1", FromAccountID: "from", ToAccountID: "to", AmountMinor: 100,
+	})
+	if err != ErrPeriodClosed {
+		t.Fatalf("expected ErrPeriodClosed, got %v", err)
+	}
+}
+
+func TestTransferRejectsFrozenAccount(t *testing.T) {
+	frozenAt := time.Now()
+	updated := false
+	service := NewTransactionService(fakeTxRunner{}, stubAccountStore{
+		getForUpdateFn: func(_ context.Context, _ store.Getter, accountID string) (store.Account, error) {
+			if accountID == "to" {
+				return store.Account{ID: accountID, UserID: stringPtr("user-2"), Currency: "USD", Balance: 0, FrozenAt: &frozenAt}, nil
+			}
+			return store.Account{ID: accountID, UserID: stringPtr("user-1"), Currency: "USD", Balance: 1000}, nil
+		},
+		updateBalanceFn: func(context.Context, store.Execer, string, int64) error {
+			updated = true
+			return nil
+		},
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubQuoteStore{}, stubPeriodStore{}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
+	_, err := service.Transfer(context.Background(), TransferRequest{
+		UserID: "user-1", FromAccountID: "from", ToAccountID: "to", AmountMinor: 100,
+	})
+	if err != ErrAccountFrozen {
+		t.Fatalf("expected ErrAccountFrozen, got %v", err)
+	}
+	if updated {
+		t.Fatalf("expected no balance updates")
+	}
+}
+
+func TestAdjustSuccess(t *testing.T) {
+	effectiveAt := time.Now().Add(-48 * time.Hour)
+	balances := map[string]int64{}
+	var ledgerEntries []store.LedgerEntryInput
+	var createdTx store.TransactionInput
+	auditActor := ""
+	outbox := &stubOutboxStore{}
+	service := NewTransactionService(fakeTxRunner{}, stubAccountStore{
+		getByIDFn: func(context.Context, string) (store.Account, error) {
+			return store.Account{ID: "acc-1", UserID: stringPtr("user-1"), Currency: "USD", Balance: 1000}, nil
+		},
+		getSystemAccountFn: func(context.Context, string) (string, error) {
+			return "sys-usd", nil
+		},
+		getForUpdateFn: func(_ context.Context, _ store.Getter, accountID string) (store.Account, error) {
+			if accountID == "sys-usd" {
+				return store.Account{ID: accountID, Currency: "USD", Balance: 100000, IsSystem: true}, nil
+			}
+			return store.Account{ID: accountID, UserID: stringPtr("user-1"), Currency: "USD", Balance: 1000}, nil
+		},
+		updateBalanceFn: func(_ context.Context, _ store.Execer, accountID string, balance int64) error {
+			balances[accountID] = balance
+			return nil
+		},
+	}, stubLedgerStore{
+		insertFn: func(_ context.Context, _ store.Execer, entries []store.LedgerEntryInput) error {
+			ledgerEntries = entries
+			return nil
+		},
+	}, stubTransactionStore{
+		createFn: func(_ context.Context, _ store.Execer, input store.TransactionInput) error {
+			createdTx = input
+			return nil
+		},
+	}, stubExchangeStore{}, stubQuoteStore{}, stubPeriodStore{
+		closedPeriodAtFn: func(_ context.Context, _ store.Tx, at *time.Time) (string, error) {
+			if at == nil || !at.Equal(effectiveAt) {
+				t.Fatalf("expected effective date to be checked, got %v", at)
+			}
+			return "", nil
+		},
+	}, stubAuditStore{
+		logFn: func(_ context.Context, _ store.Execer, actorID, action, _, _, _ string) error {
+			if action == "adjustment" {
+				auditActor = actorID
+			}
+			return nil
+		},
+	}, outbox, stubAlertEvaluator{}, QuoteTTLs{})
+
+	id, err := service.Adjust(context.Background(), AdjustmentRequest{
+		ActorID: "admin-1", AccountID: "acc-1", AmountMinor: -250, Reason: "fee reversal", EffectiveAt: &effectiveAt,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id == "" || createdTx.Type != "adjustment" || createdTx.UserID != "user-1" || createdTx.Amount != 250 {
+		t.Fatalf("unexpected transaction: %#v", createdTx)
+	}
+	if *createdTx.FromAccountID != "acc-1" || *createdTx.ToAccountID != "sys-usd" {
+		t.Fatalf("unexpected direction: %#v", createdTx)
+	}
+	if balances["acc-1"] != 750 || balances["sys-usd"] != 100250 {
+		t.Fatalf("unexpected balances: %#v", balances)
+	}
+	if len(ledgerEntries) != 2 || ledgerEntries[0].CreatedAt != &effectiveAt || ledgerEntries[1].Amount != 250 {
+		t.Fatalf("unexpected ledger entries: %#v", ledgerEntries)
+	}
+	if auditActor != "admin-1" {
+		t.Fatalf("expected adjustment to be audited against the admin, got %q", auditActor)
+	}
+	changes := outbox.balanceChanges(t, events.AdjustmentPosted)
+	if len(changes) != 1 || changes[0].UserID != "user-1" || changes[0].Balance != "7.50" {
+		t.Fatalf("unexpected balance changes: %#v", changes)
+	}
+}
+
+func TestAdjustRejectedInClosedPeriod(t *testing.T) {
+	effectiveAt := time.Now().Add(-90 * 24 * time.Hour)
+	service := NewTransactionService(fakeTxRunner{}, stubAccountStore{
+		getByIDFn: func(context.Context, string) (store.Account, error) {
+			return store.Account{ID: "acc-1", UserID: stringPtr("user-1"), Currency: "USD"}, nil
+		},
+		getSystemAccountFn: func(context.Context, string) (string, error) {
+			return "sys-usd", nil
+		},
+		updateBalanceFn: func(context.Context, store.Execer, string, int64) error {
+			t.Fatalf("unexpected balance update")
+			return nil
+		},
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubQuoteStore{}, stubPeriodStore{
+		closedPeriodAtFn: func(context.Context, store.Tx, *time.Time) (string, error) {
+			return "period-1", nil
+		},
+	}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
+	_, err := service.Adjust(context.Background(), AdjustmentRequest{
+		ActorID: "admin-1", AccountID: "acc-1", AmountMinor: 100, Reason: "late fee", EffectiveAt: &effectiveAt,
+	})
+	if err != ErrPeriodClosed {
+		t.Fatalf("expected ErrPeriodClosed, got %v", err)
+	}
+}
+
+func TestAdjustValidation(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	service := NewTransactionService(fakeTxRunner{}, stubAccountStore{
+		getByIDFn: func(context.Context, string) (store.Account, error) {
+			return store.Account{ID: "sys-usd", Currency: "USD", IsSystem: true}, nil
+		},
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubQuoteStore{}, stubPeriodStore{}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
+	if _, err := service.Adjust(context.Background(), AdjustmentRequest{AccountID: "acc-1"}); err != ErrInvalidAmount {
+		t.Fatalf("expected ErrInvalidAmount, got %v", err)
+	}
+	if _, err := service.Adjust(context.Background(), AdjustmentRequest{AccountID: "acc-1", AmountMinor: 1, EffectiveAt: &future}); err != ErrInvalidEffectiveDate {
+		t.Fatalf("expected ErrInvalidEffectiveDate, got %v", err)
+	}
+	if _, err := service.Adjust(context.Background(), AdjustmentRequest{AccountID: "sys-usd", AmountMinor: 1}); err != ErrInvalidAdjustment {
+		t.Fatalf("expected ErrInvalidAdjustment, got %v", err)
+	}
+}
+
+func TestReverseTransfer(t *testing.T) {
+	balances := map[string]int64{}
+	var ledgerEntries []store.LedgerEntryInput
+	var createdTx store.TransactionInput
+	outbox := &stubOutboxStore{}
+	service := NewTransactionService(fakeTxRunner{}, stubAccountStore{
+		getForUpdateFn: func(_ context.Context, _ store.Getter, accountID string) (store.Account, error) {
+			if accountID == "from" {
+				return store.Account{ID: accountID, UserID: stringPtr("user-1"), Currency: "USD", Balance: 900}, nil
+			}
+			return store.Account{ID: accountID, UserID: stringPtr("user-2"), Currency: "USD", Balance: 300}, nil
+		},
+		updateBalanceFn: func(_ context.Context, _ store.Execer, accountID string, balance int64) error {
+			balances[accountID] = balance
+			return nil
+		},
+	}, stubLedgerStore{
+		entriesFn: func(_ context.Context, _ store.Selecter, transactionID string) ([]store.LedgerEntry, error) {
+			if transactionID != "tx-1" {
+				t.Fatalf("unexpected transaction: %s", transactionID)
+			}
+			return []store.LedgerEntry{
+				{ID: "e-1", AccountID: "from", Amount: -100, Currency: "USD", Description: "Transfer debit"},
+				{ID: "e-2", AccountID: "to", Amount: 100, Currency: "USD", Description: "Transfer credit"},
+			}, nil
+		},
+		insertFn: func(_ context.Context, _ store.Execer, entries []store.LedgerEntryInput) error {
+			ledgerEntries = entries
+			return nil
+		},
+	}, stubTransactionStore{
+		getForReversalFn: func(context.Context, store.Getter, string) (store.TransactionRecord, error) {
+			return store.TransactionRecord{ID: "tx-1", UserID: "user-1", Type: "transfer", Status: "completed", Amount: 100, Currency: "USD", FromAccountID: stringPtr("from"), ToAccountID: stringPtr("to")}, nil
+		},
+		createFn: func(_ context.Context, _ store.Execer, input store.TransactionInput) error {
+			createdTx = input
+			return nil
+		},
+	}, stubExchangeStore{}, stubQuoteStore{}, stubPeriodStore{}, stubAuditStore{}, outbox, stubAlertEvaluator{}, QuoteTTLs{})
+
+	id, err := service.Reverse(context.Background(), ReversalRequest{ActorID: "admin-1", TransactionID: "tx-1", Reason: "sent in error"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id == "" || createdTx.Type != "reversal" || createdTx.ReversalOf == nil || *createdTx.ReversalOf != "tx-1" || *createdTx.FromAccountID != "to" || *createdTx.ToAccountID != "from" {
+		t.Fatalf("unexpected transaction: %#v", createdTx)
+	}
+	if balances["from"] != 1000 || balances["to"] != 200 {
+		t.Fatalf("unexpected balances: %#v", balances)
+	}
+	if len(ledgerEntries) != 2 || ledgerEntries[0].Amount != 100 || ledgerEntries[1].Amount != -100 || ledgerEntries[0].TransactionID != id {
+		t.Fatalf("unexpected ledger entries: %#v", ledgerEntries)
+	}
+	changes := outbox.balanceChanges(t, events.ReversalPosted)
+	if len(changes) != 2 || changes[0].Balance != "10.00" || changes[1].Balance != "2.00" {
+		t.Fatalf("unexpected balance changes: %#v", changes)
+	}
+}
+
+func TestReverseRejectsReversedTransactions(t *testing.T) {
+	cases := []struct {
+		original store.TransactionRecord
+		want     error
+	}{
+		{store.TransactionRecord{ID: "tx-1", Type: "transfer", Status: "completed", ReversedBy: stringPtr("tx-2")}, ErrAlreadyReversed},
+		{store.TransactionRecord{ID: "tx-2", Type: "reversal", Status: "completed"}, ErrNotReversible},
+		{store.TransactionRecord{ID: "tx-3", Type: "transfer", Status: "failed"}, ErrNotReversible},
+	}
+	for _, tc := range cases {
+		original := tc.original
+		service := NewTransactionService(fakeTxRunner{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{
+			getForReversalFn: func(context.Context, store.Getter, string) (store.TransactionRecord, error) {
+				return original, nil
+			},
+		}, stubExchangeStore{}, stubQuoteStore{}, stubPeriodStore{}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
+		if _, err := service.Reverse(context.Background(), ReversalRequest{ActorID: "admin-1", TransactionID: original.ID}); err != tc.want {
+			t.Fatalf("expected %v for %s, got %v", tc.want, original.ID, err)
+		}
+	}
+	service := NewTransactionService(fakeTxRunner{}, stubAccountStore{}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{}, stubQuoteStore{}, stubPeriodStore{}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
+	if _, err := service.Reverse(context.Background(), ReversalRequest{TransactionID: "missing"}); err != ErrTransactionNotFound {
+		t.Fatalf("expected ErrTransactionNotFound, got %v", err)
+	}
+}
+
+func TestExchangeQuoteUsesPairTTLAndInverseRate(t *testing.T) {
+	var input store.ExchangeQuoteInput
+	service := NewTransactionService(fakeTxRunner{}, stubAccountStore{
+		getByIDFn: func(_ context.Context, accountID string) (store.Account, error) {
+			if accountID == "from" {
+				return store.Account{UserID: stringPtr("user-1"), Currency: "EUR"}, nil
+			}
+			return store.Account{Currency: "USD"}, nil
+		},
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{
+		getActiveFn: func(_ context.Context, base, quote string) (map[string]any, error) {
+			if base != "USD" || quote != "EUR" {
+				t.Fatalf("unexpected pair: %s-%s", base, quote)
+			}
+			return map[string]any{"rate": "0.80"}, nil
+		},
+	}, stubQuoteStore{
+		createFn: func(_ context.Context, in store.ExchangeQuoteInput) error {
+			input = in
+			return nil
+		},
+	}, stubPeriodStore{}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{
+		Default: time.Minute,
+		Pairs:   map[string]time.Duration{"EUR-USD": 30 * time.Second},
+	})
+
+	before := time.Now()
+	quote, err := service.QuoteExchange(context.Background(), ExchangeQuoteRequest{
+		UserID: "user-1", FromAccountID: "from", ToAccountID: "to", AmountMinor: 1000,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if input.Rate != "1.250000" || quote.ConvertedMinor != 1250 {
+		t.Fatalf("unexpected rate: %s %d", input.Rate, quote.ConvertedMinor)
+	}
+	if ttl := quote.ExpiresAt.Sub(before); ttl < 29*time.Second || ttl > 31*time.Second {
+		t.Fatalf("unexpected ttl: %s", ttl)
+	}
+}
+
+func TestQuoteTTLsFor(t *testing.T) {
+	ttls := QuoteTTLs{Default: time.Minute, Pairs: map[string]time.Duration{"USD-EUR": 90 * time.Second}}
+	if ttl := ttls.For("USD", "EUR"); ttl != 90*time.Second {
+		t.Fatalf("unexpected pair ttl: %s", ttl)
+	}
+	if ttl := ttls.For("EUR", "USD"); ttl != time.Minute {
+		t.Fatalf("unexpected default ttl: %s", ttl)
+	}
+	if ttl := (QuoteTTLs{}).For("EUR", "USD"); ttl != defaultQuoteTTL {
+		t.Fatalf("unexpected fallback ttl: %s", ttl)
+	}
+}
+
+func TestExchangeQuoteRateNotSet(t *testing.T) {
+	service := NewTransactionService(fakeTxRunner{}, stubAccountStore{
+		getByIDFn: func(_ context.Context, accountID string) (store.Account, error) {
+			if accountID == "from" {
+				return store.Account{UserID: stringPtr("user-1"), Currency: "USD"}, nil
+			}
+			return store.Account{Currency: "EUR"}, nil
+		},
+	}, stubLedgerStore{}, stubTransactionStore{}, stubExchangeStore{
+		getActiveFn: func(context.Context, string, string) (map[string]any, error) { return nil, sql.ErrNoRows },
+	}, stubQuoteStore{}, stubPeriodStore{}, stubAuditStore{}, &stubOutboxStore{}, stubAlertEvaluator{}, QuoteTTLs{})
+
+	_, err := service.QuoteExchange(context.Background(), ExchangeQuoteRequest{
+		UserID: "user-1", FromAccountID: "from", ToAccountID: "to", AmountMinor: 1000,
+	})
+	if err != ErrExchangeRateNotSet {
+		t.Fatalf("expected ErrExchangeRateNotSet, got %v", err)
+	}
+}
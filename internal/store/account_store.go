@@ -1,18 +1,22 @@
 package store
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type AccountStore struct {
 	db DB
 }
 
 type Account struct {
-	ID        string  `db:"id"`
-	UserID    *string `db:"user_id"`
-	Currency  string  `db:"currency"`
-	Balance   int64   `db:"balance"`
-	IsSystem  bool    `db:"is_system"`
-	CreatedAt any     `db:"created_at"`
+	ID        string     `db:"id"`
+	UserID    *string    `db:"user_id"`
+	Currency  string     `db:"currency"`
+	Balance   int64      `db:"balance"`
+	IsSystem  bool       `db:"is_system"`
+	FrozenAt  *time.Time `db:"frozen_at"`
+	CreatedAt any        `db:"created_at"`
 }
 
 type AccountBalanceSummary struct {
@@ -42,8 +46,8 @@ func NewAccountStore(db DB) *AccountStore {
 
 func (s *AccountStore) Create(ctx context.Context, tx Execer, id string, userID *string, currency string, balance int64, isSystem bool) error {
 	query := `
-		INSERT INTO accounts (id, user_id, currency, balance, is_system)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO accounts (id, user_id, currency, balance, is_system, gl_code)
+		VALUES ($1, $2, $3, $4, $5, CASE WHEN $5 THEN '1000' ELSE '2000' END)
 	`
 	_, err := tx.ExecContext(ctx, query, id, userID, currency, balance, isSystem)
 	return err
@@ -56,14 +60,24 @@ func (s *AccountStore) GetByUser(ctx context.Context, userID string) ([]AccountB
 		       a.user_id,
 		       a.currency,
 		       a.balance AS stored_balance,
-		       COALESCE(SUM(l.amount), 0) AS calculated_balance,
-		       (a.balance - COALESCE(SUM(l.amount), 0)) AS difference,
+		       COALESCE(c.balance, 0) + d.amount AS calculated_balance,
+		       (a.balance - COALESCE(c.balance, 0) - d.amount) AS difference,
 		       a.is_system,
 		       a.created_at
 		FROM accounts a
-		LEFT JOIN ledger_entries l ON l.account_id = a.id
+		LEFT JOIN LATERAL (
+			SELECT entry_seq, balance
+			FROM balance_checkpoints
+			WHERE account_id = a.id
+			ORDER BY entry_seq DESC
+			LIMIT 1
+		) c ON TRUE
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(SUM(l.amount), 0) AS amount
+			FROM ledger_entries l
+			WHERE l.account_id = a.id AND l.seq > COALESCE(c.entry_seq, 0)
+		) d
 		WHERE a.user_id = $1
-		GROUP BY a.id, a.user_id, a.currency, a.balance, a.is_system, a.created_at
 		ORDER BY a.currency
 	`, userID)
 	if err != nil {
@@ -75,7 +89,7 @@ func (s *AccountStore) GetByUser(ctx context.Context, userID string) ([]AccountB
 func (s *AccountStore) GetByUserAndCurrency(ctx context.Context, userID, currency string) (Account, error) {
 	var row Account
 	err := s.db.GetContext(ctx, &row, `
-		SELECT id, user_id, currency, balance, is_system, created_at
+		SELECT id, user_id, currency, balance, is_system, frozen_at, created_at
 		FROM accounts
 		WHERE user_id = $1 AND currency = $2
 	`, userID, currency)
@@ -88,7 +102,7 @@ func (s *AccountStore) GetByUserAndCurrency(ctx context.Context, userID, currenc
 func (s *AccountStore) GetByID(ctx context.Context, accountID string) (Account, error) {
 	var row Account
 	err := s.db.GetContext(ctx, &row, `
-		SELECT id, user_id, currency, balance, is_system, created_at
+		SELECT id, user_id, currency, balance, is_system, frozen_at, created_at
 		FROM accounts
 		WHERE id = $1
 	`, accountID)
@@ -101,7 +115,7 @@ func (s *AccountStore) GetByID(ctx context.Context, accountID string) (Account,
 func (s *AccountStore) GetForUpdate(ctx context.Context, tx Getter, accountID string) (Account, error) {
 	var row Account
 	err := tx.GetContext(ctx, &row, `
-		SELECT id, user_id, currency, balance, is_system
+		SELECT id, user_id, currency, balance, is_system, frozen_at
 		FROM accounts
 		WHERE id = $1
 		FOR UPDATE
@@ -133,6 +147,20 @@ func (s *AccountStore) AdjustBalance(ctx context.Context, tx Execer, accountID s
 	return res.RowsAffected()
 }
 
+func (s *AccountStore) SetFrozen(ctx context.Context, tx Execer, accountID string, frozen bool, reason string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		UPDATE accounts
+		SET frozen_at = CASE WHEN $2 THEN NOW() ELSE NULL END,
+		    frozen_reason = CASE WHEN $2 THEN $3 ELSE NULL END,
+		    updated_at = NOW()
+		WHERE id = $1 AND is_system = FALSE AND (frozen_at IS NULL) = $2
+	`, accountID, frozen, reason)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 func (s *AccountStore) GetSystemAccount(ctx context.Context, currency string) (string, error) {
 	var id string
 	err := s.db.GetContext(ctx, &id, `
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+func BenchmarkAccountStoreGetByUser(b *testing.B) {
+	databaseURL := os.Getenv("BENCH_DATABASE_URL")
+	if databaseURL == "" {
+		b.Skip("BENCH_DATABASE_URL not set")
+	}
+	database, err := sqlx.Connect("postgres", databaseURL)
+	if err != nil {
+		b.Fatalf("failed to connect: %v", err)
+	}
+	defer database.Close()
+
+	for _, history := range []int{1000, 10000, 100000} {
+		for _, checkpointed := range []bool{true, false} {
+			name := fmt.Sprintf("entries=%d/checkpoint=%t", history, checkpointed)
+			b.Run(name, func(b *testing.B) {
+				ctx := context.Background()
+				tx, err := database.BeginTxx(ctx, nil)
+				if err != nil {
+					b.Fatalf("failed to begin: %v", err)
+				}
+				defer tx.Rollback()
+				userID := seedLedgerHistory(b, tx, history, checkpointed)
+				accounts := NewAccountStore(tx)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					rows, err := accounts.GetByUser(ctx, userID)
+					if err != nil {
+						b.Fatalf("unexpected error: %v", err)
+					}
+					if len(rows) != 1 || rows[0].Difference != 0 {
+						b.Fatalf("unexpected rows: %#v", rows)
+					}
+				}
+			})
+		}
+	}
+}
+
+func seedLedgerHistory(b *testing.B, tx *sqlx.Tx, history int, checkpointed bool) string {
+	b.Helper()
+	ctx := context.Background()
+	userID := uuid.NewString()
+	accountID := uuid.NewString()
+	transactionID := uuid.NewString()
+	const tail = 10
+	mustExec(b, tx, `INSERT INTO users (id, username, email, password_hash) VALUES ($1, $2, $3, 'x')`,
+		userID, "bench_"+userID[:8], userID[:8]+"@bench.local")
+	mustExec(b, tx, `INSERT INTO accounts (id, user_id, currency, balance) VALUES ($1, $2, 'USD', $3)`,
+		accountID, userID, history+tail)
+	mustExec(b, tx, `
+		INSERT INTO transactions (id, user_id, type, status, amount, currency, to_account_id)
+		VALUES ($1, $2, 'transfer', 'completed', 1, 'USD', $3)
+	`, transactionID, userID, accountID)
+	insertEntries := `
+		INSERT INTO ledger_entries (id, transaction_id, account_id, amount, currency, description)
+		SELECT gen_random_uuid()::text, $1, $2, 1, 'USD', 'Benchmark credit'
+		FROM generate_series(1, $3)
+	`
+	mustExec(b, tx, insertEntries, transactionID, accountID, history)
+	if checkpointed {
+		balances := NewBalanceStore(tx)
+		delta, err := balances.LedgerDeltaSince(ctx, tx, accountID, 0, time.Now().Add(time.Hour))
+		if err != nil {
+			b.Fatalf("failed to compute delta: %v", err)
+		}
+		if err := balances.CreateCheckpoint(ctx, tx, BalanceCheckpointInput{
+			ID:          uuid.NewString(),
+			AccountID:   accountID,
+			EntrySeq:    delta.LastSeq,
+			LastEntryID: delta.LastEntryID,
+			LastEntryAt: delta.LastEntryAt,
+			Balance:     delta.Amount,
+			EntryCount:  delta.EntryCount,
+		}); err != nil {
+			b.Fatalf("failed to create checkpoint: %v", err)
+		}
+	}
+	mustExec(b, tx, insertEntries, transactionID, accountID, tail)
+	mustExec(b, tx, `ANALYZE ledger_entries`)
+	return userID
+}
+
+func mustExec(b *testing.B, tx *sqlx.Tx, query string, args ...any) {
+	b.Helper()
+	if _, err := tx.ExecContext(context.Background(), query, args...); err != nil {
+		b.Fatalf("seed failed: %v", err)
+	}
+}
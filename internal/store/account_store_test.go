@@ -170,6 +170,29 @@ func TestAccountStoreAdjustBalance(t *testing.T) {
 	}
 }
 
+func TestAccountStoreSetFrozen(t *testing.T) {
+	ctx := context.Background()
+	execer := stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "is_system = FALSE") || !strings.Contains(query, "(frozen_at IS NULL) = $2") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 3 || args[0] != "acc-1" || args[1] != true || args[2] != "chargeback" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	}
+	store := NewAccountStore(stubDB{})
+	rows, err := store.SetFrozen(ctx, execer, "acc-1", true, "chargeback")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rows != 1 {
+		t.Fatalf("expected 1 row affected, got %d", rows)
+	}
+}
+
 func TestAccountStoreGetSystemAccount(t *testing.T) {
 	ctx := context.Background()
 	store := NewAccountStore(stubDB{
@@ -3,12 +3,33 @@ package store
 import (
 	"context"
 	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type AdminStore struct {
 	db DB
 }
 
+type Role struct {
+	Name        string         `db:"name"`
+	Description string         `db:"description"`
+	Permissions pq.StringArray `db:"permissions"`
+	CreatedBy   *string        `db:"created_by"`
+	CreatedAt   time.Time      `db:"created_at"`
+}
+
+type AdminSummary struct {
+	UserID      string         `db:"user_id"`
+	Username    string         `db:"username"`
+	Email       string         `db:"email"`
+	IsSuper     bool           `db:"is_super"`
+	Roles       pq.StringArray `db:"roles"`
+	Permissions pq.StringArray `db:"permissions"`
+	CreatedAt   time.Time      `db:"created_at"`
+}
+
 func NewAdminStore(db DB) *AdminStore {
 	return &AdminStore{db: db}
 }
@@ -29,14 +50,18 @@ func (s *AdminStore) IsAdmin(ctx context.Context, userID string) (bool, bool, er
 	return true, isSuper, nil
 }
 
-func (s *AdminStore) HasRole(ctx context.Context, userID, role string) (bool, error) {
-	var count int
-	err := s.db.GetContext(ctx, &count, `
-		SELECT COUNT(1)
-		FROM admin_roles
-		WHERE admin_user_id = $1 AND role = $2
-	`, userID, role)
-	return count > 0, err
+// Super admins are not special-cased here.
+func (s *AdminStore) Permissions(ctx context.Context, userID string) ([]string, error) {
+	var permissions []string
+	err := s.db.SelectContext(ctx, &permissions, `
+		SELECT DISTINCT permission
+		FROM admin_roles ar
+		JOIN roles r ON r.name = ar.role
+		CROSS JOIN LATERAL unnest(r.permissions) AS permission
+		WHERE ar.admin_user_id = $1
+		ORDER BY permission
+	`, userID)
+	return permissions, err
 }
 
 func (s *AdminStore) CreateAdmin(ctx context.Context, tx Execer, userID string, isSuper bool, createdBy *string) error {
@@ -47,15 +72,108 @@ func (s *AdminStore) CreateAdmin(ctx context.Context, tx Execer, userID string,
 	return err
 }
 
-func (s *AdminStore) GrantRole(ctx context.Context, tx Execer, adminUserID, role string) error {
+func (s *AdminStore) RemoveAdmin(ctx context.Context, tx Execer, userID string) (int64, error) {
+	result, err := tx.ExecContext(ctx, `
+		WITH dropped AS (
+			DELETE FROM admin_roles
+			WHERE admin_user_id = $1
+		)
+		DELETE FROM admins
+		WHERE user_id = $1 AND is_super = FALSE
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *AdminStore) GrantRole(ctx context.Context, tx Execer, adminUserID, role, grantedBy string) error {
 	_, err := tx.ExecContext(ctx, `
-		INSERT INTO admin_roles (admin_user_id, role)
-		VALUES ($1, $2)
+		INSERT INTO admin_roles (admin_user_id, role, granted_by)
+		VALUES ($1, $2, $3)
 		ON CONFLICT DO NOTHING
-	`, adminUserID, role)
+	`, adminUserID, role, grantedBy)
 	return err
 }
 
+func (s *AdminStore) RevokeRole(ctx context.Context, tx Execer, adminUserID, role string) (int64, error) {
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM admin_roles
+		WHERE admin_user_id = $1 AND role = $2
+	`, adminUserID, role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *AdminStore) CreateRole(ctx context.Context, tx Execer, role Role) (int64, error) {
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO roles (name, description, permissions, created_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO NOTHING
+	`, role.Name, role.Description, role.Permissions, role.CreatedBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *AdminStore) GetRole(ctx context.Context, name string) (Role, error) {
+	var role Role
+	err := s.db.GetContext(ctx, &role, `
+		SELECT name, description, permissions, created_by, created_at
+		FROM roles
+		WHERE name = $1
+	`, name)
+	return role, err
+}
+
+func (s *AdminStore) ListRoles(ctx context.Context) ([]Role, error) {
+	var roles []Role
+	err := s.db.SelectContext(ctx, &roles, `
+		SELECT name, description, permissions, created_by, created_at
+		FROM roles
+		ORDER BY name
+	`)
+	return roles, err
+}
+
+func (s *AdminStore) ListAdmins(ctx context.Context) ([]AdminSummary, error) {
+	var admins []AdminSummary
+	err := s.db.SelectContext(ctx, &admins, `
+		SELECT a.user_id, u.username, u.email, a.is_super, a.created_at,
+		       COALESCE(array_agg(DISTINCT ar.role) FILTER (WHERE ar.role IS NOT NULL), '{}') AS roles,
+		       COALESCE(array_agg(DISTINCT p.permission) FILTER (WHERE p.permission IS NOT NULL), '{}') AS permissions
+		FROM admins a
+		JOIN users u ON u.id = a.user_id
+		LEFT JOIN admin_roles ar ON ar.admin_user_id = a.user_id
+		LEFT JOIN roles r ON r.name = ar.role
+		LEFT JOIN LATERAL unnest(r.permissions) AS p(permission) ON TRUE
+		GROUP BY a.user_id, u.username, u.email, a.is_super, a.created_at
+		ORDER BY a.created_at
+	`)
+	return admins, err
+}
+
+func (s *AdminStore) HasOtherApprover(ctx context.Context, userID, permission string) (bool, error) {
+	var exists bool
+	err := s.db.GetContext(ctx, &exists, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM admins a
+			WHERE a.user_id <> $1
+			  AND (a.is_super OR EXISTS (
+				SELECT 1
+				FROM admin_roles ar
+				JOIN roles r ON r.name = ar.role
+				WHERE ar.admin_user_id = a.user_id AND $2 = ANY(r.permissions)
+			  ))
+		)
+	`, userID, permission)
+	return exists, err
+}
+
 func (s *AdminStore) HasAnyAdmin(ctx context.Context) (bool, error) {
 	var count int
 	err := s.db.GetContext(ctx, &count, `SELECT COUNT(1) FROM admins`)
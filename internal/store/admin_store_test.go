@@ -43,23 +43,23 @@ func TestAdminStoreIsAdmin(t *testing.T) {
 	}
 }
 
-func TestAdminStoreHasRole(t *testing.T) {
+func TestAdminStorePermissions(t *testing.T) {
 	ctx := context.Background()
 	store := NewAdminStore(stubDB{
-		getFn: func(_ context.Context, dest any, query string, args ...any) error {
-			if !strings.Contains(query, "FROM admin_roles") {
-				t.Fatalf("unexpected query: %s", query)
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "JOIN roles r ON r.name = ar.role") || args[0] != "user-1" {
+				t.Fatalf("unexpected query: %s %#v", query, args)
 			}
-			*dest.(*int) = 1
+			*dest.(*[]string) = []string{"CanViewReports"}
 			return nil
 		},
 	})
-	hasRole, err := store.HasRole(ctx, "user-1", "role")
+	permissions, err := store.Permissions(ctx, "user-1")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if !hasRole {
-		t.Fatalf("expected role to be granted")
+	if len(permissions) != 1 || permissions[0] != "CanViewReports" {
+		t.Fatalf("unexpected permissions: %v", permissions)
 	}
 }
 
@@ -89,14 +89,14 @@ func TestAdminStoreGrantRole(t *testing.T) {
 			if !strings.Contains(query, "INSERT INTO admin_roles") {
 				t.Fatalf("unexpected query: %s", query)
 			}
-			if len(args) != 2 || args[0] != "user-1" || args[1] != "role" {
+			if len(args) != 3 || args[0] != "user-1" || args[1] != "role" || args[2] != "admin-1" {
 				t.Fatalf("unexpected args: %#v", args)
 			}
 			return stubResult{rows: 1}, nil
 		},
 	}
 	store := NewAdminStore(stubDB{})
-	if err := store.GrantRole(ctx, execer, "user-1", "role"); err != nil {
+	if err := store.GrantRole(ctx, execer, "user-1", "role", "admin-1"); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
@@ -120,3 +120,68 @@ func TestAdminStoreHasAnyAdmin(t *testing.T) {
 		t.Fatalf("expected admins to exist")
 	}
 }
+
+func TestAdminStoreRemoveAdminKeepsSuperAdmins(t *testing.T) {
+	store := NewAdminStore(stubDB{})
+	rows, err := store.RemoveAdmin(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "DELETE FROM admin_roles") || !strings.Contains(query, "is_super = FALSE") || args[0] != "user-1" {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			return stubResult{rows: 0}, nil
+		},
+	}, "user-1")
+	if err != nil || rows != 0 {
+		t.Fatalf("unexpected result: %d %v", rows, err)
+	}
+}
+
+func TestAdminStoreCreateRoleReportsConflict(t *testing.T) {
+	store := NewAdminStore(stubDB{})
+	rows, err := store.CreateRole(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "ON CONFLICT (name) DO NOTHING") || args[0] != "auditor" {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			return stubResult{rows: 0}, nil
+		},
+	}, Role{Name: "auditor", Permissions: []string{"CanViewReports"}})
+	if err != nil || rows != 0 {
+		t.Fatalf("unexpected result: %d %v", rows, err)
+	}
+}
+
+func TestAdminStoreListAdmins(t *testing.T) {
+	store := NewAdminStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "FROM admins a") || !strings.Contains(query, "unnest(r.permissions)") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			*dest.(*[]AdminSummary) = []AdminSummary{{UserID: "user-1", Roles: []string{"viewer"}}}
+			return nil
+		},
+	})
+	admins, err := store.ListAdmins(context.Background())
+	if err != nil || len(admins) != 1 || admins[0].Roles[0] != "viewer" {
+		t.Fatalf("unexpected result: %#v %v", admins, err)
+	}
+}
+
+func TestAdminStoreHasOtherApprover(t *testing.T) {
+	store := NewAdminStore(stubDB{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "a.user_id <> $1") || !strings.Contains(query, "a.is_super OR") || !strings.Contains(query, "$2 = ANY(r.permissions)") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != "admin-1" || args[1] != "CanApproveAdminChanges" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*bool) = true
+			return nil
+		},
+	})
+	exists, err := store.HasOtherApprover(context.Background(), "admin-1", "CanApproveAdminChanges")
+	if err != nil || !exists {
+		t.Fatalf("expected another approver, got %v %v", exists, err)
+	}
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type AlertRuleStore struct {
+	db DB
+}
+
+type AlertRule struct {
+	ID              string     `db:"id"`
+	UserID          string     `db:"user_id"`
+	Kind            string     `db:"kind"`
+	Currency        string     `db:"currency"`
+	Threshold       int64      `db:"threshold"`
+	Active          bool       `db:"active"`
+	LastTriggeredAt *time.Time `db:"last_triggered_at"`
+	SuppressedCount int        `db:"suppressed_count"`
+	CreatedAt       time.Time  `db:"created_at"`
+}
+
+type AlertRuleInput struct {
+	ID        string
+	UserID    string
+	Kind      string
+	Currency  string
+	Threshold int64
+}
+
+type AlertClaim struct {
+	Ready      bool `db:"ready"`
+	Suppressed int  `db:"suppressed_count"`
+}
+
+const alertRuleColumns = `id, user_id, kind, currency, threshold, active, last_triggered_at, suppressed_count, created_at`
+
+func NewAlertRuleStore(db DB) *AlertRuleStore {
+	return &AlertRuleStore{db: db}
+}
+
+func (s *AlertRuleStore) Create(ctx context.Context, input AlertRuleInput) (AlertRule, error) {
+	var row AlertRule
+	err := s.db.GetContext(ctx, &row, `
+		INSERT INTO alert_rules (id, user_id, kind, currency, threshold)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+alertRuleColumns+`
+	`, input.ID, input.UserID, input.Kind, input.Currency, input.Threshold)
+	if err != nil {
+		return AlertRule{}, err
+	}
+	return row, nil
+}
+
+func (s *AlertRuleStore) ListByUser(ctx context.Context, userID string) ([]AlertRule, error) {
+	var rows []AlertRule
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT `+alertRuleColumns+`
+		FROM alert_rules
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *AlertRuleStore) Delete(ctx context.Context, userID, ruleID string) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM alert_rules WHERE id = $1 AND user_id = $2`, ruleID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *AlertRuleStore) ActiveForUsers(ctx context.Context, tx Selecter, userIDs []string) ([]AlertRule, error) {
+	var rows []AlertRule
+	err := tx.SelectContext(ctx, &rows, `
+		SELECT `+alertRuleColumns+`
+		FROM alert_rules
+		WHERE active = TRUE AND user_id = ANY($1)
+		ORDER BY created_at
+	`, pq.StringArray(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *AlertRuleStore) Claim(ctx context.Context, tx Getter, ruleID string, cooldown time.Duration) (AlertClaim, error) {
+	var claim AlertClaim
+	err := tx.GetContext(ctx, &claim, `
+		WITH current AS (
+			SELECT id, suppressed_count,
+			       (last_triggered_at IS NULL OR last_triggered_at <= NOW() - make_interval(secs => $2)) AS ready
+			FROM alert_rules
+			WHERE id = $1
+			FOR UPDATE
+		)
+		UPDATE alert_rules r
+		SET last_triggered_at = CASE WHEN c.ready THEN NOW() ELSE r.last_triggered_at END,
+		    suppressed_count = CASE WHEN c.ready THEN 0 ELSE r.suppressed_count + 1 END
+		FROM current c
+		WHERE r.id = c.id
+		RETURNING c.ready, c.suppressed_count
+	`, ruleID, cooldown.Seconds())
+	return claim, err
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestAlertRuleStoreCreate(t *testing.T) {
+	store := NewAlertRuleStore(stubDB{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "INSERT INTO alert_rules") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 5 || args[2] != "low_balance" || args[3] != "USD" || args[4] != int64(10000) {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*AlertRule) = AlertRule{ID: "rule-1", Kind: "low_balance"}
+			return nil
+		},
+	})
+	rule, err := store.Create(context.Background(), AlertRuleInput{ID: "rule-1", UserID: "user-1", Kind: "low_balance", Currency: "USD", Threshold: 10000})
+	if err != nil || rule.ID != "rule-1" {
+		t.Fatalf("unexpected result: %#v %v", rule, err)
+	}
+}
+
+func TestAlertRuleStoreDeleteIsScopedToOwner(t *testing.T) {
+	store := NewAlertRuleStore(stubDB{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "WHERE id = $1 AND user_id = $2") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != "rule-1" || args[1] != "user-1" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	})
+	rows, err := store.Delete(context.Background(), "user-1", "rule-1")
+	if err != nil || rows != 1 {
+		t.Fatalf("unexpected result: %d %v", rows, err)
+	}
+}
+
+func TestAlertRuleStoreActiveForUsers(t *testing.T) {
+	tx := stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "active = TRUE AND user_id = ANY($1)") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			users, ok := args[0].(pq.StringArray)
+			if !ok || len(users) != 2 {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*[]AlertRule) = []AlertRule{{ID: "rule-1"}}
+			return nil
+		},
+	}
+	rows, err := NewAlertRuleStore(stubDB{}).ActiveForUsers(context.Background(), tx, []string{"user-1", "user-2"})
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("unexpected result: %#v %v", rows, err)
+	}
+}
+
+func TestAlertRuleStoreClaim(t *testing.T) {
+	tx := stubGetter{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "FOR UPDATE") || !strings.Contains(query, "suppressed_count + 1") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != "rule-1" || args[1] != float64(300) {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*AlertClaim) = AlertClaim{Ready: true, Suppressed: 3}
+			return nil
+		},
+	}
+	claim, err := NewAlertRuleStore(stubDB{}).Claim(context.Background(), tx, "rule-1", 5*time.Minute)
+	if err != nil || !claim.Ready || claim.Suppressed != 3 {
+		t.Fatalf("unexpected result: %#v %v", claim, err)
+	}
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type APIKeyStore struct {
+	db DB
+}
+
+type APIKey struct {
+	ID             string         `db:"id"`
+	UserID         string         `db:"user_id"`
+	OrganizationID *string        `db:"organization_id"`
+	Name           string         `db:"name"`
+	Prefix         string         `db:"prefix"`
+	Scopes         pq.StringArray `db:"scopes"`
+	AllowedIPs     pq.StringArray `db:"allowed_ips"`
+	ExpiresAt      *time.Time     `db:"expires_at"`
+	LastUsedAt     *time.Time     `db:"last_used_at"`
+	LastUsedIP     string         `db:"last_used_ip"`
+	CreatedAt      time.Time      `db:"created_at"`
+}
+
+type APIKeyInput struct {
+	ID             string
+	UserID         string
+	OrganizationID *string
+	Name           string
+	Prefix         string
+	KeyHash        string
+	Scopes         []string
+	AllowedIPs     []string
+	ExpiresAt      *time.Time
+}
+
+const apiKeyColumns = `id, user_id, organization_id, name, prefix, scopes, allowed_ips, expires_at, last_used_at, last_used_ip, created_at`
+
+func NewAPIKeyStore(db DB) *APIKeyStore {
+	return &APIKeyStore{db: db}
+}
+
+func (s *APIKeyStore) Create(ctx context.Context, tx Execer, input APIKeyInput) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO api_keys (id, user_id, organization_id, name, prefix, key_hash, scopes, allowed_ips, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, input.ID, input.UserID, input.OrganizationID, input.Name, input.Prefix, input.KeyHash, pq.StringArray(input.Scopes), pq.StringArray(input.AllowedIPs), input.ExpiresAt)
+	return err
+}
+
+func (s *APIKeyStore) ListByUser(ctx context.Context, userID string) ([]APIKey, error) {
+	var keys []APIKey
+	err := s.db.SelectContext(ctx, &keys, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE user_id = $1 AND organization_id IS NULL AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`, userID)
+	return keys, err
+}
+
+func (s *APIKeyStore) ListByOrganization(ctx context.Context, organizationID string) ([]APIKey, error) {
+	var keys []APIKey
+	err := s.db.SelectContext(ctx, &keys, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE organization_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`, organizationID)
+	return keys, err
+}
+
+// An organization key stops matching once its creator leaves the
+// organization.
+func (s *APIKeyStore) GetActiveByHash(ctx context.Context, keyHash string) (APIKey, error) {
+	var key APIKey
+	err := s.db.GetContext(ctx, &key, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		  AND (organization_id IS NULL OR EXISTS (
+			SELECT 1 FROM organization_members m
+			WHERE m.organization_id = api_keys.organization_id AND m.user_id = api_keys.user_id
+		  ))
+	`, keyHash)
+	return key, err
+}
+
+func (s *APIKeyStore) Revoke(ctx context.Context, tx Execer, userID, keyID string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND organization_id IS NULL AND revoked_at IS NULL
+	`, keyID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// A non-empty createdBy limits the revocation to keys that user created.
+func (s *APIKeyStore) RevokeForOrganization(ctx context.Context, tx Execer, organizationID, keyID, createdBy string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND organization_id = $2 AND ($3 = '' OR user_id = $3) AND revoked_at IS NULL
+	`, keyID, organizationID, createdBy)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *APIKeyStore) RevokeMemberKeys(ctx context.Context, tx Execer, organizationID, userID string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE organization_id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, organizationID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Touch only moves the time once a minute unless the address changes, so busy
+// keys don't write on every request.
+func (s *APIKeyStore) Touch(ctx context.Context, keyID, ip string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $2
+		WHERE id = $1
+		  AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute' OR last_used_ip <> $2)
+	`, keyID, ip)
+	return err
+}
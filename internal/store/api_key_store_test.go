@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestAPIKeyStoreCreate(t *testing.T) {
+	store := NewAPIKeyStore(stubDB{})
+	err := store.Create(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "INSERT INTO api_keys") || len(args) != 9 || args[5] != "hash" {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			scopes, ok := args[6].(pq.StringArray)
+			if !ok || len(scopes) != 1 || scopes[0] != "read:accounts" {
+				t.Fatalf("unexpected scopes: %#v", args[6])
+			}
+			return stubResult{rows: 1}, nil
+		},
+	}, APIKeyInput{ID: "key-1", UserID: "user-1", Name: "payroll", Prefix: "bk_12345678", KeyHash: "hash", Scopes: []string{"read:accounts"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestAPIKeyStoreGetActiveByHash(t *testing.T) {
+	store := NewAPIKeyStore(stubDB{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())") || !strings.Contains(query, "FROM organization_members m") || args[0] != "hash" {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			*dest.(*APIKey) = APIKey{ID: "key-1", UserID: "user-1"}
+			return nil
+		},
+	})
+	key, err := store.GetActiveByHash(context.Background(), "hash")
+	if err != nil || key.ID != "key-1" {
+		t.Fatalf("unexpected result: %#v %v", key, err)
+	}
+}
+
+func TestAPIKeyStoreRevoke(t *testing.T) {
+	store := NewAPIKeyStore(stubDB{})
+	rows, err := store.Revoke(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "WHERE id = $1 AND user_id = $2 AND organization_id IS NULL AND revoked_at IS NULL") || args[0] != "key-1" || args[1] != "user-1" {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			return stubResult{rows: 0}, nil
+		},
+	}, "user-1", "key-1")
+	if err != nil || rows != 0 {
+		t.Fatalf("unexpected result: %d %v", rows, err)
+	}
+}
+
+func TestAPIKeyStoreRevokeForOrganization(t *testing.T) {
+	store := NewAPIKeyStore(stubDB{})
+	rows, err := store.RevokeForOrganization(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "organization_id = $2 AND ($3 = '' OR user_id = $3)") || args[0] != "key-1" || args[1] != "org-1" || args[2] != "user-1" {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	}, "org-1", "key-1", "user-1")
+	if err != nil || rows != 1 {
+		t.Fatalf("unexpected result: %d %v", rows, err)
+	}
+}
+
+func TestAPIKeyStoreTouchIsThrottled(t *testing.T) {
+	store := NewAPIKeyStore(stubDB{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "last_used_at < NOW() - INTERVAL '1 minute'") || args[0] != "key-1" || args[1] != "10.0.0.1" {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	})
+	if err := store.Touch(context.Background(), "key-1", "10.0.0.1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type ApprovalStore struct {
+	db DB
+}
+
+type Approval struct {
+	ID             string     `db:"id"`
+	Action         string     `db:"action"`
+	Payload        string     `db:"payload"`
+	RequestedBy    string     `db:"requested_by"`
+	Status         string     `db:"status"`
+	DecidedBy      *string    `db:"decided_by"`
+	DecidedAt      *time.Time `db:"decided_at"`
+	DecisionReason string     `db:"decision_reason"`
+	Result         *string    `db:"result"`
+	Error          string     `db:"error"`
+	ExpiresAt      time.Time  `db:"expires_at"`
+	CreatedAt      time.Time  `db:"created_at"`
+}
+
+type ApprovalInput struct {
+	ID          string
+	Action      string
+	Payload     string
+	RequestedBy string
+	ExpiresAt   time.Time
+}
+
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+	ApprovalExpired  = "expired"
+	ApprovalExecuted = "executed"
+	ApprovalFailed   = "failed"
+)
+
+const approvalColumns = `id, action, payload::text AS payload, requested_by, status, decided_by, decided_at,
+	decision_reason, result::text AS result, error, expires_at, created_at`
+
+func NewApprovalStore(db DB) *ApprovalStore {
+	return &ApprovalStore{db: db}
+}
+
+func (s *ApprovalStore) Create(ctx context.Context, tx Execer, input ApprovalInput) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO approval_requests (id, action, payload, requested_by, expires_at)
+		VALUES ($1, $2, $3::jsonb, $4, $5)
+	`, input.ID, input.Action, input.Payload, input.RequestedBy, input.ExpiresAt)
+	return err
+}
+
+func (s *ApprovalStore) Get(ctx context.Context, id string) (Approval, error) {
+	var approval Approval
+	err := s.db.GetContext(ctx, &approval, `
+		SELECT `+approvalColumns+`
+		FROM approval_requests
+		WHERE id = $1
+	`, id)
+	return approval, err
+}
+
+func (s *ApprovalStore) List(ctx context.Context, status string, limit, offset int) ([]Approval, error) {
+	var approvals []Approval
+	err := s.db.SelectContext(ctx, &approvals, `
+		SELECT `+approvalColumns+`
+		FROM approval_requests
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, status, limit, offset)
+	return approvals, err
+}
+
+func (s *ApprovalStore) ListByRequester(ctx context.Context, userID string, limit, offset int) ([]Approval, error) {
+	var approvals []Approval
+	err := s.db.SelectContext(ctx, &approvals, `
+		SELECT `+approvalColumns+`
+		FROM approval_requests
+		WHERE requested_by = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+	return approvals, err
+}
+
+// Decide is a single conditional update, so concurrent approvals decide a
+// request once; the loser sees 0 rows.
+func (s *ApprovalStore) Decide(ctx context.Context, tx Execer, id, deciderID, status, reason string) (int64, error) {
+	result, err := tx.ExecContext(ctx, `
+		UPDATE approval_requests
+		SET status = $3, decided_by = $2, decided_at = NOW(), decision_reason = $4
+		WHERE id = $1 AND status = 'pending' AND expires_at > NOW() AND requested_by <> $2
+	`, id, deciderID, status, reason)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *ApprovalStore) Complete(ctx context.Context, tx Execer, id, status, result, errMessage string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE approval_requests
+		SET status = $2, result = NULLIF($3, '')::jsonb, error = $4
+		WHERE id = $1 AND status = 'approved'
+	`, id, status, result, errMessage)
+	return err
+}
+
+func (s *ApprovalStore) ExpirePending(ctx context.Context, tx Selecter) ([]string, error) {
+	var ids []string
+	err := tx.SelectContext(ctx, &ids, `
+		UPDATE approval_requests
+		SET status = 'expired'
+		WHERE status = 'pending' AND expires_at <= NOW()
+		RETURNING id
+	`)
+	return ids, err
+}
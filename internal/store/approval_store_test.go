@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApprovalStoreCreate(t *testing.T) {
+	store := NewApprovalStore(stubDB{})
+	err := store.Create(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "INSERT INTO approval_requests") || len(args) != 5 || args[1] != "promote_admin" || args[3] != "admin-1" {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	}, ApprovalInput{ID: "apr-1", Action: "promote_admin", Payload: `{}`, RequestedBy: "admin-1", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestApprovalStoreDecideGuardsRequester(t *testing.T) {
+	store := NewApprovalStore(stubDB{})
+	rows, err := store.Decide(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "status = 'pending' AND expires_at > NOW() AND requested_by <> $2") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if args[0] != "apr-1" || args[1] != "admin-2" || args[2] != ApprovalRejected || args[3] != "not needed" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 0}, nil
+		},
+	}, "apr-1", "admin-2", ApprovalRejected, "not needed")
+	if err != nil || rows != 0 {
+		t.Fatalf("unexpected result: %d %v", rows, err)
+	}
+}
+
+func TestApprovalStoreExpirePending(t *testing.T) {
+	store := NewApprovalStore(stubDB{})
+	ids, err := store.ExpirePending(context.Background(), stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "SET status = 'expired'") || !strings.Contains(query, "RETURNING id") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			*dest.(*[]string) = []string{"apr-1"}
+			return nil
+		},
+	})
+	if err != nil || len(ids) != 1 || ids[0] != "apr-1" {
+		t.Fatalf("unexpected result: %v %v", ids, err)
+	}
+}
+
+func TestApprovalStoreList(t *testing.T) {
+	store := NewApprovalStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "WHERE $1 = '' OR status = $1") || args[0] != ApprovalPending || args[1] != 20 || args[2] != 40 {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			return nil
+		},
+	})
+	if _, err := store.List(context.Background(), ApprovalPending, 20, 40); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -23,7 +23,7 @@ func NewAuditStore(db DB) *AuditStore {
 func (s *AuditStore) Log(ctx context.Context, tx Execer, actorID, action, entityType, entityID, data string) error {
 	_, err := tx.ExecContext(ctx, `
 		INSERT INTO audit_logs (id, actor_user_id, action, entity_type, entity_id, data)
-		VALUES (gen_random_uuid()::text, $1, $2, $3, $4, $5)
+		VALUES (gen_random_uuid()::text, NULLIF($1, ''), $2, $3, $4, $5)
 	`, actorID, action, entityType, entityID, data)
 	return err
 }
@@ -0,0 +1,194 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type BalanceStore struct {
+	db DB
+}
+
+type BalanceCheckpoint struct {
+	ID          string     `db:"id"`
+	AccountID   string     `db:"account_id"`
+	EntrySeq    int64      `db:"entry_seq"`
+	LastEntryID *string    `db:"last_entry_id"`
+	LastEntryAt *time.Time `db:"last_entry_at"`
+	Balance     int64      `db:"balance"`
+	EntryCount  int64      `db:"entry_count"`
+	CreatedAt   time.Time  `db:"created_at"`
+}
+
+type BalanceCheckpointInput struct {
+	ID          string
+	AccountID   string
+	EntrySeq    int64
+	LastEntryID *string
+	LastEntryAt *time.Time
+	Balance     int64
+	EntryCount  int64
+}
+
+type LedgerDelta struct {
+	Amount      int64      `db:"amount"`
+	EntryCount  int64      `db:"entry_count"`
+	LastSeq     int64      `db:"last_seq"`
+	LastEntryID *string    `db:"last_entry_id"`
+	LastEntryAt *time.Time `db:"last_entry_at"`
+}
+
+func NewBalanceStore(db DB) *BalanceStore {
+	return &BalanceStore{db: db}
+}
+
+func (s *BalanceStore) ListCheckpointCandidates(ctx context.Context, cutoff time.Time, minEntries int64, limit int) ([]string, error) {
+	var ids []string
+	err := s.db.SelectContext(ctx, &ids, `
+		SELECT a.id
+		FROM accounts a
+		LEFT JOIN LATERAL (
+			SELECT c.entry_seq
+			FROM balance_checkpoints c
+			WHERE c.account_id = a.id
+			ORDER BY c.entry_seq DESC
+			LIMIT 1
+		) c ON TRUE
+		WHERE (
+			SELECT COUNT(1)
+			FROM (
+				SELECT 1
+				FROM ledger_entries l
+				WHERE l.account_id = a.id
+				  AND l.seq > COALESCE(c.entry_seq, 0)
+				  AND l.created_at <= $1
+				LIMIT $2
+			) pending
+		) >= $2
+		ORDER BY a.id
+		LIMIT $3
+	`, cutoff, minEntries, limit)
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (s *BalanceStore) LatestCheckpoint(ctx context.Context, tx Getter, accountID string) (BalanceCheckpoint, error) {
+	var row BalanceCheckpoint
+	err := tx.GetContext(ctx, &row, `
+		SELECT id, account_id, entry_seq, last_entry_id, last_entry_at, balance, entry_count, created_at
+		FROM balance_checkpoints
+		WHERE account_id = $1
+		ORDER BY entry_seq DESC
+		LIMIT 1
+	`, accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return BalanceCheckpoint{AccountID: accountID}, nil
+		}
+		return BalanceCheckpoint{}, err
+	}
+	return row, nil
+}
+
+func (s *BalanceStore) LedgerDeltaSince(ctx context.Context, tx Getter, accountID string, afterSeq int64, cutoff time.Time) (LedgerDelta, error) {
+	var row LedgerDelta
+	err := tx.GetContext(ctx, &row, `
+		WITH bound AS (
+			SELECT MIN(seq) AS seq
+			FROM ledger_entries
+			WHERE account_id = $1 AND seq > $2 AND created_at > $3
+		),
+		delta AS (
+			SELECT l.id, l.seq, l.amount, l.created_at
+			FROM ledger_entries l, bound b
+			WHERE l.account_id = $1
+			  AND l.seq > $2
+			  AND (b.seq IS NULL OR l.seq < b.seq)
+		)
+		SELECT COALESCE(SUM(amount), 0) AS amount,
+		       COUNT(1) AS entry_count,
+		       COALESCE(MAX(seq), 0) AS last_seq,
+		       (SELECT id FROM delta ORDER BY seq DESC LIMIT 1) AS last_entry_id,
+		       MAX(created_at) AS last_entry_at
+		FROM delta
+	`, accountID, afterSeq, cutoff)
+	if err != nil {
+		return LedgerDelta{}, err
+	}
+	return row, nil
+}
+
+func (s *BalanceStore) CreateCheckpoint(ctx context.Context, tx Execer, input BalanceCheckpointInput) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO balance_checkpoints (id, account_id, entry_seq, last_entry_id, last_entry_at, balance, entry_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, input.ID, input.AccountID, input.EntrySeq, input.LastEntryID, input.LastEntryAt, input.Balance, input.EntryCount)
+	return err
+}
+
+type BalanceAsOf struct {
+	AccountID    string  `db:"account_id"`
+	UserID       *string `db:"user_id"`
+	Currency     string  `db:"currency"`
+	IsSystem     bool    `db:"is_system"`
+	Balance      int64   `db:"balance"`
+	LastEntryID  *string `db:"last_entry_id"`
+	LastEntrySeq int64   `db:"last_entry_seq"`
+}
+
+const balanceAsOfQuery = `
+	SELECT a.id AS account_id,
+	       a.user_id,
+	       a.currency,
+	       a.is_system,
+	       COALESCE(c.balance, 0) + d.amount AS balance,
+	       COALESCE(d.last_entry_id, c.last_entry_id) AS last_entry_id,
+	       GREATEST(COALESCE(c.entry_seq, 0), d.last_seq) AS last_entry_seq
+	FROM accounts a
+	LEFT JOIN LATERAL (
+		SELECT entry_seq, balance, last_entry_id
+		FROM balance_checkpoints
+		WHERE account_id = a.id AND last_entry_at <= $1
+		ORDER BY entry_seq DESC
+		LIMIT 1
+	) c ON TRUE
+	CROSS JOIN LATERAL (
+		SELECT COALESCE(SUM(l.amount), 0) AS amount,
+		       COALESCE(MAX(l.seq), 0) AS last_seq,
+		       (
+		           SELECT x.id
+		           FROM ledger_entries x
+		           WHERE x.account_id = a.id AND x.seq > COALESCE(c.entry_seq, 0) AND x.created_at <= $1
+		           ORDER BY x.seq DESC
+		           LIMIT 1
+		       ) AS last_entry_id
+		FROM ledger_entries l
+		WHERE l.account_id = a.id AND l.seq > COALESCE(c.entry_seq, 0) AND l.created_at <= $1
+	) d
+`
+
+func (s *BalanceStore) BalanceAsOf(ctx context.Context, accountID string, asOf time.Time) (BalanceAsOf, error) {
+	var row BalanceAsOf
+	err := s.db.GetContext(ctx, &row, balanceAsOfQuery+`
+		WHERE a.id = $2
+	`, asOf, accountID)
+	if err != nil {
+		return BalanceAsOf{}, err
+	}
+	return row, nil
+}
+
+func (s *BalanceStore) BalancesAsOf(ctx context.Context, asOf time.Time) ([]BalanceAsOf, error) {
+	var rows []BalanceAsOf
+	err := s.db.SelectContext(ctx, &rows, balanceAsOfQuery+`
+		WHERE a.created_at <= $1
+		ORDER BY a.is_system DESC, a.user_id, a.currency
+	`, asOf)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBalanceStoreListCheckpointCandidates(t *testing.T) {
+	ctx := context.Background()
+	cutoff := time.Now().UTC()
+	store := NewBalanceStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "FROM balance_checkpoints") || !strings.Contains(query, "l.seq > COALESCE(c.entry_seq, 0)") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 3 || args[0] != cutoff || args[1] != int64(100) || args[2] != 50 {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*[]string) = []string{"acc-1", "acc-2"}
+			return nil
+		},
+	})
+	ids, err := store.ListCheckpointCandidates(ctx, cutoff, 100, 50)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != "acc-1" {
+		t.Fatalf("unexpected ids: %#v", ids)
+	}
+}
+
+func TestBalanceStoreLatestCheckpointNoRows(t *testing.T) {
+	ctx := context.Background()
+	store := NewBalanceStore(stubDB{})
+	row, err := store.LatestCheckpoint(ctx, stubGetter{
+		getFn: func(context.Context, any, string, ...any) error {
+			return sql.ErrNoRows
+		},
+	}, "acc-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if row.AccountID != "acc-1" || row.EntrySeq != 0 || row.Balance != 0 {
+		t.Fatalf("expected empty checkpoint, got %#v", row)
+	}
+}
+
+func TestBalanceStoreLatestCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	store := NewBalanceStore(stubDB{})
+	row, err := store.LatestCheckpoint(ctx, stubGetter{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "ORDER BY entry_seq DESC") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 1 || args[0] != "acc-1" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*BalanceCheckpoint) = BalanceCheckpoint{AccountID: "acc-1", EntrySeq: 42, Balance: 1500}
+			return nil
+		},
+	}, "acc-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if row.EntrySeq != 42 || row.Balance != 1500 {
+		t.Fatalf("unexpected checkpoint: %#v", row)
+	}
+}
+
+func TestBalanceStoreLedgerDeltaSince(t *testing.T) {
+	ctx := context.Background()
+	cutoff := time.Now().UTC()
+	store := NewBalanceStore(stubDB{})
+	delta, err := store.LedgerDeltaSince(ctx, stubGetter{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "created_at > $3") || !strings.Contains(query, "l.seq < b.seq") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 3 || args[0] != "acc-1" || args[1] != int64(42) || args[2] != cutoff {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*LedgerDelta) = LedgerDelta{Amount: -200, EntryCount: 3, LastSeq: 57}
+			return nil
+		},
+	}, "acc-1", 42, cutoff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if delta.Amount != -200 || delta.EntryCount != 3 || delta.LastSeq != 57 {
+		t.Fatalf("unexpected delta: %#v", delta)
+	}
+}
+
+func TestBalanceStoreCreateCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	entryID := "entry-57"
+	execer := stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "INSERT INTO balance_checkpoints") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 7 || args[0] != "cp-1" || args[1] != "acc-1" || args[2] != int64(57) || args[5] != int64(1300) || args[6] != int64(10) {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	}
+	store := NewBalanceStore(stubDB{})
+	err := store.CreateCheckpoint(ctx, execer, BalanceCheckpointInput{
+		ID:          "cp-1",
+		AccountID:   "acc-1",
+		EntrySeq:    57,
+		LastEntryID: &entryID,
+		Balance:     1300,
+		EntryCount:  10,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestBalanceStoreBalanceAsOf(t *testing.T) {
+	ctx := context.Background()
+	asOf := time.Date(2025, 3, 31, 23, 59, 59, 0, time.UTC)
+	entryID := "entry-9"
+	store := NewBalanceStore(stubDB{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "last_entry_at <= $1") || !strings.Contains(query, "WHERE a.id = $2") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != asOf || args[1] != "acc-1" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*BalanceAsOf) = BalanceAsOf{AccountID: "acc-1", Balance: 4200, LastEntryID: &entryID, LastEntrySeq: 9}
+			return nil
+		},
+	})
+	row, err := store.BalanceAsOf(ctx, "acc-1", asOf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if row.Balance != 4200 || row.LastEntryID == nil || *row.LastEntryID != "entry-9" {
+		t.Fatalf("unexpected row: %#v", row)
+	}
+}
+
+func TestBalanceStoreBalancesAsOf(t *testing.T) {
+	ctx := context.Background()
+	asOf := time.Date(2025, 3, 31, 23, 59, 59, 0, time.UTC)
+	store := NewBalanceStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "a.created_at <= $1") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 1 || args[0] != asOf {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*[]BalanceAsOf) = []BalanceAsOf{{AccountID: "acc-1"}, {AccountID: "acc-2"}}
+			return nil
+		},
+	})
+	rows, err := store.BalancesAsOf(ctx, asOf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("unexpected rows: %#v", rows)
+	}
+}
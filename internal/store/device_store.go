@@ -0,0 +1,34 @@
+package store
+
+import "context"
+
+type DeviceStore struct {
+	db DB
+}
+
+func NewDeviceStore(db DB) *DeviceStore {
+	return &DeviceStore{db: db}
+}
+
+// A user's first device is never reported as new.
+func (s *DeviceStore) Touch(ctx context.Context, tx Getter, userID, deviceHash, userAgent string) (bool, error) {
+	var isNew bool
+	err := tx.GetContext(ctx, &isNew, `
+		WITH known AS (
+			SELECT EXISTS(SELECT 1 FROM user_devices WHERE user_id = $1) AS seen_before
+		), seen AS (
+			UPDATE user_devices
+			SET last_seen_at = NOW(), user_agent = $3
+			WHERE user_id = $1 AND device_hash = $2
+			RETURNING 1
+		), added AS (
+			INSERT INTO user_devices (user_id, device_hash, user_agent)
+			SELECT $1, $2, $3
+			WHERE NOT EXISTS (SELECT 1 FROM seen)
+			ON CONFLICT (user_id, device_hash) DO NOTHING
+			RETURNING 1
+		)
+		SELECT EXISTS(SELECT 1 FROM added) AND (SELECT seen_before FROM known)
+	`, userID, deviceHash, userAgent)
+	return isNew, err
+}
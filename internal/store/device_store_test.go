@@ -0,0 +1,29 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDeviceStoreTouch(t *testing.T) {
+	tx := stubGetter{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "INSERT INTO user_devices") || !strings.Contains(query, "seen_before") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 3 || args[0] != "user-1" || args[1] != "hash" || args[2] != "curl/8.0" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*bool) = true
+			return nil
+		},
+	}
+	isNew, err := NewDeviceStore(stubDB{}).Touch(context.Background(), tx, "user-1", "hash", "curl/8.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !isNew {
+		t.Fatalf("expected new device")
+	}
+}
@@ -69,3 +69,44 @@ func (s *ExchangeQuoteStore) Consume(ctx context.Context, tx Execer, quoteID str
 	}
 	return result.RowsAffected()
 }
+
+func (s *ExchangeQuoteStore) ClaimExpiring(ctx context.Context, tx Selecter, warning time.Duration, limit int) ([]ExchangeQuote, error) {
+	var quotes []ExchangeQuote
+	err := tx.SelectContext(ctx, &quotes, `
+		UPDATE exchange_quotes
+		SET expiring_notified_at = NOW()
+		WHERE id IN (
+			SELECT id FROM exchange_quotes
+			WHERE consumed_at IS NULL AND expiring_notified_at IS NULL AND expired_notified_at IS NULL
+				AND expires_at > NOW() AND expires_at <= NOW() + make_interval(secs => $1)
+			ORDER BY expires_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, from_account_id, to_account_id, amount_minor, converted_minor, rate, base_currency, quote_currency, expires_at, consumed_at
+	`, warning.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return quotes, nil
+}
+
+func (s *ExchangeQuoteStore) ClaimExpired(ctx context.Context, tx Selecter, limit int) ([]ExchangeQuote, error) {
+	var quotes []ExchangeQuote
+	err := tx.SelectContext(ctx, &quotes, `
+		UPDATE exchange_quotes
+		SET expired_notified_at = NOW()
+		WHERE id IN (
+			SELECT id FROM exchange_quotes
+			WHERE consumed_at IS NULL AND expired_notified_at IS NULL AND expires_at <= NOW()
+			ORDER BY expires_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, from_account_id, to_account_id, amount_minor, converted_minor, rate, base_currency, quote_currency, expires_at, consumed_at
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return quotes, nil
+}
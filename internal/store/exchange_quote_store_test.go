@@ -85,3 +85,43 @@ func TestExchangeQuoteStoreConsume(t *testing.T) {
 		t.Fatalf("expected 1 row, got %d", rows)
 	}
 }
+
+func TestExchangeQuoteStoreClaimExpiring(t *testing.T) {
+	store := NewExchangeQuoteStore(stubDB{})
+	tx := stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "SET expiring_notified_at = NOW()") || !strings.Contains(query, "FOR UPDATE SKIP LOCKED") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != float64(15) || args[1] != 50 {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*[]ExchangeQuote) = []ExchangeQuote{{ID: "quote-1"}}
+			return nil
+		},
+	}
+	quotes, err := store.ClaimExpiring(context.Background(), tx, 15*time.Second, 50)
+	if err != nil || len(quotes) != 1 || quotes[0].ID != "quote-1" {
+		t.Fatalf("unexpected result: %#v %v", quotes, err)
+	}
+}
+
+func TestExchangeQuoteStoreClaimExpired(t *testing.T) {
+	store := NewExchangeQuoteStore(stubDB{})
+	tx := stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "SET expired_notified_at = NOW()") || !strings.Contains(query, "expires_at <= NOW()") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 1 || args[0] != 50 {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*[]ExchangeQuote) = []ExchangeQuote{{ID: "quote-2"}}
+			return nil
+		},
+	}
+	quotes, err := store.ClaimExpired(context.Background(), tx, 50)
+	if err != nil || len(quotes) != 1 || quotes[0].ID != "quote-2" {
+		t.Fatalf("unexpected result: %#v %v", quotes, err)
+	}
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// The checkpoint row doubles as a lease so only one instance exports at a time.
+type ExportCheckpointStore struct {
+	db DB
+}
+
+type ExportPosition struct {
+	TxID int64 `db:"last_txid"`
+	Seq  int64 `db:"last_seq"`
+}
+
+func NewExportCheckpointStore(db DB) *ExportCheckpointStore {
+	return &ExportCheckpointStore{db: db}
+}
+
+func (s *ExportCheckpointStore) Claim(ctx context.Context, name string, lease time.Duration) (ExportPosition, bool, error) {
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO event_export_checkpoints (name)
+		VALUES ($1)
+		ON CONFLICT (name) DO NOTHING
+	`, name); err != nil {
+		return ExportPosition{}, false, err
+	}
+	var position ExportPosition
+	err := s.db.GetContext(ctx, &position, `
+		UPDATE event_export_checkpoints
+		SET leased_until = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE name = $1 AND leased_until <= NOW()
+		RETURNING last_txid, last_seq
+	`, name, lease.Milliseconds())
+	if err == sql.ErrNoRows {
+		return ExportPosition{}, false, nil
+	}
+	if err != nil {
+		return ExportPosition{}, false, err
+	}
+	return position, true, nil
+}
+
+// Advance never moves the checkpoint back, so a holder whose lease lapsed
+// cannot undo a newer export.
+func (s *ExportCheckpointStore) Advance(ctx context.Context, name string, position ExportPosition, lease time.Duration) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE event_export_checkpoints
+		SET last_txid = $2, last_seq = $3, leased_until = NOW() + $4 * INTERVAL '1 millisecond', updated_at = NOW()
+		WHERE name = $1 AND (last_txid, last_seq) < ($2, $3)
+	`, name, position.TxID, position.Seq, lease.Milliseconds())
+	return err
+}
+
+func (s *ExportCheckpointStore) Release(ctx context.Context, name string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE event_export_checkpoints
+		SET leased_until = NOW()
+		WHERE name = $1
+	`, name)
+	return err
+}
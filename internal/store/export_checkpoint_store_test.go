@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportCheckpointStoreClaim(t *testing.T) {
+	var seeded bool
+	store := NewExportCheckpointStore(stubDB{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "ON CONFLICT (name) DO NOTHING") || args[0] != "cloudevents" {
+				t.Fatalf("unexpected seed: %s %#v", query, args)
+			}
+			seeded = true
+			return stubResult{rows: 1}, nil
+		},
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "leased_until <= NOW()") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != "cloudevents" || args[1] != int64(60000) {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			if !strings.Contains(query, "RETURNING last_txid, last_seq") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			*dest.(*ExportPosition) = ExportPosition{TxID: 7, Seq: 42}
+			return nil
+		},
+	})
+	position, ok, err := store.Claim(context.Background(), "cloudevents", time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !seeded || !ok || position != (ExportPosition{TxID: 7, Seq: 42}) {
+		t.Fatalf("unexpected claim: %#v %v", position, ok)
+	}
+}
+
+func TestExportCheckpointStoreClaimLeased(t *testing.T) {
+	store := NewExportCheckpointStore(stubDB{
+		getFn: func(context.Context, any, string, ...any) error {
+			return sql.ErrNoRows
+		},
+	})
+	_, ok, err := store.Claim(context.Background(), "cloudevents", time.Minute)
+	if err != nil || ok {
+		t.Fatalf("expected lease to be held elsewhere, got %v %v", ok, err)
+	}
+}
+
+func TestExportCheckpointStoreAdvance(t *testing.T) {
+	store := NewExportCheckpointStore(stubDB{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "(last_txid, last_seq) < ($2, $3)") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 4 || args[0] != "cloudevents" || args[1] != int64(9) || args[2] != int64(50) || args[3] != int64(60000) {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	})
+	if err := store.Advance(context.Background(), "cloudevents", ExportPosition{TxID: 9, Seq: 50}, time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
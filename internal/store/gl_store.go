@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type GLStore struct {
+	db DB
+}
+
+type GLAccount struct {
+	Code      string    `db:"code"`
+	Name      string    `db:"name"`
+	Type      string    `db:"type"`
+	Accounts  int64     `db:"accounts"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type GLBalance struct {
+	Code     string `db:"gl_code"`
+	Name     string `db:"name"`
+	Type     string `db:"type"`
+	Currency string `db:"currency"`
+	Opening  int64  `db:"opening"`
+	Debits   int64  `db:"debits"`
+	Credits  int64  `db:"credits"`
+	Closing  int64  `db:"closing"`
+}
+
+func NewGLStore(db DB) *GLStore {
+	return &GLStore{db: db}
+}
+
+func (s *GLStore) ListAccounts(ctx context.Context) ([]GLAccount, error) {
+	var rows []GLAccount
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT g.code, g.name, g.type, COUNT(a.id) AS accounts, g.created_at
+		FROM gl_accounts g
+		LEFT JOIN accounts a ON a.gl_code = g.code
+		GROUP BY g.code, g.name, g.type, g.created_at
+		ORDER BY g.code
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *GLStore) MapSystemAccount(ctx context.Context, tx Execer, accountID, glCode string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		UPDATE accounts
+		SET gl_code = $1, updated_at = NOW()
+		WHERE id = $2 AND is_system = TRUE
+	`, glCode, accountID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *GLStore) Balances(ctx context.Context, from, to time.Time, currency string) ([]GLBalance, error) {
+	var rows []GLBalance
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT g.code AS gl_code,
+		       g.name,
+		       g.type,
+		       l.currency,
+		       COALESCE(SUM(l.amount) FILTER (WHERE l.created_at < $1), 0) AS opening,
+		       COALESCE(SUM(-l.amount) FILTER (WHERE l.created_at >= $1 AND l.amount < 0), 0) AS debits,
+		       COALESCE(SUM(l.amount) FILTER (WHERE l.created_at >= $1 AND l.amount > 0), 0) AS credits,
+		       COALESCE(SUM(l.amount), 0) AS closing
+		FROM ledger_entries l
+		JOIN accounts a ON a.id = l.account_id
+		JOIN gl_accounts g ON g.code = a.gl_code
+		WHERE l.created_at <= $2 AND ($3 = '' OR l.currency = $3)
+		GROUP BY g.code, g.name, g.type, l.currency
+		ORDER BY l.currency, g.code
+	`, from, to, currency)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGLStoreListAccounts(t *testing.T) {
+	store := NewGLStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "FROM gl_accounts") || !strings.Contains(query, "LEFT JOIN accounts") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			*dest.(*[]GLAccount) = []GLAccount{{Code: "1000", Type: "asset"}, {Code: "2000", Type: "liability", Accounts: 4}}
+			return nil
+		},
+	})
+	rows, err := store.ListAccounts(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 2 || rows[1].Accounts != 4 {
+		t.Fatalf("unexpected rows: %#v", rows)
+	}
+}
+
+func TestGLStoreMapSystemAccount(t *testing.T) {
+	store := NewGLStore(stubDB{})
+	updated, err := store.MapSystemAccount(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "is_system = TRUE") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != "4000" || args[1] != "sys-usd" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	}, "sys-usd", "4000")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated != 1 {
+		t.Fatalf("expected 1 row, got %d", updated)
+	}
+}
+
+func TestGLStoreBalances(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 31, 23, 59, 59, 0, time.UTC)
+	store := NewGLStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "JOIN gl_accounts g ON g.code = a.gl_code") || !strings.Contains(query, "GROUP BY g.code, g.name, g.type, l.currency") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 3 || args[0] != from || args[1] != to || args[2] != "USD" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*[]GLBalance) = []GLBalance{{Code: "2000", Currency: "USD", Closing: 500}}
+			return nil
+		},
+	})
+	rows, err := store.Balances(context.Background(), from, to, "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Closing != 500 {
+		t.Fatalf("unexpected rows: %#v", rows)
+	}
+}
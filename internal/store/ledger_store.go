@@ -1,6 +1,9 @@
 package store
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type LedgerStore struct {
 	db DB
@@ -12,17 +15,28 @@ func NewLedgerStore(db DB) *LedgerStore {
 
 func (s *LedgerStore) InsertEntries(ctx context.Context, tx Execer, entries []LedgerEntryInput) error {
 	query := `
-		INSERT INTO ledger_entries (id, transaction_id, account_id, amount, currency, description)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO ledger_entries (id, transaction_id, account_id, amount, currency, description, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7::timestamptz, NOW()))
 	`
 	for _, entry := range entries {
-		if _, err := tx.ExecContext(ctx, query, entry.ID, entry.TransactionID, entry.AccountID, entry.Amount, entry.Currency, entry.Description); err != nil {
+		if _, err := tx.ExecContext(ctx, query, entry.ID, entry.TransactionID, entry.AccountID, entry.Amount, entry.Currency, entry.Description, entry.CreatedAt); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (s *LedgerStore) EntriesByTransaction(ctx context.Context, tx Selecter, transactionID string) ([]LedgerEntry, error) {
+	var rows []LedgerEntry
+	err := tx.SelectContext(ctx, &rows, `
+		SELECT id, account_id, amount, currency, description
+		FROM ledger_entries
+		WHERE transaction_id = $1
+		ORDER BY seq
+	`, transactionID)
+	return rows, err
+}
+
 func (s *LedgerStore) SumByAccount(ctx context.Context, accountID string) (int64, error) {
 	var sum int64
 	err := s.db.GetContext(ctx, &sum, `
@@ -40,4 +54,13 @@ type LedgerEntryInput struct {
 	Amount        int64
 	Currency      string
 	Description   string
+	CreatedAt     *time.Time
+}
+
+type LedgerEntry struct {
+	ID          string `db:"id"`
+	AccountID   string `db:"account_id"`
+	Amount      int64  `db:"amount"`
+	Currency    string `db:"currency"`
+	Description string `db:"description"`
 }
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestLedgerStoreInsertEntries(t *testing.T) {
@@ -32,6 +33,28 @@ func TestLedgerStoreInsertEntries(t *testing.T) {
 	}
 }
 
+func TestLedgerStoreInsertEntriesEffectiveDate(t *testing.T) {
+	effectiveAt := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+	execer := stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "COALESCE($7::timestamptz, NOW())") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 7 || args[6] != &effectiveAt {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	}
+	store := NewLedgerStore(stubDB{})
+	err := store.InsertEntries(context.Background(), execer, []LedgerEntryInput{
+		{ID: "1", TransactionID: "tx", AccountID: "acc1", Amount: 100, Currency: "USD", Description: "a", CreatedAt: &effectiveAt},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestLedgerStoreSumByAccount(t *testing.T) {
 	ctx := context.Background()
 	store := NewLedgerStore(stubDB{
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type LoginChallengeStore struct {
+	db DB
+}
+
+type LoginChallenge struct {
+	UserID   string `db:"user_id"`
+	Attempts int    `db:"attempts"`
+}
+
+func NewLoginChallengeStore(db DB) *LoginChallengeStore {
+	return &LoginChallengeStore{db: db}
+}
+
+func (s *LoginChallengeStore) Create(ctx context.Context, challengeHash, userID string, ttl time.Duration) (time.Time, error) {
+	var expiresAt time.Time
+	err := s.db.GetContext(ctx, &expiresAt, `
+		WITH purged AS (
+			DELETE FROM login_challenges WHERE expires_at < NOW()
+		)
+		INSERT INTO login_challenges (challenge_hash, user_id, expires_at)
+		VALUES ($1, $2, NOW() + make_interval(secs => $3))
+		RETURNING expires_at
+	`, challengeHash, userID, ttl.Seconds())
+	return expiresAt, err
+}
+
+func (s *LoginChallengeStore) Attempt(ctx context.Context, challengeHash string, maxAttempts int) (LoginChallenge, error) {
+	var challenge LoginChallenge
+	err := s.db.GetContext(ctx, &challenge, `
+		UPDATE login_challenges
+		SET attempts = attempts + 1
+		WHERE challenge_hash = $1 AND expires_at >= NOW() AND attempts < $2
+		RETURNING user_id, attempts
+	`, challengeHash, maxAttempts)
+	return challenge, err
+}
+
+func (s *LoginChallengeStore) Delete(ctx context.Context, challengeHash string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM login_challenges WHERE challenge_hash = $1`, challengeHash)
+	return err
+}
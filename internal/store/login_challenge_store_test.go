@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoginChallengeStoreCreate(t *testing.T) {
+	expires := time.Date(2025, 1, 1, 0, 5, 0, 0, time.UTC)
+	store := NewLoginChallengeStore(stubDB{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "INSERT INTO login_challenges") || !strings.Contains(query, "DELETE FROM login_challenges") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 3 || args[0] != "hash" || args[1] != "user-1" || args[2] != float64(300) {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*time.Time) = expires
+			return nil
+		},
+	})
+	got, err := store.Create(context.Background(), "hash", "user-1", 5*time.Minute)
+	if err != nil || !got.Equal(expires) {
+		t.Fatalf("unexpected result: %v %v", got, err)
+	}
+}
+
+func TestLoginChallengeStoreAttemptLimits(t *testing.T) {
+	store := NewLoginChallengeStore(stubDB{
+		getFn: func(_ context.Context, _ any, query string, args ...any) error {
+			if !strings.Contains(query, "attempts < $2") || !strings.Contains(query, "expires_at >= NOW()") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != "hash" || args[1] != 5 {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return sql.ErrNoRows
+		},
+	})
+	if _, err := store.Attempt(context.Background(), "hash", 5); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	LoginThrottleEmail  = "email"
+	LoginThrottleIP     = "ip"
+	LoginThrottleStepUp = "step_up"
+)
+
+type LoginThrottleStore struct {
+	db DB
+}
+
+type LoginThrottle struct {
+	Failures     int        `db:"failures"`
+	LastFailedAt *time.Time `db:"last_failed_at"`
+	LockedUntil  *time.Time `db:"locked_until"`
+}
+
+func NewLoginThrottleStore(db DB) *LoginThrottleStore {
+	return &LoginThrottleStore{db: db}
+}
+
+// Lock holds an advisory lock on the key until tx ends, so concurrent
+// attempts are checked and counted in turn.
+func (s *LoginThrottleStore) Lock(ctx context.Context, tx Tx, scope, key string) (LoginThrottle, error) {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1 || ':' || $2, 0))`, scope, key); err != nil {
+		return LoginThrottle{}, err
+	}
+	var throttle LoginThrottle
+	err := tx.GetContext(ctx, &throttle, `
+		SELECT failures, last_failed_at, locked_until
+		FROM login_throttles
+		WHERE scope = $1 AND key = $2
+	`, scope, key)
+	if err == sql.ErrNoRows {
+		return LoginThrottle{}, nil
+	}
+	return throttle, err
+}
+
+func (s *LoginThrottleStore) RecordFailure(ctx context.Context, tx Getter, scope, key string, window time.Duration, maxFailures int, lockout time.Duration) (LoginThrottle, error) {
+	var throttle LoginThrottle
+	err := tx.GetContext(ctx, &throttle, `
+		WITH purged AS (
+			DELETE FROM login_throttles
+			WHERE last_failed_at < NOW() - make_interval(secs => $3)
+			  AND (locked_until IS NULL OR locked_until < NOW())
+			  AND NOT (scope = $1 AND key = $2)
+		)
+		INSERT INTO login_throttles (scope, key, failures, last_failed_at, locked_until)
+		VALUES ($1, $2, 1, NOW(), CASE WHEN $4 <= 1 THEN NOW() + make_interval(secs => $5) END)
+		ON CONFLICT (scope, key) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttles.last_failed_at < NOW() - make_interval(secs => $3) THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			last_failed_at = NOW(),
+			locked_until = CASE
+				WHEN (CASE
+					WHEN login_throttles.last_failed_at < NOW() - make_interval(secs => $3) THEN 1
+					ELSE login_throttles.failures + 1
+				END) >= $4 THEN NOW() + make_interval(secs => $5)
+				ELSE login_throttles.locked_until
+			END
+		RETURNING failures, last_failed_at, locked_until
+	`, scope, key, window.Seconds(), maxFailures, lockout.Seconds())
+	return throttle, err
+}
+
+// Release restores previous unless more failures have been recorded since.
+func (s *LoginThrottleStore) Release(ctx context.Context, tx Execer, scope, key string, recorded, previous LoginThrottle) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE login_throttles SET
+			failures = CASE WHEN failures = $3 THEN $4 ELSE GREATEST(failures - 1, 0) END,
+			last_failed_at = CASE WHEN failures = $3 THEN $5 ELSE last_failed_at END,
+			locked_until = CASE WHEN failures = $3 THEN $6 ELSE locked_until END
+		WHERE scope = $1 AND key = $2
+	`, scope, key, recorded.Failures, previous.Failures, previous.LastFailedAt, previous.LockedUntil); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM login_throttles WHERE scope = $1 AND key = $2 AND failures = 0`, scope, key)
+	return err
+}
+
+func (s *LoginThrottleStore) Reset(ctx context.Context, tx Execer, scope, key string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `DELETE FROM login_throttles WHERE scope = $1 AND key = $2`, scope, key)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoginThrottleStoreLockMissing(t *testing.T) {
+	store := NewLoginThrottleStore(stubDB{})
+	locked := false
+	tx := stubDB{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "pg_advisory_xact_lock") || len(args) != 2 || args[0] != LoginThrottleEmail {
+				t.Fatalf("unexpected lock: %s %#v", query, args)
+			}
+			locked = true
+			return stubResult{}, nil
+		},
+		getFn: func(_ context.Context, _ any, query string, args ...any) error {
+			if !locked || !strings.Contains(query, "FROM login_throttles") || len(args) != 2 {
+				t.Fatalf("expected the key locked before it is read: %s %#v", query, args)
+			}
+			return sql.ErrNoRows
+		},
+	}
+	throttle, err := store.Lock(context.Background(), tx, LoginThrottleEmail, "alice@example.com")
+	if err != nil || throttle.Failures != 0 || throttle.LockedUntil != nil {
+		t.Fatalf("expected an empty throttle, got %#v %v", throttle, err)
+	}
+}
+
+func TestLoginThrottleStoreRelease(t *testing.T) {
+	store := NewLoginThrottleStore(stubDB{})
+	var queries []string
+	tx := stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			queries = append(queries, query)
+			if len(queries) == 1 && (len(args) != 6 || args[2] != 4 || args[3] != 3) {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	}
+	err := store.Release(context.Background(), tx, LoginThrottleIP, "10.0.0.1", LoginThrottle{Failures: 4}, LoginThrottle{Failures: 3})
+	if err != nil || len(queries) != 2 || !strings.Contains(queries[0], "UPDATE login_throttles") || !strings.Contains(queries[1], "failures = 0") {
+		t.Fatalf("unexpected release: %v %#v", err, queries)
+	}
+}
+
+func TestLoginThrottleStoreRecordFailure(t *testing.T) {
+	locked := time.Date(2025, 1, 1, 0, 15, 0, 0, time.UTC)
+	store := NewLoginThrottleStore(stubDB{})
+	tx := stubGetter{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "ON CONFLICT (scope, key) DO UPDATE") || !strings.Contains(query, "DELETE FROM login_throttles") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 5 || args[0] != LoginThrottleIP || args[1] != "10.0.0.1" || args[2] != float64(900) || args[3] != 5 || args[4] != float64(600) {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*LoginThrottle) = LoginThrottle{Failures: 5, LockedUntil: &locked}
+			return nil
+		},
+	}
+	throttle, err := store.RecordFailure(context.Background(), tx, LoginThrottleIP, "10.0.0.1", 15*time.Minute, 5, 10*time.Minute)
+	if err != nil || throttle.Failures != 5 || !throttle.LockedUntil.Equal(locked) {
+		t.Fatalf("unexpected result: %#v %v", throttle, err)
+	}
+}
+
+func TestLoginThrottleStoreReset(t *testing.T) {
+	store := NewLoginThrottleStore(stubDB{})
+	tx := stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "DELETE FROM login_throttles") || len(args) != 2 || args[1] != "alice@example.com" {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	}
+	removed, err := store.Reset(context.Background(), tx, LoginThrottleEmail, "alice@example.com")
+	if err != nil || removed != 1 {
+		t.Fatalf("unexpected result: %d %v", removed, err)
+	}
+}
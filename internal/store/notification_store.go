@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type NotificationStore struct {
+	db DB
+}
+
+type NotificationPreference struct {
+	Channel   string    `db:"channel"`
+	Kind      string    `db:"kind"`
+	Enabled   bool      `db:"enabled"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type NotificationRecipient struct {
+	UserID           string         `db:"id"`
+	Username         string         `db:"username"`
+	Email            string         `db:"email"`
+	DisabledChannels pq.StringArray `db:"disabled_channels"`
+}
+
+func (r NotificationRecipient) Disabled(channel string) bool {
+	for _, disabled := range r.DisabledChannels {
+		if disabled == channel {
+			return true
+		}
+	}
+	return false
+}
+
+type NotificationDelivery struct {
+	ID            string     `db:"id"`
+	EventID       string     `db:"event_id"`
+	UserID        string     `db:"user_id"`
+	Channel       string     `db:"channel"`
+	Kind          string     `db:"kind"`
+	Destination   string     `db:"destination"`
+	Subject       string     `db:"subject"`
+	Body          string     `db:"body"`
+	TokenRef      *string    `db:"token_ref"`
+	Status        string     `db:"status"`
+	Attempts      int        `db:"attempts"`
+	LastError     *string    `db:"last_error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	SentAt        *time.Time `db:"sent_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+}
+
+type NotificationDeliveryInput struct {
+	ID          string
+	EventID     string
+	UserID      string
+	Channel     string
+	Kind        string
+	Destination string
+	Subject     string
+	Body        string
+	TokenRef    string
+}
+
+const notificationDeliveryColumns = `id, event_id, user_id, channel, kind, destination, subject, body, token_ref,
+	status, attempts, last_error, next_attempt_at, sent_at, created_at`
+
+func NewNotificationStore(db DB) *NotificationStore {
+	return &NotificationStore{db: db}
+}
+
+func (s *NotificationStore) Recipient(ctx context.Context, userID, kind string) (NotificationRecipient, error) {
+	var row NotificationRecipient
+	err := s.db.GetContext(ctx, &row, `
+		SELECT u.id, u.username, u.email,
+		       COALESCE(array_agg(p.channel) FILTER (WHERE p.enabled = FALSE), '{}') AS disabled_channels
+		FROM users u
+		LEFT JOIN notification_preferences p ON p.user_id = u.id AND p.kind = $2
+		WHERE u.id = $1
+		GROUP BY u.id, u.username, u.email
+	`, userID, kind)
+	if err != nil {
+		return NotificationRecipient{}, err
+	}
+	return row, nil
+}
+
+func (s *NotificationStore) ListPreferences(ctx context.Context, userID string) ([]NotificationPreference, error) {
+	var rows []NotificationPreference
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT channel, kind, enabled, updated_at
+		FROM notification_preferences
+		WHERE user_id = $1
+		ORDER BY channel, kind
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *NotificationStore) SetPreference(ctx context.Context, tx Execer, userID, channel, kind string, enabled bool) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO notification_preferences (user_id, channel, kind, enabled)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, channel, kind) DO UPDATE
+		SET enabled = EXCLUDED.enabled, updated_at = NOW()
+	`, userID, channel, kind, enabled)
+	return err
+}
+
+func (s *NotificationStore) EnqueueDelivery(ctx context.Context, input NotificationDeliveryInput) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO notification_deliveries (id, event_id, user_id, channel, kind, destination, subject, body, token_ref)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''))
+		ON CONFLICT (event_id, user_id, channel, kind) DO NOTHING
+	`, input.ID, input.EventID, input.UserID, input.Channel, input.Kind, input.Destination, input.Subject, input.Body, input.TokenRef)
+	return err
+}
+
+func (s *NotificationStore) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]NotificationDelivery, error) {
+	var rows []NotificationDelivery
+	err := s.db.SelectContext(ctx, &rows, `
+		UPDATE notification_deliveries
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id
+			FROM notification_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+notificationDeliveryColumns+`
+	`, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *NotificationStore) RecordSuccess(ctx context.Context, deliveryID string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE notification_deliveries
+		SET status = 'sent', attempts = attempts + 1, last_error = NULL, sent_at = NOW()
+		WHERE id = $1
+	`, deliveryID)
+	return err
+}
+
+func (s *NotificationStore) RecordFailure(ctx context.Context, deliveryID, message string, retryAt *time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE notification_deliveries
+		SET attempts = attempts + 1, last_error = $2,
+		    status = CASE WHEN $3::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+		    next_attempt_at = COALESCE($3::timestamptz, next_attempt_at)
+		WHERE id = $1
+	`, deliveryID, message, retryAt)
+	return err
+}
+
+func (s *NotificationStore) ListDeliveries(ctx context.Context, userID string, limit, offset int) ([]NotificationDelivery, error) {
+	var rows []NotificationDelivery
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT `+notificationDeliveryColumns+`
+		FROM notification_deliveries
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
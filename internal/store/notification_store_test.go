@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotificationStoreRecipient(t *testing.T) {
+	store := NewNotificationStore(stubDB{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "FILTER (WHERE p.enabled = FALSE)") || !strings.Contains(query, "p.kind = $2") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != "user-1" || args[1] != "transfer.received" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*NotificationRecipient) = NotificationRecipient{UserID: "user-1", Email: "a@example.com", DisabledChannels: []string{"email"}}
+			return nil
+		},
+	})
+	recipient, err := store.Recipient(context.Background(), "user-1", "transfer.received")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !recipient.Disabled("email") || recipient.Disabled("sms") {
+		t.Fatalf("unexpected recipient: %#v", recipient)
+	}
+}
+
+func TestNotificationStoreSetPreferenceUpserts(t *testing.T) {
+	tx := stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "ON CONFLICT (user_id, channel, kind) DO UPDATE") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 4 || args[0] != "user-1" || args[1] != "email" || args[2] != "transfer.sent" || args[3] != false {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	}
+	if err := NewNotificationStore(stubDB{}).SetPreference(context.Background(), tx, "user-1", "email", "transfer.sent", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestNotificationStoreEnqueueDeliveryIsIdempotent(t *testing.T) {
+	store := NewNotificationStore(stubDB{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "ON CONFLICT (event_id, user_id, channel, kind) DO NOTHING") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 9 || args[1] != "event-1" || args[5] != "a@example.com" || args[8] != "" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{}, nil
+		},
+	})
+	err := store.EnqueueDelivery(context.Background(), NotificationDeliveryInput{
+		ID: "n-1", EventID: "event-1", UserID: "user-1", Channel: "email", Kind: "transfer.sent",
+		Destination: "a@example.com", Subject: "s", Body: "b",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestNotificationStoreClaimDeliveries(t *testing.T) {
+	store := NewNotificationStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "FOR UPDATE SKIP LOCKED") || !strings.Contains(query, "status = 'pending'") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != 10 || args[1] != int64(60000) {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*[]NotificationDelivery) = []NotificationDelivery{{ID: "n-1"}}
+			return nil
+		},
+	})
+	rows, err := store.ClaimDeliveries(context.Background(), 10, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 1 || rows[0].ID != "n-1" {
+		t.Fatalf("unexpected rows: %#v", rows)
+	}
+}
+
+func TestNotificationStoreRecordFailure(t *testing.T) {
+	retryAt := time.Date(2025, 1, 1, 0, 5, 0, 0, time.UTC)
+	store := NewNotificationStore(stubDB{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "CASE WHEN $3::timestamptz IS NULL THEN 'failed' ELSE 'pending' END") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 3 || args[0] != "n-1" || args[1] != "smtp down" || args[2] != &retryAt {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	})
+	if err := store.RecordFailure(context.Background(), "n-1", "smtp down", &retryAt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestNotificationStoreListDeliveries(t *testing.T) {
+	store := NewNotificationStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "WHERE user_id = $1") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 3 || args[0] != "user-1" || args[1] != 20 || args[2] != 40 {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*[]NotificationDelivery) = []NotificationDelivery{{ID: "n-1", Status: "sent"}}
+			return nil
+		},
+	})
+	rows, err := store.ListDeliveries(context.Background(), "user-1", 20, 40)
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("unexpected result: %#v %v", rows, err)
+	}
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	OrganizationOwner  = "owner"
+	OrganizationMember = "member"
+)
+
+type OrganizationStore struct {
+	db DB
+}
+
+type Organization struct {
+	ID        string    `db:"id"`
+	Name      string    `db:"name"`
+	CreatedBy string    `db:"created_by"`
+	CreatedAt time.Time `db:"created_at"`
+	Role      string    `db:"role"`
+}
+
+type OrganizationMemberRecord struct {
+	UserID    string    `db:"user_id"`
+	Role      string    `db:"role"`
+	AddedBy   *string   `db:"added_by"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func NewOrganizationStore(db DB) *OrganizationStore {
+	return &OrganizationStore{db: db}
+}
+
+func (s *OrganizationStore) Create(ctx context.Context, tx Execer, id, name, ownerID string) error {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO organizations (id, name, created_by)
+		VALUES ($1, $2, $3)
+	`, id, name, ownerID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO organization_members (organization_id, user_id, role)
+		VALUES ($1, $2, 'owner')
+	`, id, ownerID)
+	return err
+}
+
+func (s *OrganizationStore) ListByUser(ctx context.Context, userID string) ([]Organization, error) {
+	var organizations []Organization
+	err := s.db.SelectContext(ctx, &organizations, `
+		SELECT o.id, o.name, o.created_by, o.created_at, m.role
+		FROM organizations o
+		JOIN organization_members m ON m.organization_id = o.id
+		WHERE m.user_id = $1
+		ORDER BY o.created_at DESC
+	`, userID)
+	return organizations, err
+}
+
+func (s *OrganizationStore) Role(ctx context.Context, organizationID, userID string) (string, error) {
+	var role string
+	err := s.db.GetContext(ctx, &role, `
+		SELECT role FROM organization_members
+		WHERE organization_id = $1 AND user_id = $2
+	`, organizationID, userID)
+	return role, err
+}
+
+func (s *OrganizationStore) ListMembers(ctx context.Context, organizationID string) ([]OrganizationMemberRecord, error) {
+	var members []OrganizationMemberRecord
+	err := s.db.SelectContext(ctx, &members, `
+		SELECT user_id, role, added_by, created_at
+		FROM organization_members
+		WHERE organization_id = $1
+		ORDER BY created_at
+	`, organizationID)
+	return members, err
+}
+
+func (s *OrganizationStore) AddMember(ctx context.Context, tx Execer, organizationID, userID, role, addedBy string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO organization_members (organization_id, user_id, role, added_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (organization_id, user_id) DO NOTHING
+	`, organizationID, userID, role, addedBy)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RemoveMember only removes plain members, so an organization keeps its
+// owners.
+func (s *OrganizationStore) RemoveMember(ctx context.Context, tx Execer, organizationID, userID string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM organization_members
+		WHERE organization_id = $1 AND user_id = $2 AND role = 'member'
+	`, organizationID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,40 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestOrganizationStoreCreateAddsOwner(t *testing.T) {
+	store := NewOrganizationStore(stubDB{})
+	var queries []string
+	err := store.Create(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if args[0] != "org-1" || args[len(args)-1] != "user-1" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			queries = append(queries, query)
+			return stubResult{rows: 1}, nil
+		},
+	}, "org-1", "Acme", "user-1")
+	if err != nil || len(queries) != 2 || !strings.Contains(queries[1], "'owner'") {
+		t.Fatalf("unexpected result: %v %v", queries, err)
+	}
+}
+
+func TestOrganizationStoreRemoveMemberKeepsOwners(t *testing.T) {
+	store := NewOrganizationStore(stubDB{})
+	rows, err := store.RemoveMember(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "role = 'member'") || args[0] != "org-1" || args[1] != "user-2" {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			return stubResult{rows: 0}, nil
+		},
+	}, "org-1", "user-2")
+	if err != nil || rows != 0 {
+		t.Fatalf("unexpected result: %d %v", rows, err)
+	}
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type OutboxStore struct {
+	db DB
+}
+
+type DomainEvent struct {
+	ID             string         `db:"id"`
+	Seq            int64          `db:"seq"`
+	TxID           int64          `db:"txid"`
+	Type           string         `db:"event_type"`
+	AggregateType  string         `db:"aggregate_type"`
+	AggregateID    string         `db:"aggregate_id"`
+	UserID         *string        `db:"user_id"`
+	Payload        string         `db:"payload"`
+	CreatedAt      time.Time      `db:"created_at"`
+	DeliveredSinks pq.StringArray `db:"delivered_sinks"`
+	DeliveredAt    *time.Time     `db:"delivered_at"`
+	Attempts       int            `db:"attempts"`
+	LastError      *string        `db:"last_error"`
+	NextAttemptAt  time.Time      `db:"next_attempt_at"`
+}
+
+type DomainEventInput struct {
+	ID            string
+	Type          string
+	AggregateType string
+	AggregateID   string
+	UserID        *string
+	Payload       string
+}
+
+const domainEventColumns = `id, seq, txid, event_type, aggregate_type, aggregate_id, user_id, payload::text AS payload,
+	created_at, delivered_sinks, delivered_at, attempts, last_error, next_attempt_at`
+
+func NewOutboxStore(db DB) *OutboxStore {
+	return &OutboxStore{db: db}
+}
+
+func (s *OutboxStore) Append(ctx context.Context, tx Execer, input DomainEventInput) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO domain_events (id, event_type, aggregate_type, aggregate_id, user_id, payload)
+		VALUES ($1, $2, $3, $4, $5, $6::jsonb)
+	`, input.ID, input.Type, input.AggregateType, input.AggregateID, input.UserID, input.Payload)
+	return err
+}
+
+// Claim leases up to limit due events by pushing their next attempt past the
+// lease, so concurrent dispatchers skip them while they are being delivered.
+func (s *OutboxStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]DomainEvent, error) {
+	var rows []DomainEvent
+	err := s.db.SelectContext(ctx, &rows, `
+		UPDATE domain_events
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id
+			FROM domain_events
+			WHERE delivered_at IS NULL AND next_attempt_at <= NOW()
+			ORDER BY seq
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+domainEventColumns+`
+	`, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Seq < rows[j].Seq })
+	return rows, nil
+}
+
+func (s *OutboxStore) MarkDelivered(ctx context.Context, eventID string, sinks []string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE domain_events
+		SET delivered_sinks = $2, delivered_at = NOW(), attempts = attempts + 1, last_error = NULL
+		WHERE id = $1
+	`, eventID, pq.StringArray(sinks))
+	return err
+}
+
+func (s *OutboxStore) MarkFailed(ctx context.Context, eventID string, sinks []string, message string, retryAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE domain_events
+		SET delivered_sinks = $2, attempts = attempts + 1, last_error = $3, next_attempt_at = $4
+		WHERE id = $1
+	`, eventID, pq.StringArray(sinks), message, retryAt)
+	return err
+}
+
+func (s *OutboxStore) ListUndelivered(ctx context.Context, eventType string, limit, offset int) ([]DomainEvent, error) {
+	var rows []DomainEvent
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT `+domainEventColumns+`
+		FROM domain_events
+		WHERE delivered_at IS NULL
+		  AND ($1 = '' OR event_type = $1)
+		ORDER BY seq
+		LIMIT $2 OFFSET $3
+	`, eventType, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// Since leaves out events of transactions that may still be running. Every
+// transaction below the snapshot's xmin has ended, so no event can later
+// appear before the last one returned.
+func (s *OutboxStore) Since(ctx context.Context, after ExportPosition, limit int) ([]DomainEvent, error) {
+	var rows []DomainEvent
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT `+domainEventColumns+`
+		FROM domain_events
+		WHERE (txid, seq) > ($1, $2)
+		  AND txid < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+		ORDER BY txid, seq
+		LIMIT $3
+	`, after.TxID, after.Seq, limit)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
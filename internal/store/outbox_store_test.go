@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestOutboxStoreAppend(t *testing.T) {
+	store := NewOutboxStore(stubDB{})
+	userID := "user-1"
+	err := store.Append(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "INSERT INTO domain_events") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 6 || args[0] != "event-1" || args[1] != "transfer.completed" || args[4] != &userID || args[5] != `{"a":1}` {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	}, DomainEventInput{
+		ID:            "event-1",
+		Type:          "transfer.completed",
+		AggregateType: "transaction",
+		AggregateID:   "tx-1",
+		UserID:        &userID,
+		Payload:       `{"a":1}`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestOutboxStoreClaimOrdersBySeq(t *testing.T) {
+	store := NewOutboxStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "FOR UPDATE SKIP LOCKED") || !strings.Contains(query, "RETURNING") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != 50 || args[1] != int64(30000) {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*[]DomainEvent) = []DomainEvent{{ID: "b", Seq: 2}, {ID: "a", Seq: 1}}
+			return nil
+		},
+	})
+	rows, err := store.Claim(context.Background(), 50, 30*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 2 || rows[0].ID != "a" || rows[1].ID != "b" {
+		t.Fatalf("unexpected rows: %#v", rows)
+	}
+}
+
+func TestOutboxStoreMarkFailed(t *testing.T) {
+	retryAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewOutboxStore(stubDB{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "attempts = attempts + 1") || strings.Contains(query, "delivered_at") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			sinks, ok := args[1].(pq.StringArray)
+			if !ok || len(sinks) != 1 || sinks[0] != "websocket" || args[2] != "boom" || args[3] != retryAt {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	})
+	if err := store.MarkFailed(context.Background(), "event-1", []string{"websocket"}, "boom", retryAt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestOutboxStoreListUndelivered(t *testing.T) {
+	store := NewOutboxStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "delivered_at IS NULL") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 3 || args[0] != "transfer.completed" || args[1] != 20 || args[2] != 40 {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*[]DomainEvent) = []DomainEvent{{ID: "event-1"}}
+			return nil
+		},
+	})
+	rows, err := store.ListUndelivered(context.Background(), "transfer.completed", 20, 40)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 1 || rows[0].ID != "event-1" {
+		t.Fatalf("unexpected rows: %#v", rows)
+	}
+}
+
+func TestOutboxStoreSince(t *testing.T) {
+	store := NewOutboxStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "(txid, seq) > ($1, $2)") || !strings.Contains(query, "pg_snapshot_xmin(pg_current_snapshot())") || !strings.Contains(query, "ORDER BY txid, seq") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 3 || args[0] != int64(7) || args[1] != int64(41) || args[2] != 100 {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*[]DomainEvent) = []DomainEvent{{ID: "event-1", Seq: 42}}
+			return nil
+		},
+	})
+	rows, err := store.Since(context.Background(), ExportPosition{TxID: 7, Seq: 41}, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Seq != 42 {
+		t.Fatalf("unexpected rows: %#v", rows)
+	}
+}
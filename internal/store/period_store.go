@@ -0,0 +1,216 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type PeriodStore struct {
+	db DB
+}
+
+type AccountingPeriod struct {
+	ID           string     `db:"id"`
+	PeriodStart  *time.Time `db:"period_start"`
+	PeriodEnd    time.Time  `db:"period_end"`
+	Status       string     `db:"status"`
+	ClosedBy     string     `db:"closed_by"`
+	ClosedAt     time.Time  `db:"closed_at"`
+	ReopenedBy   *string    `db:"reopened_by"`
+	ReopenedAt   *time.Time `db:"reopened_at"`
+	ReopenReason *string    `db:"reopen_reason"`
+}
+
+type AccountingPeriodInput struct {
+	ID          string
+	PeriodStart *time.Time
+	PeriodEnd   time.Time
+	ClosedBy    string
+}
+
+type PeriodBalance struct {
+	PeriodID     string  `db:"period_id"`
+	AccountID    string  `db:"account_id"`
+	UserID       *string `db:"user_id"`
+	Currency     string  `db:"currency"`
+	IsSystem     bool    `db:"is_system"`
+	Balance      int64   `db:"balance"`
+	LastEntryID  *string `db:"last_entry_id"`
+	LastEntrySeq int64   `db:"last_entry_seq"`
+}
+
+const accountingPeriodColumns = `id, period_start, period_end, status, closed_by, closed_at, reopened_by, reopened_at, reopen_reason`
+
+func NewPeriodStore(db DB) *PeriodStore {
+	return &PeriodStore{db: db}
+}
+
+func (s *PeriodStore) List(ctx context.Context) ([]AccountingPeriod, error) {
+	var rows []AccountingPeriod
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT `+accountingPeriodColumns+`
+		FROM accounting_periods
+		ORDER BY period_end DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *PeriodStore) GetForUpdate(ctx context.Context, tx Getter, periodID string) (AccountingPeriod, error) {
+	var row AccountingPeriod
+	err := tx.GetContext(ctx, &row, `
+		SELECT `+accountingPeriodColumns+`
+		FROM accounting_periods
+		WHERE id = $1
+		FOR UPDATE
+	`, periodID)
+	if err != nil {
+		return AccountingPeriod{}, err
+	}
+	return row, nil
+}
+
+func (s *PeriodStore) GetByEndForUpdate(ctx context.Context, tx Getter, periodEnd time.Time) (AccountingPeriod, error) {
+	var row AccountingPeriod
+	err := tx.GetContext(ctx, &row, `
+		SELECT `+accountingPeriodColumns+`
+		FROM accounting_periods
+		WHERE period_end = $1
+		FOR UPDATE
+	`, periodEnd)
+	if err != nil {
+		return AccountingPeriod{}, err
+	}
+	return row, nil
+}
+
+func (s *PeriodStore) Latest(ctx context.Context, tx Getter) (AccountingPeriod, error) {
+	var row AccountingPeriod
+	err := tx.GetContext(ctx, &row, `
+		SELECT `+accountingPeriodColumns+`
+		FROM accounting_periods
+		ORDER BY period_end DESC
+		LIMIT 1
+	`)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return AccountingPeriod{}, nil
+		}
+		return AccountingPeriod{}, err
+	}
+	return row, nil
+}
+
+func (s *PeriodStore) ReopenedBefore(ctx context.Context, tx Getter, periodEnd time.Time) (bool, error) {
+	var exists bool
+	err := tx.GetContext(ctx, &exists, `
+		SELECT EXISTS (
+			SELECT 1 FROM accounting_periods WHERE status = 'reopened' AND period_end < $1
+		)
+	`, periodEnd)
+	return exists, err
+}
+
+func (s *PeriodStore) ClosedAfter(ctx context.Context, tx Getter, periodEnd time.Time) (bool, error) {
+	var exists bool
+	err := tx.GetContext(ctx, &exists, `
+		SELECT EXISTS (
+			SELECT 1 FROM accounting_periods WHERE status = 'closed' AND period_end > $1
+		)
+	`, periodEnd)
+	return exists, err
+}
+
+// LockForClose holds the period lock exclusively until tx ends. Postings
+// take it shared in ClosedPeriodAt, so none can land in a period while it
+// is being closed.
+func (s *PeriodStore) LockForClose(ctx context.Context, tx Execer) error {
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended('accounting_periods', 0))`)
+	return err
+}
+
+func (s *PeriodStore) ClosedPeriodAt(ctx context.Context, tx Tx, at *time.Time) (string, error) {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock_shared(hashtextextended('accounting_periods', 0))`); err != nil {
+		return "", err
+	}
+	var periodID string
+	err := tx.GetContext(ctx, &periodID, `
+		SELECT id
+		FROM accounting_periods
+		WHERE status = 'closed'
+		  AND (period_start IS NULL OR period_start <= COALESCE($1::timestamptz, NOW()))
+		  AND period_end >= COALESCE($1::timestamptz, NOW())
+		LIMIT 1
+	`, at)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return periodID, nil
+}
+
+func (s *PeriodStore) Create(ctx context.Context, tx Execer, input AccountingPeriodInput) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO accounting_periods (id, period_start, period_end, status, closed_by)
+		VALUES ($1, $2, $3, 'closed', $4)
+	`, input.ID, input.PeriodStart, input.PeriodEnd, input.ClosedBy)
+	return err
+}
+
+func (s *PeriodStore) MarkClosed(ctx context.Context, tx Execer, periodID, actorID string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE accounting_periods
+		SET status = 'closed', closed_by = $2, closed_at = NOW()
+		WHERE id = $1
+	`, periodID, actorID)
+	return err
+}
+
+func (s *PeriodStore) MarkReopened(ctx context.Context, tx Execer, periodID, actorID, reason string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		UPDATE accounting_periods
+		SET status = 'reopened', reopened_by = $2, reopened_at = NOW(), reopen_reason = $3
+		WHERE id = $1 AND status = 'closed'
+	`, periodID, actorID, reason)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *PeriodStore) SnapshotBalances(ctx context.Context, tx Execer, periodID string, asOf time.Time) (int64, error) {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM period_balances WHERE period_id = $1`, periodID); err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO period_balances (period_id, account_id, currency, balance, last_entry_id, last_entry_seq)
+		SELECT $2, b.account_id, b.currency, b.balance, b.last_entry_id, b.last_entry_seq
+		FROM (`+balanceAsOfQuery+`
+			WHERE a.created_at <= $1
+		) b
+	`, asOf, periodID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *PeriodStore) Balances(ctx context.Context, periodID string) ([]PeriodBalance, error) {
+	var rows []PeriodBalance
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT p.period_id, p.account_id, a.user_id, p.currency, a.is_system, p.balance, p.last_entry_id, p.last_entry_seq
+		FROM period_balances p
+		JOIN accounts a ON a.id = p.account_id
+		WHERE p.period_id = $1
+		ORDER BY a.is_system DESC, a.user_id, p.currency
+	`, periodID)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
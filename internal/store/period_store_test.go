@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPeriodStoreClosedPeriodAt(t *testing.T) {
+	at := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+	store := NewPeriodStore(stubDB{})
+	locked := false
+	periodID, err := store.ClosedPeriodAt(context.Background(), stubDB{
+		execFn: func(_ context.Context, query string, _ ...any) (sql.Result, error) {
+			if !strings.Contains(query, "pg_advisory_xact_lock_shared") {
+				t.Fatalf("unexpected lock: %s", query)
+			}
+			locked = true
+			return stubResult{}, nil
+		},
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !locked {
+				t.Fatalf("expected the period lock before the check")
+			}
+			if !strings.Contains(query, "status = 'closed'") || !strings.Contains(query, "COALESCE($1::timestamptz, NOW())") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 1 || args[0] != &at {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*string) = "period-1"
+			return nil
+		},
+	}, &at)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if periodID != "period-1" {
+		t.Fatalf("unexpected period: %q", periodID)
+	}
+}
+
+func TestPeriodStoreClosedPeriodAtOpen(t *testing.T) {
+	store := NewPeriodStore(stubDB{})
+	periodID, err := store.ClosedPeriodAt(context.Background(), stubDB{
+		getFn: func(context.Context, any, string, ...any) error {
+			return sql.ErrNoRows
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if periodID != "" {
+		t.Fatalf("expected open period, got %q", periodID)
+	}
+}
+
+func TestPeriodStoreLatestNoRows(t *testing.T) {
+	store := NewPeriodStore(stubDB{})
+	period, err := store.Latest(context.Background(), stubGetter{
+		getFn: func(context.Context, any, string, ...any) error {
+			return sql.ErrNoRows
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if period.ID != "" {
+		t.Fatalf("expected empty period, got %#v", period)
+	}
+}
+
+func TestPeriodStoreSnapshotBalances(t *testing.T) {
+	asOf := time.Date(2025, 3, 31, 23, 59, 59, 999999000, time.UTC)
+	var queries []string
+	execer := stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			queries = append(queries, query)
+			if strings.Contains(query, "INSERT INTO period_balances") {
+				if !strings.Contains(query, "last_entry_at <= $1") || len(args) != 2 || args[0] != asOf || args[1] != "period-1" {
+					t.Fatalf("unexpected snapshot: %s %#v", query, args)
+				}
+				return stubResult{rows: 4}, nil
+			}
+			return stubResult{rows: 0}, nil
+		},
+	}
+	store := NewPeriodStore(stubDB{})
+	accounts, err := store.SnapshotBalances(context.Background(), execer, "period-1", asOf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if accounts != 4 {
+		t.Fatalf("expected 4 accounts, got %d", accounts)
+	}
+	if len(queries) != 2 || !strings.Contains(queries[0], "DELETE FROM period_balances") {
+		t.Fatalf("expected previous snapshot to be replaced: %#v", queries)
+	}
+}
+
+func TestPeriodStoreMarkReopened(t *testing.T) {
+	store := NewPeriodStore(stubDB{})
+	updated, err := store.MarkReopened(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "status = 'reopened'") || !strings.Contains(query, "AND status = 'closed'") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 3 || args[0] != "period-1" || args[1] != "admin-1" || args[2] != "late invoice" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	}, "period-1", "admin-1", "late invoice")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated != 1 {
+		t.Fatalf("expected 1 row, got %d", updated)
+	}
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type RateLimitStore struct {
+	db DB
+}
+
+func NewRateLimitStore(db DB) *RateLimitStore {
+	return &RateLimitStore{db: db}
+}
+
+// A refused Take leaves the bucket as it was, so refilling carries on.
+func (s *RateLimitStore) Take(ctx context.Context, key string, limit int, window time.Duration) (bool, float64, error) {
+	rate := float64(limit) / window.Seconds()
+	var tokens float64
+	err := s.db.GetContext(ctx, &tokens, `
+		INSERT INTO rate_limit_buckets AS b (key, tokens, updated_at)
+		VALUES ($1, $2 - 1, NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = LEAST($2, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * $3) - 1,
+			updated_at = NOW()
+		WHERE LEAST($2, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * $3) >= 1
+		RETURNING tokens
+	`, key, limit, rate)
+	if err == nil {
+		return true, tokens, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, 0, err
+	}
+	err = s.db.GetContext(ctx, &tokens, `
+		SELECT LEAST($2, tokens + EXTRACT(EPOCH FROM NOW() - updated_at) * $3)
+		FROM rate_limit_buckets
+		WHERE key = $1
+	`, key, limit, rate)
+	return false, tokens, err
+}
+
+func (s *RateLimitStore) Prune(ctx context.Context, idle time.Duration) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM rate_limit_buckets
+		WHERE updated_at < NOW() - make_interval(secs => $1)
+	`, idle.Seconds())
+	return err
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitStoreTake(t *testing.T) {
+	store := NewRateLimitStore(stubDB{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "ON CONFLICT (key) DO UPDATE") || !strings.Contains(query, ">= 1") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 3 || args[0] != "quote:user:user-1" || args[1] != 30 || args[2] != 0.5 {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*float64) = 12.5
+			return nil
+		},
+	})
+	allowed, tokens, err := store.Take(context.Background(), "quote:user:user-1", 30, time.Minute)
+	if err != nil || !allowed || tokens != 12.5 {
+		t.Fatalf("unexpected result: %v %v %v", allowed, tokens, err)
+	}
+}
+
+func TestRateLimitStoreTakeRefused(t *testing.T) {
+	calls := 0
+	store := NewRateLimitStore(stubDB{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			calls++
+			if calls == 1 {
+				return sql.ErrNoRows
+			}
+			if !strings.Contains(query, "FROM rate_limit_buckets") || args[0] != "register:ip:10.0.0.1" {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			*dest.(*float64) = 0.25
+			return nil
+		},
+	})
+	allowed, tokens, err := store.Take(context.Background(), "register:ip:10.0.0.1", 5, time.Hour)
+	if err != nil || allowed || tokens != 0.25 || calls != 2 {
+		t.Fatalf("unexpected result: %v %v %v after %d queries", allowed, tokens, err, calls)
+	}
+}
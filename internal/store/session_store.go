@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type SessionStore struct {
+	db DB
+}
+
+type RefreshToken struct {
+	TokenHash        string     `db:"token_hash"`
+	SessionID        string     `db:"session_id"`
+	UserID           string     `db:"user_id"`
+	ExpiresAt        time.Time  `db:"expires_at"`
+	UsedAt           *time.Time `db:"used_at"`
+	SessionRevokedAt *time.Time `db:"session_revoked_at"`
+}
+
+type SessionInput struct {
+	ID          string
+	UserID      string
+	DeviceLabel string
+	IP          string
+	UserAgent   string
+}
+
+type Session struct {
+	ID          string    `db:"id"`
+	DeviceLabel string    `db:"device_label"`
+	IP          string    `db:"ip"`
+	UserAgent   string    `db:"user_agent"`
+	CreatedAt   time.Time `db:"created_at"`
+	LastSeenAt  time.Time `db:"last_seen_at"`
+}
+
+type RefreshTokenInput struct {
+	TokenHash string
+	SessionID string
+	UserID    string
+	TTL       time.Duration
+}
+
+func NewSessionStore(db DB) *SessionStore {
+	return &SessionStore{db: db}
+}
+
+func (s *SessionStore) CreateSession(ctx context.Context, tx Execer, input SessionInput) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO auth_sessions (id, user_id, device_label, ip, user_agent)
+		VALUES ($1, $2, $3, $4, $5)
+	`, input.ID, input.UserID, input.DeviceLabel, input.IP, input.UserAgent)
+	return err
+}
+
+// A session expires with its last unused refresh token.
+func (s *SessionStore) ListActive(ctx context.Context, userID string) ([]Session, error) {
+	var sessions []Session
+	err := s.db.SelectContext(ctx, &sessions, `
+		SELECT s.id, s.device_label, s.ip, s.user_agent, s.created_at,
+		       COALESCE(s.last_refreshed_at, s.created_at) AS last_seen_at
+		FROM auth_sessions s
+		WHERE s.user_id = $1 AND s.revoked_at IS NULL
+		  AND EXISTS (
+			SELECT 1 FROM refresh_tokens rt
+			WHERE rt.session_id = s.id AND rt.used_at IS NULL AND rt.expires_at > NOW()
+		  )
+		ORDER BY last_seen_at DESC, s.id
+	`, userID)
+	return sessions, err
+}
+
+func (s *SessionStore) AddRefreshToken(ctx context.Context, tx Execer, input RefreshTokenInput) error {
+	_, err := tx.ExecContext(ctx, `
+		WITH touched AS (
+			UPDATE auth_sessions SET last_refreshed_at = NOW() WHERE id = $2
+		)
+		INSERT INTO refresh_tokens (token_hash, session_id, user_id, expires_at)
+		VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+	`, input.TokenHash, input.SessionID, input.UserID, input.TTL.Seconds())
+	return err
+}
+
+// GetRefreshToken locks the token row so concurrent refreshes with the same
+// token are serialized and the second sees it used.
+func (s *SessionStore) GetRefreshToken(ctx context.Context, tx Getter, tokenHash string) (RefreshToken, error) {
+	var token RefreshToken
+	err := tx.GetContext(ctx, &token, `
+		SELECT rt.token_hash, rt.session_id, rt.user_id, rt.expires_at, rt.used_at, s.revoked_at AS session_revoked_at
+		FROM refresh_tokens rt
+		JOIN auth_sessions s ON s.id = rt.session_id
+		WHERE rt.token_hash = $1
+		FOR UPDATE OF rt
+	`, tokenHash)
+	return token, err
+}
+
+func (s *SessionStore) MarkRefreshTokenUsed(ctx context.Context, tx Execer, tokenHash string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET used_at = NOW() WHERE token_hash = $1
+	`, tokenHash)
+	return err
+}
+
+func (s *SessionStore) RevokeAll(ctx context.Context, tx Selecter, userID, exceptSessionID, reason string) ([]string, error) {
+	var ids []string
+	err := tx.SelectContext(ctx, &ids, `
+		UPDATE auth_sessions
+		SET revoked_at = NOW(), revoked_reason = $3
+		WHERE user_id = $1 AND revoked_at IS NULL AND id <> $2
+		RETURNING id
+	`, userID, exceptSessionID, reason)
+	return ids, err
+}
+
+func (s *SessionStore) Revoke(ctx context.Context, tx Getter, userID, sessionID, reason string) (bool, error) {
+	var id string
+	err := tx.GetContext(ctx, &id, `
+		UPDATE auth_sessions
+		SET revoked_at = NOW(), revoked_reason = $3
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+		RETURNING id
+	`, sessionID, userID, reason)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *SessionStore) ListRevoked(ctx context.Context, window time.Duration) ([]string, error) {
+	var ids []string
+	err := s.db.SelectContext(ctx, &ids, `
+		SELECT id
+		FROM auth_sessions
+		WHERE revoked_at >= NOW() - $1 * INTERVAL '1 millisecond'
+	`, window.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
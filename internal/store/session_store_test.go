@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionStoreAddRefreshToken(t *testing.T) {
+	store := NewSessionStore(stubDB{})
+	err := store.AddRefreshToken(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "INSERT INTO refresh_tokens") || !strings.Contains(query, "last_refreshed_at = NOW()") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 4 || args[0] != "hash" || args[1] != "session-1" || args[2] != "user-1" || args[3] != float64(3600) {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	}, RefreshTokenInput{TokenHash: "hash", SessionID: "session-1", UserID: "user-1", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSessionStoreGetRefreshTokenLocks(t *testing.T) {
+	store := NewSessionStore(stubDB{})
+	token, err := store.GetRefreshToken(context.Background(), stubGetter{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "FOR UPDATE OF rt") || args[0] != "hash" {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			*dest.(*RefreshToken) = RefreshToken{SessionID: "session-1"}
+			return nil
+		},
+	}, "hash")
+	if err != nil || token.SessionID != "session-1" {
+		t.Fatalf("unexpected result: %#v %v", token, err)
+	}
+}
+
+func TestSessionStoreRevoke(t *testing.T) {
+	store := NewSessionStore(stubDB{})
+	revoked, err := store.Revoke(context.Background(), stubGetter{
+		getFn: func(_ context.Context, _ any, query string, args ...any) error {
+			if !strings.Contains(query, "revoked_at IS NULL") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 3 || args[0] != "session-1" || args[1] != "user-1" || args[2] != "logout" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return nil
+		},
+	}, "user-1", "session-1", "logout")
+	if err != nil || !revoked {
+		t.Fatalf("expected revocation, got %v %v", revoked, err)
+	}
+
+	revoked, err = store.Revoke(context.Background(), stubGetter{
+		getFn: func(context.Context, any, string, ...any) error {
+			return sql.ErrNoRows
+		},
+	}, "user-1", "session-1", "logout")
+	if err != nil || revoked {
+		t.Fatalf("expected already revoked session to report false, got %v %v", revoked, err)
+	}
+}
+
+func TestSessionStoreListRevoked(t *testing.T) {
+	store := NewSessionStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "revoked_at >= NOW()") || args[0] != int64(900000) {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			*dest.(*[]string) = []string{"session-1"}
+			return nil
+		},
+	})
+	ids, err := store.ListRevoked(context.Background(), 15*time.Minute)
+	if err != nil || len(ids) != 1 || ids[0] != "session-1" {
+		t.Fatalf("unexpected result: %v %v", ids, err)
+	}
+}
+
+func TestSessionStoreRevokeAll(t *testing.T) {
+	store := NewSessionStore(stubDB{})
+	ids, err := store.RevokeAll(context.Background(), stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "WHERE user_id = $1 AND revoked_at IS NULL AND id <> $2") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 3 || args[0] != "user-1" || args[1] != "session-3" || args[2] != "revoke_others" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*[]string) = []string{"session-1", "session-2"}
+			return nil
+		},
+	}, "user-1", "session-3", "revoke_others")
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("unexpected result: %v %v", ids, err)
+	}
+}
+
+func TestSessionStoreCreateSession(t *testing.T) {
+	store := NewSessionStore(stubDB{})
+	err := store.CreateSession(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "INSERT INTO auth_sessions (id, user_id, device_label, ip, user_agent)") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 5 || args[0] != "session-1" || args[2] != "Firefox on Linux" || args[3] != "10.0.0.1" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	}, SessionInput{ID: "session-1", UserID: "user-1", DeviceLabel: "Firefox on Linux", IP: "10.0.0.1", UserAgent: "Mozilla/5.0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSessionStoreListActive(t *testing.T) {
+	store := NewSessionStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "s.revoked_at IS NULL") || !strings.Contains(query, "rt.expires_at > NOW()") || args[0] != "user-1" {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			*dest.(*[]Session) = []Session{{ID: "session-1"}}
+			return nil
+		},
+	})
+	sessions, err := store.ListActive(context.Background(), "user-1")
+	if err != nil || len(sessions) != 1 || sessions[0].ID != "session-1" {
+		t.Fatalf("unexpected result: %v %v", sessions, err)
+	}
+}
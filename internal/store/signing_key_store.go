@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type SigningKeyStore struct {
+	db DB
+}
+
+type SigningKey struct {
+	ID          string    `db:"kid"`
+	Generation  int       `db:"generation"`
+	Algorithm   string    `db:"algorithm"`
+	PrivateKey  string    `db:"private_key"`
+	ActivatesAt time.Time `db:"activates_at"`
+	RetiresAt   time.Time `db:"retires_at"`
+}
+
+func NewSigningKeyStore(db DB) *SigningKeyStore {
+	return &SigningKeyStore{db: db}
+}
+
+func (s *SigningKeyStore) Latest(ctx context.Context) (SigningKey, error) {
+	var key SigningKey
+	err := s.db.GetContext(ctx, &key, `
+		SELECT kid, generation, algorithm, private_key, activates_at, retires_at
+		FROM jwt_signing_keys
+		ORDER BY generation DESC
+		LIMIT 1
+	`)
+	return key, err
+}
+
+func (s *SigningKeyStore) Create(ctx context.Context, key SigningKey) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO jwt_signing_keys (kid, generation, algorithm, private_key, activates_at, retires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (generation) DO NOTHING
+	`, key.ID, key.Generation, key.Algorithm, key.PrivateKey, key.ActivatesAt, key.RetiresAt)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	return rows > 0, err
+}
+
+func (s *SigningKeyStore) ListUsable(ctx context.Context, retiredWithin time.Duration) ([]SigningKey, error) {
+	var keys []SigningKey
+	err := s.db.SelectContext(ctx, &keys, `
+		SELECT kid, generation, algorithm, private_key, activates_at, retires_at
+		FROM jwt_signing_keys
+		WHERE retires_at > NOW() - make_interval(secs => $1)
+		ORDER BY generation
+	`, retiredWithin.Seconds())
+	return keys, err
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSigningKeyStoreCreateIgnoresTakenGeneration(t *testing.T) {
+	store := NewSigningKeyStore(stubDB{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "ON CONFLICT (generation) DO NOTHING") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 6 || args[0] != "kid-2" || args[1] != 2 {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 0}, nil
+		},
+	})
+	created, err := store.Create(context.Background(), SigningKey{ID: "kid-2", Generation: 2, Algorithm: "EdDSA"})
+	if err != nil || created {
+		t.Fatalf("expected the key to be skipped, got %v %v", created, err)
+	}
+}
+
+func TestSigningKeyStoreListUsable(t *testing.T) {
+	store := NewSigningKeyStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "retires_at > NOW() - make_interval(secs => $1)") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 1 || args[0] != float64(900) {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*[]SigningKey) = []SigningKey{{ID: "kid-1", Generation: 1}}
+			return nil
+		},
+	})
+	keys, err := store.ListUsable(context.Background(), 15*time.Minute)
+	if err != nil || len(keys) != 1 || keys[0].ID != "kid-1" {
+		t.Fatalf("unexpected keys: %#v %v", keys, err)
+	}
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type StreamStore struct {
+	db DB
+}
+
+type StreamEvent struct {
+	UserID    string    `db:"user_id"`
+	Seq       int64     `db:"seq"`
+	EventID   string    `db:"event_id"`
+	AccountID string    `db:"account_id"`
+	Payload   string    `db:"payload"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type StreamEventInput struct {
+	UserID    string
+	EventID   string
+	AccountID string
+	Payload   string
+}
+
+func NewStreamStore(db DB) *StreamStore {
+	return &StreamStore{db: db}
+}
+
+// Appending the same event and account again returns the seq it already has,
+// so redelivered outbox events neither duplicate entries nor leave gaps.
+func (s *StreamStore) Append(ctx context.Context, input StreamEventInput) (int64, error) {
+	var seq int64
+	err := s.db.GetContext(ctx, &seq, `
+		WITH existing AS (
+			SELECT seq FROM user_stream_events
+			WHERE user_id = $1 AND event_id = $2 AND account_id = $3
+		), head AS (
+			INSERT INTO user_stream_heads (user_id, last_seq)
+			SELECT $1, 1 WHERE NOT EXISTS (SELECT 1 FROM existing)
+			ON CONFLICT (user_id) DO UPDATE SET last_seq = user_stream_heads.last_seq + 1
+			RETURNING last_seq
+		), inserted AS (
+			INSERT INTO user_stream_events (user_id, seq, event_id, account_id, payload)
+			SELECT $1, last_seq, $2, $3, $4::jsonb FROM head
+			RETURNING seq
+		)
+		SELECT seq FROM inserted
+		UNION ALL
+		SELECT seq FROM existing
+	`, input.UserID, input.EventID, input.AccountID, input.Payload)
+	return seq, err
+}
+
+func (s *StreamStore) Head(ctx context.Context, userID string) (int64, error) {
+	var seq int64
+	err := s.db.GetContext(ctx, &seq, `
+		SELECT COALESCE((SELECT last_seq FROM user_stream_heads WHERE user_id = $1), 0)
+	`, userID)
+	return seq, err
+}
+
+func (s *StreamStore) Since(ctx context.Context, userID string, afterSeq int64, limit int) ([]StreamEvent, error) {
+	var rows []StreamEvent
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT user_id, seq, event_id, account_id, payload::text AS payload, created_at
+		FROM user_stream_events
+		WHERE user_id = $1 AND seq > $2
+		ORDER BY seq
+		LIMIT $3
+	`, userID, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestStreamStoreAppend(t *testing.T) {
+	store := NewStreamStore(stubDB{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "ON CONFLICT (user_id) DO UPDATE SET last_seq = user_stream_heads.last_seq + 1") || !strings.Contains(query, "SELECT seq FROM existing") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 4 || args[0] != "user-1" || args[1] != "event-1" || args[2] != "acc-1" || args[3] != `{"balance":"1.00"}` {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*int64) = 7
+			return nil
+		},
+	})
+	seq, err := store.Append(context.Background(), StreamEventInput{UserID: "user-1", EventID: "event-1", AccountID: "acc-1", Payload: `{"balance":"1.00"}`})
+	if err != nil || seq != 7 {
+		t.Fatalf("unexpected result: %d %v", seq, err)
+	}
+}
+
+func TestStreamStoreSince(t *testing.T) {
+	store := NewStreamStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "seq > $2") || !strings.Contains(query, "ORDER BY seq") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 3 || args[0] != "user-1" || args[1] != int64(4) || args[2] != 101 {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*[]StreamEvent) = []StreamEvent{{Seq: 5}, {Seq: 6}}
+			return nil
+		},
+	})
+	rows, err := store.Since(context.Background(), "user-1", 4, 101)
+	if err != nil || len(rows) != 2 || rows[1].Seq != 6 {
+		t.Fatalf("unexpected result: %#v %v", rows, err)
+	}
+}
@@ -26,22 +26,46 @@ type transactionRow struct {
 	CreatedAt      any     `db:"created_at"`
 }
 
+type TransactionRecord struct {
+	ID            string  `db:"id"`
+	UserID        string  `db:"user_id"`
+	Type          string  `db:"type"`
+	Status        string  `db:"status"`
+	Amount        int64   `db:"amount"`
+	Currency      string  `db:"currency"`
+	FromAccountID *string `db:"from_account_id"`
+	ToAccountID   *string `db:"to_account_id"`
+	ReversedBy    *string `db:"reversed_by"`
+}
+
 func NewTransactionStore(db DB) *TransactionStore {
 	return &TransactionStore{db: db}
 }
 
 func (s *TransactionStore) Create(ctx context.Context, tx Execer, input TransactionInput) error {
 	query := `
-		INSERT INTO transactions (id, user_id, type, status, amount, currency, from_account_id, to_account_id, exchange_rate_id, metadata, client_request_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO transactions (id, user_id, type, status, amount, currency, from_account_id, to_account_id, exchange_rate_id, metadata, client_request_id, reversal_of)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
 	_, err := tx.ExecContext(ctx, query,
 		input.ID, input.UserID, input.Type, input.Status, input.Amount, input.Currency,
-		input.FromAccountID, input.ToAccountID, input.ExchangeRateID, input.Metadata, input.ClientRequestID,
+		input.FromAccountID, input.ToAccountID, input.ExchangeRateID, input.Metadata, input.ClientRequestID, input.ReversalOf,
 	)
 	return err
 }
 
+func (s *TransactionStore) GetForReversal(ctx context.Context, tx Getter, transactionID string) (TransactionRecord, error) {
+	var row TransactionRecord
+	err := tx.GetContext(ctx, &row, `
+		SELECT t.id, t.user_id, t.type, t.status, t.amount, t.currency, t.from_account_id, t.to_account_id,
+		       (SELECT r.id FROM transactions r WHERE r.reversal_of = t.id) AS reversed_by
+		FROM transactions t
+		WHERE t.id = $1
+		FOR UPDATE
+	`, transactionID)
+	return row, err
+}
+
 func (s *TransactionStore) UpdateStatus(ctx context.Context, tx Execer, transactionID, status string) error {
 	_, err := tx.ExecContext(ctx, `UPDATE transactions SET status = $1 WHERE id = $2`, status, transactionID)
 	return err
@@ -110,6 +134,7 @@ type TransactionInput struct {
 	ExchangeRateID  *string
 	Metadata        string
 	ClientRequestID *string
+	ReversalOf      *string
 }
 
 func itoa(value int) string {
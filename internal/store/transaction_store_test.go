@@ -14,7 +14,7 @@ func TestTransactionStoreCreate(t *testing.T) {
 			if !strings.Contains(query, "INSERT INTO transactions") {
 				t.Fatalf("unexpected query: %s", query)
 			}
-			if len(args) != 11 || args[0] != "tx-1" {
+			if len(args) != 12 || args[0] != "tx-1" {
 				t.Fatalf("unexpected args: %#v", args)
 			}
 			return stubResult{rows: 1}, nil
@@ -27,6 +27,30 @@ func TestTransactionStoreCreate(t *testing.T) {
 	}
 }
 
+func TestTransactionStoreGetForReversal(t *testing.T) {
+	getter := stubGetter{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "r.reversal_of = t.id") || !strings.Contains(query, "FOR UPDATE") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 1 || args[0] != "tx-1" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			reversedBy := "tx-2"
+			*dest.(*TransactionRecord) = TransactionRecord{ID: "tx-1", ReversedBy: &reversedBy}
+			return nil
+		},
+	}
+	store := NewTransactionStore(stubDB{})
+	row, err := store.GetForReversal(context.Background(), getter, "tx-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if row.ReversedBy == nil || *row.ReversedBy != "tx-2" {
+		t.Fatalf("unexpected row: %#v", row)
+	}
+}
+
 func TestTransactionStoreUpdateStatus(t *testing.T) {
 	ctx := context.Background()
 	execer := stubExecer{
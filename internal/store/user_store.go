@@ -1,6 +1,11 @@
 package store
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
 
 type UserStore struct {
 	db DB
@@ -16,6 +21,8 @@ type userRow struct {
 	Email        string `db:"email"`
 	PasswordHash string `db:"password_hash"`
 	CreatedAt    any    `db:"created_at"`
+
+	EmailVerifiedAt *time.Time `db:"email_verified_at"`
 }
 
 func (s *UserStore) Create(ctx context.Context, tx Execer, id, username, email, passwordHash string) error {
@@ -58,14 +65,137 @@ func (s *UserStore) GetByUsername(ctx context.Context, username string) (map[str
 
 func (s *UserStore) GetByID(ctx context.Context, userID string) (map[string]any, error) {
 	var row userRow
-	err := s.db.GetContext(ctx, &row, `SELECT id, username, email, created_at FROM users WHERE id = $1`, userID)
+	err := s.db.GetContext(ctx, &row, `SELECT id, username, email, password_hash, created_at, email_verified_at FROM users WHERE id = $1`, userID)
 	if err != nil {
 		return nil, err
 	}
 	return map[string]any{
-		"id":         row.ID,
-		"username":   row.Username,
-		"email":      row.Email,
-		"created_at": row.CreatedAt,
+		"id":                row.ID,
+		"username":          row.Username,
+		"email":             row.Email,
+		"password_hash":     row.PasswordHash,
+		"created_at":        row.CreatedAt,
+		"email_verified_at": row.EmailVerifiedAt,
 	}, nil
 }
+
+func (s *UserStore) UpdatePassword(ctx context.Context, tx Execer, userID, passwordHash string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `UPDATE users SET password_hash = $2 WHERE id = $1`, userID, passwordHash)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *UserStore) EmailVerified(ctx context.Context, userID string) (bool, error) {
+	var verified bool
+	err := s.db.GetContext(ctx, &verified, `SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1`, userID)
+	return verified, err
+}
+
+func (s *UserStore) MarkEmailVerified(ctx context.Context, tx Execer, userID, email string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW())
+		WHERE id = $1 AND email = $2
+	`, userID, email)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+type TOTPState struct {
+	Email       string  `db:"email"`
+	Secret      *string `db:"totp_secret"`
+	Enabled     bool    `db:"totp_enabled"`
+	LastCounter *int64  `db:"totp_last_counter"`
+}
+
+func (s *UserStore) GetTOTP(ctx context.Context, userID string) (TOTPState, error) {
+	var state TOTPState
+	err := s.db.GetContext(ctx, &state, `
+		SELECT email, totp_secret, totp_enabled, totp_last_counter
+		FROM users
+		WHERE id = $1
+	`, userID)
+	return state, err
+}
+
+func (s *UserStore) SetPendingTOTP(ctx context.Context, tx Execer, userID, sealedSecret string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET totp_secret = $2, totp_last_counter = NULL
+		WHERE id = $1 AND NOT totp_enabled
+	`, userID, sealedSecret)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *UserStore) EnableTOTP(ctx context.Context, tx Execer, userID string, counter int64) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET totp_enabled = TRUE, totp_enabled_at = NOW(), totp_last_counter = $2
+		WHERE id = $1 AND totp_secret IS NOT NULL AND NOT totp_enabled
+	`, userID, counter)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *UserStore) DisableTOTP(ctx context.Context, tx Execer, userID string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		WITH codes AS (
+			DELETE FROM user_recovery_codes WHERE user_id = $1
+		)
+		UPDATE users
+		SET totp_secret = NULL, totp_enabled = FALSE, totp_enabled_at = NULL, totp_last_counter = NULL
+		WHERE id = $1 AND totp_enabled
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// UseTOTPCounter records counter as the last accepted code. It reports false
+// when a code at or after it was already used, so each code works once.
+func (s *UserStore) UseTOTPCounter(ctx context.Context, userID string, counter int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE users
+		SET totp_last_counter = $2
+		WHERE id = $1 AND totp_enabled AND COALESCE(totp_last_counter, -1) < $2
+	`, userID, counter)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	return rows == 1, err
+}
+
+func (s *UserStore) ReplaceRecoveryCodes(ctx context.Context, tx Execer, userID string, codeHashes []string) error {
+	_, err := tx.ExecContext(ctx, `
+		WITH cleared AS (
+			DELETE FROM user_recovery_codes WHERE user_id = $1
+		)
+		INSERT INTO user_recovery_codes (user_id, code_hash)
+		SELECT $1, UNNEST($2::text[])
+	`, userID, pq.StringArray(codeHashes))
+	return err
+}
+
+func (s *UserStore) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE user_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	return rows == 1, err
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"strings"
 	"testing"
+
+	"github.com/lib/pq"
 )
 
 func TestUserStoreCreate(t *testing.T) {
@@ -91,3 +93,76 @@ func TestUserStoreGetByID(t *testing.T) {
 		t.Fatalf("unexpected row: %#v", row)
 	}
 }
+
+func TestUserStoreUpdatePassword(t *testing.T) {
+	execer := stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "UPDATE users SET password_hash = $2") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != "user-1" || args[1] != "hash" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	}
+	rows, err := NewUserStore(stubDB{}).UpdatePassword(context.Background(), execer, "user-1", "hash")
+	if err != nil || rows != 1 {
+		t.Fatalf("unexpected result: %d %v", rows, err)
+	}
+}
+
+func TestUserStoreUseTOTPCounterRejectsReplay(t *testing.T) {
+	store := NewUserStore(stubDB{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "COALESCE(totp_last_counter, -1) < $2") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != "user-1" || args[1] != int64(41152263) {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 0}, nil
+		},
+	})
+	ok, err := store.UseTOTPCounter(context.Background(), "user-1", 41152263)
+	if err != nil || ok {
+		t.Fatalf("expected a used counter to be rejected, got %v %v", ok, err)
+	}
+}
+
+func TestUserStoreReplaceRecoveryCodes(t *testing.T) {
+	store := NewUserStore(stubDB{})
+	err := store.ReplaceRecoveryCodes(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "DELETE FROM user_recovery_codes") || !strings.Contains(query, "UNNEST($2::text[])") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			hashes, ok := args[1].(pq.StringArray)
+			if !ok || len(hashes) != 2 || args[0] != "user-1" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 2}, nil
+		},
+	}, "user-1", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestUserStoreMarkEmailVerifiedChecksAddress(t *testing.T) {
+	store := NewUserStore(stubDB{})
+	updated, err := store.MarkEmailVerified(context.Background(), stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "WHERE id = $1 AND email = $2") || !strings.Contains(query, "COALESCE(email_verified_at, NOW())") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != "user-1" || args[1] != "old@example.com" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 0}, nil
+		},
+	}, "user-1", "old@example.com")
+	if err != nil || updated != 0 {
+		t.Fatalf("unexpected result: %d %v", updated, err)
+	}
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+)
+
+type UserTokenStore struct {
+	db DB
+}
+
+type UserTokenInput struct {
+	TokenHash string
+	UserID    string
+	Purpose   string
+	Email     string
+	TTL       time.Duration
+	Cooldown  time.Duration
+}
+
+type UserToken struct {
+	UserID string `db:"user_id"`
+	Email  string `db:"email"`
+}
+
+func NewUserTokenStore(db DB) *UserTokenStore {
+	return &UserTokenStore{db: db}
+}
+
+// Create discards the user's unused tokens for the purpose, so only the
+// latest mail works.
+func (s *UserTokenStore) Create(ctx context.Context, tx Execer, input UserTokenInput) (bool, error) {
+	res, err := tx.ExecContext(ctx, `
+		WITH recent AS (
+			SELECT 1 FROM user_tokens
+			WHERE user_id = $2 AND purpose = $3 AND created_at > NOW() - make_interval(secs => $6)
+		), discarded AS (
+			DELETE FROM user_tokens
+			WHERE user_id = $2 AND purpose = $3 AND used_at IS NULL
+			  AND NOT EXISTS (SELECT 1 FROM recent)
+		)
+		INSERT INTO user_tokens (token_hash, user_id, purpose, email, expires_at)
+		SELECT $1, $2, $3, $4, NOW() + make_interval(secs => $5)
+		WHERE NOT EXISTS (SELECT 1 FROM recent)
+	`, input.TokenHash, input.UserID, input.Purpose, input.Email, input.TTL.Seconds(), input.Cooldown.Seconds())
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	return rows > 0, err
+}
+
+func (s *UserTokenStore) Consume(ctx context.Context, tx Getter, tokenHash, purpose string) (UserToken, error) {
+	var token UserToken
+	err := tx.GetContext(ctx, &token, `
+		UPDATE user_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id, email
+	`, tokenHash, purpose)
+	return token, err
+}
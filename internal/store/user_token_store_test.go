@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserTokenStoreCreateRespectsCooldown(t *testing.T) {
+	store := NewUserTokenStore(stubDB{})
+	tx := stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "INSERT INTO user_tokens") || !strings.Contains(query, "WHERE NOT EXISTS (SELECT 1 FROM recent)") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 6 || args[2] != TokenResetPassword || args[4] != float64(3600) || args[5] != float64(60) {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 0}, nil
+		},
+	}
+	created, err := store.Create(context.Background(), tx, UserTokenInput{
+		TokenHash: "hash",
+		UserID:    "user-1",
+		Purpose:   TokenResetPassword,
+		Email:     "alice@example.com",
+		TTL:       time.Hour,
+		Cooldown:  time.Minute,
+	})
+	if err != nil || created {
+		t.Fatalf("expected no token within the cooldown, got %v %v", created, err)
+	}
+}
+
+func TestUserTokenStoreConsume(t *testing.T) {
+	store := NewUserTokenStore(stubDB{})
+	tx := stubGetter{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "used_at IS NULL AND expires_at > NOW()") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != "hash" || args[1] != TokenVerifyEmail {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*UserToken) = UserToken{UserID: "user-1", Email: "alice@example.com"}
+			return nil
+		},
+	}
+	token, err := store.Consume(context.Background(), tx, "hash", TokenVerifyEmail)
+	if err != nil || token.UserID != "user-1" || token.Email != "alice@example.com" {
+		t.Fatalf("unexpected token: %#v %v", token, err)
+	}
+}
@@ -0,0 +1,277 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type WebhookStore struct {
+	db DB
+}
+
+type WebhookEndpoint struct {
+	ID                  string         `db:"id"`
+	OwnerUserID         string         `db:"owner_user_id"`
+	Scope               string         `db:"scope"`
+	URL                 string         `db:"url"`
+	Secret              string         `db:"secret"`
+	EventTypes          pq.StringArray `db:"event_types"`
+	Active              bool           `db:"active"`
+	ConsecutiveFailures int            `db:"consecutive_failures"`
+	DisabledAt          *time.Time     `db:"disabled_at"`
+	CreatedAt           time.Time      `db:"created_at"`
+}
+
+type WebhookEndpointInput struct {
+	ID          string
+	OwnerUserID string
+	Scope       string
+	URL         string
+	Secret      string
+	EventTypes  []string
+}
+
+type WebhookDelivery struct {
+	ID             string     `db:"id"`
+	EndpointID     string     `db:"endpoint_id"`
+	EventID        string     `db:"event_id"`
+	EventType      string     `db:"event_type"`
+	Payload        string     `db:"payload"`
+	Status         string     `db:"status"`
+	Attempts       int        `db:"attempts"`
+	LastStatusCode *int       `db:"last_status_code"`
+	LastError      *string    `db:"last_error"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at"`
+	DeliveredAt    *time.Time `db:"delivered_at"`
+	CreatedAt      time.Time  `db:"created_at"`
+}
+
+type WebhookDeliveryInput struct {
+	ID         string
+	EndpointID string
+	EventID    string
+	EventType  string
+	Payload    string
+}
+
+type PendingWebhookDelivery struct {
+	WebhookDelivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+type WebhookAttempt struct {
+	ID          int64     `db:"id"`
+	DeliveryID  string    `db:"delivery_id"`
+	StatusCode  *int      `db:"status_code"`
+	Error       *string   `db:"error"`
+	AttemptedAt time.Time `db:"attempted_at"`
+}
+
+const webhookEndpointColumns = `id, owner_user_id, scope, url, secret, event_types, active,
+	consecutive_failures, disabled_at, created_at`
+
+const webhookDeliveryColumns = `id, endpoint_id, event_id, event_type, payload::text AS payload, status, attempts,
+	last_status_code, last_error, next_attempt_at, delivered_at, created_at`
+
+func NewWebhookStore(db DB) *WebhookStore {
+	return &WebhookStore{db: db}
+}
+
+func (s *WebhookStore) CreateEndpoint(ctx context.Context, input WebhookEndpointInput) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_endpoints (id, owner_user_id, scope, url, secret, event_types)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, input.ID, input.OwnerUserID, input.Scope, input.URL, input.Secret, pq.StringArray(input.EventTypes))
+	return err
+}
+
+func (s *WebhookStore) ListEndpoints(ctx context.Context, ownerUserID string) ([]WebhookEndpoint, error) {
+	var rows []WebhookEndpoint
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT `+webhookEndpointColumns+`
+		FROM webhook_endpoints
+		WHERE owner_user_id = $1
+		ORDER BY created_at DESC
+	`, ownerUserID)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *WebhookStore) GetEndpoint(ctx context.Context, endpointID string) (WebhookEndpoint, error) {
+	var row WebhookEndpoint
+	err := s.db.GetContext(ctx, &row, `
+		SELECT `+webhookEndpointColumns+`
+		FROM webhook_endpoints
+		WHERE id = $1
+	`, endpointID)
+	if err != nil {
+		return WebhookEndpoint{}, err
+	}
+	return row, nil
+}
+
+func (s *WebhookStore) DeleteEndpoint(ctx context.Context, endpointID string) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, endpointID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *WebhookStore) EnableEndpoint(ctx context.Context, endpointID string) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_endpoints
+		SET active = TRUE, consecutive_failures = 0, disabled_at = NULL
+		WHERE id = $1
+	`, endpointID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *WebhookStore) MatchingEndpoints(ctx context.Context, eventType string, userIDs []string) ([]WebhookEndpoint, error) {
+	var rows []WebhookEndpoint
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT `+webhookEndpointColumns+`
+		FROM webhook_endpoints
+		WHERE active = TRUE
+		  AND $1 = ANY(event_types)
+		  AND (scope = 'admin' OR owner_user_id = ANY($2))
+		ORDER BY created_at
+	`, eventType, pq.StringArray(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *WebhookStore) EnqueueDelivery(ctx context.Context, input WebhookDeliveryInput) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (id, endpoint_id, event_id, event_type, payload)
+		VALUES ($1, $2, $3, $4, $5::jsonb)
+		ON CONFLICT (endpoint_id, event_id, event_type) DO NOTHING
+	`, input.ID, input.EndpointID, input.EventID, input.EventType, input.Payload)
+	return err
+}
+
+func (s *WebhookStore) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]PendingWebhookDelivery, error) {
+	var rows []PendingWebhookDelivery
+	err := s.db.SelectContext(ctx, &rows, `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+		FROM webhook_endpoints e
+		WHERE e.id = d.endpoint_id
+		  AND d.id IN (
+			SELECT pd.id
+			FROM webhook_deliveries pd
+			JOIN webhook_endpoints pe ON pe.id = pd.endpoint_id
+			WHERE pd.status = 'pending' AND pd.next_attempt_at <= NOW() AND pe.active = TRUE
+			ORDER BY pd.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF pd SKIP LOCKED
+		  )
+		RETURNING d.id, d.endpoint_id, d.event_id, d.event_type, d.payload::text AS payload, d.status, d.attempts,
+		          d.last_status_code, d.last_error, d.next_attempt_at, d.delivered_at, d.created_at,
+		          e.url, e.secret
+	`, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *WebhookStore) RecordSuccess(ctx context.Context, deliveryID string, statusCode int) error {
+	_, err := s.db.ExecContext(ctx, `
+		WITH d AS (
+			UPDATE webhook_deliveries
+			SET status = 'succeeded', attempts = attempts + 1, last_status_code = $2,
+			    last_error = NULL, delivered_at = NOW()
+			WHERE id = $1
+			RETURNING id, endpoint_id
+		), a AS (
+			INSERT INTO webhook_delivery_attempts (delivery_id, status_code)
+			SELECT id, $2 FROM d
+		)
+		UPDATE webhook_endpoints
+		SET consecutive_failures = 0
+		WHERE id IN (SELECT endpoint_id FROM d)
+	`, deliveryID, statusCode)
+	return err
+}
+
+// A nil retryAt marks the delivery as failed for good.
+func (s *WebhookStore) RecordFailure(ctx context.Context, deliveryID string, statusCode *int, message string, retryAt *time.Time, disableAfter int) (bool, error) {
+	var disabled bool
+	err := s.db.GetContext(ctx, &disabled, `
+		WITH d AS (
+			UPDATE webhook_deliveries
+			SET attempts = attempts + 1, last_status_code = $2, last_error = $3,
+			    status = CASE WHEN $4::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+			    next_attempt_at = COALESCE($4::timestamptz, next_attempt_at)
+			WHERE id = $1
+			RETURNING id, endpoint_id
+		), a AS (
+			INSERT INTO webhook_delivery_attempts (delivery_id, status_code, error)
+			SELECT id, $2, $3 FROM d
+		)
+		UPDATE webhook_endpoints
+		SET consecutive_failures = consecutive_failures + 1,
+		    active = active AND consecutive_failures + 1 < $5,
+		    disabled_at = CASE WHEN active AND consecutive_failures + 1 >= $5 THEN NOW() ELSE disabled_at END
+		WHERE id IN (SELECT endpoint_id FROM d)
+		RETURNING NOT active
+	`, deliveryID, statusCode, message, retryAt, disableAfter)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return disabled, err
+}
+
+func (s *WebhookStore) ListDeliveries(ctx context.Context, endpointID string, limit, offset int) ([]WebhookDelivery, error) {
+	var rows []WebhookDelivery
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries
+		WHERE endpoint_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, endpointID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *WebhookStore) ListAttempts(ctx context.Context, endpointID, deliveryID string) ([]WebhookAttempt, error) {
+	var rows []WebhookAttempt
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT a.id, a.delivery_id, a.status_code, a.error, a.attempted_at
+		FROM webhook_delivery_attempts a
+		JOIN webhook_deliveries d ON d.id = a.delivery_id
+		WHERE d.endpoint_id = $1 AND a.delivery_id = $2
+		ORDER BY a.id
+	`, endpointID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *WebhookStore) Redeliver(ctx context.Context, endpointID, deliveryID string) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+		WHERE id = $1 AND endpoint_id = $2
+	`, deliveryID, endpointID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
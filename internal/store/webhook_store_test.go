@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestWebhookStoreMatchingEndpoints(t *testing.T) {
+	store := NewWebhookStore(stubDB{
+		selectFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "$1 = ANY(event_types)") || !strings.Contains(query, "scope = 'admin' OR owner_user_id = ANY($2)") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			users, ok := args[1].(pq.StringArray)
+			if args[0] != "transfer.received" || !ok || len(users) != 1 || users[0] != "user-2" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*[]WebhookEndpoint) = []WebhookEndpoint{{ID: "hook-1"}}
+			return nil
+		},
+	})
+	rows, err := store.MatchingEndpoints(context.Background(), "transfer.received", []string{"user-2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 1 || rows[0].ID != "hook-1" {
+		t.Fatalf("unexpected rows: %#v", rows)
+	}
+}
+
+func TestWebhookStoreEnqueueDeliveryIsIdempotent(t *testing.T) {
+	store := NewWebhookStore(stubDB{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "ON CONFLICT (endpoint_id, event_id, event_type) DO NOTHING") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 5 || args[1] != "hook-1" || args[2] != "event-1" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{}, nil
+		},
+	})
+	err := store.EnqueueDelivery(context.Background(), WebhookDeliveryInput{
+		ID: "delivery-1", EndpointID: "hook-1", EventID: "event-1", EventType: "transfer.sent", Payload: "{}",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestWebhookStoreRecordFailure(t *testing.T) {
+	retryAt := time.Date(2025, 1, 1, 0, 5, 0, 0, time.UTC)
+	code := 500
+	store := NewWebhookStore(stubDB{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "INSERT INTO webhook_delivery_attempts") || !strings.Contains(query, "RETURNING NOT active") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 5 || args[0] != "delivery-1" || args[1] != &code || args[3] != &retryAt || args[4] != 20 {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*bool) = true
+			return nil
+		},
+	})
+	disabled, err := store.RecordFailure(context.Background(), "delivery-1", &code, "status 500", &retryAt, 20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !disabled {
+		t.Fatalf("expected endpoint to be reported disabled")
+	}
+}
+
+func TestWebhookStoreRecordFailureMissingDelivery(t *testing.T) {
+	store := NewWebhookStore(stubDB{
+		getFn: func(context.Context, any, string, ...any) error {
+			return sql.ErrNoRows
+		},
+	})
+	disabled, err := store.RecordFailure(context.Background(), "missing", nil, "timeout", nil, 20)
+	if err != nil || disabled {
+		t.Fatalf("unexpected result: %v %v", disabled, err)
+	}
+}
+
+func TestWebhookStoreRedeliver(t *testing.T) {
+	store := NewWebhookStore(stubDB{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if !strings.Contains(query, "status = 'pending', attempts = 0") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 2 || args[0] != "delivery-1" || args[1] != "hook-1" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			return stubResult{rows: 1}, nil
+		},
+	})
+	rows, err := store.Redeliver(context.Background(), "hook-1", "delivery-1")
+	if err != nil || rows != 1 {
+		t.Fatalf("unexpected result: %d %v", rows, err)
+	}
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type WSTicketStore struct {
+	db DB
+}
+
+type WSTicket struct {
+	UserID    string    `db:"user_id"`
+	SessionID string    `db:"session_id"`
+	Origin    string    `db:"origin"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+type WSTicketInput struct {
+	TicketHash string
+	UserID     string
+	SessionID  string
+	Origin     string
+	TTL        time.Duration
+}
+
+func NewWSTicketStore(db DB) *WSTicketStore {
+	return &WSTicketStore{db: db}
+}
+
+func (s *WSTicketStore) Create(ctx context.Context, input WSTicketInput) (time.Time, error) {
+	var expiresAt time.Time
+	err := s.db.GetContext(ctx, &expiresAt, `
+		WITH purged AS (
+			DELETE FROM ws_tickets WHERE expires_at < NOW()
+		)
+		INSERT INTO ws_tickets (ticket_hash, user_id, session_id, origin, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + make_interval(secs => $5))
+		RETURNING expires_at
+	`, input.TicketHash, input.UserID, input.SessionID, input.Origin, input.TTL.Seconds())
+	return expiresAt, err
+}
+
+// Consume deletes the ticket as it reads it, so each ticket opens at most one
+// connection.
+func (s *WSTicketStore) Consume(ctx context.Context, ticketHash string) (WSTicket, error) {
+	var ticket WSTicket
+	err := s.db.GetContext(ctx, &ticket, `
+		DELETE FROM ws_tickets
+		WHERE ticket_hash = $1 AND expires_at >= NOW()
+		RETURNING user_id, session_id, origin, expires_at
+	`, ticketHash)
+	return ticket, err
+}
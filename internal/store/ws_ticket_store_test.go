@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWSTicketStoreCreate(t *testing.T) {
+	expires := time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC)
+	store := NewWSTicketStore(stubDB{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "DELETE FROM ws_tickets WHERE expires_at < NOW()") || !strings.Contains(query, "make_interval(secs => $5)") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 5 || args[0] != "hash" || args[1] != "user-1" || args[2] != "session-1" || args[3] != "https://app.example.com" || args[4] != float64(30) {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*time.Time) = expires
+			return nil
+		},
+	})
+	got, err := store.Create(context.Background(), WSTicketInput{
+		TicketHash: "hash",
+		UserID:     "user-1",
+		SessionID:  "session-1",
+		Origin:     "https://app.example.com",
+		TTL:        30 * time.Second,
+	})
+	if err != nil || !got.Equal(expires) {
+		t.Fatalf("unexpected result: %v %v", got, err)
+	}
+}
+
+func TestWSTicketStoreConsume(t *testing.T) {
+	store := NewWSTicketStore(stubDB{
+		getFn: func(_ context.Context, dest any, query string, args ...any) error {
+			if !strings.Contains(query, "DELETE FROM ws_tickets") || !strings.Contains(query, "expires_at >= NOW()") {
+				t.Fatalf("unexpected query: %s", query)
+			}
+			if len(args) != 1 || args[0] != "hash" {
+				t.Fatalf("unexpected args: %#v", args)
+			}
+			*dest.(*WSTicket) = WSTicket{UserID: "user-1", Origin: "https://app.example.com"}
+			return nil
+		},
+	})
+	ticket, err := store.Consume(context.Background(), "hash")
+	if err != nil || ticket.UserID != "user-1" || ticket.Origin != "https://app.example.com" {
+		t.Fatalf("unexpected result: %#v %v", ticket, err)
+	}
+}
@@ -0,0 +1,53 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAllowedAddress(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"0.0.0.0":         false,
+		"::":              false,
+		"::ffff:10.0.0.1": false,
+	}
+	for address, want := range cases {
+		if got := AllowedAddress(net.ParseIP(address)); got != want {
+			t.Errorf("AllowedAddress(%s) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestClientRefusesInternalAddresses(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Fatal("the request must not reach a loopback receiver")
+	}))
+	defer receiver.Close()
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, receiver.URL, nil)
+	_, err := NewClient(time.Second).Do(req)
+	if !errors.Is(err, ErrAddressNotAllowed) {
+		t.Fatalf("expected ErrAddressNotAllowed, got %v", err)
+	}
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	client := NewClient(time.Second)
+	if err := client.CheckRedirect(nil, nil); !errors.Is(err, http.ErrUseLastResponse) {
+		t.Fatalf("expected redirects to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"banking/internal/store"
+)
+
+type DeliveryStore interface {
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]store.PendingWebhookDelivery, error)
+	RecordSuccess(ctx context.Context, deliveryID string, statusCode int) error
+	RecordFailure(ctx context.Context, deliveryID string, statusCode *int, message string, retryAt *time.Time, disableAfter int) (bool, error)
+}
+
+type Sender struct {
+	store        DeliveryStore
+	client       *http.Client
+	batchSize    int
+	lease        time.Duration
+	maxAttempts  int
+	disableAfter int
+	baseDelay    time.Duration
+	maxDelay     time.Duration
+}
+
+func NewSender(store DeliveryStore, client *http.Client) *Sender {
+	return &Sender{
+		store:        store,
+		client:       client,
+		batchSize:    50,
+		lease:        time.Minute,
+		maxAttempts:  8,
+		disableAfter: 20,
+		baseDelay:    30 * time.Second,
+		maxDelay:     time.Hour,
+	}
+}
+
+func (s *Sender) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.SendOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("webhook delivery failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sender) SendOnce(ctx context.Context) (int, error) {
+	sent := 0
+	for {
+		batch, err := s.store.ClaimDeliveries(ctx, s.batchSize, s.lease)
+		if err != nil {
+			return sent, err
+		}
+		for _, delivery := range batch {
+			ok, err := s.send(ctx, delivery)
+			if err != nil {
+				return sent, err
+			}
+			if ok {
+				sent++
+			}
+		}
+		if len(batch) < s.batchSize {
+			return sent, nil
+		}
+	}
+}
+
+func (s *Sender) send(ctx context.Context, delivery store.PendingWebhookDelivery) (bool, error) {
+	statusCode, err := s.post(ctx, delivery)
+	if err == nil {
+		return true, s.store.RecordSuccess(ctx, delivery.ID, statusCode)
+	}
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+	var retryAt *time.Time
+	if attempts := delivery.Attempts + 1; attempts < s.maxAttempts {
+		next := time.Now().Add(s.retryDelay(attempts))
+		retryAt = &next
+	}
+	disabled, err := s.store.RecordFailure(ctx, delivery.ID, code, err.Error(), retryAt, s.disableAfter)
+	if err != nil {
+		return false, err
+	}
+	if disabled {
+		log.Printf("webhook endpoint %s disabled after repeated failures", delivery.EndpointID)
+	}
+	return false, nil
+}
+
+func (s *Sender) post(ctx context.Context, delivery store.PendingWebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, time.Now(), body))
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (s *Sender) retryDelay(attempts int) time.Duration {
+	delay := s.baseDelay
+	for i := 1; i < attempts && delay < s.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.maxDelay {
+		return s.maxDelay
+	}
+	return delay
+}
@@ -0,0 +1,148 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"banking/internal/store"
+)
+
+type failure struct {
+	statusCode *int
+	retryAt    *time.Time
+}
+
+type stubDeliveryStore struct {
+	pending   []store.PendingWebhookDelivery
+	succeeded map[string]int
+	failed    map[string]failure
+	disable   bool
+}
+
+func (s *stubDeliveryStore) ClaimDeliveries(context.Context, int, time.Duration) ([]store.PendingWebhookDelivery, error) {
+	batch := s.pending
+	s.pending = nil
+	return batch, nil
+}
+
+func (s *stubDeliveryStore) RecordSuccess(_ context.Context, deliveryID string, statusCode int) error {
+	s.succeeded[deliveryID] = statusCode
+	return nil
+}
+
+func (s *stubDeliveryStore) RecordFailure(_ context.Context, deliveryID string, statusCode *int, _ string, retryAt *time.Time, _ int) (bool, error) {
+	s.failed[deliveryID] = failure{statusCode: statusCode, retryAt: retryAt}
+	return s.disable, nil
+}
+
+func newDelivery(id, url string, attempts int) store.PendingWebhookDelivery {
+	return store.PendingWebhookDelivery{
+		WebhookDelivery: store.WebhookDelivery{
+			ID:         id,
+			EndpointID: "hook-1",
+			EventType:  TransferReceived,
+			Payload:    `{"id":"` + id + `","type":"transfer.received"}`,
+			Attempts:   attempts,
+		},
+		URL:    url,
+		Secret: "whsec_test",
+	}
+}
+
+func TestSenderSignsDeliveries(t *testing.T) {
+	var verified bool
+	var event, delivery string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verified = Verify("whsec_test", r.Header.Get(SignatureHeader), body, time.Now(), time.Minute)
+		event = r.Header.Get(EventHeader)
+		delivery = r.Header.Get(DeliveryHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	deliveries := &stubDeliveryStore{
+		pending:   []store.PendingWebhookDelivery{newDelivery("d-1", receiver.URL, 0)},
+		succeeded: map[string]int{},
+		failed:    map[string]failure{},
+	}
+	sent, err := NewSender(deliveries, receiver.Client()).SendOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent != 1 || deliveries.succeeded["d-1"] != http.StatusNoContent {
+		t.Fatalf("expected delivery to succeed, got %d %#v", sent, deliveries.succeeded)
+	}
+	if !verified || event != TransferReceived || delivery != "d-1" {
+		t.Fatalf("unexpected request: verified=%v event=%q delivery=%q", verified, event, delivery)
+	}
+}
+
+func TestSenderSchedulesRetryWithBackoff(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	deliveries := &stubDeliveryStore{
+		pending:   []store.PendingWebhookDelivery{newDelivery("d-1", receiver.URL, 2)},
+		succeeded: map[string]int{},
+		failed:    map[string]failure{},
+	}
+	before := time.Now()
+	if _, err := NewSender(deliveries, receiver.Client()).SendOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := deliveries.failed["d-1"]
+	if got.statusCode == nil || *got.statusCode != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status: %#v", got)
+	}
+	if got.retryAt == nil {
+		t.Fatalf("expected a retry to be scheduled")
+	}
+	if delay := got.retryAt.Sub(before); delay < 2*time.Minute || delay > 2*time.Minute+5*time.Second {
+		t.Fatalf("unexpected retry delay: %v", delay)
+	}
+}
+
+func TestSenderGivesUpAfterMaxAttempts(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	deliveries := &stubDeliveryStore{
+		pending:   []store.PendingWebhookDelivery{newDelivery("d-1", receiver.URL, 7)},
+		succeeded: map[string]int{},
+		failed:    map[string]failure{},
+		disable:   true,
+	}
+	if _, err := NewSender(deliveries, receiver.Client()).SendOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, ok := deliveries.failed["d-1"]; !ok || got.retryAt != nil {
+		t.Fatalf("expected delivery to be marked failed for good, got %#v", got)
+	}
+}
+
+func TestSenderRecordsConnectionErrors(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	url := receiver.URL
+	receiver.Close()
+
+	deliveries := &stubDeliveryStore{
+		pending:   []store.PendingWebhookDelivery{newDelivery("d-1", url, 0)},
+		succeeded: map[string]int{},
+		failed:    map[string]failure{},
+	}
+	if _, err := NewSender(deliveries, http.DefaultClient).SendOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, ok := deliveries.failed["d-1"]; !ok || got.statusCode != nil || got.retryAt == nil {
+		t.Fatalf("unexpected failure: %#v", got)
+	}
+}
@@ -0,0 +1,39 @@
+package webhooks
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	at := time.Unix(1735689600, 0)
+	body := []byte(`{"type":"transfer.sent"}`)
+	header := Sign("whsec_test", at, body)
+	if !strings.HasPrefix(header, "t=1735689600,v1=") {
+		t.Fatalf("unexpected header: %s", header)
+	}
+	if !Verify("whsec_test", header, body, at.Add(time.Minute), 5*time.Minute) {
+		t.Fatalf("expected signature to verify")
+	}
+	if Verify("whsec_other", header, body, at, 5*time.Minute) {
+		t.Fatalf("expected wrong secret to fail")
+	}
+	if Verify("whsec_test", header, []byte(`{"type":"transfer.received"}`), at, 5*time.Minute) {
+		t.Fatalf("expected tampered body to fail")
+	}
+	if Verify("whsec_test", header, body, at.Add(10*time.Minute), 5*time.Minute) {
+		t.Fatalf("expected stale signature to fail")
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	first, err := NewSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, _ := NewSecret()
+	if !strings.HasPrefix(first, "whsec_") || len(first) != 70 || first == second {
+		t.Fatalf("unexpected secrets: %s %s", first, second)
+	}
+}
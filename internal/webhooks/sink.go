@@ -0,0 +1,137 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+
+	"banking/internal/events"
+	"banking/internal/store"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TransferSent      = "transfer.sent"
+	TransferReceived  = "transfer.received"
+	ExchangeCompleted = events.ExchangeCompleted
+	AccountFrozen     = events.AccountFrozen
+)
+
+const (
+	ScopeUser  = "user"
+	ScopeAdmin = "admin"
+)
+
+var EventTypes = []string{TransferSent, TransferReceived, ExchangeCompleted, AccountFrozen}
+
+func IsEventType(value string) bool {
+	for _, eventType := range EventTypes {
+		if eventType == value {
+			return true
+		}
+	}
+	return false
+}
+
+type EndpointStore interface {
+	MatchingEndpoints(ctx context.Context, eventType string, userIDs []string) ([]store.WebhookEndpoint, error)
+	EnqueueDelivery(ctx context.Context, input store.WebhookDeliveryInput) error
+}
+
+type Sink struct {
+	store EndpointStore
+}
+
+type target struct {
+	eventType string
+	userID    string
+}
+
+func NewSink(store EndpointStore) *Sink {
+	return &Sink{store: store}
+}
+
+func (s *Sink) Name() string {
+	return "webhooks"
+}
+
+func (s *Sink) Deliver(ctx context.Context, event store.DomainEvent) error {
+	targets := targetsFor(event)
+	if len(targets) == 0 {
+		return nil
+	}
+	var data map[string]any
+	if err := json.Unmarshal([]byte(event.Payload), &data); err != nil {
+		return err
+	}
+	for _, target := range targets {
+		endpoints, err := s.store.MatchingEndpoints(ctx, target.eventType, []string{target.userID})
+		if err != nil {
+			return err
+		}
+		for _, endpoint := range endpoints {
+			deliveryID := uuid.NewString()
+			body, _ := json.Marshal(map[string]any{
+				"id":         deliveryID,
+				"event_id":   event.ID,
+				"type":       target.eventType,
+				"created_at": event.CreatedAt,
+				"data":       visibleData(endpoint, data),
+			})
+			if err := s.store.EnqueueDelivery(ctx, store.WebhookDeliveryInput{
+				ID:         deliveryID,
+				EndpointID: endpoint.ID,
+				EventID:    event.ID,
+				EventType:  target.eventType,
+				Payload:    string(body),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func targetsFor(event store.DomainEvent) []target {
+	if event.UserID == nil {
+		return nil
+	}
+	switch event.Type {
+	case events.TransferCompleted:
+		targets := []target{{eventType: TransferSent, userID: *event.UserID}}
+		var payload struct {
+			ToUserID string `json:"to_user_id"`
+		}
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err == nil && payload.ToUserID != "" {
+			targets = append(targets, target{eventType: TransferReceived, userID: payload.ToUserID})
+		}
+		return targets
+	case events.ExchangeCompleted:
+		return []target{{eventType: ExchangeCompleted, userID: *event.UserID}}
+	case events.AccountFrozen:
+		return []target{{eventType: AccountFrozen, userID: *event.UserID}}
+	}
+	return nil
+}
+
+// visibleData hides other users' balances from user endpoints, so the
+// recipient of a transfer never sees the sender's balance.
+func visibleData(endpoint store.WebhookEndpoint, data map[string]any) map[string]any {
+	if endpoint.Scope == ScopeAdmin {
+		return data
+	}
+	visible := make(map[string]any, len(data))
+	for key, value := range data {
+		visible[key] = value
+	}
+	if balances, ok := data["balances"].([]any); ok {
+		own := make([]any, 0, len(balances))
+		for _, balance := range balances {
+			if entry, ok := balance.(map[string]any); ok && entry["user_id"] == endpoint.OwnerUserID {
+				own = append(own, entry)
+			}
+		}
+		visible["balances"] = own
+	}
+	return visible
+}
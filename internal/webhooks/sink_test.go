@@ -0,0 +1,85 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"banking/internal/events"
+	"banking/internal/store"
+)
+
+type stubEndpointStore struct {
+	endpoints map[string][]store.WebhookEndpoint
+	queried   []string
+	queued    []store.WebhookDeliveryInput
+}
+
+func (s *stubEndpointStore) MatchingEndpoints(_ context.Context, eventType string, userIDs []string) ([]store.WebhookEndpoint, error) {
+	s.queried = append(s.queried, eventType+":"+userIDs[0])
+	return s.endpoints[eventType+":"+userIDs[0]], nil
+}
+
+func (s *stubEndpointStore) EnqueueDelivery(_ context.Context, input store.WebhookDeliveryInput) error {
+	s.queued = append(s.queued, input)
+	return nil
+}
+
+func TestSinkQueuesTransferForBothSides(t *testing.T) {
+	sender := "user-1"
+	input := events.New(events.TransferCompleted, events.AggregateTransaction, "tx-1", &sender, map[string]any{
+		"transaction_id": "tx-1",
+		"to_user_id":     "user-2",
+		"balances": []events.BalanceChange{
+			{UserID: "user-1", AccountID: "acc-1", Currency: "USD", Balance: "90.00"},
+			{UserID: "user-2", AccountID: "acc-2", Currency: "USD", Balance: "110.00"},
+		},
+	})
+	endpoints := &stubEndpointStore{endpoints: map[string][]store.WebhookEndpoint{
+		"transfer.received:user-2": {{ID: "hook-2", OwnerUserID: "user-2", Scope: ScopeUser}},
+		"transfer.sent:user-1":     {{ID: "hook-admin", OwnerUserID: "admin-1", Scope: ScopeAdmin}},
+	}}
+	event := store.DomainEvent{ID: input.ID, Type: input.Type, UserID: input.UserID, Payload: input.Payload}
+	if err := NewSink(endpoints).Deliver(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(endpoints.queried) != 2 || endpoints.queried[0] != "transfer.sent:user-1" || endpoints.queried[1] != "transfer.received:user-2" {
+		t.Fatalf("unexpected lookups: %v", endpoints.queried)
+	}
+	if len(endpoints.queued) != 2 {
+		t.Fatalf("expected 2 deliveries, got %#v", endpoints.queued)
+	}
+
+	var admin, recipient struct {
+		ID      string `json:"id"`
+		EventID string `json:"event_id"`
+		Type    string `json:"type"`
+		Data    struct {
+			Balances []events.BalanceChange `json:"balances"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal([]byte(endpoints.queued[0].Payload), &admin)
+	_ = json.Unmarshal([]byte(endpoints.queued[1].Payload), &recipient)
+	if admin.Type != TransferSent || len(admin.Data.Balances) != 2 || admin.ID != endpoints.queued[0].ID {
+		t.Fatalf("unexpected admin payload: %#v", admin)
+	}
+	if recipient.Type != TransferReceived || recipient.EventID != event.ID {
+		t.Fatalf("unexpected recipient payload: %#v", recipient)
+	}
+	if len(recipient.Data.Balances) != 1 || recipient.Data.Balances[0].UserID != "user-2" {
+		t.Fatalf("expected recipient to see only their balance, got %#v", recipient.Data.Balances)
+	}
+}
+
+func TestSinkIgnoresUnsubscribedEventTypes(t *testing.T) {
+	actor := "admin-1"
+	input := events.New(events.AdminPromoted, events.AggregateUser, "user-2", &actor, map[string]string{})
+	endpoints := &stubEndpointStore{}
+	event := store.DomainEvent{ID: input.ID, Type: input.Type, UserID: input.UserID, Payload: input.Payload}
+	if err := NewSink(endpoints).Deliver(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(endpoints.queried) != 0 {
+		t.Fatalf("expected no endpoint lookups, got %v", endpoints.queried)
+	}
+}
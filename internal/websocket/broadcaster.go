@@ -0,0 +1,20 @@
+package websocket
+
+import "context"
+
+type Broadcaster interface {
+	Publish(ctx context.Context, message Message) error
+}
+
+type MemoryBroadcaster struct {
+	hub *Hub
+}
+
+func NewMemoryBroadcaster(hub *Hub) *MemoryBroadcaster {
+	return &MemoryBroadcaster{hub: hub}
+}
+
+func (b *MemoryBroadcaster) Publish(_ context.Context, message Message) error {
+	b.hub.Publish(message)
+	return nil
+}
@@ -0,0 +1,24 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestMemoryBroadcasterDeliversToLocalClients(t *testing.T) {
+	hub := NewHub()
+	client := hub.Subscribe("user-1", "", true, ChannelBalances)
+
+	err := NewMemoryBroadcaster(hub).Publish(context.Background(), BalanceMessage("user-1", BalanceUpdate{AccountID: "acc-1", Balance: "10.00", Currency: "USD"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var update BalanceUpdate
+	if err := json.Unmarshal((<-client.send).payload, &update); err != nil {
+		t.Fatalf("unexpected payload: %v", err)
+	}
+	if update.AccountID != "acc-1" || update.Balance != "10.00" {
+		t.Fatalf("unexpected update: %#v", update)
+	}
+}
@@ -1,39 +1,83 @@
 package websocket
 
 import (
+	"encoding/json"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+const CloseResync = 4000
+
+const CloseRevoked = 4001
+
+type Options struct {
+	// Legacy serves the original /ws/balances stream: subscribed to balances
+	// from Since on connect, bare balance messages, client frames ignored.
+	Legacy      bool
+	Since       *int64
+	SessionID   string
+	CheckOrigin func(r *http.Request) bool
+	Backlog     func(since *int64) (Backlog, error)
+	Authorize   func(channel string) (bool, error)
+}
+
+// command carries a parsed client request, or a reply to write, from the
+// reader to the writer so the socket only ever has one writer.
+type command struct {
+	op      string
+	id      string
+	channel string
+	since   *int64
+	reply   any
+}
+
 type Client struct {
-	conn *websocket.Conn
-	send chan []byte
+	*Subscription
+	conn     *websocket.Conn
+	commands chan command
+	done     chan struct{}
+
+	// Writer-only state: the highest balance seq written, and the channels
+	// whose messages are written. Balances only become active once their
+	// backlog is out; anything queued before that is covered by it.
+	lastSeq int64
+	active  map[string]bool
 }
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool { return true },
+func newClient(conn *websocket.Conn, sub *Subscription) *Client {
+	return &Client{
+		Subscription: sub,
+		conn:         conn,
+		commands:     make(chan command, 16),
+		done:         make(chan struct{}),
+		active:       make(map[string]bool),
+	}
 }
 
-func ServeWS(w http.ResponseWriter, r *http.Request, hub *Hub, userID string) {
+// ServeWS upgrades the connection and registers the client before any
+// backlog loads, so updates published meanwhile are queued rather than lost.
+func ServeWS(w http.ResponseWriter, r *http.Request, hub *Hub, userID string, opts Options) {
+	upgrader := websocket.Upgrader{CheckOrigin: opts.CheckOrigin}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		http.Error(w, "websocket upgrade failed", http.StatusBadRequest)
 		return
 	}
-	client := &Client{
-		conn: conn,
-		send: make(chan []byte, 10),
+	var sub *Subscription
+	if opts.Legacy {
+		sub = hub.Subscribe(userID, opts.SessionID, true, ChannelBalances)
+	} else {
+		sub = hub.Subscribe(userID, opts.SessionID, false)
 	}
-	hub.Register(userID, client)
-	go client.writePump(hub, userID)
-	client.readPump(hub, userID)
+	client := newClient(conn, sub)
+	go client.writePump(opts)
+	client.readPump(opts)
 }
 
-func (c *Client) readPump(hub *Hub, userID string) {
+func (c *Client) readPump(opts Options) {
 	defer func() {
-		hub.Unregister(userID, c)
+		c.Close()
 		_ = c.conn.Close()
 	}()
 	c.conn.SetReadLimit(512)
@@ -43,19 +87,82 @@ func (c *Client) readPump(hub *Hub, userID string) {
 		return nil
 	})
 	for {
-		if _, _, err := c.conn.ReadMessage(); err != nil {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
 			break
 		}
+		if c.legacy {
+			continue
+		}
+		select {
+		case c.commands <- c.parseRequest(data, opts):
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Client) parseRequest(data []byte, opts Options) command {
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return errorReply("", "invalid_message", "message must be a JSON object")
+	}
+	switch req.Op {
+	case OpSubscribe:
+		if !ValidChannel(req.Channel) {
+			return errorReply(req.ID, "unknown_channel", "unknown channel: "+req.Channel)
+		}
+		c.mu.RLock()
+		_, already := c.subs[req.Channel]
+		count := len(c.subs)
+		c.mu.RUnlock()
+		if !already && count >= maxSubscriptions {
+			return errorReply(req.ID, "too_many_subscriptions", "subscription limit reached")
+		}
+		if opts.Authorize != nil {
+			allowed, err := opts.Authorize(req.Channel)
+			if err != nil {
+				return errorReply(req.ID, "internal_error", "unable to subscribe")
+			}
+			if !allowed {
+				return errorReply(req.ID, "forbidden", "not allowed to subscribe to "+req.Channel)
+			}
+		}
+		c.mu.Lock()
+		c.subs[req.Channel] = struct{}{}
+		c.mu.Unlock()
+	case OpUnsubscribe:
+		c.mu.Lock()
+		delete(c.subs, req.Channel)
+		c.mu.Unlock()
+	default:
+		return errorReply(req.ID, "unknown_op", "unknown op: "+req.Op)
 	}
+	return command{op: req.Op, id: req.ID, channel: req.Channel, since: req.Since}
+}
+
+func errorReply(id, code, message string) command {
+	return command{reply: errorMessage{Type: MessageError, ID: id, Code: code, Message: message}}
 }
 
-func (c *Client) writePump(hub *Hub, userID string) {
+func (c *Client) writePump(opts Options) {
 	ticker := time.NewTicker(50 * time.Second)
 	defer func() {
 		ticker.Stop()
-		hub.Unregister(userID, c)
+		close(c.done)
+		c.Close()
 		_ = c.conn.Close()
 	}()
+	if c.legacy {
+		backlog, err := loadBacklog(opts, opts.Since)
+		if err == nil {
+			err = c.writeBacklog(backlog, opts.Since)
+		}
+		if err != nil {
+			_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "unable to load balances"), time.Now().Add(time.Second))
+			return
+		}
+	}
 	for {
 		select {
 		case message, ok := <-c.send:
@@ -64,9 +171,36 @@ func (c *Client) writePump(hub *Hub, userID string) {
 				_ = c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
-			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			if !c.active[message.channel] {
+				continue
+			}
+			if message.seq != 0 && message.seq <= c.lastSeq {
+				continue
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, message.payload); err != nil {
 				return
 			}
+			if message.seq != 0 {
+				c.lastSeq = message.seq
+			}
+		case cmd := <-c.commands:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := c.handleCommand(cmd, opts); err != nil {
+				return
+			}
+		case <-c.resync:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			payload, _ := json.Marshal(resyncMessage{Type: MessageResync, Seq: c.lastSeq})
+			_ = c.conn.WriteMessage(websocket.TextMessage, payload)
+			_ = c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(CloseResync, "resync"))
+			return
+		case <-c.revoked:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if !c.legacy {
+				_ = c.conn.WriteJSON(revokedMessage{Type: MessageRevoked})
+			}
+			_ = c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(CloseRevoked, "session revoked"))
+			return
 		case <-ticker.C:
 			_ = c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
@@ -75,3 +209,71 @@ func (c *Client) writePump(hub *Hub, userID string) {
 		}
 	}
 }
+
+func (c *Client) handleCommand(cmd command, opts Options) error {
+	if cmd.reply != nil {
+		return c.conn.WriteJSON(cmd.reply)
+	}
+	if cmd.op == OpUnsubscribe {
+		delete(c.active, cmd.channel)
+		return c.conn.WriteJSON(ackMessage{Type: MessageAck, ID: cmd.id, Op: cmd.op, Channel: cmd.channel})
+	}
+	if cmd.channel != ChannelBalances {
+		c.active[cmd.channel] = true
+		return c.conn.WriteJSON(ackMessage{Type: MessageAck, ID: cmd.id, Op: cmd.op, Channel: cmd.channel})
+	}
+	delete(c.active, ChannelBalances)
+	backlog, err := loadBacklog(opts, cmd.since)
+	if err != nil {
+		c.mu.Lock()
+		delete(c.subs, ChannelBalances)
+		c.mu.Unlock()
+		return c.conn.WriteJSON(errorMessage{Type: MessageError, ID: cmd.id, Code: "internal_error", Message: "unable to load balances"})
+	}
+	if err := c.conn.WriteJSON(ackMessage{Type: MessageAck, ID: cmd.id, Op: cmd.op, Channel: cmd.channel}); err != nil {
+		return err
+	}
+	return c.writeBacklog(backlog, cmd.since)
+}
+
+func loadBacklog(opts Options, since *int64) (Backlog, error) {
+	if opts.Backlog == nil {
+		return Backlog{}, nil
+	}
+	return opts.Backlog(since)
+}
+
+func (c *Client) writeBacklog(backlog Backlog, since *int64) error {
+	_ = c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	c.lastSeq = 0
+	if since != nil {
+		c.lastSeq = *since
+	}
+	if backlog.Snapshot != nil {
+		snapshot := *backlog.Snapshot
+		if snapshot.Balances == nil {
+			snapshot.Balances = []BalanceUpdate{}
+		}
+		var frame any = snapshotMessage{Type: MessageSnapshot, Snapshot: snapshot}
+		if !c.legacy {
+			data, _ := json.Marshal(snapshot)
+			frame = envelope{Type: MessageEvent, Channel: ChannelBalances, Event: EventBalanceSnapshot, Seq: snapshot.Seq, Data: data}
+		}
+		if err := c.conn.WriteJSON(frame); err != nil {
+			return err
+		}
+		c.lastSeq = snapshot.Seq
+	}
+	for _, update := range backlog.Updates {
+		var frame any = balanceMessage{Type: MessageBalance, BalanceUpdate: update}
+		if !c.legacy {
+			frame = eventEnvelope(BalanceMessage("", update))
+		}
+		if err := c.conn.WriteJSON(frame); err != nil {
+			return err
+		}
+		c.lastSeq = update.Seq
+	}
+	c.active[ChannelBalances] = true
+	return nil
+}
@@ -0,0 +1,233 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func dialTestServer(t *testing.T, hub *Hub, opts Options) *websocket.Conn {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWS(w, r, hub, "user-1", opts)
+	}))
+	t.Cleanup(server.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	return conn
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) map[string]any {
+	t.Helper()
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	var message map[string]any
+	if err := json.Unmarshal(data, &message); err != nil {
+		t.Fatalf("invalid message %s: %v", data, err)
+	}
+	return message
+}
+
+func sendRequest(t *testing.T, conn *websocket.Conn, request string) {
+	t.Helper()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+}
+
+func TestServeWSSendsSnapshotThenSkipsCoveredUpdates(t *testing.T) {
+	hub := NewHub()
+	conn := dialTestServer(t, hub, Options{Legacy: true, Backlog: func(since *int64) (Backlog, error) {
+		if since != nil {
+			t.Errorf("unexpected since: %d", *since)
+		}
+		// Published while the snapshot is being built: already covered by it.
+		hub.Publish(BalanceMessage("user-1", BalanceUpdate{Seq: 3, AccountID: "acc-1", Balance: "5.00", Currency: "USD"}))
+		return Backlog{Snapshot: &Snapshot{Seq: 3, Balances: []BalanceUpdate{{AccountID: "acc-1", Balance: "5.00", Currency: "USD"}}}}, nil
+	}})
+
+	snapshot := readMessage(t, conn)
+	if snapshot["type"] != MessageSnapshot || snapshot["seq"] != float64(3) {
+		t.Fatalf("unexpected snapshot: %#v", snapshot)
+	}
+	hub.Publish(BalanceMessage("user-1", BalanceUpdate{Seq: 4, AccountID: "acc-1", Balance: "6.00", Currency: "USD"}))
+	update := readMessage(t, conn)
+	if update["type"] != MessageBalance || update["seq"] != float64(4) || update["balance"] != "6.00" {
+		t.Fatalf("unexpected update: %#v", update)
+	}
+}
+
+func TestServeWSReplaysMissedUpdates(t *testing.T) {
+	hub := NewHub()
+	since := int64(7)
+	conn := dialTestServer(t, hub, Options{Legacy: true, Since: &since, Backlog: func(got *int64) (Backlog, error) {
+		if got == nil || *got != 7 {
+			t.Errorf("unexpected since: %v", got)
+		}
+		return Backlog{Updates: []BalanceUpdate{
+			{Seq: 8, AccountID: "acc-1", Balance: "1.00", Currency: "USD"},
+			{Seq: 9, AccountID: "acc-2", Balance: "2.00", Currency: "EUR"},
+		}}, nil
+	}})
+	for _, seq := range []float64{8, 9} {
+		message := readMessage(t, conn)
+		if message["type"] != MessageBalance || message["seq"] != seq {
+			t.Fatalf("unexpected replay: %#v", message)
+		}
+	}
+}
+
+func TestServeWSClosesWhenBacklogFails(t *testing.T) {
+	conn := dialTestServer(t, NewHub(), Options{Legacy: true, Backlog: func(*int64) (Backlog, error) {
+		return Backlog{}, errors.New("db down")
+	}})
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseInternalServerErr {
+		t.Fatalf("expected internal error close, got %v", err)
+	}
+}
+
+func TestWritePumpSendsResyncAndCloses(t *testing.T) {
+	hub := NewHub()
+	registered := make(chan *Subscription, 1)
+	conn := dialTestServer(t, hub, Options{Legacy: true, Backlog: func(*int64) (Backlog, error) {
+		hub.mu.RLock()
+		for client := range hub.clients["user-1"] {
+			registered <- client
+		}
+		hub.mu.RUnlock()
+		return Backlog{Snapshot: &Snapshot{Seq: 2}}, nil
+	}})
+	readMessage(t, conn)
+	client := <-registered
+	client.resyncOnce.Do(func() { close(client.resync) })
+
+	message := readMessage(t, conn)
+	if message["type"] != MessageResync || message["seq"] != float64(2) {
+		t.Fatalf("unexpected resync: %#v", message)
+	}
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseResync {
+		t.Fatalf("expected resync close, got %v", err)
+	}
+}
+
+func TestWritePumpClosesOnRevokedSession(t *testing.T) {
+	hub := NewHub()
+	conn := dialTestServer(t, hub, Options{SessionID: "session-1"})
+	sendRequest(t, conn, `{"id":"1","op":"subscribe","channel":"transactions"}`)
+	readMessage(t, conn)
+
+	hub.Publish(SessionRevokedMessage("user-1", "session-1"))
+	if message := readMessage(t, conn); message["type"] != MessageRevoked {
+		t.Fatalf("unexpected message: %#v", message)
+	}
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseRevoked {
+		t.Fatalf("expected revoked close, got %v", err)
+	}
+}
+
+func TestServeWSRejectsOrigin(t *testing.T) {
+	hub := NewHub()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWS(w, r, hub, "user-1", Options{CheckOrigin: func(r *http.Request) bool {
+			return r.Header.Get("Origin") == "https://app.example.com"
+		}})
+	}))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.example.com"}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://app.example.com"}})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	_ = conn.Close()
+}
+
+func TestProtocolSubscribeBalances(t *testing.T) {
+	hub := NewHub()
+	conn := dialTestServer(t, hub, Options{Backlog: func(*int64) (Backlog, error) {
+		return Backlog{Snapshot: &Snapshot{Seq: 5, Balances: []BalanceUpdate{{AccountID: "acc-1", Balance: "5.00", Currency: "USD"}}}}, nil
+	}})
+
+	sendRequest(t, conn, `{"id":"1","op":"subscribe","channel":"balances"}`)
+	ack := readMessage(t, conn)
+	if ack["type"] != MessageAck || ack["id"] != "1" || ack["channel"] != ChannelBalances {
+		t.Fatalf("unexpected ack: %#v", ack)
+	}
+	snapshot := readMessage(t, conn)
+	data, _ := snapshot["data"].(map[string]any)
+	if snapshot["type"] != MessageEvent || snapshot["event"] != EventBalanceSnapshot || snapshot["seq"] != float64(5) || len(data["balances"].([]any)) != 1 {
+		t.Fatalf("unexpected snapshot: %#v", snapshot)
+	}
+
+	hub.Publish(BalanceMessage("user-1", BalanceUpdate{Seq: 6, AccountID: "acc-1", Balance: "6.00", Currency: "USD"}))
+	update := readMessage(t, conn)
+	data, _ = update["data"].(map[string]any)
+	if update["type"] != MessageEvent || update["event"] != EventBalanceUpdated || update["seq"] != float64(6) || data["balance"] != "6.00" {
+		t.Fatalf("unexpected update: %#v", update)
+	}
+}
+
+func TestProtocolUnsubscribeStopsDelivery(t *testing.T) {
+	hub := NewHub()
+	conn := dialTestServer(t, hub, Options{})
+
+	sendRequest(t, conn, `{"id":"1","op":"subscribe","channel":"transactions"}`)
+	sendRequest(t, conn, `{"id":"2","op":"subscribe","channel":"rates:USD-EUR"}`)
+	readMessage(t, conn)
+	readMessage(t, conn)
+	sendRequest(t, conn, `{"id":"3","op":"unsubscribe","channel":"transactions"}`)
+	if ack := readMessage(t, conn); ack["type"] != MessageAck || ack["op"] != OpUnsubscribe {
+		t.Fatalf("unexpected ack: %#v", ack)
+	}
+
+	hub.Publish(Message{UserID: "user-1", Channel: ChannelTransactions, Event: "transfer.completed", Data: []byte(`{}`)})
+	hub.Publish(Message{Channel: "rates:USD-EUR", Event: "rate.updated", Data: []byte(`{"rate":"0.92"}`)})
+	message := readMessage(t, conn)
+	if message["channel"] != "rates:USD-EUR" {
+		t.Fatalf("expected only the rate message, got %#v", message)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	conn := dialTestServer(t, NewHub(), Options{Authorize: func(channel string) (bool, error) {
+		return channel != "quotes:someone-elses", nil
+	}})
+	tests := []struct {
+		request string
+		code    string
+	}{
+		{request: `not json`, code: "invalid_message"},
+		{request: `{"id":"1","op":"publish","channel":"balances"}`, code: "unknown_op"},
+		{request: `{"id":"2","op":"subscribe","channel":"rates:usd-eur"}`, code: "unknown_channel"},
+		{request: `{"id":"3","op":"subscribe","channel":"quotes:someone-elses"}`, code: "forbidden"},
+	}
+	for _, tt := range tests {
+		sendRequest(t, conn, tt.request)
+		message := readMessage(t, conn)
+		if message["type"] != MessageError || message["code"] != tt.code {
+			t.Fatalf("%s: unexpected reply %#v", tt.request, message)
+		}
+	}
+}
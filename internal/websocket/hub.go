@@ -6,51 +6,190 @@ import (
 )
 
 type BalanceUpdate struct {
+	Seq       int64  `json:"seq,omitempty"`
 	AccountID string `json:"account_id"`
 	Balance   string `json:"balance"`
 	Currency  string `json:"currency"`
 }
 
+type Snapshot struct {
+	Seq      int64           `json:"seq"`
+	Balances []BalanceUpdate `json:"balances"`
+}
+
+type Backlog struct {
+	Snapshot *Snapshot
+	Updates  []BalanceUpdate
+}
+
+type Message struct {
+	UserID  string          `json:"user_id,omitempty"`
+	Channel string          `json:"channel"`
+	Event   string          `json:"event"`
+	Seq     int64           `json:"seq,omitempty"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func BalanceMessage(userID string, update BalanceUpdate) Message {
+	data, _ := json.Marshal(BalanceUpdate{AccountID: update.AccountID, Balance: update.Balance, Currency: update.Currency})
+	return Message{UserID: userID, Channel: ChannelBalances, Event: EventBalanceUpdated, Seq: update.Seq, Data: data}
+}
+
+type outbound struct {
+	channel string
+	event   string
+	seq     int64
+	payload []byte
+}
+
+type Subscription struct {
+	hub        *Hub
+	userID     string
+	sessionID  string
+	legacy     bool
+	send       chan outbound
+	resync     chan struct{}
+	resyncOnce sync.Once
+	revoked    chan struct{}
+	revokeOnce sync.Once
+	closeOnce  sync.Once
+
+	mu   sync.RWMutex
+	subs map[string]struct{}
+}
+
 type Hub struct {
 	mu      sync.RWMutex
-	clients map[string]map[*Client]struct{}
+	clients map[string]map[*Subscription]struct{}
 }
 
 func NewHub() *Hub {
 	return &Hub{
-		clients: make(map[string]map[*Client]struct{}),
+		clients: make(map[string]map[*Subscription]struct{}),
 	}
 }
 
-func (h *Hub) Register(userID string, client *Client) {
+func (h *Hub) Subscribe(userID, sessionID string, legacy bool, channels ...string) *Subscription {
+	sub := &Subscription{
+		hub:       h,
+		userID:    userID,
+		sessionID: sessionID,
+		legacy:    legacy,
+		send:      make(chan outbound, 10),
+		resync:    make(chan struct{}),
+		revoked:   make(chan struct{}),
+		subs:      make(map[string]struct{}),
+	}
+	for _, channel := range channels {
+		sub.subs[channel] = struct{}{}
+	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	if h.clients[userID] == nil {
-		h.clients[userID] = make(map[*Client]struct{})
+		h.clients[userID] = make(map[*Subscription]struct{})
 	}
-	h.clients[userID][client] = struct{}{}
+	h.clients[userID][sub] = struct{}{}
+	return sub
 }
 
-func (h *Hub) Unregister(userID string, client *Client) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	if h.clients[userID] == nil {
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() {
+		h := s.hub
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.clients[s.userID], s)
+		if len(h.clients[s.userID]) == 0 {
+			delete(h.clients, s.userID)
+		}
+	})
+}
+
+func (s *Subscription) subscribed(channel string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.subs[channel]
+	return ok
+}
+
+// enqueue never blocks the hub. A subscriber that can't keep up is told to
+// resync instead of silently missing updates.
+func (s *Subscription) enqueue(message outbound) {
+	select {
+	case s.send <- message:
+	default:
+		s.resyncOnce.Do(func() { close(s.resync) })
+	}
+}
+
+func (h *Hub) Publish(message Message) {
+	if message.Channel == ChannelControl {
+		h.control(message)
+		return
+	}
+	envelope, _ := json.Marshal(eventEnvelope(message))
+	var legacy []byte
+	if message.Channel == ChannelBalances {
+		var update BalanceUpdate
+		if err := json.Unmarshal(message.Data, &update); err == nil {
+			update.Seq = message.Seq
+			legacy, _ = json.Marshal(balanceMessage{Type: MessageBalance, BalanceUpdate: update})
+		}
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	deliver := func(sub *Subscription) {
+		if !sub.subscribed(message.Channel) {
+			return
+		}
+		payload := envelope
+		if sub.legacy {
+			if legacy == nil {
+				return
+			}
+			payload = legacy
+		}
+		sub.enqueue(outbound{channel: message.Channel, event: message.Event, seq: message.Seq, payload: payload})
+	}
+	if message.UserID != "" {
+		for sub := range h.clients[message.UserID] {
+			deliver(sub)
+		}
 		return
 	}
-	delete(h.clients[userID], client)
-	if len(h.clients[userID]) == 0 {
-		delete(h.clients, userID)
+	for _, subs := range h.clients {
+		for sub := range subs {
+			deliver(sub)
+		}
+	}
+}
+
+func SessionRevokedMessage(userID, sessionID string) Message {
+	data, _ := json.Marshal(sessionRevoked{SessionID: sessionID})
+	return Message{UserID: userID, Channel: ChannelControl, Event: EventSessionRevoked, Data: data}
+}
+
+type sessionRevoked struct {
+	SessionID string `json:"session_id,omitempty"`
+}
+
+func (h *Hub) control(message Message) {
+	if message.Event != EventSessionRevoked || message.UserID == "" {
+		return
+	}
+	var revoked sessionRevoked
+	if err := json.Unmarshal(message.Data, &revoked); err != nil {
+		return
 	}
+	h.Disconnect(message.UserID, revoked.SessionID)
 }
 
-func (h *Hub) BroadcastBalance(userID string, update BalanceUpdate) {
-	payload, _ := json.Marshal(update)
+func (h *Hub) Disconnect(userID, sessionID string) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	for client := range h.clients[userID] {
-		select {
-		case client.send <- payload:
-		default:
+	for sub := range h.clients[userID] {
+		if sessionID != "" && sub.sessionID != sessionID {
+			continue
 		}
+		sub.revokeOnce.Do(func() { close(sub.revoked) })
 	}
 }
@@ -0,0 +1,87 @@
+package websocket
+
+import "testing"
+
+func TestHubRequestsResyncWhenClientFallsBehind(t *testing.T) {
+	hub := NewHub()
+	client := hub.Subscribe("user-1", "", true, ChannelBalances)
+
+	for seq := int64(1); seq <= int64(cap(client.send))+2; seq++ {
+		hub.Publish(BalanceMessage("user-1", BalanceUpdate{Seq: seq, AccountID: "acc-1", Balance: "1.00", Currency: "USD"}))
+	}
+
+	select {
+	case <-client.resync:
+	default:
+		t.Fatalf("expected resync to be requested")
+	}
+	if len(client.send) != cap(client.send) {
+		t.Fatalf("expected queued updates to be kept, got %d", len(client.send))
+	}
+}
+
+func TestHubRoutesBySubscription(t *testing.T) {
+	hub := NewHub()
+	legacy := hub.Subscribe("user-1", "", true, ChannelBalances)
+	rates := hub.Subscribe("user-1", "", false, "rates:USD-EUR")
+	other := hub.Subscribe("user-2", "", false, ChannelTransactions)
+
+	hub.Publish(Message{Channel: "rates:USD-EUR", Event: "rate.updated", Data: []byte(`{"rate":"0.92"}`)})
+	hub.Publish(Message{UserID: "user-1", Channel: ChannelTransactions, Event: "transfer.completed", Data: []byte(`{}`)})
+
+	if len(legacy.send) != 0 || len(other.send) != 0 {
+		t.Fatalf("unsubscribed clients received messages: %d %d", len(legacy.send), len(other.send))
+	}
+	if len(rates.send) != 1 {
+		t.Fatalf("expected one rate message, got %d", len(rates.send))
+	}
+	message := <-rates.send
+	if string(message.payload) != `{"type":"event","channel":"rates:USD-EUR","event":"rate.updated","data":{"rate":"0.92"}}` {
+		t.Fatalf("unexpected payload: %s", message.payload)
+	}
+}
+
+func TestSubscriptionCloseUnregisters(t *testing.T) {
+	hub := NewHub()
+	first := hub.Subscribe("user-1", "", false, ChannelBalances)
+	second := hub.Subscribe("user-1", "", false, ChannelBalances)
+	first.Close()
+	first.Close()
+
+	hub.Publish(BalanceMessage("user-1", BalanceUpdate{Seq: 1, AccountID: "acc-1", Balance: "1.00", Currency: "USD"}))
+	if len(first.send) != 0 || len(second.send) != 1 {
+		t.Fatalf("unexpected queues: %d %d", len(first.send), len(second.send))
+	}
+	second.Close()
+	if len(hub.clients) != 0 {
+		t.Fatalf("expected no registrations, got %d", len(hub.clients))
+	}
+}
+
+func TestHubSessionRevokedClosesMatchingSubscriptions(t *testing.T) {
+	hub := NewHub()
+	revoked := hub.Subscribe("user-1", "session-1", false, ChannelBalances)
+	kept := hub.Subscribe("user-1", "session-2", false, ChannelBalances)
+	other := hub.Subscribe("user-2", "session-1", false, ChannelBalances)
+
+	hub.Publish(SessionRevokedMessage("user-1", "session-1"))
+	select {
+	case <-revoked.revoked:
+	default:
+		t.Fatal("expected session-1 to be revoked")
+	}
+	for _, sub := range []*Subscription{kept, other} {
+		select {
+		case <-sub.revoked:
+			t.Fatalf("unexpected revocation for %s/%s", sub.userID, sub.sessionID)
+		default:
+		}
+	}
+
+	hub.Publish(SessionRevokedMessage("user-1", ""))
+	select {
+	case <-kept.revoked:
+	default:
+		t.Fatal("expected every session of user-1 to be revoked")
+	}
+}
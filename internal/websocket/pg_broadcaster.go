@@ -0,0 +1,103 @@
+package websocket
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const NotifyChannel = "ws_messages"
+
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+type notificationSource interface {
+	NotificationChannel() <-chan *pq.Notification
+	Ping() error
+}
+
+type PGBroadcaster struct {
+	db           Execer
+	hub          *Hub
+	pingInterval time.Duration
+	minReconnect time.Duration
+	maxReconnect time.Duration
+}
+
+func NewPGBroadcaster(db Execer, hub *Hub) *PGBroadcaster {
+	return &PGBroadcaster{
+		db:           db,
+		hub:          hub,
+		pingInterval: 90 * time.Second,
+		minReconnect: time.Second,
+		maxReconnect: time.Minute,
+	}
+}
+
+func (b *PGBroadcaster) Publish(ctx context.Context, message Message) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = b.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, NotifyChannel, string(payload))
+	return err
+}
+
+// Listen delivers notifications to the local hub until ctx is cancelled.
+// Messages sent while pq.Listener is reconnecting are lost.
+func (b *PGBroadcaster) Listen(ctx context.Context, databaseURL string) {
+	listener := pq.NewListener(databaseURL, b.minReconnect, b.maxReconnect, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			log.Printf("ws listener disconnected: %v", err)
+		case pq.ListenerEventConnectionAttemptFailed:
+			log.Printf("ws listener reconnect failed: %v", err)
+		case pq.ListenerEventReconnected:
+			log.Printf("ws listener reconnected")
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen(NotifyChannel); err != nil {
+		log.Printf("ws listener: %v", err)
+		return
+	}
+	b.consume(ctx, listener)
+}
+
+func (b *PGBroadcaster) consume(ctx context.Context, source notificationSource) {
+	ticker := time.NewTicker(b.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification, ok := <-source.NotificationChannel():
+			if !ok {
+				return
+			}
+			// pq sends nil after a reconnect.
+			if notification == nil {
+				continue
+			}
+			b.deliver(notification.Extra)
+		case <-ticker.C:
+			if err := source.Ping(); err != nil {
+				log.Printf("ws listener ping failed: %v", err)
+			}
+		}
+	}
+}
+
+func (b *PGBroadcaster) deliver(payload string) {
+	var message Message
+	if err := json.Unmarshal([]byte(payload), &message); err != nil {
+		log.Printf("ws listener: bad payload: %v", err)
+		return
+	}
+	b.hub.Publish(message)
+}
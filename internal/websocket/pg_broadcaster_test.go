@@ -0,0 +1,107 @@
+package websocket
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type stubExecer struct {
+	execFn func(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (s stubExecer) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.execFn(ctx, query, args...)
+}
+
+type fakeListener struct {
+	notifications chan *pq.Notification
+	pings         chan struct{}
+}
+
+func (l *fakeListener) NotificationChannel() <-chan *pq.Notification {
+	return l.notifications
+}
+
+func (l *fakeListener) Ping() error {
+	select {
+	case l.pings <- struct{}{}:
+	default:
+	}
+	return errors.New("connection lost")
+}
+
+func TestPGBroadcasterPublishesNotification(t *testing.T) {
+	var payload string
+	broadcaster := NewPGBroadcaster(stubExecer{
+		execFn: func(_ context.Context, query string, args ...any) (sql.Result, error) {
+			if query != `SELECT pg_notify($1, $2)` || args[0] != NotifyChannel {
+				t.Fatalf("unexpected query: %s %#v", query, args)
+			}
+			payload = args[1].(string)
+			return nil, nil
+		},
+	}, NewHub())
+	err := broadcaster.Publish(context.Background(), BalanceMessage("user-1", BalanceUpdate{Seq: 3, AccountID: "acc-1", Balance: "5.00", Currency: "EUR"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded Message
+	if err := json.Unmarshal([]byte(payload), &decoded); err != nil {
+		t.Fatalf("unexpected payload %q: %v", payload, err)
+	}
+	if decoded.UserID != "user-1" || decoded.Channel != ChannelBalances || decoded.Seq != 3 || string(decoded.Data) != `{"account_id":"acc-1","balance":"5.00","currency":"EUR"}` {
+		t.Fatalf("unexpected notification: %#v", decoded)
+	}
+}
+
+func TestPGBroadcasterDeliversNotificationsToLocalHub(t *testing.T) {
+	hub := NewHub()
+	client := hub.Subscribe("user-1", "", true, ChannelBalances)
+	broadcaster := NewPGBroadcaster(stubExecer{}, hub)
+	listener := &fakeListener{notifications: make(chan *pq.Notification, 3), pings: make(chan struct{})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		broadcaster.consume(ctx, listener)
+		close(done)
+	}()
+
+	listener.notifications <- nil
+	listener.notifications <- &pq.Notification{Channel: NotifyChannel, Extra: "not json"}
+	listener.notifications <- &pq.Notification{Channel: NotifyChannel, Extra: `{"user_id":"user-1","channel":"balances","event":"balance.updated","seq":1,"data":{"account_id":"acc-1","balance":"7.00","currency":"USD"}}`}
+
+	select {
+	case message := <-client.send:
+		var update BalanceUpdate
+		if err := json.Unmarshal(message.payload, &update); err != nil || update.Balance != "7.00" {
+			t.Fatalf("unexpected message %s: %v", message.payload, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("notification was not delivered")
+	}
+	cancel()
+	<-done
+}
+
+func TestPGBroadcasterPingsListener(t *testing.T) {
+	broadcaster := NewPGBroadcaster(stubExecer{}, NewHub())
+	broadcaster.pingInterval = time.Millisecond
+	listener := &fakeListener{notifications: make(chan *pq.Notification), pings: make(chan struct{}, 1)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go broadcaster.consume(ctx, listener)
+
+	select {
+	case <-listener.pings:
+	case <-time.After(time.Second):
+		t.Fatalf("listener was not pinged")
+	}
+}
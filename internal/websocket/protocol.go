@@ -0,0 +1,122 @@
+package websocket
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const (
+	ChannelBalances     = "balances"
+	ChannelTransactions = "transactions"
+	ChannelAlerts       = "alerts"
+	ChannelQuotesPrefix = "quotes:"
+	ChannelRatesPrefix  = "rates:"
+	// ChannelControl carries instructions between instances' hubs; clients
+	// can't subscribe to it.
+	ChannelControl = "control"
+)
+
+const (
+	EventBalanceUpdated  = "balance.updated"
+	EventBalanceSnapshot = "balance.snapshot"
+	EventSessionRevoked  = "session.revoked"
+	EventRateUpdated     = "rate.updated"
+	EventQuoteExpiring   = "quote.expiring"
+	EventQuoteExpired    = "quote.expired"
+	EventQuoteConsumed   = "quote.consumed"
+	EventAlertTriggered  = "alert.triggered"
+)
+
+const (
+	MessageEvent    = "event"
+	MessageAck      = "ack"
+	MessageError    = "error"
+	MessageBalance  = "balance"
+	MessageSnapshot = "snapshot"
+	MessageResync   = "resync"
+	MessageRevoked  = "revoked"
+)
+
+const (
+	OpSubscribe   = "subscribe"
+	OpUnsubscribe = "unsubscribe"
+)
+
+const maxSubscriptions = 32
+
+type request struct {
+	ID      string `json:"id"`
+	Op      string `json:"op"`
+	Channel string `json:"channel"`
+	Since   *int64 `json:"since,omitempty"`
+}
+
+type envelope struct {
+	Type    string          `json:"type"`
+	Channel string          `json:"channel"`
+	Event   string          `json:"event"`
+	Seq     int64           `json:"seq,omitempty"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type ackMessage struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Op      string `json:"op"`
+	Channel string `json:"channel"`
+}
+
+type errorMessage struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type balanceMessage struct {
+	Type string `json:"type"`
+	BalanceUpdate
+}
+
+type snapshotMessage struct {
+	Type string `json:"type"`
+	Snapshot
+}
+
+type revokedMessage struct {
+	Type string `json:"type"`
+}
+
+type resyncMessage struct {
+	Type string `json:"type"`
+	Seq  int64  `json:"seq"`
+}
+
+func eventEnvelope(message Message) envelope {
+	return envelope{Type: MessageEvent, Channel: message.Channel, Event: message.Event, Seq: message.Seq, Data: message.Data}
+}
+
+func ValidChannel(channel string) bool {
+	switch {
+	case channel == ChannelBalances, channel == ChannelTransactions, channel == ChannelAlerts:
+		return true
+	case strings.HasPrefix(channel, ChannelQuotesPrefix):
+		return len(channel) > len(ChannelQuotesPrefix)
+	case strings.HasPrefix(channel, ChannelRatesPrefix):
+		base, quote, ok := strings.Cut(strings.TrimPrefix(channel, ChannelRatesPrefix), "-")
+		return ok && isCurrencyCode(base) && isCurrencyCode(quote) && base != quote
+	}
+	return false
+}
+
+func isCurrencyCode(value string) bool {
+	if len(value) != 3 {
+		return false
+	}
+	for _, r := range value {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,18 @@
+package websocket
+
+import "testing"
+
+func TestValidChannel(t *testing.T) {
+	valid := []string{"balances", "transactions", "alerts", "quotes:q-1", "rates:USD-EUR"}
+	invalid := []string{"", "quotes:", "rates:USD", "rates:USD-USD", "rates:usd-eur", "ledger"}
+	for _, channel := range valid {
+		if !ValidChannel(channel) {
+			t.Fatalf("expected %q to be valid", channel)
+		}
+	}
+	for _, channel := range invalid {
+		if ValidChannel(channel) {
+			t.Fatalf("expected %q to be invalid", channel)
+		}
+	}
+}
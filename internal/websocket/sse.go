@@ -0,0 +1,116 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+type SSEOptions struct {
+	Channels  []string
+	SessionID string
+	Since     *int64
+	Backlog   func(since *int64) (Backlog, error)
+	Heartbeat time.Duration
+}
+
+// ServeSSE ends the stream on resync or revocation; clients reconnect with
+// Last-Event-ID.
+func ServeSSE(w http.ResponseWriter, r *http.Request, hub *Hub, userID string, opts SSEOptions) {
+	sub := hub.Subscribe(userID, opts.SessionID, false, opts.Channels...)
+	defer sub.Close()
+	var backlog Backlog
+	if slices.Contains(opts.Channels, ChannelBalances) && opts.Backlog != nil {
+		var err error
+		backlog, err = opts.Backlog(opts.Since)
+		if err != nil {
+			http.Error(w, "unable to load balances", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	controller := http.NewResponseController(w)
+	// The stream outlives the server's write timeout.
+	_ = controller.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	var lastSeq int64
+	if opts.Since != nil {
+		lastSeq = *opts.Since
+	}
+	if backlog.Snapshot != nil {
+		snapshot := *backlog.Snapshot
+		if snapshot.Balances == nil {
+			snapshot.Balances = []BalanceUpdate{}
+		}
+		data, _ := json.Marshal(snapshot)
+		payload, _ := json.Marshal(envelope{Type: MessageEvent, Channel: ChannelBalances, Event: EventBalanceSnapshot, Seq: snapshot.Seq, Data: data})
+		if writeSSE(w, snapshot.Seq, EventBalanceSnapshot, payload) != nil {
+			return
+		}
+		lastSeq = snapshot.Seq
+	}
+	for _, update := range backlog.Updates {
+		payload, _ := json.Marshal(eventEnvelope(BalanceMessage("", update)))
+		if writeSSE(w, update.Seq, EventBalanceUpdated, payload) != nil {
+			return
+		}
+		lastSeq = update.Seq
+	}
+	if controller.Flush() != nil {
+		return
+	}
+
+	heartbeat := opts.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case message := <-sub.send:
+			if message.seq != 0 && message.seq <= lastSeq {
+				continue
+			}
+			if writeSSE(w, message.seq, message.event, message.payload) != nil || controller.Flush() != nil {
+				return
+			}
+			if message.seq != 0 {
+				lastSeq = message.seq
+			}
+		case <-sub.resync:
+			payload, _ := json.Marshal(resyncMessage{Type: MessageResync, Seq: lastSeq})
+			_ = writeSSE(w, 0, MessageResync, payload)
+			_ = controller.Flush()
+			return
+		case <-sub.revoked:
+			payload, _ := json.Marshal(revokedMessage{Type: MessageRevoked})
+			_ = writeSSE(w, 0, MessageRevoked, payload)
+			_ = controller.Flush()
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil || controller.Flush() != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, seq int64, event string, payload []byte) error {
+	if seq != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", seq); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
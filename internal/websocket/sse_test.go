@@ -0,0 +1,177 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+func openSSE(t *testing.T, hub *Hub, opts SSEOptions) *bufio.Reader {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeSSE(w, r, hub, "user-1", opts)
+	}))
+	t.Cleanup(server.Close)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// readSSE returns the next event, or a comment as an event named ":".
+func readSSE(t *testing.T, reader *bufio.Reader) sseEvent {
+	t.Helper()
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return event
+		case strings.HasPrefix(line, ":"):
+			event.event = ":"
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func waitForSubscription(t *testing.T, hub *Hub) *Subscription {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		hub.mu.RLock()
+		for sub := range hub.clients["user-1"] {
+			hub.mu.RUnlock()
+			return sub
+		}
+		hub.mu.RUnlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("no subscription registered")
+	return nil
+}
+
+func TestServeSSESnapshotThenLiveEvents(t *testing.T) {
+	hub := NewHub()
+	reader := openSSE(t, hub, SSEOptions{
+		Channels: []string{ChannelBalances, ChannelTransactions},
+		Backlog: func(since *int64) (Backlog, error) {
+			return Backlog{Snapshot: &Snapshot{Seq: 4, Balances: []BalanceUpdate{{AccountID: "acc-1", Balance: "4.00", Currency: "USD"}}}}, nil
+		},
+	})
+
+	snapshot := readSSE(t, reader)
+	if snapshot.id != "4" || snapshot.event != EventBalanceSnapshot || !strings.Contains(snapshot.data, `"balances":[{"account_id":"acc-1"`) {
+		t.Fatalf("unexpected snapshot: %#v", snapshot)
+	}
+	hub.Publish(BalanceMessage("user-1", BalanceUpdate{Seq: 4, AccountID: "acc-1", Balance: "4.00", Currency: "USD"}))
+	hub.Publish(Message{UserID: "user-1", Channel: ChannelTransactions, Event: "transfer.completed", Data: []byte(`{"transaction_id":"tx-1"}`)})
+	hub.Publish(BalanceMessage("user-1", BalanceUpdate{Seq: 5, AccountID: "acc-1", Balance: "5.00", Currency: "USD"}))
+
+	transaction := readSSE(t, reader)
+	if transaction.id != "" || transaction.event != "transfer.completed" || !strings.Contains(transaction.data, `"channel":"transactions"`) {
+		t.Fatalf("unexpected transaction event: %#v", transaction)
+	}
+	update := readSSE(t, reader)
+	if update.id != "5" || update.event != EventBalanceUpdated || !strings.Contains(update.data, `"balance":"5.00"`) {
+		t.Fatalf("unexpected update: %#v", update)
+	}
+}
+
+func TestServeSSEResumesFromLastEventID(t *testing.T) {
+	since := int64(2)
+	reader := openSSE(t, NewHub(), SSEOptions{
+		Channels: []string{ChannelBalances},
+		Since:    &since,
+		Backlog: func(got *int64) (Backlog, error) {
+			if got == nil || *got != 2 {
+				t.Errorf("unexpected since: %v", got)
+			}
+			return Backlog{Updates: []BalanceUpdate{{Seq: 3, AccountID: "acc-1", Balance: "3.00", Currency: "USD"}}}, nil
+		},
+	})
+	event := readSSE(t, reader)
+	if event.id != "3" || event.event != EventBalanceUpdated {
+		t.Fatalf("unexpected replay: %#v", event)
+	}
+}
+
+func TestServeSSEHeartbeatAndResync(t *testing.T) {
+	hub := NewHub()
+	reader := openSSE(t, hub, SSEOptions{Channels: []string{ChannelTransactions}, Heartbeat: 10 * time.Millisecond})
+
+	if event := readSSE(t, reader); event.event != ":" {
+		t.Fatalf("expected heartbeat, got %#v", event)
+	}
+	sub := waitForSubscription(t, hub)
+	sub.resyncOnce.Do(func() { close(sub.resync) })
+	for {
+		event := readSSE(t, reader)
+		if event.event == ":" {
+			continue
+		}
+		if event.event != MessageResync || event.data != `{"type":"resync","seq":0}` {
+			t.Fatalf("unexpected event: %#v", event)
+		}
+		break
+	}
+	if _, err := reader.ReadString('\n'); err == nil {
+		t.Fatalf("expected the stream to end after resync")
+	}
+}
+
+func TestServeSSEEndsOnRevokedSession(t *testing.T) {
+	hub := NewHub()
+	reader := openSSE(t, hub, SSEOptions{Channels: []string{ChannelTransactions}, SessionID: "session-1"})
+	waitForSubscription(t, hub)
+
+	hub.Disconnect("user-1", "session-1")
+	if event := readSSE(t, reader); event.event != MessageRevoked {
+		t.Fatalf("unexpected event: %#v", event)
+	}
+	if _, err := reader.ReadString('\n'); err == nil {
+		t.Fatalf("expected the stream to end after revocation")
+	}
+}
+
+func TestServeSSEBacklogError(t *testing.T) {
+	hub := NewHub()
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	ServeSSE(rr, req, hub, "user-1", SSEOptions{
+		Channels: []string{ChannelBalances},
+		Backlog:  func(*int64) (Backlog, error) { return Backlog{}, context.DeadlineExceeded },
+	})
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rr.Code)
+	}
+	if len(hub.clients) != 0 {
+		t.Fatalf("expected subscription to be released")
+	}
+}
@@ -0,0 +1,29 @@
+-- +migrate Up
+ALTER TABLE ledger_entries
+    ADD COLUMN IF NOT EXISTS seq BIGSERIAL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS ledger_seq_idx
+    ON ledger_entries (seq);
+
+CREATE INDEX IF NOT EXISTS ledger_account_seq_idx
+    ON ledger_entries (account_id, seq) INCLUDE (amount);
+
+CREATE TABLE IF NOT EXISTS balance_checkpoints (
+    id TEXT PRIMARY KEY,
+    account_id TEXT NOT NULL REFERENCES accounts(id),
+    entry_seq BIGINT NOT NULL,
+    last_entry_id TEXT REFERENCES ledger_entries(id),
+    last_entry_at TIMESTAMPTZ,
+    balance BIGINT NOT NULL,
+    entry_count BIGINT NOT NULL CHECK (entry_count >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS balance_checkpoints_account_seq_idx
+    ON balance_checkpoints (account_id, entry_seq DESC);
+
+-- +migrate Down
+DROP TABLE IF EXISTS balance_checkpoints;
+DROP INDEX IF EXISTS ledger_account_seq_idx;
+DROP INDEX IF EXISTS ledger_seq_idx;
+ALTER TABLE ledger_entries DROP COLUMN IF EXISTS seq;
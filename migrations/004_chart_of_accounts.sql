@@ -0,0 +1,36 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS gl_accounts (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('asset', 'liability', 'equity', 'income', 'expense')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO gl_accounts (code, name, type) VALUES
+    ('1000', 'Settlement and treasury', 'asset'),
+    ('2000', 'Customer deposits', 'liability'),
+    ('3000', 'Owner equity', 'equity'),
+    ('4000', 'Foreign exchange income', 'income'),
+    ('5000', 'Customer incentives', 'expense')
+ON CONFLICT (code) DO NOTHING;
+
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS gl_code TEXT REFERENCES gl_accounts(code);
+
+UPDATE accounts
+SET gl_code = CASE WHEN is_system THEN '1000' ELSE '2000' END
+WHERE gl_code IS NULL;
+
+ALTER TABLE accounts
+    ALTER COLUMN gl_code SET DEFAULT '2000';
+
+ALTER TABLE accounts
+    ALTER COLUMN gl_code SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS accounts_gl_code_idx
+    ON accounts (gl_code);
+
+-- +migrate Down
+DROP INDEX IF EXISTS accounts_gl_code_idx;
+ALTER TABLE accounts DROP COLUMN IF EXISTS gl_code;
+DROP TABLE IF EXISTS gl_accounts;
@@ -0,0 +1,40 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS accounting_periods (
+    id TEXT PRIMARY KEY,
+    period_start TIMESTAMPTZ,
+    period_end TIMESTAMPTZ NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('closed', 'reopened')),
+    closed_by TEXT NOT NULL REFERENCES users(id),
+    closed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    reopened_by TEXT REFERENCES users(id),
+    reopened_at TIMESTAMPTZ,
+    reopen_reason TEXT,
+    CHECK (period_start IS NULL OR period_start <= period_end)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS accounting_periods_end_idx
+    ON accounting_periods (period_end);
+
+CREATE TABLE IF NOT EXISTS period_balances (
+    period_id TEXT NOT NULL REFERENCES accounting_periods(id),
+    account_id TEXT NOT NULL REFERENCES accounts(id),
+    currency CHAR(3) NOT NULL,
+    balance BIGINT NOT NULL,
+    last_entry_id TEXT REFERENCES ledger_entries(id),
+    last_entry_seq BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (period_id, account_id)
+);
+
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS transactions_type_check;
+
+ALTER TABLE transactions
+    ADD CONSTRAINT transactions_type_check CHECK (type IN ('transfer', 'exchange', 'adjustment'));
+
+-- +migrate Down
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS transactions_type_check;
+ALTER TABLE transactions
+    ADD CONSTRAINT transactions_type_check CHECK (type IN ('transfer', 'exchange'));
+DROP TABLE IF EXISTS period_balances;
+DROP TABLE IF EXISTS accounting_periods;
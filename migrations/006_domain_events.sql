@@ -0,0 +1,26 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS domain_events (
+    id TEXT PRIMARY KEY,
+    seq BIGSERIAL NOT NULL,
+    event_type TEXT NOT NULL,
+    aggregate_type TEXT NOT NULL,
+    aggregate_id TEXT NOT NULL,
+    user_id TEXT REFERENCES users(id),
+    payload JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_sinks TEXT[] NOT NULL DEFAULT '{}',
+    delivered_at TIMESTAMPTZ,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS domain_events_seq_idx
+    ON domain_events (seq);
+
+CREATE INDEX IF NOT EXISTS domain_events_pending_idx
+    ON domain_events (next_attempt_at, seq)
+    WHERE delivered_at IS NULL;
+
+-- +migrate Down
+DROP TABLE IF EXISTS domain_events;
@@ -0,0 +1,22 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS user_stream_heads (
+    user_id TEXT PRIMARY KEY REFERENCES users(id),
+    last_seq BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_stream_events (
+    user_id TEXT NOT NULL REFERENCES users(id),
+    seq BIGINT NOT NULL,
+    event_id TEXT NOT NULL REFERENCES domain_events(id),
+    account_id TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, seq)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_stream_events_event_idx
+    ON user_stream_events (user_id, event_id, account_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS user_stream_events;
+DROP TABLE IF EXISTS user_stream_heads;
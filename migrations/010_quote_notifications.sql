@@ -0,0 +1,20 @@
+-- +migrate Up
+ALTER TABLE exchange_quotes
+    ADD COLUMN IF NOT EXISTS expiring_notified_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS expired_notified_at TIMESTAMPTZ;
+
+-- Quotes that are already settled don't need lifecycle events.
+UPDATE exchange_quotes
+SET expiring_notified_at = COALESCE(expiring_notified_at, NOW()),
+    expired_notified_at = COALESCE(expired_notified_at, NOW())
+WHERE consumed_at IS NOT NULL OR expires_at <= NOW();
+
+CREATE INDEX IF NOT EXISTS exchange_quotes_pending_expiry_idx
+    ON exchange_quotes (expires_at)
+    WHERE consumed_at IS NULL AND expired_notified_at IS NULL;
+
+-- +migrate Down
+DROP INDEX IF EXISTS exchange_quotes_pending_expiry_idx;
+ALTER TABLE exchange_quotes
+    DROP COLUMN IF EXISTS expired_notified_at,
+    DROP COLUMN IF EXISTS expiring_notified_at;
@@ -0,0 +1,19 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('low_balance', 'large_transaction', 'incoming_funds')),
+    currency TEXT NOT NULL,
+    threshold BIGINT NOT NULL CHECK (threshold >= 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    last_triggered_at TIMESTAMPTZ,
+    suppressed_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS alert_rules_user_idx
+    ON alert_rules (user_id)
+    WHERE active = TRUE;
+
+-- +migrate Down
+DROP TABLE IF EXISTS alert_rules;
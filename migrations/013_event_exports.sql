@@ -0,0 +1,10 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS event_export_checkpoints (
+    name TEXT PRIMARY KEY,
+    last_seq BIGINT NOT NULL DEFAULT 0,
+    leased_until TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS event_export_checkpoints;
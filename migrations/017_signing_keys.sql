@@ -0,0 +1,16 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS jwt_signing_keys (
+    kid TEXT PRIMARY KEY,
+    generation INT NOT NULL UNIQUE,
+    algorithm TEXT NOT NULL,
+    private_key TEXT NOT NULL,
+    activates_at TIMESTAMPTZ NOT NULL,
+    retires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS jwt_signing_keys_retires_idx
+    ON jwt_signing_keys (retires_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS jwt_signing_keys;
@@ -0,0 +1,16 @@
+-- +migrate Up
+ALTER TABLE auth_sessions
+    ADD COLUMN IF NOT EXISTS device_label TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS ip TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS auth_sessions_user_active_idx
+    ON auth_sessions (user_id, last_refreshed_at DESC)
+    WHERE revoked_at IS NULL;
+
+-- +migrate Down
+DROP INDEX IF EXISTS auth_sessions_user_active_idx;
+ALTER TABLE auth_sessions
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS ip,
+    DROP COLUMN IF EXISTS device_label;
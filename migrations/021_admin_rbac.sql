@@ -0,0 +1,41 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS roles (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    permissions TEXT[] NOT NULL DEFAULT '{}',
+    created_by TEXT REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO roles (name, description, permissions) VALUES
+    ('viewer', 'Read-only access to users, transactions and reports', ARRAY['CanViewUsers', 'CanViewTransactions', 'CanViewReports']),
+    ('support', 'Look up users and freeze or unlock them', ARRAY['CanViewUsers', 'CanViewTransactions', 'CanFreezeAccounts']),
+    ('accountant', 'Reports, period close and adjustments', ARRAY['CanViewTransactions', 'CanViewReports', 'CanClosePeriods', 'CanPostAdjustments']),
+    ('treasury', 'Publish exchange rates', ARRAY['CanViewReports', 'CanManageRates'])
+ON CONFLICT (name) DO NOTHING;
+
+-- Roles granted before this migration were permission names. Each becomes a
+-- role of the same name holding that permission; unknown names hold none.
+INSERT INTO roles (name, description, permissions)
+SELECT DISTINCT role,
+       'Granted before roles bundled permissions',
+       CASE
+           WHEN role IN ('CanViewUsers', 'CanViewTransactions', 'CanViewReports', 'CanClosePeriods', 'CanPostAdjustments', 'CanManageRates', 'CanFreezeAccounts', 'CanViewAdmins')
+           THEN ARRAY[role]
+           ELSE '{}'::TEXT[]
+       END
+FROM admin_roles
+ON CONFLICT (name) DO NOTHING;
+
+ALTER TABLE admin_roles
+    ADD COLUMN IF NOT EXISTS granted_by TEXT REFERENCES users(id),
+    ADD COLUMN IF NOT EXISTS granted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD CONSTRAINT admin_roles_role_fkey FOREIGN KEY (role) REFERENCES roles(name);
+
+-- +migrate Down
+ALTER TABLE admin_roles
+    DROP CONSTRAINT IF EXISTS admin_roles_role_fkey,
+    DROP COLUMN IF EXISTS granted_at,
+    DROP COLUMN IF EXISTS granted_by;
+
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,24 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS approval_requests (
+    id TEXT PRIMARY KEY,
+    action TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+    requested_by TEXT NOT NULL REFERENCES users(id),
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'expired', 'executed', 'failed')),
+    decided_by TEXT REFERENCES users(id),
+    decided_at TIMESTAMPTZ,
+    decision_reason TEXT NOT NULL DEFAULT '',
+    result JSONB,
+    error TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS approval_requests_status_idx
+    ON approval_requests (status, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS approval_requests_requested_by_idx
+    ON approval_requests (requested_by, created_at DESC);
+
+-- +migrate Down
+DROP TABLE IF EXISTS approval_requests;
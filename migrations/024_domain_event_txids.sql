@@ -0,0 +1,13 @@
+-- +migrate Up
+ALTER TABLE domain_events ADD COLUMN IF NOT EXISTS txid BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE domain_events ALTER COLUMN txid SET DEFAULT pg_current_xact_id()::text::bigint;
+
+CREATE INDEX IF NOT EXISTS domain_events_txid_idx
+    ON domain_events (txid, seq);
+
+ALTER TABLE event_export_checkpoints ADD COLUMN IF NOT EXISTS last_txid BIGINT NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE event_export_checkpoints DROP COLUMN IF EXISTS last_txid;
+DROP INDEX IF EXISTS domain_events_txid_idx;
+ALTER TABLE domain_events DROP COLUMN IF EXISTS txid;
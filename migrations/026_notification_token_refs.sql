@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE notification_deliveries ADD COLUMN IF NOT EXISTS token_ref TEXT;
+
+-- +migrate Down
+ALTER TABLE notification_deliveries DROP COLUMN IF EXISTS token_ref;
@@ -0,0 +1,21 @@
+-- +migrate Up
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS transactions_type_check;
+
+ALTER TABLE transactions
+    ADD CONSTRAINT transactions_type_check CHECK (type IN ('transfer', 'exchange', 'adjustment', 'reversal'));
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS reversal_of TEXT REFERENCES transactions(id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_reversal_of_idx
+    ON transactions (reversal_of)
+    WHERE reversal_of IS NOT NULL;
+
+-- +migrate Down
+DROP INDEX IF EXISTS transactions_reversal_of_idx;
+ALTER TABLE transactions DROP COLUMN IF EXISTS reversal_of;
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS transactions_type_check;
+ALTER TABLE transactions
+    ADD CONSTRAINT transactions_type_check CHECK (type IN ('transfer', 'exchange', 'adjustment'));